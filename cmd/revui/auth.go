@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/deparker/revui/internal/auth"
+)
+
+var authCommand = &command{
+	name:    "auth",
+	args:    "status",
+	summary: "Show which credential each hosting integration would use",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		return func(args []string) error {
+			if len(args) != 1 || args[0] != "status" {
+				return errors.New("auth takes one subcommand: status")
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			// Outside a repository, targets that depend on it are left out.
+			runner, _ := openRepo()
+
+			type entry struct {
+				label  string
+				lookup auth.Lookup
+			}
+			var entries []entry
+			for _, t := range configuredTargets(cfg, runner) {
+				if l, ok := t.Lookup(); ok {
+					entries = append(entries, entry{t.Label, l})
+				}
+			}
+			if len(entries) == 0 {
+				fmt.Println("No hosting integrations are configured.")
+				return nil
+			}
+			for _, e := range entries {
+				printCredential(os.Stdout, e.label, e.lookup)
+			}
+			return nil
+		}
+	},
+}
+
+// printCredential writes where lookup finds a credential, without showing it.
+func printCredential(w io.Writer, label string, lookup auth.Lookup) {
+	fmt.Fprintf(w, "%s\n", label)
+	cred, err := lookup.Resolve()
+	if err != nil {
+		fmt.Fprintf(w, "  %v\n", err)
+		return
+	}
+	user := ""
+	if cred.Username != "" {
+		user = " for " + cred.Username
+	}
+	fmt.Fprintf(w, "  token%s from %s (%d characters)\n", user, cred.Source, len(cred.Token))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/deparker/revui/internal/difftest"
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/ui"
+)
+
+// benchCommand times parsing and rendering a real diff, for finding what
+// makes big reviews slow. It isn't listed in usage or completions.
+var benchCommand = &command{
+	name:    "bench",
+	args:    "[<base> | <file.diff>]",
+	summary: "Time parsing and rendering the branch's diff, or a captured one",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		remote := fs.String("remote", "origin", "remote to detect default branch from")
+		count := fs.Int("count", 5, "times to parse and render the diff")
+		cpuProfile := fs.String("cpuprofile", "", "write a CPU profile to this file")
+		memProfile := fs.String("memprofile", "", "write a heap profile to this file")
+		capture := fs.String("capture", "", "save the diff to this file (gzipped if it ends in .gz) for the benchmarks in internal/difftest/testdata")
+
+		return func(args []string) error {
+			if len(args) > 1 {
+				return fmt.Errorf("too many arguments: %v (want at most a base ref or a diff file)", args)
+			}
+			if *count < 1 {
+				return errors.New("--count must be at least 1")
+			}
+
+			var raw string
+			if len(args) == 1 {
+				if fi, err := os.Stat(args[0]); err == nil && fi.Mode().IsRegular() {
+					if raw, err = difftest.Load(args[0]); err != nil {
+						return err
+					}
+				}
+			}
+			if raw == "" {
+				runner, err := openRepo()
+				if err != nil {
+					return err
+				}
+				base := runner.DefaultBranch(*remote)
+				if len(args) == 1 {
+					base = args[0]
+				}
+				if raw, err = runner.Diff(base); err != nil {
+					return err
+				}
+			}
+			if *capture != "" {
+				if err := difftest.Save(*capture, raw); err != nil {
+					return fmt.Errorf("saving diff: %w", err)
+				}
+			}
+
+			if *cpuProfile != "" {
+				f, err := os.Create(*cpuProfile)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				if err := pprof.StartCPUProfile(f); err != nil {
+					return err
+				}
+				defer pprof.StopCPUProfile()
+			}
+
+			var parse, render time.Duration
+			var diffs []git.FileDiff
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for range *count {
+				start := time.Now()
+				var err error
+				if diffs, err = git.ParseDiff(raw); err != nil {
+					return err
+				}
+				parse += time.Since(start)
+
+				start = time.Now()
+				renderDiffs(diffs)
+				render += time.Since(start)
+			}
+			runtime.ReadMemStats(&after)
+
+			lines := 0
+			for _, fd := range diffs {
+				for _, h := range fd.Hunks {
+					lines += len(h.Lines)
+				}
+			}
+			n := time.Duration(*count)
+			fmt.Printf("%d files, %d lines, %d bytes\n", len(diffs), lines, len(raw))
+			fmt.Printf("parse:  %v\n", parse/n)
+			fmt.Printf("render: %v\n", render/n)
+			fmt.Printf("alloc:  %d MB per run\n", (after.TotalAlloc-before.TotalAlloc)/uint64(*count)>>20)
+
+			if *memProfile != "" {
+				f, err := os.Create(*memProfile)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				runtime.GC()
+				if err := pprof.WriteHeapProfile(f); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	},
+}
+
+// renderDiffs opens each of diffs the way the review does, rendering its
+// top and bottom, unified and side by side.
+func renderDiffs(diffs []git.FileDiff) {
+	bottom := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'G'}}
+	for i := range diffs {
+		for _, sideBySide := range []bool{false, true} {
+			dv := ui.NewDiffViewer(120, 40)
+			dv.SetSideBySide(sideBySide)
+			dv.SetDiff(&diffs[i])
+			dv.View()
+			dv, _ = dv.Update(bottom)
+			dv.View()
+		}
+	}
+}
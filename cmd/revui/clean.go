@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deparker/revui/internal/config"
+	"github.com/deparker/revui/internal/output"
+)
+
+// defaultKeepTempDays is how many days the files reviews were handed to
+// Claude in, and review files left in the temp directory, are kept when the
+// config doesn't say.
+const defaultKeepTempDays = 7
+
+var cleanCommand = &command{
+	name:    "clean",
+	summary: "Remove old review files, including those handed to Claude, from the temp directory",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		days := fs.Int("days", -1, "remove files written more than this many days ago; 0 removes them all (keep_temp_days, or 7, if not set)")
+		dryRun := fs.Bool("dry-run", false, "list the files that would be removed, without removing them")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments: %v", args)
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			output.SetTempDir(cfg.TempDir)
+			keep := *days
+			if keep < 0 {
+				keep = max(0, keepTempDays(cfg))
+			}
+			removed, err := cleanHandoffFiles(keep, *dryRun)
+			for _, path := range removed {
+				fmt.Println(path)
+			}
+			if len(removed) == 0 {
+				fmt.Printf("No review files written more than %d days ago in %s.\n", keep, output.TempDir())
+			}
+			return err
+		}
+	},
+}
+
+// keepTempDays returns how many days cfg keeps handoff files for.
+func keepTempDays(cfg *config.Config) int {
+	if cfg.KeepTempDays == 0 {
+		return defaultKeepTempDays
+	}
+	return cfg.KeepTempDays
+}
+
+// cleanHandoffFiles removes the files reviews were handed to Claude in, and
+// review files left in the temp directory, more than days ago and returns
+// their paths, or only lists them when dryRun is set. Review files written
+// under another name or elsewhere are left alone, as are files it isn't
+// allowed to remove, such as another user's in a shared temp directory, which
+// aren't listed.
+func cleanHandoffFiles(days int, dryRun bool) ([]string, error) {
+	old, err := output.OldHandoffFiles(time.Now().AddDate(0, 0, -days))
+	if err != nil || dryRun {
+		return old, err
+	}
+	var removed []string
+	for _, path := range old {
+		if err := os.Remove(path); err != nil {
+			if os.IsPermission(err) || os.IsNotExist(err) {
+				continue
+			}
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
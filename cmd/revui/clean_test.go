@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/deparker/revui/internal/output"
+)
+
+func TestCleanHandoffFiles(t *testing.T) {
+	dir := t.TempDir()
+	output.SetTempDir(dir)
+	defer output.SetTempDir("")
+
+	old := time.Now().AddDate(0, 0, -10)
+	files := map[string]time.Time{
+		"revui-claude-old.md":        old,
+		"revui-claude-new.md":        time.Now(),
+		"revui-review-1700000000.md": old.Add(-time.Hour), // handed to Claude before hand-offs had their own name
+		"my-review.md":               old,                 // named by the user
+	}
+	for name, at := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	listed, err := cleanHandoffFiles(7, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "revui-review-1700000000.md"), filepath.Join(dir, "revui-claude-old.md")}
+	if !slices.Equal(listed, want) {
+		t.Errorf("dry run listed %v, want %v", listed, want)
+	}
+	for _, path := range want {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("dry run removed %s", path)
+		}
+	}
+
+	removed, err := cleanHandoffFiles(7, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, want) {
+		t.Errorf("removed %v, want %v", removed, want)
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(left) != 2 {
+		t.Errorf("left %v, want the new handoff file and the named review file", left)
+	}
+}
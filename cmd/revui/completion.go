@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var completionCommand = &command{
+	name:    "completion",
+	args:    "<bash|zsh|fish>",
+	summary: "Print a shell completion script",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		return func(args []string) error {
+			if len(args) != 1 {
+				return errors.New("completion takes one shell argument: bash, zsh, or fish")
+			}
+			switch args[0] {
+			case "bash":
+				fmt.Print(bashCompletion())
+			case "zsh":
+				fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion())
+			case "fish":
+				fmt.Print(fishCompletion())
+			default:
+				return fmt.Errorf("unsupported shell %q (want bash, zsh, or fish)", args[0])
+			}
+			return nil
+		}
+	},
+}
+
+// commandFlags returns the "--name" flags registered by a command. One-letter
+// shorthands are left out; completing them saves nothing.
+func commandFlags(c *command) []string {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	c.setup(fs)
+	var flags []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) > 1 {
+			flags = append(flags, "--"+f.Name)
+		}
+	})
+	return flags
+}
+
+func bashCompletion() string {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+
+	var b strings.Builder
+	b.WriteString("_revui() {\n")
+	b.WriteString("  local cur=${COMP_WORDS[COMP_CWORD]}\n")
+	b.WriteString("  local cmd=" + commands[0].name + "\n")
+	b.WriteString("  if [ \"$COMP_CWORD\" -gt 1 ] || [[ \"$cur\" != -* ]]; then\n")
+	b.WriteString("    case \"${COMP_WORDS[1]}\" in " + strings.Join(names, "|") + ") cmd=${COMP_WORDS[1]};; esac\n")
+	b.WriteString("  fi\n")
+	b.WriteString("  if [ \"$COMP_CWORD\" -eq 1 ] && [[ \"$cur\" != -* ]]; then\n")
+	fmt.Fprintf(&b, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	b.WriteString("    return\n")
+	b.WriteString("  fi\n")
+	b.WriteString("  case \"$cmd\" in\n")
+	for _, c := range commands {
+		words := strings.Join(commandFlags(c), " ")
+		switch c.name {
+		case "completion":
+			words = "bash zsh fish"
+		case "auth":
+			words = "status"
+		case "sessions":
+			words = "resume delete " + words
+		case "history":
+			words = "open send " + words
+		case "import":
+			fmt.Fprintf(&b, "    %s) COMPREPLY=($(compgen -f -- \"$cur\"));;\n", c.name)
+			continue
+		}
+		fmt.Fprintf(&b, "    %s) COMPREPLY=($(compgen -W %q -- \"$cur\"));;\n", c.name, words)
+	}
+	b.WriteString("  esac\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -F _revui revui\n")
+	return b.String()
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString("complete -c revui -f\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "complete -c revui -n __fish_use_subcommand -a %s -d %q\n", c.name, c.summary)
+		for _, f := range commandFlags(c) {
+			cond := "__fish_seen_subcommand_from " + c.name
+			if c == commands[0] {
+				cond = "not __fish_seen_subcommand_from " + strings.Join(otherCommandNames(c), " ")
+			}
+			fmt.Fprintf(&b, "complete -c revui -n %q -l %s\n", cond, strings.TrimPrefix(f, "--"))
+		}
+	}
+	b.WriteString("complete -c revui -n \"__fish_seen_subcommand_from completion\" -a \"bash zsh fish\"\n")
+	b.WriteString("complete -c revui -n \"__fish_seen_subcommand_from import\" -F\n")
+	b.WriteString("complete -c revui -n \"__fish_seen_subcommand_from auth\" -a status\n")
+	b.WriteString("complete -c revui -n \"__fish_seen_subcommand_from sessions\" -a \"resume delete\"\n")
+	b.WriteString("complete -c revui -n \"__fish_seen_subcommand_from history\" -a \"open send\"\n")
+	return b.String()
+}
+
+func otherCommandNames(skip *command) []string {
+	var names []string
+	for _, c := range commands {
+		if c != skip {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
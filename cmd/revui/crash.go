@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// resetTerminal undoes every mode the TUI may have turned on, in case the
+// program's own restoring fails: it leaves the alternate screen, shows the
+// cursor, turns off mouse reporting, bracketed paste, and focus reporting,
+// and resets colors.
+const resetTerminal = "\x1b[?1049l\x1b[?25h\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l\x1b[?2004l\x1b[?1004l\x1b[0m"
+
+// exit ends the program after a crash, and crashOutput takes the report;
+// both are replaced in tests.
+var (
+	exit                  = os.Exit
+	crashOutput io.Writer = os.Stderr
+)
+
+// crashGuard turns a panic anywhere in the review, in its event loop or in
+// one of its commands or background goroutines, into a usable terminal and
+// a report on stderr, instead of a stack trace scrawled over the alternate
+// screen of a terminal left in raw mode.
+type crashGuard struct {
+	program     *tea.Program
+	sessionPath string // where the review's comments are autosaved, if anywhere
+	once        sync.Once
+}
+
+// recover is deferred by each goroutine the review runs. On a panic it
+// restores the terminal, reports the panic, and exits.
+func (g *crashGuard) recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	g.once.Do(func() {
+		g.restore()
+		fmt.Fprintf(crashOutput, "revui crashed: %v\n\n%s\n", r, stack)
+		if _, err := os.Stat(g.sessionPath); g.sessionPath != "" && err == nil {
+			fmt.Fprintf(crashOutput, "Comments written before the crash are saved in %s; `revui resume` picks them up.\n", g.sessionPath)
+		}
+	})
+	exit(2)
+}
+
+func (g *crashGuard) restore() {
+	defer func() {
+		// The program may be too broken to restore itself.
+		recover()
+		os.Stdout.WriteString(resetTerminal)
+	}()
+	if g.program != nil {
+		g.program.ReleaseTerminal()
+	}
+}
+
+// cmd returns cmd, recovering from panics in it and in the commands of any
+// batch it returns, which Bubble Tea runs on goroutines of their own.
+func (g *crashGuard) cmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer g.recover()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i := range batch {
+				batch[i] = g.cmd(batch[i])
+			}
+		}
+		return msg
+	}
+}
+
+// guardedModel runs a model's commands under a crashGuard.
+type guardedModel struct {
+	tea.Model
+	guard *crashGuard
+}
+
+func (m guardedModel) Init() tea.Cmd {
+	return m.guard.cmd(m.Model.Init())
+}
+
+func (m guardedModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.Model.Update(msg)
+	return guardedModel{next, m.guard}, m.guard.cmd(cmd)
+}
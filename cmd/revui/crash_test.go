@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// catchCrashes makes crash guards record their exit code and report instead
+// of exiting, until the test ends.
+func catchCrashes(t *testing.T) (code *int, report *bytes.Buffer) {
+	t.Helper()
+	code, report = new(int), new(bytes.Buffer)
+	oldExit, oldOutput := exit, crashOutput
+	exit = func(c int) { *code = c }
+	crashOutput = report
+	t.Cleanup(func() { exit, crashOutput = oldExit, oldOutput })
+	return code, report
+}
+
+// panicModel is a model whose commands panic.
+type panicModel struct {
+	batch bool // return the panicking command in a batch
+}
+
+func (m panicModel) Init() tea.Cmd { return nil }
+
+func (m panicModel) Update(tea.Msg) (tea.Model, tea.Cmd) {
+	boom := func() tea.Msg { panic("boom") }
+	if m.batch {
+		return m, tea.Batch(boom, boom)
+	}
+	return m, boom
+}
+
+func (m panicModel) View() string { return "" }
+
+func TestGuardedModelCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		batch bool
+	}{
+		{name: "command"},
+		{name: "batched commands", batch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, report := catchCrashes(t)
+			m := guardedModel{panicModel{batch: tt.batch}, &crashGuard{}}
+			next, cmd := m.Update(nil)
+			if _, ok := next.(guardedModel); !ok {
+				t.Fatalf("Update returned %T, want the model still guarded", next)
+			}
+
+			msg := cmd()
+			if batch, ok := msg.(tea.BatchMsg); ok {
+				// Bubble Tea runs a batch's commands on goroutines of
+				// their own, out of reach of the guard on the batch.
+				for _, c := range batch {
+					c()
+				}
+			}
+			if *code != 2 || !strings.Contains(report.String(), "revui crashed: boom") {
+				t.Errorf("exit code %d, report %q; want the panic reported and exit code 2", *code, report)
+			}
+		})
+	}
+}
+
+func TestCrashGuardSessionPath(t *testing.T) {
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "feature.json")
+	if err := os.WriteFile(saved, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantPath bool
+	}{
+		{name: "saved session", path: saved, wantPath: true},
+		{name: "nothing saved yet", path: filepath.Join(dir, "other.json")},
+		{name: "no session", path: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, report := catchCrashes(t)
+			g := &crashGuard{sessionPath: tt.path}
+			func() {
+				defer g.recover()
+				panic("boom")
+			}()
+			if *code != 2 {
+				t.Errorf("exit code = %d, want 2", *code)
+			}
+			if got := strings.Contains(report.String(), "Comments written before the crash are saved"); got != tt.wantPath {
+				t.Errorf("report %q names the session: %v, want %v", report, got, tt.wantPath)
+			}
+			if tt.wantPath && !strings.Contains(report.String(), tt.path) {
+				t.Errorf("report %q doesn't name %s", report, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/session"
+)
+
+var exportCommand = &command{
+	name:    "export",
+	summary: "Print the saved review for the current branch without opening the TUI",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		format := fs.String("format", "markdown", "output format: markdown, json (readable by import), or patch (format-patch series annotated with comments)")
+		outPath := fs.String("output", "", "write to this file instead of stdout")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments: %v", args)
+			}
+
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+			path, branch, err := sessionPath(runner)
+			if err != nil {
+				return err
+			}
+			sess, err := session.Load(path)
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("no saved review session for branch %q", branch)
+			}
+			if err != nil {
+				return err
+			}
+
+			var out []byte
+			switch *format {
+			case "markdown":
+				out = []byte(formatSession(runner, sess))
+				if table := comment.FormatVerdicts(sess.Verdicts, sess.Comments); table != "" {
+					out = append([]byte(table+"\n"), out...)
+					if len(sess.Comments) == 0 {
+						out = []byte(table)
+					}
+				}
+			case "json":
+				out, err = json.MarshalIndent(sess, "", "  ")
+				if err != nil {
+					return err
+				}
+				out = append(out, '\n')
+			case "patch":
+				if sess.Uncommitted || sess.Base == "" {
+					return errors.New("patch export needs a branch review; this session reviewed uncommitted changes")
+				}
+				series, err := runner.FormatPatch(sess.Base)
+				if err != nil {
+					return err
+				}
+				out = []byte(comment.AnnotatePatch(series, sess.Comments))
+			default:
+				return fmt.Errorf("unknown format %q (want markdown, json, or patch)", *format)
+			}
+
+			if *outPath == "" {
+				_, err = os.Stdout.Write(out)
+				return err
+			}
+			return os.WriteFile(*outPath, out, 0o644)
+		}
+	},
+}
+
+// formatSession formats a saved review, with code snippets and references to
+// other files when the config asks for them. Both come from the diff as it is
+// now, so comments on lines that have since changed may get none.
+func formatSession(runner *git.Runner, sess *session.Session) string {
+	cfg, err := loadConfig()
+	if err != nil || cfg.SnippetContext == nil && !cfg.SeeAlso {
+		return comment.Format(sess.Comments)
+	}
+	diffs := sessionDiffs(runner, sess)
+	comments := sess.Comments
+	if cfg.SeeAlso {
+		var files []git.ChangedFile
+		switch {
+		case sess.Uncommitted:
+			files, _ = runner.UncommittedFiles()
+		case sess.Base != "":
+			files, _ = runner.ChangedFiles(sess.Base)
+		}
+		changed := make([]*git.FileDiff, 0, len(files))
+		for _, f := range files {
+			changed = append(changed, sessionDiff(runner, sess, f.Path))
+		}
+		comments = comment.SeeAlso(comments, diffs, comment.NewIndex(changed))
+	}
+	if cfg.SnippetContext == nil {
+		return comment.Format(comments)
+	}
+	return comment.FormatSnippets(comments, diffs, *cfg.SnippetContext)
+}
+
+// sessionDiffs returns the diff of each file a saved review comments on, as
+// it is now. Files whose diff can't be loaded are left out.
+func sessionDiffs(runner *git.Runner, sess *session.Session) map[string]*git.FileDiff {
+	diffs := make(map[string]*git.FileDiff)
+	for _, c := range sess.Comments {
+		if _, ok := diffs[c.FilePath]; ok {
+			continue
+		}
+		if fd := sessionDiff(runner, sess, c.FilePath); fd != nil {
+			diffs[c.FilePath] = fd
+		}
+	}
+	return diffs
+}
+
+// sessionDiff returns the diff of path in a saved review, as it is now, or nil
+// if it can't be loaded.
+func sessionDiff(runner *git.Runner, sess *session.Session, path string) *git.FileDiff {
+	var fd *git.FileDiff
+	switch {
+	case sess.Uncommitted:
+		fd, _ = runner.UncommittedFileDiff(path)
+	case sess.Base != "":
+		fd, _ = runner.FileDiff(sess.Base, path)
+	}
+	return fd
+}
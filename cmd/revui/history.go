@@ -0,0 +1,160 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/output"
+	"github.com/deparker/revui/internal/session"
+)
+
+var historyCommand = &command{
+	name:    "history",
+	args:    "[open <n> | send <n>]",
+	summary: "List the reviews delivered from this repository, or open or send one again",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		target := fs.String("output", "", "with send, the target to send to, by its label or a unique part of it; the one it went to by default")
+		dryRun := fs.Bool("dry-run", false, "with send, print the API requests sending to a webhook or hosting service would make, without sending them")
+
+		return func(args []string) error {
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+			gitDir, err := runner.GitDir()
+			if err != nil {
+				return err
+			}
+			history, err := session.LoadHistory(session.HistoryDir(gitDir))
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				listHistory(os.Stdout, history, time.Now())
+				return nil
+			}
+
+			if len(args) != 2 {
+				return fmt.Errorf("history %s takes the number of a delivery, as listed by revui history", args[0])
+			}
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 || n > len(history) {
+				return fmt.Errorf("no delivery %s; revui history lists %d", args[1], len(history))
+			}
+			d := history[n-1]
+			switch args[0] {
+			case "open":
+				editor := cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi")
+				cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", d.MarkdownPath())
+				cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+				return cmd.Run()
+			case "send":
+				if *dryRun {
+					defer startDryRun()()
+				}
+				result, err := redeliver(runner, d, cmp.Or(*target, d.Target))
+				if err != nil {
+					return err
+				}
+				fmt.Println(result)
+				return nil
+			}
+			return fmt.Errorf("unknown history subcommand %q: use open or send", args[0])
+		}
+	},
+}
+
+// listHistory writes a numbered table of the deliveries, most recent first.
+func listHistory(w io.Writer, history []*session.Delivery, now time.Time) {
+	if len(history) == 0 {
+		fmt.Fprintln(w, "No reviews have been delivered from this repository.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tWHEN\tBRANCH\tTARGET\tSIZE\tRESULT")
+	for i, d := range history {
+		result := "sent"
+		if d.Failed() {
+			result = "failed: " + d.Error
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d bytes\t%s\n", i+1, age(now.Sub(d.At)), d.Review.Branch, d.Target, d.Bytes, result)
+	}
+	tw.Flush()
+}
+
+// recordDelivery adds a delivery of review to the repository's history. The
+// history is a convenience, and the TUI owns the terminal while deliveries
+// are made, so failing to keep it goes unreported. Dry runs aren't kept.
+func recordDelivery(runner *git.Runner, target output.OutputTarget, review output.Review, result string, err error) {
+	if output.DryRun() {
+		return
+	}
+	gitDir, gerr := runner.GitDir()
+	if gerr != nil {
+		return
+	}
+	d := &session.Delivery{Target: target.Label, File: target.Path, Result: result, At: time.Now(), Review: review}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	session.SaveDelivery(session.HistoryDir(gitDir), d)
+}
+
+// redeliver sends d's review again to the target named name, recording the
+// delivery in the history.
+func redeliver(runner *git.Runner, d *session.Delivery, name string) (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	output.SetClaudePrompt(cfg.ClaudePrompt)
+	output.SetClaudeSubmit(cfg.ClaudeSubmit)
+	output.SetTempDir(cfg.TempDir)
+	targets := slices.Concat(output.DetectTargets(os.Getenv("TMUX"), os.Getenv("TMUX_PANE")), configuredTargets(cfg, runner))
+	target, err := findTarget(targets, name)
+	if err != nil {
+		return "", err
+	}
+	if target.Kind == output.TargetFile && name == d.Target {
+		target.Path = d.File
+	}
+	result, err := output.Deliver(target, d.Review)
+	recordDelivery(runner, target, d.Review, result, err)
+	return result, err
+}
+
+// findTarget returns the target labelled name or, failing that, the only one
+// whose label contains it, ignoring case.
+func findTarget(targets []output.OutputTarget, name string) (output.OutputTarget, error) {
+	if i := slices.IndexFunc(targets, func(t output.OutputTarget) bool { return t.Label == name }); i >= 0 {
+		return targets[i], nil
+	}
+	var matches []output.OutputTarget
+	for _, t := range targets {
+		if strings.Contains(strings.ToLower(t.Label), strings.ToLower(name)) {
+			matches = append(matches, t)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	labels := make([]string, len(targets))
+	for i, t := range targets {
+		labels[i] = t.Label
+	}
+	if len(matches) == 0 {
+		return output.OutputTarget{}, fmt.Errorf("no output target %q; the targets are: %s", name, strings.Join(labels, ", "))
+	}
+	return output.OutputTarget{}, errors.New("more than one output target matches " + strconv.Quote(name) + "; give more of its label")
+}
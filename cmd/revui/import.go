@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/session"
+)
+
+var importCommand = &command{
+	name:    "import",
+	args:    "<file.json>",
+	summary: "Merge comments from an exported JSON review into the current branch's session",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		return func(args []string) error {
+			if len(args) != 1 {
+				return errors.New("import takes exactly one file argument")
+			}
+
+			imported, err := session.Load(args[0])
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", args[0], err)
+			}
+
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+			path, branch, err := sessionPath(runner)
+			if err != nil {
+				return err
+			}
+			err = session.Update(path, func(sess *session.Session) (*session.Session, error) {
+				if sess == nil {
+					sess = &session.Session{
+						Branch:      branch,
+						Base:        imported.Base,
+						Uncommitted: imported.Uncommitted,
+					}
+				}
+
+				// Merge through a Store so imported comments replace ones on the same line.
+				store := comment.NewStore()
+				for _, c := range sess.Comments {
+					store.Add(c)
+				}
+				for _, c := range imported.Comments {
+					store.Add(c)
+				}
+				sess.Comments = store.All()
+				return sess, nil
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Imported %d comments into the review session for %s\n", len(imported.Comments), branch)
+			return nil
+		}
+	},
+}
@@ -1,64 +1,437 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/config"
 	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/output"
+	"github.com/deparker/revui/internal/session"
+	"github.com/deparker/revui/internal/spell"
 	"github.com/deparker/revui/internal/ui"
 )
 
+// command is a revui subcommand. setup registers the command's flags on fs and
+// returns the function that runs it with the remaining positional arguments.
+type command struct {
+	name    string
+	args    string // positional argument synopsis for usage output
+	summary string
+	setup   func(fs *flag.FlagSet) func(args []string) error
+}
+
+// commands lists the subcommands in usage order. The first is the default.
+// It is populated in init because completion refers back to it.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		reviewCommand,
+		resumeCommand,
+		prCommand,
+		sessionsCommand,
+		submitCommand,
+		historyCommand,
+		resendCommand,
+		cleanCommand,
+		exportCommand,
+		importCommand,
+		pagerCommand,
+		authCommand,
+		completionCommand,
+	}
+}
+
+// hiddenCommands are run by name like commands, but left out of usage and
+// completions: tools for working on revui rather than with it.
+var hiddenCommands = []*command{benchCommand}
+
+func findCommand(name string) *command {
+	for _, c := range slices.Concat(commands, hiddenCommands) {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
 func main() {
-	base := flag.String("base", "", "base branch to diff against (auto-detected if not set)")
-	remote := flag.String("remote", "origin", "remote to detect default branch from")
-	flag.Parse()
+	// A leading word that isn't a command name is a positional argument to
+	// the default command (e.g. `revui main feature-x`).
+	cmd, args := commands[0], os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if c := findCommand(args[0]); c != nil {
+			cmd, args = c, args[1:]
+		}
+	}
 
-	dir, err := os.Getwd()
-	if err != nil {
+	fs := newFlagSet(cmd)
+	run := cmd.setup(fs)
+
+	if err := run(parseInterspersed(fs, args)); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+}
+
+// newFlagSet creates the flag set for a command with usage output that lists
+// the command's flags, and for the default command, all other commands.
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet("revui "+cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: revui %s [flags] %s\n\n%s\n", cmd.name, cmd.args, cmd.summary)
+		if cmd == commands[0] {
+			fmt.Fprintln(out)
+			printCommands(out)
+		}
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseInterspersed parses flags that may appear before, between, or after
+// positional arguments, returning the positional arguments in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...)
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func printCommands(out interface{ Write([]byte) (int, error) }) {
+	fmt.Fprintln(out, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-12s %s\n", c.name, c.summary)
+	}
+}
 
+// openRepo returns a runner for the git repository in the working directory,
+// with the configured exclusions.
+func openRepo() (*git.Runner, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
 	runner := &git.Runner{Dir: dir}
 	if !runner.IsGitRepo() {
-		fmt.Fprintln(os.Stderr, "Error: not a git repository")
-		os.Exit(1)
+		return nil, errors.New("not a git repository")
+	}
+	// Config errors are reported when the review starts.
+	if cfg, err := loadConfig(); err == nil {
+		runner.Exclude = cfg.Exclude
+	}
+	return runner, nil
+}
+
+// sessionPath returns the session file for the currently checked-out branch.
+func sessionPath(runner *git.Runner) (string, string, error) {
+	gitDir, err := runner.GitDir()
+	if err != nil {
+		return "", "", err
+	}
+	branch, err := runner.CurrentBranch()
+	if err != nil {
+		return "", "", err
 	}
+	return session.Path(gitDir, branch), branch, nil
+}
 
-	var model ui.RootModel
-	if runner.HasUncommittedChanges() {
-		model = ui.NewRootModelUncommitted(runner, 80, 24)
+// runModel runs the TUI and prints the delivery results to stdout. With a
+// runner, the review session is saved as comments change and on exit, and
+// comments added to it by another process appear in the TUI. A nil runner
+// skips the session.
+func runModel(runner *git.Runner, model ui.RootModel, opts ...tea.ProgramOption) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config: %v\n", err)
+		cfg = &config.Config{}
+	}
+	model.SetAutoAdvance(cfg.AutoAdvance)
+	model.SetFallbackToFile(cfg.FallbackToFile)
+	model.SetFileNumbers(cfg.FileNumbers)
+	model.SetReviewPace(cfg.ReviewPace)
+	model.SetSeeAlso(cfg.SeeAlso)
+	model.SetSummarizeCommand(cfg.SummarizeCommand)
+	output.SetClaudePrompt(cfg.ClaudePrompt)
+	output.SetClaudeSubmit(cfg.ClaudeSubmit)
+	output.SetTempDir(cfg.TempDir)
+	if cfg.KeepTempDays >= 0 {
+		cleanHandoffFiles(keepTempDays(cfg), false)
+	}
+	model.SetDictionary(loadDictionary(cfg.Dictionary))
+	if cfg.SnippetContext != nil {
+		model.SetSnippetContext(*cfg.SnippetContext)
+	}
+	if cfg.FileSort != "" {
+		if err := model.SetFileSort(cfg.FileSort); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring config: %v\n", err)
+		}
+	}
+	if runner != nil {
+		model.SetAuthor(runner.UserName())
+		if dir, err := runner.TopLevel(); err == nil {
+			model.SetWorkDir(dir)
+		}
 	} else {
-		// Auto-detect base branch if not explicitly provided
-		baseBranch := *base
-		if baseBranch == "" {
-			baseBranch = runner.DefaultBranch(*remote)
+		// Reviews outside a repository still pick up the global identity.
+		model.SetAuthor((&git.Runner{}).UserName())
+	}
+	targets := configuredTargets(cfg, runner)
+	model.SetExtraTargets(targets)
+	if slices.ContainsFunc(targets, output.OutputTarget.Hosted) && runner != nil {
+		// Mentions only mean something in comments posted to a hosting service.
+		if authors, err := runner.RecentAuthors(50); err == nil {
+			model.SetMentions(authors)
 		}
+	}
+	state := loadState()
+	model.SetLastFileDir(state.LastFileDir)
 
-		if !runner.BranchExists(baseBranch) {
-			fmt.Fprintf(os.Stderr, "Error: base branch %q does not exist. Use --base to specify.\n", baseBranch)
-			os.Exit(1)
+	var syncer *sessionSync
+	if runner != nil {
+		if syncer, err = newModelSync(runner, model); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: review session won't be saved: %v\n", err)
 		}
-
-		model = ui.NewRootModel(runner, baseBranch, 80, 24)
+	}
+	if syncer != nil {
+		// Only one instance may save a branch's session; a second one is
+		// opened read-only rather than overwriting the first's autosaves.
+		lock, err := session.Acquire(syncer.path)
+		var locked *session.LockedError
+		switch {
+		case errors.As(err, &locked):
+			fmt.Fprintf(os.Stderr, "Warning: %v; opening read-only\n", err)
+			model.SetReadOnly(true)
+			model.SetFlash(fmt.Sprintf("Another revui (pid %d) is editing this review; opened read-only", locked.PID))
+			syncer = nil
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: review session won't be saved: %v\n", err)
+			syncer = nil
+		default:
+			defer lock.Release()
+		}
+	}
+	if syncer != nil {
+		// A review whose run ended without it being sent, such as when the
+		// terminal closed, carries on rather than being replaced.
+		if restored, verdicts := syncer.restore(model.Comments()); len(restored) > 0 {
+			model.LoadComments(restored)
+			if len(model.Verdicts()) == 0 {
+				model.LoadVerdicts(verdicts)
+			}
+			model.SetFlash(fmt.Sprintf("Restored %d unsent comment(s) from the last review of this branch", len(restored)))
+		}
+		model.SetOnCommentsChanged(syncer.edit)
+		model.SetOnVerdictsChanged(syncer.judge)
+	}
+	if runner != nil {
+		model.SetOnUnsent(func(target output.OutputTarget, review output.Review, err error) error {
+			return keepUnsent(runner, target, review, err)
+		})
+		model.SetOnDelivered(func(target output.OutputTarget, review output.Review, result string, err error) {
+			recordDelivery(runner, target, review, result, err)
+		})
 	}
 
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	// Panics are recovered by the guard rather than Bubble Tea, so that the
+	// report goes to stderr with where the comments are saved.
+	guard := &crashGuard{}
+	if syncer != nil {
+		guard.sessionPath = syncer.path
+	}
+	p := tea.NewProgram(guardedModel{model, guard}, append([]tea.ProgramOption{tea.WithAltScreen(), tea.WithoutCatchPanics()}, opts...)...)
+	guard.program = p
+	defer guard.recover()
+	done := make(chan struct{})
+	if syncer != nil {
+		go func() {
+			defer guard.recover()
+			syncer.watch(func(added []comment.Comment) {
+				p.Send(ui.CommentsAddedMsg{Comments: added})
+			}, done)
+		}()
+	}
+	if i := slices.IndexFunc(targets, output.OutputTarget.Hosted); i >= 0 && runner != nil {
+		go func() {
+			defer guard.recover()
+			branch, _ := runner.CurrentBranch()
+			loadThreads(p, targets[i], cmp.Or(model.HostBranch(), branch), model.PullRequest())
+		}()
+	}
 	finalModel, err := p.Run()
+	close(done)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	rm, ok := finalModel.(ui.RootModel)
+	guarded, ok := finalModel.(guardedModel)
 	if !ok {
-		fmt.Fprintf(os.Stderr, "Error: unexpected model type\n")
-		os.Exit(1)
+		return errors.New("unexpected model type")
+	}
+	rm, ok := guarded.Model.(ui.RootModel)
+	if !ok {
+		return errors.New("unexpected model type")
+	}
+	if syncer != nil {
+		syncer.template.Base = rm.Base()
+		syncer.template.Uncommitted = rm.Uncommitted()
+		syncer.template.Verdicts = rm.Verdicts()
+		syncer.template.File, syncer.template.Line = rm.Position()
+		syncer.template.Marks = nil
+		for name, mark := range rm.Marks() {
+			if syncer.template.Marks == nil {
+				syncer.template.Marks = make(map[string]session.Mark)
+			}
+			syncer.template.Marks[name] = session.Mark(mark)
+		}
+		if rm.DeliveryResult() != "" {
+			syncer.template.Delivered = true
+		}
+		if err := syncer.save(rm.Comments()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not save review session: %v\n", err)
+		}
+	}
+	if dir := rm.LastFileDir(); dir != state.LastFileDir {
+		state.LastFileDir = dir
+		saveState(state)
+	}
+	// Deliveries are reported even after returning to the review and quitting,
+	// so scripts can pick up the written file or target.
+	if result := rm.DeliveryResult(); result != "" {
+		fmt.Println(result)
+	}
+	return nil
+}
+
+// loadThreads shows the comments already on the pull request under review,
+// number or else branch's, from the hosting service target posts to, in the
+// running review.
+func loadThreads(p *tea.Program, target output.OutputTarget, branch string, number int) {
+	comments, err := output.FetchThreads(target, branch, number)
+	p.Send(ui.ThreadsLoadedMsg{Source: target.Label, Comments: comments, Err: err})
+}
+
+// startDryRun makes deliveries collect the API requests they would send
+// instead of sending them. The TUI owns the terminal meanwhile, so the
+// returned function prints them once it has exited.
+func startDryRun() func() {
+	var log bytes.Buffer
+	output.SetDryRun(&log)
+	return func() {
+		os.Stdout.Write(log.Bytes())
+	}
+}
+
+// newModelSync tracks the session file for the model's branch.
+func newModelSync(runner *git.Runner, model ui.RootModel) (*sessionSync, error) {
+	path, branch, err := sessionPath(runner)
+	if err != nil {
+		return nil, err
+	}
+	template := session.Session{
+		Branch:      branch,
+		Base:        model.Base(),
+		Uncommitted: model.Uncommitted(),
+	}
+	if !template.Uncommitted {
+		// Recorded so resuming can show what the author changed since.
+		template.Head, _ = runner.HeadCommit()
+	}
+	if sess, err := session.Load(path); err == nil {
+		template.Delivered = sess.Delivered
+	}
+	return newSessionSync(path, template), nil
+}
+
+// configuredTargets returns the output targets set up in the config, for the
+// repository of runner, which may be nil.
+func configuredTargets(cfg *config.Config, runner *git.Runner) []output.OutputTarget {
+	changeID, originURL := "", ""
+	if runner != nil {
+		changeID, _ = runner.ChangeID()
+		originURL, _ = runner.RemoteURL("origin")
+	}
+	return slices.Concat(
+		output.WebhookTargets(cfg.Webhooks),
+		output.SlackTargets(cfg.Slack),
+		output.BitbucketTargets(cfg.Bitbucket),
+		output.GerritTargets(cfg.Gerrit, changeID),
+		output.AzureDevOpsTargets(cfg.AzureDevOps, originURL),
+		output.GitHubTargets(cfg.GitHub, originURL),
+		output.GitLabTargets(cfg.GitLab, originURL),
+	)
+}
+
+func loadConfig() (*config.Config, error) {
+	path, err := config.Path()
+	if err != nil {
+		return nil, err
+	}
+	return config.Load(path)
+}
+
+// loadDictionary loads the word list comments are spell checked against:
+// path, or the system's when path is empty. Spell checking is an aid, so a
+// system without a word list just goes without, and only a configured one
+// that can't be read is warned about.
+func loadDictionary(path string) *spell.Dictionary {
+	switch path {
+	case "none":
+		return nil
+	case "":
+		d, _ := spell.LoadSystem()
+		return d
+	}
+	d, err := spell.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: spell checking is off: %v\n", err)
+		return nil
+	}
+	return d
+}
+
+// loadState reads what revui remembers between runs. State is a convenience,
+// so a missing or unreadable file just starts fresh.
+func loadState() *config.State {
+	path, err := config.StatePath()
+	if err != nil {
+		return &config.State{}
 	}
-	if rm.Finished() && rm.DeliveryResult() != "" {
-		fmt.Println(rm.DeliveryResult())
+	state, err := config.LoadState(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring saved state: %v\n", err)
+		return &config.State{}
+	}
+	return state
+}
+
+func saveState(state *config.State) {
+	path, err := config.StatePath()
+	if err == nil {
+		err = state.Save(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save state: %v\n", err)
 	}
 }
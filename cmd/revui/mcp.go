@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/mcp"
+	"github.com/deparker/revui/internal/session"
+)
+
+// version is reported to MCP clients.
+const version = "dev"
+
+// mcpBackend exposes a repository's diff and its branch's review session to
+// the MCP server. Comments go through the session file, where a running TUI
+// picks them up.
+type mcpBackend struct {
+	runner      *git.Runner
+	base        string
+	uncommitted bool
+	worktree    bool // branch diff includes uncommitted changes
+	path        string
+	branch      string
+}
+
+func (b *mcpBackend) ChangedFiles() ([]git.ChangedFile, error) {
+	switch {
+	case b.uncommitted:
+		return b.runner.UncommittedFiles()
+	case b.worktree:
+		return b.runner.WorktreeChangedFiles(b.base)
+	default:
+		return b.runner.ChangedFiles(b.base)
+	}
+}
+
+func (b *mcpBackend) FileDiff(path string) (*git.FileDiff, error) {
+	switch {
+	case b.uncommitted:
+		return b.runner.UncommittedFileDiff(path)
+	case b.worktree:
+		return b.runner.WorktreeFileDiff(b.base, path)
+	default:
+		return b.runner.FileDiff(b.base, path)
+	}
+}
+
+func (b *mcpBackend) Comments() ([]comment.Comment, error) {
+	sess, err := session.Load(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sess.Comments, nil
+}
+
+// AddComment adds c to the session file. The update is locked against the
+// TUI's autosaves, which would otherwise overwrite it.
+func (b *mcpBackend) AddComment(c comment.Comment) error {
+	return session.Update(b.path, func(sess *session.Session) (*session.Session, error) {
+		if sess == nil {
+			sess = &session.Session{Branch: b.branch, Base: b.base, Uncommitted: b.uncommitted}
+		}
+		store := comment.NewStore()
+		for _, existing := range sess.Comments {
+			store.Add(existing)
+		}
+		store.Add(c)
+		sess.Comments = store.All()
+		return sess, nil
+	})
+}
+
+// serveMCP serves the review over MCP on stdin/stdout. The diff is chosen the
+// same way the TUI would choose it for the same flags: uncommitted changes
+// when asked for, or when there are any and baseGiven is unset. It doesn't
+// take the session's lock, which a TUI reviewing alongside it holds; each
+// comment is saved under session.Update instead.
+func serveMCP(runner *git.Runner, base string, baseExists, dirty, uncommitted, baseGiven bool) error {
+	path, branch, err := sessionPath(runner)
+	if err != nil {
+		return err
+	}
+	b := &mcpBackend{runner: runner, base: base, path: path, branch: branch}
+	switch {
+	case runner.Head == "" && dirty:
+		b.worktree = true
+	case runner.Head == "" && (uncommitted || !baseGiven && runner.HasUncommittedChanges()):
+		b.uncommitted = true
+	}
+	if !b.uncommitted && !baseExists {
+		return fmt.Errorf("base branch %q does not exist. Use --base to specify", base)
+	}
+	return mcp.NewServer(b, version).Serve(os.Stdin, os.Stdout)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/ui"
+)
+
+var pagerCommand = &command{
+	name:    "pager",
+	summary: "Read a diff from stdin and browse it read-only (for git's pager.diff / pager.show)",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		return func(args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments: %v", args)
+			}
+
+			raw, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("reading stdin: %w", err)
+			}
+			diffs, err := git.ParseDiff(git.StripColor(string(raw)))
+			if err != nil {
+				return err
+			}
+			// Nothing to review (e.g. `git show` of a merge, or git sending
+			// other output through the pager): pass it through unchanged.
+			if len(diffs) == 0 {
+				_, err := os.Stdout.Write(raw)
+				return err
+			}
+
+			// stdin was the diff, so keys are read from the terminal instead.
+			tty, err := os.Open("/dev/tty")
+			if err != nil {
+				return errors.New("pager needs a terminal to read keys from")
+			}
+			defer tty.Close()
+
+			source := &git.Static{Name: "git diff", Files: diffs}
+			return runReview(nil, ui.NewRootModel(source, "", 80, 24), true, tea.WithInput(tty))
+		}
+	},
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/output"
+	"github.com/deparker/revui/internal/ui"
+)
+
+var prCommand = &command{
+	name:    "pr",
+	args:    "<number>",
+	summary: "Fetch a pull request and review it against its base, without checking it out",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		base := fs.String("base", "", "base branch to diff against (the pull request's, or the remote's default branch, if not set)")
+		remote := fs.String("remote", "origin", "remote the pull request is on")
+		readOnly := fs.Bool("read-only", false, "browse the diff without commenting; no review session is saved")
+		dryRun := fs.Bool("dry-run", false, "print the API requests that sending the review to a webhook or hosting service would make, without sending them")
+
+		return func(args []string) error {
+			if len(args) != 1 {
+				return errors.New("pr takes the number of a pull request")
+			}
+			number, err := strconv.Atoi(strings.TrimLeft(args[0], "#!"))
+			if err != nil || number < 1 {
+				return fmt.Errorf("%q is not a pull request number", args[0])
+			}
+			if *dryRun {
+				defer startDryRun()()
+			}
+
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+			cleanup, err := runner.FetchPullRequest(*remote, number)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			// The hosting service names the branches, so that the session
+			// is the branch's and comments can be posted to the pull
+			// request. Without it, the remote's default branch is the base.
+			head, prBase, err := pullRequestBranches(runner, *remote, number)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v; reviewing against the default branch\n", err)
+			}
+			if head != "" {
+				runner.HeadName = head
+			}
+			baseBranch := *base
+			if baseBranch == "" {
+				// Fetch the base too, so that it is as fresh as the pull
+				// request rather than as of the last fetch.
+				ref, cleanupBase, err := runner.FetchBranch(*remote, cmp.Or(prBase, runner.DefaultBranch(*remote)))
+				if err != nil {
+					return fmt.Errorf("%w. Use --base to specify the base", err)
+				}
+				defer cleanupBase()
+				baseBranch = ref
+			}
+			if !runner.BranchExists(baseBranch) {
+				return fmt.Errorf("base ref %q does not exist. Use --base to specify", baseBranch)
+			}
+			// Review what the pull request adds, not what the base has
+			// gained since it forked.
+			if baseBranch, err = runner.MergeBase(baseBranch, runner.Head); err != nil {
+				return err
+			}
+			model := ui.NewRootModel(runner, baseBranch, 80, 24)
+			// The review is posted to this pull request rather than one
+			// looked up by branch, when it is on the remote posted to.
+			if sameRemote(runner, *remote, "origin") {
+				model.SetPullRequest(number)
+			}
+			return runReview(runner, model, *readOnly)
+		}
+	},
+}
+
+// pullRequestBranches asks the hosting service remote is on for the branch
+// pull request number is from and the one it is to be merged into. Only
+// GitHub and GitLab remotes are asked; for others both are empty.
+func pullRequestBranches(runner *git.Runner, remote string, number int) (head, base string, err error) {
+	remoteURL, err := runner.RemoteURL(remote)
+	if err != nil {
+		return "", "", nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", "", err
+	}
+	if targets := output.GitHubTargets(cfg.GitHub, remoteURL); targets != nil {
+		return targets[0].GitHub.PullRequestBranches(number)
+	}
+	if targets := output.GitLabTargets(cfg.GitLab, remoteURL); targets != nil {
+		return targets[0].GitLab.MergeRequestBranches(number)
+	}
+	return "", "", nil
+}
+
+// sameRemote reports whether remotes a and b have the same URL.
+func sameRemote(runner *git.Runner, a, b string) bool {
+	urlA, errA := runner.RemoteURL(a)
+	urlB, errB := runner.RemoteURL(b)
+	return errA == nil && errB == nil && urlA == urlB
+}
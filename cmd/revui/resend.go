@@ -0,0 +1,114 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/output"
+	"github.com/deparker/revui/internal/session"
+)
+
+var resendCommand = &command{
+	name:    "resend",
+	summary: "Deliver the current branch's review again without reopening it",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		target := fs.String("output", "", "the target to send to, by its label or a unique part of it; the one the review last went to by default")
+		dryRun := fs.Bool("dry-run", false, "print the API requests sending to a webhook or hosting service would make, without sending them")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments: %v", args)
+			}
+			if *dryRun {
+				defer startDryRun()()
+			}
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+			d, err := latestReview(runner)
+			if err != nil {
+				return err
+			}
+			name := cmp.Or(*target, d.Target)
+			if name == "" {
+				return fmt.Errorf("the review of %s hasn't been delivered before; choose a target with --output", d.Review.Branch)
+			}
+			result, err := redeliver(runner, d, name)
+			if err != nil {
+				return err
+			}
+			fmt.Println(result)
+			return nil
+		}
+	},
+}
+
+// latestReview returns the current branch's last delivery, or its saved
+// session as a delivery yet to be made when it changed since then or was
+// never delivered, in which case the delivery has the last one's target.
+// The session's review carries the diffs its comments are on as they are
+// now, for placing them on a pull request's diff.
+func latestReview(runner *git.Runner) (*session.Delivery, error) {
+	path, branch, err := sessionPath(runner)
+	if err != nil {
+		return nil, err
+	}
+	gitDir, err := runner.GitDir()
+	if err != nil {
+		return nil, err
+	}
+	history, err := session.LoadHistory(session.HistoryDir(gitDir))
+	if err != nil {
+		return nil, err
+	}
+	sess, err := session.Load(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	last, edited, err := reviewToSend(branch, history, sess)
+	if err != nil || edited == nil {
+		return last, err
+	}
+	d := &session.Delivery{
+		At: time.Now(),
+		Review: output.Review{
+			Branch:   branch,
+			Base:     edited.Base,
+			Markdown: formatSession(runner, edited),
+			Comments: edited.Comments,
+			Diffs:    sessionDiffs(runner, edited),
+		},
+	}
+	if last != nil {
+		d.Target, d.File = last.Target, last.File
+	}
+	return d, nil
+}
+
+// reviewToSend picks which of branch's reviews to send again, from the
+// deliveries in history, newest first, and its saved session, which may be
+// nil. It returns the last delivery, and the session too when it has
+// comments that weren't delivered: it was never delivered, or was edited
+// since the last delivery, whether or not that one failed.
+func reviewToSend(branch string, history []*session.Delivery, sess *session.Session) (last *session.Delivery, edited *session.Session, err error) {
+	for _, d := range history {
+		if d.Review.Branch == branch {
+			last = d
+			break
+		}
+	}
+	if sess == nil || len(sess.Comments) == 0 || last != nil && (sess.Delivered || !sess.UpdatedAt.After(last.At)) {
+		if last == nil {
+			return nil, nil, fmt.Errorf("no review of branch %q to send", branch)
+		}
+		return last, nil, nil
+	}
+	return last, sess, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/output"
+	"github.com/deparker/revui/internal/session"
+)
+
+func TestReviewToSend(t *testing.T) {
+	sentAt := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	sent := &session.Delivery{Target: "Slack", At: sentAt, Review: output.Review{Branch: "feature"}}
+	failed := &session.Delivery{Target: "GitHub PR: acme/api", Error: "503", At: sentAt, Review: output.Review{Branch: "feature"}}
+	other := &session.Delivery{Target: "Slack", At: sentAt.Add(time.Hour), Review: output.Review{Branch: "other"}}
+	comments := []comment.Comment{{FilePath: "a.go", StartLine: 1, EndLine: 1, Body: "nit"}}
+	saved := func(updated time.Time, delivered bool) *session.Session {
+		return &session.Session{Branch: "feature", Comments: comments, UpdatedAt: updated, Delivered: delivered}
+	}
+
+	tests := []struct {
+		name       string
+		history    []*session.Delivery
+		sess       *session.Session
+		wantLast   *session.Delivery
+		wantEdited bool
+		wantErr    string
+	}{
+		{name: "no history", wantErr: `no review of branch "feature"`},
+		{name: "another branch's delivery", history: []*session.Delivery{other}, wantErr: `no review of branch "feature"`},
+		{name: "never delivered", sess: saved(sentAt, false), wantEdited: true},
+		{name: "delivered session", history: []*session.Delivery{other, sent}, sess: saved(sentAt.Add(time.Minute), true), wantLast: sent},
+		{name: "session saved before the delivery", history: []*session.Delivery{sent}, sess: saved(sentAt.Add(-time.Minute), false), wantLast: sent},
+		{name: "session without comments", history: []*session.Delivery{sent}, sess: &session.Session{Branch: "feature", UpdatedAt: sentAt.Add(time.Minute)}, wantLast: sent},
+		{name: "edited after the last delivery", history: []*session.Delivery{sent}, sess: saved(sentAt.Add(time.Minute), false), wantLast: sent, wantEdited: true},
+		{name: "last delivery failed", history: []*session.Delivery{failed}, sess: saved(sentAt.Add(-time.Minute), false), wantLast: failed},
+		{name: "edited after a failed delivery", history: []*session.Delivery{failed}, sess: saved(sentAt.Add(time.Minute), false), wantLast: failed, wantEdited: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last, edited, err := reviewToSend("feature", tt.history, tt.sess)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if last != tt.wantLast {
+				t.Errorf("last delivery = %+v, want %+v", last, tt.wantLast)
+			}
+			if (edited != nil) != tt.wantEdited {
+				t.Errorf("edited session = %+v, want one: %v", edited, tt.wantEdited)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/session"
+	"github.com/deparker/revui/internal/ui"
+)
+
+var resumeCommand = &command{
+	name:    "resume",
+	summary: "Resume the saved review session for the current branch",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		dryRun := fs.Bool("dry-run", false, "print the API requests that sending the review to a webhook or hosting service would make, without sending them")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments: %v", args)
+			}
+
+			if *dryRun {
+				defer startDryRun()()
+			}
+
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+			return resumeSession(runner)
+		}
+	},
+}
+
+// resumeSession reopens the saved review of the branch runner reviews.
+func resumeSession(runner *git.Runner) error {
+	path, branch, err := sessionPath(runner)
+	if err != nil {
+		return err
+	}
+	sess, err := session.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no saved review session for branch %q", branch)
+	}
+	if err != nil {
+		return err
+	}
+
+	var model ui.RootModel
+	if sess.Uncommitted {
+		model = ui.NewRootModelUncommitted(runner, 80, 24)
+		if sess.Base != "" && runner.BranchExists(sess.Base) {
+			model.SetBase(sess.Base)
+		}
+	} else {
+		if !runner.BranchExists(sess.Base) {
+			return fmt.Errorf("base branch %q from saved session no longer exists", sess.Base)
+		}
+		model = ui.NewRootModel(runner, sess.Base, 80, 24)
+	}
+	model.LoadComments(sess.Comments)
+	model.LoadVerdicts(sess.Verdicts)
+	marks := make(map[string]ui.Mark, len(sess.Marks))
+	for name, mark := range sess.Marks {
+		marks[name] = ui.Mark(mark)
+	}
+	model.LoadMarks(marks)
+	model.SetPosition(sess.File, sess.Line)
+	markRevisions(runner, &model, sess)
+
+	return runModel(runner, model)
+}
+
+// markRevisions flags the hunks that changed since the session's review of a
+// branch, so a re-review can focus on them.
+func markRevisions(runner *git.Runner, model *ui.RootModel, sess *session.Session) {
+	if sess.Uncommitted || sess.Head == "" {
+		return
+	}
+	head, err := runner.HeadCommit()
+	if err != nil || head == sess.Head {
+		return
+	}
+	revised, err := runner.Interdiff(sess.Head)
+	if err != nil {
+		// The reviewed commit may be gone after a force-push and gc.
+		fmt.Fprintf(os.Stderr, "Warning: can't show changes since the last review: %v\n", err)
+		return
+	}
+	model.SetRevisions(revised)
+	model.SetFlash(fmt.Sprintf("%d file(s) changed since your last review; R jumps to them", len(revised)))
+}
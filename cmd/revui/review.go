@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/ui"
+)
+
+var reviewCommand = &command{
+	name:    "review",
+	args:    "[<base> [<head>] | <file.patch>]",
+	summary: "Review the current branch or uncommitted changes (default)",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		base := fs.String("base", "", "base branch to diff against (auto-detected if not set)")
+		commitRange := fs.String("range", "", "review a commit range such as abc123..def456, or base...head from where head forked")
+		remote := fs.String("remote", "origin", "remote to detect default branch from")
+		remoteBranch := fs.String("remote-branch", "", "fetch and review a remote branch such as origin/feature-x, without checking it out")
+		dirty := fs.Bool("dirty", false, "include uncommitted working tree changes in the branch diff")
+		uncommitted := fs.Bool("uncommitted", false, "review uncommitted changes against HEAD (the default when the working tree is dirty and no base is given)")
+		fs.BoolVar(uncommitted, "u", false, "shorthand for --uncommitted")
+		dirs := fs.Bool("dirs", false, "compare two directories given as <old> <new> instead of git refs")
+		readOnly := fs.Bool("read-only", false, "browse the diff without commenting; no review session is saved")
+		dryRun := fs.Bool("dry-run", false, "print the API requests that sending the review to a webhook or hosting service would make, without sending them")
+		resume := fs.Bool("resume", false, "reopen the branch's saved review where it was left, like `revui resume`")
+		serve := fs.Bool("mcp", false, "serve the review to an AI agent over MCP on stdio instead of opening the TUI")
+
+		return func(args []string) error {
+			if *dryRun {
+				defer startDryRun()()
+			}
+			if *resume {
+				if len(args) > 0 || *base != "" || *commitRange != "" || *remoteBranch != "" || *dirty || *uncommitted || *dirs || *serve {
+					return errors.New("--resume reopens the saved review as it was and takes no refs or other modes")
+				}
+				runner, err := openRepo()
+				if err != nil {
+					return err
+				}
+				return resumeSession(runner)
+			}
+			if *dirs {
+				return reviewDirs(args, *readOnly)
+			}
+			if len(args) == 1 {
+				if fi, err := os.Stat(args[0]); err == nil && fi.Mode().IsRegular() {
+					return reviewPatch(args[0], *readOnly)
+				}
+			}
+			if len(args) > 2 {
+				return fmt.Errorf("too many arguments: %v (want at most <base> <head>)", args)
+			}
+			if len(args) > 0 && *base != "" {
+				return errors.New("give the base ref either positionally or with --base, not both")
+			}
+			if *uncommitted && (*dirty || len(args) == 2) {
+				return errors.New("--uncommitted cannot be combined with --dirty or a head ref")
+			}
+			if *remoteBranch != "" && (*dirty || *uncommitted || len(args) == 2) {
+				return errors.New("--remote-branch cannot be combined with --dirty, --uncommitted, or a head ref")
+			}
+			if *commitRange != "" && (len(args) > 0 || *base != "" || *remoteBranch != "" || *dirty || *uncommitted) {
+				return errors.New("--range names both refs and cannot be combined with other refs, --remote-branch, --dirty, or --uncommitted")
+			}
+
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+
+			// A dirty working tree is reviewed by default, unless the
+			// refs to review were asked for.
+			baseGiven := *base != "" || len(args) > 0 || *commitRange != "" || *remoteBranch != ""
+
+			// Positional refs mirror `git diff <base> [<head>]`.
+			baseBranch := *base
+			if len(args) > 0 {
+				baseBranch = args[0]
+			}
+			if len(args) == 2 {
+				if *dirty {
+					return errors.New("--dirty compares against the working tree and cannot be combined with a head ref")
+				}
+				if !runner.BranchExists(args[1]) {
+					return fmt.Errorf("head ref %q does not exist", args[1])
+				}
+				runner.Head = args[1]
+			}
+			if *commitRange != "" {
+				if baseBranch, runner.Head, err = runner.ResolveRange(*commitRange); err != nil {
+					return err
+				}
+			}
+			var hostBranch string
+			if *remoteBranch != "" {
+				remoteName, branch, ok := strings.Cut(*remoteBranch, "/")
+				if !ok || remoteName == "" || branch == "" {
+					return fmt.Errorf("remote branch %q should look like origin/feature-x", *remoteBranch)
+				}
+				cleanup, err := runner.FetchHead(remoteName, branch)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				// The review is named origin/feature-x, apart from a local
+				// feature-x, but its pull request is from feature-x.
+				hostBranch = branch
+				// Compare against the remote's copy of the base, which is
+				// as fresh as the fetched branch, when there is one.
+				if baseBranch == "" {
+					if b := remoteName + "/" + runner.DefaultBranch(remoteName); runner.BranchExists(b) {
+						baseBranch = b
+					}
+				}
+			}
+
+			// Auto-detect base branch if not explicitly provided
+			if baseBranch == "" {
+				baseBranch = runner.DefaultBranch(*remote)
+			}
+			if *remoteBranch != "" && runner.BranchExists(baseBranch) {
+				// The base may have moved on since the branch forked; review
+				// only what the branch adds.
+				if baseBranch, err = runner.MergeBase(baseBranch, runner.Head); err != nil {
+					return err
+				}
+			}
+			baseExists := runner.BranchExists(baseBranch)
+
+			if *serve {
+				return serveMCP(runner, baseBranch, baseExists, *dirty, *uncommitted, baseGiven)
+			}
+
+			if runner.Head == "" && runner.IsUnborn() {
+				// A branch without commits has nothing to compare with a
+				// base yet, so review what's in the working tree.
+				if !runner.HasUncommittedChanges() {
+					branch, _ := runner.CurrentBranch()
+					return fmt.Errorf("branch %q has no commits yet and nothing to review", branch)
+				}
+				return runReview(runner, ui.NewRootModelUncommitted(runner, 80, 24), *readOnly)
+			}
+
+			var model ui.RootModel
+			if runner.Head != "" {
+				if !baseExists {
+					return fmt.Errorf("base ref %q does not exist", baseBranch)
+				}
+				model = ui.NewRootModel(runner, baseBranch, 80, 24)
+			} else if *dirty {
+				if !baseExists {
+					return fmt.Errorf("base branch %q does not exist. Use --base to specify", baseBranch)
+				}
+				model = ui.NewRootModelWorktree(runner, baseBranch, 80, 24)
+			} else if *uncommitted && !runner.HasUncommittedChanges() {
+				return errors.New("no uncommitted changes to review")
+			} else if (*uncommitted || !baseGiven) && runner.HasUncommittedChanges() {
+				model = ui.NewRootModelUncommitted(runner, 80, 24)
+				// Allow toggling into branch mode when the base resolves.
+				if baseExists {
+					model.SetBase(baseBranch)
+				}
+			} else {
+				if !baseExists {
+					return fmt.Errorf("base branch %q does not exist. Use --base to specify", baseBranch)
+				}
+				// A clean tree with nothing ahead of base would open on an
+				// empty file list.
+				if files, err := runner.ChangedFiles(baseBranch); err == nil && len(files) == 0 {
+					branch, _ := runner.CurrentBranch()
+					return fmt.Errorf("nothing to review: %s has no changes against %s and the working tree is clean", branch, baseBranch)
+				}
+				model = ui.NewRootModel(runner, baseBranch, 80, 24)
+			}
+
+			model.SetHostBranch(hostBranch)
+			return runReview(runner, model, *readOnly)
+		}
+	},
+}
+
+// runReview runs the model, as a read-only pager if readOnly is set. Browsing
+// never saves a session, so it can't clobber a review in progress.
+func runReview(runner *git.Runner, model ui.RootModel, readOnly bool, opts ...tea.ProgramOption) error {
+	if readOnly {
+		model.SetReadOnly(true)
+		runner = nil
+	}
+	return runModel(runner, model, opts...)
+}
+
+// reviewDirs reviews the recursive diff between two directories. No git
+// repository is needed, so the review isn't saved as a session.
+func reviewDirs(args []string, readOnly bool) error {
+	if len(args) != 2 {
+		return errors.New("--dirs takes two directory arguments: <old> <new>")
+	}
+	for _, dir := range args {
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			return fmt.Errorf("%q is not a directory", dir)
+		}
+	}
+
+	diffs, err := git.DirDiff(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		return fmt.Errorf("no differences between %s and %s", args[0], args[1])
+	}
+	source := &git.Static{Name: args[1], Files: diffs}
+	return runReview(nil, ui.NewRootModel(source, args[0], 80, 24), readOnly)
+}
+
+// reviewPatch reviews the contents of a patch or diff file. Like reviewDirs,
+// it needs no repository and isn't saved as a session.
+func reviewPatch(path string, readOnly bool) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	diffs, err := git.ParseDiff(string(raw))
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(diffs) == 0 {
+		return fmt.Errorf("no diffs found in %s", path)
+	}
+	source := &git.Static{Name: filepath.Base(path), Files: diffs}
+	return runReview(nil, ui.NewRootModel(source, "", 80, 24), readOnly)
+}
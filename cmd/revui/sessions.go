@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/session"
+)
+
+var sessionsCommand = &command{
+	name:    "sessions",
+	args:    "[resume <branch> | delete <branch>...]",
+	summary: "List the saved review sessions, or resume or delete one",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		dryRun := fs.Bool("dry-run", false, "with resume, print the API requests that sending the review to a webhook or hosting service would make, without sending them")
+
+		return func(args []string) error {
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+			gitDir, err := runner.GitDir()
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				current, _ := runner.CurrentBranch()
+				return listSessions(os.Stdout, gitDir, current, time.Now())
+			}
+
+			switch args[0] {
+			case "resume":
+				if len(args) != 2 {
+					return errors.New("sessions resume takes one branch")
+				}
+				if *dryRun {
+					defer startDryRun()()
+				}
+				return resumeBranch(runner, gitDir, args[1])
+			case "delete":
+				if len(args) < 2 {
+					return errors.New("sessions delete takes the branches whose sessions to delete")
+				}
+				for _, branch := range args[1:] {
+					if err := deleteSession(gitDir, branch); err != nil {
+						return err
+					}
+					fmt.Printf("Deleted the review session for %s\n", branch)
+				}
+				return nil
+			}
+			return fmt.Errorf("unknown sessions subcommand %q: use resume or delete", args[0])
+		}
+	},
+}
+
+// listSessions writes a table of the saved sessions, marking the current
+// branch's.
+func listSessions(w io.Writer, gitDir, current string, now time.Time) error {
+	sessions, err := session.List(gitDir)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Fprintln(w, "No saved review sessions.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  BRANCH\tUPDATED\tCOMMENTS\tDELIVERED")
+	for _, s := range sessions {
+		mark := "  "
+		if s.Branch == current {
+			mark = "* "
+		}
+		branch := s.Branch
+		if s.Uncommitted {
+			branch += " (uncommitted)"
+		}
+		delivered := "no"
+		if s.Delivered {
+			delivered = "yes"
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\t%d\t%s\n", mark, branch, age(now.Sub(s.UpdatedAt)), len(s.Comments), delivered)
+	}
+	return tw.Flush()
+}
+
+// age describes how long ago something happened, roughly.
+func age(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	}
+	return plural(int(d/(24*time.Hour)), "day")
+}
+
+// resumeBranch reopens branch's saved review. A branch other than the one
+// checked out is reviewed as a ref, so a review of its uncommitted changes
+// can't be resumed.
+func resumeBranch(runner *git.Runner, gitDir, branch string) error {
+	if current, _ := runner.CurrentBranch(); branch != current {
+		sess, err := session.Load(session.Path(gitDir, branch))
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no saved review session for branch %q", branch)
+		}
+		if err != nil {
+			return err
+		}
+		if sess.Uncommitted {
+			return fmt.Errorf("the session for %s reviews uncommitted changes; check out %s to resume it", branch, branch)
+		}
+		if !runner.BranchExists(branch) {
+			return fmt.Errorf("branch %q no longer exists; remove its session with revui sessions delete %s", branch, branch)
+		}
+		runner.Head = branch
+	}
+	return resumeSession(runner)
+}
+
+// deleteSession deletes branch's saved review, unless a running revui has it
+// open.
+func deleteSession(gitDir, branch string) error {
+	path := session.Path(gitDir, branch)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no saved review session for branch %q", branch)
+	}
+	lock, err := session.Acquire(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return session.Remove(path)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/output"
+	"github.com/deparker/revui/internal/session"
+)
+
+var submitCommand = &command{
+	name:    "submit",
+	summary: "List reviews a hosting service didn't take, or send them again with --retry",
+	setup: func(fs *flag.FlagSet) func(args []string) error {
+		retry := fs.Bool("retry", false, "send the pending reviews again")
+		dryRun := fs.Bool("dry-run", false, "with --retry, print the API requests sending would make, without sending them or forgetting the reviews")
+
+		return func(args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unexpected arguments: %v", args)
+			}
+			runner, err := openRepo()
+			if err != nil {
+				return err
+			}
+			gitDir, err := runner.GitDir()
+			if err != nil {
+				return err
+			}
+			dir := session.OutboxDir(gitDir)
+			pending, err := session.LoadPending(dir)
+			if err != nil {
+				return err
+			}
+			if len(pending) == 0 {
+				fmt.Println("No reviews are waiting to be sent.")
+				return nil
+			}
+			if !*retry {
+				for _, p := range pending {
+					printPending(os.Stdout, p)
+				}
+				fmt.Println("\nRun revui submit --retry to send them.")
+				return nil
+			}
+
+			if *dryRun {
+				defer startDryRun()()
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			targets := configuredTargets(cfg, runner)
+			failed := 0
+			for _, p := range pending {
+				result, err := sendPending(dir, p, targets, *dryRun)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", p.Target, err)
+					failed++
+					continue
+				}
+				fmt.Println(result)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d reviews weren't sent", failed, len(pending))
+			}
+			return nil
+		}
+	},
+}
+
+// printPending describes a review waiting to be sent.
+func printPending(w io.Writer, p *session.Pending) {
+	var verdict string
+	if p.Review.Verdict != output.VerdictNone {
+		verdict = " and a verdict"
+	}
+	fmt.Fprintf(w, "%s, branch %s: %d comments%s\n", p.Target, p.Review.Branch, len(p.Review.Comments), verdict)
+	fmt.Fprintf(w, "  failed %s: %s\n", p.FailedAt.Format(time.DateTime), p.Error)
+}
+
+// sendPending sends p to its target as configured now. Once sent, it is
+// forgotten; if sending fails again, what's still unsent is kept for another
+// try. A dry run changes neither.
+func sendPending(dir string, p *session.Pending, targets []output.OutputTarget, dryRun bool) (string, error) {
+	i := slices.IndexFunc(targets, func(t output.OutputTarget) bool { return t.Label == p.Target })
+	if i < 0 {
+		return "", errors.New("no longer configured")
+	}
+	target := targets[i]
+	if target.Gerrit != nil && p.ChangeID != "" {
+		// The change reviewed, not the one checked out now
+		server := *target.Gerrit
+		server.ChangeID = p.ChangeID
+		target.Gerrit = &server
+	}
+
+	result, err := output.Deliver(target, p.Review)
+	if dryRun {
+		return result, err
+	}
+	var unsent *output.UnsentError
+	if errors.As(err, &unsent) {
+		p.Review, p.Error, p.FailedAt = unsent.Unsent, err.Error(), time.Now()
+		if serr := session.SavePending(dir, p); serr != nil {
+			return "", fmt.Errorf("%w (saving what's left failed: %v)", err, serr)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := session.Remove(p.Path()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: sent, but could not remove %s: %v\n", p.Path(), err)
+	}
+	return result, nil
+}
+
+// keepUnsent saves what a hosting service didn't take from a review, for
+// revui submit --retry.
+func keepUnsent(runner *git.Runner, target output.OutputTarget, review output.Review, err error) error {
+	gitDir, gerr := runner.GitDir()
+	if gerr != nil {
+		return gerr
+	}
+	p := &session.Pending{Target: target.Label, Review: review, Error: err.Error(), FailedAt: time.Now()}
+	if target.Gerrit != nil {
+		p.ChangeID = target.Gerrit.ChangeID
+	}
+	return session.SavePending(session.OutboxDir(gitDir), p)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/session"
+)
+
+// syncInterval is how often the session file is checked for comments added
+// by another process.
+const syncInterval = 2 * time.Second
+
+// commentID identifies a comment's content for detecting ones added elsewhere.
+type commentID struct {
+	file string
+	line int
+	body string
+}
+
+func idOf(c comment.Comment) commentID {
+	return commentID{c.FilePath, c.StartLine, c.Body}
+}
+
+// sessionSync keeps the session file and a running TUI in step. The TUI's
+// comments are saved as they change, and comments another process (such as
+// `revui --mcp`) writes to the file are handed back to the TUI.
+type sessionSync struct {
+	mu       sync.Mutex
+	path     string
+	template session.Session // branch, base, and mode to save with
+	seen     map[commentID]bool
+	// owned is set once this process has written the file or taken its
+	// comments into the review; until then, the file is never removed.
+	owned bool
+}
+
+// newSessionSync starts tracking path. Comments already in a delivered
+// session are treated as seen: a fresh review replaces them, and the
+// history keeps what was sent. An undelivered session's comments are left
+// for restore, so a review cut short isn't lost by starting another.
+func newSessionSync(path string, template session.Session) *sessionSync {
+	s := &sessionSync{path: path, template: template, seen: make(map[commentID]bool)}
+	if sess, err := session.Load(path); err == nil && sess.Delivered {
+		for _, c := range sess.Comments {
+			s.seen[idOf(c)] = true
+		}
+	}
+	return s
+}
+
+// restore returns the saved comments the review doesn't already have, to be
+// merged into it, with the saved verdicts when any are restored, and takes
+// over the file for the review's saves.
+func (s *sessionSync) restore(have []comment.Comment) ([]comment.Comment, map[string]comment.FileVerdict) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range have {
+		s.seen[idOf(c)] = true
+	}
+	if len(have) > 0 {
+		s.owned = true
+	}
+	sess, err := session.Load(s.path)
+	if err != nil {
+		return nil, nil
+	}
+	var restored []comment.Comment
+	for _, c := range sess.Comments {
+		if id := idOf(c); !s.seen[id] {
+			s.seen[id] = true
+			restored = append(restored, c)
+		}
+	}
+	if len(restored) == 0 {
+		return nil, nil
+	}
+	s.owned = true
+	if len(s.template.Verdicts) == 0 {
+		s.template.Verdicts = sess.Verdicts
+	}
+	return restored, sess.Verdicts
+}
+
+// save writes the TUI's comments, keeping any written by another process
+// that haven't been handed to the TUI yet.
+func (s *sessionSync) save(comments []comment.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(func(saved *session.Session) (*session.Session, error) {
+		all := append([]comment.Comment(nil), comments...)
+		if saved != nil {
+			for _, c := range saved.Comments {
+				if !s.seen[idOf(c)] {
+					all = append(all, c)
+				}
+			}
+		}
+		for _, c := range comments {
+			s.seen[idOf(c)] = true
+		}
+
+		if len(all) == 0 && len(s.template.Verdicts) == 0 && len(s.template.Marks) == 0 {
+			return nil, s.remove()
+		}
+		sess := s.template
+		sess.Comments = all
+		return &sess, nil
+	})
+}
+
+// update changes the file under session.Update's lock, so a comment another
+// process adds meanwhile isn't overwritten. The review owns the file once
+// it has written it.
+func (s *sessionSync) update(fn func(*session.Session) (*session.Session, error)) error {
+	wrote := false
+	err := session.Update(s.path, func(saved *session.Session) (*session.Session, error) {
+		sess, err := fn(saved)
+		wrote = sess != nil
+		return sess, err
+	})
+	if err == nil && wrote {
+		s.owned = true
+	}
+	return err
+}
+
+// remove deletes the file, if the review owns it; a session another run
+// left behind is kept.
+func (s *sessionSync) remove() error {
+	if !s.owned {
+		return nil
+	}
+	return session.Remove(s.path)
+}
+
+// edit saves comments the reviewer changed, which makes them undelivered.
+func (s *sessionSync) edit(comments []comment.Comment) error {
+	s.mu.Lock()
+	s.template.Delivered = false
+	s.mu.Unlock()
+	return s.save(comments)
+}
+
+// judge saves the reviewer's verdicts on files, which makes the review
+// undelivered, keeping the comments already saved.
+func (s *sessionSync) judge(verdicts map[string]comment.FileVerdict) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.template.Verdicts = verdicts
+	s.template.Delivered = false
+	return s.update(func(saved *session.Session) (*session.Session, error) {
+		sess := s.template
+		if saved != nil {
+			sess.Comments = saved.Comments
+		}
+		if len(sess.Comments) == 0 && len(verdicts) == 0 && len(sess.Marks) == 0 {
+			return nil, s.remove()
+		}
+		return &sess, nil
+	})
+}
+
+// poll returns comments in the file that the TUI hasn't seen.
+func (s *sessionSync) poll() []comment.Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := session.Load(s.path)
+	if err != nil {
+		return nil
+	}
+	var added []comment.Comment
+	for _, c := range sess.Comments {
+		if id := idOf(c); !s.seen[id] {
+			s.seen[id] = true
+			added = append(added, c)
+		}
+	}
+	return added
+}
+
+// watch polls the session file until done is closed, sending new comments
+// to the TUI.
+func (s *sessionSync) watch(send func([]comment.Comment), done <-chan struct{}) {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if added := s.poll(); len(added) > 0 {
+				send(added)
+			}
+		}
+	}
+}
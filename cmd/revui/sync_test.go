@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/session"
+)
+
+func saveSession(t *testing.T, path string, sess *session.Session) {
+	t.Helper()
+	if err := session.Save(path, sess); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionSyncRestoresUnsentReview(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feature.json")
+	saved := comment.Comment{FilePath: "main.go", StartLine: 3, EndLine: 3, Body: "left from last time"}
+	saveSession(t, path, &session.Session{
+		Branch:   "feature",
+		Comments: []comment.Comment{saved},
+		Verdicts: map[string]comment.FileVerdict{"main.go": comment.FileLGTM},
+	})
+
+	s := newSessionSync(path, session.Session{Branch: "feature"})
+	restored, verdicts := s.restore(nil)
+	if len(restored) != 1 || restored[0] != saved || verdicts["main.go"] != comment.FileLGTM {
+		t.Fatalf("restore() = %+v, %v, want the saved comment and verdict", restored, verdicts)
+	}
+
+	added := comment.Comment{FilePath: "main.go", StartLine: 7, EndLine: 7, Body: "new"}
+	if err := s.save([]comment.Comment{saved, added}); err != nil {
+		t.Fatal(err)
+	}
+	sess, err := session.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sess.Comments) != 2 || len(sess.Verdicts) != 1 {
+		t.Errorf("saved %+v, want both comments and the verdict", sess)
+	}
+}
+
+func TestSessionSyncKeepsSessionItDidNotWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feature.json")
+	saveSession(t, path, &session.Session{
+		Branch:    "feature",
+		Delivered: true,
+		Comments:  []comment.Comment{{FilePath: "main.go", StartLine: 1, EndLine: 1, Body: "sent"}},
+	})
+
+	// A delivered review is replaced by a fresh one, not merged into it
+	s := newSessionSync(path, session.Session{Branch: "feature"})
+	if restored, _ := s.restore(nil); len(restored) != 0 {
+		t.Errorf("restore() = %+v, want nothing from a delivered review", restored)
+	}
+	// Quitting without comments leaves the file alone
+	if err := s.save(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("session written by another run was removed: %v", err)
+	}
+
+	// Once the review has saved, clearing its comments removes the file
+	if err := s.save([]comment.Comment{{FilePath: "main.go", StartLine: 2, EndLine: 2, Body: "new"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.save(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("session should be removed once its comments are deleted, stat: %v", err)
+	}
+}
+
+func TestSessionSyncPoll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feature.json")
+	s := newSessionSync(path, session.Session{Branch: "feature"})
+	mine := comment.Comment{FilePath: "a.go", StartLine: 1, EndLine: 1, Body: "mine"}
+	if err := s.save([]comment.Comment{mine}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process, such as the MCP server, adds a comment to the file
+	theirs := comment.Comment{FilePath: "b.go", StartLine: 2, EndLine: 2, Body: "theirs"}
+	saveSession(t, path, &session.Session{Branch: "feature", Comments: []comment.Comment{mine, theirs}})
+
+	if added := s.poll(); len(added) != 1 || added[0] != theirs {
+		t.Fatalf("poll() = %+v, want only the other process's comment", added)
+	}
+	if added := s.poll(); len(added) != 0 {
+		t.Errorf("second poll() = %+v, want nothing new", added)
+	}
+}
@@ -0,0 +1,314 @@
+// Package apidiff compares the exported API of two versions of a Go package,
+// in the manner of golang.org/x/exp/apidiff but from syntax alone, to report
+// the changes that break the package's importers.
+package apidiff
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Kind is what happened to an exported name.
+type Kind int
+
+const (
+	Removed Kind = iota
+	Changed
+	Added
+)
+
+// Change is a difference in a package's exported API.
+type Change struct {
+	Name     string // as in Server, Server.Run, or Config.Timeout
+	Kind     Kind
+	Old, New string // what was compared, such as a signature, for a change
+	Breaking bool   // importers written against the old API may not compile
+	File     string // the file declaring Name, in the new package unless removed
+	Line     int
+}
+
+// String describes c, as in "Server.Run: changed from func() to func(int)".
+func (c Change) String() string {
+	switch c.Kind {
+	case Removed:
+		return c.Name + ": removed"
+	case Changed:
+		return fmt.Sprintf("%s: changed from %s to %s", c.Name, c.Old, c.New)
+	}
+	if c.Breaking {
+		return c.Name + ": added to an interface, which other packages' types may no longer implement"
+	}
+	return c.Name + ": added"
+}
+
+// feature is an exported name of a package: a declaration, or a field or
+// method of an exported type.
+type feature struct {
+	desc   string // what's compared between versions, such as a signature
+	parent string // the type a field or method is part of
+	iface  bool   // a method an interface requires
+	file   string
+	line   int
+}
+
+// Compare returns the changes between the exported APIs of the packages
+// whose Go files are old and new, by name. Test files are left out, and a
+// main package, which can't be imported, has no API. Breaking changes come
+// first, then compatible ones, each sorted by name.
+func Compare(old, new map[string]string) ([]Change, error) {
+	before, err := exported(old)
+	if err != nil {
+		return nil, err
+	}
+	after, err := exported(new)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for name, f := range before {
+		g, ok := after[name]
+		switch {
+		case !ok:
+			// A removed type's fields and methods went with it
+			if _, kept := after[f.parent]; f.parent == "" || kept {
+				changes = append(changes, Change{Name: name, Kind: Removed, Breaking: true, File: f.file, Line: f.line})
+			}
+		case f.desc != g.desc:
+			changes = append(changes, Change{Name: name, Kind: Changed, Old: f.desc, New: g.desc, Breaking: true, File: g.file, Line: g.line})
+		}
+	}
+	for name, g := range after {
+		if _, ok := before[name]; ok {
+			continue
+		}
+		if g.parent != "" {
+			// Members of a new type, or of one whose kind changed, are
+			// reported with it.
+			if p, ok := before[g.parent]; !ok || p.desc != after[g.parent].desc {
+				continue
+			}
+		}
+		changes = append(changes, Change{Name: name, Kind: Added, Breaking: g.iface, File: g.file, Line: g.line})
+	}
+	slices.SortFunc(changes, func(a, b Change) int {
+		if a.Breaking != b.Breaking {
+			if a.Breaking {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return changes, nil
+}
+
+// exported parses files and returns their package's exported features.
+func exported(files map[string]string) (map[string]feature, error) {
+	features := make(map[string]feature)
+	fset := token.NewFileSet()
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, files[name], parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if f.Name.Name == "main" {
+			return nil, nil
+		}
+		add := func(name string, node ast.Node, ft feature) {
+			pos := fset.Position(node.Pos())
+			ft.file, ft.line = pos.Filename, pos.Line
+			features[name] = ft
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				if d.Recv == nil {
+					add(d.Name.Name, d, feature{desc: funcString(d.Type)})
+					continue
+				}
+				if recv := receiverName(d.Recv); ast.IsExported(recv) {
+					add(recv+"."+d.Name.Name, d, feature{desc: funcString(d.Type), parent: recv})
+				}
+			case *ast.GenDecl:
+				addGenDecl(d, add)
+			}
+		}
+	}
+	return features, nil
+}
+
+// addGenDecl adds the exported types, constants, and variables d declares,
+// and the exported fields and methods of its struct and interface types.
+func addGenDecl(d *ast.GenDecl, add func(string, ast.Node, feature)) {
+	// In a const group, specs without a type repeat the one before
+	var constType string
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			if !s.Name.IsExported() {
+				continue
+			}
+			add(s.Name.Name, s, feature{desc: typeString(s)})
+			addMembers(s.Name.Name, s.Type, add)
+		case *ast.ValueSpec:
+			kind := "var"
+			if d.Tok == token.CONST {
+				kind = "const"
+				if s.Type != nil || s.Values != nil {
+					constType = ""
+					if s.Type != nil {
+						constType = types.ExprString(s.Type)
+					}
+				}
+			}
+			desc := kind
+			if s.Type != nil {
+				desc += " " + types.ExprString(s.Type)
+			} else if d.Tok == token.CONST && constType != "" {
+				desc += " " + constType
+			}
+			for _, n := range s.Names {
+				if n.IsExported() {
+					add(n.Name, n, feature{desc: desc})
+				}
+			}
+		}
+	}
+}
+
+// addMembers adds the exported fields of a struct type, or the methods and
+// embedded interfaces of an interface type, named typeName.
+func addMembers(typeName string, t ast.Expr, add func(string, ast.Node, feature)) {
+	switch t := t.(type) {
+	case *ast.StructType:
+		for _, field := range t.Fields.List {
+			desc := types.ExprString(field.Type)
+			if len(field.Names) == 0 {
+				// An embedded field is named by its type
+				if name := embeddedName(field.Type); ast.IsExported(name) {
+					add(typeName+"."+name, field, feature{desc: "embedded " + desc, parent: typeName})
+				}
+				continue
+			}
+			for _, n := range field.Names {
+				if n.IsExported() {
+					add(typeName+"."+n.Name, n, feature{desc: desc, parent: typeName})
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		for _, m := range t.Methods.List {
+			if ft, ok := m.Type.(*ast.FuncType); ok {
+				for _, n := range m.Names {
+					add(typeName+"."+n.Name, n, feature{desc: funcString(ft), parent: typeName, iface: true})
+				}
+				continue
+			}
+			// An embedded interface or type constraint
+			desc := types.ExprString(m.Type)
+			add(typeName+"."+desc, m, feature{desc: "embedded " + desc, parent: typeName, iface: true})
+		}
+	}
+}
+
+// typeString describes a type declaration: its type parameters, and its
+// underlying type, or just its kind for a struct or interface, whose members
+// are compared one by one.
+func typeString(s *ast.TypeSpec) string {
+	var b strings.Builder
+	if s.TypeParams != nil {
+		b.WriteString("[" + fieldList(s.TypeParams, true) + "] ")
+	}
+	if s.Assign.IsValid() {
+		b.WriteString("= ")
+	}
+	switch s.Type.(type) {
+	case *ast.StructType:
+		b.WriteString("struct")
+	case *ast.InterfaceType:
+		b.WriteString("interface")
+	default:
+		b.WriteString(types.ExprString(s.Type))
+	}
+	return b.String()
+}
+
+// funcString returns a function's signature without its parameters' names,
+// which callers don't depend on.
+func funcString(ft *ast.FuncType) string {
+	s := "func"
+	if ft.TypeParams != nil {
+		s += "[" + fieldList(ft.TypeParams, true) + "]"
+	}
+	s += "(" + fieldList(ft.Params, false) + ")"
+	if ft.Results != nil && len(ft.Results.List) > 0 {
+		results := fieldList(ft.Results, false)
+		if len(ft.Results.List) == 1 && len(ft.Results.List[0].Names) <= 1 {
+			s += " " + results
+		} else {
+			s += " (" + results + ")"
+		}
+	}
+	return s
+}
+
+// fieldList lists the types of fl's fields, one for each name they have,
+// with the names if withNames is set.
+func fieldList(fl *ast.FieldList, withNames bool) string {
+	var parts []string
+	for _, f := range fl.List {
+		t := types.ExprString(f.Type)
+		if len(f.Names) == 0 {
+			parts = append(parts, t)
+			continue
+		}
+		for _, n := range f.Names {
+			if withNames {
+				parts = append(parts, n.Name+" "+t)
+			} else {
+				parts = append(parts, t)
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// receiverName returns the name of a method's receiver type, without a
+// pointer or type parameters.
+func receiverName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	return embeddedName(recv.List[0].Type)
+}
+
+// embeddedName returns the name of a type used as an embedded field or
+// receiver, as in *T, pkg.T, or T[K].
+func embeddedName(t ast.Expr) string {
+	switch t := t.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package apidiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+const oldSource = `package server
+
+import "time"
+
+// Server serves.
+type Server struct {
+	Addr    string
+	Timeout time.Duration
+	Debug   bool
+	private int
+}
+
+func (s *Server) Run() error { return nil }
+func (s *Server) Close() {}
+func (s Server) helper()    {}
+
+type Handler interface {
+	Serve(req string) error
+}
+
+type Gone struct{ Field int }
+
+func (g Gone) Method() {}
+
+const (
+	KindA Kind = iota
+	KindB
+)
+
+type Kind int
+
+func Parse(s string) (int, error) { return 0, nil }
+func unexported()                  {}
+`
+
+const newSource = `package server
+
+import (
+	"context"
+	"time"
+)
+
+// Server serves.
+type Server struct {
+	Addr    string
+	Timeout int
+	Logger  *Logger
+	private string
+}
+
+func (s *Server) Run(ctx context.Context) error { return nil }
+func (s *Server) Close()                        {}
+
+type Handler interface {
+	Serve(request string) error
+	Shutdown()
+}
+
+type Logger struct{ Prefix string }
+
+const (
+	KindA Kind = iota
+	KindB
+	KindC
+)
+
+type Kind int
+
+func Parse(input string) (int, error) { return 0, nil }
+func unexported(x int)                 {}
+`
+
+func TestCompare(t *testing.T) {
+	got, err := Compare(
+		map[string]string{"server.go": oldSource, "server_test.go": "package server\n\nfunc TestX() {}\n"},
+		map[string]string{"server.go": newSource},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Name: "Gone", Kind: Removed, Breaking: true, File: "server.go", Line: 21},
+		{Name: "Handler.Shutdown", Kind: Added, Breaking: true, File: "server.go", Line: 21},
+		{Name: "Server.Debug", Kind: Removed, Breaking: true, File: "server.go", Line: 9},
+		{Name: "Server.Run", Kind: Changed, Old: "func() error", New: "func(context.Context) error", Breaking: true, File: "server.go", Line: 16},
+		{Name: "Server.Timeout", Kind: Changed, Old: "time.Duration", New: "int", Breaking: true, File: "server.go", Line: 11},
+		{Name: "KindC", Kind: Added, File: "server.go", Line: 29},
+		{Name: "Logger", Kind: Added, File: "server.go", Line: 24},
+		{Name: "Server.Logger", Kind: Added, File: "server.go", Line: 12},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestCompareMainPackage(t *testing.T) {
+	got, err := Compare(
+		map[string]string{"main.go": "package main\n\nfunc Exported() {}\n"},
+		map[string]string{"main.go": "package main\n"},
+	)
+	if err != nil || len(got) != 0 {
+		t.Errorf("Compare of a main package = %+v, %v, want nothing", got, err)
+	}
+}
+
+func TestCompareParseError(t *testing.T) {
+	if _, err := Compare(nil, map[string]string{"bad.go": "package"}); err == nil {
+		t.Error("expected an error for a file that doesn't parse")
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	tests := []struct {
+		c    Change
+		want string
+	}{
+		{Change{Name: "Gone", Kind: Removed}, "Gone: removed"},
+		{Change{Name: "Run", Kind: Changed, Old: "func()", New: "func(int)"}, "Run: changed from func() to func(int)"},
+		{Change{Name: "Handler.Stop", Kind: Added, Breaking: true}, "Handler.Stop: added to an interface, which other packages' types may no longer implement"},
+		{Change{Name: "Logger", Kind: Added}, "Logger: added"},
+	}
+	for _, tt := range tests {
+		if got := tt.c.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
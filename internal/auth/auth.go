@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Credential is a token for a hosting API, with the username it belongs to
+// when the API takes basic auth, and where it was found.
+type Credential struct {
+	Username string
+	Token    string
+	Source   string // e.g. "config", "$GH_TOKEN", "git credential helper"
+}
+
+// Lookup says where to look for a service's credential. Sources are tried in
+// this order, and the first with a token wins:
+//
+//  1. Config, the username and token from revui's config file
+//  2. TokenEnv, in order, with the username from UserEnv
+//  3. the gh or glab CLI's config file, when CLI is set
+//  4. git credential helpers, for https://Host
+type Lookup struct {
+	Service  string // for messages, e.g. "Bitbucket"
+	Host     string // host the API is on; "" skips the CLI and git credential helpers
+	Config   Credential
+	UserEnv  string
+	TokenEnv []string
+	CLI      string // "gh" or "glab"
+}
+
+// GitHub returns the lookup for the GitHub API on host.
+func GitHub(host string) Lookup {
+	return Lookup{Service: "GitHub", Host: host, TokenEnv: []string{"GH_TOKEN", "GITHUB_TOKEN"}, CLI: "gh"}
+}
+
+// GitLab returns the lookup for the GitLab API on host.
+func GitLab(host string) Lookup {
+	return Lookup{Service: "GitLab", Host: host, TokenEnv: []string{"GITLAB_TOKEN"}, CLI: "glab"}
+}
+
+// Bitbucket returns the lookup for the Bitbucket API on host.
+func Bitbucket(host string) Lookup {
+	return Lookup{Service: "Bitbucket", Host: host, UserEnv: "BITBUCKET_USERNAME", TokenEnv: []string{"BITBUCKET_TOKEN"}}
+}
+
+// Gerrit returns the lookup for the Gerrit API on host.
+func Gerrit(host string) Lookup {
+	return Lookup{Service: "Gerrit", Host: host, UserEnv: "GERRIT_USERNAME", TokenEnv: []string{"GERRIT_PASSWORD"}}
+}
+
+// AzureDevOps returns the lookup for the Azure DevOps API on host.
+func AzureDevOps(host string) Lookup {
+	return Lookup{Service: "Azure DevOps", Host: host, TokenEnv: []string{"AZURE_DEVOPS_EXT_PAT"}}
+}
+
+// Resolve returns the first credential found, in the order documented on
+// Lookup, or an error naming every place it looked.
+func (l Lookup) Resolve() (Credential, error) {
+	if l.Config.Token != "" {
+		return Credential{Username: l.Config.Username, Token: l.Config.Token, Source: "config"}, nil
+	}
+	for _, env := range l.TokenEnv {
+		if token := os.Getenv(env); token != "" {
+			var username string
+			if l.UserEnv != "" {
+				username = os.Getenv(l.UserEnv)
+			}
+			return Credential{Username: cmp.Or(username, l.Config.Username), Token: token, Source: "$" + env}, nil
+		}
+	}
+	if l.Host != "" {
+		if c, ok := cliCredential(l.CLI, l.Host); ok {
+			return c, nil
+		}
+		if c, ok := gitCredential(l.Host, l.Config.Username); ok {
+			return c, nil
+		}
+	}
+	return Credential{}, fmt.Errorf("no %s credential found in %s", l.Service, l.places())
+}
+
+// places lists every source Resolve tries, for error messages.
+func (l Lookup) places() string {
+	places := append([]string{"the config"}, l.TokenEnv...)
+	if l.Host != "" {
+		if l.CLI != "" {
+			places = append(places, l.CLI+"'s config")
+		}
+		places = append(places, "git credential helpers for https://"+l.Host)
+	}
+	if len(places) == 1 {
+		return places[0]
+	}
+	return strings.Join(places[:len(places)-1], ", ") + ", or " + places[len(places)-1]
+}
+
+// cliCredential reads host's token from the gh or glab config file. Tokens the
+// CLI keeps in the system keyring aren't found.
+func cliCredential(cli, host string) (Credential, bool) {
+	var path string
+	var keys []string
+	switch cli {
+	case "gh":
+		dir := os.Getenv("GH_CONFIG_DIR")
+		if dir == "" {
+			dir = filepath.Join(configHome(), "gh")
+		}
+		path, keys = filepath.Join(dir, "hosts.yml"), []string{host}
+	case "glab":
+		dir := os.Getenv("GLAB_CONFIG_DIR")
+		if dir == "" {
+			dir = filepath.Join(configHome(), "glab-cli")
+		}
+		path, keys = filepath.Join(dir, "config.yml"), []string{"hosts", host}
+	default:
+		return Credential{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Credential{}, false
+	}
+	tokenKey := "oauth_token"
+	if cli == "glab" {
+		tokenKey = "token"
+	}
+	token := yamlValue(data, append(keys, tokenKey)...)
+	if token == "" {
+		return Credential{}, false
+	}
+	return Credential{Username: yamlValue(data, append(keys, "user")...), Token: token, Source: path}, true
+}
+
+// configHome returns the XDG config directory, which gh and glab use on every
+// platform.
+func configHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config")
+}
+
+// yamlValue returns the scalar at the path of nested mapping keys in a YAML
+// document, or "" if there is none. It understands only the block mappings
+// that gh and glab write.
+func yamlValue(data []byte, path ...string) string {
+	type entry struct {
+		indent int
+		key    string
+	}
+	var parents []entry // enclosing keys of the current line
+	for line := range strings.Lines(string(data)) {
+		text := strings.TrimRight(line, "\r\n")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(text) - len(trimmed)
+		for len(parents) > 0 && indent <= parents[len(parents)-1].indent {
+			parents = parents[:len(parents)-1]
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		parents = append(parents, entry{indent, strings.Trim(key, `"'`)})
+		if len(parents) != len(path) || !slices.EqualFunc(parents, path, func(e entry, k string) bool { return e.key == k }) {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		return strings.Trim(value, `"'`)
+	}
+	return ""
+}
+
+// gitCredential asks the configured git credential helpers for a credential
+// for https://host, never prompting.
+func gitCredential(host, username string) (Credential, bool) {
+	input := "protocol=https\nhost=" + host + "\n"
+	if username != "" {
+		input += "username=" + username + "\n"
+	}
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input + "\n")
+	// An empty GIT_ASKPASS disables askpass programs, along with
+	// SSH_ASKPASS and core.askPass, so a missing credential fails instead.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		return Credential{}, false
+	}
+	c := Credential{Source: "git credential helper"}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			c.Username = value
+		case "password":
+			c.Token = value
+		}
+	}
+	return c, c.Token != ""
+}
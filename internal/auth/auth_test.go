@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolate keeps the user's environment, CLI logins, and git credential
+// helpers out of a test.
+func isolate(t *testing.T) {
+	t.Helper()
+	for _, env := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GITLAB_TOKEN", "BITBUCKET_TOKEN", "BITBUCKET_USERNAME"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GH_CONFIG_DIR", "")
+	t.Setenv("GLAB_CONFIG_DIR", "")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_COUNT", "0")
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	isolate(t)
+	lookup := Bitbucket("bitbucket.org")
+	lookup.Config.Username = "config-user"
+
+	// git credential helpers come last
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "credential.helper")
+	t.Setenv("GIT_CONFIG_VALUE_0", "!f() { echo username=helper-user; echo password=helper-token; }; f")
+	want := Credential{Username: "helper-user", Token: "helper-token", Source: "git credential helper"}
+	if got, err := lookup.Resolve(); err != nil || got != want {
+		t.Errorf("Resolve() = %+v, %v; want %+v", got, err, want)
+	}
+
+	// then the environment, keeping the configured username without one there
+	t.Setenv("BITBUCKET_TOKEN", "env-token")
+	want = Credential{Username: "config-user", Token: "env-token", Source: "$BITBUCKET_TOKEN"}
+	if got, err := lookup.Resolve(); err != nil || got != want {
+		t.Errorf("Resolve() = %+v, %v; want %+v", got, err, want)
+	}
+
+	// and the config first
+	lookup.Config.Token = "config-token"
+	want = Credential{Username: "config-user", Token: "config-token", Source: "config"}
+	if got, err := lookup.Resolve(); err != nil || got != want {
+		t.Errorf("Resolve() = %+v, %v; want %+v", got, err, want)
+	}
+}
+
+func TestResolveCLIConfig(t *testing.T) {
+	isolate(t)
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	writeFile(t, filepath.Join(dir, "gh", "hosts.yml"), `github.com:
+    user: octocat
+    oauth_token: gho_abc123
+    git_protocol: https
+ghe.acme.com:
+    user: me
+`)
+	writeFile(t, filepath.Join(dir, "glab-cli", "config.yml"), `# glab config
+git_protocol: ssh
+hosts:
+    gitlab.com:
+        # the token for gitlab.com
+        token: glpat-xyz
+        api_host: gitlab.com
+`)
+
+	tests := []struct {
+		lookup Lookup
+		want   Credential
+	}{
+		{lookup: GitHub("github.com"), want: Credential{Username: "octocat", Token: "gho_abc123", Source: filepath.Join(dir, "gh", "hosts.yml")}},
+		{lookup: GitLab("gitlab.com"), want: Credential{Token: "glpat-xyz", Source: filepath.Join(dir, "glab-cli", "config.yml")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lookup.Service, func(t *testing.T) {
+			got, err := tt.lookup.Resolve()
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve() = %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+
+	// A host logged in without a token in the file (kept in the keyring)
+	if _, err := GitHub("ghe.acme.com").Resolve(); err == nil {
+		t.Error("expected no credential for a host without a token")
+	}
+}
+
+func TestResolveNotFound(t *testing.T) {
+	isolate(t)
+	_, err := GitHub("github.com").Resolve()
+	want := "no GitHub credential found in the config, GH_TOKEN, GITHUB_TOKEN, gh's config, or git credential helpers for https://github.com"
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+
+	// Without a host, only the config and environment are tried
+	_, err = AzureDevOps("").Resolve()
+	if err == nil || !strings.HasSuffix(err.Error(), "the config, or AZURE_DEVOPS_EXT_PAT") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestYAMLValue(t *testing.T) {
+	doc := `a:
+  b: one
+  c:
+    d: "two"   # quoted
+e: three
+c:
+  d: four
+`
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{path: []string{"a", "b"}, want: "one"},
+		{path: []string{"a", "c", "d"}, want: "two"},
+		{path: []string{"e"}, want: "three"},
+		{path: []string{"c", "d"}, want: "four"},
+		{path: []string{"a", "d"}, want: ""},
+		{path: []string{"b"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.path, "."), func(t *testing.T) {
+			if got := yamlValue([]byte(doc), tt.path...); got != tt.want {
+				t.Errorf("yamlValue(%v) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
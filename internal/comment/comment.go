@@ -3,12 +3,33 @@ package comment
 import "github.com/deparker/revui/internal/git"
 
 // Comment represents an inline review comment on a diff.
+// StartLine and EndLine count lines of the old file for a comment on removed
+// lines, and of the new file otherwise.
 type Comment struct {
-	FilePath  string
-	StartLine int
-	EndLine   int
-	LineType  git.LineType
-	Body      string
+	FilePath  string       `json:"file"`
+	StartLine int          `json:"start_line"`
+	EndLine   int          `json:"end_line"`
+	LineType  git.LineType `json:"line_type"`
+	Body      string       `json:"body"`
+	Author    string       `json:"author,omitempty"` // who wrote it; empty when unknown
+	// OldStartLine and OldEndLine are the old file's numbers for a comment on
+	// context lines, which exist on both sides. They are 0 when unknown.
+	OldStartLine int `json:"old_start_line,omitempty"`
+	OldEndLine   int `json:"old_end_line,omitempty"`
+	// NearStartLine and NearEndLine are the changed lines nearest a comment
+	// on context lines, which say why it matters: new file numbers, or old
+	// ones if NearRemoved is set because the change only removed lines. They
+	// are 0 when unknown.
+	NearStartLine int  `json:"near_start_line,omitempty"`
+	NearEndLine   int  `json:"near_end_line,omitempty"`
+	NearRemoved   bool `json:"near_removed,omitempty"`
+	// Thread is the ID of the discussion on a hosting service the comment
+	// belongs to: set on comments fetched from a pull request, and on the
+	// reviewer's replies to them.
+	Thread string `json:"thread,omitempty"`
+	// Question marks a comment that asks the author something rather than
+	// saying what to change. Output lists questions together at the end.
+	Question bool `json:"question,omitempty"`
 }
 
 type commentKey struct {
@@ -63,6 +84,28 @@ func (s *Store) Get(filePath string, line int) *Comment {
 	return &s.comments[idx]
 }
 
+// Covering returns the comment on line of filePath, a line of type lineType:
+// the one starting there, or else the narrowest whose range takes it in, so
+// that a range comment is found from any of its lines. Removed lines are
+// numbered on the old side of the diff and other lines on the new, so only
+// comments on the same side count. It returns nil if no comment covers the
+// line.
+func (s *Store) Covering(filePath string, line int, lineType git.LineType) *Comment {
+	removed := lineType == git.LineRemoved
+	var found *Comment
+	for i := range s.comments {
+		c := &s.comments[i]
+		if c.FilePath != filePath || line < c.StartLine || line > c.EndLine || (c.LineType == git.LineRemoved) != removed {
+			continue
+		}
+		if found == nil || found.StartLine != line &&
+			(c.StartLine == line || c.EndLine-c.StartLine < found.EndLine-found.StartLine) {
+			found = c
+		}
+	}
+	return found
+}
+
 func (s *Store) All() []Comment {
 	return s.comments
 }
@@ -3,17 +3,36 @@ package comment
 import (
 	"strconv"
 	"strings"
+
+	"github.com/deparker/revui/internal/git"
 )
 
 func Format(comments []Comment) string {
+	return format(comments, MultipleAuthors(comments))
+}
+
+// format formats comments, naming each one's author if showAuthors is set.
+func format(comments []Comment, showAuthors bool) string {
+	return formatWith(comments, showAuthors, nil)
+}
+
+// formatWith is format, calling after, if set, following each comment.
+// Questions are left out of their files and listed under "Open questions" at
+// the end, each naming its file, so the author can answer them in turn.
+func formatWith(comments []Comment, showAuthors bool, after func(*strings.Builder, Comment)) string {
 	if len(comments) == 0 {
 		return ""
 	}
 
 	grouped := make(map[string][]Comment)
 	var fileOrder []string
+	var questions []Comment
 	seen := make(map[string]bool)
 	for _, c := range comments {
+		if c.Question {
+			questions = append(questions, c)
+			continue
+		}
 		if !seen[c.FilePath] {
 			fileOrder = append(fileOrder, c.FilePath)
 			seen[c.FilePath] = true
@@ -29,11 +48,7 @@ func Format(comments []Comment) string {
 		b.WriteByte('\n')
 
 		for _, c := range grouped[file] {
-			b.WriteString("- ")
-			writeLineInfo(&b, c)
-			b.WriteString(": ")
-			b.WriteString(c.Body)
-			b.WriteByte('\n')
+			writeComment(&b, c, "", showAuthors, after)
 		}
 
 		if i < len(fileOrder)-1 {
@@ -41,23 +56,105 @@ func Format(comments []Comment) string {
 		}
 	}
 
+	if len(questions) > 0 {
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString("Open questions\n")
+		for _, c := range questions {
+			writeComment(&b, c, c.FilePath+" ", showAuthors, after)
+		}
+	}
+
 	return b.String()
 }
 
+// writeComment writes c as a list item, its line info led by prefix, then
+// calls after, if set.
+func writeComment(b *strings.Builder, c Comment, prefix string, showAuthors bool, after func(*strings.Builder, Comment)) {
+	b.WriteString("- ")
+	b.WriteString(prefix)
+	writeLineInfo(b, c)
+	if showAuthors {
+		writeAuthor(b, c)
+	}
+	b.WriteString(": ")
+	b.WriteString(c.Body)
+	b.WriteByte('\n')
+	if after != nil {
+		after(b, c)
+	}
+}
+
+// MultipleAuthors reports whether the comments come from more than one
+// author, in which case output names the author of each comment.
+func MultipleAuthors(comments []Comment) bool {
+	for _, c := range comments[min(1, len(comments)):] {
+		if c.Author != comments[0].Author {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAuthor writes " [author]", or nothing for a comment without one.
+func writeAuthor(b *strings.Builder, c Comment) {
+	if c.Author == "" {
+		return
+	}
+	b.WriteString(" [")
+	b.WriteString(c.Author)
+	b.WriteByte(']')
+}
+
 // writeLineInfo writes the line info directly to a builder, avoiding intermediate string allocation.
+// It names the side the numbers refer to: "old L5-8 (removed)", "new L10 (added)",
+// or "new L10 (old L8, near change at L14-16)" for context lines, whose old
+// numbers and nearest change are given when known.
 func writeLineInfo(b *strings.Builder, c Comment) {
-	b.WriteByte('L')
-	b.WriteString(strconv.Itoa(c.StartLine))
-
-	if c.EndLine != 0 && c.EndLine != c.StartLine {
-		b.WriteByte('-')
-		b.WriteString(strconv.Itoa(c.EndLine))
+	if c.StartLine == 0 {
+		// A comment on the whole file, such as a binary one
+		b.WriteString("L0")
+		return
+	}
+	if c.LineType == git.LineRemoved {
+		b.WriteString("old ")
+	} else {
+		b.WriteString("new ")
 	}
+	writeRange(b, c.StartLine, c.EndLine)
 
-	lineType := c.LineType.String()
-	if lineType != "context" {
+	switch {
+	case c.LineType != git.LineContext:
 		b.WriteString(" (")
-		b.WriteString(lineType)
+		b.WriteString(c.LineType.String())
 		b.WriteByte(')')
+	case c.OldStartLine != 0 || c.NearStartLine != 0:
+		b.WriteString(" (")
+		if c.OldStartLine != 0 {
+			b.WriteString("old ")
+			writeRange(b, c.OldStartLine, c.OldEndLine)
+			if c.NearStartLine != 0 {
+				b.WriteString(", ")
+			}
+		}
+		if c.NearStartLine != 0 {
+			b.WriteString("near change at ")
+			if c.NearRemoved {
+				b.WriteString("old ")
+			}
+			writeRange(b, c.NearStartLine, c.NearEndLine)
+		}
+		b.WriteByte(')')
+	}
+}
+
+// writeRange writes "L5", or "L5-8" for a range of lines.
+func writeRange(b *strings.Builder, start, end int) {
+	b.WriteByte('L')
+	b.WriteString(strconv.Itoa(start))
+	if end != 0 && end != start {
+		b.WriteByte('-')
+		b.WriteString(strconv.Itoa(end))
 	}
 }
@@ -19,7 +19,7 @@ func TestFormatSingleComment(t *testing.T) {
 
 	out := Format(store.All())
 
-	expected := "main.go\n- L10 (added): This needs error handling.\n"
+	expected := "main.go\n- new L10 (added): This needs error handling.\n"
 	if out != expected {
 		t.Errorf("got:\n%s\nwant:\n%s", out, expected)
 	}
@@ -37,12 +37,38 @@ func TestFormatRangeComment(t *testing.T) {
 
 	out := Format(store.All())
 
-	expected := "util.go\n- L5-8 (removed): Why was this removed?\n"
+	expected := "util.go\n- old L5-8 (removed): Why was this removed?\n"
 	if out != expected {
 		t.Errorf("got:\n%s\nwant:\n%s", out, expected)
 	}
 }
 
+func TestFormatLineSides(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Comment
+		want string
+	}{
+		{name: "added", c: Comment{StartLine: 10, EndLine: 10, LineType: git.LineAdded}, want: "new L10 (added)"},
+		{name: "removed", c: Comment{StartLine: 4, EndLine: 6, LineType: git.LineRemoved}, want: "old L4-6 (removed)"},
+		{name: "context with old line", c: Comment{StartLine: 10, EndLine: 10, OldStartLine: 8, OldEndLine: 8}, want: "new L10 (old L8)"},
+		{name: "context range", c: Comment{StartLine: 10, EndLine: 12, OldStartLine: 8, OldEndLine: 10}, want: "new L10-12 (old L8-10)"},
+		{name: "context near a change", c: Comment{StartLine: 10, EndLine: 10, OldStartLine: 8, OldEndLine: 8, NearStartLine: 14, NearEndLine: 16}, want: "new L10 (old L8, near change at L14-16)"},
+		{name: "context near a removal", c: Comment{StartLine: 10, EndLine: 10, NearStartLine: 12, NearEndLine: 12, NearRemoved: true}, want: "new L10 (near change at old L12)"},
+		{name: "context without old line", c: Comment{StartLine: 10, EndLine: 10}, want: "new L10"},
+		{name: "whole file", c: Comment{}, want: "L0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeLineInfo(&b, tt.c)
+			if got := b.String(); got != tt.want {
+				t.Errorf("writeLineInfo() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFormatGroupsByFile(t *testing.T) {
 	store := NewStore()
 	store.Add(Comment{FilePath: "a.go", StartLine: 1, EndLine: 1, Body: "first"})
@@ -59,6 +85,28 @@ func TestFormatGroupsByFile(t *testing.T) {
 	}
 }
 
+func TestFormatOpenQuestions(t *testing.T) {
+	comments := []Comment{
+		{FilePath: "a.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "Why a map?", Question: true},
+		{FilePath: "a.go", StartLine: 5, EndLine: 5, LineType: git.LineAdded, Body: "Rename this."},
+		{FilePath: "b.go", StartLine: 2, EndLine: 4, LineType: git.LineRemoved, Body: "Is this still used?", Question: true},
+	}
+	want := "a.go\n" +
+		"- new L5 (added): Rename this.\n" +
+		"\n" +
+		"Open questions\n" +
+		"- a.go new L3 (added): Why a map?\n" +
+		"- b.go old L2-4 (removed): Is this still used?\n"
+	if got := Format(comments); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = "Open questions\n- a.go new L3 (added): Why a map?\n"
+	if got := Format(comments[:1]); got != want {
+		t.Errorf("questions only, got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
 func TestStoreAddAndDelete(t *testing.T) {
 	store := NewStore()
 	store.Add(Comment{FilePath: "a.go", StartLine: 1, EndLine: 1, Body: "hello"})
@@ -97,6 +145,44 @@ func TestStoreGetByFileLine(t *testing.T) {
 	}
 }
 
+func TestStoreCovering(t *testing.T) {
+	store := NewStore()
+	store.Add(Comment{FilePath: "a.go", StartLine: 5, EndLine: 8, LineType: git.LineAdded, Body: "range"})
+	store.Add(Comment{FilePath: "a.go", StartLine: 6, EndLine: 7, LineType: git.LineContext, Body: "inner"})
+	store.Add(Comment{FilePath: "a.go", StartLine: 8, EndLine: 8, LineType: git.LineAdded, Body: "last line"})
+	store.Add(Comment{FilePath: "a.go", StartLine: 20, EndLine: 22, LineType: git.LineRemoved, Body: "removed"})
+	store.Add(Comment{FilePath: "b.go", StartLine: 1, EndLine: 20, Body: "other file"})
+
+	tests := []struct {
+		line     int
+		lineType git.LineType
+		want     string
+	}{
+		{line: 4, lineType: git.LineAdded},
+		{line: 5, lineType: git.LineAdded, want: "range"},
+		{line: 6, lineType: git.LineContext, want: "inner"},
+		{line: 7, lineType: git.LineAdded, want: "inner"},
+		{line: 8, lineType: git.LineContext, want: "last line"},
+		{line: 9, lineType: git.LineAdded},
+		// Old and new line numbers don't mix
+		{line: 6, lineType: git.LineRemoved},
+		{line: 21, lineType: git.LineRemoved, want: "removed"},
+		{line: 21, lineType: git.LineAdded},
+	}
+	for _, tt := range tests {
+		got := ""
+		if c := store.Covering("a.go", tt.line, tt.lineType); c != nil {
+			got = c.Body
+		}
+		if got != tt.want {
+			t.Errorf("Covering(a.go, %d, %v) = %q, want %q", tt.line, tt.lineType, got, tt.want)
+		}
+	}
+	if store.Get("a.go", 7) != nil {
+		t.Error("Get should only find a comment starting on the line")
+	}
+}
+
 func BenchmarkFormat(b *testing.B) {
 	comments := []Comment{
 		{FilePath: "a.go", StartLine: 1, EndLine: 1, LineType: git.LineAdded, Body: "first comment"},
@@ -109,3 +195,43 @@ func BenchmarkFormat(b *testing.B) {
 		Format(comments)
 	}
 }
+
+func TestFormatAuthors(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments []Comment
+		want     string
+	}{
+		{
+			name: "single author is not named",
+			comments: []Comment{
+				{FilePath: "a.go", StartLine: 1, Body: "first", Author: "Ann"},
+				{FilePath: "a.go", StartLine: 2, Body: "second", Author: "Ann"},
+			},
+			want: "a.go\n- new L1: first\n- new L2: second\n",
+		},
+		{
+			name: "several authors are named",
+			comments: []Comment{
+				{FilePath: "a.go", StartLine: 1, Body: "first", Author: "Ann"},
+				{FilePath: "a.go", StartLine: 2, Body: "second", Author: "Bob"},
+			},
+			want: "a.go\n- new L1 [Ann]: first\n- new L2 [Bob]: second\n",
+		},
+		{
+			name: "unattributed comments stay bare",
+			comments: []Comment{
+				{FilePath: "a.go", StartLine: 1, Body: "first"},
+				{FilePath: "a.go", StartLine: 2, Body: "second", Author: "review-agent"},
+			},
+			want: "a.go\n- new L1: first\n- new L2 [review-agent]: second\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.comments); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package comment
+
+import (
+	"path"
+	"strings"
+)
+
+// extensionLanguages maps file extensions to the language tags that Markdown
+// renderers such as GitHub's highlight fenced code blocks by.
+var extensionLanguages = map[string]string{
+	".bash":       "bash",
+	".c":          "c",
+	".cc":         "cpp",
+	".clj":        "clojure",
+	".cmake":      "cmake",
+	".cpp":        "cpp",
+	".cs":         "csharp",
+	".css":        "css",
+	".cxx":        "cpp",
+	".dart":       "dart",
+	".dockerfile": "dockerfile",
+	".el":         "elisp",
+	".erl":        "erlang",
+	".ex":         "elixir",
+	".exs":        "elixir",
+	".fish":       "fish",
+	".go":         "go",
+	".gradle":     "groovy",
+	".graphql":    "graphql",
+	".groovy":     "groovy",
+	".h":          "c",
+	".hpp":        "cpp",
+	".hs":         "haskell",
+	".htm":        "html",
+	".html":       "html",
+	".ini":        "ini",
+	".java":       "java",
+	".js":         "javascript",
+	".json":       "json",
+	".jsx":        "jsx",
+	".kt":         "kotlin",
+	".kts":        "kotlin",
+	".less":       "less",
+	".lua":        "lua",
+	".m":          "objectivec",
+	".md":         "markdown",
+	".mjs":        "javascript",
+	".mk":         "makefile",
+	".ml":         "ocaml",
+	".mm":         "objectivec",
+	".nix":        "nix",
+	".php":        "php",
+	".pl":         "perl",
+	".pm":         "perl",
+	".proto":      "protobuf",
+	".ps1":        "powershell",
+	".py":         "python",
+	".r":          "r",
+	".rb":         "ruby",
+	".rs":         "rust",
+	".scala":      "scala",
+	".scss":       "scss",
+	".sh":         "sh",
+	".sql":        "sql",
+	".swift":      "swift",
+	".tf":         "hcl",
+	".toml":       "toml",
+	".ts":         "typescript",
+	".tsx":        "tsx",
+	".vim":        "vim",
+	".vue":        "vue",
+	".xml":        "xml",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".zig":        "zig",
+	".zsh":        "zsh",
+}
+
+// nameLanguages maps file names that say their language without an extension.
+var nameLanguages = map[string]string{
+	"BUILD":          "starlark",
+	"BUILD.bazel":    "starlark",
+	"CMakeLists.txt": "cmake",
+	"Containerfile":  "dockerfile",
+	"Dockerfile":     "dockerfile",
+	"GNUmakefile":    "makefile",
+	"Gemfile":        "ruby",
+	"Jenkinsfile":    "groovy",
+	"Makefile":       "makefile",
+	"Rakefile":       "ruby",
+	"Vagrantfile":    "ruby",
+	"WORKSPACE":      "starlark",
+	"go.mod":         "go-mod",
+	"makefile":       "makefile",
+}
+
+// interpreterLanguages maps the programs that shebang lines run to the
+// language of the script.
+var interpreterLanguages = map[string]string{
+	"ash":     "sh",
+	"bash":    "bash",
+	"dash":    "sh",
+	"deno":    "typescript",
+	"fish":    "fish",
+	"lua":     "lua",
+	"node":    "javascript",
+	"perl":    "perl",
+	"php":     "php",
+	"python":  "python",
+	"python2": "python",
+	"python3": "python",
+	"ruby":    "ruby",
+	"sh":      "sh",
+	"zsh":     "zsh",
+}
+
+// language returns the language tag for a fenced block of the file at
+// filePath, whose first line is firstLine if known, or "" if it can't tell.
+// The name decides first, so that a Dockerfile.dev or a Makefile is known
+// without an extension; then the extension; then a shebang on the first
+// line, for scripts with neither.
+func language(filePath, firstLine string) string {
+	name := path.Base(filePath)
+	if lang, ok := nameLanguages[name]; ok {
+		return lang
+	}
+	for _, prefix := range []string{"Dockerfile.", "Containerfile."} {
+		if strings.HasPrefix(name, prefix) {
+			return "dockerfile"
+		}
+	}
+	if strings.HasPrefix(name, "Makefile.") {
+		return "makefile"
+	}
+	if lang, ok := extensionLanguages[strings.ToLower(path.Ext(name))]; ok {
+		return lang
+	}
+	return shebangLanguage(firstLine)
+}
+
+// shebangLanguage returns the language of the interpreter a shebang line
+// runs, directly or through env, as in "#!/usr/bin/env python3".
+func shebangLanguage(line string) string {
+	rest, ok := strings.CutPrefix(line, "#!")
+	if !ok {
+		return ""
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	program := path.Base(fields[0])
+	if program == "env" {
+		program = ""
+		for _, f := range fields[1:] {
+			// Skip env's options, such as -S, and variable assignments.
+			if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+				continue
+			}
+			program = path.Base(f)
+			break
+		}
+	}
+	if lang, ok := interpreterLanguages[program]; ok {
+		return lang
+	}
+	// Versioned interpreters, such as python3.12 or ruby2.7
+	return interpreterLanguages[strings.TrimRight(program, "0123456789.")]
+}
@@ -0,0 +1,32 @@
+package comment
+
+import "testing"
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		path, firstLine string
+		want            string
+	}{
+		{"main.go", "", "go"},
+		{"web/App.TSX", "", "tsx"},
+		{"Dockerfile", "", "dockerfile"},
+		{"build/Dockerfile.dev", "", "dockerfile"},
+		{"ci/app.dockerfile", "", "dockerfile"},
+		{"Makefile", "", "makefile"},
+		{"Makefile.in", "", "makefile"},
+		{"src/CMakeLists.txt", "", "cmake"},
+		{"README.md", "#!/bin/sh", "markdown"},
+		{"scripts/deploy", "#!/bin/bash -e", "bash"},
+		{"scripts/tool", "#!/usr/bin/env python3", "python"},
+		{"scripts/tool", "#!/usr/bin/env python3.12", "python"},
+		{"scripts/run", "#!/usr/bin/env -S node --no-warnings", "javascript"},
+		{"scripts/run", "#!/usr/bin/env LANG=C perl", "perl"},
+		{"scripts/run", "#!/opt/unknown", ""},
+		{"LICENSE", "Copyright", ""},
+	}
+	for _, tt := range tests {
+		if got := language(tt.path, tt.firstLine); got != tt.want {
+			t.Errorf("language(%q, %q) = %q, want %q", tt.path, tt.firstLine, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package comment
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+var (
+	mailStartRe = regexp.MustCompile(`^From [0-9a-f]{40} `)
+	patchFileRe = regexp.MustCompile(`^diff --git a/(.+) b/(.+)$`)
+	patchHunkRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+)
+
+// coverBlurb is the placeholder format-patch leaves in the cover letter body.
+const coverBlurb = "*** BLURB HERE ***"
+
+// patchHunk locates one hunk of a format-patch series.
+type patchHunk struct {
+	mail               int
+	file               string
+	oldStart, oldCount int
+	newStart, newCount int
+	end                int // index of the hunk's last line
+}
+
+// contains reports whether the comment's start line falls in the hunk, using
+// old line numbers for removed lines and new ones otherwise.
+func (h patchHunk) contains(c Comment) bool {
+	if c.LineType == git.LineRemoved {
+		return c.StartLine >= h.oldStart && c.StartLine < h.oldStart+h.oldCount
+	}
+	return c.StartLine >= h.newStart && c.StartLine < h.newStart+h.newCount
+}
+
+// AnnotatePatch inserts review comments into `git format-patch --stdout
+// --cover-letter` output for mailing-list review. Comments are placed below
+// the hunk they refer to in the last patch touching their file, where line
+// numbers match the reviewed branch. Comments that can't be placed that way
+// (removed lines in files several patches touch, or lines no hunk covers) are
+// listed in the cover letter instead.
+func AnnotatePatch(raw string, comments []Comment) string {
+	lines := strings.Split(raw, "\n")
+
+	var hunks []patchHunk
+	lastMail := make(map[string]int)
+	touches := make(map[string]int)
+	mail, file, blurb := -1, "", -1
+	var oldLeft, newLeft int
+
+	for i, line := range lines {
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, `\ `):
+			default:
+				oldLeft--
+				newLeft--
+			}
+			hunks[len(hunks)-1].end = i
+			continue
+		}
+
+		switch {
+		case mailStartRe.MatchString(line):
+			mail++
+			file = ""
+		case line == coverBlurb:
+			blurb = i
+		case strings.HasPrefix(line, `\ `) && len(hunks) > 0 && hunks[len(hunks)-1].end == i-1:
+			// "\ No newline at end of file" belongs with the hunk before it.
+			hunks[len(hunks)-1].end = i
+		}
+		if m := patchFileRe.FindStringSubmatch(line); m != nil {
+			file = m[2]
+			if touches[file] == 0 || lastMail[file] != mail {
+				touches[file]++
+			}
+			lastMail[file] = mail
+			continue
+		}
+		if m := patchHunkRe.FindStringSubmatch(line); m != nil && file != "" {
+			h := patchHunk{
+				mail:     mail,
+				file:     file,
+				oldStart: atoiDefault(m[1], 0),
+				oldCount: atoiDefault(m[2], 1),
+				newStart: atoiDefault(m[3], 0),
+				newCount: atoiDefault(m[4], 1),
+				end:      i,
+			}
+			hunks = append(hunks, h)
+			oldLeft, newLeft = h.oldCount, h.newCount
+		}
+	}
+
+	inline := make(map[int][]Comment)
+	var cover []Comment
+	for _, c := range comments {
+		placed := false
+		if m, ok := lastMail[c.FilePath]; ok && (c.LineType != git.LineRemoved || touches[c.FilePath] == 1) {
+			for _, h := range hunks {
+				if h.mail == m && h.file == c.FilePath && h.contains(c) {
+					inline[h.end] = append(inline[h.end], c)
+					placed = true
+					break
+				}
+			}
+		}
+		if !placed {
+			cover = append(cover, c)
+		}
+	}
+
+	showAuthors := MultipleAuthors(comments)
+	var b strings.Builder
+	b.Grow(len(raw) + 128*len(comments))
+	if blurb < 0 && len(cover) > 0 {
+		b.WriteString(format(cover, showAuthors))
+		b.WriteByte('\n')
+	}
+	for i, line := range lines {
+		if i == blurb {
+			b.WriteString(coverText(len(comments)-len(cover), cover, showAuthors))
+		} else {
+			b.WriteString(line)
+		}
+		if i < len(lines)-1 {
+			b.WriteByte('\n')
+		}
+		for _, c := range inline[i] {
+			writeAnnotation(&b, c, showAuthors)
+		}
+	}
+	return b.String()
+}
+
+func coverText(inline int, cover []Comment, showAuthors bool) string {
+	var b strings.Builder
+	if inline > 0 {
+		b.WriteString("Review comments are inline below each hunk, on lines starting with \"#\".\n")
+	}
+	if len(cover) > 0 {
+		if inline > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString("Review comments:\n\n")
+		b.WriteString(format(cover, showAuthors))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// writeAnnotation writes a comment as "#"-prefixed lines so it can't be
+// mistaken for diff content.
+func writeAnnotation(b *strings.Builder, c Comment, showAuthor bool) {
+	b.WriteString("#\n# review ")
+	writeLineInfo(b, c)
+	if showAuthor {
+		writeAuthor(b, c)
+	}
+	b.WriteString(":\n")
+	for _, line := range strings.Split(c.Body, "\n") {
+		b.WriteString("# ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteString("#\n")
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,62 @@
+package comment
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestAnnotatePatch(t *testing.T) {
+	raw, err := os.ReadFile("testdata/series.patch")
+	if err != nil {
+		t.Fatalf("reading test fixture: %v", err)
+	}
+	comments := []Comment{
+		{FilePath: "a.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "why z?"},
+		{FilePath: "b.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "name y better\nsecond line"},
+		{FilePath: "b.go", StartLine: 9, EndLine: 9, LineType: git.LineContext, Body: "outside any hunk"},
+		// a.go is touched by two patches, so old line numbers are ambiguous.
+		{FilePath: "a.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "removed line"},
+	}
+
+	out := AnnotatePatch(string(raw), comments)
+
+	// a.go's comment lands in patch 2 (the last to touch a.go), after the
+	// "\ No newline" marker that ends its hunk.
+	wantA := "\\ No newline at end of file\n#\n# review new L3 (added):\n# why z?\n#\n-- \n"
+	if !strings.Contains(out, wantA) {
+		t.Errorf("a.go comment not placed below its hunk:\n%s", out)
+	}
+	if strings.Index(out, "why z?") < strings.Index(out, "[PATCH 2/2]") {
+		t.Error("a.go comment should be in the last patch touching a.go")
+	}
+
+	wantB := "+var y = 1\n // end\n#\n# review new L2 (added):\n# name y better\n# second line\n#\n-- \n"
+	if !strings.Contains(out, wantB) {
+		t.Errorf("b.go comment not placed below its hunk:\n%s", out)
+	}
+
+	cover := out[:strings.Index(out, "[PATCH 1/2]")]
+	if strings.Contains(cover, coverBlurb) {
+		t.Error("cover letter placeholder should be replaced")
+	}
+	for _, body := range []string{"outside any hunk", "removed line"} {
+		if !strings.Contains(cover, body) {
+			t.Errorf("unplaced comment %q should be in the cover letter", body)
+		}
+	}
+	if strings.Count(out, "why z?") != 1 {
+		t.Error("each comment should appear once")
+	}
+}
+
+func TestAnnotatePatchNoCoverLetter(t *testing.T) {
+	raw := "From 2222222222222222222222222222222222222222 Mon Sep 17 00:00:00 2001\n" +
+		"Subject: [PATCH] x\n\n---\ndiff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b\n"
+	out := AnnotatePatch(raw, []Comment{{FilePath: "c.go", StartLine: 1, EndLine: 1, Body: "elsewhere"}})
+	if !strings.HasPrefix(out, "c.go\n- new L1: elsewhere\n") {
+		t.Errorf("without a cover letter, unplaced comments should lead the output:\n%s", out)
+	}
+}
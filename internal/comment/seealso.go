@@ -0,0 +1,162 @@
+package comment
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+// declaration matches the name a line declares, in the common forms of most
+// languages: func, def, class, type, fn, function, and variable keywords,
+// with a Go method's receiver skipped.
+var declaration = regexp.MustCompile(`\b(?:func|def|class|type|struct|interface|enum|trait|fn|function|var|let|const|val)\s+(?:\([^)]*\)\s*)?([A-Za-z_]\w*)`)
+
+// identifier matches the tokens the index is made of.
+var identifier = regexp.MustCompile(`[A-Za-z_]\w*`)
+
+// call matches the name a call is to, as in parse(x) or s.Parse(x).
+var call = regexp.MustCompile(`([A-Za-z_]\w*)\s*\(`)
+
+// minIdentifier is the shortest name worth cross-referencing; shorter ones,
+// such as i or ok, are in too many files to mean anything.
+const minIdentifier = 3
+
+// maxSeeAlso is how many other places a comment refers to.
+const maxSeeAlso = 5
+
+// Mention is a line of a file that mentions an identifier.
+type Mention struct {
+	Path string
+	Line int
+}
+
+// Index maps each identifier in a change's new code to the first line of
+// each file that mentions it.
+type Index map[string][]Mention
+
+// NewIndex indexes the added and context lines of diffs.
+func NewIndex(diffs []*git.FileDiff) Index {
+	index := make(Index)
+	for _, fd := range diffs {
+		if fd == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, h := range fd.Hunks {
+			for _, l := range h.Lines {
+				if l.Type == git.LineRemoved {
+					continue
+				}
+				for _, tok := range identifier.FindAllString(l.Content, -1) {
+					if len(tok) >= minIdentifier && !seen[tok] {
+						seen[tok] = true
+						index[tok] = append(index[tok], Mention{fd.Path, l.NewLineNo})
+					}
+				}
+			}
+		}
+	}
+	for _, mentions := range index {
+		slices.SortFunc(mentions, func(a, b Mention) int { return strings.Compare(a.Path, b.Path) })
+	}
+	return index
+}
+
+// SeeAlso returns comments with "(see also ...)" appended to each one on
+// added lines that declare a function, type, or variable, naming where other
+// files in index mention it. diffs holds the commented files' diffs, which
+// say what the commented lines declare.
+func SeeAlso(comments []Comment, diffs map[string]*git.FileDiff, index Index) []Comment {
+	out := make([]Comment, len(comments))
+	for i, c := range comments {
+		out[i] = c
+		if c.LineType != git.LineAdded || c.Thread != "" {
+			continue
+		}
+		fd := diffs[c.FilePath]
+		if fd == nil {
+			continue
+		}
+		var refs []string
+		for _, name := range declaredNames(fd, c) {
+			for _, m := range index[name] {
+				ref := fmt.Sprintf("`%s:%d`", m.Path, m.Line)
+				if m.Path != c.FilePath && len(refs) < maxSeeAlso && !slices.Contains(refs, ref) {
+					refs = append(refs, ref)
+				}
+			}
+		}
+		if len(refs) > 0 {
+			out[i].Body += " (see also " + strings.Join(refs, ", ") + ")"
+		}
+	}
+	return out
+}
+
+// declaredNames returns the names c's lines of fd declare.
+func declaredNames(fd *git.FileDiff, c Comment) []string {
+	var names []string
+	for _, h := range fd.Hunks {
+		first, last := commentedLines(h.Lines, c)
+		if first < 0 {
+			continue
+		}
+		for _, l := range h.Lines[first : last+1] {
+			if l.Type != git.LineAdded {
+				continue
+			}
+			for _, m := range declaration.FindAllStringSubmatch(l.Content, -1) {
+				if len(m[1]) >= minIdentifier {
+					names = append(names, m[1])
+				}
+			}
+		}
+		break
+	}
+	return names
+}
+
+// Definitions indexes the names the added lines of diffs declare, each to
+// the lines declaring it: the functions, types, and variables a change adds
+// or rewrites.
+func Definitions(diffs []*git.FileDiff) Index {
+	index := make(Index)
+	for _, fd := range diffs {
+		if fd == nil {
+			continue
+		}
+		for _, h := range fd.Hunks {
+			for _, l := range h.Lines {
+				if l.Type != git.LineAdded {
+					continue
+				}
+				for _, m := range declaration.FindAllStringSubmatch(l.Content, -1) {
+					index[m[1]] = append(index[m[1]], Mention{fd.Path, l.NewLineNo})
+				}
+			}
+		}
+	}
+	for _, mentions := range index {
+		slices.SortStableFunc(mentions, func(a, b Mention) int { return strings.Compare(a.Path, b.Path) })
+	}
+	return index
+}
+
+// Calls returns the names line calls, in order, leaving out those it
+// declares, such as a function's own name.
+func Calls(line string) []string {
+	var declared []string
+	for _, m := range declaration.FindAllStringSubmatch(line, -1) {
+		declared = append(declared, m[1])
+	}
+	var names []string
+	for _, m := range call.FindAllStringSubmatch(line, -1) {
+		if !slices.Contains(declared, m[1]) && !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,113 @@
+package comment
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestSeeAlso(t *testing.T) {
+	server := &git.FileDiff{
+		Path: "server.go",
+		Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "package main", Type: git.LineContext, OldLineNo: 1, NewLineNo: 1},
+			{Content: "func (s *Server) parseArgs(args []string) error {", Type: git.LineAdded, NewLineNo: 2},
+			{Content: "const maxRetries = 3", Type: git.LineAdded, NewLineNo: 3},
+			{Content: "func helper() {}", Type: git.LineRemoved, OldLineNo: 2},
+			{Content: "x := 1", Type: git.LineAdded, NewLineNo: 4},
+		}}},
+	}
+	diffs := []*git.FileDiff{
+		server,
+		{Path: "main.go", Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "if err := s.parseArgs(os.Args); err != nil {", Type: git.LineAdded, NewLineNo: 10},
+			{Content: "s.parseArgs(nil)", Type: git.LineAdded, NewLineNo: 14},
+		}}}},
+		{Path: "cli/flags.go", Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "// parseArgs and maxRetries", Type: git.LineContext, OldLineNo: 3, NewLineNo: 4},
+		}}}},
+		{Path: "old.go", Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "parseArgs()", Type: git.LineRemoved, OldLineNo: 7},
+		}}}},
+	}
+	index := NewIndex(diffs)
+	commented := map[string]*git.FileDiff{"server.go": server}
+
+	tests := []struct {
+		name string
+		c    Comment
+		want string
+	}{
+		{
+			name: "function",
+			c:    Comment{FilePath: "server.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "rename?"},
+			want: "rename? (see also `cli/flags.go:4`, `main.go:10`)",
+		},
+		{
+			name: "range declaring two names",
+			c:    Comment{FilePath: "server.go", StartLine: 2, EndLine: 3, LineType: git.LineAdded, Body: "hm"},
+			want: "hm (see also `cli/flags.go:4`, `main.go:10`)",
+		},
+		{
+			name: "no declaration",
+			c:    Comment{FilePath: "server.go", StartLine: 4, EndLine: 4, LineType: git.LineAdded, Body: "why 1?"},
+			want: "why 1?",
+		},
+		{
+			name: "removed line",
+			c:    Comment{FilePath: "server.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "gone"},
+			want: "gone",
+		},
+		{
+			name: "file without a diff",
+			c:    Comment{FilePath: "other.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "ok"},
+			want: "ok",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SeeAlso([]Comment{tt.c}, commented, index)
+			if got[0].Body != tt.want {
+				t.Errorf("body = %q, want %q", got[0].Body, tt.want)
+			}
+			if tt.c.Body == tt.want && !reflect.DeepEqual(got[0], tt.c) {
+				t.Errorf("comment changed to %+v", got[0])
+			}
+		})
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	diffs := []*git.FileDiff{
+		{Path: "server.go", Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "func (s *Server) parseArgs(args []string) error {", Type: git.LineAdded, NewLineNo: 2},
+			{Content: "func helper() {}", Type: git.LineRemoved, OldLineNo: 2},
+			{Content: "func unchanged() {}", Type: git.LineContext, OldLineNo: 3, NewLineNo: 3},
+		}}}},
+		{Path: "cli/args.go", Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "func parseArgs() {}", Type: git.LineAdded, NewLineNo: 7},
+		}}}},
+	}
+	want := Index{"parseArgs": {{"cli/args.go", 7}, {"server.go", 2}}}
+	if got := Definitions(diffs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Definitions = %v, want %v", got, want)
+	}
+}
+
+func TestCalls(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "if err := s.parseArgs(os.Args); err != nil {", want: []string{"parseArgs"}},
+		{line: "x := f(g(1), f(2))", want: []string{"f", "g"}},
+		{line: "func run(args []string) error { return exec(args) }", want: []string{"exec"}},
+		{line: "// no calls here", want: nil},
+	}
+	for _, tt := range tests {
+		if got := Calls(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Calls(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package comment
+
+import "strings"
+
+// Severity is how much a comment matters to the author of the change, from
+// a nit that can be ignored to a blocker that must be fixed before merging.
+type Severity int
+
+const (
+	SeverityNone    Severity = iota // no label saying
+	SeverityNit                     // "nit:", "nitpick:"
+	SeverityIssue                   // "issue:", "bug:", "todo:"
+	SeverityBlocker                 // "blocker:", or any label decorated "(blocking)"
+)
+
+// severityLabels maps the labels comments start with, as in Conventional
+// Comments ("nit: ...", "issue (blocking): ..."), to their severity.
+var severityLabels = map[string]Severity{
+	"nit":      SeverityNit,
+	"nitpick":  SeverityNit,
+	"issue":    SeverityIssue,
+	"bug":      SeverityIssue,
+	"todo":     SeverityIssue,
+	"blocker":  SeverityBlocker,
+	"blocking": SeverityBlocker,
+}
+
+// Severity returns the severity c's body is labeled with.
+func (c Comment) Severity() Severity {
+	label, _, ok := strings.Cut(c.Body, ":")
+	if !ok {
+		return SeverityNone
+	}
+	name, decorations, _ := strings.Cut(strings.ToLower(label), "(")
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " \t") {
+		// Prose with a colon in it, not a label
+		return SeverityNone
+	}
+	for d := range strings.SplitSeq(strings.TrimSuffix(strings.TrimSpace(decorations), ")"), ",") {
+		if strings.TrimSpace(d) == "blocking" {
+			return SeverityBlocker
+		}
+	}
+	return severityLabels[name]
+}
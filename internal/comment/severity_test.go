@@ -0,0 +1,27 @@
+package comment
+
+import "testing"
+
+func TestSeverity(t *testing.T) {
+	tests := []struct {
+		body string
+		want Severity
+	}{
+		{"rename this", SeverityNone},
+		{"nit: trailing space", SeverityNit},
+		{"Nitpick: spacing", SeverityNit},
+		{"issue: leaks the file", SeverityIssue},
+		{"issue (non-blocking): slow", SeverityIssue},
+		{"issue (blocking): data race", SeverityBlocker},
+		{"suggestion (security, blocking): escape it", SeverityBlocker},
+		{"BLOCKER: breaks the build", SeverityBlocker},
+		{"question: why?", SeverityNone},
+		{"this is an issue: see above", SeverityNone},
+		{"see https://example.com", SeverityNone},
+	}
+	for _, tt := range tests {
+		if got := (Comment{Body: tt.body}).Severity(); got != tt.want {
+			t.Errorf("Severity of %q = %d, want %d", tt.body, got, tt.want)
+		}
+	}
+}
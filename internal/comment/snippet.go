@@ -0,0 +1,92 @@
+package comment
+
+import (
+	"strings"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+// FormatSnippets formats comments like Format, following each one with a
+// fenced snippet of the diff it refers to. The snippet shows up to context
+// lines around the commented ones, which are marked with "> ". Comments on
+// files missing from diffs, or on lines their diff doesn't show, get no
+// snippet.
+func FormatSnippets(comments []Comment, diffs map[string]*git.FileDiff, context int) string {
+	context = max(context, 0)
+	return formatWith(comments, MultipleAuthors(comments), func(b *strings.Builder, c Comment) {
+		if fd := diffs[c.FilePath]; fd != nil {
+			writeSnippet(b, fd, c, context)
+		}
+	})
+}
+
+// writeSnippet writes the fenced snippet for c, indented to sit under its
+// list item and tagged with the file's language for highlighting.
+func writeSnippet(b *strings.Builder, fd *git.FileDiff, c Comment, context int) {
+	for _, h := range fd.Hunks {
+		first, last := commentedLines(h.Lines, c)
+		if first < 0 {
+			continue
+		}
+		from := max(first-context, 0)
+		to := min(last+context, len(h.Lines)-1)
+		b.WriteString("  ```" + language(fd.Path, firstLine(fd)) + "\n")
+		for i := from; i <= to; i++ {
+			l := h.Lines[i]
+			if i >= first && i <= last {
+				b.WriteString("  > ")
+			} else {
+				b.WriteString("    ")
+			}
+			b.WriteString(l.Marker())
+			b.WriteString(l.Content)
+			b.WriteByte('\n')
+		}
+		b.WriteString("  ```\n")
+		return
+	}
+}
+
+// firstLine returns the content of the first line of fd's file, new or old,
+// or "" if no hunk shows it.
+func firstLine(fd *git.FileDiff) string {
+	if len(fd.Hunks) == 0 {
+		return ""
+	}
+	for _, l := range fd.Hunks[0].Lines {
+		if l.NewLineNo == 1 || l.OldLineNo == 1 {
+			return l.Content
+		}
+	}
+	return ""
+}
+
+// commentedLines returns the indexes of the first and last of lines that c
+// covers, or -1, -1 if c doesn't start in lines. Lines are numbered as in the
+// diff viewer: removed lines by their old number, others by their new one.
+func commentedLines(lines []git.Line, c Comment) (first, last int) {
+	number := func(l git.Line) int {
+		if l.Type == git.LineRemoved {
+			return l.OldLineNo
+		}
+		return l.NewLineNo
+	}
+	first = -1
+	for i, l := range lines {
+		if number(l) == c.StartLine && (l.Type == git.LineRemoved) == (c.LineType == git.LineRemoved) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return -1, -1
+	}
+	last = first
+	for i := first; i < len(lines); i++ {
+		if number(lines[i]) == c.EndLine {
+			last = i
+			break
+		}
+	}
+	return first, last
+}
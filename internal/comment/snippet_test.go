@@ -0,0 +1,86 @@
+package comment
+
+import (
+	"testing"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestFormatSnippets(t *testing.T) {
+	diffs := map[string]*git.FileDiff{
+		"main.go": {
+			Path: "main.go",
+			Hunks: []git.Hunk{{
+				Lines: []git.Line{
+					{Content: "package main", Type: git.LineContext, OldLineNo: 1, NewLineNo: 1},
+					{Content: "", Type: git.LineContext, OldLineNo: 2, NewLineNo: 2},
+					{Content: "var x = 1", Type: git.LineRemoved, OldLineNo: 3},
+					{Content: "var x = 2", Type: git.LineAdded, NewLineNo: 3},
+					{Content: "var y = 3", Type: git.LineAdded, NewLineNo: 4},
+					{Content: "", Type: git.LineContext, OldLineNo: 4, NewLineNo: 5},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		c       Comment
+		context int
+		want    string
+	}{
+		{
+			name: "no context",
+			c:    Comment{FilePath: "main.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "why 2?"},
+			want: "main.go\n- new L3 (added): why 2?\n  ```go\n  > +var x = 2\n  ```\n",
+		},
+		{
+			name:    "context around a range",
+			c:       Comment{FilePath: "main.go", StartLine: 3, EndLine: 4, LineType: git.LineAdded, Body: "group these"},
+			context: 1,
+			want:    "main.go\n- new L3-4 (added): group these\n  ```go\n    -var x = 1\n  > +var x = 2\n  > +var y = 3\n     \n  ```\n",
+		},
+		{
+			name:    "context stops at the hunk",
+			c:       Comment{FilePath: "main.go", StartLine: 3, EndLine: 3, LineType: git.LineRemoved, Body: "gone"},
+			context: 5,
+			want:    "main.go\n- old L3 (removed): gone\n  ```go\n     package main\n     \n  > -var x = 1\n    +var x = 2\n    +var y = 3\n     \n  ```\n",
+		},
+		{
+			name: "line outside the diff",
+			c:    Comment{FilePath: "main.go", StartLine: 40, EndLine: 40, Body: "later"},
+			want: "main.go\n- new L40: later\n",
+		},
+		{
+			name: "file without a diff",
+			c:    Comment{FilePath: "other.go", StartLine: 1, EndLine: 1, Body: "hm"},
+			want: "other.go\n- new L1: hm\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatSnippets([]Comment{tt.c}, diffs, tt.context); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSnippetsShebang(t *testing.T) {
+	diffs := map[string]*git.FileDiff{
+		"bin/deploy": {
+			Path: "bin/deploy",
+			Hunks: []git.Hunk{{
+				Lines: []git.Line{
+					{Content: "#!/usr/bin/env bash", Type: git.LineContext, OldLineNo: 1, NewLineNo: 1},
+					{Content: "set -e", Type: git.LineAdded, NewLineNo: 2},
+				},
+			}},
+		},
+	}
+	c := Comment{FilePath: "bin/deploy", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "and -u?"}
+	want := "bin/deploy\n- new L2 (added): and -u?\n  ```bash\n  > +set -e\n  ```\n"
+	if got := FormatSnippets([]Comment{c}, diffs, 0); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
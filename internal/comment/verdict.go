@@ -0,0 +1,90 @@
+package comment
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FileVerdict is the reviewer's call on a whole file.
+type FileVerdict int
+
+const (
+	FileUnjudged    FileVerdict = iota // no call made
+	FileLGTM                           // looks good as it is
+	FileNeedsWork                      // has to change before merging
+	FileNotReviewed                    // deliberately left for someone else
+)
+
+// fileVerdictNames are how verdicts are saved in sessions.
+var fileVerdictNames = map[FileVerdict]string{
+	FileLGTM:        "lgtm",
+	FileNeedsWork:   "needs-work",
+	FileNotReviewed: "not-reviewed",
+}
+
+// String returns how the verdict reads in the review.
+func (v FileVerdict) String() string {
+	switch v {
+	case FileLGTM:
+		return "LGTM"
+	case FileNeedsWork:
+		return "needs work"
+	case FileNotReviewed:
+		return "didn't review"
+	}
+	return ""
+}
+
+// Next returns the verdict after v in the order a key cycles through them,
+// back to FileUnjudged after the last.
+func (v FileVerdict) Next() FileVerdict {
+	return (v + 1) % (FileNotReviewed + 1)
+}
+
+func (v FileVerdict) MarshalText() ([]byte, error) {
+	name, ok := fileVerdictNames[v]
+	if !ok {
+		return nil, fmt.Errorf("no name for file verdict %d", v)
+	}
+	return []byte(name), nil
+}
+
+func (v *FileVerdict) UnmarshalText(text []byte) error {
+	for verdict, name := range fileVerdictNames {
+		if string(text) == name {
+			*v = verdict
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown file verdict %q", text)
+}
+
+// FormatVerdicts returns a Markdown table of the files given a verdict, in
+// path order, with how many of comments each has, to head a review. It
+// returns "" when no file has one.
+func FormatVerdicts(verdicts map[string]FileVerdict, comments []Comment) string {
+	var paths []string
+	for path, v := range verdicts {
+		if v != FileUnjudged {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return ""
+	}
+	slices.Sort(paths)
+	counts := make(map[string]int)
+	for _, c := range comments {
+		counts[c.FilePath]++
+	}
+
+	var b strings.Builder
+	b.WriteString("| File | Verdict | Comments |\n")
+	b.WriteString("|------|---------|----------|\n")
+	for _, path := range paths {
+		b.WriteString("| `" + path + "` | " + verdicts[path].String() + " | " + strconv.Itoa(counts[path]) + " |\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package comment
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFormatVerdicts(t *testing.T) {
+	verdicts := map[string]FileVerdict{
+		"b.go":      FileNeedsWork,
+		"a.go":      FileLGTM,
+		"gen.pb.go": FileNotReviewed,
+		"c.go":      FileUnjudged,
+	}
+	comments := []Comment{{FilePath: "b.go"}, {FilePath: "b.go"}, {FilePath: "c.go"}}
+	want := "| File | Verdict | Comments |\n" +
+		"|------|---------|----------|\n" +
+		"| `a.go` | LGTM | 0 |\n" +
+		"| `b.go` | needs work | 2 |\n" +
+		"| `gen.pb.go` | didn't review | 0 |\n"
+	if got := FormatVerdicts(verdicts, comments); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := FormatVerdicts(map[string]FileVerdict{"c.go": FileUnjudged}, comments); got != "" {
+		t.Errorf("no verdicts should format as nothing, got %q", got)
+	}
+}
+
+func TestFileVerdictJSON(t *testing.T) {
+	in := map[string]FileVerdict{"a.go": FileLGTM, "b.go": FileNeedsWork, "c.go": FileNotReviewed}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a.go":"lgtm","b.go":"needs-work","c.go":"not-reviewed"}`; string(data) != want {
+		t.Errorf("marshaled %s, want %s", data, want)
+	}
+	var out map[string]FileVerdict
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip gave %v, want %v", out, in)
+	}
+	if err := json.Unmarshal([]byte(`{"a.go":"meh"}`), &out); err == nil {
+		t.Error("an unknown verdict should be an error")
+	}
+}
+
+func TestFileVerdictNext(t *testing.T) {
+	var got []FileVerdict
+	v := FileUnjudged
+	for range 5 {
+		v = v.Next()
+		got = append(got, v)
+	}
+	want := []FileVerdict{FileLGTM, FileNeedsWork, FileNotReviewed, FileUnjudged, FileLGTM}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
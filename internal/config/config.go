@@ -0,0 +1,128 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deparker/revui/internal/output"
+)
+
+// Config holds user preferences read from the revui config file.
+type Config struct {
+	// AutoAdvance moves the cursor to the next change after a comment is
+	// submitted, for quick passes over many small nits.
+	AutoAdvance bool `json:"auto_advance"`
+
+	// FallbackToFile saves the review to a file when delivery to the chosen
+	// target fails, so a vanished tmux pane never loses a review.
+	FallbackToFile bool `json:"fallback_to_file"`
+
+	// FileSort is the initial file list order: path (the default), status,
+	// churn, or directory.
+	FileSort string `json:"file_sort,omitempty"`
+
+	// SnippetContext, when set, follows each comment in the review output with
+	// the code it refers to and this many lines of context around it.
+	SnippetContext *int `json:"snippet_context,omitempty"`
+
+	// SeeAlso follows comments on added functions, types, and variables with
+	// the other files in the change that mention them.
+	SeeAlso bool `json:"see_also"`
+
+	// FileNumbers numbers the files in the file list, for jumping to one by
+	// typing its number and Enter.
+	FileNumbers bool `json:"file_numbers"`
+
+	// Dictionary is the word list comments are spell checked against: one
+	// word per line, or a hunspell .dic file. Unset uses the system's word
+	// list, if it has one; "none" turns spell checking off.
+	Dictionary string `json:"dictionary,omitempty"`
+
+	// ReviewPace is the lines per minute the review time estimate in the
+	// header assumes; zero uses the default of 10.
+	ReviewPace int `json:"review_pace,omitempty"`
+
+	// SummarizeCommand is a shell command that's given a hunk, as a patch, on
+	// stdin and prints a short summary of it, such as an LLM's command line
+	// tool, for getting oriented in a large hunk before reviewing it.
+	SummarizeCommand string `json:"summarize_command,omitempty"`
+
+	// ClaudePrompt is the instructions a review sent to Claude comes with,
+	// such as how to address the comments. The review goes in place of
+	// {review}, or after the prompt when it has none.
+	ClaudePrompt string `json:"claude_prompt,omitempty"`
+
+	// ClaudeSubmit presses Enter after sending a review to a Claude pane, so
+	// Claude starts on it without switching to the pane.
+	ClaudeSubmit bool `json:"claude_submit"`
+
+	// TempDir is the directory reviews sent to Claude and files written
+	// without a name go in, such as a private one on a shared machine; empty
+	// uses the system's temp directory.
+	TempDir string `json:"temp_dir,omitempty"`
+
+	// KeepTempDays is how many days the files reviews are handed to Claude
+	// in, and review files written under the default name, are kept in
+	// TempDir before revui removes them; zero uses the default of 7, and a
+	// negative number keeps them until removed with revui clean.
+	KeepTempDays int `json:"keep_temp_days,omitempty"`
+
+	// Exclude lists gitignore-style patterns for untracked files to hide from
+	// uncommitted changes, such as local env files a team doesn't gitignore.
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Webhooks are offered as output targets when finishing a review.
+	Webhooks []output.Webhook `json:"webhooks,omitempty"`
+
+	// Slack destinations are offered as output targets when finishing a review.
+	Slack []output.Slack `json:"slack,omitempty"`
+
+	// Bitbucket repositories are offered as output targets that comment on
+	// the reviewed branch's pull request.
+	Bitbucket []output.Bitbucket `json:"bitbucket,omitempty"`
+
+	// Gerrit servers are offered as output targets that post the comments as
+	// a review of the change being reviewed.
+	Gerrit []output.Gerrit `json:"gerrit,omitempty"`
+
+	// AzureDevOps sets the token, and overrides the repository detected from
+	// the origin remote, for commenting on Azure DevOps pull requests.
+	AzureDevOps *output.AzureDevOps `json:"azure_devops,omitempty"`
+
+	// GitHub sets the token, and overrides the repository detected from the
+	// origin remote, for reviewing GitHub pull requests.
+	GitHub *output.GitHub `json:"github,omitempty"`
+
+	// GitLab sets the token, and overrides the project detected from the
+	// origin remote, for commenting on GitLab merge requests.
+	GitLab *output.GitLab `json:"gitlab,omitempty"`
+}
+
+// Path returns the config file location, revui/config.json under the user's
+// config directory (e.g. ~/.config on Linux).
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "revui", "config.json"), nil
+}
+
+// Load reads the config from path. A missing file yields the defaults.
+func Load(path string) (*Config, error) {
+	var c Config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/deparker/revui/internal/output"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	two := 2
+
+	tests := []struct {
+		name    string
+		content string // empty means no file
+		want    Config
+		wantErr bool
+	}{
+		{name: "missing file uses defaults"},
+		{name: "auto advance", content: `{"auto_advance": true}`, want: Config{AutoAdvance: true}},
+		{name: "file sort", content: `{"file_sort": "churn"}`, want: Config{FileSort: "churn"}},
+		{name: "snippet context", content: `{"snippet_context": 2}`, want: Config{SnippetContext: &two}},
+		{name: "file numbers", content: `{"file_numbers": true}`, want: Config{FileNumbers: true}},
+		{name: "review pace", content: `{"review_pace": 25}`, want: Config{ReviewPace: 25}},
+		{name: "exclude", content: `{"exclude": [".env.local", "build/"]}`, want: Config{Exclude: []string{".env.local", "build/"}}},
+		{name: "unknown keys ignored", content: `{"future": 1}`},
+		{
+			name:    "webhooks",
+			content: `{"webhooks": [{"name": "bot", "url": "https://example.com/hook", "secret": "s", "headers": {"X-Team": "a"}}]}`,
+			want: Config{Webhooks: []output.Webhook{
+				{Name: "bot", URL: "https://example.com/hook", Secret: "s", Headers: map[string]string{"X-Team": "a"}},
+			}},
+		},
+		{
+			name:    "bitbucket",
+			content: `{"bitbucket": [{"workspace": "team", "repo": "app", "username": "me", "token": "app-pass"}]}`,
+			want:    Config{Bitbucket: []output.Bitbucket{{Workspace: "team", Repo: "app", Username: "me", Token: "app-pass"}}},
+		},
+		{
+			name:    "gerrit",
+			content: `{"gerrit": [{"url": "https://review.example.com", "labels": {"Code-Review": -1}}]}`,
+			want:    Config{Gerrit: []output.Gerrit{{URL: "https://review.example.com", Labels: map[string]int{"Code-Review": -1}}}},
+		},
+		{
+			name:    "azure devops",
+			content: `{"azure_devops": {"token": "pat"}}`,
+			want:    Config{AzureDevOps: &output.AzureDevOps{Token: "pat"}},
+		},
+		{
+			name:    "github",
+			content: `{"github": {"owner": "acme", "repo": "api"}}`,
+			want:    Config{GitHub: &output.GitHub{Owner: "acme", Repo: "api"}},
+		},
+		{
+			name:    "gitlab",
+			content: `{"gitlab": {"url": "https://gitlab.acme.com", "project": "acme/platform/api"}}`,
+			want:    Config{GitLab: &output.GitLab{URL: "https://gitlab.acme.com", Project: "acme/platform/api"}},
+		},
+		{name: "invalid json", content: `{`, wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i))+".json")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// State is what revui remembers between runs. It is kept apart from Config
+// so revui never rewrites the user's config file.
+type State struct {
+	// LastFileDir is the directory a review was last written to.
+	LastFileDir string `json:"last_file_dir,omitempty"`
+}
+
+// StatePath returns the state file location, revui/state.json under the
+// user's config directory.
+func StatePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "revui", "state.json"), nil
+}
+
+// LoadState reads the state from path. A missing file yields an empty state.
+func LoadState(path string) (*State, error) {
+	var s State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing state %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes the state to path, creating its directory if needed.
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
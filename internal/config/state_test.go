@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revui", "state.json")
+
+	s, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("missing state file: %v", err)
+	}
+	if s.LastFileDir != "" {
+		t.Errorf("missing state file should be empty, got %+v", s)
+	}
+
+	s.LastFileDir = "/home/me/reviews"
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastFileDir != s.LastFileDir {
+		t.Errorf("LastFileDir = %q, want %q", got.LastFileDir, s.LastFileDir)
+	}
+}
+
+func TestLoadStateInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadState(path); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,464 @@
+// Package deps summarizes the changes a diff makes to a dependency manifest,
+// such as go.mod or package.json: the dependencies it adds and removes, and
+// those it moves to another version.
+package deps
+
+import (
+	"cmp"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+// Kind is what happened to a dependency.
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Upgraded
+	Downgraded
+	Changed // to a version that can't be compared, or only in scope
+)
+
+// Change is a change to one dependency.
+type Change struct {
+	Name     string
+	OldName  string // the name before, if it changed, as a Go module's does with its major version
+	Kind     Kind
+	Old, New string // versions as the manifest gives them, such as ^1.2.0
+	Scope    string // as in "indirect" or "dev"; empty for a direct dependency, or when the diff doesn't say
+	OldScope string // the scope before, for a change
+	Major    bool   // the major version changed
+}
+
+// Summary is what a manifest's diff changes.
+type Summary struct {
+	Manifest string // the manifest's file name, as in go.mod
+	Changes  []Change
+	Other    int // changed lines that aren't dependencies, such as a go directive
+}
+
+// Counts returns how many changes there are of each kind, and how many of
+// them change the major version.
+func (s Summary) Counts() (kinds map[Kind]int, major int) {
+	kinds = make(map[Kind]int)
+	for _, c := range s.Changes {
+		kinds[c.Kind]++
+		if c.Major {
+			major++
+		}
+	}
+	return kinds, major
+}
+
+// entry is a dependency as a line of a manifest declares it.
+type entry struct {
+	name, version, scope string
+}
+
+// reader returns the dependency a line of a manifest declares, if it
+// declares one. section carries what the lines of the hunk read so far say
+// about where in the file they are, such as the block or table they're in;
+// it starts out empty, for unknown.
+type reader func(line string, section *string) (entry, bool)
+
+// readerFor returns the reader for the manifest at p, if it's one this
+// package understands.
+func readerFor(p string) (reader, bool) {
+	switch base := path.Base(p); {
+	case base == "go.mod":
+		return readGoMod, true
+	case base == "go.sum":
+		return readGoSum, true
+	case base == "package.json":
+		return readPackageJSON, true
+	case base == "Cargo.toml":
+		return readCargoToml, true
+	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+		return readRequirements, true
+	}
+	return nil, false
+}
+
+// Summarize returns the summary of fd, if it's the diff of a dependency
+// manifest this package understands that changes at least one dependency.
+func Summarize(fd *git.FileDiff) (Summary, bool) {
+	read, ok := readerFor(fd.Path)
+	if !ok {
+		return Summary{}, false
+	}
+	s := Summary{Manifest: path.Base(fd.Path)}
+	removed, added := make(map[string][]entry), make(map[string][]entry)
+	for _, h := range fd.Hunks {
+		section := ""
+		for _, l := range h.Lines {
+			before := section
+			e, ok := read(l.Content, &section)
+			switch {
+			case l.Type == git.LineContext:
+			case ok && l.Type == git.LineRemoved:
+				removed[e.name] = append(removed[e.name], e)
+			case ok:
+				added[e.name] = append(added[e.name], e)
+			case section == before && strings.Trim(l.Content, "(){}[], \t") != "":
+				// Not a section header, blank, or bracket
+				s.Other++
+			}
+		}
+	}
+	var base func(string) string
+	if s.Manifest == "go.mod" || s.Manifest == "go.sum" {
+		base = goModuleBase
+	}
+	s.Changes = pair(removed, added, base)
+	return s, len(s.Changes) > 0
+}
+
+// pair matches the dependencies removed with those added, by name or, when
+// base isn't nil, by the name base returns, and returns the changes.
+func pair(removed, added map[string][]entry, base func(string) string) []Change {
+	var changes []Change
+	for name, olds := range removed {
+		if news, ok := added[name]; ok {
+			if c, ok := change(olds, news); ok {
+				changes = append(changes, c)
+			}
+			delete(removed, name)
+			delete(added, name)
+		}
+	}
+	if base != nil {
+		// A name that changed, paired only when nothing else shares its base
+		byBase := make(map[string][]string)
+		for name := range added {
+			byBase[base(name)] = append(byBase[base(name)], name)
+		}
+		for name, olds := range removed {
+			news := byBase[base(name)]
+			if len(news) != 1 {
+				continue
+			}
+			c, _ := change(olds, added[news[0]])
+			c.Name, c.OldName, c.Major = news[0], name, true
+			changes = append(changes, c)
+			delete(removed, name)
+			delete(added, news[0])
+		}
+	}
+	for _, olds := range removed {
+		e := highest(olds)
+		changes = append(changes, Change{Name: e.name, Kind: Removed, Old: e.version, Scope: e.scope})
+	}
+	for _, news := range added {
+		e := highest(news)
+		changes = append(changes, Change{Name: e.name, Kind: Added, New: e.version, Scope: e.scope})
+	}
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return changes
+}
+
+// change returns the change from the declarations olds of a dependency to
+// news, leaving out versions on both sides, as when a line only moves, and
+// whether anything changed.
+func change(olds, news []entry) (Change, bool) {
+	keptScope := make(map[string]string)
+	for _, n := range news {
+		keptScope[n.version] = n.scope
+	}
+	kept := func(e entry) bool {
+		_, ok := keptScope[e.version]
+		return ok
+	}
+	name := olds[0].name
+	rescoped := false
+	for _, o := range olds {
+		if s, ok := keptScope[o.version]; ok && s != o.scope {
+			rescoped = true
+		}
+	}
+	oldVersions := slices.DeleteFunc(slices.Clone(olds), kept)
+	newVersions := slices.DeleteFunc(slices.Clone(news), func(e entry) bool {
+		return slices.ContainsFunc(olds, func(o entry) bool { return o.version == e.version })
+	})
+	switch {
+	case len(oldVersions) == 0 && len(newVersions) == 0:
+		if !rescoped {
+			return Change{}, false
+		}
+		o, n := highest(olds), highest(news)
+		return Change{Name: name, Kind: Changed, Old: o.version, New: n.version, Scope: n.scope, OldScope: o.scope}, true
+	case len(oldVersions) == 0:
+		n := highest(newVersions)
+		return Change{Name: name, Kind: Added, New: n.version, Scope: n.scope}, true
+	case len(newVersions) == 0:
+		o := highest(oldVersions)
+		return Change{Name: name, Kind: Removed, Old: o.version, Scope: o.scope}, true
+	}
+	o, n := highest(oldVersions), highest(newVersions)
+	c := Change{Name: name, Kind: Changed, Old: o.version, New: n.version, Scope: n.scope, OldScope: o.scope}
+	ov, ok1 := parseVersion(o.version)
+	nv, ok2 := parseVersion(n.version)
+	if ok1 && ok2 {
+		switch compareVersions(ov, nv) {
+		case -1:
+			c.Kind = Upgraded
+		case 1:
+			c.Kind = Downgraded
+		}
+		c.Major = ov.nums[0] != nv.nums[0]
+	}
+	return c, true
+}
+
+// highest returns the entry with the highest version of entries, or the last
+// if their versions can't be compared.
+func highest(entries []entry) entry {
+	best := entries[len(entries)-1]
+	bv, ok := parseVersion(best.version)
+	for _, e := range entries {
+		if v, vok := parseVersion(e.version); vok && ok && compareVersions(v, bv) > 0 {
+			best, bv = e, v
+		}
+	}
+	return best
+}
+
+// version is a version's numbers and pre-release, as in 1.2.3-rc.1.
+type version struct {
+	nums []int
+	pre  string
+}
+
+// parseVersion parses the version s names or starts a range at, as in
+// v1.2.3, ^1.2, or >=2.0.
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimLeft(s, "^~=<>! v")
+	s, _, _ = strings.Cut(s, "+") // build metadata, or Go's +incompatible
+	s, pre, _ := strings.Cut(s, "-")
+	var v version
+	for part := range strings.SplitSeq(s, ".") {
+		if part == "x" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return version{}, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	if len(v.nums) == 0 {
+		return version{}, false
+	}
+	v.pre = pre
+	return v, true
+}
+
+// compareVersions returns -1, 0, or 1 as a is lower than, the same as, or
+// higher than b. A pre-release comes before its release.
+func compareVersions(a, b version) int {
+	for i := range max(len(a.nums), len(b.nums)) {
+		x, y := 0, 0
+		if i < len(a.nums) {
+			x = a.nums[i]
+		}
+		if i < len(b.nums) {
+			y = b.nums[i]
+		}
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a.pre == b.pre:
+		return 0
+	case a.pre == "":
+		return 1
+	case b.pre == "":
+		return -1
+	}
+	return strings.Compare(a.pre, b.pre)
+}
+
+// goMajorSuffix is the major version at the end of a Go module path, as in
+// example.com/mod/v2 or gopkg.in/yaml.v3.
+var goMajorSuffix = regexp.MustCompile(`[/.]v[0-9]+$`)
+
+// goModuleBase returns a Go module path without its major version.
+func goModuleBase(p string) string {
+	return goMajorSuffix.ReplaceAllString(p, "")
+}
+
+// readGoMod reads a requirement, in a require block or on its own line, as
+// in "example.com/mod v1.2.3 // indirect". section is the block the line is
+// in.
+func readGoMod(line string, section *string) (entry, bool) {
+	code, comment, _ := strings.Cut(line, "//")
+	f := strings.Fields(code)
+	switch {
+	case len(f) == 2 && f[1] == "(":
+		*section = f[0]
+		return entry{}, false
+	case len(f) == 1 && f[0] == ")":
+		*section = "top"
+		return entry{}, false
+	case len(f) == 3 && f[0] == "require":
+		f = f[1:]
+	case len(f) != 2 || (*section != "" && *section != "require"):
+		return entry{}, false
+	}
+	if !strings.HasPrefix(f[1], "v") || !strings.ContainsAny(f[0], "./") {
+		return entry{}, false
+	}
+	e := entry{name: f[0], version: f[1]}
+	if strings.HasPrefix(strings.TrimSpace(comment), "indirect") {
+		e.scope = "indirect"
+	}
+	return e, true
+}
+
+// readGoSum reads the module version a checksum is for, as in
+// "example.com/mod v1.2.3/go.mod h1:...".
+func readGoSum(line string, _ *string) (entry, bool) {
+	f := strings.Fields(line)
+	if len(f) != 3 || !strings.HasPrefix(f[2], "h1:") {
+		return entry{}, false
+	}
+	return entry{name: f[0], version: strings.TrimSuffix(f[1], "/go.mod")}, true
+}
+
+var (
+	jsonObject = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*\{\s*$`)
+	jsonString = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*"([^"]*)",?\s*$`)
+)
+
+// npmScopes are the scopes of package.json's dependency objects.
+var npmScopes = map[string]string{
+	"dependencies":         "",
+	"devDependencies":      "dev",
+	"peerDependencies":     "peer",
+	"optionalDependencies": "optional",
+}
+
+// readPackageJSON reads a dependency in package.json, as in
+// `"lodash": "^4.17.21",`. section is the object the line is in, prefixed
+// "deps:" for a dependency object. Outside any object the diff shows, a
+// string that looks like a version is taken for a dependency.
+func readPackageJSON(line string, section *string) (entry, bool) {
+	if m := jsonObject.FindStringSubmatch(line); m != nil {
+		*section = "other"
+		if scope, ok := npmScopes[m[1]]; ok {
+			*section = "deps:" + scope
+		}
+		return entry{}, false
+	}
+	if t := strings.TrimSpace(line); t == "}" || t == "}," {
+		*section = "other"
+		return entry{}, false
+	}
+	m := jsonString.FindStringSubmatch(line)
+	if m == nil {
+		return entry{}, false
+	}
+	scope, ok := strings.CutPrefix(*section, "deps:")
+	if !ok {
+		if _, isVersion := parseVersion(m[2]); *section != "" || !isVersion || m[1] == "version" {
+			return entry{}, false
+		}
+	}
+	return entry{name: m[1], version: m[2], scope: scope}, true
+}
+
+var (
+	tomlTable      = regexp.MustCompile(`^\s*\[+([^\]]+)\]+\s*$`)
+	tomlKey        = regexp.MustCompile(`^\s*([A-Za-z0-9_-]+)\s*=\s*(.+?)\s*$`)
+	tomlString     = regexp.MustCompile(`^"([^"]*)"$`)
+	tomlVersionKey = regexp.MustCompile(`\bversion\s*=\s*"([^"]*)"`)
+)
+
+// cargoScopes are the scopes of Cargo.toml's dependency tables, which may
+// also be a target's, as in target.'cfg(unix)'.dependencies.
+var cargoScopes = map[string]string{
+	"dependencies":       "",
+	"dev-dependencies":   "dev",
+	"build-dependencies": "build",
+}
+
+// readCargoToml reads a dependency in Cargo.toml, as in `serde = "1.0"` or
+// `serde = { version = "1.0", features = ["derive"] }`, or the version of one
+// given a table of its own, as in [dependencies.serde]. section is the table
+// the line is in, prefixed "deps:" for a table of dependencies and "dep:"
+// for a dependency's own. Outside any table the diff shows, a value that
+// looks like a version is taken for a dependency.
+func readCargoToml(line string, section *string) (entry, bool) {
+	if m := tomlTable.FindStringSubmatch(line); m != nil {
+		*section = "other"
+		parts := strings.Split(m[1], ".")
+		for i, part := range parts {
+			if scope, ok := cargoScopes[part]; ok {
+				*section = "deps:" + scope
+				if i+1 < len(parts) {
+					*section = "dep:" + scope + ":" + parts[i+1]
+				}
+				break
+			}
+		}
+		return entry{}, false
+	}
+	m := tomlKey.FindStringSubmatch(line)
+	if m == nil {
+		return entry{}, false
+	}
+	key, value := m[1], m[2]
+	if rest, ok := strings.CutPrefix(*section, "dep:"); ok {
+		scope, name, _ := strings.Cut(rest, ":")
+		if key != "version" {
+			return entry{}, false
+		}
+		v := tomlString.FindStringSubmatch(value)
+		if v == nil {
+			return entry{}, false
+		}
+		return entry{name: name, version: v[1], scope: scope}, true
+	}
+	var version string
+	if v := tomlString.FindStringSubmatch(value); v != nil {
+		version = v[1]
+	} else if v := tomlVersionKey.FindStringSubmatch(value); v != nil && strings.HasPrefix(value, "{") {
+		version = v[1]
+	} else if !strings.HasPrefix(value, "{") {
+		return entry{}, false
+	}
+	scope, ok := strings.CutPrefix(*section, "deps:")
+	if !ok {
+		if _, isVersion := parseVersion(version); *section != "" || !isVersion || key == "version" {
+			return entry{}, false
+		}
+	}
+	return entry{name: key, version: version, scope: scope}, true
+}
+
+// requirement is a line of a pip requirements file, as in
+// "requests[security]==2.31.0 ; python_version >= '3.8'".
+var requirement = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*(?:(?:===|==|~=|>=|<=|!=|>|<)\s*([^\s,;#]+))?`)
+
+// readRequirements reads a requirement in a pip requirements file, its
+// name normalized as pip does.
+func readRequirements(line string, _ *string) (entry, bool) {
+	t := strings.TrimSpace(line)
+	if t == "" || strings.HasPrefix(t, "#") || strings.HasPrefix(t, "-") {
+		return entry{}, false
+	}
+	m := requirement.FindStringSubmatch(t)
+	if m == nil {
+		return entry{}, false
+	}
+	name := strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(m[1]))
+	return entry{name: name, version: m[2]}, true
+}
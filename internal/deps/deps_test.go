@@ -0,0 +1,202 @@
+package deps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+// diff builds a one-hunk diff of path from lines marked as in a unified
+// diff: "-" removed, "+" added, " " context.
+func diff(path string, lines ...string) *git.FileDiff {
+	var h git.Hunk
+	for _, l := range lines {
+		t := git.LineContext
+		switch l[0] {
+		case '-':
+			t = git.LineRemoved
+		case '+':
+			t = git.LineAdded
+		}
+		h.Lines = append(h.Lines, git.Line{Type: t, Content: l[1:]})
+	}
+	return &git.FileDiff{Path: path, Hunks: []git.Hunk{h}}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name  string
+		fd    *git.FileDiff
+		want  []Change
+		other int
+	}{
+		{
+			name: "go.mod",
+			fd: diff("go.mod",
+				" require (",
+				"-\tgithub.com/a/up v1.2.0",
+				"+\tgithub.com/a/up v1.3.0",
+				"-\tgithub.com/b/gone v0.1.0 // indirect",
+				"+\tgithub.com/c/new v0.4.0 // indirect",
+				"-\tgithub.com/d/mod v1.9.0",
+				"+\tgithub.com/d/mod/v2 v2.1.0",
+				"-\tgolang.org/x/sys v0.20.0 // indirect",
+				"+\tgolang.org/x/sys v0.20.0",
+				" )",
+				"-go 1.22",
+				"+go 1.23",
+			),
+			want: []Change{
+				{Name: "github.com/a/up", Kind: Upgraded, Old: "v1.2.0", New: "v1.3.0"},
+				{Name: "github.com/b/gone", Kind: Removed, Old: "v0.1.0", Scope: "indirect"},
+				{Name: "github.com/c/new", Kind: Added, New: "v0.4.0", Scope: "indirect"},
+				{Name: "github.com/d/mod/v2", OldName: "github.com/d/mod", Kind: Upgraded, Old: "v1.9.0", New: "v2.1.0", Major: true},
+				{Name: "golang.org/x/sys", Kind: Changed, Old: "v0.20.0", New: "v0.20.0", OldScope: "indirect"},
+			},
+			other: 2,
+		},
+		{
+			name: "go.mod replace block",
+			fd: diff("go.mod",
+				" replace (",
+				"-\tgithub.com/a/up v1.2.0",
+				"+\tgithub.com/a/up v1.3.0",
+				" )",
+				"-require github.com/e/one v3.0.0+incompatible",
+				"+require github.com/e/one v2.5.0+incompatible",
+			),
+			want: []Change{
+				{Name: "github.com/e/one", Kind: Downgraded, Old: "v3.0.0+incompatible", New: "v2.5.0+incompatible", Major: true},
+			},
+			other: 2,
+		},
+		{
+			name: "go.sum",
+			fd: diff("go.sum",
+				"-github.com/a/up v1.2.0 h1:aaa=",
+				"-github.com/a/up v1.2.0/go.mod h1:bbb=",
+				"+github.com/a/up v1.3.0 h1:ccc=",
+				"+github.com/a/up v1.3.0/go.mod h1:ddd=",
+				" github.com/f/same v1.0.0 h1:eee=",
+			),
+			want: []Change{
+				{Name: "github.com/a/up", Kind: Upgraded, Old: "v1.2.0", New: "v1.3.0"},
+			},
+		},
+		{
+			name: "package.json",
+			fd: diff("package.json",
+				`   "devDependencies": {`,
+				`-    "jest": "^28.1.0",`,
+				`+    "jest": "^29.0.0",`,
+				`   },`,
+				`   "dependencies": {`,
+				`+    "left-pad": "~1.3.0",`,
+				`     "react": "^18.2.0"`,
+			),
+			want: []Change{
+				{Name: "jest", Kind: Upgraded, Old: "^28.1.0", New: "^29.0.0", Scope: "dev", OldScope: "dev", Major: true},
+				{Name: "left-pad", Kind: Added, New: "~1.3.0"},
+			},
+		},
+		{
+			name: "package.json without the object in view",
+			fd: diff("package.json",
+				`-  "version": "1.0.0",`,
+				`+  "version": "1.1.0",`,
+				`-    "lodash": "^4.17.20",`,
+				`+    "lodash": "^4.17.21",`,
+				`+    "build": "tsc",`,
+			),
+			want: []Change{
+				{Name: "lodash", Kind: Upgraded, Old: "^4.17.20", New: "^4.17.21"},
+			},
+			other: 3,
+		},
+		{
+			name: "Cargo.toml",
+			fd: diff("Cargo.toml",
+				" [dependencies]",
+				`-serde = { version = "1.0.180", features = ["derive"] }`,
+				`+serde = { version = "1.0.190", features = ["derive"] }`,
+				`+local = { path = "../local" }`,
+				" [dev-dependencies.criterion]",
+				`-version = "0.4"`,
+				`+version = "0.5"`,
+			),
+			want: []Change{
+				{Name: "criterion", Kind: Upgraded, Old: "0.4", New: "0.5", Scope: "dev", OldScope: "dev"},
+				{Name: "local", Kind: Added},
+				{Name: "serde", Kind: Upgraded, Old: "1.0.180", New: "1.0.190"},
+			},
+		},
+		{
+			name: "requirements.txt",
+			fd: diff("requirements-dev.txt",
+				"-Django==4.2.7",
+				"+django==5.0",
+				"+typing_extensions>=4.8 ; python_version < '3.11'",
+				"+# pinned for CI",
+				" -r requirements.txt",
+			),
+			want: []Change{
+				{Name: "django", Kind: Upgraded, Old: "4.2.7", New: "5.0", Major: true},
+				{Name: "typing-extensions", Kind: Added, New: "4.8"},
+			},
+			other: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := Summarize(tt.fd)
+			if !ok {
+				t.Fatal("not summarized")
+			}
+			if !reflect.DeepEqual(s.Changes, tt.want) {
+				t.Errorf("changes =\n%+v\nwant\n%+v", s.Changes, tt.want)
+			}
+			if s.Other != tt.other {
+				t.Errorf("other = %d, want %d", s.Other, tt.other)
+			}
+		})
+	}
+}
+
+func TestSummarizeNotAManifest(t *testing.T) {
+	for _, fd := range []*git.FileDiff{
+		diff("main.go", "+require github.com/a/b v1.0.0"),
+		diff("go.mod", "-go 1.22", "+go 1.23"),
+		diff("go.mod", "-\tgithub.com/a/b v1.0.0", "+\tgithub.com/a/b v1.0.0"),
+	} {
+		if s, ok := Summarize(fd); ok {
+			t.Errorf("%s %s: summarized as %+v", fd.Path, strings.TrimSpace(fd.Hunks[0].Lines[0].Content), s)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.10.0", -1},
+		{"^2.0", "2.0.0", 0},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"v0.0.0-20240101000000-abc", "v0.0.0-20230101000000-def", 1},
+		{">=3", "2.9", 1},
+		{"1.x", "1", 0},
+	}
+	for _, tt := range tests {
+		a, aok := parseVersion(tt.a)
+		b, bok := parseVersion(tt.b)
+		if !aok || !bok {
+			t.Errorf("parseVersion(%q) or (%q) failed", tt.a, tt.b)
+			continue
+		}
+		if got := compareVersions(a, b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package difftest provides large diffs for benchmarks: a generator for
+// diffs of any size, and a loader for diffs captured from real repositories
+// with `revui bench --capture`.
+package difftest
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Lines is the size of the generated diff benchmarks run against, in lines
+// of hunks: about what a large vendored dependency update comes to.
+const Lines = 50000
+
+// Dir is where captured diffs are kept for benchmarks, relative to a
+// package under internal. Captures are usually too big to commit, so it may
+// not exist.
+const Dir = "../difftest/testdata"
+
+// Case is a diff to benchmark against.
+type Case struct {
+	Name string
+	Diff string
+}
+
+// Cases returns generated diffs of Lines lines, spread over many files and
+// all in one, followed by the diffs captured in dir, if any, named for their
+// files.
+func Cases(dir string) ([]Case, error) {
+	name := fmt.Sprintf("generated-%dk", Lines/1000)
+	cases := []Case{
+		{Name: name, Diff: Generate(Lines/500, Lines)},
+		{Name: name + "-one-file", Diff: Generate(1, Lines)},
+	}
+	paths, err := Captures(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		diff, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".diff")
+		cases = append(cases, Case{Name: name, Diff: diff})
+	}
+	return cases, nil
+}
+
+// Captures returns the paths of the captured diffs in dir, *.diff and
+// gzipped *.diff.gz files, in name order. A missing dir has none.
+func Captures(dir string) ([]string, error) {
+	var paths []string
+	for _, pattern := range []string{"*.diff", "*.diff.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// Load reads a captured diff, decompressing it if its name ends in .gz.
+func Load(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// Save writes diff to path for Load, compressing it if the name ends in .gz.
+func Save(path, diff string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		zw = gzip.NewWriter(f)
+		w = zw
+	}
+	if _, err := io.WriteString(w, diff); err != nil {
+		f.Close()
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// Generate returns a diff of files Go files with about lines lines of hunks
+// between them. Its hunks mix context, removed, and added lines the way
+// edits to real code do, with the odd very long or non-ASCII line. The same
+// arguments always generate the same diff.
+func Generate(files, lines int) string {
+	files = max(files, 1)
+	rng := rand.New(rand.NewPCG(uint64(files), uint64(lines)))
+	var b strings.Builder
+	for f := range files {
+		path := fmt.Sprintf("pkg/mod%02d/file%04d.go", f%17, f)
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n", path, path)
+		fmt.Fprintf(&b, "index %07x..%07x 100644\n", rng.Uint32()>>4, rng.Uint32()>>4)
+		fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+		oldLine, newLine := 1, 1
+		for remaining := lines / files; remaining > 0; {
+			gap := 5 + rng.IntN(60)
+			oldLine += gap
+			newLine += gap
+			removed, added := rng.IntN(8), 1+rng.IntN(12)
+			if rng.IntN(5) == 0 {
+				// A rewritten block
+				removed, added = 20+rng.IntN(40), 20+rng.IntN(40)
+			}
+			const context = 3
+			fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@ func handler%d(ctx context.Context) error {\n",
+				oldLine, removed+2*context, newLine, added+2*context, rng.IntN(1000))
+			for range context {
+				b.WriteString(" " + codeLine(rng) + "\n")
+			}
+			for range removed {
+				b.WriteString("-" + codeLine(rng) + "\n")
+			}
+			for range added {
+				b.WriteString("+" + codeLine(rng) + "\n")
+			}
+			for range context {
+				b.WriteString(" " + codeLine(rng) + "\n")
+			}
+			oldLine += removed + 2*context
+			newLine += added + 2*context
+			remaining -= removed + added + 2*context
+		}
+	}
+	return b.String()
+}
+
+// codeLines are the templates of generated lines; %d is filled in to vary
+// identifiers.
+var codeLines = []string{
+	"",
+	"}",
+	"\treturn nil",
+	"\tif err != nil {",
+	"\t\treturn fmt.Errorf(\"loading item%d: %%w\", err)",
+	"\tvalue%d, err := store.Get(ctx, key%d)",
+	"\tfor i, item := range items%d {",
+	"\t\titems[i] = transform(item, opts%d)",
+	"\t// Retry once: the cache may have been cleared by another request (%d).",
+	"\tresult := make(map[string][]int, len(input%d))",
+	"func (s *Server) handle%d(w http.ResponseWriter, r *http.Request) {",
+	"\tlog.Printf(\"request %%s took %%v (shard %d)\", r.URL.Path, time.Since(start))",
+	"\t// Überprüfen: 日本語のコメント %d",
+}
+
+// codeLine returns a generated line of Go code, now and then one long enough
+// to need truncating or wrapping.
+func codeLine(rng *rand.Rand) string {
+	line := codeLines[rng.IntN(len(codeLines))]
+	if strings.Contains(line, "%d") {
+		args := make([]any, strings.Count(line, "%d"))
+		for i := range args {
+			args[i] = rng.IntN(100)
+		}
+		line = fmt.Sprintf(line, args...)
+	}
+	if rng.IntN(50) == 0 {
+		line += " // " + strings.Repeat("a long explanation that runs on ", 8)
+	}
+	return line
+}
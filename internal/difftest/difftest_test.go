@@ -0,0 +1,71 @@
+package difftest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestGenerate(t *testing.T) {
+	diff := Generate(10, 5000)
+	if Generate(10, 5000) != diff {
+		t.Error("Generate should be deterministic")
+	}
+	diffs, err := git.ParseDiff(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 10 {
+		t.Fatalf("got %d files, want 10", len(diffs))
+	}
+	lines := 0
+	for _, fd := range diffs {
+		for _, h := range fd.Hunks {
+			lines += len(h.Lines)
+		}
+	}
+	if lines < 5000 || lines > 6000 {
+		t.Errorf("got %d lines, want about 5000", lines)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	diff := Generate(2, 100)
+	for _, name := range []string{"b.diff", "a.diff.gz"} {
+		path := filepath.Join(dir, name)
+		if err := Save(path, diff); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != diff {
+			t.Errorf("%s: loaded diff differs from the saved one", name)
+		}
+	}
+
+	cases, err := Cases(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range cases {
+		names = append(names, c.Name)
+	}
+	if len(names) != 4 || names[2] != "a" || names[3] != "b" {
+		t.Errorf("case names = %v, want the generated ones, a, b", names)
+	}
+}
+
+func TestCasesMissingDir(t *testing.T) {
+	cases, err := Cases(filepath.Join(t.TempDir(), "none"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 2 {
+		t.Errorf("got %d cases, want just the generated ones", len(cases))
+	}
+}
@@ -0,0 +1,160 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DirDiff computes file diffs between two directory trees with diff(1), for
+// reviewing content outside a git repository such as extracted release
+// artifacts. Paths are relative to the directories; .git directories are
+// skipped.
+func DirDiff(oldDir, newDir string) ([]FileDiff, error) {
+	oldFiles, err := listFiles(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(oldFiles)+len(newFiles))
+	for p := range oldFiles {
+		paths = append(paths, p)
+	}
+	for p := range newFiles {
+		if !oldFiles[p] {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var diffs []FileDiff
+	for _, p := range paths {
+		oldPath, newPath := filepath.Join(oldDir, p), filepath.Join(newDir, p)
+		status := "M"
+		switch {
+		case !oldFiles[p]:
+			oldPath, status = os.DevNull, "A"
+		case !newFiles[p]:
+			newPath, status = os.DevNull, "D"
+		default:
+			same, err := sameContent(oldPath, newPath)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				continue
+			}
+		}
+
+		fd, err := diffFiles(oldPath, newPath, p)
+		if err != nil {
+			return nil, err
+		}
+		if len(fd.Hunks) == 0 {
+			status = "B"
+		}
+		fd.Status = status
+		diffs = append(diffs, *fd)
+	}
+	return diffs, nil
+}
+
+// listFiles returns the set of regular file paths under dir, relative to dir.
+func listFiles(dir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+func sameContent(a, b string) (bool, error) {
+	ab, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	bb, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ab, bb), nil
+}
+
+// ContentDiff diffs two versions of the file at path, given as their content,
+// with the whole file as context, for hosting services that serve files rather
+// than diffs. An empty version is taken as the file not existing.
+func ContentDiff(path, old, new string) (*FileDiff, error) {
+	dir, err := os.MkdirTemp("", "revui-diff-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	paths := []string{os.DevNull, os.DevNull}
+	for i, content := range []string{old, new} {
+		if content == "" {
+			continue
+		}
+		paths[i] = filepath.Join(dir, strconv.Itoa(i))
+		if err := os.WriteFile(paths[i], []byte(content), 0o600); err != nil {
+			return nil, err
+		}
+	}
+	context := max(strings.Count(old, "\n"), strings.Count(new, "\n")) + 1
+	return diffFilesContext(paths[0], paths[1], path, context)
+}
+
+// diffFiles runs diff -u on two files and parses the result as the diff for
+// path. Binary files produce a FileDiff with no hunks.
+func diffFiles(oldPath, newPath, path string) (*FileDiff, error) {
+	return diffFilesContext(oldPath, newPath, path, 3)
+}
+
+// diffFilesContext is diffFiles with context lines around each change.
+func diffFilesContext(oldPath, newPath, path string, context int) (*FileDiff, error) {
+	out, err := exec.Command("diff", "-U"+strconv.Itoa(context), "-L", "a/"+path, "-L", "b/"+path, oldPath, newPath).Output()
+	var exitErr *exec.ExitError
+	// diff exits 1 when the files differ and 2 on trouble.
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return nil, fmt.Errorf("diff %s: %w", path, err)
+	}
+	if !strings.HasPrefix(string(out), "--- ") {
+		return &FileDiff{Path: path}, nil
+	}
+
+	diffs, err := ParseDiff("diff --git a/" + path + " b/" + path + "\n" + string(out))
+	if err != nil {
+		return nil, err
+	}
+	if len(diffs) == 0 {
+		return &FileDiff{Path: path}, nil
+	}
+	return &diffs[0], nil
+}
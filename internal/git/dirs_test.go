@@ -0,0 +1,80 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirDiff(t *testing.T) {
+	root := t.TempDir()
+	oldDir, newDir := filepath.Join(root, "old"), filepath.Join(root, "new")
+	writeFile(t, filepath.Join(oldDir, "same.txt"), "same\n")
+	writeFile(t, filepath.Join(newDir, "same.txt"), "same\n")
+	writeFile(t, filepath.Join(oldDir, "mod.go"), "a\nb\n")
+	writeFile(t, filepath.Join(newDir, "mod.go"), "a\nc\n")
+	writeFile(t, filepath.Join(oldDir, "sub", "gone.txt"), "bye\n")
+	writeFile(t, filepath.Join(newDir, "sub", "new.txt"), "hi\n")
+	writeFile(t, filepath.Join(oldDir, "bin.dat"), "\x00\x01")
+	writeFile(t, filepath.Join(newDir, "bin.dat"), "\x00\x02")
+
+	diffs, err := DirDiff(oldDir, newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		path, status string
+		hunks        int
+	}{
+		{"bin.dat", "B", 0},
+		{"mod.go", "M", 1},
+		{"sub/gone.txt", "D", 1},
+		{"sub/new.txt", "A", 1},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %d diffs, got %d: %+v", len(want), len(diffs), diffs)
+	}
+	for i, w := range want {
+		if diffs[i].Path != w.path || diffs[i].Status != w.status || len(diffs[i].Hunks) != w.hunks {
+			t.Errorf("diff %d = {%s %s %d hunks}, want {%s %s %d hunks}",
+				i, diffs[i].Path, diffs[i].Status, len(diffs[i].Hunks), w.path, w.status, w.hunks)
+		}
+	}
+
+	lines := diffs[1].Hunks[0].Lines
+	if len(lines) != 3 || lines[1].Type != LineRemoved || lines[2].Content != "c" || lines[2].NewLineNo != 2 {
+		t.Errorf("unexpected mod.go lines: %+v", lines)
+	}
+}
+
+func TestContentDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+	fd, err := ContentDiff("x.go", old, strings.Replace(old, "h", "H", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The whole file is context, not only the lines near the change
+	if len(fd.Hunks) != 1 || len(fd.Hunks[0].Lines) != 10 || fd.Hunks[0].Lines[0].Content != "a" {
+		t.Fatalf("hunks = %+v, want one with every line", fd.Hunks)
+	}
+
+	fd, err = ContentDiff("x.go", "", "new\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fd.Hunks) != 1 || fd.Hunks[0].Lines[0].Type != LineAdded || fd.Hunks[0].Lines[0].NewLineNo != 1 {
+		t.Errorf("added file = %+v, want its line added", fd.Hunks)
+	}
+}
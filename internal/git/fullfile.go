@@ -0,0 +1,83 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileContent returns path's content as it is at HEAD (or Head, if set), or
+// in the working tree if worktree is set.
+func (r *Runner) FileContent(worktree bool, path string) (string, error) {
+	if worktree {
+		data, err := os.ReadFile(filepath.Join(r.Dir, path))
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", path, err)
+		}
+		return string(data), nil
+	}
+	out, err := r.run("show", r.head()+":"+path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s at %s: %w", path, r.head(), err)
+	}
+	return out, nil
+}
+
+// FullFile returns fd as one hunk covering the whole of content, the file on
+// its new side: the lines between and around fd's hunks become context, and
+// the changed lines stay where they are. A file without hunks, such as a
+// binary one, is returned as is.
+func FullFile(fd *FileDiff, content string) *FileDiff {
+	if len(fd.Hunks) == 0 {
+		return fd
+	}
+	lines := strings.Split(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	full := Hunk{}
+	// next is the first new-side line not yet shown, and offset the old-side
+	// line number minus the new-side one in the unchanged lines before it.
+	next, offset := 1, 0
+	context := func(end int) {
+		for ; next < end && next <= len(lines); next++ {
+			full.Lines = append(full.Lines, Line{
+				Content:   lines[next-1],
+				Type:      LineContext,
+				OldLineNo: next + offset,
+				NewLineNo: next,
+			})
+		}
+	}
+	for _, h := range fd.Hunks {
+		// A side with no lines gives the line before the change as its start.
+		newStart, oldStart := h.NewStart, h.OldStart
+		if h.NewCount == 0 {
+			newStart++
+		}
+		if h.OldCount == 0 {
+			oldStart++
+		}
+		context(newStart)
+		full.Lines = append(full.Lines, h.Lines...)
+		full.Dirty = full.Dirty || h.Dirty
+		full.Revised = full.Revised || h.Revised
+		next = newStart + h.NewCount
+		offset = oldStart + h.OldCount - next
+	}
+	context(len(lines) + 1)
+
+	for _, l := range full.Lines {
+		if l.Type != LineAdded {
+			full.OldCount++
+		}
+		if l.Type != LineRemoved {
+			full.NewCount++
+		}
+	}
+	full.OldStart, full.NewStart = min(1, full.OldCount), min(1, full.NewCount)
+	full.Header = fmt.Sprintf("@@ -%d,%d +%d,%d @@", full.OldStart, full.OldCount, full.NewStart, full.NewCount)
+	return &FileDiff{Path: fd.Path, Status: fd.Status, Hunks: []Hunk{full}}
+}
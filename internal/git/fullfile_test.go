@@ -0,0 +1,98 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileContent(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	want := "package main\n\nfunc hello() {\n\tfmt.Println(\"hello\")\n}\n"
+	if got, err := r.FileContent(false, "hello.go"); err != nil || got != want {
+		t.Errorf("FileContent at HEAD = %q, %v, want %q", got, err, want)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.FileContent(true, "hello.go"); err != nil || got != "package main\n" {
+		t.Errorf("FileContent in the working tree = %q, %v", got, err)
+	}
+	if _, err := r.FileContent(false, "missing.go"); err == nil {
+		t.Error("reading a file HEAD doesn't have should fail")
+	}
+}
+
+func TestFullFile(t *testing.T) {
+	var old []string
+	for i := 1; i <= 30; i++ {
+		old = append(old, fmt.Sprintf("line %d", i))
+	}
+	tests := []struct {
+		name string
+		edit func(lines []string) []string
+	}{
+		{
+			name: "changes in the middle",
+			edit: func(lines []string) []string {
+				lines[2] = "changed 3"
+				lines[14] = "changed 15"
+				return slices.Insert(slices.Delete(lines, 19, 20), 26, "added")
+			},
+		},
+		{
+			name: "changes at the ends",
+			edit: func(lines []string) []string {
+				return append(slices.Insert(lines[1:], 0, "new first"), "new last")
+			},
+		},
+		{
+			name: "lines removed",
+			edit: func(lines []string) []string {
+				return slices.Delete(lines, 10, 20)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			oldPath, newPath := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+			content := strings.Join(tt.edit(slices.Clone(old)), "\n") + "\n"
+			if err := os.WriteFile(oldPath, []byte(strings.Join(old, "\n")+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(newPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			diff := func(context string) *FileDiff {
+				// git diff --no-index exits 1 when the files differ
+				cmd := exec.Command("git", "diff", "--no-index", "--no-color", context, "old", "new")
+				cmd.Dir = dir
+				out, _ := cmd.Output()
+				diffs, err := ParseDiff(string(out))
+				if err != nil || len(diffs) != 1 {
+					t.Fatalf("ParseDiff = %+v, %v", diffs, err)
+				}
+				return &diffs[0]
+			}
+
+			got := FullFile(diff("-U1"), content)
+			want := diff("-U100")
+			if len(got.Hunks) != 1 {
+				t.Fatalf("got %d hunks, want 1", len(got.Hunks))
+			}
+			if got.Hunks[0].Header != strings.TrimSpace(want.Hunks[0].Header) {
+				t.Errorf("header = %q, want %q", got.Hunks[0].Header, want.Hunks[0].Header)
+			}
+			if !slices.Equal(got.Hunks[0].Lines, want.Hunks[0].Lines) {
+				t.Errorf("lines =\n%+v\nwant:\n%+v", got.Hunks[0].Lines, want.Hunks[0].Lines)
+			}
+		})
+	}
+}
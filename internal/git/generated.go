@@ -0,0 +1,58 @@
+package git
+
+import (
+	"regexp"
+	"strings"
+)
+
+// generatedMarker matches the comments code generators mark their output
+// with: Go's "Code generated ... DO NOT EDIT.", and the @generated and
+// <auto-generated> of other ecosystems.
+var generatedMarker = regexp.MustCompile(`^\s*(?://|#|/?\*|--|;|<!--)\s*(?:Code generated .*DO NOT EDIT|.*@generated\b|<auto-generated)`)
+
+// maxMarkerLine is how far into a file a generated marker is looked for;
+// generators put it in the header.
+const maxMarkerLine = 20
+
+// HasGeneratedMarker reports whether the diff shows a generated marker in
+// the header of the file, as it is after the change or, for a deleted file,
+// as it was.
+func (fd *FileDiff) HasGeneratedMarker() bool {
+	for _, h := range fd.Hunks {
+		for _, l := range h.Lines {
+			n := l.NewLineNo
+			if l.Type == LineRemoved {
+				if fd.Status != "D" {
+					continue
+				}
+				n = l.OldLineNo
+			}
+			if n <= maxMarkerLine && generatedMarker.MatchString(l.Content) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GeneratedAttr returns whether path has the linguist-generated attribute
+// set, as GitHub uses to collapse generated files, and whether the attribute
+// is given at all: unsetting it marks a file as not generated.
+func (r *Runner) GeneratedAttr(path string) (generated, set bool, err error) {
+	out, err := r.run("check-attr", "-z", "linguist-generated", "--", path)
+	if err != nil {
+		return false, false, err
+	}
+	// path NUL attribute NUL value NUL
+	fields := strings.Split(out, "\x00")
+	if len(fields) < 3 {
+		return false, false, nil
+	}
+	switch fields[2] {
+	case "set", "true":
+		return true, true, nil
+	case "unset", "false":
+		return false, true, nil
+	}
+	return false, false, nil
+}
@@ -0,0 +1,60 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHasGeneratedMarker(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		line   Line
+		want   bool
+	}{
+		{name: "go", line: Line{Content: "// Code generated by protoc-gen-go. DO NOT EDIT.", Type: LineAdded, NewLineNo: 1}, want: true},
+		{name: "context", line: Line{Content: "// Code generated by mockgen. DO NOT EDIT.", Type: LineContext, OldLineNo: 1, NewLineNo: 1}, want: true},
+		{name: "at generated", line: Line{Content: " * @generated by relay-compiler", Type: LineAdded, NewLineNo: 3}, want: true},
+		{name: "csharp", line: Line{Content: "// <auto-generated>", Type: LineAdded, NewLineNo: 2}, want: true},
+		{name: "below the header", line: Line{Content: "// Code generated by stringer. DO NOT EDIT.", Type: LineAdded, NewLineNo: 300}},
+		{name: "in code", line: Line{Content: `const header = "// Code generated ... DO NOT EDIT."`, Type: LineAdded, NewLineNo: 5}},
+		{name: "marker removed", status: "M", line: Line{Content: "// Code generated by hand. DO NOT EDIT.", Type: LineRemoved, OldLineNo: 1}},
+		{name: "file deleted", status: "D", line: Line{Content: "// Code generated by hand. DO NOT EDIT.", Type: LineRemoved, OldLineNo: 1}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd := &FileDiff{Path: "x.go", Status: tt.status, Hunks: []Hunk{{Lines: []Line{tt.line}}}}
+			if got := fd.HasGeneratedMarker(); got != tt.want {
+				t.Errorf("HasGeneratedMarker() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratedAttr(t *testing.T) {
+	dir := setupTestRepo(t)
+	attrs := "*.pb.go linguist-generated\nvendor/** linguist-generated=true\nvendor/ours.go -linguist-generated\n"
+	if err := os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte(attrs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{Dir: dir}
+	tests := []struct {
+		path           string
+		generated, set bool
+	}{
+		{path: "api/api.pb.go", generated: true, set: true},
+		{path: "vendor/lib/lib.go", generated: true, set: true},
+		{path: "vendor/ours.go", set: true},
+		{path: "hello.go"},
+	}
+	for _, tt := range tests {
+		generated, set, err := r.GeneratedAttr(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if generated != tt.generated || set != tt.set {
+			t.Errorf("GeneratedAttr(%q) = %v, %v, want %v, %v", tt.path, generated, set, tt.generated, tt.set)
+		}
+	}
+}
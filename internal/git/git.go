@@ -1,39 +1,131 @@
 package git
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Runner executes git commands in a working directory.
 type Runner struct {
 	Dir string
+	// Head is the ref reviewed against the base in branch mode. Empty means HEAD.
+	Head string
+	// HeadName is shown in place of Head when set, for refs such as the
+	// private ones FetchHead creates.
+	HeadName string
+	// Exclude holds gitignore-style patterns for untracked files to leave out
+	// of uncommitted changes, on top of the repository's ignore rules.
+	Exclude []string
 }
 
-// CurrentBranch returns the name of the currently checked-out branch.
+// head returns the ref being reviewed in branch mode.
+func (r *Runner) head() string {
+	if r.Head != "" {
+		return r.Head
+	}
+	return "HEAD"
+}
+
+// CurrentBranch returns the name of the currently checked-out branch, which
+// may not have any commits yet. A detached HEAD is named by its short commit
+// SHA. If Head is set, it is returned instead (or HeadName, if set), since that
+// is the ref under review.
 func (r *Runner) CurrentBranch() (string, error) {
-	out, err := r.run("rev-parse", "--abbrev-ref", "HEAD")
+	if r.HeadName != "" {
+		return r.HeadName, nil
+	}
+	if r.Head != "" {
+		return r.Head, nil
+	}
+	if out, err := r.run("symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		return strings.TrimSpace(out), nil
+	}
+	out, err := r.run("rev-parse", "--short", "HEAD")
 	if err != nil {
 		return "", fmt.Errorf("getting current branch: %w", err)
 	}
 	return strings.TrimSpace(out), nil
 }
 
-// ChangedFiles returns the list of files changed between the given base ref and HEAD.
+// fetchedRefPrefix holds the refs FetchHead fetches remote branches into, out
+// of the way of local and remote-tracking branches.
+const fetchedRefPrefix = "refs/revui/fetched/"
+
+// FetchHead fetches branch from remote into a private ref and reviews it as
+// Head, named remote/branch so that its review is apart from a local branch
+// of the same name, without creating a local branch or touching the working
+// tree. The returned function deletes the ref once the review is done.
+func (r *Runner) FetchHead(remote, branch string) (func(), error) {
+	ref, cleanup, err := r.FetchBranch(remote, branch)
+	if err != nil {
+		return nil, err
+	}
+	r.Head = ref
+	r.HeadName = remote + "/" + branch
+	return cleanup, nil
+}
+
+// FetchBranch fetches branch from remote into a private ref, which it returns
+// with a function that deletes it, without creating a local branch.
+func (r *Runner) FetchBranch(remote, branch string) (string, func(), error) {
+	ref := fetchedRefPrefix + remote + "/" + branch
+	if _, err := r.run("fetch", "--quiet", "--no-tags", remote, "+refs/heads/"+branch+":"+ref); err != nil {
+		return "", nil, fmt.Errorf("fetching %s/%s: %w", remote, branch, err)
+	}
+	return ref, func() { r.run("update-ref", "-d", ref) }, nil
+}
+
+// pullRequestRefs are where hosting services publish the head of pull request
+// %d: GitHub, Gitea, and Forgejo; GitLab; and Bitbucket Server.
+var pullRequestRefs = []string{"refs/pull/%d/head", "refs/merge-requests/%d/head", "refs/pull-requests/%d/from"}
+
+// FetchPullRequest fetches the head of pull request number from remote into a
+// private ref and reviews it as Head, named pull/number, like FetchHead. The
+// returned function deletes the ref once the review is done.
+func (r *Runner) FetchPullRequest(remote string, number int) (func(), error) {
+	ref := fmt.Sprintf("%s%s/pull/%d", fetchedRefPrefix, remote, number)
+	var err error
+	for _, src := range pullRequestRefs {
+		if _, err = r.run("fetch", "--quiet", "--no-tags", remote, "+"+fmt.Sprintf(src, number)+":"+ref); err == nil {
+			r.Head = ref
+			r.HeadName = fmt.Sprintf("pull/%d", number)
+			return func() { r.run("update-ref", "-d", ref) }, nil
+		}
+	}
+	return nil, fmt.Errorf("fetching pull request %d from %s: %w", number, remote, err)
+}
+
+// IsUnborn reports whether the checked-out branch has no commits yet, as in
+// a freshly initialized repository.
+func (r *Runner) IsUnborn() bool {
+	_, err := r.run("rev-parse", "--verify", "-q", "HEAD")
+	return err != nil
+}
+
+// ChangedFiles returns the list of files changed between the given base ref and HEAD
+// (or Head, if set). Copies of files the change also modifies are listed as
+// copies, with the file they came from.
 func (r *Runner) ChangedFiles(base string) ([]ChangedFile, error) {
-	out, err := r.run("diff", "--name-status", base+"..HEAD")
+	out, err := r.run("diff", "--name-status", "-z", "-C", base+".."+r.head())
 	if err != nil {
 		return nil, fmt.Errorf("getting changed files: %w", err)
 	}
-	return ParseNameStatus(out), nil
+	return ParseNameStatusZ(out), nil
 }
 
-// FileDiff returns the parsed diff for a single file between the given base ref and HEAD.
+// FileDiff returns the parsed diff for a single file between the given base ref and
+// HEAD (or Head, if set).
 func (r *Runner) FileDiff(base, path string) (*FileDiff, error) {
-	out, err := r.run("diff", base+"..HEAD", "--", path)
+	out, err := r.run("diff", base+".."+r.head(), "--", path)
 	if err != nil {
 		return nil, fmt.Errorf("getting diff for %s: %w", path, err)
 	}
@@ -42,12 +134,358 @@ func (r *Runner) FileDiff(base, path string) (*FileDiff, error) {
 		return nil, err
 	}
 	if len(diffs) == 0 {
-		return &FileDiff{Path: path}, nil
+		return &FileDiff{Path: path, Status: "M"}, nil
 	}
 	diffs[0].Path = path
 	return &diffs[0], nil
 }
 
+// CompareFiles diffs two files against each other, for refactors git didn't
+// detect as renames: oldPath as it is at base, against newPath as it is at
+// HEAD (or Head, if set), or in the working tree if worktree is set. A file
+// that doesn't exist on its side, such as an added oldPath, is taken from the
+// other. The diff's Path names both files.
+func (r *Runner) CompareFiles(base string, worktree bool, oldPath, newPath string) (*FileDiff, error) {
+	newRev := r.head()
+	if worktree {
+		newRev = ""
+	}
+	oldBlob, err := r.blob(base, newRev, oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newBlob, err := r.blob(newRev, base, newPath)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.run("diff", "--no-color", "--no-ext-diff", oldBlob, newBlob)
+	if err != nil {
+		return nil, fmt.Errorf("comparing %s with %s: %w", oldPath, newPath, err)
+	}
+	diffs, err := ParseDiff(out)
+	if err != nil {
+		return nil, err
+	}
+	fd := &FileDiff{}
+	if len(diffs) > 0 {
+		fd = &diffs[0]
+	}
+	fd.Path = oldPath + " → " + newPath
+	fd.Status = "M"
+	return fd, nil
+}
+
+// DirFiles returns the contents, by name, of the files directly in dir whose
+// names end in ext, as they are at base, and as they are at HEAD (or Head, if
+// set), or in the working tree if worktree is set. A directory that doesn't
+// exist on a side has no files there.
+func (r *Runner) DirFiles(base string, worktree bool, dir, ext string) (old, new map[string]string, err error) {
+	if old, err = r.treeFiles(base, dir, ext); err != nil {
+		return nil, nil, err
+	}
+	if worktree {
+		new, err = worktreeFiles(filepath.Join(r.Dir, dir), ext)
+	} else {
+		new, err = r.treeFiles(r.head(), dir, ext)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return old, new, nil
+}
+
+// treeFiles returns the files directly in dir at rev whose names end in ext.
+func (r *Runner) treeFiles(rev, dir, ext string) (map[string]string, error) {
+	tree := rev + ":" + strings.TrimSuffix(dir, "/")
+	if dir == "." || dir == "" {
+		tree = rev + ":"
+	}
+	files := make(map[string]string)
+	if _, err := r.run("cat-file", "-e", tree); err != nil {
+		return files, nil
+	}
+	out, err := r.run("ls-tree", "-z", tree)
+	if err != nil {
+		return nil, err
+	}
+	for entry := range strings.SplitSeq(out, "\x00") {
+		// "<mode> blob <object>\t<name>"
+		info, name, ok := strings.Cut(entry, "\t")
+		if !ok || !strings.HasSuffix(name, ext) || strings.Fields(info)[1] != "blob" {
+			continue
+		}
+		content, err := r.run("cat-file", "blob", strings.Fields(info)[2])
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path.Join(dir, name), err)
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// worktreeFiles returns the files directly in dir whose names end in ext.
+func worktreeFiles(dir, ext string) (map[string]string, error) {
+	files := make(map[string]string)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), ext) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[e.Name()] = string(content)
+	}
+	return files, nil
+}
+
+// blob returns a name git diff accepts for path's content at rev, or else
+// at fallback. An empty rev is the working tree, whose file is written to
+// the object database to have a name.
+func (r *Runner) blob(rev, fallback, path string) (string, error) {
+	for _, rev := range []string{rev, fallback} {
+		if rev != "" {
+			if _, err := r.run("cat-file", "-e", rev+":"+path); err == nil {
+				return rev + ":" + path, nil
+			}
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(r.Dir, path)); err != nil {
+			continue
+		}
+		out, err := r.run("hash-object", "-w", "--", path)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", path, err)
+		}
+		return strings.TrimSpace(out), nil
+	}
+	return "", fmt.Errorf("%s doesn't exist before or after the change", path)
+}
+
+// WorktreeChangedFiles returns the files changed between the given base ref and
+// the working tree, so uncommitted edits to tracked files are included.
+func (r *Runner) WorktreeChangedFiles(base string) ([]ChangedFile, error) {
+	out, err := r.run("diff", "--name-status", "-z", "-C", base)
+	if err != nil {
+		return nil, fmt.Errorf("getting changed files: %w", err)
+	}
+	return ParseNameStatusZ(out), nil
+}
+
+// WorktreeFileDiff returns the parsed diff for a single file between the given
+// base ref and the working tree. Hunks that overlap uncommitted changes are
+// marked Dirty.
+func (r *Runner) WorktreeFileDiff(base, path string) (*FileDiff, error) {
+	out, err := r.run("diff", base, "--", path)
+	if err != nil {
+		return nil, fmt.Errorf("getting diff for %s: %w", path, err)
+	}
+	diffs, err := ParseDiff(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(diffs) == 0 {
+		return &FileDiff{Path: path, Status: "M"}, nil
+	}
+	diffs[0].Path = path
+
+	if dirtyOut, err := r.run("diff", "HEAD", "--", path); err == nil {
+		if dirty, err := ParseDiff(dirtyOut); err == nil && len(dirty) > 0 {
+			markDirtyHunks(&diffs[0], dirty[0].Hunks)
+		}
+	}
+	return &diffs[0], nil
+}
+
+// markDirtyHunks flags each hunk of fd whose new-file range overlaps one of the
+// uncommitted hunks (HEAD vs working tree). Both diffs share new-file line numbers.
+func markDirtyHunks(fd *FileDiff, uncommitted []Hunk) {
+	for i := range fd.Hunks {
+		if overlapsAny(fd.Hunks[i], uncommitted) {
+			fd.Hunks[i].Dirty = true
+		}
+	}
+}
+
+// MarkRevisedHunks flags each hunk of fd whose new-file range overlaps one of
+// the hunks from Interdiff, which share its new-file line numbers.
+func MarkRevisedHunks(fd *FileDiff, revised []Hunk) {
+	for i := range fd.Hunks {
+		fd.Hunks[i].Revised = overlapsAny(fd.Hunks[i], revised)
+	}
+}
+
+// overlapsAny reports whether h's new-file range overlaps any of others. A
+// pure deletion counts as covering the line it follows.
+func overlapsAny(h Hunk, others []Hunk) bool {
+	start, end := h.NewStart, h.NewStart+max(h.NewCount, 1)
+	for _, o := range others {
+		oStart, oEnd := o.NewStart, o.NewStart+max(o.NewCount, 1)
+		if start < oEnd && oStart < end {
+			return true
+		}
+	}
+	return false
+}
+
+// LineCounts returns the lines each changed file adds and removes between
+// the given base ref and HEAD (or Head, if set), or the working tree if
+// worktree is set, keyed by path as ChangedFiles lists them. It's one git
+// command however many files there are. Binary files count as unchanged, and
+// untracked files aren't listed.
+func (r *Runner) LineCounts(base string, worktree bool) (map[string]LineCount, error) {
+	rev := base + ".." + r.head()
+	if worktree {
+		rev = base
+	}
+	out, err := r.run("diff", "--numstat", "-z", "-C", rev)
+	if err != nil {
+		return nil, fmt.Errorf("counting changed lines: %w", err)
+	}
+	counts := make(map[string]LineCount)
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		stat := strings.SplitN(fields[i], "\t", 3)
+		if len(stat) < 3 {
+			continue
+		}
+		path := stat[2]
+		if path == "" && i+2 < len(fields) {
+			// A rename or copy, followed by its old and new paths
+			path = fields[i+2]
+			i += 2
+		}
+		// Binary files show "-" for both counts
+		added, _ := strconv.Atoi(stat[0])
+		removed, _ := strconv.Atoi(stat[1])
+		counts[path] = LineCount{Added: added, Removed: removed}
+	}
+	return counts, nil
+}
+
+// Commits returns the commits between the given base ref and HEAD (or Head, if
+// set), oldest first, with the files each one touched.
+func (r *Runner) Commits(base string) ([]Commit, error) {
+	out, err := r.run("log", "--reverse", "--format=%x00%h%x09%s", "--name-only", base+".."+r.head())
+	if err != nil {
+		return nil, fmt.Errorf("listing commits: %w", err)
+	}
+	return ParseLog(out), nil
+}
+
+// RecentAuthors returns up to n authors of the latest commits reachable from
+// HEAD (or Head, if set), most recent first, each once.
+func (r *Runner) RecentAuthors(n int) ([]Author, error) {
+	out, err := r.run("log", "-n", "500", "--format=%aN%x09%aE", r.head())
+	if err != nil {
+		return nil, fmt.Errorf("listing authors: %w", err)
+	}
+	var authors []Author
+	seen := make(map[string]bool)
+	for line := range strings.Lines(out) {
+		name, email, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		if key := strings.ToLower(email); name != "" && !seen[key] && len(authors) < n {
+			seen[key] = true
+			authors = append(authors, Author{Name: name, Email: email})
+		}
+	}
+	return authors, nil
+}
+
+// ChangeID returns the Change-Id trailer Gerrit identifies changes by from the
+// commit message of HEAD (or Head, if set), or "" if it has none.
+func (r *Runner) ChangeID() (string, error) {
+	out, err := r.run("log", "-1", "--format=%(trailers:key=Change-Id,valueonly)", r.head())
+	if err != nil {
+		return "", fmt.Errorf("reading Change-Id: %w", err)
+	}
+	id, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	return id, nil
+}
+
+// RemoteURL returns the URL of the given remote.
+func (r *Runner) RemoteURL(remote string) (string, error) {
+	out, err := r.run("remote", "get-url", remote)
+	if err != nil {
+		return "", fmt.Errorf("getting URL of remote %s: %w", remote, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// HeadCommit returns the commit SHA of HEAD (or Head, if set).
+func (r *Runner) HeadCommit() (string, error) {
+	out, err := r.run("rev-parse", r.head()+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", r.head(), err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Interdiff returns the hunks changed between the since commit and HEAD (or
+// Head, if set), keyed by path. Hunks have no context, so their new-file
+// ranges cover exactly the changed lines.
+func (r *Runner) Interdiff(since string) (map[string][]Hunk, error) {
+	out, err := r.run("diff", "-U0", since+".."+r.head())
+	if err != nil {
+		return nil, fmt.Errorf("comparing with %s: %w", since, err)
+	}
+	diffs, err := ParseDiff(out)
+	if err != nil {
+		return nil, err
+	}
+	revised := make(map[string][]Hunk, len(diffs))
+	for _, fd := range diffs {
+		revised[fd.Path] = fd.Hunks
+	}
+	return revised, nil
+}
+
+// Diff returns the raw diff of the whole change, between the base ref and
+// HEAD (or Head, if set).
+func (r *Runner) Diff(base string) (string, error) {
+	out, err := r.run("diff", base+".."+r.head())
+	if err != nil {
+		return "", fmt.Errorf("getting diff: %w", err)
+	}
+	return out, nil
+}
+
+// FormatPatch returns `git format-patch --stdout --cover-letter` output for
+// the commits between the base ref and HEAD (or Head, if set).
+func (r *Runner) FormatPatch(base string) (string, error) {
+	out, err := r.run("format-patch", "--stdout", "--cover-letter", base+".."+r.head())
+	if err != nil {
+		return "", fmt.Errorf("formatting patches: %w", err)
+	}
+	return out, nil
+}
+
+// GitDir returns the absolute path of the repository's .git directory.
+func (r *Runner) GitDir() (string, error) {
+	out, err := r.run("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", fmt.Errorf("getting git dir: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// TopLevel returns the absolute path of the repository's working tree, which
+// changed files' paths are relative to.
+func (r *Runner) TopLevel() (string, error) {
+	out, err := r.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("getting top level: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
 // IsGitRepo returns true if the working directory is inside a git repository.
 func (r *Runner) IsGitRepo() bool {
 	_, err := r.run("rev-parse", "--git-dir")
@@ -76,8 +514,52 @@ func (r *Runner) DefaultBranch(remote string) string {
 	return "main"
 }
 
-// HasUncommittedChanges returns true if there are staged, unstaged, or untracked changes.
+// ResolveRange splits a commit range such as abc123..def456 into the refs
+// to review between, like `git diff` does: an omitted end is HEAD, and in
+// the three-dot form base...head, the base is where head forked from base,
+// abbreviated. Both ends must exist.
+func (r *Runner) ResolveRange(spec string) (base, head string, err error) {
+	base, head, symmetric := strings.Cut(spec, "...")
+	if !symmetric {
+		var ok bool
+		if base, head, ok = strings.Cut(spec, ".."); !ok {
+			return "", "", fmt.Errorf("range %q should look like base..head", spec)
+		}
+	}
+	base, head = cmp.Or(base, "HEAD"), cmp.Or(head, "HEAD")
+	for _, ref := range []string{base, head} {
+		if !r.BranchExists(ref) {
+			return "", "", fmt.Errorf("ref %q in range %q does not exist", ref, spec)
+		}
+	}
+	if symmetric {
+		if base, err = r.MergeBase(base, head); err != nil {
+			return "", "", err
+		}
+	}
+	return base, head, nil
+}
+
+// MergeBase returns the abbreviated commit where head forked from base, to
+// review only what head adds rather than undo what base has gained since.
+func (r *Runner) MergeBase(base, head string) (string, error) {
+	out, err := r.run("merge-base", base, head)
+	if err != nil {
+		return "", fmt.Errorf("finding the merge base of %s and %s: %w", base, head, err)
+	}
+	if out, err = r.run("rev-parse", "--short", strings.TrimSpace(out)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// HasUncommittedChanges returns true if there are staged, unstaged, or untracked
+// changes. Untracked files matching Exclude don't count.
 func (r *Runner) HasUncommittedChanges() bool {
+	if len(r.Exclude) > 0 {
+		files, err := r.UncommittedFiles()
+		return err == nil && len(files) > 0
+	}
 	out, err := r.run("status", "--porcelain")
 	if err != nil {
 		return false
@@ -89,15 +571,15 @@ func (r *Runner) HasUncommittedChanges() bool {
 // Binary files are marked with status "B".
 func (r *Runner) UncommittedFiles() ([]ChangedFile, error) {
 	// Get tracked changes (staged + unstaged)
-	diffOut, err := r.run("diff", "HEAD", "--name-status")
+	diffOut, err := r.run("diff", "HEAD", "--name-status", "-z")
 	if err != nil {
 		// If HEAD doesn't exist (initial commit), try --cached
-		diffOut, err = r.run("diff", "--cached", "--name-status")
+		diffOut, err = r.run("diff", "--cached", "--name-status", "-z")
 		if err != nil {
 			diffOut = ""
 		}
 	}
-	files := ParseNameStatus(diffOut)
+	files := ParseNameStatusZ(diffOut)
 
 	// Identify binary files among tracked changes via --numstat
 	binaries := r.detectBinaryTracked()
@@ -110,7 +592,11 @@ func (r *Runner) UncommittedFiles() ([]ChangedFile, error) {
 	}
 
 	// Get untracked files
-	untrackedOut, err := r.run("ls-files", "--others", "--exclude-standard")
+	args := []string{"ls-files", "-z", "--others", "--exclude-standard"}
+	for _, pattern := range r.Exclude {
+		args = append(args, "--exclude="+pattern)
+	}
+	untrackedOut, err := r.run(args...)
 	if err != nil {
 		return files, nil
 	}
@@ -120,16 +606,15 @@ func (r *Runner) UncommittedFiles() ([]ChangedFile, error) {
 		seen[f.Path] = true
 	}
 
-	for line := range strings.SplitSeq(strings.TrimSpace(untrackedOut), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" || seen[line] {
+	for path := range strings.SplitSeq(untrackedOut, "\x00") {
+		if path == "" || seen[path] {
 			continue
 		}
 		status := "A"
-		if r.isBinaryFile(line) {
+		if r.isBinaryFile(path) {
 			status = "B"
 		}
-		files = append(files, ChangedFile{Path: line, Status: status})
+		files = append(files, ChangedFile{Path: path, Status: status})
 	}
 
 	return files, nil
@@ -137,21 +622,24 @@ func (r *Runner) UncommittedFiles() ([]ChangedFile, error) {
 
 // detectBinaryTracked returns a set of paths that are binary among tracked changes.
 func (r *Runner) detectBinaryTracked() map[string]bool {
-	out, err := r.run("diff", "HEAD", "--numstat")
+	out, err := r.run("diff", "HEAD", "--numstat", "-z")
 	if err != nil {
 		return nil
 	}
 	binaries := make(map[string]bool)
-	for line := range strings.SplitSeq(out, "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		// Binary files show as "-\t-\tpath". Renames leave the path empty
+		// and follow with the old and new paths.
+		path, ok := strings.CutPrefix(fields[i], "-\t-\t")
+		if !ok {
 			continue
 		}
-		// Binary files show as "-\t-\tfilename"
-		if strings.HasPrefix(line, "-\t-\t") {
-			path := line[4:]
-			binaries[path] = true
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
 		}
+		binaries[path] = true
 	}
 	return binaries
 }
@@ -199,7 +687,7 @@ func (r *Runner) UncommittedFileDiff(path string) (*FileDiff, error) {
 		return nil, err
 	}
 	if len(diffs) == 0 {
-		return &FileDiff{Path: path}, nil
+		return &FileDiff{Path: path, Status: "M"}, nil
 	}
 	diffs[0].Path = path
 	return &diffs[0], nil
@@ -244,13 +732,81 @@ func (r *Runner) synthesizeNewFileDiff(path string) (*FileDiff, error) {
 	}, nil
 }
 
+// UserName returns the configured user.name, or user.email if no name is
+// set, for attributing review comments. It is empty if neither is set.
+func (r *Runner) UserName() string {
+	for _, key := range []string{"user.name", "user.email"} {
+		if out, err := r.run("config", key); err == nil && strings.TrimSpace(out) != "" {
+			return strings.TrimSpace(out)
+		}
+	}
+	return ""
+}
+
+// BlameAges returns the author time of each line of path as of rev, keyed by
+// line number.
+func (r *Runner) BlameAges(rev, path string) (map[int]time.Time, error) {
+	out, err := r.run("blame", "--incremental", rev, "--", path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBlameIncremental(out), nil
+}
+
+// ParseBlameIncremental parses `git blame --incremental` output into the
+// author time of each final line number. Commit details are only printed the
+// first time a commit appears, so times are resolved once all entries are read.
+func ParseBlameIncremental(raw string) map[int]time.Time {
+	type entry struct {
+		sha         string
+		start, size int
+	}
+	var entries []entry
+	times := make(map[string]time.Time)
+	var cur entry
+	for _, line := range strings.Split(raw, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 4 && len(fields[0]) >= 40:
+			cur = entry{sha: fields[0], start: atoi(fields[2]), size: atoi(fields[3])}
+		case len(fields) == 2 && fields[0] == "author-time":
+			times[cur.sha] = time.Unix(int64(atoi(fields[1])), 0)
+		case strings.HasPrefix(line, "filename "):
+			// The filename line ends each entry.
+			entries = append(entries, cur)
+		}
+	}
+
+	ages := make(map[int]time.Time)
+	for _, e := range entries {
+		t, ok := times[e.sha]
+		if !ok {
+			continue
+		}
+		for i := range e.size {
+			ages[e.start+i] = t
+		}
+	}
+	return ages
+}
+
+// CommandError is a git command that exited with an error.
+type CommandError struct {
+	Args   []string // arguments after "git"
+	Stderr string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("git %s: %s", strings.Join(e.Args, " "), e.Stderr)
+}
+
 func (r *Runner) run(args ...string) (string, error) {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = r.Dir
 	out, err := cmd.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), string(exitErr.Stderr))
+			return "", &CommandError{Args: args, Stderr: string(exitErr.Stderr)}
 		}
 		return "", err
 	}
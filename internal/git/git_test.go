@@ -1,9 +1,13 @@
 package git
 
 import (
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -90,6 +94,262 @@ func TestFileDiff(t *testing.T) {
 	}
 }
 
+func TestLineCounts(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+	counts, err := r.LineCounts("main", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hello.go swaps one line for three; world.go adds three
+	want := map[string]LineCount{"hello.go": {Added: 3, Removed: 1}, "world.go": {Added: 3}}
+	if !maps.Equal(counts, want) {
+		t.Errorf("LineCounts() = %v, want %v", counts, want)
+	}
+
+	// Against the working tree, renamed files are keyed by their new path
+	runCmd(t, dir, "git", "mv", "world.go", "earth.go")
+	os.WriteFile(filepath.Join(dir, "hello.go"), []byte("package main\n"), 0o644)
+	counts, err = r.LineCounts("HEAD", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]LineCount{"hello.go": {Removed: 4}, "earth.go": {}}
+	if !maps.Equal(counts, want) {
+		t.Errorf("LineCounts(worktree) = %v, want %v", counts, want)
+	}
+}
+
+func TestCommits(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+	commits, err := r.Commits("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].Subject != "add feature" {
+		t.Fatalf("Commits() = %+v, want the one feature commit", commits)
+	}
+	if !slices.Equal(commits[0].Files, []string{"hello.go", "world.go"}) {
+		t.Errorf("files = %v, want [hello.go world.go]", commits[0].Files)
+	}
+}
+
+func TestRecentAuthors(t *testing.T) {
+	dir := setupTestRepo(t)
+	runCmd(t, dir, "git", "-c", "user.name=Ann", "-c", "user.email=ann@example.com", "commit", "--allow-empty", "-m", "by ann")
+	r := &Runner{Dir: dir}
+	authors, err := r.RecentAuthors(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Author{{"Ann", "ann@example.com"}, {"Test", "test@test.com"}}
+	if !slices.Equal(authors, want) {
+		t.Errorf("RecentAuthors(10) = %v, want %v", authors, want)
+	}
+	if authors, _ := r.RecentAuthors(1); len(authors) != 1 {
+		t.Errorf("RecentAuthors(1) = %v, want one author", authors)
+	}
+}
+
+func TestCompareFiles(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	// hello.go before the change against the added world.go
+	fd, err := r.CompareFiles("main", false, "hello.go", "world.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fd.Path != "hello.go → world.go" {
+		t.Errorf("Path = %q", fd.Path)
+	}
+	var changed []string
+	for _, h := range fd.Hunks {
+		for _, l := range h.Lines {
+			if l.Type != LineContext {
+				changed = append(changed, l.Marker()+l.Content)
+			}
+		}
+	}
+	if want := []string{"-func hello() {}", "+func world() {}"}; !slices.Equal(changed, want) {
+		t.Errorf("changed lines = %q, want %q", changed, want)
+	}
+
+	// An untracked copy in the working tree; world.go only exists after the
+	// change, so is taken from there.
+	if err := os.WriteFile(filepath.Join(dir, "copy.go"), []byte("package main\n\nfunc world() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fd, err = r.CompareFiles("main", true, "world.go", "copy.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fd.Hunks) != 0 {
+		t.Errorf("identical files have hunks: %+v", fd.Hunks)
+	}
+
+	if _, err := r.CompareFiles("main", false, "hello.go", "missing.go"); err == nil {
+		t.Error("comparing with a missing file should fail")
+	}
+}
+
+func TestChangedFilesCopies(t *testing.T) {
+	dir := setupTestRepo(t)
+	// A copy of hello.go as it was before the branch modified it, with a
+	// comment added
+	if err := os.WriteFile(filepath.Join(dir, "copy.go"), []byte("package main\n\nfunc hello() {}\n\n// copied\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCmd(t, dir, "git", "add", ".")
+	runCmd(t, dir, "git", "commit", "-m", "copy")
+
+	r := &Runner{Dir: dir}
+	files, err := r.ChangedFiles("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := slices.IndexFunc(files, func(f ChangedFile) bool { return f.Path == "copy.go" }); i < 0 || files[i].Status != "C" || files[i].From != "hello.go" {
+		t.Errorf("ChangedFiles() = %+v, want copy.go copied from hello.go", files)
+	}
+}
+
+func TestFetchHead(t *testing.T) {
+	origin := setupTestRepo(t)
+	dir := t.TempDir()
+	runCmd(t, dir, "git", "clone", "--quiet", "--branch", "main", origin, ".")
+	r := &Runner{Dir: dir}
+
+	cleanup, err := r.FetchHead("origin", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch, _ := r.CurrentBranch(); branch != "origin/feature" {
+		t.Errorf("CurrentBranch() = %q, want origin/feature, apart from a local feature", branch)
+	}
+	// main moving on after feature forked doesn't show as undone by feature
+	os.WriteFile(filepath.Join(dir, "later.go"), []byte("package main\n"), 0o644)
+	runCmd(t, dir, "git", "add", "later.go")
+	runCmd(t, dir, "git", "-c", "user.email=test@test.com", "-c", "user.name=Test", "commit", "-qm", "later")
+	base, err := r.MergeBase("main", r.Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := r.ChangedFiles(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("got %d changed files, want 2", len(files))
+	}
+	// Nothing local changes: no branch, and the checkout stays on main
+	if r.BranchExists("refs/heads/feature") {
+		t.Error("FetchHead should not create a local branch")
+	}
+	if out, _ := r.run("symbolic-ref", "--short", "HEAD"); strings.TrimSpace(out) != "main" {
+		t.Errorf("checked out %q, want main", strings.TrimSpace(out))
+	}
+
+	cleanup()
+	if r.BranchExists(r.Head) {
+		t.Error("cleanup should delete the fetched ref")
+	}
+
+	if _, err := r.FetchHead("origin", "no-such-branch"); err == nil {
+		t.Error("fetching a missing branch should fail")
+	}
+}
+
+func TestFetchBranch(t *testing.T) {
+	origin := setupTestRepo(t)
+	dir := t.TempDir()
+	runCmd(t, dir, "git", "clone", "--quiet", "--branch", "main", origin, ".")
+	r := &Runner{Dir: dir}
+	runCmd(t, origin, "git", "checkout", "-q", "main")
+	os.WriteFile(filepath.Join(origin, "later.go"), []byte("package main\n"), 0o644)
+	runCmd(t, origin, "git", "add", "later.go")
+	runCmd(t, origin, "git", "commit", "-qm", "later")
+
+	ref, cleanup, err := r.FetchBranch("origin", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched, _ := r.run("rev-parse", ref)
+	want, _ := (&Runner{Dir: origin}).run("rev-parse", "main")
+	if fetched != want {
+		t.Errorf("fetched %s, want origin's main %s", fetched, want)
+	}
+	cleanup()
+	if r.BranchExists(ref) {
+		t.Error("cleanup should delete the fetched ref")
+	}
+}
+
+func TestFetchPullRequest(t *testing.T) {
+	origin := setupTestRepo(t)
+	runCmd(t, origin, "git", "update-ref", "refs/merge-requests/7/head", "feature")
+	dir := t.TempDir()
+	runCmd(t, dir, "git", "clone", "--quiet", "--branch", "main", origin, ".")
+	r := &Runner{Dir: dir}
+
+	cleanup, err := r.FetchPullRequest("origin", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch, _ := r.CurrentBranch(); branch != "pull/7" {
+		t.Errorf("CurrentBranch() = %q, want pull/7", branch)
+	}
+	files, err := r.ChangedFiles("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("got %d changed files, want 2", len(files))
+	}
+	cleanup()
+	if r.BranchExists(r.Head) {
+		t.Error("cleanup should delete the fetched ref")
+	}
+
+	if _, err := r.FetchPullRequest("origin", 8); err == nil {
+		t.Error("fetching a missing pull request should fail")
+	}
+}
+
+func TestChangeID(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+	if id, err := r.ChangeID(); err != nil || id != "" {
+		t.Errorf("ChangeID() without a trailer = %q, %v; want empty", id, err)
+	}
+
+	runCmd(t, dir, "git", "commit", "--allow-empty", "-m", "Fix things\n\nChange-Id: I8473b95934b5732ac55d26311a706c9c2bde9940")
+	id, err := r.ChangeID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "I8473b95934b5732ac55d26311a706c9c2bde9940" {
+		t.Errorf("ChangeID() = %q", id)
+	}
+}
+
+func TestRemoteURL(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+	if _, err := r.RemoteURL("origin"); err == nil {
+		t.Error("expected an error for a missing remote")
+	}
+
+	runCmd(t, dir, "git", "remote", "add", "origin", "https://dev.azure.com/acme/Platform/_git/api")
+	url, err := r.RemoteURL("origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://dev.azure.com/acme/Platform/_git/api" {
+		t.Errorf("RemoteURL() = %q", url)
+	}
+}
+
 func TestCurrentBranch(t *testing.T) {
 	dir := setupTestRepo(t)
 	r := &Runner{Dir: dir}
@@ -102,6 +362,45 @@ func TestCurrentBranch(t *testing.T) {
 	}
 }
 
+func TestCurrentBranchDetached(t *testing.T) {
+	dir := setupTestRepo(t)
+	runCmd(t, dir, "git", "checkout", "-q", "--detach")
+	r := &Runner{Dir: dir}
+
+	want, err := r.run("rev-parse", "--short", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, err := r.CurrentBranch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch != strings.TrimSpace(want) {
+		t.Errorf("branch = %q, want the short SHA %q", branch, strings.TrimSpace(want))
+	}
+	if r.IsUnborn() {
+		t.Error("a detached HEAD is not unborn")
+	}
+}
+
+func TestUnbornBranch(t *testing.T) {
+	dir := t.TempDir()
+	runCmd(t, dir, "git", "init", "-q")
+	runCmd(t, dir, "git", "symbolic-ref", "HEAD", "refs/heads/trunk")
+	r := &Runner{Dir: dir}
+
+	if !r.IsUnborn() {
+		t.Error("a repository without commits should be unborn")
+	}
+	branch, err := r.CurrentBranch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch != "trunk" {
+		t.Errorf("branch = %q, want %q", branch, "trunk")
+	}
+}
+
 func TestIsGitRepo(t *testing.T) {
 	dir := setupTestRepo(t)
 	r := &Runner{Dir: dir}
@@ -208,6 +507,36 @@ func TestUncommittedFiles(t *testing.T) {
 	}
 }
 
+func TestUncommittedFilesExclude(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir, Exclude: []string{".env.local", "build/"}}
+
+	for _, name := range []string{".env.local", "build/out.txt", "keep.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := r.UncommittedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "keep.go" {
+		t.Errorf("files = %v, want only keep.go", files)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "keep.go")); err != nil {
+		t.Fatal(err)
+	}
+	if r.HasUncommittedChanges() {
+		t.Error("excluded files alone shouldn't count as uncommitted changes")
+	}
+}
+
 func TestUncommittedFilesBinary(t *testing.T) {
 	dir := setupTestRepo(t)
 	r := &Runner{Dir: dir}
@@ -323,3 +652,362 @@ func TestDefaultBranchWithRemote(t *testing.T) {
 		t.Errorf("DefaultBranch = %q, want %q", branch, "main")
 	}
 }
+
+func TestWorktreeFileDiff(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	// Uncommitted edit on top of the committed feature change
+	content := "package main\n\nfunc hello() {\n\tfmt.Println(\"hello\")\n}\n\nfunc dirty() {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "hello.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.WorktreeChangedFiles("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected 2 changed files, got %d", len(files))
+	}
+
+	fd, err := r.WorktreeFileDiff("main", "hello.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sawDirty bool
+	for _, h := range fd.Hunks {
+		for _, l := range h.Lines {
+			if l.Content == "func dirty() {}" && h.Dirty {
+				sawDirty = true
+			}
+		}
+	}
+	if !sawDirty {
+		t.Error("hunk containing the uncommitted change should be marked Dirty")
+	}
+
+	committed, err := r.WorktreeFileDiff("main", "world.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range committed.Hunks {
+		if h.Dirty {
+			t.Error("committed-only file should have no dirty hunks")
+		}
+	}
+}
+
+func TestMarkDirtyHunks(t *testing.T) {
+	fd := &FileDiff{Hunks: []Hunk{
+		{NewStart: 1, NewCount: 5},
+		{NewStart: 20, NewCount: 3},
+		{NewStart: 40, NewCount: 0},
+	}}
+	markDirtyHunks(fd, []Hunk{{NewStart: 21, NewCount: 1}, {NewStart: 40, NewCount: 0}})
+
+	want := []bool{false, true, true}
+	for i, h := range fd.Hunks {
+		if h.Dirty != want[i] {
+			t.Errorf("hunk %d: Dirty = %v, want %v", i, h.Dirty, want[i])
+		}
+	}
+}
+
+func TestGitDir(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+	gitDir, err := r.GitDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filepath.IsAbs(gitDir) || filepath.Base(gitDir) != ".git" {
+		t.Errorf("GitDir() = %q, want absolute path ending in .git", gitDir)
+	}
+}
+
+func TestTopLevel(t *testing.T) {
+	dir := setupTestRepo(t)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	top, err := (&Runner{Dir: sub}).TopLevel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := filepath.EvalSymlinks(dir)
+	if got, _ := filepath.EvalSymlinks(top); got != want {
+		t.Errorf("TopLevel() = %q, want %q", top, dir)
+	}
+}
+
+func TestRunnerHead(t *testing.T) {
+	dir := setupTestRepo(t)
+	runCmd(t, dir, "git", "tag", "v1")
+	runCmd(t, dir, "git", "checkout", "main")
+
+	// HEAD is now main, so only an explicit Head shows the feature changes
+	r := &Runner{Dir: dir, Head: "v1"}
+	files, err := r.ChangedFiles("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected 2 changed files between main and v1, got %d", len(files))
+	}
+	fd, err := r.FileDiff("main", "world.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fd.Hunks) == 0 {
+		t.Error("expected hunks for world.go between main and v1")
+	}
+	if branch, _ := r.CurrentBranch(); branch != "v1" {
+		t.Errorf("CurrentBranch() = %q, want the reviewed head %q", branch, "v1")
+	}
+}
+
+func TestFormatPatch(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	out, err := r.FormatPatch("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "*** BLURB HERE ***") {
+		t.Error("expected a cover letter")
+	}
+	if !strings.Contains(out, "diff --git a/world.go b/world.go") {
+		t.Error("expected the branch's changes")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	out, err := r.Diff("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := ParseDiff(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := r.ChangedFiles("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != len(files) {
+		t.Errorf("got %d file diffs, want one for each of the %d changed files", len(diffs), len(files))
+	}
+}
+
+func TestResolveRange(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+	out, err := r.run("rev-parse", "--short", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainSHA := strings.TrimSpace(out)
+
+	tests := []struct {
+		spec               string
+		wantBase, wantHead string
+		wantErr            bool
+	}{
+		{"main..feature", "main", "feature", false},
+		{"main..", "main", "HEAD", false},
+		{"..main", "HEAD", "main", false},
+		{"feature...main", mainSHA, "main", false},
+		{"main", "", "", true},
+		{"main..nope", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			base, head, err := r.ResolveRange(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if base != tt.wantBase || head != tt.wantHead {
+				t.Errorf("got %q..%q, want %q..%q", base, head, tt.wantBase, tt.wantHead)
+			}
+		})
+	}
+}
+
+func TestBlameAges(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	tests := []struct {
+		rev       string
+		wantLines int
+	}{
+		{"main", 3},
+		{"feature", 5},
+	}
+	for _, tt := range tests {
+		ages, err := r.BlameAges(tt.rev, "hello.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ages) != tt.wantLines {
+			t.Errorf("BlameAges(%q) has %d lines, want %d", tt.rev, len(ages), tt.wantLines)
+		}
+		for line := 1; line <= tt.wantLines; line++ {
+			if ages[line].IsZero() {
+				t.Errorf("BlameAges(%q): line %d has no time", tt.rev, line)
+			}
+		}
+	}
+}
+
+func TestParseBlameIncremental(t *testing.T) {
+	const sha1 = "1111111111111111111111111111111111111111"
+	const sha2 = "2222222222222222222222222222222222222222"
+	raw := sha2 + " 3 3 2\n" +
+		"author New\n" +
+		"author-time 2000\n" +
+		"summary newer\n" +
+		"filename hello.go\n" +
+		sha1 + " 1 1 2\n" +
+		"author Old\n" +
+		"author-time 1000\n" +
+		"summary older\n" +
+		"boundary\n" +
+		"filename hello.go\n" +
+		sha2 + " 5 5 1\n" +
+		"filename hello.go\n"
+
+	ages := ParseBlameIncremental(raw)
+	want := map[int]int64{1: 1000, 2: 1000, 3: 2000, 4: 2000, 5: 2000}
+	if len(ages) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(ages), len(want))
+	}
+	for line, unix := range want {
+		if got := ages[line].Unix(); got != unix {
+			t.Errorf("line %d: time %d, want %d", line, got, unix)
+		}
+	}
+}
+
+func TestUserName(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+	if got := r.UserName(); got != "Test" {
+		t.Errorf("UserName() = %q, want %q", got, "Test")
+	}
+}
+
+func TestInterdiff(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	reviewed, err := r.HeadCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The author pushes a follow-up that only touches world.go
+	if err := os.WriteFile(filepath.Join(dir, "world.go"), []byte("package main\n\nfunc world() {}\n\nfunc more() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCmd(t, dir, "git", "commit", "-am", "follow-up")
+
+	revised, err := r.Interdiff(reviewed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := revised["hello.go"]; ok {
+		t.Error("hello.go is unchanged since the review and shouldn't be revised")
+	}
+	for path, want := range map[string]bool{"hello.go": false, "world.go": true} {
+		fd, err := r.FileDiff("main", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		MarkRevisedHunks(fd, revised[path])
+		if len(fd.Hunks) != 1 || fd.Hunks[0].Revised != want {
+			t.Errorf("%s: hunks %+v, want one with Revised = %v", path, fd.Hunks, want)
+		}
+	}
+
+	if _, err := r.Interdiff("0000000000000000000000000000000000000000"); err == nil {
+		t.Error("expected an error for an unknown commit")
+	}
+}
+
+func TestUnusualPaths(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	// git quotes non-ASCII and quote characters unless -z is used
+	committed, untracked := "a bé.go", `say "hi".go`
+	if err := os.WriteFile(filepath.Join(dir, committed), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCmd(t, dir, "git", "add", ".")
+	runCmd(t, dir, "git", "commit", "-m", "unusual name")
+	if err := os.WriteFile(filepath.Join(dir, untracked), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.ChangedFiles("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(files, ChangedFile{Path: committed, Status: "A"}) {
+		t.Errorf("ChangedFiles() = %v, want it to include %q", files, committed)
+	}
+	fd, err := r.FileDiff("main", committed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fd.Hunks) != 1 {
+		t.Errorf("FileDiff(%q) has %d hunks, want 1", committed, len(fd.Hunks))
+	}
+
+	files, err = r.UncommittedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(files, ChangedFile{Path: untracked, Status: "A"}) {
+		t.Errorf("UncommittedFiles() = %v, want it to include %q", files, untracked)
+	}
+}
+
+func TestDirFiles(t *testing.T) {
+	dir := setupTestRepo(t)
+	r := &Runner{Dir: dir}
+
+	old, new, err := r.DirFiles("main", false, ".", ".go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"hello.go": "package main\n\nfunc hello() {}\n"}; !reflect.DeepEqual(old, want) {
+		t.Errorf("old = %q, want %q", old, want)
+	}
+	if got := slices.Sorted(maps.Keys(new)); !slices.Equal(got, []string{"hello.go", "world.go"}) {
+		t.Errorf("new files = %q", got)
+	}
+
+	// The working tree, where the directory only exists after the change
+	if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.go": "package pkg\n", "notes.txt": "not Go\n"} {
+		if err := os.WriteFile(filepath.Join(dir, "pkg", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old, new, err = r.DirFiles("main", true, "pkg", ".go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old) != 0 || !reflect.DeepEqual(new, map[string]string{"a.go": "package pkg\n"}) {
+		t.Errorf("pkg: old = %q, new = %q", old, new)
+	}
+}
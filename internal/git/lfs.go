@@ -0,0 +1,133 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// lfsVersion is the first line of every Git LFS pointer file.
+const lfsVersion = "version https://git-lfs.github.com/spec/v1"
+
+// LFSPointer is a Git LFS pointer file, the small stand-in committed in place
+// of a large object.
+type LFSPointer struct {
+	OID  string // e.g. "sha256:4d7a…"
+	Size int64
+}
+
+// String returns the pointer file content.
+func (p LFSPointer) String() string {
+	return fmt.Sprintf("%s\noid %s\nsize %d\n", lfsVersion, p.OID, p.Size)
+}
+
+// ParseLFSPointer parses content as a Git LFS pointer file.
+func ParseLFSPointer(content string) (LFSPointer, bool) {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) < 3 || lines[0] != lfsVersion {
+		return LFSPointer{}, false
+	}
+	var p LFSPointer
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			return LFSPointer{}, false
+		}
+		switch key {
+		case "oid":
+			p.OID = value
+		case "size":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return LFSPointer{}, false
+			}
+			p.Size = n
+		}
+	}
+	if p.OID == "" {
+		return LFSPointer{}, false
+	}
+	return p, true
+}
+
+// maxLFSPointerLines bounds the diff lines a pointer diff can have: a few
+// lines per side, including optional extension lines.
+const maxLFSPointerLines = 16
+
+// LFSPointers returns the pointers on each side of fd when fd is the diff of
+// a Git LFS pointer file. A side is nil when the file is added or deleted.
+func (fd *FileDiff) LFSPointers() (old, new *LFSPointer, ok bool) {
+	if len(fd.Hunks) != 1 || len(fd.Hunks[0].Lines) > maxLFSPointerLines {
+		return nil, nil, false
+	}
+	var oldText, newText strings.Builder
+	for _, l := range fd.Hunks[0].Lines {
+		if l.Type != LineAdded {
+			oldText.WriteString(l.Content + "\n")
+		}
+		if l.Type != LineRemoved {
+			newText.WriteString(l.Content + "\n")
+		}
+	}
+	side := func(text string) (*LFSPointer, bool) {
+		if text == "" {
+			return nil, true
+		}
+		p, ok := ParseLFSPointer(text)
+		return &p, ok
+	}
+	old, oldOK := side(oldText.String())
+	new, newOK := side(newText.String())
+	if !oldOK || !newOK || (old == nil && new == nil) {
+		return nil, nil, false
+	}
+	return old, new, true
+}
+
+// LFSDiff fetches the objects behind a pointer diff with `git lfs smudge` and
+// returns the diff of their contents for path. Either pointer may be nil for
+// an added or deleted file. Binary objects produce a FileDiff with no hunks.
+func (r *Runner) LFSDiff(path string, old, new *LFSPointer) (*FileDiff, error) {
+	dir, err := os.MkdirTemp("", "revui-lfs-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath, err := r.smudge(old, filepath.Join(dir, "old"))
+	if err != nil {
+		return nil, err
+	}
+	newPath, err := r.smudge(new, filepath.Join(dir, "new"))
+	if err != nil {
+		return nil, err
+	}
+	return diffFiles(oldPath, newPath, path)
+}
+
+// smudge writes the object behind p to dest and returns dest, or returns
+// os.DevNull for a nil pointer.
+func (r *Runner) smudge(p *LFSPointer, dest string) (string, error) {
+	if p == nil {
+		return os.DevNull, nil
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	cmd := exec.Command("git", "lfs", "smudge")
+	cmd.Dir = r.Dir
+	cmd.Stdin = strings.NewReader(p.String())
+	cmd.Stdout = f
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git lfs smudge %s: %v %s", p.OID, err, strings.TrimSpace(stderr.String()))
+	}
+	return dest, nil
+}
@@ -0,0 +1,100 @@
+package git
+
+import "testing"
+
+const testPointer = "version https://git-lfs.github.com/spec/v1\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\nsize 12345\n"
+
+func TestParseLFSPointer(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    LFSPointer
+		wantOK  bool
+	}{
+		{
+			name:    "pointer",
+			content: testPointer,
+			want:    LFSPointer{OID: "sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", Size: 12345},
+			wantOK:  true,
+		},
+		{name: "plain text", content: "package main\n\nfunc main() {}\n"},
+		{name: "missing oid", content: "version https://git-lfs.github.com/spec/v1\nsize 1\nx y\n"},
+		{name: "bad size", content: "version https://git-lfs.github.com/spec/v1\noid sha256:ab\nsize big\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseLFSPointer(tt.content)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ParseLFSPointer() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if ok && got.String() != tt.content {
+				t.Errorf("String() = %q, want %q", got.String(), tt.content)
+			}
+		})
+	}
+}
+
+func TestLFSPointers(t *testing.T) {
+	const modified = `diff --git a/big.bin b/big.bin
+index 1111111..2222222 100644
+--- a/big.bin
++++ b/big.bin
+@@ -1,3 +1,3 @@
+ version https://git-lfs.github.com/spec/v1
+-oid sha256:aaaa
+-size 10
++oid sha256:bbbb
++size 20
+`
+	const added = `diff --git a/big.bin b/big.bin
+new file mode 100644
+index 0000000..2222222
+--- /dev/null
++++ b/big.bin
+@@ -0,0 +1,3 @@
++version https://git-lfs.github.com/spec/v1
++oid sha256:bbbb
++size 20
+`
+	const text = `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-package a
++package b
+`
+	tests := []struct {
+		name    string
+		diff    string
+		wantOld *LFSPointer
+		wantNew *LFSPointer
+		wantLFS bool
+	}{
+		{"modified", modified, &LFSPointer{OID: "sha256:aaaa", Size: 10}, &LFSPointer{OID: "sha256:bbbb", Size: 20}, true},
+		{"added", added, nil, &LFSPointer{OID: "sha256:bbbb", Size: 20}, true},
+		{"text", text, nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := ParseDiff(tt.diff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			old, new, ok := diffs[0].LFSPointers()
+			if ok != tt.wantLFS {
+				t.Fatalf("LFSPointers() ok = %v, want %v", ok, tt.wantLFS)
+			}
+			if !samePointer(old, tt.wantOld) || !samePointer(new, tt.wantNew) {
+				t.Errorf("LFSPointers() = %+v, %+v, want %+v, %+v", old, new, tt.wantOld, tt.wantNew)
+			}
+		})
+	}
+}
+
+func samePointer(a, b *LFSPointer) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
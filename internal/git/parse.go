@@ -2,6 +2,7 @@ package git
 
 import (
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -9,8 +10,23 @@ import (
 var (
 	diffHeaderRe = regexp.MustCompile(`^diff --git a/(.+) b/(.+)$`)
 	hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+	// combinedHunkRe matches a merge's combined diff hunk header, which has
+	// one old range per parent: "@@@ -1,5 -1,4 +1,6 @@@".
+	combinedHunkRe = regexp.MustCompile(`^@@@+ ((?:-\d+(?:,\d+)? )+)\+(\d+)(?:,(\d+))? @@@+`)
+	oldRangeRe     = regexp.MustCompile(`-(\d+)(?:,(\d+))?`)
+	combinedRe     = regexp.MustCompile(`^diff --(?:cc|combined) (.+)$`)
+	colorRe        = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 )
 
+// StripColor removes ANSI color escapes, such as those git writes to a pager
+// when color output is enabled, so the diff can be parsed.
+func StripColor(raw string) string {
+	if !strings.Contains(raw, "\x1b[") {
+		return raw
+	}
+	return colorRe.ReplaceAllString(raw, "")
+}
+
 // ParseDiff parses unified diff output into a slice of FileDiff values.
 func ParseDiff(raw string) ([]FileDiff, error) {
 	if raw == "" {
@@ -21,29 +37,65 @@ func ParseDiff(raw string) ([]FileDiff, error) {
 	lines := strings.Split(raw, "\n")
 
 	var current *FileDiff
+	// Lines still expected by the current hunk's header. Anything past them
+	// (such as a format-patch signature) isn't part of the hunk.
+	var oldLeft, newLeft int
+	// Lines still expected from each parent of a combined diff hunk, or nil
+	// in an ordinary diff.
+	var parentLeft []int
 
 	for i := range lines {
 		line := lines[i]
 
 		// Match diff --git header to start a new file diff.
-		if m := diffHeaderRe.FindStringSubmatch(line); m != nil {
+		if path, ok := parseDiffHeader(line); ok {
 			if current != nil {
 				diffs = append(diffs, *current)
 			}
 			current = &FileDiff{
-				Path: m[2],
+				Path: path,
 			}
+			parentLeft = nil
 			continue
 		}
+		if m := combinedRe.FindStringSubmatch(line); m != nil {
+			if current != nil {
+				diffs = append(diffs, *current)
+			}
+			current = &FileDiff{Path: unquotePath(m[1])}
+			parentLeft = nil
+			continue
+		}
+
+		inHunk := oldLeft > 0 || newLeft > 0 || slices.ContainsFunc(parentLeft, func(n int) bool { return n > 0 })
+
+		// The file headers name the paths unambiguously, so they override
+		// the path taken from the diff --git line.
+		if !inHunk && current != nil {
+			if status := headerStatus(line); status != "" {
+				current.Status = status
+			}
+			for _, prefix := range []string{"rename from ", "copy from "} {
+				if from, ok := strings.CutPrefix(line, prefix); ok {
+					current.OldPath = unquotePath(from)
+				}
+			}
+			if path, ok := fileHeaderPath(line); ok {
+				current.Path = path
+				continue
+			}
+		}
 
-		// Skip index, mode, and --- / +++ headers.
-		if strings.HasPrefix(line, "index ") ||
+		// Skip index, mode, and --- / +++ headers. Inside a hunk, "--- " is a
+		// removed line that begins with "-- ".
+		if !inHunk && (strings.HasPrefix(line, "index ") ||
 			strings.HasPrefix(line, "old mode ") ||
 			strings.HasPrefix(line, "new mode ") ||
 			strings.HasPrefix(line, "new file mode ") ||
 			strings.HasPrefix(line, "deleted file mode ") ||
+			strings.HasPrefix(line, "mode ") ||
 			strings.HasPrefix(line, "--- ") ||
-			strings.HasPrefix(line, "+++ ") {
+			strings.HasPrefix(line, "+++ ")) {
 			continue
 		}
 
@@ -60,6 +112,30 @@ func ParseDiff(raw string) ([]FileDiff, error) {
 				Header:   line,
 			}
 			current.Hunks = append(current.Hunks, h)
+			oldLeft, newLeft = h.OldCount, h.NewCount
+			parentLeft = nil
+			continue
+		}
+
+		// Combined diff hunks keep the first parent's range as the old side.
+		if m := combinedHunkRe.FindStringSubmatch(line); m != nil {
+			if current == nil {
+				continue
+			}
+			olds := oldRangeRe.FindAllStringSubmatch(m[1], -1)
+			parentLeft = make([]int, len(olds))
+			for i, o := range olds {
+				parentLeft[i] = atoiDefault(o[2], 1)
+			}
+			h := Hunk{
+				OldStart: atoi(olds[0][1]),
+				OldCount: parentLeft[0],
+				NewStart: atoi(m[2]),
+				NewCount: atoiDefault(m[3], 1),
+				Header:   line,
+			}
+			current.Hunks = append(current.Hunks, h)
+			oldLeft, newLeft = 0, h.NewCount
 			continue
 		}
 
@@ -69,35 +145,54 @@ func ParseDiff(raw string) ([]FileDiff, error) {
 		}
 
 		// Parse content lines within a hunk.
-		if current == nil || len(current.Hunks) == 0 {
+		if current == nil || len(current.Hunks) == 0 || !inHunk {
 			continue
 		}
 
 		hunk := &current.Hunks[len(current.Hunks)-1]
 
+		if parentLeft != nil {
+			l := parseCombinedLine(line, len(parentLeft))
+			if l.Type != LineRemoved {
+				newLeft--
+			}
+			for i := range parentLeft {
+				if inParent(l, i) {
+					parentLeft[i]--
+				}
+			}
+			hunk.Lines = append(hunk.Lines, l)
+			continue
+		}
+
 		switch {
 		case strings.HasPrefix(line, "+"):
 			hunk.Lines = append(hunk.Lines, Line{
 				Content: line[1:],
 				Type:    LineAdded,
 			})
+			newLeft--
 		case strings.HasPrefix(line, "-"):
 			hunk.Lines = append(hunk.Lines, Line{
 				Content: line[1:],
 				Type:    LineRemoved,
 			})
+			oldLeft--
 		case strings.HasPrefix(line, " "):
 			hunk.Lines = append(hunk.Lines, Line{
 				Content: line[1:],
 				Type:    LineContext,
 			})
-		case line == "" && !hunkComplete(hunk):
+			oldLeft--
+			newLeft--
+		case line == "":
 			// Empty lines within a hunk represent blank context lines.
-			// Only include them if the hunk still expects more lines.
 			hunk.Lines = append(hunk.Lines, Line{
 				Content: "",
 				Type:    LineContext,
 			})
+			oldLeft--
+			newLeft--
 		}
 	}
 
@@ -105,39 +200,158 @@ func ParseDiff(raw string) ([]FileDiff, error) {
 		diffs = append(diffs, *current)
 	}
 
-	// Assign line numbers to all hunks.
+	// Assign line numbers to all hunks, and a status to diffs whose headers
+	// didn't give one.
 	for i := range diffs {
 		for j := range diffs[i].Hunks {
 			assignLineNumbers(&diffs[i].Hunks[j])
 		}
+		diffs[i].Status = fileStatus(&diffs[i])
 	}
 
 	return diffs, nil
 }
 
-// hunkComplete returns true if the hunk has consumed all expected old and new lines.
-func hunkComplete(h *Hunk) bool {
-	var oldConsumed, newConsumed int
-	for _, l := range h.Lines {
-		switch l.Type {
-		case LineContext:
-			oldConsumed++
-			newConsumed++
-		case LineAdded:
-			newConsumed++
-		case LineRemoved:
-			oldConsumed++
+// parseDiffHeader returns the new-side path of a "diff --git a/... b/..."
+// line. Git C-quotes paths with unusual characters, and leaves paths with
+// spaces unquoted, so an unquoted header is split where both sides match.
+func parseDiffHeader(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, "diff --git ")
+	if !ok {
+		return "", false
+	}
+	// A quoted new path ends the line
+	if strings.HasSuffix(rest, `"`) {
+		if i := strings.LastIndex(rest, ` "b/`); i >= 0 {
+			if path, err := strconv.Unquote(rest[i+1:]); err == nil {
+				return strings.TrimPrefix(path, "b/"), true
+			}
+		}
+	}
+	if strings.HasPrefix(rest, `"a/`) {
+		if old, err := strconv.QuotedPrefix(rest); err == nil {
+			path, ok := strings.CutPrefix(rest[len(old):], " b/")
+			return path, ok
+		}
+	}
+	// Unchanged paths: "a/<path> b/<path>"
+	if n := len(rest) - len("a/ b/"); n > 0 && n%2 == 0 {
+		a, b := rest[2:2+n/2], rest[len(rest)-n/2:]
+		if a == b && rest[2+n/2:len(rest)-n/2] == " b/" {
+			return b, true
+		}
+	}
+	if m := diffHeaderRe.FindStringSubmatch(line); m != nil {
+		return m[2], true
+	}
+	return "", false
+}
+
+// headerStatus returns the file status an extended header line gives, such
+// as "A" for "new file mode 100644", or empty for other lines. A binary
+// file's "Binary files ... differ" line follows any other, so "B" wins.
+func headerStatus(line string) string {
+	switch {
+	case strings.HasPrefix(line, "new file mode "):
+		return "A"
+	case strings.HasPrefix(line, "deleted file mode "):
+		return "D"
+	case strings.HasPrefix(line, "rename from "):
+		return "R"
+	case strings.HasPrefix(line, "copy from "):
+		return "C"
+	case strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ"),
+		line == "GIT binary patch":
+		return "B"
+	}
+	return ""
+}
+
+// fileHeaderPath returns the path named by a "rename to", "copy to", "--- a/",
+// or "+++ b/" line. A deleted file's "+++ /dev/null" names nothing, leaving
+// the path from its "--- a/" line.
+func fileHeaderPath(line string) (string, bool) {
+	for _, prefix := range []string{"rename to ", "copy to "} {
+		if rest, ok := strings.CutPrefix(line, prefix); ok {
+			return unquotePath(rest), true
+		}
+	}
+	for _, prefix := range []string{"--- ", "+++ "} {
+		rest, ok := strings.CutPrefix(line, prefix)
+		if !ok {
+			continue
+		}
+		// git ends the line with a tab when the path has a space, and diff -u
+		// follows the tab with a timestamp
+		if i := strings.IndexByte(rest, '\t'); i >= 0 {
+			rest = rest[:i]
+		}
+		rest = unquotePath(rest)
+		side := "a/"
+		if prefix == "+++ " {
+			side = "b/"
+		}
+		return strings.CutPrefix(rest, side)
+	}
+	return "", false
+}
+
+// unquotePath undoes git's C-style quoting of a path, such as
+// "a b\303\251.go". Unquoted paths are returned as is.
+func unquotePath(path string) string {
+	if strings.HasPrefix(path, `"`) {
+		if s, err := strconv.Unquote(path); err == nil {
+			return s
 		}
 	}
-	return oldConsumed >= h.OldCount && newConsumed >= h.NewCount
+	return path
+}
+
+// parseCombinedLine parses a line of a combined diff hunk, whose first
+// parents columns mark the line's origin against each parent. A line added
+// against any parent is added; a line in no parent's result is removed.
+func parseCombinedLine(line string, parents int) Line {
+	if len(line) < parents {
+		// A blank context line may have lost its trailing spaces
+		return Line{Type: LineContext, Origin: strings.Repeat(" ", parents)}
+	}
+	l := Line{Content: line[parents:], Origin: line[:parents], Type: LineContext}
+	switch {
+	case strings.Contains(l.Origin, "-"):
+		l.Type = LineRemoved
+	case strings.Contains(l.Origin, "+"):
+		l.Type = LineAdded
+	}
+	return l
+}
+
+// inParent reports whether a combined diff line exists in the merge's parent
+// i: a result line the parent already had, or a line removed from it.
+func inParent(l Line, i int) bool {
+	if l.Type == LineRemoved {
+		return l.Origin[i] == '-'
+	}
+	return l.Origin[i] == ' '
 }
 
 // assignLineNumbers fills in OldLineNo and NewLineNo for each line in a hunk.
+// Combined diff lines are numbered against the merge's first parent.
 func assignLineNumbers(h *Hunk) {
 	oldNo := h.OldStart
 	newNo := h.NewStart
 
 	for i := range h.Lines {
+		if l := &h.Lines[i]; l.Origin != "" {
+			if l.Type != LineRemoved {
+				l.NewLineNo = newNo
+				newNo++
+			}
+			if inParent(*l, 0) {
+				l.OldLineNo = oldNo
+				oldNo++
+			}
+			continue
+		}
 		switch h.Lines[i].Type {
 		case LineContext:
 			h.Lines[i].OldLineNo = oldNo
@@ -155,6 +369,8 @@ func assignLineNumbers(h *Hunk) {
 }
 
 // ParseNameStatus parses git diff --name-status output into a slice of ChangedFile values.
+// Renames and copies are listed under their new path, and copies with the
+// path they were copied from.
 func ParseNameStatus(raw string) []ChangedFile {
 	if raw == "" {
 		return nil
@@ -166,18 +382,75 @@ func ParseNameStatus(raw string) []ChangedFile {
 		if line == "" {
 			continue
 		}
-		parts := strings.SplitN(line, "\t", 2)
-		if len(parts) != 2 {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 2 {
 			continue
 		}
-		files = append(files, ChangedFile{
-			Status: parts[0],
-			Path:   parts[1],
-		})
+		f := ChangedFile{
+			Status: nameStatus(parts[0]),
+			Path:   unquotePath(parts[len(parts)-1]),
+		}
+		if f.Status == "C" && len(parts) == 3 {
+			f.From = unquotePath(parts[1])
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// ParseNameStatusZ parses git diff --name-status -z output, whose paths are
+// NUL-separated and never quoted.
+func ParseNameStatusZ(raw string) []ChangedFile {
+	fields := strings.Split(strings.TrimSuffix(raw, "\x00"), "\x00")
+	var files []ChangedFile
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := fields[i]
+		var from string
+		if strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C") {
+			// Renames and copies list the old path, then the new one
+			i++
+			if i+1 >= len(fields) {
+				break
+			}
+			if status[0] == 'C' {
+				from = fields[i]
+			}
+		}
+		files = append(files, ChangedFile{Status: nameStatus(status), Path: fields[i+1], From: from})
 	}
 	return files
 }
 
+// ParseLog parses git log --name-only output formatted with
+// --format=%x00%h%x09%s, in which each commit starts with a NUL.
+func ParseLog(raw string) []Commit {
+	var commits []Commit
+	for record := range strings.SplitSeq(raw, "\x00") {
+		lines := strings.Split(strings.Trim(record, "\n"), "\n")
+		hash, subject, ok := strings.Cut(lines[0], "\t")
+		if !ok {
+			continue
+		}
+		c := Commit{Hash: hash, Subject: subject}
+		for _, line := range lines[1:] {
+			if line != "" {
+				c.Files = append(c.Files, unquotePath(line))
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// nameStatus drops the similarity score from rename and copy statuses, such
+// as "R100".
+func nameStatus(status string) string {
+	if len(status) > 1 && (status[0] == 'R' || status[0] == 'C') {
+		return status[:1]
+	}
+	return status
+}
+
 func atoi(s string) int {
 	n, _ := strconv.Atoi(s)
 	return n
@@ -2,7 +2,10 @@ package git
 
 import (
 	"os"
+	"reflect"
 	"testing"
+
+	"github.com/deparker/revui/internal/difftest"
 )
 
 func TestParseFileDiff(t *testing.T) {
@@ -168,6 +171,248 @@ func TestParseNameStatus(t *testing.T) {
 	}
 }
 
+func TestParseLog(t *testing.T) {
+	raw := "\x00a1b2c3d\tAdd parser\n\nparse.go\n\"n\\303\\251.go\"\n\x00e4f5a6b\tEmpty commit\n\x00c7d8e9f\tFix\tthings\n\nparse.go\n"
+	want := []Commit{
+		{Hash: "a1b2c3d", Subject: "Add parser", Files: []string{"parse.go", "né.go"}},
+		{Hash: "e4f5a6b", Subject: "Empty commit"},
+		{Hash: "c7d8e9f", Subject: "Fix\tthings", Files: []string{"parse.go"}},
+	}
+	if got := ParseLog(raw); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLog() = %+v, want %+v", got, want)
+	}
+	if got := ParseLog(""); got != nil {
+		t.Errorf("ParseLog(\"\") = %+v, want nil", got)
+	}
+}
+
+func TestParseNameStatusUnusualPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		z    bool
+		want []ChangedFile
+	}{
+		{
+			name: "quoted",
+			raw:  "M\t\"a b\\303\\251.go\"\nA\twith space.go",
+			want: []ChangedFile{{Path: "a bé.go", Status: "M"}, {Path: "with space.go", Status: "A"}},
+		},
+		{
+			name: "rename",
+			raw:  "R100\told.go\tnew.go",
+			want: []ChangedFile{{Path: "new.go", Status: "R"}},
+		},
+		{
+			name: "copy",
+			raw:  "C075\tsrc.go\tcopy.go",
+			want: []ChangedFile{{Path: "copy.go", Status: "C", From: "src.go"}},
+		},
+		{
+			name: "nul separated",
+			raw:  "M\x00a \"q\".go\x00R087\x00old.go\x00dir/né w.go\x00C090\x00src.go\x00copy.go\x00D\x00gone.go\x00",
+			z:    true,
+			want: []ChangedFile{
+				{Path: `a "q".go`, Status: "M"},
+				{Path: "dir/né w.go", Status: "R"},
+				{Path: "copy.go", Status: "C", From: "src.go"},
+				{Path: "gone.go", Status: "D"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := ParseNameStatus
+			if tt.z {
+				parse = ParseNameStatusZ
+			}
+			if got := parse(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffHeaderPaths(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"diff --git a/main.go b/main.go", "main.go"},
+		{"diff --git a/with space.go b/with space.go", "with space.go"},
+		{"diff --git a/x b/y.go b/x b/y.go", "x b/y.go"},
+		{`diff --git "a/a b\303\251.go" "b/a b\303\251.go"`, "a bé.go"},
+		{`diff --git "a/tab\there.go" b/plain.go`, "plain.go"},
+		{`diff --git a/plain.go "b/quote\"d.go"`, `quote"d.go`},
+		{"diff --git a/old.go b/new.go", "new.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			diffs, err := ParseDiff(tt.header + "\n@@ -1 +1 @@\n-a\n+b\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(diffs) != 1 || diffs[0].Path != tt.want {
+				t.Errorf("got %+v, want path %q", diffs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffFileHeaderPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{
+			name: "space and b/ in path",
+			diff: "diff --git a/x b/y.go b/x b/y.go\n--- a/x b/y.go\t\n+++ b/x b/y.go\t\n@@ -1 +1 @@\n-a\n+b\n",
+			want: "x b/y.go",
+		},
+		{
+			name: "rename with changes",
+			diff: "diff --git a/old name.go b/new.go\nsimilarity index 90%\nrename from old name.go\nrename to new.go\n--- a/old name.go\t\n+++ b/new.go\n@@ -1 +1 @@\n-a\n+b\n",
+			want: "new.go",
+		},
+		{
+			name: "pure rename",
+			diff: "diff --git a/a b/c b/d\nsimilarity index 100%\nrename from a b/c\nrename to \"d\\303\\251\"\n",
+			want: "dé",
+		},
+		{
+			name: "deleted",
+			diff: "diff --git a/gone b/x.go b/gone b/x.go\ndeleted file mode 100644\n--- a/gone b/x.go\t\n+++ /dev/null\n@@ -1 +0,0 @@\n-a\n",
+			want: "gone b/x.go",
+		},
+		{
+			name: "diff -u timestamps",
+			diff: "diff --git a/lib.c b/lib.c\n--- a/lib.c\t2024-01-01 10:00:00.000000000 +0000\n+++ b/lib.c\t2024-01-02 10:00:00.000000000 +0000\n@@ -1 +1 @@\n-a\n+b\n",
+			want: "lib.c",
+		},
+		{
+			name: "quoted",
+			diff: "diff --git \"a/\\303\\251.go\" \"b/\\303\\251.go\"\n--- \"a/\\303\\251.go\"\n+++ \"b/\\303\\251.go\"\n@@ -1 +1 @@\n-a\n+b\n",
+			want: "é.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := ParseDiff(tt.diff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(diffs) != 1 || diffs[0].Path != tt.want {
+				t.Errorf("got %+v, want path %q", diffs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{
+			name: "modified",
+			diff: "diff --git a/x.go b/x.go\nindex 1234567..89abcde 100644\n--- a/x.go\n+++ b/x.go\n@@ -1 +1 @@\n-a\n+b\n",
+			want: "M",
+		},
+		{
+			name: "new file mode",
+			diff: "diff --git a/x.go b/x.go\nnew file mode 100644\n--- /dev/null\n+++ b/x.go\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+			want: "A",
+		},
+		{
+			name: "deleted file mode",
+			diff: "diff --git a/x.go b/x.go\ndeleted file mode 100644\n--- a/x.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-a\n",
+			want: "D",
+		},
+		{
+			name: "empty new file",
+			diff: "diff --git a/x.go b/x.go\nnew file mode 100644\nindex 0000000..e69de29\n",
+			want: "A",
+		},
+		{
+			name: "rename",
+			diff: "diff --git a/old.go b/new.go\nsimilarity index 100%\nrename from old.go\nrename to new.go\n",
+			want: "R",
+		},
+		{
+			name: "binary",
+			diff: "diff --git a/x.png b/x.png\nnew file mode 100644\nindex 0000000..1234567\nBinary files /dev/null and b/x.png differ\n",
+			want: "B",
+		},
+		{
+			name: "mode change",
+			diff: "diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n",
+			want: "M",
+		},
+		{
+			name: "added without mode lines",
+			diff: "diff --git a/x.go b/x.go\n--- /dev/null\n+++ b/x.go\n@@ -0,0 +1 @@\n+a\n",
+			want: "A",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := ParseDiff(tt.diff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(diffs) != 1 || diffs[0].Status != tt.want {
+				t.Errorf("got %+v, want status %q", diffs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCombinedDiff(t *testing.T) {
+	// git show of a merge whose resolution keeps both sides' edits
+	raw := `diff --cc f
+index f4ea702,3b6f40a..59bdb4f
+--- a/f
++++ b/f
+@@@ -1,4 -1,4 +1,5 @@@
+  a
+ +B1
++ B2
+- b
+  c
+++d
+ -gone
+`
+	diffs, err := ParseDiff(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].Path != "f" || len(diffs[0].Hunks) != 1 {
+		t.Fatalf("got %+v, want one file f with one hunk", diffs)
+	}
+	h := diffs[0].Hunks[0]
+	if h.OldStart != 1 || h.OldCount != 4 || h.NewStart != 1 || h.NewCount != 5 {
+		t.Errorf("hunk ranges = -%d,%d +%d,%d, want -1,4 +1,5", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+	}
+
+	want := []Line{
+		{Content: "a", Type: LineContext, OldLineNo: 1, NewLineNo: 1, Origin: "  "},
+		{Content: "B1", Type: LineAdded, OldLineNo: 2, NewLineNo: 2, Origin: " +"},
+		{Content: "B2", Type: LineAdded, NewLineNo: 3, Origin: "+ "},
+		{Content: "b", Type: LineRemoved, OldLineNo: 3, Origin: "- "},
+		{Content: "c", Type: LineContext, OldLineNo: 4, NewLineNo: 4, Origin: "  "},
+		{Content: "d", Type: LineAdded, NewLineNo: 5, Origin: "++"},
+		{Content: "gone", Type: LineRemoved, Origin: " -"},
+	}
+	if !reflect.DeepEqual(h.Lines, want) {
+		t.Errorf("lines:\n got %+v\nwant %+v", h.Lines, want)
+	}
+	if got := h.Lines[1].Marker(); got != " +" {
+		t.Errorf("Marker() = %q, want the per-parent origin", got)
+	}
+}
+
 func TestParseNameStatusEmpty(t *testing.T) {
 	files := ParseNameStatus("")
 	if len(files) != 0 {
@@ -196,3 +441,64 @@ func BenchmarkParseDiff(b *testing.B) {
 		ParseDiff(input)
 	}
 }
+
+// BenchmarkParseDiffLarge parses a generated 50k-line diff, and any diffs
+// captured into difftest's testdata with `revui bench --capture`.
+func BenchmarkParseDiffLarge(b *testing.B) {
+	cases, err := difftest.Cases(difftest.Dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, c := range cases {
+		b.Run(c.Name, func(b *testing.B) {
+			b.SetBytes(int64(len(c.Diff)))
+			for b.Loop() {
+				ParseDiff(c.Diff)
+			}
+		})
+	}
+}
+
+func TestParseFormatPatch(t *testing.T) {
+	raw, err := os.ReadFile("testdata/format.patch")
+	if err != nil {
+		t.Fatalf("reading test fixture: %v", err)
+	}
+	diffs, err := ParseDiff(string(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("got %d diffs, want 2", len(diffs))
+	}
+
+	// Lines that look like --- / +++ headers are content inside a hunk, not headers.
+	readme := diffs[0].Hunks[0].Lines
+	if len(readme) != 4 || readme[1].Content != "-- old separator" || readme[2].Content != "--- new separator" {
+		t.Errorf("unexpected README.md lines: %+v", readme)
+	}
+
+	// The trailing "-- " signature is past the hunk's counts and is dropped.
+	util := diffs[1].Hunks[0].Lines
+	if len(util) != 3 {
+		t.Fatalf("got %d util.go lines, want 3: %+v", len(util), util)
+	}
+	if util[1].Content != "" || util[1].NewLineNo != 2 {
+		t.Errorf("util.go line 2 = %+v, want blank line 2", util[1])
+	}
+}
+
+func TestStripColor(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"\x1b[1mdiff --git a/x b/x\x1b[m", "diff --git a/x b/x"},
+		{"\x1b[32m+added\x1b[m\n\x1b[1;31m-removed\x1b[0m", "+added\n-removed"},
+	}
+	for _, tt := range tests {
+		if got := StripColor(tt.in); got != tt.want {
+			t.Errorf("StripColor(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// Subset returns a copy of the hunk keeping only the changes for which keep
+// reports true. Other removed lines become context and other added lines are
+// dropped, so the result still applies to the old side of the file. It
+// returns false if no changes remain.
+func (h Hunk) Subset(keep func(*Line) bool) (Hunk, bool) {
+	out := h
+	out.Lines = nil
+	changed := false
+	for i := range h.Lines {
+		l := h.Lines[i]
+		if l.Type != LineContext && !keep(&h.Lines[i]) {
+			if l.Type == LineAdded {
+				continue
+			}
+			l.Type = LineContext
+		}
+		if l.Type != LineContext {
+			changed = true
+		}
+		out.Lines = append(out.Lines, l)
+	}
+	return out, changed
+}
+
+// Patch renders the file diff as a standalone patch that `git apply` accepts
+// against the old side. Hunk ranges are recomputed from the lines, so hunks
+// trimmed with Subset, or a subset of the file's hunks, produce a valid patch.
+// A renamed or copied file is patched from its OldPath.
+func (fd FileDiff) Patch() string {
+	var body strings.Builder
+	offset, newTotal := 0, 0
+	for _, h := range fd.Hunks {
+		oldCount, newCount := 0, 0
+		for _, l := range h.Lines {
+			if l.Type != LineAdded {
+				oldCount++
+			}
+			if l.Type != LineRemoved {
+				newCount++
+			}
+		}
+		// An empty range starts at the line before it, so the new start is
+		// one off from the old start when exactly one side is empty.
+		newStart := h.OldStart + offset
+		switch {
+		case oldCount == 0 && newCount > 0:
+			newStart++
+		case newCount == 0 && oldCount > 0:
+			newStart--
+		}
+		offset += newCount - oldCount
+		newTotal += newCount
+
+		fmt.Fprintf(&body, "@@ -%s +%s @@%s\n", hunkRange(h.OldStart, oldCount), hunkRange(newStart, newCount), hunkContext(h.Header))
+		for _, l := range h.Lines {
+			prefix := " "
+			switch l.Type {
+			case LineAdded:
+				prefix = "+"
+			case LineRemoved:
+				prefix = "-"
+			}
+			body.WriteString(prefix + l.Content + "\n")
+		}
+	}
+
+	oldPath := cmp.Or(fd.OldPath, fd.Path)
+	header := fmt.Sprintf("diff --git a/%s b/%s\n", oldPath, fd.Path)
+	if oldPath != fd.Path {
+		verb := "rename"
+		if fd.Status == "C" {
+			verb = "copy"
+		}
+		header += fmt.Sprintf("%s from %s\n%s to %s\n", verb, oldPath, verb, fd.Path)
+	}
+	oldName, newName := "a/"+oldPath, "b/"+fd.Path
+	if fd.Status == "A" {
+		oldName = "/dev/null"
+	}
+	if fd.Status == "D" && newTotal == 0 {
+		newName = "/dev/null"
+	}
+	return fmt.Sprintf("%s--- %s\n+++ %s\n%s", header, oldName, newName, body.String())
+}
+
+// hunkRange formats one side of a hunk header, omitting a count of one as git
+// does.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// hunkContext returns the text git printed after a hunk header's ranges,
+// including its leading space, or "" if there is none.
+func hunkContext(header string) string {
+	if len(header) < 2 {
+		return ""
+	}
+	if i := strings.Index(header[2:], "@@"); i >= 0 {
+		return header[i+4:]
+	}
+	return ""
+}
@@ -0,0 +1,205 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+const patchTestDiff = `diff --git a/list.txt b/list.txt
+--- a/list.txt
++++ b/list.txt
+@@ -1,4 +1,4 @@ header
+ one
+-two
+-three
++TWO
++THREE
+ four
+@@ -8,2 +8,3 @@
+ eight
++eight and a half
+ nine
+`
+
+func TestFileDiffPatch(t *testing.T) {
+	files, err := ParseDiff(patchTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := files[0]
+
+	// keepContent keeps only the changes whose content is listed.
+	keepContent := func(contents ...string) func(*Line) bool {
+		return func(l *Line) bool {
+			for _, c := range contents {
+				if l.Content == c {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		name  string
+		hunks func() []Hunk
+		want  string
+	}{
+		{
+			name:  "whole file",
+			hunks: func() []Hunk { return fd.Hunks },
+			want:  patchTestDiff,
+		},
+		{
+			name:  "second hunk alone",
+			hunks: func() []Hunk { return fd.Hunks[1:] },
+			want: `diff --git a/list.txt b/list.txt
+--- a/list.txt
++++ b/list.txt
+@@ -8,2 +8,3 @@
+ eight
++eight and a half
+ nine
+`,
+		},
+		{
+			name: "partial selection",
+			hunks: func() []Hunk {
+				first, _ := fd.Hunks[0].Subset(keepContent("two", "TWO"))
+				second, _ := fd.Hunks[1].Subset(keepContent("eight and a half"))
+				return []Hunk{first, second}
+			},
+			want: `diff --git a/list.txt b/list.txt
+--- a/list.txt
++++ b/list.txt
+@@ -1,4 +1,4 @@ header
+ one
+-two
+ three
++TWO
+ four
+@@ -8,2 +8,3 @@
+ eight
++eight and a half
+ nine
+`,
+		},
+		{
+			name: "selection removes lines",
+			hunks: func() []Hunk {
+				h, _ := fd.Hunks[0].Subset(keepContent("two", "three"))
+				return []Hunk{h, fd.Hunks[1]}
+			},
+			want: `diff --git a/list.txt b/list.txt
+--- a/list.txt
++++ b/list.txt
+@@ -1,4 +1,2 @@ header
+ one
+-two
+-three
+ four
+@@ -8,2 +6,3 @@
+ eight
++eight and a half
+ nine
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FileDiff{Path: fd.Path, Status: fd.Status, Hunks: tt.hunks()}.Patch()
+			if got != tt.want {
+				t.Errorf("Patch() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHunkSubsetNoChanges(t *testing.T) {
+	files, err := ParseDiff(patchTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, ok := files[0].Hunks[0].Subset(func(*Line) bool { return false })
+	if ok {
+		t.Error("Subset with nothing kept should report no changes")
+	}
+	if len(h.Lines) != 4 {
+		t.Errorf("got %d lines, want the 4 old-side lines as context", len(h.Lines))
+	}
+}
+
+func TestFileDiffPatchApplies(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	old := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\n"
+	if err := os.WriteFile(filepath.Join(dir, "list.txt"), []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ParseDiff(patchTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := files[0]
+	h, _ := fd.Hunks[0].Subset(func(l *Line) bool { return l.Content == "three" })
+	fd.Hunks = []Hunk{h, fd.Hunks[1]}
+	if err := os.WriteFile(filepath.Join(dir, "hunk.patch"), []byte(fd.Patch()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("git", "apply", "hunk.patch")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git apply: %v\n%s", err, out)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "list.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "one\ntwo\nfour\nfive\nsix\nseven\neight\neight and a half\nnine\n"
+	if string(got) != want {
+		t.Errorf("applied file = %q, want %q", got, want)
+	}
+}
+
+func TestFileDiffPatchRenamed(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "old.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ParseDiff("diff --git a/old.txt b/new.txt\nsimilarity index 50%\nrename from old.txt\nrename to new.txt\n--- a/old.txt\n+++ b/new.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+TWO\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := files[0]
+	if fd.OldPath != "old.txt" {
+		t.Fatalf("OldPath = %q, want old.txt", fd.OldPath)
+	}
+	want := "diff --git a/old.txt b/new.txt\nrename from old.txt\nrename to new.txt\n--- a/old.txt\n+++ b/new.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+TWO\n"
+	if got := fd.Patch(); got != want {
+		t.Fatalf("Patch() =\n%s\nwant:\n%s", got, want)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rename.patch"), []byte(fd.Patch()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("git", "apply", "rename.patch")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git apply: %v\n%s", err, out)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "new.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "one\nTWO\n" {
+		t.Errorf("applied file = %q, want %q", got, "one\nTWO\n")
+	}
+}
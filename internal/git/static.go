@@ -0,0 +1,55 @@
+package git
+
+import "fmt"
+
+// Static serves a fixed set of file diffs computed up front, for reviewing
+// content that isn't a git branch. It has no working tree, so it offers only
+// the Runner methods that list and diff changed files.
+type Static struct {
+	Name  string // shown in place of the branch name
+	Files []FileDiff
+}
+
+// CurrentBranch returns the source's display name.
+func (s *Static) CurrentBranch() (string, error) {
+	return s.Name, nil
+}
+
+// ChangedFiles returns every file in the source. The base is ignored.
+func (s *Static) ChangedFiles(base string) ([]ChangedFile, error) {
+	files := make([]ChangedFile, len(s.Files))
+	for i, fd := range s.Files {
+		files[i] = ChangedFile{Path: fd.Path, Status: fileStatus(&fd)}
+	}
+	return files, nil
+}
+
+// FileDiff returns the diff for path. The base is ignored.
+func (s *Static) FileDiff(base, path string) (*FileDiff, error) {
+	for i := range s.Files {
+		if s.Files[i].Path == path {
+			fd := s.Files[i]
+			fd.Status = fileStatus(&fd)
+			return &fd, nil
+		}
+	}
+	return nil, fmt.Errorf("no diff for %s", path)
+}
+
+// fileStatus returns the diff's status, inferring added or deleted from the
+// hunk ranges when neither it nor its headers recorded one.
+func fileStatus(fd *FileDiff) string {
+	if fd.Status != "" {
+		return fd.Status
+	}
+	if len(fd.Hunks) == 1 {
+		h := fd.Hunks[0]
+		switch {
+		case h.OldStart == 0 && h.OldCount == 0:
+			return "A"
+		case h.NewStart == 0 && h.NewCount == 0:
+			return "D"
+		}
+	}
+	return "M"
+}
@@ -0,0 +1,27 @@
+package git
+
+import "testing"
+
+func TestStatic(t *testing.T) {
+	s := &Static{
+		Name: "new",
+		Files: []FileDiff{
+			{Path: "a.go", Hunks: []Hunk{{OldStart: 0, OldCount: 0, NewStart: 1, NewCount: 2}}},
+			{Path: "b.go", Hunks: []Hunk{{OldStart: 3, OldCount: 2, NewStart: 3, NewCount: 1}}},
+		},
+	}
+
+	files, err := s.ChangedFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Status != "A" || files[1].Status != "M" {
+		t.Errorf("unexpected files: %+v", files)
+	}
+	if fd, err := s.FileDiff("", "b.go"); err != nil || fd.Path != "b.go" {
+		t.Errorf("FileDiff(b.go) = %v, %v", fd, err)
+	}
+	if _, err := s.FileDiff("", "missing.go"); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
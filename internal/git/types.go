@@ -1,5 +1,7 @@
 package git
 
+import "fmt"
+
 // LineType represents the type of a diff line.
 type LineType int
 
@@ -20,6 +22,26 @@ func (lt LineType) String() string {
 	}
 }
 
+// MarshalText encodes the line type as its string name for JSON persistence.
+func (lt LineType) MarshalText() ([]byte, error) {
+	return []byte(lt.String()), nil
+}
+
+// UnmarshalText decodes a line type from its string name.
+func (lt *LineType) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "added":
+		*lt = LineAdded
+	case "removed":
+		*lt = LineRemoved
+	case "context", "":
+		*lt = LineContext
+	default:
+		return fmt.Errorf("unknown line type %q", text)
+	}
+	return nil
+}
+
 // FileStatusString returns a human-readable string for a git status code.
 func FileStatusString(status string) string {
 	switch status {
@@ -31,6 +53,8 @@ func FileStatusString(status string) string {
 		return "deleted"
 	case "R":
 		return "renamed"
+	case "C":
+		return "copied"
 	case "B":
 		return "binary"
 	default:
@@ -44,6 +68,25 @@ type Line struct {
 	Type      LineType
 	OldLineNo int
 	NewLineNo int
+	// Origin holds a combined diff line's marker for each parent of a merge,
+	// such as " +" or "--". It is empty for ordinary diffs.
+	Origin string
+}
+
+// Marker returns the line's diff prefix: its per-parent origin in a combined
+// diff, otherwise "+", "-", or " ".
+func (l Line) Marker() string {
+	if l.Origin != "" {
+		return l.Origin
+	}
+	switch l.Type {
+	case LineAdded:
+		return "+"
+	case LineRemoved:
+		return "-"
+	default:
+		return " "
+	}
 }
 
 // Hunk represents a contiguous section of a diff.
@@ -54,17 +97,106 @@ type Hunk struct {
 	NewCount int
 	Header   string
 	Lines    []Line
+	Dirty    bool // overlaps uncommitted working tree changes
+	Revised  bool // changed since the previous review session
 }
 
 // FileDiff represents the diff for a single file.
 type FileDiff struct {
-	Path   string
-	Status string // A, M, D, R, B
-	Hunks  []Hunk
+	Path    string
+	OldPath string // the path a renamed or copied file had before, if any
+	Status  string // A, M, D, R, B
+	Hunks   []Hunk
 }
 
 // ChangedFile represents a file that changed between two refs.
 type ChangedFile struct {
 	Path   string
 	Status string
+	From   string // the file a copy (status C) was made from
+}
+
+// LineCount is how many lines a change to a file adds and removes.
+type LineCount struct {
+	Added, Removed int
+}
+
+// OldLineNo returns the old file's number for the context line shown at
+// newLine in the new file, or 0 if the diff shows no such line.
+func (fd *FileDiff) OldLineNo(newLine int) int {
+	for _, h := range fd.Hunks {
+		for _, l := range h.Lines {
+			if l.Type == LineContext && l.NewLineNo == newLine {
+				return l.OldLineNo
+			}
+		}
+	}
+	return 0
+}
+
+// NearestChange returns the changed lines closest to the context lines shown
+// from newStart to newEnd in the new file, in the same hunk: the added lines
+// of the nearest run of changes, by their new numbers, or for a run that only
+// removes lines, the removed lines by their old numbers, with removed set.
+// start is 0 when the diff shows no such context lines.
+func (fd *FileDiff) NearestChange(newStart, newEnd int) (start, end int, removed bool) {
+	for _, h := range fd.Hunks {
+		first, last := -1, -1
+		for i, l := range h.Lines {
+			if l.Type == LineContext && l.NewLineNo >= newStart && l.NewLineNo <= newEnd {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		// Runs of changed lines, the nearest first; an earlier run wins a tie.
+		bestFrom, bestTo, bestDistance := -1, -1, 0
+		for i := 0; i < len(h.Lines); i++ {
+			if h.Lines[i].Type == LineContext {
+				continue
+			}
+			j := i
+			for j+1 < len(h.Lines) && h.Lines[j+1].Type != LineContext {
+				j++
+			}
+			distance := max(first-j, i-last)
+			if bestFrom < 0 || distance < bestDistance {
+				bestFrom, bestTo, bestDistance = i, j, distance
+			}
+			i = j
+		}
+		if bestFrom < 0 {
+			return 0, 0, false
+		}
+		for _, l := range h.Lines[bestFrom : bestTo+1] {
+			if l.Type == LineAdded {
+				if start == 0 {
+					start = l.NewLineNo
+				}
+				end = l.NewLineNo
+			}
+		}
+		if start != 0 {
+			return start, end, false
+		}
+		return h.Lines[bestFrom].OldLineNo, h.Lines[bestTo].OldLineNo, true
+	}
+	return 0, 0, false
+}
+
+// Author is someone who has committed to the repository.
+type Author struct {
+	Name  string
+	Email string
+}
+
+// Commit is a commit on the branch under review and the files it touched.
+type Commit struct {
+	Hash    string // abbreviated
+	Subject string
+	Files   []string
 }
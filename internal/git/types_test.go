@@ -35,3 +35,62 @@ func TestFileStatusString(t *testing.T) {
 		}
 	}
 }
+
+func TestLineTypeTextRoundTrip(t *testing.T) {
+	for _, lt := range []LineType{LineContext, LineAdded, LineRemoved} {
+		text, err := lt.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got LineType
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if got != lt {
+			t.Errorf("round trip of %v = %v", lt, got)
+		}
+	}
+
+	var lt LineType
+	if err := lt.UnmarshalText([]byte("bogus")); err == nil {
+		t.Error("expected error for unknown line type")
+	}
+}
+
+func TestNearestChange(t *testing.T) {
+	fd := &FileDiff{Hunks: []Hunk{{Lines: []Line{
+		{Type: LineContext, OldLineNo: 1, NewLineNo: 1},
+		{Type: LineContext, OldLineNo: 2, NewLineNo: 2},
+		{Type: LineRemoved, OldLineNo: 3},
+		{Type: LineRemoved, OldLineNo: 4},
+		{Type: LineAdded, NewLineNo: 3},
+		{Type: LineAdded, NewLineNo: 4},
+		{Type: LineContext, OldLineNo: 5, NewLineNo: 5},
+		{Type: LineContext, OldLineNo: 6, NewLineNo: 6},
+		{Type: LineContext, OldLineNo: 7, NewLineNo: 7},
+		{Type: LineContext, OldLineNo: 8, NewLineNo: 8},
+		{Type: LineRemoved, OldLineNo: 9},
+		{Type: LineContext, OldLineNo: 10, NewLineNo: 9},
+	}}}}
+	tests := []struct {
+		name               string
+		newStart, newEnd   int
+		wantStart, wantEnd int
+		wantRemoved        bool
+	}{
+		{"before a replacement", 1, 2, 3, 4, false},
+		{"after a replacement", 5, 5, 3, 4, false},
+		{"tie goes to the earlier change", 6, 7, 3, 4, false},
+		{"before a removal", 8, 8, 9, 9, true},
+		{"not shown", 40, 40, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, removed := fd.NearestChange(tt.newStart, tt.newEnd)
+			if start != tt.wantStart || end != tt.wantEnd || removed != tt.wantRemoved {
+				t.Errorf("NearestChange(%d, %d) = %d, %d, %v, want %d, %d, %v",
+					tt.newStart, tt.newEnd, start, end, removed, tt.wantStart, tt.wantEnd, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// Package mcp serves a review over the Model Context Protocol so an AI agent
+// can read the diff and draft comments into the reviewer's session.
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// protocolVersion is the MCP revision spoken when the client doesn't ask for one.
+const protocolVersion = "2024-11-05"
+
+// Backend provides the review the server exposes.
+type Backend interface {
+	ChangedFiles() ([]git.ChangedFile, error)
+	FileDiff(path string) (*git.FileDiff, error)
+	Comments() ([]comment.Comment, error)
+	AddComment(c comment.Comment) error
+}
+
+// Server answers MCP requests against a Backend.
+type Server struct {
+	backend Backend
+	version string
+	client  string // the agent's name from initialize, recorded as comment author
+}
+
+// NewServer creates a server for the backend. version is reported to clients.
+func NewServer(backend Backend, version string) *Server {
+	return &Server{backend: backend, version: version}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes responses
+// to w until r is exhausted.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			if err := enc.Encode(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		// Notifications carry no id and get no response.
+		if len(req.ID) == 0 {
+			continue
+		}
+		resp := response{JSONRPC: "2.0", ID: req.ID}
+		resp.Result, resp.Error = s.handle(req)
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handle(req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+			ClientInfo      struct {
+				Name string `json:"name"`
+			} `json:"clientInfo"`
+		}
+		json.Unmarshal(req.Params, &params)
+		s.client = params.ClientInfo.Name
+		version := params.ProtocolVersion
+		if version == "" {
+			version = protocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "revui", "version": s.version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+		text, err := s.callTool(params.Name, params.Arguments)
+		if errors.Is(err, errUnknownTool) {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+		if err != nil {
+			// Tool failures are reported in the result so the model can see them.
+			return toolResult(err.Error(), true), nil
+		}
+		return toolResult(text, false), nil
+	default:
+		return nil, &rpcError{codeMethodNotFound, "method not found: " + req.Method}
+	}
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	result := map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+	}
+	if isError {
+		result["isError"] = true
+	}
+	return result
+}
+
+// FormatDiff renders a file diff as unified diff text.
+func FormatDiff(fd *git.FileDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", fd.Path, fd.Path)
+	if len(fd.Hunks) == 0 {
+		b.WriteString("(binary or empty diff)\n")
+	}
+	for _, h := range fd.Hunks {
+		b.WriteString(h.Header)
+		b.WriteByte('\n')
+		for _, l := range h.Lines {
+			switch l.Type {
+			case git.LineAdded:
+				b.WriteByte('+')
+			case git.LineRemoved:
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(l.Content)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
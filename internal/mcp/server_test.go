@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+type fakeBackend struct {
+	diffs    map[string]*git.FileDiff
+	comments []comment.Comment
+}
+
+func (f *fakeBackend) ChangedFiles() ([]git.ChangedFile, error) {
+	return []git.ChangedFile{{Path: "main.go", Status: "M"}}, nil
+}
+
+func (f *fakeBackend) FileDiff(path string) (*git.FileDiff, error) {
+	fd, ok := f.diffs[path]
+	if !ok {
+		return nil, errors.New("no diff for " + path)
+	}
+	return fd, nil
+}
+
+func (f *fakeBackend) Comments() ([]comment.Comment, error) { return f.comments, nil }
+
+func (f *fakeBackend) AddComment(c comment.Comment) error {
+	f.comments = append(f.comments, c)
+	return nil
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{diffs: map[string]*git.FileDiff{
+		"main.go": {
+			Path: "main.go",
+			Hunks: []git.Hunk{{
+				Header: "@@ -1,2 +1,2 @@",
+				Lines: []git.Line{
+					{Content: "package main", Type: git.LineContext, OldLineNo: 1, NewLineNo: 1},
+					{Content: "old", Type: git.LineRemoved, OldLineNo: 2},
+					{Content: "new", Type: git.LineAdded, NewLineNo: 2},
+				},
+			}},
+		},
+	}}
+}
+
+// roundTrip sends newline-delimited requests and decodes the responses.
+func roundTrip(t *testing.T, s *Server, reqs ...string) []response {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(strings.NewReader(strings.Join(reqs, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	var resps []response
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r response
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		resps = append(resps, r)
+	}
+	return resps
+}
+
+func resultText(t *testing.T, r response) (string, bool) {
+	t.Helper()
+	m, ok := r.Result.(map[string]any)
+	if !ok {
+		t.Fatalf("unexpected result %#v (error %+v)", r.Result, r.Error)
+	}
+	content := m["content"].([]any)[0].(map[string]any)
+	isError, _ := m["isError"].(bool)
+	return content["text"].(string), isError
+}
+
+func TestServeProtocol(t *testing.T) {
+	s := NewServer(newFakeBackend(), "test")
+	resps := roundTrip(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"bogus"}`,
+		`not json`,
+	)
+	if len(resps) != 4 {
+		t.Fatalf("got %d responses, want 4 (notifications get none)", len(resps))
+	}
+	if v := resps[0].Result.(map[string]any)["protocolVersion"]; v != "2025-03-26" {
+		t.Errorf("initialize should echo the client's protocol version, got %v", v)
+	}
+	if n := len(resps[1].Result.(map[string]any)["tools"].([]any)); n != len(tools) {
+		t.Errorf("tools/list returned %d tools, want %d", n, len(tools))
+	}
+	if resps[2].Error == nil || resps[2].Error.Code != codeMethodNotFound {
+		t.Errorf("unknown method should fail with method not found, got %+v", resps[2].Error)
+	}
+	if resps[3].Error == nil || resps[3].Error.Code != codeParseError {
+		t.Errorf("bad JSON should fail with a parse error, got %+v", resps[3].Error)
+	}
+}
+
+func TestServeTools(t *testing.T) {
+	backend := newFakeBackend()
+	s := NewServer(backend, "test")
+
+	tests := []struct {
+		name      string
+		call      string
+		wantText  string
+		wantError bool
+	}{
+		{"list files", `{"name":"list_changed_files"}`, `"path": "main.go"`, false},
+		{"diff", `{"name":"get_file_diff","arguments":{"path":"main.go"}}`, "-old\n+new\n", false},
+		{"missing diff", `{"name":"get_file_diff","arguments":{"path":"nope.go"}}`, "", true},
+		{"add on new side", `{"name":"add_comment","arguments":{"path":"main.go","line":2,"body":"nit"}}`, "main.go:2", false},
+		{"add on old side", `{"name":"add_comment","arguments":{"path":"main.go","line":2,"side":"old","body":"why?"}}`, "main.go:2", false},
+		{"add outside diff", `{"name":"add_comment","arguments":{"path":"main.go","line":9,"body":"x"}}`, "not part of the diff", true},
+		{"comments", `{"name":"get_comments","arguments":{"path":"main.go"}}`, `"body": "nit"`, false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := `{"jsonrpc":"2.0","id":` + string(rune('1'+i)) + `,"method":"tools/call","params":` + tt.call + `}`
+			resps := roundTrip(t, s, req)
+			text, isError := resultText(t, resps[0])
+			if isError != tt.wantError || !strings.Contains(text, tt.wantText) {
+				t.Errorf("got (%q, isError=%v), want text containing %q, isError=%v", text, isError, tt.wantText, tt.wantError)
+			}
+		})
+	}
+
+	if len(backend.comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d", len(backend.comments))
+	}
+	if backend.comments[0].LineType != git.LineAdded || backend.comments[1].LineType != git.LineRemoved {
+		t.Errorf("line types should come from the diff: %+v", backend.comments)
+	}
+
+	resps := roundTrip(t, s, `{"jsonrpc":"2.0","id":99,"method":"tools/call","params":{"name":"nope"}}`)
+	if resps[0].Error == nil || resps[0].Error.Code != codeInvalidParams {
+		t.Errorf("unknown tool should fail with invalid params, got %+v", resps[0].Error)
+	}
+}
+
+func TestServeCommentAuthor(t *testing.T) {
+	backend := newFakeBackend()
+	s := NewServer(backend, "test")
+
+	roundTrip(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"review-agent","version":"1.0"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"add_comment","arguments":{"path":"main.go","line":2,"body":"nit"}}}`,
+	)
+	if len(backend.comments) != 1 || backend.comments[0].Author != "review-agent" {
+		t.Errorf("comments = %+v, want one attributed to the client", backend.comments)
+	}
+}
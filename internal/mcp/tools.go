@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+var errUnknownTool = errors.New("unknown tool")
+
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+func objectSchema(props map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+var tools = []tool{
+	{
+		Name:        "list_changed_files",
+		Description: "List the files changed in the review with their git status (A, M, D, R, B).",
+		InputSchema: objectSchema(map[string]any{}),
+	},
+	{
+		Name:        "get_file_diff",
+		Description: "Get the unified diff for one changed file.",
+		InputSchema: objectSchema(map[string]any{
+			"path": map[string]any{"type": "string", "description": "File path as returned by list_changed_files"},
+		}, "path"),
+	},
+	{
+		Name:        "get_comments",
+		Description: "Get the review comments in the session, optionally for one file.",
+		InputSchema: objectSchema(map[string]any{
+			"path": map[string]any{"type": "string", "description": "Only return comments on this file"},
+		}),
+	},
+	{
+		Name:        "add_comment",
+		Description: "Add a review comment to the session. It replaces any comment starting on the same line.",
+		InputSchema: objectSchema(map[string]any{
+			"path":     map[string]any{"type": "string"},
+			"line":     map[string]any{"type": "integer", "description": "First line of the comment"},
+			"end_line": map[string]any{"type": "integer", "description": "Last line for a range comment (defaults to line)"},
+			"side":     map[string]any{"type": "string", "enum": []string{"new", "old"}, "description": "new (default) for added and unchanged lines, or old to address a removed line by its old line number"},
+			"body":     map[string]any{"type": "string"},
+		}, "path", "line", "body"),
+	},
+}
+
+func (s *Server) callTool(name string, args json.RawMessage) (string, error) {
+	var params struct {
+		Path    string `json:"path"`
+		Line    int    `json:"line"`
+		EndLine int    `json:"end_line"`
+		Side    string `json:"side"`
+		Body    string `json:"body"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	switch name {
+	case "list_changed_files":
+		files, err := s.backend.ChangedFiles()
+		if err != nil {
+			return "", err
+		}
+		type entry struct {
+			Path   string `json:"path"`
+			Status string `json:"status"`
+		}
+		out := make([]entry, len(files))
+		for i, f := range files {
+			out[i] = entry{f.Path, f.Status}
+		}
+		return marshal(out)
+
+	case "get_file_diff":
+		if params.Path == "" {
+			return "", errors.New("path is required")
+		}
+		fd, err := s.backend.FileDiff(params.Path)
+		if err != nil {
+			return "", err
+		}
+		return FormatDiff(fd), nil
+
+	case "get_comments":
+		comments, err := s.backend.Comments()
+		if err != nil {
+			return "", err
+		}
+		out := []comment.Comment{}
+		for _, c := range comments {
+			if params.Path == "" || c.FilePath == params.Path {
+				out = append(out, c)
+			}
+		}
+		return marshal(out)
+
+	case "add_comment":
+		c, err := s.newComment(params.Path, params.Line, params.EndLine, params.Side, params.Body)
+		if err != nil {
+			return "", err
+		}
+		if err := s.backend.AddComment(c); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added comment on %s:%d", c.FilePath, c.StartLine), nil
+
+	default:
+		return "", fmt.Errorf("%w: %s", errUnknownTool, name)
+	}
+}
+
+// newComment validates an add_comment request against the file's diff and
+// fills in the line type from the line being commented on.
+func (s *Server) newComment(path string, line, endLine int, side, body string) (comment.Comment, error) {
+	if path == "" || body == "" || line <= 0 {
+		return comment.Comment{}, errors.New("path, a positive line, and body are required")
+	}
+	if endLine < line {
+		endLine = line
+	}
+	fd, err := s.backend.FileDiff(path)
+	if err != nil {
+		return comment.Comment{}, err
+	}
+
+	for _, h := range fd.Hunks {
+		for _, l := range h.Lines {
+			var match bool
+			switch side {
+			case "old":
+				match = l.Type == git.LineRemoved && l.OldLineNo == line
+			case "", "new":
+				match = l.Type != git.LineRemoved && l.NewLineNo == line
+			default:
+				return comment.Comment{}, fmt.Errorf("side must be new or old, got %q", side)
+			}
+			if match {
+				c := comment.Comment{
+					FilePath:  path,
+					StartLine: line,
+					EndLine:   endLine,
+					LineType:  l.Type,
+					Body:      body,
+					Author:    s.client,
+				}
+				if l.Type == git.LineContext {
+					c.OldStartLine = l.OldLineNo
+					c.OldEndLine = fd.OldLineNo(endLine)
+					c.NearStartLine, c.NearEndLine, c.NearRemoved = fd.NearestChange(line, endLine)
+				}
+				return c, nil
+			}
+		}
+	}
+	return comment.Comment{}, fmt.Errorf("line %d is not part of the diff for %s", line, path)
+}
+
+func marshal(v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,430 @@
+package output
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/deparker/revui/internal/auth"
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// azureAPIVersion is the Azure DevOps REST API version requested.
+const azureAPIVersion = "7.1"
+
+// AzureDevOps is an Azure DevOps repository whose active pull request for the
+// reviewed branch receives the comments as threads. URL, Project, and Repo are
+// detected from the origin remote when left empty. Token is a personal access
+// token with Code (Read & Write) scope; when empty, it is resolved as
+// described on auth.Lookup, from AZURE_DEVOPS_EXT_PAT (as used by the Azure
+// CLI) or a git credential helper.
+type AzureDevOps struct {
+	URL     string `json:"url,omitempty"` // organization URL, e.g. https://dev.azure.com/acme
+	Project string `json:"project,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// ParseAzureRemote returns the organization URL, project, and repository of
+// an Azure DevOps remote URL, in any of its HTTPS, SSH, or legacy
+// visualstudio.com forms.
+func ParseAzureRemote(remote string) (AzureDevOps, bool) {
+	// git@ssh.dev.azure.com:v3/{org}/{project}/{repo}
+	if rest, ok := strings.CutPrefix(remote, "git@ssh.dev.azure.com:v3/"); ok {
+		parts := strings.Split(rest, "/")
+		if len(parts) != 3 {
+			return AzureDevOps{}, false
+		}
+		return azureRepo("https://dev.azure.com/"+parts[0], parts[1], parts[2])
+	}
+
+	u, err := url.Parse(remote)
+	if err != nil || u.Scheme != "https" {
+		return AzureDevOps{}, false
+	}
+	project, repo, ok := strings.Cut(strings.Trim(u.Path, "/"), "/_git/")
+	if !ok {
+		return AzureDevOps{}, false
+	}
+	switch {
+	case u.Host == "dev.azure.com":
+		// https://dev.azure.com/{org}/{project}/_git/{repo}
+		org, project, ok := strings.Cut(project, "/")
+		if !ok {
+			return AzureDevOps{}, false
+		}
+		return azureRepo("https://dev.azure.com/"+org, project, repo)
+	case strings.HasSuffix(u.Host, ".visualstudio.com"):
+		// https://{org}.visualstudio.com/{project}/_git/{repo}, sometimes
+		// with DefaultCollection before the project
+		project = strings.TrimPrefix(project, "DefaultCollection/")
+		return azureRepo("https://"+u.Host, project, repo)
+	}
+	return AzureDevOps{}, false
+}
+
+// azureRepo returns the repository, unescaping the project and repository
+// names as they appear in remote URLs.
+func azureRepo(org, project, repo string) (AzureDevOps, bool) {
+	project, err1 := url.PathUnescape(project)
+	repo, err2 := url.PathUnescape(strings.TrimSuffix(repo, ".git"))
+	if err1 != nil || err2 != nil || project == "" || repo == "" || strings.Contains(project, "/") {
+		return AzureDevOps{}, false
+	}
+	return AzureDevOps{URL: org, Project: project, Repo: repo}, true
+}
+
+// AzureDevOpsTargets returns an output target for the repository configured by
+// cfg, which may be nil, filling in whatever it leaves out from the origin
+// remote's URL. It returns none when the repository isn't known.
+func AzureDevOpsTargets(cfg *AzureDevOps, remoteURL string) []OutputTarget {
+	var repo AzureDevOps
+	if cfg != nil {
+		repo = *cfg
+	}
+	if detected, ok := ParseAzureRemote(remoteURL); ok {
+		repo.URL = cmp.Or(repo.URL, detected.URL)
+		repo.Project = cmp.Or(repo.Project, detected.Project)
+		repo.Repo = cmp.Or(repo.Repo, detected.Repo)
+	}
+	if repo.URL == "" || repo.Project == "" || repo.Repo == "" {
+		return nil
+	}
+	return []OutputTarget{{
+		Kind:        TargetAzureDevOps,
+		Label:       "Azure DevOps PR: " + repo.Project + "/" + repo.Repo,
+		AzureDevOps: &repo,
+	}}
+}
+
+// Lookup returns where the repository's credential is looked for.
+func (repo *AzureDevOps) Lookup() auth.Lookup {
+	var host string
+	if u, err := url.Parse(repo.URL); err == nil {
+		host = u.Host
+	}
+	l := auth.AzureDevOps(host)
+	l.Config = auth.Credential{Token: repo.Token}
+	return l
+}
+
+// azurePosition is a CommentPosition in the Azure DevOps API.
+type azurePosition struct {
+	Line   int `json:"line"`
+	Offset int `json:"offset"`
+}
+
+// azureThreadContext places a thread on a file, and on lines of its old
+// (left) or new (right) side.
+type azureThreadContext struct {
+	FilePath       string         `json:"filePath"`
+	LeftFileStart  *azurePosition `json:"leftFileStart,omitempty"`
+	LeftFileEnd    *azurePosition `json:"leftFileEnd,omitempty"`
+	RightFileStart *azurePosition `json:"rightFileStart,omitempty"`
+	RightFileEnd   *azurePosition `json:"rightFileEnd,omitempty"`
+}
+
+// azureComment is a Comment in the Azure DevOps API.
+type azureComment struct {
+	ParentCommentID int    `json:"parentCommentId"`
+	Content         string `json:"content"`
+	CommentType     int    `json:"commentType"` // 1 is text
+}
+
+// azureThread is a GitPullRequestCommentThread in the Azure DevOps API.
+type azureThread struct {
+	Comments      []azureComment     `json:"comments"`
+	Status        int                `json:"status"` // 1 is active
+	ThreadContext azureThreadContext `json:"threadContext"`
+}
+
+// deliverToAzureDevOps creates a thread for each comment on the active pull
+// request from the reviewed branch, or adds it to the thread it replies to,
+// then votes the verdict. Comments are first placed on the pull request's
+// diff, which may be against a different base. Azure DevOps has no draft
+// comments.
+func deliverToAzureDevOps(repo *AzureDevOps, review Review) (string, error) {
+	if review.hostBranch() == "" {
+		return "", errors.New("azure devops: the review has no branch to find a pull request for")
+	}
+	if review.Draft {
+		return "", errors.New("azure devops: pull requests don't take draft comments")
+	}
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return "", unsent(fmt.Errorf("azure devops: %w", err), review)
+	}
+	token := cred.Token
+	base := repo.pullRequestsURL()
+	id, err := azureFindPullRequest(token, repo, base, review.hostBranch())
+	if err != nil {
+		return "", unsent(fmt.Errorf("azure devops: %w", err), review)
+	}
+
+	comments := review.Comments
+	if len(comments) > 0 {
+		diff, err := azurePullRequestDiff(token, repo, base, id, comments)
+		if err != nil {
+			return "", unsent(fmt.Errorf("azure devops: getting the diff of PR %d: %w", id, err), review)
+		}
+		if comments, err = placeComments(comments, review.Diffs, diff, fmt.Sprintf("PR %d", id)); err != nil {
+			return "", fmt.Errorf("azure devops: %w", err)
+		}
+	}
+
+	threadsURL := fmt.Sprintf("%s/%d/threads?api-version=%s", base, id, azureAPIVersion)
+	for i, c := range comments {
+		if c.Thread != "" {
+			// A reply to the thread's first comment
+			u := fmt.Sprintf("%s/%d/threads/%s/comments?api-version=%s", base, id, url.PathEscape(c.Thread), azureAPIVersion)
+			reply := azureComment{ParentCommentID: 1, Content: c.Body, CommentType: 1}
+			if err := azureCall(token, http.MethodPost, u, reply, nil); err != nil {
+				err = fmt.Errorf("azure devops: posted %d of %d comments to PR %d: %w", i, len(review.Comments), id, err)
+				return "", unsent(err, review.remaining(comments[i:]))
+			}
+			continue
+		}
+		thread := azureThread{
+			Comments:      []azureComment{{Content: c.Body, CommentType: 1}},
+			Status:        1,
+			ThreadContext: azureThreadContext{FilePath: "/" + c.FilePath},
+		}
+		if c.StartLine > 0 {
+			start, end := &azurePosition{Line: c.StartLine, Offset: 1}, &azurePosition{Line: c.EndLine, Offset: 1}
+			if c.LineType == git.LineRemoved {
+				thread.ThreadContext.LeftFileStart, thread.ThreadContext.LeftFileEnd = start, end
+			} else {
+				thread.ThreadContext.RightFileStart, thread.ThreadContext.RightFileEnd = start, end
+			}
+		}
+		if err := azureCall(token, http.MethodPost, threadsURL, thread, nil); err != nil {
+			err = fmt.Errorf("azure devops: posted %d of %d comments to PR %d: %w", i, len(review.Comments), id, err)
+			return "", unsent(err, review.remaining(comments[i:]))
+		}
+	}
+	if review.Verdict != VerdictNone {
+		if err := azureVote(token, repo, base, id, review.Verdict); err != nil {
+			err = fmt.Errorf("azure devops: posted %d comments to PR %d, but voting failed: %w", len(review.Comments), id, err)
+			return "", unsent(err, review.remaining(nil))
+		}
+	}
+	return fmt.Sprintf("Posted %d comments to Azure DevOps PR %d%s", len(review.Comments), id, verdictNote(review.Verdict)), nil
+}
+
+// pullRequestsURL returns the URL of the repository's pull requests.
+func (repo *AzureDevOps) pullRequestsURL() string {
+	return fmt.Sprintf("%s/%s/_apis/git/repositories/%s/pullrequests",
+		strings.TrimSuffix(repo.URL, "/"), url.PathEscape(repo.Project), url.PathEscape(repo.Repo))
+}
+
+// azurePullRequestDiff returns the diff of pull request id, found under base,
+// for the files comments are on, with each whole file as context. Azure DevOps
+// serves files rather than diffs, so each is fetched as it was where the pull
+// request forked and as it is at its head, and diffed here. Files the pull
+// request doesn't change are left out.
+func azurePullRequestDiff(token string, repo *AzureDevOps, base string, id int, comments []comment.Comment) ([]git.FileDiff, error) {
+	var iterations struct {
+		Value []struct {
+			Source azureCommitRef `json:"sourceRefCommit"`
+			Target azureCommitRef `json:"targetRefCommit"`
+			Common azureCommitRef `json:"commonRefCommit"`
+		} `json:"value"`
+	}
+	u := fmt.Sprintf("%s/%d/iterations?api-version=%s", base, id, azureAPIVersion)
+	if err := azureCall(token, http.MethodGet, u, nil, &iterations); err != nil {
+		return nil, err
+	}
+	if len(iterations.Value) == 0 {
+		return nil, errors.New("the pull request has no commits")
+	}
+	latest := iterations.Value[len(iterations.Value)-1]
+	from, to := cmp.Or(latest.Common.CommitID, latest.Target.CommitID), latest.Source.CommitID
+
+	var diffs []git.FileDiff
+	seen := make(map[string]bool)
+	for _, c := range comments {
+		if c.Thread != "" || seen[c.FilePath] {
+			continue
+		}
+		seen[c.FilePath] = true
+		old, err := azureFileContent(token, repo, c.FilePath, from)
+		if err != nil {
+			return nil, err
+		}
+		new, err := azureFileContent(token, repo, c.FilePath, to)
+		if err != nil {
+			return nil, err
+		}
+		if old == new {
+			continue
+		}
+		fd, err := git.ContentDiff(c.FilePath, old, new)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, *fd)
+	}
+	return diffs, nil
+}
+
+// azureCommitRef names a commit in the Azure DevOps API.
+type azureCommitRef struct {
+	CommitID string `json:"commitId"`
+}
+
+// azureFileContent returns the content of the file at path as of commit, or
+// "" if it doesn't exist there.
+func azureFileContent(token string, repo *AzureDevOps, path, commit string) (string, error) {
+	query := url.Values{
+		"path":                          {"/" + path},
+		"versionDescriptor.version":     {commit},
+		"versionDescriptor.versionType": {"commit"},
+		"includeContent":                {"true"},
+		"api-version":                   {azureAPIVersion},
+	}
+	u := fmt.Sprintf("%s/%s/_apis/git/repositories/%s/items?%s",
+		strings.TrimSuffix(repo.URL, "/"), url.PathEscape(repo.Project), url.PathEscape(repo.Repo), query.Encode())
+	var item struct {
+		Content string `json:"content"`
+	}
+	if err := azureCall(token, http.MethodGet, u, nil, &item); err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("getting %s at %s: %w", path, commit, err)
+	}
+	return item.Content, nil
+}
+
+// azureFindPullRequest returns the ID of the active pull request from branch,
+// found under base, the repository's pull requests URL.
+func azureFindPullRequest(token string, repo *AzureDevOps, base, branch string) (int, error) {
+	query := url.Values{
+		"searchCriteria.sourceRefName": {"refs/heads/" + branch},
+		"searchCriteria.status":        {"active"},
+		"api-version":                  {azureAPIVersion},
+	}
+	var prs struct {
+		Value []struct {
+			ID int `json:"pullRequestId"`
+		} `json:"value"`
+	}
+	if err := azureCall(token, http.MethodGet, base+"?"+query.Encode(), nil, &prs); err != nil {
+		return 0, fmt.Errorf("finding the pull request for %s: %w", branch, err)
+	}
+	if len(prs.Value) == 0 {
+		return 0, fmt.Errorf("no active pull request from %s in %s/%s", branch, repo.Project, repo.Repo)
+	}
+	return prs.Value[0].ID, nil
+}
+
+// fetchAzureDevOpsThreads returns the comments in threads on files of the
+// active pull request from branch. System comments, such as those noting a
+// new push, are left out.
+func fetchAzureDevOpsThreads(repo *AzureDevOps, branch string) ([]comment.Comment, error) {
+	if branch == "" {
+		return nil, errors.New("azure devops: the review has no branch to find a pull request for")
+	}
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("azure devops: %w", err)
+	}
+	base := repo.pullRequestsURL()
+	id, err := azureFindPullRequest(cred.Token, repo, base, branch)
+	if err != nil {
+		return nil, fmt.Errorf("azure devops: %w", err)
+	}
+	var threads struct {
+		Value []struct {
+			ID            int                 `json:"id"`
+			IsDeleted     bool                `json:"isDeleted"`
+			ThreadContext *azureThreadContext `json:"threadContext"`
+			Comments      []struct {
+				Content     string `json:"content"`
+				CommentType string `json:"commentType"`
+				IsDeleted   bool   `json:"isDeleted"`
+				Author      struct {
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+			} `json:"comments"`
+		} `json:"value"`
+	}
+	u := fmt.Sprintf("%s/%d/threads?api-version=%s", base, id, azureAPIVersion)
+	if err := azureCall(cred.Token, http.MethodGet, u, nil, &threads); err != nil {
+		return nil, fmt.Errorf("azure devops: getting the comments on PR %d: %w", id, err)
+	}
+
+	var comments []comment.Comment
+	for _, t := range threads.Value {
+		tc := t.ThreadContext
+		if t.IsDeleted || tc == nil || tc.FilePath == "" {
+			continue
+		}
+		var line int
+		var removed bool
+		switch {
+		case tc.RightFileStart != nil:
+			line = tc.RightFileStart.Line
+		case tc.LeftFileStart != nil:
+			line, removed = tc.LeftFileStart.Line, true
+		}
+		for _, c := range t.Comments {
+			if c.IsDeleted || c.CommentType == "system" {
+				continue
+			}
+			comments = append(comments, threadComment(strconv.Itoa(t.ID), strings.TrimPrefix(tc.FilePath, "/"), line, removed, c.Author.DisplayName, c.Content))
+		}
+	}
+	return comments, nil
+}
+
+// azureVote votes on pull request id as the token's user: approved, or
+// waiting for the author for requested changes.
+func azureVote(token string, repo *AzureDevOps, base string, id int, verdict Verdict) error {
+	var conn struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"authenticatedUser"`
+	}
+	u := strings.TrimSuffix(repo.URL, "/") + "/_apis/connectionData"
+	if err := azureCall(token, http.MethodGet, u, nil, &conn); err != nil {
+		return fmt.Errorf("finding your user: %w", err)
+	}
+	vote := 10
+	if verdict == VerdictRequestChanges {
+		vote = -5
+	}
+	u = fmt.Sprintf("%s/%d/reviewers/%s?api-version=%s", base, id, url.PathEscape(conn.User.ID), azureAPIVersion)
+	return azureCall(token, http.MethodPut, u, map[string]int{"vote": vote}, nil)
+}
+
+// azureCall sends a request to the Azure DevOps API with a personal access
+// token, JSON-encoding payload if non-nil and decoding the response into out
+// if non-nil.
+func azureCall(token, method, u string, payload, out any) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "revui")
+	req.SetBasicAuth("", token)
+	return doJSON(req, out)
+}
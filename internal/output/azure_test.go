@@ -0,0 +1,299 @@
+package output
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestParseAzureRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   AzureDevOps
+		ok     bool
+	}{
+		{remote: "https://dev.azure.com/acme/Platform/_git/api", want: AzureDevOps{URL: "https://dev.azure.com/acme", Project: "Platform", Repo: "api"}, ok: true},
+		{remote: "https://me@dev.azure.com/acme/My%20Project/_git/api", want: AzureDevOps{URL: "https://dev.azure.com/acme", Project: "My Project", Repo: "api"}, ok: true},
+		{remote: "git@ssh.dev.azure.com:v3/acme/Platform/api", want: AzureDevOps{URL: "https://dev.azure.com/acme", Project: "Platform", Repo: "api"}, ok: true},
+		{remote: "https://acme.visualstudio.com/DefaultCollection/Platform/_git/api", want: AzureDevOps{URL: "https://acme.visualstudio.com", Project: "Platform", Repo: "api"}, ok: true},
+		{remote: "https://acme.visualstudio.com/Platform/_git/api.git", want: AzureDevOps{URL: "https://acme.visualstudio.com", Project: "Platform", Repo: "api"}, ok: true},
+		{remote: "git@github.com:acme/api.git"},
+		{remote: "https://github.com/acme/_git/api"},
+		{remote: "https://dev.azure.com/acme/_git/api"},
+		{remote: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remote, func(t *testing.T) {
+			got, ok := ParseAzureRemote(tt.remote)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseAzureRemote(%q) = %+v, %v; want %+v, %v", tt.remote, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAzureDevOpsTargets(t *testing.T) {
+	if got := AzureDevOpsTargets(nil, "git@github.com:acme/api.git"); got != nil {
+		t.Errorf("targets for a GitHub remote = %+v, want none", got)
+	}
+
+	targets := AzureDevOpsTargets(&AzureDevOps{Token: "pat", Repo: "api-fork"}, "https://dev.azure.com/acme/Platform/_git/api")
+	if len(targets) != 1 {
+		t.Fatalf("got %d targets, want 1", len(targets))
+	}
+	if targets[0].Label != "Azure DevOps PR: Platform/api-fork" {
+		t.Errorf("label = %q", targets[0].Label)
+	}
+	want := AzureDevOps{URL: "https://dev.azure.com/acme", Project: "Platform", Repo: "api-fork", Token: "pat"}
+	if *targets[0].AzureDevOps != want {
+		t.Errorf("repo = %+v, want %+v", *targets[0].AzureDevOps, want)
+	}
+}
+
+// azureFiles are the files of the pull request served by azureFileServer, by
+// commit and then path.
+var azureFiles = map[string]map[string]string{
+	"fork": {
+		"/a.go":  "l1\nl2\nl3\nl4\nl5\nl6\nl7\nl8\nl9\n",
+		"/b.png": "png1",
+	},
+	"head": {
+		"/a.go":  "l1\nl2\nl3\nl5\nl6\nl7\nl8\nl9\na9\na10\na11\na12\n",
+		"/b.png": "png2",
+	},
+}
+
+// serveAzureFiles answers the requests for the pull request's commits and
+// files, reporting whether r was one.
+func serveAzureFiles(w http.ResponseWriter, r *http.Request) bool {
+	switch r.URL.Path {
+	case "/Platform/_apis/git/repositories/api/pullrequests/42/iterations":
+		w.Write([]byte(`{"value": [{"sourceRefCommit": {"commitId": "old"}}, {"sourceRefCommit": {"commitId": "head"}, "commonRefCommit": {"commitId": "fork"}}]}`))
+	case "/Platform/_apis/git/repositories/api/items":
+		content, ok := azureFiles[r.URL.Query().Get("versionDescriptor.version")][r.URL.Query().Get("path")]
+		if !ok {
+			http.NotFound(w, r)
+			break
+		}
+		json.NewEncoder(w).Encode(map[string]string{"content": content})
+	default:
+		return false
+	}
+	return true
+}
+
+func TestDeliverAzureDevOps(t *testing.T) {
+	var threads []azureThread
+	var replies []azureComment
+	var search string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pass, ok := r.BasicAuth(); !ok || pass != "pat" {
+			t.Error("want basic auth with the token")
+		}
+		if serveAzureFiles(w, r) {
+			return
+		}
+		switch r.URL.Path {
+		case "/Platform/_apis/git/repositories/api/pullrequests":
+			search = r.URL.Query().Get("searchCriteria.sourceRefName")
+			w.Write([]byte(`{"value": [{"pullRequestId": 42}], "count": 1}`))
+		case "/Platform/_apis/git/repositories/api/pullrequests/42/threads":
+			var th azureThread
+			json.NewDecoder(r.Body).Decode(&th)
+			threads = append(threads, th)
+			w.Write([]byte(`{"id": 1}`))
+		case "/Platform/_apis/git/repositories/api/pullrequests/42/threads/5/comments":
+			var c azureComment
+			json.NewDecoder(r.Body).Decode(&c)
+			replies = append(replies, c)
+			w.Write([]byte(`{"id": 2}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	repo := &AzureDevOps{URL: srv.URL, Project: "Platform", Repo: "api", Token: "pat"}
+	msg, err := Deliver(OutputTarget{Kind: TargetAzureDevOps, AzureDevOps: repo}, Review{Branch: "feature", Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 12, LineType: git.LineAdded, Body: "split this"},
+		{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineRemoved, Body: "keep this"},
+		{FilePath: "b.png", Body: "too big"},
+		{FilePath: "a.go", StartLine: 20, EndLine: 20, LineType: git.LineAdded, Body: "fixed", Thread: "5"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Posted 4 comments to Azure DevOps PR 42" {
+		t.Errorf("message = %q", msg)
+	}
+	if search != "refs/heads/feature" {
+		t.Errorf("searched for pull requests from %q", search)
+	}
+	pos := func(line int) *azurePosition { return &azurePosition{Line: line, Offset: 1} }
+	text := func(body string) []azureComment { return []azureComment{{Content: body, CommentType: 1}} }
+	want := []azureThread{
+		{Comments: text("split this"), Status: 1, ThreadContext: azureThreadContext{FilePath: "/a.go", RightFileStart: pos(10), RightFileEnd: pos(12)}},
+		{Comments: text("keep this"), Status: 1, ThreadContext: azureThreadContext{FilePath: "/a.go", LeftFileStart: pos(4), LeftFileEnd: pos(4)}},
+		{Comments: text("too big"), Status: 1, ThreadContext: azureThreadContext{FilePath: "/b.png"}},
+	}
+	if !reflect.DeepEqual(threads, want) {
+		t.Errorf("threads = %+v, want %+v", threads, want)
+	}
+	if want := []azureComment{{ParentCommentID: 1, Content: "fixed", CommentType: 1}}; !reflect.DeepEqual(replies, want) {
+		t.Errorf("replies = %+v, want %+v", replies, want)
+	}
+}
+
+func TestDeliverAzureDevOpsPlacement(t *testing.T) {
+	var threads []azureThread
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveAzureFiles(w, r) {
+			return
+		}
+		switch r.URL.Path {
+		case "/Platform/_apis/git/repositories/api/pullrequests":
+			w.Write([]byte(`{"value": [{"pullRequestId": 42}], "count": 1}`))
+		case "/Platform/_apis/git/repositories/api/pullrequests/42/threads":
+			var th azureThread
+			json.NewDecoder(r.Body).Decode(&th)
+			threads = append(threads, th)
+			w.Write([]byte(`{"id": 1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	repo := &AzureDevOps{URL: srv.URL, Project: "Platform", Repo: "api", Token: "pat"}
+
+	// Written on a diff where a10 was line 20: it moves to where a10 is
+	local := &git.FileDiff{Path: "a.go", Hunks: []git.Hunk{{Lines: []git.Line{
+		{Type: git.LineAdded, Content: "a10", NewLineNo: 20},
+	}}}}
+	_, err := deliverToAzureDevOps(repo, Review{Branch: "feature", Diffs: map[string]*git.FileDiff{"a.go": local}, Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 20, EndLine: 20, LineType: git.LineAdded, Body: "moved"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 1 || threads[0].ThreadContext.RightFileStart.Line != 10 {
+		t.Errorf("threads = %+v, want the comment on line 10", threads)
+	}
+
+	// A file the pull request doesn't change can't take comments
+	threads = nil
+	_, err = deliverToAzureDevOps(repo, Review{Branch: "feature", Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 1, EndLine: 1, LineType: git.LineContext, Body: "fine"},
+		{FilePath: "c.go", Body: "not in the PR"},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "c.go") || len(threads) != 0 {
+		t.Errorf("error = %v, posted %d; want c.go named and nothing posted", err, len(threads))
+	}
+}
+
+func TestFetchAzureDevOpsThreads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Platform/_apis/git/repositories/api/pullrequests":
+			w.Write([]byte(`{"value": [{"pullRequestId": 42}], "count": 1}`))
+		case "/Platform/_apis/git/repositories/api/pullrequests/42/threads":
+			w.Write([]byte(`{"value": [
+				{"id": 5, "threadContext": {"filePath": "/a.go", "rightFileStart": {"line": 10, "offset": 1}}, "comments": [
+					{"id": 1, "content": "why?", "commentType": "text", "author": {"displayName": "Ann"}},
+					{"id": 2, "content": "because", "commentType": "text", "author": {"displayName": "Cy"}},
+					{"id": 3, "content": "gone", "commentType": "text", "isDeleted": true}
+				]},
+				{"id": 6, "comments": [{"id": 1, "content": "Ann voted 10", "commentType": "system"}]},
+				{"id": 7, "threadContext": {"filePath": "/b.go", "leftFileStart": {"line": 2, "offset": 1}}, "comments": [
+					{"id": 1, "content": "keep", "commentType": "text", "author": {"displayName": "Bo"}}
+				]}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	repo := &AzureDevOps{URL: srv.URL, Project: "Platform", Repo: "api", Token: "pat"}
+	got, err := FetchThreads(OutputTarget{Kind: TargetAzureDevOps, AzureDevOps: repo}, "feature", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "why?", Author: "Ann", Thread: "5"},
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "because", Author: "Cy", Thread: "5"},
+		{FilePath: "b.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "keep", Author: "Bo", Thread: "7"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threads = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeliverAzureDevOpsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"value": [], "count": 0}`))
+	}))
+	defer srv.Close()
+	t.Setenv("AZURE_DEVOPS_EXT_PAT", "")
+
+	tests := []struct {
+		name   string
+		branch string
+		repo   AzureDevOps
+		want   string
+	}{
+		{name: "no branch", repo: AzureDevOps{Token: "pat"}, want: "no branch"},
+		{name: "no token", branch: "feature", want: "AZURE_DEVOPS_EXT_PAT"},
+		{name: "no pull request", branch: "feature", repo: AzureDevOps{URL: srv.URL, Project: "Platform", Repo: "api", Token: "pat"}, want: "no active pull request from feature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := deliverToAzureDevOps(&tt.repo, Review{Branch: tt.branch})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverAzureDevOpsVerdict(t *testing.T) {
+	var vote map[string]int
+	var votePath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/_apis/connectionData":
+			w.Write([]byte(`{"authenticatedUser": {"id": "u-1"}}`))
+		case r.Method == http.MethodPut:
+			votePath = r.URL.Path
+			json.NewDecoder(r.Body).Decode(&vote)
+			w.Write([]byte(`{}`))
+		case strings.HasSuffix(r.URL.Path, "/pullrequests"):
+			w.Write([]byte(`{"value": [{"pullRequestId": 42}]}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+
+	repo := &AzureDevOps{URL: srv.URL, Project: "Platform", Repo: "api", Token: "pat"}
+	msg, err := deliverToAzureDevOps(repo, Review{Branch: "feature", Verdict: VerdictRequestChanges})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Posted 0 comments to Azure DevOps PR 42 and requested changes" {
+		t.Errorf("message = %q", msg)
+	}
+	if votePath != "/Platform/_apis/git/repositories/api/pullrequests/42/reviewers/u-1" || vote["vote"] != -5 {
+		t.Errorf("voted %v at %s", vote, votePath)
+	}
+
+	if _, err := deliverToAzureDevOps(repo, Review{Branch: "feature", Draft: true}); err == nil {
+		t.Error("expected an error for drafts")
+	}
+}
@@ -0,0 +1,461 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/deparker/revui/internal/auth"
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// bitbucketCloudAPI is the Bitbucket Cloud REST API base URL, replaced in tests.
+var bitbucketCloudAPI = "https://api.bitbucket.org/2.0"
+
+// Bitbucket is a configured Bitbucket repository whose open pull request for
+// the reviewed branch receives the comments. URL is set for Bitbucket
+// Server/Data Center and left empty for Bitbucket Cloud. Token is an app
+// password when Username is set, or an access token otherwise; when empty, it
+// is resolved as described on auth.Lookup, from BITBUCKET_TOKEN (and
+// BITBUCKET_USERNAME) or a git credential helper.
+type Bitbucket struct {
+	Name      string `json:"name"`
+	URL       string `json:"url,omitempty"`
+	Workspace string `json:"workspace"` // Cloud workspace, or Server project key
+	Repo      string `json:"repo"`
+	Username  string `json:"username,omitempty"`
+	Token     string `json:"token,omitempty"`
+}
+
+// BitbucketTargets returns an output target for each configured Bitbucket
+// repository.
+func BitbucketTargets(repos []Bitbucket) []OutputTarget {
+	targets := make([]OutputTarget, len(repos))
+	for i := range repos {
+		name := repos[i].Name
+		if name == "" {
+			name = repos[i].Workspace + "/" + repos[i].Repo
+		}
+		targets[i] = OutputTarget{
+			Kind:      TargetBitbucket,
+			Label:     "Bitbucket PR: " + name,
+			Bitbucket: &repos[i],
+		}
+	}
+	return targets
+}
+
+// deliverToBitbucket posts each comment on the open pull request from the
+// reviewed branch, inline on its file and line, then records the verdict. Draft
+// comments are left pending, for the reviewer to publish from Bitbucket.
+func deliverToBitbucket(repo *Bitbucket, review Review) (string, error) {
+	if review.hostBranch() == "" {
+		return "", errors.New("bitbucket: the review has no branch to find a pull request for")
+	}
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return "", unsent(fmt.Errorf("bitbucket: %w", err), review)
+	}
+	c := bitbucketClient{repo: repo, username: cred.Username, token: cred.Token}
+
+	id, err := c.findPullRequest(review.hostBranch())
+	if err != nil {
+		return "", unsent(fmt.Errorf("bitbucket: %w", err), review)
+	}
+	// Comments are anchored to the pull request's diff, which rejects lines
+	// it doesn't show.
+	diff, err := c.pullRequestDiff(id)
+	if err != nil {
+		return "", unsent(fmt.Errorf("bitbucket: getting the diff of PR #%d: %w", id, err), review)
+	}
+	comments, err := placeComments(review.Comments, review.Diffs, diff, fmt.Sprintf("PR #%d", id))
+	if err != nil {
+		return "", fmt.Errorf("bitbucket: %w", err)
+	}
+	for i, cm := range comments {
+		if err := c.postComment(id, cm, review.Draft); err != nil {
+			err = fmt.Errorf("bitbucket: posted %d of %d comments to PR #%d: %w", i, len(review.Comments), id, err)
+			return "", unsent(err, review.remaining(comments[i:]))
+		}
+	}
+	if review.Draft {
+		return fmt.Sprintf("Saved %d draft comments on Bitbucket PR #%d", len(review.Comments), id), nil
+	}
+	if err := c.setVerdict(id, review.Verdict); err != nil {
+		err = fmt.Errorf("bitbucket: posted %d comments to PR #%d, but recording the verdict failed: %w", len(review.Comments), id, err)
+		return "", unsent(err, review.remaining(nil))
+	}
+	return fmt.Sprintf("Posted %d comments to Bitbucket PR #%d%s", len(review.Comments), id, verdictNote(review.Verdict)), nil
+}
+
+// Lookup returns where the repository's credential is looked for.
+func (repo *Bitbucket) Lookup() auth.Lookup {
+	host := "bitbucket.org"
+	if u, err := url.Parse(repo.URL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	l := auth.Bitbucket(host)
+	l.Config = auth.Credential{Username: repo.Username, Token: repo.Token}
+	return l
+}
+
+// bitbucketClient calls the Bitbucket Cloud or Server API for repo.
+type bitbucketClient struct {
+	repo            *Bitbucket
+	username, token string
+}
+
+// server reports whether the repository is on Bitbucket Server/Data Center.
+func (c bitbucketClient) server() bool {
+	return c.repo.URL != ""
+}
+
+// pullRequestsURL returns the URL of the repository's pull requests.
+func (c bitbucketClient) pullRequestsURL() string {
+	if c.server() {
+		return fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests",
+			strings.TrimSuffix(c.repo.URL, "/"), url.PathEscape(c.repo.Workspace), url.PathEscape(c.repo.Repo))
+	}
+	return fmt.Sprintf("%s/repositories/%s/%s/pullrequests",
+		bitbucketCloudAPI, url.PathEscape(c.repo.Workspace), url.PathEscape(c.repo.Repo))
+}
+
+// findPullRequest returns the ID of the open pull request from branch.
+func (c bitbucketClient) findPullRequest(branch string) (int, error) {
+	query := url.Values{}
+	if c.server() {
+		query.Set("at", "refs/heads/"+branch)
+		query.Set("direction", "OUTGOING")
+		query.Set("state", "OPEN")
+	} else {
+		query.Set("q", fmt.Sprintf("source.branch.name=%q AND state=\"OPEN\"", branch))
+	}
+	// Cloud lists pull requests under "values", as does Server.
+	var page struct {
+		Values []struct {
+			ID int `json:"id"`
+		} `json:"values"`
+	}
+	if err := c.call(http.MethodGet, c.pullRequestsURL()+"?"+query.Encode(), nil, &page); err != nil {
+		return 0, fmt.Errorf("finding the pull request for %s: %w", branch, err)
+	}
+	if len(page.Values) == 0 {
+		return 0, fmt.Errorf("no open pull request from %s in %s/%s", branch, c.repo.Workspace, c.repo.Repo)
+	}
+	return page.Values[0].ID, nil
+}
+
+// pullRequestDiff returns the diff of pull request id.
+func (c bitbucketClient) pullRequestDiff(id int) ([]git.FileDiff, error) {
+	u := fmt.Sprintf("%s/%d/diff", c.pullRequestsURL(), id)
+	if c.server() {
+		u = fmt.Sprintf("%s/%d.diff", c.pullRequestsURL(), id)
+	}
+	req, err := c.request(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain")
+	raw, err := doText(req)
+	if err != nil {
+		return nil, err
+	}
+	return git.ParseDiff(raw)
+}
+
+// postComment posts cm on pull request id, as a draft if draft is set.
+// Comments on a range are anchored to its last line and say which lines they
+// cover; file comments are anchored to the file, and replies to the comment
+// starting their thread.
+func (c bitbucketClient) postComment(id int, cm comment.Comment, draft bool) error {
+	text := cm.Body
+	if cm.EndLine > cm.StartLine {
+		text = fmt.Sprintf("Lines %d-%d: %s", cm.StartLine, cm.EndLine, cm.Body)
+	}
+	removed := cm.LineType == git.LineRemoved
+	var parent map[string]int
+	if cm.Thread != "" {
+		thread, err := strconv.Atoi(cm.Thread)
+		if err != nil {
+			return fmt.Errorf("replying to %s: not a Bitbucket comment", cm.Thread)
+		}
+		parent = map[string]int{"id": thread}
+	}
+
+	var payload map[string]any
+	if c.server() {
+		payload = map[string]any{"text": text}
+		if parent != nil {
+			payload["parent"] = parent
+		} else {
+			anchor := map[string]any{"path": cm.FilePath, "diffType": "EFFECTIVE"}
+			if cm.StartLine > 0 {
+				anchor["line"] = cm.EndLine
+				anchor["lineType"], anchor["fileType"] = "ADDED", "TO"
+				switch {
+				case removed:
+					anchor["lineType"], anchor["fileType"] = "REMOVED", "FROM"
+				case cm.LineType == git.LineContext:
+					anchor["lineType"] = "CONTEXT"
+				}
+			}
+			payload["anchor"] = anchor
+		}
+		if draft {
+			payload["state"] = "PENDING"
+		}
+	} else {
+		payload = map[string]any{"content": map[string]string{"raw": text}}
+		if parent != nil {
+			payload["parent"] = parent
+		} else {
+			inline := map[string]any{"path": cm.FilePath}
+			if cm.StartLine > 0 {
+				side := "to"
+				if removed {
+					side = "from"
+				}
+				inline[side] = cm.EndLine
+			}
+			payload["inline"] = inline
+		}
+		if draft {
+			payload["pending"] = true
+		}
+	}
+	return c.call(http.MethodPost, fmt.Sprintf("%s/%d/comments", c.pullRequestsURL(), id), payload, nil)
+}
+
+// fetchBitbucketThreads returns the inline comments on the open pull request
+// from branch.
+func fetchBitbucketThreads(repo *Bitbucket, branch string) ([]comment.Comment, error) {
+	if branch == "" {
+		return nil, errors.New("bitbucket: the review has no branch to find a pull request for")
+	}
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket: %w", err)
+	}
+	c := bitbucketClient{repo: repo, username: cred.Username, token: cred.Token}
+	id, err := c.findPullRequest(branch)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket: %w", err)
+	}
+	var comments []comment.Comment
+	if c.server() {
+		comments, err = c.serverThreads(id)
+	} else {
+		comments, err = c.cloudThreads(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket: getting the comments on PR #%d: %w", id, err)
+	}
+	return groupThreads(comments), nil
+}
+
+// cloudThreads returns the inline comments on Bitbucket Cloud pull request
+// id. Each is in the thread of the comment its replies lead back to, which
+// alone says where the thread is.
+func (c bitbucketClient) cloudThreads(id int) ([]comment.Comment, error) {
+	type cloudComment struct {
+		ID      int `json:"id"`
+		Content struct {
+			Raw string `json:"raw"`
+		} `json:"content"`
+		User struct {
+			DisplayName string `json:"display_name"`
+		} `json:"user"`
+		Inline *struct {
+			Path string `json:"path"`
+			From *int   `json:"from"`
+			To   *int   `json:"to"`
+		} `json:"inline"`
+		Parent *struct {
+			ID int `json:"id"`
+		} `json:"parent"`
+		Deleted bool `json:"deleted"`
+	}
+	var all []cloudComment
+	for next := fmt.Sprintf("%s/%d/comments?pagelen=100", c.pullRequestsURL(), id); next != ""; {
+		var page struct {
+			Values []cloudComment `json:"values"`
+			Next   string         `json:"next"`
+		}
+		if err := c.call(http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Values...)
+		next = page.Next
+	}
+
+	byID := make(map[int]cloudComment, len(all))
+	for _, cm := range all {
+		byID[cm.ID] = cm
+	}
+	var comments []comment.Comment
+	for _, cm := range all {
+		root := cm
+		for depth := 0; root.Parent != nil && depth < len(all); depth++ {
+			parent, ok := byID[root.Parent.ID]
+			if !ok {
+				break
+			}
+			root = parent
+		}
+		if cm.Deleted || root.Inline == nil {
+			continue
+		}
+		var line int
+		var removed bool
+		switch {
+		case root.Inline.To != nil:
+			line = *root.Inline.To
+		case root.Inline.From != nil:
+			line, removed = *root.Inline.From, true
+		}
+		comments = append(comments, threadComment(strconv.Itoa(root.ID), root.Inline.Path, line, removed, cm.User.DisplayName, cm.Content.Raw))
+	}
+	return comments, nil
+}
+
+// serverComment is a comment in the Bitbucket Server API, with its replies.
+type serverComment struct {
+	ID     int    `json:"id"`
+	Text   string `json:"text"`
+	Author struct {
+		DisplayName string `json:"displayName"`
+	} `json:"author"`
+	Comments []serverComment `json:"comments"`
+}
+
+// serverThreads returns the inline comments on Bitbucket Server pull request
+// id, found in its activity, newest first, where each thread's first comment
+// carries the replies to it.
+func (c bitbucketClient) serverThreads(id int) ([]comment.Comment, error) {
+	type activity struct {
+		Action  string        `json:"action"`
+		Comment serverComment `json:"comment"`
+		Anchor  *struct {
+			Path     string `json:"path"`
+			Line     int    `json:"line"`
+			FileType string `json:"fileType"` // "FROM" for the old file
+		} `json:"commentAnchor"`
+	}
+	var activities []activity
+	for start := 0; ; {
+		var page struct {
+			Values        []activity `json:"values"`
+			IsLastPage    bool       `json:"isLastPage"`
+			NextPageStart int        `json:"nextPageStart"`
+		}
+		u := fmt.Sprintf("%s/%d/activities?limit=100&start=%d", c.pullRequestsURL(), id, start)
+		if err := c.call(http.MethodGet, u, nil, &page); err != nil {
+			return nil, err
+		}
+		activities = append(activities, page.Values...)
+		if page.IsLastPage || len(page.Values) == 0 {
+			break
+		}
+		start = page.NextPageStart
+	}
+
+	// Replies have activity of their own, but are shown with their thread.
+	replies := make(map[int]bool)
+	var markReplies func(serverComment)
+	markReplies = func(sc serverComment) {
+		for _, r := range sc.Comments {
+			replies[r.ID] = true
+			markReplies(r)
+		}
+	}
+	for _, a := range activities {
+		markReplies(a.Comment)
+	}
+
+	var comments []comment.Comment
+	for _, a := range slices.Backward(activities) {
+		if a.Action != "COMMENTED" || a.Anchor == nil || replies[a.Comment.ID] {
+			continue
+		}
+		thread := strconv.Itoa(a.Comment.ID)
+		var add func(serverComment)
+		add = func(sc serverComment) {
+			comments = append(comments, threadComment(thread, a.Anchor.Path, a.Anchor.Line, a.Anchor.FileType == "FROM", sc.Author.DisplayName, sc.Text))
+			for _, r := range sc.Comments {
+				add(r)
+			}
+		}
+		add(a.Comment)
+	}
+	return comments, nil
+}
+
+// setVerdict approves pull request id or requests changes to it. Bitbucket
+// Server records it against the user, so needs their username.
+func (c bitbucketClient) setVerdict(id int, verdict Verdict) error {
+	if verdict == VerdictNone {
+		return nil
+	}
+	if !c.server() {
+		action := "approve"
+		if verdict == VerdictRequestChanges {
+			action = "request-changes"
+		}
+		return c.call(http.MethodPost, fmt.Sprintf("%s/%d/%s", c.pullRequestsURL(), id, action), nil, nil)
+	}
+	if c.username == "" {
+		return errors.New("set username to approve or request changes on Bitbucket Server")
+	}
+	status := "APPROVED"
+	if verdict == VerdictRequestChanges {
+		status = "NEEDS_WORK"
+	}
+	u := fmt.Sprintf("%s/%d/participants/%s", c.pullRequestsURL(), id, url.PathEscape(c.username))
+	return c.call(http.MethodPut, u, map[string]any{"user": map[string]string{"name": c.username}, "status": status}, nil)
+}
+
+// call sends a request to the Bitbucket API, JSON-encoding payload if non-nil
+// and decoding the response into out if non-nil.
+func (c bitbucketClient) call(method, u string, payload, out any) error {
+	req, err := c.request(method, u, payload)
+	if err != nil {
+		return err
+	}
+	return doJSON(req, out)
+}
+
+// request returns an authenticated request to the Bitbucket API with payload
+// JSON-encoded as its body, if non-nil.
+func (c bitbucketClient) request(method, u string, payload any) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "revui")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
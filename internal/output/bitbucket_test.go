@@ -0,0 +1,342 @@
+package output
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+var bitbucketReview = Review{
+	Branch: "feature",
+	Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 12, LineType: git.LineAdded, Body: "split this"},
+		{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineRemoved, Body: "keep this"},
+		{FilePath: "b.png", Body: "too big"},
+	},
+}
+
+// bitbucketDiff is the pull request's diff, showing the lines commented on in
+// bitbucketReview.
+const bitbucketDiff = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -4,2 +4,1 @@
+-old four
+ five
+@@ -10,1 +9,4 @@
+ nine
++ten
++eleven
++twelve
+diff --git a/b.png b/b.png
+Binary files a/b.png and b/b.png differ
+`
+
+func TestDeliverBitbucketCloud(t *testing.T) {
+	var posted []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "app-pass" {
+			t.Errorf("%s: want basic auth with the app password", r.URL.Path)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repositories/team/app/pullrequests":
+			if q := r.URL.Query().Get("q"); q != `source.branch.name="feature" AND state="OPEN"` {
+				t.Errorf("query = %q", q)
+			}
+			w.Write([]byte(`{"values": [{"id": 7}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repositories/team/app/pullrequests/7/diff":
+			w.Write([]byte(bitbucketDiff))
+		case r.Method == http.MethodPost && r.URL.Path == "/repositories/team/app/pullrequests/7/comments":
+			var p map[string]any
+			json.NewDecoder(r.Body).Decode(&p)
+			posted = append(posted, p)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	old := bitbucketCloudAPI
+	bitbucketCloudAPI = srv.URL
+	defer func() { bitbucketCloudAPI = old }()
+
+	target := BitbucketTargets([]Bitbucket{{Workspace: "team", Repo: "app", Username: "me", Token: "app-pass"}})[0]
+	if target.Label != "Bitbucket PR: team/app" {
+		t.Errorf("label = %q", target.Label)
+	}
+	msg, err := Deliver(target, bitbucketReview)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Posted 3 comments to Bitbucket PR #7" {
+		t.Errorf("message = %q", msg)
+	}
+
+	want := []map[string]any{
+		{"content": map[string]any{"raw": "Lines 10-12: split this"}, "inline": map[string]any{"path": "a.go", "to": 12.0}},
+		{"content": map[string]any{"raw": "keep this"}, "inline": map[string]any{"path": "a.go", "from": 4.0}},
+		{"content": map[string]any{"raw": "too big"}, "inline": map[string]any{"path": "b.png"}},
+	}
+	if !reflect.DeepEqual(posted, want) {
+		t.Errorf("posted %v, want %v", posted, want)
+	}
+}
+
+func TestDeliverBitbucketServer(t *testing.T) {
+	var anchors []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer env-token" {
+			t.Errorf("%s: want the token from the environment", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/rest/api/1.0/projects/PROJ/repos/app/pull-requests":
+			if r.URL.Query().Get("at") != "refs/heads/feature" {
+				t.Errorf("query = %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"values": [{"id": 42}]}`))
+		case "/rest/api/1.0/projects/PROJ/repos/app/pull-requests/42.diff":
+			w.Write([]byte(bitbucketDiff))
+		case "/rest/api/1.0/projects/PROJ/repos/app/pull-requests/42/comments":
+			var p map[string]any
+			json.NewDecoder(r.Body).Decode(&p)
+			anchors = append(anchors, p["anchor"])
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("BITBUCKET_USERNAME", "")
+	t.Setenv("BITBUCKET_TOKEN", "env-token")
+
+	target := BitbucketTargets([]Bitbucket{{Name: "server", URL: srv.URL + "/", Workspace: "PROJ", Repo: "app"}})[0]
+	if _, err := Deliver(target, bitbucketReview); err != nil {
+		t.Fatal(err)
+	}
+	want := []any{
+		map[string]any{"path": "a.go", "diffType": "EFFECTIVE", "line": 12.0, "lineType": "ADDED", "fileType": "TO"},
+		map[string]any{"path": "a.go", "diffType": "EFFECTIVE", "line": 4.0, "lineType": "REMOVED", "fileType": "FROM"},
+		map[string]any{"path": "b.png", "diffType": "EFFECTIVE"},
+	}
+	if !reflect.DeepEqual(anchors, want) {
+		t.Errorf("anchors = %v, want %v", anchors, want)
+	}
+}
+
+func TestDeliverBitbucketErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"values": []}`))
+	}))
+	defer srv.Close()
+	t.Setenv("BITBUCKET_TOKEN", "")
+
+	tests := []struct {
+		name   string
+		repo   Bitbucket
+		review Review
+		want   string
+	}{
+		{name: "no branch", repo: Bitbucket{Token: "t"}, want: "no branch"},
+		{name: "no token", repo: Bitbucket{}, review: Review{Branch: "feature"}, want: "BITBUCKET_TOKEN"},
+		{name: "no pull request", repo: Bitbucket{URL: srv.URL, Workspace: "P", Repo: "r", Token: "t"}, review: Review{Branch: "feature"}, want: "no open pull request from feature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := deliverToBitbucket(&tt.repo, tt.review)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverBitbucketDraftAndVerdict(t *testing.T) {
+	var requests []string
+	var payloads []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/pull-requests") || strings.HasSuffix(r.URL.Path, "/pullrequests"):
+			w.Write([]byte(`{"values": [{"id": 7}]}`))
+		case strings.HasSuffix(r.URL.Path, "diff"):
+			w.Write([]byte(bitbucketDiff))
+		default:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			var p map[string]any
+			json.NewDecoder(r.Body).Decode(&p)
+			payloads = append(payloads, p)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+	old := bitbucketCloudAPI
+	bitbucketCloudAPI = srv.URL
+	defer func() { bitbucketCloudAPI = old }()
+
+	review := Review{Branch: "feature", Comments: bitbucketReview.Comments[:1], Draft: true}
+	cloud := &Bitbucket{Workspace: "team", Repo: "app", Token: "t"}
+	msg, err := deliverToBitbucket(cloud, review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Saved 1 draft comments on Bitbucket PR #7" || payloads[0]["pending"] != true {
+		t.Errorf("draft = %q, posted %v", msg, payloads[0])
+	}
+
+	tests := []struct {
+		name    string
+		repo    *Bitbucket
+		verdict Verdict
+		want    string
+		payload map[string]any
+	}{
+		{name: "cloud approve", repo: cloud, verdict: VerdictApprove, want: "POST /repositories/team/app/pullrequests/7/approve"},
+		{name: "cloud request changes", repo: cloud, verdict: VerdictRequestChanges, want: "POST /repositories/team/app/pullrequests/7/request-changes"},
+		{
+			name:    "server needs work",
+			repo:    &Bitbucket{URL: srv.URL, Workspace: "PROJ", Repo: "app", Username: "me", Token: "t"},
+			verdict: VerdictRequestChanges,
+			want:    "PUT /rest/api/1.0/projects/PROJ/repos/app/pull-requests/7/participants/me",
+			payload: map[string]any{"user": map[string]any{"name": "me"}, "status": "NEEDS_WORK"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, payloads = nil, nil
+			review := Review{Branch: "feature", Comments: bitbucketReview.Comments[:1], Verdict: tt.verdict}
+			if _, err := deliverToBitbucket(tt.repo, review); err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) != 2 || requests[1] != tt.want {
+				t.Fatalf("requests = %v, want the comment then %s", requests, tt.want)
+			}
+			if tt.payload != nil && !reflect.DeepEqual(payloads[1], tt.payload) {
+				t.Errorf("verdict payload = %v, want %v", payloads[1], tt.payload)
+			}
+		})
+	}
+}
+
+func TestFetchBitbucketThreads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repositories/team/app/pullrequests":
+			w.Write([]byte(`{"values": [{"id": 7}]}`))
+		case "/repositories/team/app/pullrequests/7/comments":
+			if r.URL.Query().Get("page") == "" {
+				w.Write([]byte(`{"values": [
+					{"id": 1, "content": {"raw": "why?"}, "user": {"display_name": "Ann"}, "inline": {"path": "a.go", "to": 10}},
+					{"id": 2, "content": {"raw": "general"}, "user": {"display_name": "Bo"}},
+					{"id": 3, "content": {"raw": "old line"}, "user": {"display_name": "Bo"}, "inline": {"path": "a.go", "from": 4}}
+				], "next": "` + "http://" + r.Host + r.URL.Path + `?page=2"}`))
+				return
+			}
+			w.Write([]byte(`{"values": [
+				{"id": 4, "content": {"raw": "because"}, "user": {"display_name": "Cy"}, "parent": {"id": 1}},
+				{"id": 5, "content": {"raw": ""}, "user": {"display_name": "Cy"}, "parent": {"id": 4}, "deleted": true}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	old := bitbucketCloudAPI
+	bitbucketCloudAPI = srv.URL
+	defer func() { bitbucketCloudAPI = old }()
+
+	target := BitbucketTargets([]Bitbucket{{Workspace: "team", Repo: "app", Token: "tok"}})[0]
+	got, err := FetchThreads(target, "feature", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "why?", Author: "Ann", Thread: "1"},
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "because", Author: "Cy", Thread: "1"},
+		{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineRemoved, Body: "old line", Author: "Bo", Thread: "3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threads = %+v, want %+v", got, want)
+	}
+}
+
+func TestFetchBitbucketServerThreads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/1.0/projects/PROJ/repos/app/pull-requests":
+			w.Write([]byte(`{"values": [{"id": 42}]}`))
+		case "/rest/api/1.0/projects/PROJ/repos/app/pull-requests/42/activities":
+			// Newest first, with the reply's own activity before its thread's
+			w.Write([]byte(`{"isLastPage": true, "values": [
+				{"action": "COMMENTED", "comment": {"id": 9, "text": "agreed", "author": {"displayName": "Cy"}},
+					"commentAnchor": {"path": "a.go", "line": 10, "fileType": "TO"}},
+				{"action": "APPROVED"},
+				{"action": "COMMENTED", "comment": {"id": 8, "text": "drop it", "author": {"displayName": "Ann"}},
+					"commentAnchor": {"path": "a.go", "line": 4, "fileType": "FROM"}},
+				{"action": "COMMENTED", "comment": {"id": 7, "text": "why?", "author": {"displayName": "Ann"},
+					"comments": [{"id": 9, "text": "agreed", "author": {"displayName": "Cy"}}]},
+					"commentAnchor": {"path": "a.go", "line": 10, "fileType": "TO"}}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	target := BitbucketTargets([]Bitbucket{{URL: srv.URL, Workspace: "PROJ", Repo: "app", Token: "tok"}})[0]
+	got, err := FetchThreads(target, "feature", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "why?", Author: "Ann", Thread: "7"},
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "agreed", Author: "Cy", Thread: "7"},
+		{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineRemoved, Body: "drop it", Author: "Ann", Thread: "8"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threads = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeliverBitbucketReply(t *testing.T) {
+	var posted []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repositories/team/app/pullrequests":
+			w.Write([]byte(`{"values": [{"id": 7}]}`))
+		case r.URL.Path == "/repositories/team/app/pullrequests/7/diff":
+			w.Write([]byte(bitbucketDiff))
+		case r.Method == http.MethodPost && r.URL.Path == "/repositories/team/app/pullrequests/7/comments":
+			var p map[string]any
+			json.NewDecoder(r.Body).Decode(&p)
+			posted = append(posted, p)
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	old := bitbucketCloudAPI
+	bitbucketCloudAPI = srv.URL
+	defer func() { bitbucketCloudAPI = old }()
+
+	// The reply's line isn't in the pull request's diff, but a reply goes to
+	// its thread wherever that is.
+	target := BitbucketTargets([]Bitbucket{{Workspace: "team", Repo: "app", Token: "tok"}})[0]
+	_, err := Deliver(target, Review{Branch: "feature", Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 30, EndLine: 30, LineType: git.LineAdded, Body: "done", Thread: "1"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{{"content": map[string]any{"raw": "done"}, "parent": map[string]any{"id": 1.0}}}
+	if !reflect.DeepEqual(posted, want) {
+		t.Errorf("posted %v, want %v", posted, want)
+	}
+}
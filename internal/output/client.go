@@ -0,0 +1,213 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Retries of requests that fail in ways that may pass, such as rate limiting
+// or an overloaded server. A review posted as one request per comment should
+// get every comment through, not stop part way.
+var (
+	maxAttempts  = 4
+	retryBackoff = time.Second // doubled after each retry; shortened in tests
+	maxRetryWait = time.Minute // longer rate-limit waits fail instead
+)
+
+// dryRun, when non-nil, receives the requests that would change something on
+// a remote service in place of sending them. Reads are still sent, so that
+// what's written is built from real responses, such as the pull request found
+// for the branch.
+var dryRun io.Writer
+
+// SetDryRun makes deliveries write the requests they would send to w, rather
+// than sending them. A nil w sends them again.
+func SetDryRun(w io.Writer) {
+	dryRun = w
+}
+
+// DryRun reports whether SetDryRun is keeping requests from being sent.
+func DryRun() bool {
+	return dryRun != nil
+}
+
+// sendsRequests reports whether delivering to kind makes HTTP requests.
+func sendsRequests(kind TargetKind) bool {
+	switch kind {
+	case TargetWebhook, TargetSlack, TargetBitbucket, TargetGerrit, TargetAzureDevOps, TargetGitHub, TargetGitLab:
+		return true
+	}
+	return false
+}
+
+// send sends req, retrying it after transient failures: network errors, rate
+// limiting (429, or 403 with no requests remaining), and 502, 503, and 504
+// responses. A POST may have been acted on despite a timeout or a bad gateway,
+// and sending it again could post a comment twice, so it is only retried when
+// it can't have been: the connection was refused, or the response was 429 or
+// 503. The final response is returned whatever its status, for the caller to
+// check and close. req's body must be rewindable, as it is for requests made
+// by http.NewRequest from a bytes or strings reader.
+func send(req *http.Request) (*http.Response, error) {
+	if dryRun != nil && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return writeDryRun(req)
+	}
+	client := &http.Client{Timeout: httpTimeout}
+	wait := retryBackoff
+	idempotent := req.Method != http.MethodPost
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := client.Do(req)
+		if attempt == maxAttempts {
+			return resp, err
+		}
+		if err == nil {
+			retryAfter, retry := retryDelay(resp)
+			if !idempotent && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+				retry = false
+			}
+			if !retry {
+				return resp, nil
+			}
+			if retryAfter > maxRetryWait {
+				resp.Body.Close()
+				return nil, fmt.Errorf("rate limited for %s; try again later", retryAfter.Round(time.Second))
+			}
+			// Drain the body so the connection can be reused.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			if retryAfter > 0 {
+				wait = retryAfter
+			}
+		} else if req.GetBody == nil && req.Body != nil {
+			// The body was consumed and can't be sent again.
+			return nil, err
+		} else if !idempotent && !errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// retryDelay reports whether resp is a transient failure worth retrying, and
+// how long the server asked to wait first, if it said.
+func retryDelay(resp *http.Response) (time.Duration, bool) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	case http.StatusForbidden:
+		// GitHub-style rate limiting
+		if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+			return 0, false
+		}
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0), true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+	after := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(after); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(after); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, true
+}
+
+// writeDryRun writes req to dryRun, with a JSON body indented, and returns
+// an empty successful response in place of the server's.
+func writeDryRun(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	fmt.Fprintf(dryRun, "%s %s\n", req.Method, req.URL.Redacted())
+	var indented bytes.Buffer
+	if json.Indent(&indented, body, "", "  ") == nil {
+		body = indented.Bytes()
+	}
+	if len(body) > 0 {
+		fmt.Fprintf(dryRun, "%s\n", bytes.TrimRight(body, "\n"))
+	}
+	fmt.Fprintln(dryRun)
+	// Slack reports success in the body; other APIs ignore it.
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(`{"ok": true}`)),
+		Request:    req,
+	}, nil
+}
+
+// doText sends req like doJSON and returns a successful response's body.
+func doText(req *http.Request) (string, error) {
+	resp, err := send(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", &statusError{resp.StatusCode, fmt.Sprintf("%s: %s", resp.Status, bytes.TrimSpace(body[:min(len(body), 200)]))}
+	}
+	return string(body), nil
+}
+
+// doJSON sends req with retries, treating any non-2xx response as an error
+// that includes the start of the response body, and decodes a successful
+// response's JSON body into out if non-nil.
+func doJSON(req *http.Request, out any) error {
+	resp, err := send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return &statusError{resp.StatusCode, fmt.Sprintf("%s: %s", resp.Status, bytes.TrimSpace(snippet))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// statusError is a response with a status other than 2xx, as doJSON and
+// doText report it.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+// isNotFound reports whether err is a 404 response.
+func isNotFound(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.code == http.StatusNotFound
+}
@@ -0,0 +1,151 @@
+package output
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// fastRetries shortens the retry backoff for the test.
+func fastRetries(t *testing.T) {
+	t.Helper()
+	old := retryBackoff
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = old })
+}
+
+func TestDoJSONRetries(t *testing.T) {
+	fastRetries(t)
+	tests := []struct {
+		name     string
+		method   string // PUT when empty
+		failures []int  // statuses returned before succeeding
+		header   http.Header
+		wantErr  string
+		attempts int
+	}{
+		{name: "success", attempts: 1},
+		{name: "unavailable", failures: []int{503, 502}, attempts: 3},
+		{name: "rate limited", failures: []int{429}, header: http.Header{"Retry-After": {"0"}}, attempts: 2},
+		{name: "rate limit exhausted", failures: []int{403}, header: http.Header{"X-Ratelimit-Remaining": {"0"}}, attempts: 2},
+		{name: "forbidden", failures: []int{403}, wantErr: "403 Forbidden", attempts: 1},
+		{name: "bad request", failures: []int{400}, wantErr: "400 Bad Request", attempts: 1},
+		{name: "gives up", failures: []int{503, 503, 503, 503, 503}, wantErr: "503 Service Unavailable", attempts: 4},
+		{name: "long wait", failures: []int{429}, header: http.Header{"Retry-After": {"3600"}}, wantErr: "rate limited for 1h0m0s", attempts: 1},
+		{name: "post rejected", method: http.MethodPost, failures: []int{503, 429}, header: http.Header{"Retry-After": {"0"}}, attempts: 3},
+		{name: "post maybe received", method: http.MethodPost, failures: []int{502}, wantErr: "502 Bad Gateway", attempts: 1},
+		{name: "post rate limit exhausted", method: http.MethodPost, failures: []int{403}, header: http.Header{"X-Ratelimit-Remaining": {"0"}}, wantErr: "403 Forbidden", attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body bytes.Buffer
+				body.ReadFrom(r.Body)
+				if body.String() != `{"n":1}` {
+					t.Errorf("attempt %d sent body %q", attempts+1, body.String())
+				}
+				if attempts < len(tt.failures) {
+					for k, v := range tt.header {
+						w.Header()[k] = v
+					}
+					w.WriteHeader(tt.failures[attempts])
+					attempts++
+					return
+				}
+				attempts++
+				w.Write([]byte(`{"id": 7}`))
+			}))
+			defer srv.Close()
+
+			req, _ := http.NewRequest(cmp.Or(tt.method, http.MethodPut), srv.URL, strings.NewReader(`{"n":1}`))
+			var out struct{ ID int }
+			err := doJSON(req, &out)
+			if tt.wantErr == "" && (err != nil || out.ID != 7) {
+				t.Errorf("doJSON() = %v, decoded %+v", err, out)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("made %d attempts, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestSendRetriesRefusedPost(t *testing.T) {
+	fastRetries(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"n":1}`))
+	if _, err := send(req); err == nil || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("send() = %v, want connection refused after the retries", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var posted int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodGet:
+			posted++
+		case strings.HasSuffix(r.URL.Path, ".diff"):
+			w.Write([]byte("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,0 +3,1 @@\n+x := 1\n"))
+		default:
+			w.Write([]byte(`{"values": [{"id": 5}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	var log bytes.Buffer
+	SetDryRun(&log)
+	defer SetDryRun(nil)
+
+	target := OutputTarget{Kind: TargetBitbucket, Bitbucket: &Bitbucket{URL: srv.URL, Workspace: "PLAT", Repo: "api", Token: "t"}}
+	msg, err := Deliver(target, Review{Branch: "feature", Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "nit"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Dry run: Posted 1 comments to Bitbucket PR #5" {
+		t.Errorf("message = %q", msg)
+	}
+	if posted != 0 {
+		t.Errorf("dry run sent %d requests that change something", posted)
+	}
+	want := "POST " + srv.URL + `/rest/api/1.0/projects/PLAT/repos/api/pull-requests/5/comments
+{
+  "anchor": {
+    "diffType": "EFFECTIVE",
+    "fileType": "TO",
+    "line": 3,
+    "lineType": "ADDED",
+    "path": "a.go"
+  },
+  "text": "nit"
+}
+
+`
+	if log.String() != want {
+		t.Errorf("dry run wrote:\n%s\nwant:\n%s", log.String(), want)
+	}
+
+	// Targets that don't send requests are unaffected
+	path := t.TempDir() + "/review.md"
+	if msg, err := Deliver(OutputTarget{Kind: TargetFile, Path: path}, Review{Markdown: "# Review"}); err != nil || strings.HasPrefix(msg, "Dry run") {
+		t.Errorf("file delivery = %q, %v", msg, err)
+	}
+}
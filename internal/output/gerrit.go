@@ -0,0 +1,325 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/deparker/revui/internal/auth"
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// Gerrit is a configured Gerrit server that receives the comments as a review
+// of the change being reviewed, found by its Change-Id. Password is the HTTP
+// password from the user's Gerrit settings; when empty, it is resolved as
+// described on auth.Lookup, from GERRIT_PASSWORD (and GERRIT_USERNAME) or a
+// git credential helper. Labels are voted with every review, e.g.
+// {"Verified": 1}; Code-Review is set by the review's verdict.
+type Gerrit struct {
+	Name     string         `json:"name"`
+	URL      string         `json:"url"`
+	Username string         `json:"username,omitempty"`
+	Password string         `json:"password,omitempty"`
+	Labels   map[string]int `json:"labels,omitempty"`
+	ChangeID string         `json:"-"` // from the reviewed commit's Change-Id trailer
+}
+
+// Lookup returns where the server's credential is looked for.
+func (server *Gerrit) Lookup() auth.Lookup {
+	var host string
+	if u, err := url.Parse(server.URL); err == nil {
+		host = u.Host
+	}
+	l := auth.Gerrit(host)
+	l.Config = auth.Credential{Username: server.Username, Token: server.Password}
+	return l
+}
+
+// gerritXSSIPrefix starts every Gerrit JSON response.
+const gerritXSSIPrefix = ")]}'"
+
+// GerritTargets returns an output target for each configured Gerrit server,
+// reviewing the change with the given Change-Id.
+func GerritTargets(servers []Gerrit, changeID string) []OutputTarget {
+	targets := make([]OutputTarget, len(servers))
+	for i := range servers {
+		servers[i].ChangeID = changeID
+		name := servers[i].Name
+		if name == "" {
+			name = servers[i].URL
+		}
+		targets[i] = OutputTarget{
+			Kind:   TargetGerrit,
+			Label:  "Gerrit: " + name,
+			Gerrit: &servers[i],
+		}
+	}
+	return targets
+}
+
+// gerritComment is a CommentInput in Gerrit's review API.
+type gerritComment struct {
+	Line      int    `json:"line,omitempty"` // 0 comments on the file
+	Side      string `json:"side,omitempty"` // "PARENT" for the old file
+	Message   string `json:"message"`
+	InReplyTo string `json:"in_reply_to,omitempty"`
+}
+
+// gerritReview is a ReviewInput in Gerrit's review API.
+type gerritReview struct {
+	Labels   map[string]int             `json:"labels,omitempty"`
+	Comments map[string][]gerritComment `json:"comments,omitempty"`
+}
+
+// gerritDraft is a DraftInput in Gerrit's drafts API.
+type gerritDraft struct {
+	Path string `json:"path"`
+	gerritComment
+}
+
+// deliverToGerrit posts the comments as a review of the current patch set,
+// voting the server's labels, with Code-Review set by the review's verdict.
+// Draft comments are saved for the reviewer to publish from Gerrit, without
+// votes. Comments are first placed on the patch set's diff, which may be
+// against a different base. Comments on a range are placed on its last line
+// and say which lines they cover.
+func deliverToGerrit(server *Gerrit, review Review) (string, error) {
+	if server.ChangeID == "" {
+		return "", errors.New("gerrit: the reviewed commit has no Change-Id trailer")
+	}
+	cred, err := server.Lookup().Resolve()
+	if err != nil {
+		return "", unsent(fmt.Errorf("gerrit: %w", err), review)
+	}
+	revision := fmt.Sprintf("%s/a/changes/%s/revisions/current", strings.TrimSuffix(server.URL, "/"), url.PathEscape(server.ChangeID))
+
+	comments := review.Comments
+	if len(comments) > 0 {
+		diff, err := gerritPatchSetDiff(cred, revision, comments)
+		if err != nil {
+			return "", unsent(fmt.Errorf("gerrit: getting the diff of change %s: %w", shortChangeID(server.ChangeID), err), review)
+		}
+		if comments, err = placeComments(comments, review.Diffs, diff, "change "+shortChangeID(server.ChangeID)); err != nil {
+			return "", fmt.Errorf("gerrit: %w", err)
+		}
+	}
+
+	if review.Draft {
+		for i, c := range comments {
+			draft := gerritDraft{Path: c.FilePath, gerritComment: toGerritComment(c)}
+			if err := gerritCall(cred, http.MethodPut, revision+"/drafts", draft); err != nil {
+				err = fmt.Errorf("gerrit: saved %d of %d drafts: %w", i, len(review.Comments), err)
+				return "", unsent(err, review.remaining(comments[i:]))
+			}
+		}
+		return fmt.Sprintf("Saved %d draft comments on Gerrit change %s", len(review.Comments), shortChangeID(server.ChangeID)), nil
+	}
+
+	input := gerritReview{Labels: maps.Clone(server.Labels), Comments: make(map[string][]gerritComment)}
+	if review.Verdict != VerdictNone {
+		if input.Labels == nil {
+			input.Labels = make(map[string]int)
+		}
+		input.Labels["Code-Review"] = 1
+		if review.Verdict == VerdictRequestChanges {
+			input.Labels["Code-Review"] = -1
+		}
+	}
+	for _, c := range comments {
+		input.Comments[c.FilePath] = append(input.Comments[c.FilePath], toGerritComment(c))
+	}
+	if err := gerritCall(cred, http.MethodPost, revision+"/review", input); err != nil {
+		return "", unsent(fmt.Errorf("gerrit: %w", err), review)
+	}
+	return fmt.Sprintf("Posted %d comments to Gerrit change %s%s", len(review.Comments), shortChangeID(server.ChangeID), verdictNote(review.Verdict)), nil
+}
+
+// gerritDiffInfo is the part of a DiffInfo in Gerrit's diff API that says
+// what each line of a file is: runs of lines in both versions (ab), removed
+// (a), added (b), or left out (skip).
+type gerritDiffInfo struct {
+	Content []struct {
+		AB   []string `json:"ab"`
+		A    []string `json:"a"`
+		B    []string `json:"b"`
+		Skip int      `json:"skip"`
+	} `json:"content"`
+}
+
+// gerritPatchSetDiff returns the diff of the patch set at revision for the
+// files comments are on, with each whole file as context. Files the patch set
+// doesn't have are left out.
+func gerritPatchSetDiff(cred auth.Credential, revision string, comments []comment.Comment) ([]git.FileDiff, error) {
+	var diffs []git.FileDiff
+	seen := make(map[string]bool)
+	for _, c := range comments {
+		if c.Thread != "" || seen[c.FilePath] {
+			continue
+		}
+		seen[c.FilePath] = true
+		var info gerritDiffInfo
+		// Gerrit takes the file's path as one segment, slashes and all.
+		u := revision + "/files/" + strings.ReplaceAll(url.PathEscape(c.FilePath), "/", "%2F") + "/diff?context=ALL"
+		if err := gerritGet(cred, u, &info); err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("getting the diff of %s: %w", c.FilePath, err)
+		}
+		diffs = append(diffs, info.fileDiff(c.FilePath))
+	}
+	return diffs, nil
+}
+
+// fileDiff converts d, the diff of the file at path, to a FileDiff with one
+// hunk.
+func (d gerritDiffInfo) fileDiff(path string) git.FileDiff {
+	h := git.Hunk{OldStart: 1, NewStart: 1}
+	old, new := 1, 1
+	for _, c := range d.Content {
+		for _, l := range c.AB {
+			h.Lines = append(h.Lines, git.Line{Type: git.LineContext, Content: l, OldLineNo: old, NewLineNo: new})
+			old++
+			new++
+		}
+		for _, l := range c.A {
+			h.Lines = append(h.Lines, git.Line{Type: git.LineRemoved, Content: l, OldLineNo: old})
+			old++
+		}
+		for _, l := range c.B {
+			h.Lines = append(h.Lines, git.Line{Type: git.LineAdded, Content: l, NewLineNo: new})
+			new++
+		}
+		old += c.Skip
+		new += c.Skip
+	}
+	h.OldCount, h.NewCount = old-1, new-1
+	return git.FileDiff{Path: path, Status: "M", Hunks: []git.Hunk{h}}
+}
+
+// toGerritComment converts c to Gerrit's form.
+func toGerritComment(c comment.Comment) gerritComment {
+	gc := gerritComment{Line: c.EndLine, Message: c.Body, InReplyTo: c.Thread}
+	if c.EndLine > c.StartLine {
+		gc.Message = fmt.Sprintf("Lines %d-%d: %s", c.StartLine, c.EndLine, c.Body)
+	}
+	if c.LineType == git.LineRemoved {
+		gc.Side = "PARENT"
+	}
+	return gc
+}
+
+// fetchGerritThreads returns the comments on the change's files, from every
+// patch set, oldest first within each file.
+func fetchGerritThreads(server *Gerrit) ([]comment.Comment, error) {
+	if server.ChangeID == "" {
+		return nil, errors.New("gerrit: the reviewed commit has no Change-Id trailer")
+	}
+	cred, err := server.Lookup().Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("gerrit: %w", err)
+	}
+	type commentInfo struct {
+		ID        string `json:"id"`
+		Line      int    `json:"line"`
+		Side      string `json:"side"`
+		Message   string `json:"message"`
+		InReplyTo string `json:"in_reply_to"`
+		Updated   string `json:"updated"` // e.g. "2013-02-26 15:40:43.986000000", which sorts as text
+		Author    struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	}
+	var byPath map[string][]commentInfo
+	u := fmt.Sprintf("%s/a/changes/%s/comments", strings.TrimSuffix(server.URL, "/"), url.PathEscape(server.ChangeID))
+	if err := gerritGet(cred, u, &byPath); err != nil {
+		return nil, fmt.Errorf("gerrit: getting the comments on change %s: %w", shortChangeID(server.ChangeID), err)
+	}
+
+	var comments []comment.Comment
+	for _, path := range slices.Sorted(maps.Keys(byPath)) {
+		// Comments on the commit message and the whole patch set have
+		// paths such as /COMMIT_MSG.
+		if strings.HasPrefix(path, "/") {
+			continue
+		}
+		infos := byPath[path]
+		slices.SortStableFunc(infos, func(a, b commentInfo) int { return strings.Compare(a.Updated, b.Updated) })
+		byID := make(map[string]commentInfo, len(infos))
+		for _, ci := range infos {
+			byID[ci.ID] = ci
+		}
+		for _, ci := range infos {
+			root := ci
+			for depth := 0; root.InReplyTo != "" && depth < len(infos); depth++ {
+				parent, ok := byID[root.InReplyTo]
+				if !ok {
+					break
+				}
+				root = parent
+			}
+			comments = append(comments, threadComment(root.ID, path, root.Line, root.Side == "PARENT", ci.Author.Name, ci.Message))
+		}
+	}
+	return groupThreads(comments), nil
+}
+
+// gerritGet gets u from the Gerrit API, authenticated with cred, and decodes
+// the JSON response into out.
+func gerritGet(cred auth.Credential, u string, out any) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "revui")
+	req.SetBasicAuth(cred.Username, cred.Token)
+	body, err := doText(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(strings.TrimPrefix(body, gerritXSSIPrefix)), out)
+}
+
+// gerritCall sends payload to the Gerrit API as JSON, authenticated with cred.
+func gerritCall(cred auth.Credential, method, u string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding review: %w", err)
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("User-Agent", "revui")
+	req.SetBasicAuth(cred.Username, cred.Token)
+
+	resp, err := send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		// Gerrit explains failures in plain text
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(bytes.TrimPrefix(snippet, []byte(gerritXSSIPrefix))))
+	}
+	return nil
+}
+
+// shortChangeID abbreviates a Change-Id for messages.
+func shortChangeID(id string) string {
+	if len(id) > 9 {
+		return id[:9]
+	}
+	return id
+}
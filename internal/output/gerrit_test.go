@@ -0,0 +1,228 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// serveGerritDiffs answers requests for the diffs of the current patch set's
+// files, reporting whether r was one.
+func serveGerritDiffs(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/diff") {
+		return false
+	}
+	switch strings.TrimSuffix(r.URL.Path[strings.Index(r.URL.Path, "/files/")+len("/files/"):], "/diff") {
+	case "a.go":
+		w.Write([]byte(`)]}'
+{"content": [
+	{"ab": ["l1", "l2", "l3"]},
+	{"a": ["l4"]},
+	{"ab": ["l5", "l6", "l7", "l8", "l9"]},
+	{"b": ["a9", "a10", "a11", "a12"]}
+]}`))
+	case "b.png":
+		w.Write([]byte(")]}'\n{\"binary\": true, \"content\": [{\"skip\": 1}]}"))
+	default:
+		http.NotFound(w, r)
+	}
+	return true
+}
+
+func TestDeliverGerrit(t *testing.T) {
+	var got gerritReview
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveGerritDiffs(w, r) {
+			return
+		}
+		path = r.URL.Path
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "http-pass" {
+			t.Error("want basic auth with the HTTP password")
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(")]}'\n{}"))
+	}))
+	defer srv.Close()
+
+	servers := []Gerrit{{URL: srv.URL, Username: "me", Password: "http-pass", Labels: map[string]int{"Code-Review": -1}}}
+	target := GerritTargets(servers, "I8473b95934b5732ac55d26311a706c9c2bde9940")[0]
+	if target.Label != "Gerrit: "+srv.URL {
+		t.Errorf("label = %q", target.Label)
+	}
+	msg, err := Deliver(target, Review{Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 12, LineType: git.LineAdded, Body: "split this"},
+		{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineRemoved, Body: "keep this"},
+		{FilePath: "b.png", Body: "too big"},
+		{FilePath: "a.go", StartLine: 20, EndLine: 20, LineType: git.LineAdded, Body: "fixed", Thread: "c1"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Posted 4 comments to Gerrit change I8473b959" {
+		t.Errorf("message = %q", msg)
+	}
+	if path != "/a/changes/I8473b95934b5732ac55d26311a706c9c2bde9940/revisions/current/review" {
+		t.Errorf("posted to %s", path)
+	}
+	want := gerritReview{
+		Labels: map[string]int{"Code-Review": -1},
+		Comments: map[string][]gerritComment{
+			"a.go": {
+				{Line: 12, Message: "Lines 10-12: split this"},
+				{Line: 4, Side: "PARENT", Message: "keep this"},
+				{Line: 20, Message: "fixed", InReplyTo: "c1"},
+			},
+			"b.png": {{Message: "too big"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("review = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeliverGerritErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Applying label \"Code-Review\": -3 is restricted", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	t.Setenv("GERRIT_PASSWORD", "")
+
+	tests := []struct {
+		name   string
+		server Gerrit
+		want   string
+	}{
+		{name: "no change", server: Gerrit{Password: "p"}, want: "no Change-Id"},
+		{name: "no password", server: Gerrit{ChangeID: "I1"}, want: "GERRIT_PASSWORD"},
+		{name: "rejected", server: Gerrit{URL: srv.URL, Password: "p", ChangeID: "I1"}, want: "-3 is restricted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := deliverToGerrit(&tt.server, Review{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverGerritDraftAndVerdict(t *testing.T) {
+	var requests []string
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveGerritDiffs(w, r) {
+			return
+		}
+		var body strings.Builder
+		io.Copy(&body, r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		bodies = append(bodies, body.String())
+		w.Write([]byte(")]}'\n{}"))
+	}))
+	defer srv.Close()
+
+	server := &Gerrit{URL: srv.URL, Password: "p", ChangeID: "I1", Labels: map[string]int{"Verified": 1}}
+	comments := []comment.Comment{{FilePath: "a.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "nit"}}
+	msg, err := deliverToGerrit(server, Review{Comments: comments, Draft: true, Verdict: VerdictApprove})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Saved 1 draft comments on Gerrit change I1" {
+		t.Errorf("message = %q", msg)
+	}
+	if requests[0] != "PUT /a/changes/I1/revisions/current/drafts" || bodies[0] != `{"path":"a.go","line":3,"message":"nit"}` {
+		t.Errorf("draft request = %s %s", requests[0], bodies[0])
+	}
+
+	requests, bodies = nil, nil
+	msg, err = deliverToGerrit(server, Review{Comments: comments, Verdict: VerdictRequestChanges})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Posted 1 comments to Gerrit change I1 and requested changes" {
+		t.Errorf("message = %q", msg)
+	}
+	var got gerritReview
+	json.Unmarshal([]byte(bodies[0]), &got)
+	if want := map[string]int{"Verified": 1, "Code-Review": -1}; !reflect.DeepEqual(got.Labels, want) {
+		t.Errorf("labels = %v, want %v", got.Labels, want)
+	}
+	if len(server.Labels) != 1 {
+		t.Error("the verdict shouldn't change the configured labels")
+	}
+}
+
+func TestDeliverGerritPlacement(t *testing.T) {
+	var got gerritReview
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveGerritDiffs(w, r) {
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(")]}'\n{}"))
+	}))
+	defer srv.Close()
+	server := &Gerrit{URL: srv.URL, Password: "p", ChangeID: "I1"}
+
+	// Written on a diff where a10 was line 20: it moves to where a10 is
+	local := &git.FileDiff{Path: "a.go", Hunks: []git.Hunk{{Lines: []git.Line{
+		{Type: git.LineAdded, Content: "a10", NewLineNo: 20},
+	}}}}
+	_, err := deliverToGerrit(server, Review{Diffs: map[string]*git.FileDiff{"a.go": local}, Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 20, EndLine: 20, LineType: git.LineAdded, Body: "moved"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []gerritComment{{Line: 10, Message: "moved"}}; !reflect.DeepEqual(got.Comments["a.go"], want) {
+		t.Errorf("comments = %+v, want %+v", got.Comments["a.go"], want)
+	}
+
+	// A file the patch set doesn't have can't take comments
+	got = gerritReview{}
+	_, err = deliverToGerrit(server, Review{Comments: []comment.Comment{{FilePath: "c.go", Body: "not in the change"}}})
+	if err == nil || !strings.Contains(err.Error(), "c.go") || got.Comments != nil {
+		t.Errorf("error = %v, posted %+v; want c.go named and nothing posted", err, got)
+	}
+}
+
+func TestFetchGerritThreads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/a/changes/Iabc/comments" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`)]}'
+{
+	"/COMMIT_MSG": [{"id": "c0", "line": 1, "message": "typo", "updated": "2024-01-01 10:00:00.000000000", "author": {"name": "Ann"}}],
+	"b.go": [{"id": "c3", "line": 2, "side": "PARENT", "message": "keep", "updated": "2024-01-01 09:00:00.000000000", "author": {"name": "Bo"}}],
+	"a.go": [
+		{"id": "c2", "line": 10, "message": "because", "in_reply_to": "c1", "updated": "2024-01-02 10:00:00.000000000", "author": {"name": "Cy"}},
+		{"id": "c1", "line": 10, "message": "why?", "updated": "2024-01-01 10:00:00.000000000", "author": {"name": "Ann"}}
+	]
+}`))
+	}))
+	defer srv.Close()
+
+	target := GerritTargets([]Gerrit{{URL: srv.URL, Username: "me", Password: "pw"}}, "Iabc")[0]
+	got, err := FetchThreads(target, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "why?", Author: "Ann", Thread: "c1"},
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "because", Author: "Cy", Thread: "c1"},
+		{FilePath: "b.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "keep", Author: "Bo", Thread: "c3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threads = %+v, want %+v", got, want)
+	}
+}
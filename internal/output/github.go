@@ -0,0 +1,414 @@
+package output
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/deparker/revui/internal/auth"
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// githubAPI is the github.com REST API base URL, replaced in tests.
+var githubAPI = "https://api.github.com"
+
+// githubPageSize is how many results are asked for per page of a GitHub list.
+const githubPageSize = 100
+
+// GitHub is a GitHub repository whose open pull request for the reviewed
+// branch receives the comments as a review. URL, Owner, and Repo are detected
+// from the origin remote when left empty. URL is the API URL, and is only
+// needed for GitHub Enterprise Server, e.g. https://github.acme.com/api/v3.
+// Token needs write access to pull requests; when empty, it is resolved as
+// described on auth.Lookup, from GH_TOKEN, GITHUB_TOKEN, the gh CLI's login,
+// or a git credential helper.
+type GitHub struct {
+	URL   string `json:"url,omitempty"`
+	Owner string `json:"owner,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+	Token string `json:"token,omitempty"`
+	// headOwner owns the repository the reviewed branch is pushed to, which
+	// is Owner's fork when Owner is configured to the upstream repository.
+	headOwner string
+}
+
+// ParseGitHubRemote returns the API URL, owner, and repository of a GitHub
+// remote URL, in HTTPS, SSH, or scp-like form. Hosts other than github.com
+// are taken for GitHub Enterprise Server when their name says github.
+func ParseGitHubRemote(remote string) (GitHub, bool) {
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		if u.Scheme != "https" && u.Scheme != "ssh" {
+			return GitHub{}, false
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		// git@github.com:owner/repo.git
+		before, after, ok := strings.Cut(remote, ":")
+		if !ok {
+			return GitHub{}, false
+		}
+		host, path = before[strings.LastIndex(before, "@")+1:], after
+	}
+	if !strings.Contains(host, "github") {
+		return GitHub{}, false
+	}
+	owner, repo, ok := strings.Cut(strings.Trim(path, "/"), "/")
+	repo = strings.TrimSuffix(repo, ".git")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return GitHub{}, false
+	}
+	var api string
+	if host != "github.com" {
+		api = "https://" + host + "/api/v3"
+	}
+	return GitHub{URL: api, Owner: owner, Repo: repo}, true
+}
+
+// GitHubTargets returns an output target for the repository configured by
+// cfg, which may be nil, filling in whatever it leaves out from the origin
+// remote's URL. It returns none when the repository isn't known.
+func GitHubTargets(cfg *GitHub, remoteURL string) []OutputTarget {
+	var repo GitHub
+	if cfg != nil {
+		repo = *cfg
+	}
+	if detected, ok := ParseGitHubRemote(remoteURL); ok {
+		repo.URL = cmp.Or(repo.URL, detected.URL)
+		repo.Owner = cmp.Or(repo.Owner, detected.Owner)
+		repo.Repo = cmp.Or(repo.Repo, detected.Repo)
+		repo.headOwner = detected.Owner
+	}
+	if repo.Owner == "" || repo.Repo == "" {
+		return nil
+	}
+	return []OutputTarget{{
+		Kind:   TargetGitHub,
+		Label:  "GitHub PR: " + repo.Owner + "/" + repo.Repo,
+		GitHub: &repo,
+	}}
+}
+
+// Lookup returns where the repository's credential is looked for.
+func (repo *GitHub) Lookup() auth.Lookup {
+	host := "github.com"
+	if u, err := url.Parse(repo.URL); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	l := auth.GitHub(host)
+	l.Config = auth.Credential{Token: repo.Token}
+	return l
+}
+
+// githubReviewComment is a draft review comment in GitHub's reviews API, on
+// lines of the old (LEFT) or new (RIGHT) side of the diff.
+type githubReviewComment struct {
+	Path      string `json:"path"`
+	Body      string `json:"body"`
+	Line      int    `json:"line"`
+	Side      string `json:"side"`
+	StartLine int    `json:"start_line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+}
+
+// githubReview is a review in GitHub's reviews API. An empty Event leaves the
+// review pending, for the reviewer to submit from GitHub.
+type githubReview struct {
+	CommitID string                `json:"commit_id"`
+	Body     string                `json:"body,omitempty"`
+	Event    string                `json:"event,omitempty"`
+	Comments []githubReviewComment `json:"comments,omitempty"`
+}
+
+// githubPullRequest is the part of a pull request in GitHub's API revui uses.
+type githubPullRequest struct {
+	Number int `json:"number"`
+	Head   struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// PullRequestBranches returns the branch pull request number is from and the
+// one it is to be merged into.
+func (repo *GitHub) PullRequestBranches(number int) (head, base string, err error) {
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return "", "", fmt.Errorf("github: %w", err)
+	}
+	c := githubClient{repo: repo, token: cred.Token}
+	var pr githubPullRequest
+	if err := c.call(http.MethodGet, fmt.Sprintf("%s/%d", c.pullsURL(), number), nil, &pr); err != nil {
+		return "", "", fmt.Errorf("github: getting PR #%d: %w", number, err)
+	}
+	return pr.Head.Ref, pr.Base.Ref, nil
+}
+
+// deliverToGitHub posts the comments as a review of the open pull request from
+// the reviewed branch, with the verdict as its event, then posts replies into
+// the threads they answer. A draft review is left pending for the reviewer to
+// submit from GitHub; replies are posted straight away even then, as GitHub's
+// API can't add them to a pending review. GitHub review comments are on lines,
+// so comments on a whole file go in the review's body.
+func deliverToGitHub(repo *GitHub, review Review) (string, error) {
+	if review.hostBranch() == "" && review.PullRequest == 0 {
+		return "", errors.New("github: the review has no branch to find a pull request for")
+	}
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return "", unsent(fmt.Errorf("github: %w", err), review)
+	}
+	c := githubClient{repo: repo, token: cred.Token}
+
+	pr, err := c.findPullRequest(review.hostBranch(), review.PullRequest)
+	if err != nil {
+		return "", unsent(fmt.Errorf("github: %w", err), review)
+	}
+	// Review comments are anchored to the pull request's diff, which
+	// rejects lines it doesn't show, failing the whole review.
+	diff, err := c.pullRequestDiff(pr.Number)
+	if err != nil {
+		return "", unsent(fmt.Errorf("github: getting the diff of PR #%d: %w", pr.Number, err), review)
+	}
+	comments, err := placeComments(review.Comments, review.Diffs, diff, fmt.Sprintf("PR #%d", pr.Number))
+	if err != nil {
+		return "", fmt.Errorf("github: %w", err)
+	}
+
+	input := githubReview{CommitID: pr.Head.SHA, Event: githubEvent(review)}
+	var replies, fileComments []comment.Comment
+	for _, cm := range comments {
+		switch {
+		case cm.Thread != "":
+			replies = append(replies, cm)
+		case cm.StartLine == 0:
+			fileComments = append(fileComments, cm)
+		default:
+			input.Comments = append(input.Comments, toGitHubComment(cm))
+		}
+	}
+	var body []string
+	for _, cm := range fileComments {
+		body = append(body, fmt.Sprintf("**`%s`**: %s", cm.FilePath, cm.Body))
+	}
+	input.Body = strings.Join(body, "\n\n")
+	if input.Body == "" && input.Event == "REQUEST_CHANGES" {
+		// GitHub requires a body to request changes.
+		input.Body = "Requesting changes."
+	}
+	posted := len(input.Comments) + len(fileComments)
+	if posted > 0 || review.Verdict != VerdictNone {
+		if err := c.call(http.MethodPost, fmt.Sprintf("%s/%d/reviews", c.pullsURL(), pr.Number), input, nil); err != nil {
+			return "", unsent(fmt.Errorf("github: posting the review to PR #%d: %w", pr.Number, err), review)
+		}
+	}
+	for i, cm := range replies {
+		u := fmt.Sprintf("%s/%d/comments/%s/replies", c.pullsURL(), pr.Number, url.PathEscape(cm.Thread))
+		if err := c.call(http.MethodPost, u, map[string]string{"body": cm.Body}, nil); err != nil {
+			err = fmt.Errorf("github: posted %d of %d comments to PR #%d: %w", posted+i, len(review.Comments), pr.Number, err)
+			rest := review.remaining(replies[i:])
+			rest.Verdict = VerdictNone
+			return "", unsent(err, rest)
+		}
+	}
+	if review.Draft {
+		return fmt.Sprintf("Saved %d comments in a pending review on GitHub PR #%d", len(review.Comments), pr.Number), nil
+	}
+	return fmt.Sprintf("Posted %d comments to GitHub PR #%d%s", len(review.Comments), pr.Number, verdictNote(review.Verdict)), nil
+}
+
+// githubEvent returns the event that submits review with its verdict, or ""
+// to leave a draft pending.
+func githubEvent(review Review) string {
+	switch {
+	case review.Draft:
+		return ""
+	case review.Verdict == VerdictApprove:
+		return "APPROVE"
+	case review.Verdict == VerdictRequestChanges:
+		return "REQUEST_CHANGES"
+	}
+	return "COMMENT"
+}
+
+// toGitHubComment converts c, a comment on lines, to GitHub's form.
+func toGitHubComment(c comment.Comment) githubReviewComment {
+	side := "RIGHT"
+	if c.LineType == git.LineRemoved {
+		side = "LEFT"
+	}
+	gc := githubReviewComment{Path: c.FilePath, Body: c.Body, Line: c.EndLine, Side: side}
+	if c.EndLine > c.StartLine {
+		gc.StartLine, gc.StartSide = c.StartLine, side
+	}
+	return gc
+}
+
+// fetchGitHubThreads returns the review comments on pull request number, or
+// if it is 0, the open pull request from branch. Replies on GitHub answer a thread's first comment, whose ID is
+// the thread's. Comments on lines the pull request no longer shows are left
+// out.
+func fetchGitHubThreads(repo *GitHub, branch string, number int) ([]comment.Comment, error) {
+	if branch == "" && number == 0 {
+		return nil, errors.New("github: the review has no branch to find a pull request for")
+	}
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("github: %w", err)
+	}
+	c := githubClient{repo: repo, token: cred.Token}
+	pr, err := c.findPullRequest(branch, number)
+	if err != nil {
+		return nil, fmt.Errorf("github: %w", err)
+	}
+
+	type githubComment struct {
+		ID          int    `json:"id"`
+		InReplyToID int    `json:"in_reply_to_id"`
+		Path        string `json:"path"`
+		Line        *int   `json:"line"`
+		Side        string `json:"side"`
+		SubjectType string `json:"subject_type"`
+		Body        string `json:"body"`
+		User        struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	var all []githubComment
+	for page := 1; ; page++ {
+		var batch []githubComment
+		u := fmt.Sprintf("%s/%d/comments?per_page=%d&page=%d", c.pullsURL(), pr.Number, githubPageSize, page)
+		if err := c.call(http.MethodGet, u, nil, &batch); err != nil {
+			return nil, fmt.Errorf("github: getting the comments on PR #%d: %w", pr.Number, err)
+		}
+		all = append(all, batch...)
+		if len(batch) < githubPageSize {
+			break
+		}
+	}
+
+	byID := make(map[int]githubComment, len(all))
+	for _, cm := range all {
+		byID[cm.ID] = cm
+	}
+	var comments []comment.Comment
+	for _, cm := range all {
+		root := cm
+		if parent, ok := byID[cm.InReplyToID]; ok {
+			root = parent
+		}
+		if root.Line == nil && root.SubjectType != "file" {
+			continue
+		}
+		var line int
+		if root.Line != nil {
+			line = *root.Line
+		}
+		comments = append(comments, threadComment(strconv.Itoa(root.ID), root.Path, line, root.Side == "LEFT", cm.User.Login, cm.Body))
+	}
+	return groupThreads(comments), nil
+}
+
+// githubClient calls the GitHub REST API for repo.
+type githubClient struct {
+	repo  *GitHub
+	token string
+}
+
+// pullsURL returns the URL of the repository's pull requests.
+func (c githubClient) pullsURL() string {
+	return fmt.Sprintf("%s/repos/%s/%s/pulls",
+		strings.TrimSuffix(cmp.Or(c.repo.URL, githubAPI), "/"), url.PathEscape(c.repo.Owner), url.PathEscape(c.repo.Repo))
+}
+
+// findPullRequest returns pull request number, or if it is 0, the open pull
+// request from branch. The branch is looked for in the repository it is
+// pushed to, which may be a fork, so that forks' branches of the same name
+// aren't taken for it.
+func (c githubClient) findPullRequest(branch string, number int) (githubPullRequest, error) {
+	if number != 0 {
+		var pr githubPullRequest
+		if err := c.call(http.MethodGet, fmt.Sprintf("%s/%d", c.pullsURL(), number), nil, &pr); err != nil {
+			return githubPullRequest{}, fmt.Errorf("getting PR #%d: %w", number, err)
+		}
+		return pr, nil
+	}
+	head := cmp.Or(c.repo.headOwner, c.repo.Owner) + ":" + branch
+	var prs []githubPullRequest
+	u := fmt.Sprintf("%s?state=open&head=%s", c.pullsURL(), url.QueryEscape(head))
+	if err := c.call(http.MethodGet, u, nil, &prs); err != nil {
+		return githubPullRequest{}, fmt.Errorf("finding the pull request for %s: %w", head, err)
+	}
+	switch len(prs) {
+	case 0:
+		return githubPullRequest{}, fmt.Errorf("no open pull request from %s in %s/%s", head, c.repo.Owner, c.repo.Repo)
+	case 1:
+		return prs[0], nil
+	}
+	numbers := make([]string, len(prs))
+	for i, pr := range prs {
+		numbers[i] = fmt.Sprintf("#%d", pr.Number)
+	}
+	return githubPullRequest{}, fmt.Errorf("%d open pull requests from %s in %s/%s (%s); review one with revui pr <number>",
+		len(prs), head, c.repo.Owner, c.repo.Repo, strings.Join(numbers, ", "))
+}
+
+// pullRequestDiff returns the diff of pull request number.
+func (c githubClient) pullRequestDiff(number int) ([]git.FileDiff, error) {
+	req, err := c.request(http.MethodGet, fmt.Sprintf("%s/%d", c.pullsURL(), number), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.diff")
+	raw, err := doText(req)
+	if err != nil {
+		return nil, err
+	}
+	return git.ParseDiff(raw)
+}
+
+// call sends a request to the GitHub API, JSON-encoding payload if non-nil
+// and decoding the response into out if non-nil.
+func (c githubClient) call(method, u string, payload, out any) error {
+	req, err := c.request(method, u, payload)
+	if err != nil {
+		return err
+	}
+	return doJSON(req, out)
+}
+
+// request returns an authenticated request to the GitHub API with payload
+// JSON-encoded as its body, if non-nil.
+func (c githubClient) request(method, u string, payload any) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "revui")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return req, nil
+}
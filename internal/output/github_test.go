@@ -0,0 +1,293 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestParseGitHubRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   GitHub
+		ok     bool
+	}{
+		{remote: "https://github.com/acme/api.git", want: GitHub{Owner: "acme", Repo: "api"}, ok: true},
+		{remote: "https://github.com/acme/api", want: GitHub{Owner: "acme", Repo: "api"}, ok: true},
+		{remote: "git@github.com:acme/api.git", want: GitHub{Owner: "acme", Repo: "api"}, ok: true},
+		{remote: "ssh://git@github.com/acme/api.git", want: GitHub{Owner: "acme", Repo: "api"}, ok: true},
+		{remote: "git@github.acme.com:platform/api.git", want: GitHub{URL: "https://github.acme.com/api/v3", Owner: "platform", Repo: "api"}, ok: true},
+		{remote: "https://dev.azure.com/acme/Platform/_git/api"},
+		{remote: "https://github.com/acme"},
+		{remote: "http://github.com/acme/api"},
+		{remote: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remote, func(t *testing.T) {
+			got, ok := ParseGitHubRemote(tt.remote)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseGitHubRemote(%q) = %+v, %v; want %+v, %v", tt.remote, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGitHubTargets(t *testing.T) {
+	if got := GitHubTargets(nil, "https://dev.azure.com/acme/Platform/_git/api"); got != nil {
+		t.Errorf("targets for an Azure DevOps remote = %+v, want none", got)
+	}
+
+	targets := GitHubTargets(&GitHub{Token: "tok", Owner: "upstream"}, "git@github.com:me/api.git")
+	if len(targets) != 1 {
+		t.Fatalf("got %d targets, want 1", len(targets))
+	}
+	if targets[0].Label != "GitHub PR: upstream/api" {
+		t.Errorf("label = %q", targets[0].Label)
+	}
+	want := GitHub{Owner: "upstream", Repo: "api", Token: "tok", headOwner: "me"}
+	if *targets[0].GitHub != want {
+		t.Errorf("repo = %+v, want %+v", *targets[0].GitHub, want)
+	}
+}
+
+const githubTestDiff = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,5 +1,6 @@
+ package a
+-func old() {}
++func one() {}
++func two() {}
+ 
+ var x = 1
+ var y = 2
+`
+
+func TestDeliverGitHub(t *testing.T) {
+	var review githubReview
+	var replies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Error("want a bearer token")
+		}
+		switch {
+		case r.URL.Path == "/repos/acme/api/pulls":
+			if got := r.URL.Query().Get("head"); got != "acme:feature" {
+				t.Errorf("pull requests asked for by head %q, want acme:feature", got)
+			}
+			w.Write([]byte(`[{"number": 7, "head": {"ref": "feature", "sha": "abc123"}}]`))
+		case r.URL.Path == "/repos/acme/api/pulls/7" && r.Header.Get("Accept") == "application/vnd.github.diff":
+			w.Write([]byte(githubTestDiff))
+		case r.URL.Path == "/repos/acme/api/pulls/7/reviews":
+			json.NewDecoder(r.Body).Decode(&review)
+			w.Write([]byte(`{"id": 1}`))
+		case r.URL.Path == "/repos/acme/api/pulls/7/comments/55/replies":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			replies = append(replies, body["body"])
+			w.Write([]byte(`{"id": 2}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	repo := &GitHub{URL: srv.URL, Owner: "acme", Repo: "api", Token: "tok"}
+	// A remote branch reviewed as origin/feature is looked up as feature
+	msg, err := Deliver(OutputTarget{Kind: TargetGitHub, GitHub: repo}, Review{Branch: "origin/feature", HostBranch: "feature", Verdict: VerdictApprove, Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 2, EndLine: 3, LineType: git.LineAdded, Body: "split this"},
+		{FilePath: "a.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "keep this"},
+		{FilePath: "a.go", Body: "rename the file"},
+		{FilePath: "a.go", StartLine: 5, EndLine: 5, LineType: git.LineContext, Body: "fixed", Thread: "55"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Posted 4 comments to GitHub PR #7 and approved it" {
+		t.Errorf("message = %q", msg)
+	}
+	want := githubReview{
+		CommitID: "abc123",
+		Body:     "**`a.go`**: rename the file",
+		Event:    "APPROVE",
+		Comments: []githubReviewComment{
+			{Path: "a.go", Body: "split this", Line: 3, Side: "RIGHT", StartLine: 2, StartSide: "RIGHT"},
+			{Path: "a.go", Body: "keep this", Line: 2, Side: "LEFT"},
+		},
+	}
+	if !reflect.DeepEqual(review, want) {
+		t.Errorf("review = %+v, want %+v", review, want)
+	}
+	if !reflect.DeepEqual(replies, []string{"fixed"}) {
+		t.Errorf("replies = %q", replies)
+	}
+}
+
+func TestDeliverGitHubDraft(t *testing.T) {
+	var review githubReview
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/api/pulls":
+			w.Write([]byte(`[{"number": 7, "head": {"ref": "feature", "sha": "abc123"}}]`))
+		case "/repos/acme/api/pulls/7":
+			w.Write([]byte(githubTestDiff))
+		case "/repos/acme/api/pulls/7/reviews":
+			json.NewDecoder(r.Body).Decode(&review)
+			http.Error(w, `{"message": "Validation Failed"}`, http.StatusUnprocessableEntity)
+		}
+	}))
+	defer srv.Close()
+
+	repo := &GitHub{URL: srv.URL, Owner: "acme", Repo: "api", Token: "tok"}
+	sent := Review{Branch: "feature", Draft: true, Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "nit"},
+	}}
+	_, err := deliverToGitHub(repo, sent)
+	if review.Event != "" {
+		t.Errorf("a draft review should be left pending, got event %q", review.Event)
+	}
+	var ue *UnsentError
+	if !errors.As(err, &ue) || !reflect.DeepEqual(ue.Unsent, sent) {
+		t.Errorf("error = %v, want the whole review unsent", err)
+	}
+}
+
+func TestFetchGitHubThreads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/api/pulls":
+			w.Write([]byte(`[{"number": 7, "head": {"ref": "feature", "sha": "abc123"}}]`))
+		case "/repos/acme/api/pulls/7/comments":
+			w.Write([]byte(`[
+				{"id": 5, "path": "a.go", "line": 10, "side": "RIGHT", "subject_type": "line", "body": "why?", "user": {"login": "ann"}},
+				{"id": 6, "path": "b.go", "line": 2, "side": "LEFT", "subject_type": "line", "body": "keep", "user": {"login": "bo"}},
+				{"id": 8, "in_reply_to_id": 5, "path": "a.go", "line": 10, "side": "RIGHT", "subject_type": "line", "body": "because", "user": {"login": "cy"}},
+				{"id": 9, "path": "c.go", "line": null, "side": "RIGHT", "subject_type": "line", "body": "outdated", "user": {"login": "ann"}},
+				{"id": 10, "path": "d.go", "line": null, "subject_type": "file", "body": "whole file", "user": {"login": "ann"}}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	repo := &GitHub{URL: srv.URL, Owner: "acme", Repo: "api", Token: "tok"}
+	got, err := FetchThreads(OutputTarget{Kind: TargetGitHub, GitHub: repo}, "feature", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "why?", Author: "ann", Thread: "5"},
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "because", Author: "cy", Thread: "5"},
+		{FilePath: "b.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "keep", Author: "bo", Thread: "6"},
+		{FilePath: "d.go", LineType: git.LineAdded, Body: "whole file", Author: "ann", Thread: "10"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threads = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeliverGitHubErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+
+	tests := []struct {
+		name   string
+		branch string
+		repo   GitHub
+		want   string
+	}{
+		{name: "no branch", repo: GitHub{Token: "tok"}, want: "no branch"},
+		{name: "no token", branch: "feature", repo: GitHub{URL: "https://localhost.invalid/api/v3"}, want: "GITHUB_TOKEN"},
+		{name: "no pull request", branch: "feature", repo: GitHub{URL: srv.URL, Owner: "acme", Repo: "api", Token: "tok"}, want: "no open pull request from acme:feature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := deliverToGitHub(&tt.repo, Review{Branch: tt.branch})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubFindPullRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/acme/api/pulls/9":
+			w.Write([]byte(`{"number": 9, "head": {"ref": "patch-1", "sha": "ccc"}}`))
+		case r.URL.Path == "/repos/acme/api/pulls" && r.URL.Query().Get("head") == "me:patch-1":
+			w.Write([]byte(`[{"number": 4, "head": {"ref": "patch-1", "sha": "bbb"}}]`))
+		case r.URL.Path == "/repos/acme/api/pulls" && r.URL.Query().Get("head") == "me:twice":
+			w.Write([]byte(`[{"number": 4}, {"number": 5}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+
+	// The branch is looked for in the fork it is pushed to
+	c := githubClient{repo: &GitHub{URL: srv.URL, Owner: "acme", Repo: "api", headOwner: "me"}}
+	tests := []struct {
+		name    string
+		branch  string
+		number  int
+		want    int
+		wantErr string
+	}{
+		{name: "by number", branch: "patch-1", number: 9, want: 9},
+		{name: "by branch", branch: "patch-1", want: 4},
+		{name: "other fork's branch", branch: "main", wantErr: "no open pull request from me:main"},
+		{name: "ambiguous", branch: "twice", wantErr: "2 open pull requests from me:twice in acme/api (#4, #5)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr, err := c.findPullRequest(tt.branch, tt.number)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pr.Number != tt.want {
+				t.Errorf("found PR #%d, want #%d", pr.Number, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubPullRequestBranches(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/api/pulls/7" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"number": 7, "head": {"ref": "feature", "sha": "abc123"}, "base": {"ref": "main"}}`))
+	}))
+	defer srv.Close()
+
+	repo := &GitHub{URL: srv.URL, Owner: "acme", Repo: "api", Token: "tok"}
+	head, base, err := repo.PullRequestBranches(7)
+	if err != nil || head != "feature" || base != "main" {
+		t.Errorf("PullRequestBranches(7) = %q, %q, %v; want feature, main", head, base, err)
+	}
+	if _, _, err := repo.PullRequestBranches(8); err == nil {
+		t.Error("a missing pull request should fail")
+	}
+}
@@ -0,0 +1,483 @@
+package output
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/deparker/revui/internal/auth"
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// gitlabURL is GitLab.com's URL, used when a GitLab target doesn't set one.
+const gitlabURL = "https://gitlab.com"
+
+// gitlabPageSize is how many results are asked for per page of a GitLab list.
+const gitlabPageSize = 100
+
+// GitLab is a GitLab project whose open merge request for the reviewed branch
+// receives the comments as discussions. URL and Project are detected from the
+// origin remote when left empty. URL is the instance's URL, and is only needed
+// for self-managed GitLab, e.g. https://gitlab.acme.com. Project is the
+// project's path, with any subgroups, e.g. platform/backend/api. Token needs
+// the api scope; when empty, it is resolved as described on auth.Lookup, from
+// GITLAB_TOKEN, the glab CLI's login, or a git credential helper.
+type GitLab struct {
+	URL     string `json:"url,omitempty"`
+	Project string `json:"project,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// ParseGitLabRemote returns the instance URL and project path of a GitLab
+// remote URL, in HTTPS, SSH, or scp-like form. Hosts other than gitlab.com
+// are taken for self-managed GitLab when their name says gitlab.
+func ParseGitLabRemote(remote string) (GitLab, bool) {
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		if u.Scheme != "https" && u.Scheme != "ssh" {
+			return GitLab{}, false
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		// git@gitlab.com:group/project.git
+		before, after, ok := strings.Cut(remote, ":")
+		if !ok {
+			return GitLab{}, false
+		}
+		host, path = before[strings.LastIndex(before, "@")+1:], after
+	}
+	if !strings.Contains(host, "gitlab") {
+		return GitLab{}, false
+	}
+	project := strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	// Projects are in a group or a user's namespace, perhaps in subgroups.
+	if !strings.Contains(project, "/") || strings.Contains(project, "//") {
+		return GitLab{}, false
+	}
+	var instance string
+	if host != "gitlab.com" {
+		instance = "https://" + host
+	}
+	return GitLab{URL: instance, Project: project}, true
+}
+
+// GitLabTargets returns an output target for the project configured by cfg,
+// which may be nil, filling in whatever it leaves out from the origin remote's
+// URL. It returns none when the project isn't known.
+func GitLabTargets(cfg *GitLab, remoteURL string) []OutputTarget {
+	var repo GitLab
+	if cfg != nil {
+		repo = *cfg
+	}
+	if detected, ok := ParseGitLabRemote(remoteURL); ok {
+		repo.URL = cmp.Or(repo.URL, detected.URL)
+		repo.Project = cmp.Or(repo.Project, detected.Project)
+	}
+	if repo.Project == "" {
+		return nil
+	}
+	return []OutputTarget{{
+		Kind:   TargetGitLab,
+		Label:  "GitLab MR: " + repo.Project,
+		GitLab: &repo,
+	}}
+}
+
+// Lookup returns where the project's credential is looked for.
+func (repo *GitLab) Lookup() auth.Lookup {
+	host := "gitlab.com"
+	if u, err := url.Parse(repo.URL); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	l := auth.GitLab(host)
+	l.Config = auth.Credential{Token: repo.Token}
+	return l
+}
+
+// gitlabMergeRequest is the part of a merge request in GitLab's API revui
+// uses. DiffRefs is only given when a single merge request is asked for.
+type gitlabMergeRequest struct {
+	IID          int    `json:"iid"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	DiffRefs     struct {
+		BaseSHA  string `json:"base_sha"`
+		StartSHA string `json:"start_sha"`
+		HeadSHA  string `json:"head_sha"`
+	} `json:"diff_refs"`
+}
+
+// MergeRequestBranches returns the branch merge request iid is from and the
+// one it is to be merged into.
+func (repo *GitLab) MergeRequestBranches(iid int) (head, base string, err error) {
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return "", "", fmt.Errorf("gitlab: %w", err)
+	}
+	c := gitlabClient{repo: repo, token: cred.Token}
+	var mr gitlabMergeRequest
+	if err := c.call(http.MethodGet, c.mergeRequestURL(iid), nil, &mr); err != nil {
+		return "", "", fmt.Errorf("gitlab: getting MR !%d: %w", iid, err)
+	}
+	return mr.SourceBranch, mr.TargetBranch, nil
+}
+
+// gitlabLine is an end of a range of lines in a GitLab position. Type is "new"
+// for an added line, "old" for a removed one, and empty for context.
+type gitlabLine struct {
+	LineCode string `json:"line_code"`
+	Type     string `json:"type,omitempty"`
+	OldLine  int    `json:"old_line,omitempty"`
+	NewLine  int    `json:"new_line,omitempty"`
+}
+
+// gitlabPosition anchors a discussion to a file of a merge request's diff
+// version and, for a text position, to a line: NewLine for added and context
+// lines, OldLine for removed and context lines. LineRange is set for comments
+// on more than one line.
+type gitlabPosition struct {
+	PositionType string           `json:"position_type"`
+	BaseSHA      string           `json:"base_sha"`
+	StartSHA     string           `json:"start_sha"`
+	HeadSHA      string           `json:"head_sha"`
+	OldPath      string           `json:"old_path"`
+	NewPath      string           `json:"new_path"`
+	OldLine      int              `json:"old_line,omitempty"`
+	NewLine      int              `json:"new_line,omitempty"`
+	LineRange    *gitlabLineRange `json:"line_range,omitempty"`
+}
+
+// gitlabLineRange is the lines a multi-line comment is on.
+type gitlabLineRange struct {
+	Start gitlabLine `json:"start"`
+	End   gitlabLine `json:"end"`
+}
+
+// gitlabDiscussion is a new discussion, or a draft note, in GitLab's API.
+// Draft notes take their body as Note and their thread as InReplyTo.
+type gitlabDiscussion struct {
+	Body      string          `json:"body,omitempty"`
+	Note      string          `json:"note,omitempty"`
+	InReplyTo string          `json:"in_reply_to_discussion_id,omitempty"`
+	Position  *gitlabPosition `json:"position,omitempty"`
+}
+
+// deliverToGitLab starts a discussion for each comment on the open merge
+// request from the reviewed branch, on its file and lines, and posts replies
+// into the discussions they answer, then records the verdict: approving the
+// merge request, or for requested changes, saying so in a note, as GitLab's
+// API can't request changes. Draft comments are saved as draft notes, for the
+// reviewer to submit from GitLab.
+func deliverToGitLab(repo *GitLab, review Review) (string, error) {
+	if review.hostBranch() == "" && review.PullRequest == 0 {
+		return "", errors.New("gitlab: the review has no branch to find a merge request for")
+	}
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return "", unsent(fmt.Errorf("gitlab: %w", err), review)
+	}
+	c := gitlabClient{repo: repo, token: cred.Token}
+
+	mr, err := c.findMergeRequest(review.hostBranch(), review.PullRequest)
+	if err != nil {
+		return "", unsent(fmt.Errorf("gitlab: %w", err), review)
+	}
+	// Positions are in the merge request's diff, which rejects lines it
+	// doesn't show.
+	diff, oldPaths, err := c.mergeRequestDiff(mr.IID)
+	if err != nil {
+		return "", unsent(fmt.Errorf("gitlab: getting the diff of MR !%d: %w", mr.IID, err), review)
+	}
+	comments, err := placeComments(review.Comments, review.Diffs, diff, fmt.Sprintf("MR !%d", mr.IID))
+	if err != nil {
+		return "", fmt.Errorf("gitlab: %w", err)
+	}
+	byPath := make(map[string]*git.FileDiff, len(diff))
+	for i := range diff {
+		byPath[diff[i].Path] = &diff[i]
+	}
+
+	for i, cm := range comments {
+		var d gitlabDiscussion
+		u := c.mergeRequestURL(mr.IID) + "/discussions"
+		if cm.Thread == "" {
+			pos := toGitLabPosition(mr, cm, cmp.Or(oldPaths[cm.FilePath], cm.FilePath), byPath[cm.FilePath])
+			d.Position = &pos
+		}
+		switch {
+		case review.Draft:
+			d.Note, d.InReplyTo = cm.Body, cm.Thread
+			u = c.mergeRequestURL(mr.IID) + "/draft_notes"
+		case cm.Thread != "":
+			d.Body = cm.Body
+			u += "/" + url.PathEscape(cm.Thread) + "/notes"
+		default:
+			d.Body = cm.Body
+		}
+		if err := c.call(http.MethodPost, u, d, nil); err != nil {
+			err = fmt.Errorf("gitlab: posted %d of %d comments to MR !%d: %w", i, len(review.Comments), mr.IID, err)
+			return "", unsent(err, review.remaining(comments[i:]))
+		}
+	}
+	if review.Draft {
+		return fmt.Sprintf("Saved %d draft comments on GitLab MR !%d", len(review.Comments), mr.IID), nil
+	}
+	if err := c.setVerdict(mr.IID, review.Verdict); err != nil {
+		err = fmt.Errorf("gitlab: posted %d comments to MR !%d, but recording the verdict failed: %w", len(review.Comments), mr.IID, err)
+		return "", unsent(err, review.remaining(nil))
+	}
+	return fmt.Sprintf("Posted %d comments to GitLab MR !%d%s", len(review.Comments), mr.IID, verdictNote(review.Verdict)), nil
+}
+
+// toGitLabPosition returns where c, a comment on oldPath as it is at
+// c.FilePath in fd, the merge request's diff of it, goes in mr's diff.
+func toGitLabPosition(mr gitlabMergeRequest, c comment.Comment, oldPath string, fd *git.FileDiff) gitlabPosition {
+	pos := gitlabPosition{
+		PositionType: "file",
+		BaseSHA:      mr.DiffRefs.BaseSHA,
+		StartSHA:     mr.DiffRefs.StartSHA,
+		HeadSHA:      mr.DiffRefs.HeadSHA,
+		OldPath:      oldPath,
+		NewPath:      c.FilePath,
+	}
+	if c.StartLine == 0 || fd == nil {
+		return pos
+	}
+	removed := c.LineType == git.LineRemoved
+	end := gitlabLineAt(fd, removed, c.EndLine)
+	pos.PositionType = "text"
+	pos.OldLine, pos.NewLine = end.OldLine, end.NewLine
+	if c.EndLine > c.StartLine {
+		pos.LineRange = &gitlabLineRange{Start: gitlabLineAt(fd, removed, c.StartLine), End: end}
+	}
+	return pos
+}
+
+// gitlabLineAt returns the line fd shows at n, on the old side if removed and
+// otherwise the new. GitLab identifies a line by its line code: a hash of the
+// file's path and the line's old and new numbers, where a line only on one
+// side takes the number the next line on the other would have.
+func gitlabLineAt(fd *git.FileDiff, removed bool, n int) gitlabLine {
+	for _, h := range fd.Hunks {
+		oldNo, newNo := h.OldStart, h.NewStart
+		for _, l := range h.Lines {
+			at := newNo == n && l.Type != git.LineRemoved
+			if removed {
+				at = oldNo == n && l.Type != git.LineAdded
+			}
+			if at {
+				gl := gitlabLine{
+					LineCode: fmt.Sprintf("%x_%d_%d", sha1.Sum([]byte(fd.Path)), oldNo, newNo),
+					OldLine:  oldNo,
+					NewLine:  newNo,
+				}
+				switch l.Type {
+				case git.LineAdded:
+					gl.Type, gl.OldLine = "new", 0
+				case git.LineRemoved:
+					gl.Type, gl.NewLine = "old", 0
+				}
+				return gl
+			}
+			if l.Type != git.LineAdded {
+				oldNo++
+			}
+			if l.Type != git.LineRemoved {
+				newNo++
+			}
+		}
+	}
+	return gitlabLine{}
+}
+
+// fetchGitLabThreads returns the comments in the discussions on merge request
+// iid, or if it is 0, the open merge request from branch, leaving out system notes and discussions that
+// aren't on a file.
+func fetchGitLabThreads(repo *GitLab, branch string, iid int) ([]comment.Comment, error) {
+	if branch == "" && iid == 0 {
+		return nil, errors.New("gitlab: the review has no branch to find a merge request for")
+	}
+	cred, err := repo.Lookup().Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: %w", err)
+	}
+	c := gitlabClient{repo: repo, token: cred.Token}
+	mr, err := c.findMergeRequest(branch, iid)
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: %w", err)
+	}
+
+	type gitlabNote struct {
+		Body   string `json:"body"`
+		System bool   `json:"system"`
+		Author struct {
+			Username string `json:"username"`
+		} `json:"author"`
+		Position *struct {
+			PositionType string `json:"position_type"`
+			NewPath      string `json:"new_path"`
+			OldLine      int    `json:"old_line"`
+			NewLine      int    `json:"new_line"`
+		} `json:"position"`
+	}
+	var comments []comment.Comment
+	for page := 1; ; page++ {
+		var batch []struct {
+			ID    string       `json:"id"`
+			Notes []gitlabNote `json:"notes"`
+		}
+		u := fmt.Sprintf("%s/discussions?per_page=%d&page=%d", c.mergeRequestURL(mr.IID), gitlabPageSize, page)
+		if err := c.call(http.MethodGet, u, nil, &batch); err != nil {
+			return nil, fmt.Errorf("gitlab: getting the discussions on MR !%d: %w", mr.IID, err)
+		}
+		for _, d := range batch {
+			if len(d.Notes) == 0 || d.Notes[0].System || d.Notes[0].Position == nil {
+				continue
+			}
+			pos := d.Notes[0].Position
+			line, removed := pos.NewLine, false
+			if pos.PositionType == "file" {
+				line = 0
+			} else if line == 0 {
+				line, removed = pos.OldLine, true
+			}
+			for _, n := range d.Notes {
+				if !n.System {
+					comments = append(comments, threadComment(d.ID, pos.NewPath, line, removed, n.Author.Username, n.Body))
+				}
+			}
+		}
+		if len(batch) < gitlabPageSize {
+			break
+		}
+	}
+	return comments, nil
+}
+
+// gitlabClient calls the GitLab REST API for repo.
+type gitlabClient struct {
+	repo  *GitLab
+	token string
+}
+
+// projectURL returns the URL of the project in the API.
+func (c gitlabClient) projectURL() string {
+	return fmt.Sprintf("%s/api/v4/projects/%s",
+		strings.TrimSuffix(cmp.Or(c.repo.URL, gitlabURL), "/"), url.PathEscape(c.repo.Project))
+}
+
+// mergeRequestURL returns the URL of merge request iid.
+func (c gitlabClient) mergeRequestURL(iid int) string {
+	return fmt.Sprintf("%s/merge_requests/%d", c.projectURL(), iid)
+}
+
+// findMergeRequest returns merge request iid, or if it is 0, the open merge
+// request from branch, with the diff version comments are positioned in.
+func (c gitlabClient) findMergeRequest(branch string, iid int) (gitlabMergeRequest, error) {
+	if iid != 0 {
+		var mr gitlabMergeRequest
+		if err := c.call(http.MethodGet, c.mergeRequestURL(iid), nil, &mr); err != nil {
+			return gitlabMergeRequest{}, fmt.Errorf("getting MR !%d: %w", iid, err)
+		}
+		return mr, nil
+	}
+	var mrs []gitlabMergeRequest
+	u := fmt.Sprintf("%s/merge_requests?state=opened&source_branch=%s", c.projectURL(), url.QueryEscape(branch))
+	if err := c.call(http.MethodGet, u, nil, &mrs); err != nil {
+		return gitlabMergeRequest{}, fmt.Errorf("finding the merge request for %s: %w", branch, err)
+	}
+	if len(mrs) == 0 {
+		return gitlabMergeRequest{}, fmt.Errorf("no open merge request from %s in %s", branch, c.repo.Project)
+	}
+	var mr gitlabMergeRequest
+	if err := c.call(http.MethodGet, c.mergeRequestURL(mrs[0].IID), nil, &mr); err != nil {
+		return gitlabMergeRequest{}, fmt.Errorf("getting MR !%d: %w", mrs[0].IID, err)
+	}
+	return mr, nil
+}
+
+// mergeRequestDiff returns the diff of merge request iid, and the old path of
+// each renamed file by its new one.
+func (c gitlabClient) mergeRequestDiff(iid int) ([]git.FileDiff, map[string]string, error) {
+	var raw strings.Builder
+	oldPaths := make(map[string]string)
+	for page := 1; ; page++ {
+		var batch []struct {
+			OldPath     string `json:"old_path"`
+			NewPath     string `json:"new_path"`
+			Diff        string `json:"diff"`
+			NewFile     bool   `json:"new_file"`
+			RenamedFile bool   `json:"renamed_file"`
+			DeletedFile bool   `json:"deleted_file"`
+		}
+		u := fmt.Sprintf("%s/diffs?per_page=%d&page=%d", c.mergeRequestURL(iid), gitlabPageSize, page)
+		if err := c.call(http.MethodGet, u, nil, &batch); err != nil {
+			return nil, nil, err
+		}
+		for _, f := range batch {
+			if f.RenamedFile {
+				oldPaths[f.NewPath] = f.OldPath
+			}
+			from, to := "a/"+f.OldPath, "b/"+f.NewPath
+			if f.NewFile {
+				from = "/dev/null"
+			}
+			if f.DeletedFile {
+				to = "/dev/null"
+			}
+			fmt.Fprintf(&raw, "diff --git a/%s b/%s\n--- %s\n+++ %s\n%s", f.OldPath, f.NewPath, from, to, f.Diff)
+			if !strings.HasSuffix(f.Diff, "\n") {
+				raw.WriteString("\n")
+			}
+		}
+		if len(batch) < gitlabPageSize {
+			break
+		}
+	}
+	diff, err := git.ParseDiff(raw.String())
+	return diff, oldPaths, err
+}
+
+// setVerdict approves merge request iid, or says changes are requested in a
+// note on it.
+func (c gitlabClient) setVerdict(iid int, verdict Verdict) error {
+	switch verdict {
+	case VerdictApprove:
+		return c.call(http.MethodPost, c.mergeRequestURL(iid)+"/approve", nil, nil)
+	case VerdictRequestChanges:
+		return c.call(http.MethodPost, c.mergeRequestURL(iid)+"/notes", map[string]string{"body": "Requesting changes."}, nil)
+	}
+	return nil
+}
+
+// call sends a request to the GitLab API, JSON-encoding payload if non-nil
+// and decoding the response into out if non-nil.
+func (c gitlabClient) call(method, u string, payload, out any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "revui")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return doJSON(req, out)
+}
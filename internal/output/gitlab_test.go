@@ -0,0 +1,258 @@
+package output
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestParseGitLabRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   GitLab
+		ok     bool
+	}{
+		{remote: "https://gitlab.com/acme/api.git", want: GitLab{Project: "acme/api"}, ok: true},
+		{remote: "git@gitlab.com:acme/platform/api.git", want: GitLab{Project: "acme/platform/api"}, ok: true},
+		{remote: "ssh://git@gitlab.acme.com:2222/platform/api.git", want: GitLab{URL: "https://gitlab.acme.com", Project: "platform/api"}, ok: true},
+		{remote: "https://github.com/acme/api.git"},
+		{remote: "https://gitlab.com/acme"},
+		{remote: "http://gitlab.com/acme/api"},
+		{remote: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remote, func(t *testing.T) {
+			got, ok := ParseGitLabRemote(tt.remote)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseGitLabRemote(%q) = %+v, %v; want %+v, %v", tt.remote, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGitLabTargets(t *testing.T) {
+	if got := GitLabTargets(nil, "git@github.com:acme/api.git"); got != nil {
+		t.Errorf("targets for a GitHub remote = %+v, want none", got)
+	}
+
+	targets := GitLabTargets(&GitLab{Token: "tok"}, "git@gitlab.acme.com:platform/api.git")
+	if len(targets) != 1 {
+		t.Fatalf("got %d targets, want 1", len(targets))
+	}
+	if targets[0].Label != "GitLab MR: platform/api" {
+		t.Errorf("label = %q", targets[0].Label)
+	}
+	want := GitLab{URL: "https://gitlab.acme.com", Project: "platform/api", Token: "tok"}
+	if *targets[0].GitLab != want {
+		t.Errorf("project = %+v, want %+v", *targets[0].GitLab, want)
+	}
+}
+
+// gitlabTestDiffs is githubTestDiff's file as GitLab's merge request diffs
+// API gives it.
+const gitlabTestDiffs = `[{"old_path": "a.go", "new_path": "a.go", "diff": "@@ -1,5 +1,6 @@\n package a\n-func old() {}\n+func one() {}\n+func two() {}\n \n var x = 1\n var y = 2\n"}]`
+
+// gitlabTestServer serves merge request !7 from branch feature in acme/api,
+// recording what is posted to each path.
+func gitlabTestServer(t *testing.T, posted map[string][]gitlabDiscussion) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Error("want a bearer token")
+		}
+		path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/projects/acme%2Fapi")
+		switch {
+		case path == "/merge_requests" && r.URL.Query().Get("source_branch") == "feature":
+			w.Write([]byte(`[{"iid": 7}]`))
+		case path == "/merge_requests":
+			w.Write([]byte(`[]`))
+		case path == "/merge_requests/7":
+			w.Write([]byte(`{"iid": 7, "diff_refs": {"base_sha": "base", "start_sha": "start", "head_sha": "head"}}`))
+		case path == "/merge_requests/7/diffs":
+			w.Write([]byte(gitlabTestDiffs))
+		case r.Method == http.MethodPost:
+			var d gitlabDiscussion
+			json.NewDecoder(r.Body).Decode(&d)
+			posted[path] = append(posted[path], d)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request to %s", path)
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestDeliverGitLab(t *testing.T) {
+	posted := make(map[string][]gitlabDiscussion)
+	srv := gitlabTestServer(t, posted)
+	defer srv.Close()
+
+	repo := &GitLab{URL: srv.URL, Project: "acme/api", Token: "tok"}
+	msg, err := Deliver(OutputTarget{Kind: TargetGitLab, GitLab: repo}, Review{Branch: "feature", Verdict: VerdictApprove, Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 2, EndLine: 3, LineType: git.LineAdded, Body: "split this"},
+		{FilePath: "a.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "keep this"},
+		{FilePath: "a.go", StartLine: 5, EndLine: 5, LineType: git.LineContext, Body: "why x?"},
+		{FilePath: "a.go", Body: "rename the file"},
+		{FilePath: "a.go", StartLine: 6, EndLine: 6, LineType: git.LineContext, Body: "fixed", Thread: "abc"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Posted 5 comments to GitLab MR !7 and approved it" {
+		t.Errorf("message = %q", msg)
+	}
+
+	code := func(old, new int) string {
+		return fmt.Sprintf("%x_%d_%d", sha1.Sum([]byte("a.go")), old, new)
+	}
+	pos := func(p gitlabPosition) *gitlabPosition {
+		p.BaseSHA, p.StartSHA, p.HeadSHA = "base", "start", "head"
+		p.OldPath, p.NewPath = "a.go", "a.go"
+		return &p
+	}
+	want := map[string][]gitlabDiscussion{
+		"/merge_requests/7/discussions": {
+			{Body: "split this", Position: pos(gitlabPosition{PositionType: "text", NewLine: 3, LineRange: &gitlabLineRange{
+				Start: gitlabLine{LineCode: code(3, 2), Type: "new", NewLine: 2},
+				End:   gitlabLine{LineCode: code(3, 3), Type: "new", NewLine: 3},
+			}})},
+			{Body: "keep this", Position: pos(gitlabPosition{PositionType: "text", OldLine: 2})},
+			{Body: "why x?", Position: pos(gitlabPosition{PositionType: "text", OldLine: 4, NewLine: 5})},
+			{Body: "rename the file", Position: pos(gitlabPosition{PositionType: "file"})},
+		},
+		"/merge_requests/7/discussions/abc/notes": {{Body: "fixed"}},
+		"/merge_requests/7/approve":               {{}},
+	}
+	if !reflect.DeepEqual(posted, want) {
+		t.Errorf("posted = %+v, want %+v", posted, want)
+	}
+}
+
+func TestDeliverGitLabDraft(t *testing.T) {
+	posted := make(map[string][]gitlabDiscussion)
+	srv := gitlabTestServer(t, posted)
+	defer srv.Close()
+
+	repo := &GitLab{URL: srv.URL, Project: "acme/api", Token: "tok"}
+	msg, err := deliverToGitLab(repo, Review{Branch: "feature", Draft: true, Verdict: VerdictApprove, Comments: []comment.Comment{
+		{FilePath: "a.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "nit"},
+		{FilePath: "a.go", StartLine: 6, EndLine: 6, LineType: git.LineContext, Body: "fixed", Thread: "abc"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Saved 2 draft comments on GitLab MR !7" {
+		t.Errorf("message = %q", msg)
+	}
+	drafts := posted["/merge_requests/7/draft_notes"]
+	if len(posted) != 1 || len(drafts) != 2 {
+		t.Fatalf("posted = %+v, want only two draft notes", posted)
+	}
+	if drafts[0].Note != "nit" || drafts[0].Position == nil || drafts[0].Position.OldLine != 2 {
+		t.Errorf("draft = %+v, want a note on old line 2", drafts[0])
+	}
+	if drafts[1].Note != "fixed" || drafts[1].InReplyTo != "abc" || drafts[1].Position != nil {
+		t.Errorf("draft = %+v, want a reply to abc", drafts[1])
+	}
+}
+
+func TestFetchGitLabThreads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects/acme/api/merge_requests":
+			w.Write([]byte(`[{"iid": 7}]`))
+		case "/api/v4/projects/acme/api/merge_requests/7":
+			w.Write([]byte(`{"iid": 7}`))
+		case "/api/v4/projects/acme/api/merge_requests/7/discussions":
+			w.Write([]byte(`[
+				{"id": "d1", "notes": [
+					{"body": "why?", "author": {"username": "ann"}, "position": {"position_type": "text", "new_path": "a.go", "new_line": 10}},
+					{"body": "because", "author": {"username": "cy"}, "position": {"position_type": "text", "new_path": "a.go", "new_line": 10}},
+					{"body": "resolved", "system": true, "author": {"username": "cy"}}
+				]},
+				{"id": "d2", "notes": [{"body": "added 1 commit", "system": true, "author": {"username": "bo"}}]},
+				{"id": "d3", "notes": [{"body": "overall fine", "author": {"username": "bo"}}]},
+				{"id": "d4", "notes": [{"body": "keep", "author": {"username": "bo"}, "position": {"position_type": "text", "new_path": "b.go", "old_line": 2}}]},
+				{"id": "d5", "notes": [{"body": "whole file", "author": {"username": "ann"}, "position": {"position_type": "file", "new_path": "d.go"}}]}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	repo := &GitLab{URL: srv.URL, Project: "acme/api", Token: "tok"}
+	got, err := FetchThreads(OutputTarget{Kind: TargetGitLab, GitLab: repo}, "feature", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []comment.Comment{
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "why?", Author: "ann", Thread: "d1"},
+		{FilePath: "a.go", StartLine: 10, EndLine: 10, LineType: git.LineAdded, Body: "because", Author: "cy", Thread: "d1"},
+		{FilePath: "b.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "keep", Author: "bo", Thread: "d4"},
+		{FilePath: "d.go", LineType: git.LineAdded, Body: "whole file", Author: "ann", Thread: "d5"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threads = %+v, want %+v", got, want)
+	}
+
+	// A known merge request is asked for by number, without a branch
+	got, err = FetchThreads(OutputTarget{Kind: TargetGitLab, GitLab: repo}, "", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threads of !7 = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeliverGitLabErrors(t *testing.T) {
+	posted := make(map[string][]gitlabDiscussion)
+	srv := gitlabTestServer(t, posted)
+	defer srv.Close()
+	t.Setenv("GITLAB_TOKEN", "")
+	t.Setenv("GLAB_CONFIG_DIR", t.TempDir())
+
+	tests := []struct {
+		name   string
+		branch string
+		repo   GitLab
+		want   string
+	}{
+		{name: "no branch", repo: GitLab{Token: "tok"}, want: "no branch"},
+		{name: "no token", branch: "feature", repo: GitLab{URL: "https://localhost.invalid"}, want: "GITLAB_TOKEN"},
+		{name: "no merge request", branch: "other", repo: GitLab{URL: srv.URL, Project: "acme/api", Token: "tok"}, want: "no open merge request from other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := deliverToGitLab(&tt.repo, Review{Branch: tt.branch})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitLabMergeRequestBranches(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/acme/api/merge_requests/7" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"iid": 7, "source_branch": "feature", "target_branch": "main"}`))
+	}))
+	defer srv.Close()
+
+	repo := &GitLab{URL: srv.URL, Project: "acme/api", Token: "tok"}
+	head, base, err := repo.MergeRequestBranches(7)
+	if err != nil || head != "feature" || base != "main" {
+		t.Errorf("MergeRequestBranches(7) = %q, %q, %v; want feature, main", head, base, err)
+	}
+}
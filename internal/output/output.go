@@ -1,14 +1,20 @@
 package output
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/aymanbagabas/go-osc52/v2"
+
+	"github.com/deparker/revui/internal/auth"
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
 )
 
 // TargetKind identifies the type of output destination.
@@ -19,14 +25,103 @@ const (
 	TargetTmuxBuffer
 	TargetClipboard
 	TargetFile
+	TargetWebhook
+	TargetSlack
+	TargetBitbucket
+	TargetGerrit
+	TargetAzureDevOps
+	TargetGitHub
+	TargetGitLab
 )
 
 // OutputTarget represents a destination for review output.
 type OutputTarget struct {
 	Kind         TargetKind
 	Label        string
-	TmuxTarget   string // pane identifier for tmux send-keys (Claude targets only)
-	ZellijTarget string // pane identifier for zellij actions (Claude targets only)
+	TmuxTarget   string       // pane identifier for tmux send-keys (Claude targets only)
+	ZellijTarget string       // pane identifier for zellij actions (Claude targets only)
+	Submit       bool         // press Enter after sending the review (Claude targets only)
+	Webhook      *Webhook     // endpoint configuration (webhook targets only)
+	Slack        *Slack       // destination configuration (Slack targets only)
+	Bitbucket    *Bitbucket   // repository configuration (Bitbucket targets only)
+	Gerrit       *Gerrit      // server configuration (Gerrit targets only)
+	AzureDevOps  *AzureDevOps // repository configuration (Azure DevOps targets only)
+	GitHub       *GitHub      // repository configuration (GitHub targets only)
+	GitLab       *GitLab      // project configuration (GitLab targets only)
+	Path         string       // destination file, or a name pattern as DefaultFilePath gives (file targets only); empty means a new file in TempDir
+}
+
+// Review is a finished review to deliver.
+type Review struct {
+	Branch   string            `json:"branch"`
+	Base     string            `json:"base,omitempty"`
+	Markdown string            `json:"markdown,omitempty"` // comments formatted by comment.Format
+	Comments []comment.Comment `json:"comments"`
+	// Diffs holds the diff each commented file's comments were written on,
+	// for placing them on a pull request's diff. A file may be missing.
+	Diffs map[string]*git.FileDiff `json:"diffs,omitempty"`
+	// Draft saves the comments on a hosting service as drafts for the
+	// reviewer to publish there, where the target supports them.
+	Draft   bool    `json:"draft,omitempty"`
+	Verdict Verdict `json:"verdict,omitempty"` // posted with the comments on a hosting service
+	// PullRequest is the number of the pull or merge request under review,
+	// when known, as with `revui pr`. Otherwise it is looked up by Branch.
+	PullRequest int `json:"pull_request,omitempty"`
+	// HostBranch is the name the hosting service knows the branch by, when
+	// Branch is another, as for a remote branch reviewed as origin/feature.
+	HostBranch string `json:"host_branch,omitempty"`
+}
+
+// hostBranch returns the name of the branch on the hosting service.
+func (r Review) hostBranch() string {
+	return cmp.Or(r.HostBranch, r.Branch)
+}
+
+// remaining returns the review with only comments left to send. They have
+// been placed on the pull request's diff already, so the local diffs are
+// dropped: sending them again only checks they're still there.
+func (r Review) remaining(comments []comment.Comment) Review {
+	r.Comments = comments
+	r.Diffs = nil
+	return r
+}
+
+// UnsentError is a delivery to a hosting service that failed in a way
+// sending again later may get past, such as a network or credential failure.
+// Unsent is what wasn't sent: the comments left, once some were posted, and
+// the verdict.
+type UnsentError struct {
+	Err    error
+	Unsent Review
+}
+
+func (e *UnsentError) Error() string { return e.Err.Error() }
+
+func (e *UnsentError) Unwrap() error { return e.Err }
+
+// unsent returns err as an UnsentError for review.
+func unsent(err error, review Review) error {
+	return &UnsentError{Err: err, Unsent: review}
+}
+
+// Verdict is a review's overall outcome, for hosting services that record one.
+type Verdict int
+
+const (
+	VerdictNone Verdict = iota // comments only
+	VerdictApprove
+	VerdictRequestChanges
+)
+
+// verdictNote describes v for delivery messages, e.g. " and approved it".
+func verdictNote(v Verdict) string {
+	switch v {
+	case VerdictApprove:
+		return " and approved it"
+	case VerdictRequestChanges:
+		return " and requested changes"
+	}
+	return ""
 }
 
 // parseTmuxPanes parses output from `tmux list-panes -a -F '#{session_name}:#{window_index}.#{pane_index} #{pane_current_command} #{pane_pid}'`.
@@ -98,9 +193,50 @@ func DetectTargets(tmuxEnv, tmuxPane string) []OutputTarget {
 	return targets
 }
 
-// Deliver sends the review content to the specified target.
-// Returns a human-readable status message on success.
-func Deliver(target OutputTarget, content string) (string, error) {
+// Lookup returns where the target's API credential is looked for, for targets
+// on a hosting service.
+func (t OutputTarget) Lookup() (auth.Lookup, bool) {
+	switch t.Kind {
+	case TargetBitbucket:
+		return t.Bitbucket.Lookup(), true
+	case TargetGerrit:
+		return t.Gerrit.Lookup(), true
+	case TargetAzureDevOps:
+		return t.AzureDevOps.Lookup(), true
+	case TargetGitHub:
+		return t.GitHub.Lookup(), true
+	case TargetGitLab:
+		return t.GitLab.Lookup(), true
+	}
+	return auth.Lookup{}, false
+}
+
+// Hosted reports whether the target posts comments to a hosting service, where
+// the review can carry a verdict.
+func (t OutputTarget) Hosted() bool {
+	_, ok := t.Lookup()
+	return ok
+}
+
+// Drafts reports whether the target can save the comments as drafts.
+func (t OutputTarget) Drafts() bool {
+	return t.Kind == TargetBitbucket || t.Kind == TargetGerrit || t.Kind == TargetGitHub || t.Kind == TargetGitLab
+}
+
+// Deliver sends the review to the specified target. Targets that take plain
+// text receive the markdown. Returns a human-readable status message on success,
+// which notes a dry run when SetDryRun kept the requests from being sent.
+func Deliver(target OutputTarget, review Review) (string, error) {
+	result, err := deliver(target, review)
+	if err == nil && dryRun != nil && sendsRequests(target.Kind) {
+		result = "Dry run: " + result
+	}
+	return result, err
+}
+
+// deliver is Deliver without the dry-run note.
+func deliver(target OutputTarget, review Review) (string, error) {
+	content := review.Markdown
 	switch target.Kind {
 	case TargetClaude:
 		return deliverToClaude(target, content)
@@ -109,35 +245,239 @@ func Deliver(target OutputTarget, content string) (string, error) {
 	case TargetClipboard:
 		return deliverToClipboard(content)
 	case TargetFile:
-		return deliverToFile(content)
+		return deliverToFile(target.Path, content)
+	case TargetWebhook:
+		return deliverToWebhook(target.Webhook, review)
+	case TargetSlack:
+		return deliverToSlack(target.Slack, review)
+	case TargetBitbucket:
+		return deliverToBitbucket(target.Bitbucket, review)
+	case TargetGerrit:
+		return deliverToGerrit(target.Gerrit, review)
+	case TargetAzureDevOps:
+		return deliverToAzureDevOps(target.AzureDevOps, review)
+	case TargetGitHub:
+		return deliverToGitHub(target.GitHub, review)
+	case TargetGitLab:
+		return deliverToGitLab(target.GitLab, review)
 	default:
 		return "", fmt.Errorf("unknown target kind: %v", target.Kind)
 	}
 }
 
-// reviewFilePath generates a timestamped file path for review output.
-func reviewFilePath() string {
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("revui-review-%d.md", timestamp)
-	return filepath.Join("/tmp", filename)
+// FetchThreads returns the comments already on the pull request or change
+// that target posts to, for the reviewed branch, so a reviewer can see what
+// others have said. Each has Thread set to its discussion's ID, for replying,
+// and the author's name; replies follow the comment they answer and share its
+// place. Comments on lines of the old file have LineType git.LineRemoved, and
+// others git.LineAdded. Comments not on a file are left out. A pullRequest
+// other than 0 is the number of the pull or merge request, found by branch
+// otherwise.
+func FetchThreads(target OutputTarget, branch string, pullRequest int) ([]comment.Comment, error) {
+	switch target.Kind {
+	case TargetBitbucket:
+		return fetchBitbucketThreads(target.Bitbucket, branch)
+	case TargetGerrit:
+		return fetchGerritThreads(target.Gerrit)
+	case TargetAzureDevOps:
+		return fetchAzureDevOpsThreads(target.AzureDevOps, branch)
+	case TargetGitHub:
+		return fetchGitHubThreads(target.GitHub, branch, pullRequest)
+	case TargetGitLab:
+		return fetchGitLabThreads(target.GitLab, branch, pullRequest)
+	}
+	return nil, fmt.Errorf("%s has no comments to fetch", target.Label)
 }
 
-// deliverToClaude writes content to a temp file and sends an @path reference to the Claude pane.
-func deliverToClaude(target OutputTarget, content string) (string, error) {
-	path := reviewFilePath()
+// threadComment returns a comment fetched from a hosting service, on line of
+// path's old file if removed is set and its new file otherwise.
+func threadComment(thread, path string, line int, removed bool, author, body string) comment.Comment {
+	c := comment.Comment{
+		FilePath:  path,
+		StartLine: line,
+		EndLine:   line,
+		LineType:  git.LineAdded,
+		Body:      body,
+		Author:    author,
+		Thread:    thread,
+	}
+	if removed {
+		c.LineType = git.LineRemoved
+	}
+	return c
+}
 
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+// groupThreads orders comments so each thread's are together, in the order
+// the threads start, keeping the order within each.
+func groupThreads(comments []comment.Comment) []comment.Comment {
+	first := make(map[string]int)
+	for i, c := range comments {
+		if _, ok := first[c.Thread]; !ok {
+			first[c.Thread] = i
+		}
+	}
+	slices.SortStableFunc(comments, func(a, b comment.Comment) int {
+		return cmp.Compare(first[a.Thread], first[b.Thread])
+	})
+	return comments
+}
+
+// tempDir is the directory review files are written to when they aren't
+// given a path, or empty for the system's temp directory.
+var tempDir string
+
+// SetTempDir sets the directory review files are written to when they aren't
+// given a path, such as a private one on a shared machine. Empty uses the
+// system's temp directory.
+func SetTempDir(dir string) {
+	tempDir = dir
+}
+
+// TempDir returns the directory review files are written to when they aren't
+// given a path.
+func TempDir() string {
+	return cmp.Or(tempDir, os.TempDir())
+}
+
+// createFile writes content to a new file in dir named after pattern, whose
+// last "*" is replaced so the name can't collide with or be swapped for
+// another file's, readable only by the user, and returns its path.
+func createFile(dir, pattern, content string) (string, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create review file: %w", err)
+	}
+	_, err = f.WriteString(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
 		return "", fmt.Errorf("failed to write review file: %w", err)
 	}
+	return f.Name(), nil
+}
+
+// reviewFilePattern names the review files written without a chosen name.
+const reviewFilePattern = "revui-review-*.md"
+
+// handoffFilePattern names the files reviews are handed to Claude in. They
+// are only read as the review is sent, and are cleaned up once old, along
+// with review files left in TempDir, where hand-offs used to be written under
+// reviewFilePattern too.
+const handoffFilePattern = "revui-claude-*.md"
+
+// DefaultFilePath returns the name pattern of a new review file in dir. A
+// file target given it creates a file whose name has the "*" replaced by a
+// unique string, rather than writing to a name that could be guessed.
+func DefaultFilePath(dir string) string {
+	return filepath.Join(dir, reviewFilePattern)
+}
+
+// OldHandoffFiles returns the files in TempDir reviews were handed to Claude
+// in, and the review files written there under the default name, last
+// written before cutoff, oldest first.
+func OldHandoffFiles(cutoff time.Time) ([]string, error) {
+	var paths []string
+	for _, pattern := range []string{handoffFilePattern, reviewFilePattern} {
+		matches, err := filepath.Glob(filepath.Join(TempDir(), pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	type file struct {
+		path    string
+		modTime time.Time
+	}
+	var old []file
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		old = append(old, file{path, info.ModTime()})
+	}
+	slices.SortFunc(old, func(a, b file) int { return a.modTime.Compare(b.modTime) })
+	files := make([]string, len(old))
+	for i, f := range old {
+		files[i] = f.path
+	}
+	return files, nil
+}
+
+// promptPlaceholder marks where a Claude prompt template takes the review.
+const promptPlaceholder = "{review}"
 
-	// Send @path reference to Claude pane (without pressing Enter)
+// claudePrompt is the template the review is wrapped in for Claude, or empty
+// to send the review alone.
+var claudePrompt string
+
+// claudeSubmit presses Enter after sending every review to Claude.
+var claudeSubmit bool
+
+// claudeSubmitDelay is how long Claude is given to take in the file reference
+// before Enter submits it.
+const claudeSubmitDelay = 300 * time.Millisecond
+
+// SetClaudeSubmit makes every review sent to Claude be submitted, as if
+// each target had Submit set.
+func SetClaudeSubmit(submit bool) {
+	claudeSubmit = submit
+}
+
+// SetClaudePrompt sets the instructions the review sent to Claude comes with,
+// such as "Address each comment, then reply with a plan". The review goes in
+// place of {review} in template, or after it when there's none.
+func SetClaudePrompt(template string) {
+	claudePrompt = template
+}
+
+// applyPrompt wraps review in template, as SetClaudePrompt describes.
+func applyPrompt(template, review string) string {
+	switch {
+	case template == "":
+		return review
+	case strings.Contains(template, promptPlaceholder):
+		return strings.ReplaceAll(template, promptPlaceholder, review)
+	}
+	return strings.TrimRight(template, "\n") + "\n\n" + review
+}
+
+// deliverToClaude writes content, in the prompt template if one is set, to a
+// temp file and sends an @path reference to the Claude pane, pressing Enter
+// after it when the target or SetClaudeSubmit says to. The pane is checked to
+// still be running claude first, as it may have gone back to a shell since it
+// was listed.
+func deliverToClaude(target OutputTarget, content string) (string, error) {
+	out, err := exec.Command("tmux", "display-message", "-p", "-t", target.TmuxTarget, "#{pane_current_command}").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find tmux pane %s: %w", target.TmuxTarget, err)
+	}
+	if running := strings.TrimSpace(string(out)); running != "claude" {
+		return "", fmt.Errorf("tmux pane %s is running %s, not claude", target.TmuxTarget, cmp.Or(running, "nothing"))
+	}
+
+	path, err := createFile(TempDir(), handoffFilePattern, applyPrompt(claudePrompt, content))
+	if err != nil {
+		return "", err
+	}
+
+	// Send @path reference to Claude pane
 	atRef := fmt.Sprintf("@%s ", path)
 	cmd := exec.Command("tmux", "send-keys", "-t", target.TmuxTarget, atRef)
 	if err := cmd.Run(); err != nil {
 		return "", fmt.Errorf("failed to send to tmux pane: %w", err)
 	}
+	if !target.Submit && !claudeSubmit {
+		return fmt.Sprintf("Review sent to Claude at %s (file: %s)", target.TmuxTarget, path), nil
+	}
 
-	return fmt.Sprintf("Review sent to Claude at %s (file: %s)", target.TmuxTarget, path), nil
+	time.Sleep(claudeSubmitDelay)
+	if err := exec.Command("tmux", "send-keys", "-t", target.TmuxTarget, "Enter").Run(); err != nil {
+		return "", fmt.Errorf("sent the review to tmux pane %s, but failed to submit it: %w", target.TmuxTarget, err)
+	}
+	return fmt.Sprintf("Review submitted to Claude at %s (file: %s)", target.TmuxTarget, path), nil
 }
 
 // deliverToTmuxBuffer loads content into the tmux paste buffer.
@@ -152,22 +492,47 @@ func deliverToTmuxBuffer(content string) (string, error) {
 	return "Review loaded into tmux paste buffer. Use prefix + ] to paste.", nil
 }
 
-// deliverToClipboard tries available clipboard utilities to copy content.
 // deliverToClipboard copies content to clipboard using OSC 52 escape sequences.
 func deliverToClipboard(content string) (string, error) {
-	seq := osc52.New(content)
-	_, err := fmt.Fprint(os.Stderr, seq)
-	if err != nil {
-		return "", fmt.Errorf("failed to write OSC 52 sequence: %w", err)
+	if err := CopyToClipboard(content); err != nil {
+		return "", err
 	}
 	return "Review copied to clipboard via OSC 52.", nil
 }
 
-// deliverToFile writes content to a timestamped file.
-func deliverToFile(content string) (string, error) {
-	path := reviewFilePath()
+// CopyToClipboard copies text to the terminal's clipboard with an OSC 52
+// escape sequence, which also works over SSH.
+func CopyToClipboard(text string) error {
+	if _, err := fmt.Fprint(os.Stderr, osc52.New(text)); err != nil {
+		return fmt.Errorf("failed to write OSC 52 sequence: %w", err)
+	}
+	return nil
+}
 
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+// deliverToFile writes content to path, to a new file named after path if its
+// name has a "*" in it, or to a new file in TempDir if path is empty. A
+// leading "~/" is expanded to the home directory. Reviews may quote code that
+// isn't public, so only the user can read the file.
+func deliverToFile(path, content string) (string, error) {
+	if path == "" {
+		path = DefaultFilePath(TempDir())
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+
+	if strings.Contains(filepath.Base(path), "*") {
+		created, err := createFile(filepath.Dir(path), filepath.Base(path), content)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Review written to %s", created), nil
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
 		return "", fmt.Errorf("failed to write review file: %w", err)
 	}
 
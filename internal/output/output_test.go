@@ -2,8 +2,11 @@ package output
 
 import (
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseTmuxPanes(t *testing.T) {
@@ -128,6 +131,12 @@ func TestTargetKindConstants(t *testing.T) {
 		TargetTmuxBuffer,
 		TargetClipboard,
 		TargetFile,
+		TargetWebhook,
+		TargetSlack,
+		TargetBitbucket,
+		TargetGerrit,
+		TargetAzureDevOps,
+		TargetGitHub,
 	}
 
 	seen := make(map[TargetKind]bool)
@@ -194,14 +203,35 @@ func TestDetectTargets(t *testing.T) {
 	}
 }
 
+func TestApplyPrompt(t *testing.T) {
+	tests := []struct {
+		name, template, want string
+	}{
+		{name: "none", want: "# Review"},
+		{name: "placeholder", template: "Address these:\n{review}\nThen reply with a plan.", want: "Address these:\n# Review\nThen reply with a plan."},
+		{name: "no placeholder", template: "Address each comment.\n", want: "Address each comment.\n\n# Review"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyPrompt(tt.template, "# Review"); got != tt.want {
+				t.Errorf("applyPrompt(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDeliverFile(t *testing.T) {
+	dir := t.TempDir()
+	SetTempDir(dir)
+	defer SetTempDir("")
+
 	content := "# Code Review\n\nTest content"
 	target := OutputTarget{
 		Kind:  TargetFile,
 		Label: "Write to file",
 	}
 
-	msg, err := Deliver(target, content)
+	msg, err := Deliver(target, Review{Markdown: content})
 	if err != nil {
 		t.Fatalf("Deliver failed: %v", err)
 	}
@@ -210,7 +240,7 @@ func TestDeliverFile(t *testing.T) {
 	if !strings.Contains(msg, "Review written to") {
 		t.Errorf("message %q does not contain expected prefix", msg)
 	}
-	if !strings.Contains(msg, "/tmp/revui-review-") {
+	if !strings.Contains(msg, filepath.Join(dir, "revui-review-")) {
 		t.Errorf("message %q does not contain expected path", msg)
 	}
 
@@ -231,11 +261,56 @@ func TestDeliverFile(t *testing.T) {
 	if string(gotContent) != content {
 		t.Errorf("file content = %q, want %q", string(gotContent), content)
 	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("file permissions = %o, want 600", perm)
+	}
 
 	// Clean up
 	os.Remove(filePath)
 }
 
+func TestDeliverFileChosenPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string // where the file should land
+		wantErr bool
+	}{
+		{name: "absolute path", path: filepath.Join(dir, "review.md"), want: filepath.Join(dir, "review.md")},
+		{name: "home relative", path: "~/notes.md", want: filepath.Join(home, "notes.md")},
+		{name: "missing directory", path: filepath.Join(dir, "nope", "review.md"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := OutputTarget{Kind: TargetFile, Label: "Write to file", Path: tt.path}
+			msg, err := Deliver(target, Review{Markdown: "# Review"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg != "Review written to "+tt.want {
+				t.Errorf("message = %q, want the written path %q", msg, tt.want)
+			}
+			if got, err := os.ReadFile(tt.want); err != nil || string(got) != "# Review" {
+				t.Errorf("file content = %q (err %v), want the review", got, err)
+			}
+		})
+	}
+}
+
 func TestDeliverClipboard(t *testing.T) {
 	content := "# Code Review\n\nTest content for clipboard"
 	target := OutputTarget{
@@ -243,7 +318,7 @@ func TestDeliverClipboard(t *testing.T) {
 		Label: "System clipboard",
 	}
 
-	msg, err := Deliver(target, content)
+	msg, err := Deliver(target, Review{Markdown: content})
 	if err != nil {
 		t.Fatalf("Deliver failed: %v", err)
 	}
@@ -261,7 +336,7 @@ func TestDeliverClipboardEmpty(t *testing.T) {
 		Label: "System clipboard",
 	}
 
-	msg, err := Deliver(target, content)
+	msg, err := Deliver(target, Review{Markdown: content})
 	if err != nil {
 		t.Fatalf("Deliver with empty content failed: %v", err)
 	}
@@ -279,7 +354,7 @@ func TestDeliverClipboardLarge(t *testing.T) {
 		Label: "System clipboard",
 	}
 
-	msg, err := Deliver(target, content)
+	msg, err := Deliver(target, Review{Markdown: content})
 	if err != nil {
 		t.Fatalf("Deliver with large content failed: %v", err)
 	}
@@ -288,3 +363,67 @@ func TestDeliverClipboardLarge(t *testing.T) {
 		t.Errorf("message %q does not mention OSC 52", msg)
 	}
 }
+
+func TestDeliverFilePattern(t *testing.T) {
+	dir := t.TempDir()
+	target := OutputTarget{Kind: TargetFile, Path: DefaultFilePath(dir)}
+
+	var paths []string
+	for range 2 {
+		msg, err := Deliver(target, Review{Markdown: "# Review"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := strings.TrimPrefix(msg, "Review written to ")
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.Mode().IsRegular() || info.Mode().Perm() != 0600 {
+			t.Errorf("%s has mode %v, want a regular file only the user can read", path, info.Mode())
+		}
+		if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), "revui-review-") || strings.Contains(path, "*") {
+			t.Errorf("wrote %s, want a unique review file in %s", path, dir)
+		}
+		paths = append(paths, path)
+	}
+	if paths[0] == paths[1] {
+		t.Errorf("both reviews went to %s", paths[0])
+	}
+}
+
+func TestOldHandoffFiles(t *testing.T) {
+	dir := t.TempDir()
+	SetTempDir(dir)
+	defer SetTempDir("")
+
+	now := time.Now()
+	files := []struct {
+		name string
+		age  time.Duration
+	}{
+		{"revui-claude-1.md", 10 * 24 * time.Hour},
+		{"revui-claude-2.md", 8 * 24 * time.Hour},
+		{"revui-claude-3.md", time.Hour},
+		{"revui-review-4.md", 30 * 24 * time.Hour},
+		{"notes.md", 30 * 24 * time.Hour},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := OldHandoffFiles(now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "revui-review-4.md"), filepath.Join(dir, "revui-claude-1.md"), filepath.Join(dir, "revui-claude-2.md")}
+	if !slices.Equal(got, want) {
+		t.Errorf("OldHandoffFiles = %v, want %v", got, want)
+	}
+}
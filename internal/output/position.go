@@ -0,0 +1,173 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// maxListedComments is how many unplaceable comments an error names.
+const maxListedComments = 5
+
+// placeComments checks each comment against remote, the diff of the pull
+// request it is being posted to, which may be against a different base than
+// the local diff it was written on. A comment on lines the pull request's diff
+// doesn't show is moved to where the same code is, when local has the diff
+// it was written on to say what that code is. When any comment can't be
+// placed, nothing should be posted: the error names them, for moving or
+// deleting before sending again.
+func placeComments(comments []comment.Comment, local map[string]*git.FileDiff, remote []git.FileDiff, where string) ([]comment.Comment, error) {
+	byPath := make(map[string]*git.FileDiff, len(remote))
+	for i := range remote {
+		byPath[remote[i].Path] = &remote[i]
+	}
+	placed := make([]comment.Comment, 0, len(comments))
+	var missing []string
+	for _, c := range comments {
+		p, ok := placeComment(c, local[c.FilePath], byPath[c.FilePath])
+		if !ok {
+			missing = append(missing, commentLocation(c))
+			continue
+		}
+		placed = append(placed, p)
+	}
+	if len(missing) == 0 {
+		return placed, nil
+	}
+	listed := strings.Join(missing[:min(len(missing), maxListedComments)], ", ")
+	if len(missing) > maxListedComments {
+		listed += fmt.Sprintf(", and %d more", len(missing)-maxListedComments)
+	}
+	return nil, fmt.Errorf("%d of %d comments aren't on lines of %s's diff, which may be against a different base: %s; move or delete them and send again",
+		len(missing), len(comments), where, listed)
+}
+
+// commentLocation names where c is, for messages.
+func commentLocation(c comment.Comment) string {
+	switch {
+	case c.StartLine == 0:
+		return c.FilePath
+	case c.EndLine > c.StartLine:
+		return fmt.Sprintf("%s:%d-%d", c.FilePath, c.StartLine, c.EndLine)
+	}
+	return fmt.Sprintf("%s:%d", c.FilePath, c.StartLine)
+}
+
+// placeComment returns c placed on remote, the pull request's diff of its
+// file, reporting false if it can't be. Replies go to their thread, wherever
+// it is. File comments only need the file to be in the diff. Line comments
+// stay on the same lines if those show the same code, and otherwise move to
+// the nearest lines that do.
+func placeComment(c comment.Comment, local, remote *git.FileDiff) (comment.Comment, bool) {
+	if c.Thread != "" {
+		return c, true
+	}
+	if remote == nil {
+		return c, false
+	}
+	if c.StartLine == 0 {
+		return c, true
+	}
+	removed := c.LineType == git.LineRemoved
+	n := c.EndLine - c.StartLine + 1
+	lines := sideLines(remote, removed)
+
+	// What the commented lines say, when the local diff shows them
+	var want []string
+	if local != nil {
+		localLines := sideLines(local, removed)
+		if i := findRun(localLines, c.StartLine, n, removed); i >= 0 {
+			for _, l := range localLines[i : i+n] {
+				want = append(want, l.Content)
+			}
+		}
+	}
+
+	best := -1
+	if want == nil {
+		best = findRun(lines, c.StartLine, n, removed)
+	} else {
+		bestDistance := 0
+		for i := range lines {
+			if i+n > len(lines) || !consecutive(lines[i:i+n], removed) {
+				continue
+			}
+			if !slices.EqualFunc(lines[i:i+n], want, func(l git.Line, s string) bool { return l.Content == s }) {
+				continue
+			}
+			if d := abs(lineNumber(lines[i], removed) - c.StartLine); best < 0 || d < bestDistance {
+				best, bestDistance = i, d
+			}
+		}
+	}
+	if best < 0 {
+		return c, false
+	}
+
+	first, last := lines[best], lines[best+n-1]
+	c.StartLine, c.EndLine = lineNumber(first, removed), lineNumber(last, removed)
+	c.LineType = last.Type
+	c.OldStartLine, c.OldEndLine = 0, 0
+	c.NearStartLine, c.NearEndLine, c.NearRemoved = 0, 0, false
+	if c.LineType == git.LineContext {
+		c.OldStartLine, c.OldEndLine = first.OldLineNo, last.OldLineNo
+		c.NearStartLine, c.NearEndLine, c.NearRemoved = remote.NearestChange(c.StartLine, c.EndLine)
+	}
+	return c, true
+}
+
+// sideLines returns the removed lines of fd, or else its added and context
+// lines: the lines a comment numbered on that side can be on.
+func sideLines(fd *git.FileDiff, removed bool) []git.Line {
+	var lines []git.Line
+	for _, h := range fd.Hunks {
+		for _, l := range h.Lines {
+			if (l.Type == git.LineRemoved) == removed {
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines
+}
+
+// lineNumber returns l's number on the side a comment is numbered by.
+func lineNumber(l git.Line, removed bool) int {
+	if removed {
+		return l.OldLineNo
+	}
+	return l.NewLineNo
+}
+
+// findRun returns the index in lines of the run of n consecutively numbered
+// lines starting at line start, or -1 if lines doesn't have them all.
+func findRun(lines []git.Line, start, n int, removed bool) int {
+	for i, l := range lines {
+		if lineNumber(l, removed) == start {
+			if i+n > len(lines) || !consecutive(lines[i:i+n], removed) {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// consecutive reports whether lines are numbered one after another.
+func consecutive(lines []git.Line, removed bool) bool {
+	for k := 1; k < len(lines); k++ {
+		if lineNumber(lines[k], removed) != lineNumber(lines[0], removed)+k {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,163 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+func mustParseDiff(t *testing.T, raw string) []git.FileDiff {
+	t.Helper()
+	diffs, err := git.ParseDiff(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diffs
+}
+
+// localDiff is the diff the comments were written on.
+const localDiff = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -3,4 +3,5 @@
+ func f() {
+-	return 1
++	x := 2
++	return x
+ }
+ 
+`
+
+func TestPlaceComment(t *testing.T) {
+	local := &mustParseDiff(t, localDiff)[0]
+	tests := []struct {
+		name   string
+		remote string
+		local  *git.FileDiff
+		c      comment.Comment
+		want   comment.Comment
+		ok     bool
+	}{
+		{
+			name:   "same lines",
+			remote: localDiff,
+			local:  local,
+			c:      comment.Comment{FilePath: "a.go", StartLine: 4, EndLine: 5, LineType: git.LineAdded},
+			want:   comment.Comment{FilePath: "a.go", StartLine: 4, EndLine: 5, LineType: git.LineAdded},
+			ok:     true,
+		},
+		{
+			name: "moved by an earlier change in the pull request's base",
+			remote: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -6,4 +6,5 @@
+ func f() {
+-	return 1
++	x := 2
++	return x
+ }
+ 
+`,
+			local: local,
+			c:     comment.Comment{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineAdded},
+			want:  comment.Comment{FilePath: "a.go", StartLine: 7, EndLine: 7, LineType: git.LineAdded},
+			ok:    true,
+		},
+		{
+			name: "removed line moved",
+			remote: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -13,2 +13,2 @@
+ func f() {
+-	return 1
++	return x
+`,
+			local: local,
+			c:     comment.Comment{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineRemoved},
+			want:  comment.Comment{FilePath: "a.go", StartLine: 14, EndLine: 14, LineType: git.LineRemoved},
+			ok:    true,
+		},
+		{
+			name: "already in the pull request's base, so context there",
+			remote: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -3,4 +3,4 @@
+ func f() {
+-	y := 2
++	x := 2
+ 	return x
+ }
+`,
+			local: local,
+			c:     comment.Comment{FilePath: "a.go", StartLine: 5, EndLine: 5, LineType: git.LineAdded},
+			want:  comment.Comment{FilePath: "a.go", StartLine: 5, EndLine: 5, LineType: git.LineContext, OldStartLine: 5, OldEndLine: 5, NearStartLine: 4, NearEndLine: 4},
+			ok:    true,
+		},
+		{
+			name: "different code",
+			remote: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -3,2 +3,2 @@
+ func f() {
+-	return 1
++	return 3
+`,
+			local: local,
+			c:     comment.Comment{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineAdded},
+		},
+		{
+			name:   "unknown code placed by number",
+			remote: localDiff,
+			c:      comment.Comment{FilePath: "a.go", StartLine: 3, EndLine: 4, LineType: git.LineContext, OldStartLine: 3, OldEndLine: 3},
+			want:   comment.Comment{FilePath: "a.go", StartLine: 3, EndLine: 4, LineType: git.LineAdded},
+			ok:     true,
+		},
+		{
+			name:   "range past the hunk",
+			remote: localDiff,
+			c:      comment.Comment{FilePath: "a.go", StartLine: 6, EndLine: 8, LineType: git.LineContext},
+		},
+		{
+			name:   "file comment",
+			remote: localDiff,
+			c:      comment.Comment{FilePath: "a.go"},
+			want:   comment.Comment{FilePath: "a.go"},
+			ok:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &mustParseDiff(t, tt.remote)[0]
+			got, ok := placeComment(tt.c, tt.local, remote)
+			if ok != tt.ok || (ok && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("placeComment() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPlaceComments(t *testing.T) {
+	remote := mustParseDiff(t, localDiff)
+	comments := []comment.Comment{
+		{FilePath: "a.go", StartLine: 4, EndLine: 4, LineType: git.LineAdded, Body: "ok"},
+		{FilePath: "a.go", StartLine: 20, EndLine: 22, LineType: git.LineAdded, Body: "gone"},
+		{FilePath: "b.go", Body: "not in the pull request"},
+	}
+	if _, err := placeComments(comments, nil, remote, "PR #3"); err == nil ||
+		!strings.Contains(err.Error(), "2 of 3 comments aren't on lines of PR #3's diff") ||
+		!strings.Contains(err.Error(), "a.go:20-22, b.go;") {
+		t.Errorf("error = %v", err)
+	}
+
+	placed, err := placeComments(comments[:1], nil, remote, "PR #3")
+	if err != nil || !reflect.DeepEqual(placed, comments[:1]) {
+		t.Errorf("placeComments() = %+v, %v", placed, err)
+	}
+}
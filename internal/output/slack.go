@@ -0,0 +1,192 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// slackAPI is the Slack Web API base URL, replaced in tests.
+var slackAPI = "https://slack.com/api/"
+
+// slackMaxText is the longest review posted inline. Longer reviews are
+// replaced by a summary, with the full review attached as a file when a bot
+// token allows uploads.
+const slackMaxText = 3000
+
+// Slack is a configured Slack destination. Either WebhookURL (an incoming
+// webhook) or Token and Channel (a bot token with chat:write and files:write)
+// must be set.
+type Slack struct {
+	Name       string `json:"name"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+	Token      string `json:"token,omitempty"`
+	Channel    string `json:"channel,omitempty"` // channel ID, for bot tokens
+}
+
+// SlackTargets returns an output target for each configured Slack destination.
+func SlackTargets(destinations []Slack) []OutputTarget {
+	targets := make([]OutputTarget, len(destinations))
+	for i := range destinations {
+		name := destinations[i].Name
+		if name == "" {
+			name = destinations[i].Channel
+		}
+		if name == "" {
+			name = "incoming webhook"
+		}
+		targets[i] = OutputTarget{
+			Kind:  TargetSlack,
+			Label: "Slack: " + name,
+			Slack: &destinations[i],
+		}
+	}
+	return targets
+}
+
+// slackSummary describes the review in a few lines, for reviews too long to
+// post inline.
+func slackSummary(review Review) string {
+	perFile := make(map[string]int)
+	for _, c := range review.Comments {
+		perFile[c.FilePath]++
+	}
+	files := make([]string, 0, len(perFile))
+	for f := range perFile {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	var b strings.Builder
+	b.WriteString("*Code review*")
+	if review.Branch != "" {
+		fmt.Fprintf(&b, " of `%s`", review.Branch)
+	}
+	fmt.Fprintf(&b, ": %d comments on %d files\n", len(review.Comments), len(files))
+	for _, f := range files {
+		fmt.Fprintf(&b, "• `%s` (%d)\n", f, perFile[f])
+	}
+	return b.String()
+}
+
+// deliverToSlack posts the review to Slack.
+func deliverToSlack(dest *Slack, review Review) (string, error) {
+	long := len(review.Markdown) > slackMaxText
+	text := review.Markdown
+	if long {
+		text = slackSummary(review)
+	}
+
+	switch {
+	case dest.Token != "" && dest.Channel != "":
+		if long {
+			if err := slackUpload(dest, text, review.Markdown); err != nil {
+				return "", fmt.Errorf("slack: %w", err)
+			}
+			return fmt.Sprintf("Review summary and file posted to Slack channel %s", dest.Channel), nil
+		}
+		if err := slackCall(dest.Token, "chat.postMessage", map[string]any{"channel": dest.Channel, "text": text}, nil); err != nil {
+			return "", fmt.Errorf("slack: %w", err)
+		}
+		return fmt.Sprintf("Review posted to Slack channel %s", dest.Channel), nil
+
+	case dest.WebhookURL != "":
+		if long {
+			text += "_Full review too long for Slack; use another output target for the complete text._"
+		}
+		body, _ := json.Marshal(map[string]string{"text": text})
+		req, err := http.NewRequest(http.MethodPost, dest.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			return "", fmt.Errorf("slack: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if err := doRequest(req); err != nil {
+			return "", fmt.Errorf("slack: %w", err)
+		}
+		return "Review posted to Slack", nil
+
+	default:
+		return "", errors.New("slack: set webhook_url, or token and channel")
+	}
+}
+
+// slackUpload attaches the full review as a file, with the summary as its
+// message, using Slack's external upload flow.
+func slackUpload(dest *Slack, summary, content string) error {
+	var upload struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	form := url.Values{"filename": {"review.md"}, "length": {strconv.Itoa(len(content))}}
+	if err := slackCall(dest.Token, "files.getUploadURLExternal?"+form.Encode(), nil, &upload); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, upload.UploadURL, strings.NewReader(content))
+	if err != nil {
+		return err
+	}
+	if err := doRequest(req); err != nil {
+		return fmt.Errorf("uploading review: %w", err)
+	}
+
+	return slackCall(dest.Token, "files.completeUploadExternal", map[string]any{
+		"files":           []map[string]string{{"id": upload.FileID, "title": "Code review"}},
+		"channel_id":      dest.Channel,
+		"initial_comment": summary,
+	}, nil)
+}
+
+// slackCall invokes a Slack Web API method. A nil payload sends a GET. Slack
+// reports failures in the body, so "ok": false is turned into an error. The
+// rest of the response is decoded into out if non-nil.
+func slackCall(token, method string, payload any, out any) error {
+	httpMethod, body := http.MethodGet, []byte(nil)
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+		httpMethod = http.MethodPost
+	}
+	req, err := http.NewRequest(httpMethod, slackAPI+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return err
+	}
+	if !status.OK {
+		name, _, _ := strings.Cut(method, "?")
+		return fmt.Errorf("%s: %s", name, status.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(raw, out)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+)
+
+func TestDeliverSlackWebhook(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	target := SlackTargets([]Slack{{WebhookURL: srv.URL}})[0]
+	if target.Label != "Slack: incoming webhook" {
+		t.Errorf("label = %q", target.Label)
+	}
+	if _, err := Deliver(target, Review{Markdown: "## Code Review Comments\nnit"}); err != nil {
+		t.Fatal(err)
+	}
+	if got["text"] != "## Code Review Comments\nnit" {
+		t.Errorf("short reviews should be posted inline, got %q", got["text"])
+	}
+
+	long := Review{
+		Branch:   "feature",
+		Markdown: strings.Repeat("x", slackMaxText+1),
+		Comments: []comment.Comment{{FilePath: "a.go"}, {FilePath: "a.go"}, {FilePath: "b.go"}},
+	}
+	if _, err := Deliver(target, long); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got["text"], "3 comments on 2 files") || !strings.Contains(got["text"], "`a.go` (2)") {
+		t.Errorf("long reviews should be summarized, got %q", got["text"])
+	}
+}
+
+func TestDeliverSlackBot(t *testing.T) {
+	var calls []string
+	var completed map[string]any
+	var uploaded string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" && r.URL.Path != "/upload" {
+			t.Errorf("%s: missing bot token", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/chat.postMessage":
+			w.Write([]byte(`{"ok": true}`))
+		case "/files.getUploadURLExternal":
+			if r.URL.Query().Get("filename") != "review.md" {
+				t.Errorf("unexpected upload query %q", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"ok": true, "upload_url": srv.URL + "/upload", "file_id": "F1"})
+		case "/upload":
+			body, _ := io.ReadAll(r.Body)
+			uploaded = string(body)
+		case "/files.completeUploadExternal":
+			json.NewDecoder(r.Body).Decode(&completed)
+			w.Write([]byte(`{"ok": true}`))
+		default:
+			w.Write([]byte(`{"ok": false, "error": "unknown_method"}`))
+		}
+	}))
+	defer srv.Close()
+
+	old := slackAPI
+	slackAPI = srv.URL + "/"
+	defer func() { slackAPI = old }()
+
+	target := SlackTargets([]Slack{{Token: "xoxb-test", Channel: "C123"}})[0]
+
+	if _, err := Deliver(target, Review{Markdown: "short"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0] != "/chat.postMessage" {
+		t.Errorf("short review should use chat.postMessage, calls = %v", calls)
+	}
+
+	calls = nil
+	long := strings.Repeat("y", slackMaxText+1)
+	if _, err := Deliver(target, Review{Markdown: long}); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 3 || uploaded != long {
+		t.Errorf("long review should be uploaded as a file, calls = %v", calls)
+	}
+	if completed["channel_id"] != "C123" || !strings.Contains(completed["initial_comment"].(string), "Code review") {
+		t.Errorf("upload should be shared to the channel with a summary: %v", completed)
+	}
+
+	bad := SlackTargets([]Slack{{Token: "xoxb-test", Channel: "C123"}})[0]
+	slackAPI = srv.URL + "/nope/"
+	if _, err := Deliver(bad, Review{Markdown: "short"}); err == nil || !strings.Contains(err.Error(), "unknown_method") {
+		t.Errorf("Slack errors should be reported, got %v", err)
+	}
+}
+
+func TestDeliverSlackUnconfigured(t *testing.T) {
+	target := SlackTargets([]Slack{{Name: "empty"}})[0]
+	if _, err := Deliver(target, Review{Markdown: "x"}); err == nil {
+		t.Error("expected an error for a Slack target with no webhook or token")
+	}
+}
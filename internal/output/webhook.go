@@ -0,0 +1,109 @@
+package output
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/deparker/revui/internal/comment"
+)
+
+// signatureHeader carries the HMAC-SHA256 of the request body when a webhook
+// has a secret, in the form "sha256=<hex>".
+const signatureHeader = "X-Revui-Signature"
+
+// httpTimeout bounds every delivery request so a dead endpoint can't hang the TUI.
+const httpTimeout = 15 * time.Second
+
+// Webhook is a configured HTTP endpoint that receives finished reviews.
+type Webhook struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Secret  string            `json:"secret,omitempty"` // signs the body with HMAC-SHA256
+	Format  string            `json:"format,omitempty"` // "json" (default) or "markdown"
+}
+
+// webhookPayload is the JSON body POSTed to webhooks.
+type webhookPayload struct {
+	Branch   string            `json:"branch,omitempty"`
+	Base     string            `json:"base,omitempty"`
+	Markdown string            `json:"markdown"`
+	Comments []comment.Comment `json:"comments"`
+}
+
+// WebhookTargets returns an output target for each configured webhook.
+func WebhookTargets(hooks []Webhook) []OutputTarget {
+	targets := make([]OutputTarget, len(hooks))
+	for i := range hooks {
+		name := hooks[i].Name
+		if name == "" {
+			name = hooks[i].URL
+		}
+		targets[i] = OutputTarget{
+			Kind:    TargetWebhook,
+			Label:   "Webhook: " + name,
+			Webhook: &hooks[i],
+		}
+	}
+	return targets
+}
+
+// deliverToWebhook POSTs the review to the webhook.
+func deliverToWebhook(hook *Webhook, review Review) (string, error) {
+	var body []byte
+	contentType := "application/json"
+	switch hook.Format {
+	case "", "json":
+		var err error
+		body, err = json.Marshal(webhookPayload{
+			Branch:   review.Branch,
+			Base:     review.Base,
+			Markdown: review.Markdown,
+			Comments: review.Comments,
+		})
+		if err != nil {
+			return "", fmt.Errorf("encoding review: %w", err)
+		}
+	case "markdown":
+		body = []byte(review.Markdown)
+		contentType = "text/markdown; charset=utf-8"
+	default:
+		return "", fmt.Errorf("webhook %s: unknown format %q (want json or markdown)", hook.Name, hook.Format)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("webhook %s: %w", hook.Name, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "revui")
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+	if hook.Secret != "" {
+		req.Header.Set(signatureHeader, "sha256="+sign(hook.Secret, body))
+	}
+
+	if err := doRequest(req); err != nil {
+		return "", fmt.Errorf("webhook %s: %w", hook.Name, err)
+	}
+	return fmt.Sprintf("Review posted to %s", hook.URL), nil
+}
+
+// sign returns the hex HMAC-SHA256 of body keyed with secret.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// doRequest sends req like doJSON, ignoring a successful response's body.
+func doRequest(req *http.Request) error {
+	return doJSON(req, nil)
+}
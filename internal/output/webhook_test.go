@@ -0,0 +1,127 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestDeliverWebhook(t *testing.T) {
+	review := Review{
+		Branch:   "feature",
+		Base:     "main",
+		Markdown: "## Code Review Comments\n",
+		Comments: []comment.Comment{{FilePath: "a.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "nit"}},
+	}
+
+	tests := []struct {
+		name        string
+		hook        Webhook
+		status      int
+		wantType    string
+		wantSigned  bool
+		wantErr     string
+		checkBodyFn func(t *testing.T, body []byte)
+	}{
+		{
+			name:     "json",
+			hook:     Webhook{Name: "bot", Headers: map[string]string{"Authorization": "Bearer x"}},
+			status:   http.StatusOK,
+			wantType: "application/json",
+			checkBodyFn: func(t *testing.T, body []byte) {
+				var p webhookPayload
+				if err := json.Unmarshal(body, &p); err != nil {
+					t.Fatal(err)
+				}
+				if p.Branch != "feature" || len(p.Comments) != 1 || p.Comments[0].Body != "nit" {
+					t.Errorf("unexpected payload: %+v", p)
+				}
+			},
+		},
+		{
+			name:       "markdown signed",
+			hook:       Webhook{Name: "bridge", Format: "markdown", Secret: "s3cret"},
+			status:     http.StatusAccepted,
+			wantType:   "text/markdown; charset=utf-8",
+			wantSigned: true,
+			checkBodyFn: func(t *testing.T, body []byte) {
+				if string(body) != review.Markdown {
+					t.Errorf("body = %q, want the markdown", body)
+				}
+			},
+		},
+		{
+			name:     "server error",
+			hook:     Webhook{Name: "down"},
+			status:   http.StatusInternalServerError,
+			wantType: "application/json",
+			wantErr:  "500",
+		},
+		{
+			name:    "bad format",
+			hook:    Webhook{Name: "odd", Format: "xml"},
+			wantErr: "unknown format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if got := r.Header.Get("Content-Type"); got != tt.wantType {
+					t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+				}
+				for k, v := range tt.hook.Headers {
+					if r.Header.Get(k) != v {
+						t.Errorf("header %s = %q, want %q", k, r.Header.Get(k), v)
+					}
+				}
+				sig := r.Header.Get(signatureHeader)
+				if tt.wantSigned && sig != "sha256="+sign(tt.hook.Secret, body) {
+					t.Errorf("signature %q does not match body", sig)
+				}
+				if !tt.wantSigned && sig != "" {
+					t.Error("unsigned webhook should not send a signature")
+				}
+				if tt.checkBodyFn != nil {
+					tt.checkBodyFn(t, body)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			hook := tt.hook
+			hook.URL = srv.URL
+			targets := WebhookTargets([]Webhook{hook})
+			msg, err := Deliver(targets[0], review)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(msg, srv.URL) {
+				t.Errorf("message %q should name the URL", msg)
+			}
+		})
+	}
+}
+
+func TestWebhookTargets(t *testing.T) {
+	targets := WebhookTargets([]Webhook{{Name: "bot", URL: "https://a"}, {URL: "https://b"}})
+	if len(targets) != 2 || targets[0].Label != "Webhook: bot" || targets[1].Label != "Webhook: https://b" {
+		t.Errorf("unexpected targets: %+v", targets)
+	}
+	if targets[0].Kind != TargetWebhook || targets[0].Webhook.URL != "https://a" {
+		t.Errorf("target should carry its webhook config: %+v", targets[0])
+	}
+}
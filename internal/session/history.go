@@ -0,0 +1,119 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/deparker/revui/internal/output"
+)
+
+// maxHistory is how many deliveries the history keeps; older ones are
+// forgotten as new ones are recorded.
+const maxHistory = 100
+
+// Delivery is a finished review sent to an output target, kept with the
+// review so it can be read or sent again.
+type Delivery struct {
+	Version int           `json:"version"`          // format version the delivery was saved at; see Version
+	Target  string        `json:"target"`           // label of the output target
+	File    string        `json:"file,omitempty"`   // file written, for file targets
+	Bytes   int           `json:"bytes"`            // size of the review's markdown
+	Result  string        `json:"result,omitempty"` // what the target reported, once delivered
+	Error   string        `json:"error,omitempty"`  // why delivery failed; empty when it succeeded
+	At      time.Time     `json:"at"`
+	Review  output.Review `json:"review"`
+
+	path string // file the delivery is saved in
+}
+
+// HistoryDir returns the directory holding the deliveries made from the
+// repository whose .git directory is gitDir.
+func HistoryDir(gitDir string) string {
+	return filepath.Join(Dir(gitDir), "history")
+}
+
+// Path returns the file d was loaded from or saved to.
+func (d *Delivery) Path() string {
+	return d.path
+}
+
+// MarkdownPath returns the file beside d's holding its review's markdown, for
+// reading it in an editor or pager.
+func (d *Delivery) MarkdownPath() string {
+	return strings.TrimSuffix(d.path, ".json") + ".md"
+}
+
+// Failed reports whether the delivery failed.
+func (d *Delivery) Failed() bool {
+	return d.Error != ""
+}
+
+// SaveDelivery records d in dir, with its review's markdown beside it, then
+// forgets the oldest deliveries past the most the history keeps.
+func SaveDelivery(dir string, d *Delivery) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating history dir: %w", err)
+	}
+	if d.path == "" {
+		d.path = filepath.Join(dir, fmt.Sprintf("%d.json", d.At.UnixNano()))
+	}
+	d.Version = Version
+	d.Bytes = len(d.Review.Markdown)
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding delivery: %w", err)
+	}
+	if err := writeAtomic(d.MarkdownPath(), []byte(d.Review.Markdown)); err != nil {
+		return fmt.Errorf("writing delivered review: %w", err)
+	}
+	if err := writeAtomic(d.path, data); err != nil {
+		return fmt.Errorf("writing delivery: %w", err)
+	}
+
+	history, err := LoadHistory(dir)
+	if err != nil {
+		return err
+	}
+	for _, old := range history[min(len(history), maxHistory):] {
+		if err := errors.Join(Remove(old.path), Remove(old.MarkdownPath())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadHistory reads the deliveries recorded in dir, most recent first. A
+// missing dir has none.
+func LoadHistory(dir string) ([]*Delivery, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []*Delivery
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		d := &Delivery{path: path}
+		if err := decode(data, d); err != nil {
+			return nil, fmt.Errorf("parsing delivery %s: %w", path, err)
+		}
+		history = append(history, d)
+	}
+	slices.SortFunc(history, func(a, b *Delivery) int { return b.At.Compare(a.At) })
+	return history, nil
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/deparker/revui/internal/output"
+)
+
+func TestHistoryRoundTrip(t *testing.T) {
+	dir := HistoryDir(t.TempDir())
+	if history, err := LoadHistory(dir); err != nil || history != nil {
+		t.Fatalf("LoadHistory of a missing dir = %v, %v; want none", history, err)
+	}
+
+	now := time.Now()
+	sent := &Delivery{Target: "System clipboard", Result: "copied", At: now, Review: output.Review{Branch: "feature", Markdown: "# Review\n"}}
+	failed := &Delivery{Target: "go:0.0  claude", Error: "pane gone", At: now.Add(time.Minute), Review: output.Review{Branch: "feature", Markdown: "# Again\n"}}
+	for _, d := range []*Delivery{sent, failed} {
+		if err := SaveDelivery(dir, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := LoadHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Target != failed.Target || !history[0].Failed() || history[1].Failed() {
+		t.Fatalf("history = %+v, want the two deliveries most recent first", history)
+	}
+	if history[1].Bytes != len("# Review\n") || history[1].Review.Branch != "feature" {
+		t.Errorf("delivery = %+v", history[1])
+	}
+	md, err := os.ReadFile(history[1].MarkdownPath())
+	if err != nil || string(md) != "# Review\n" {
+		t.Errorf("markdown = %q, %v; want the review", md, err)
+	}
+}
+
+func TestHistoryForgetsOldest(t *testing.T) {
+	dir := HistoryDir(t.TempDir())
+	start := time.Now()
+	for i := range maxHistory + 2 {
+		d := &Delivery{Target: "System clipboard", At: start.Add(time.Duration(i) * time.Second)}
+		if err := SaveDelivery(dir, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, err := LoadHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != maxHistory || !history[len(history)-1].At.Equal(start.Add(2*time.Second)) {
+		t.Errorf("kept %d deliveries, oldest at %v; want %d from the third on", len(history), history[len(history)-1].At, maxHistory)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2*maxHistory {
+		t.Errorf("%d files in the history dir, want %d", len(entries), 2*maxHistory)
+	}
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Lock marks a session as being edited by one revui process, so a second
+// instance reviewing the same branch doesn't overwrite its autosaves.
+type Lock struct {
+	path string
+}
+
+// lockInfo is the content of a lock file.
+type lockInfo struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Since    time.Time `json:"since"`
+}
+
+// LockedError reports that another process holds a session's lock.
+type LockedError struct {
+	PID      int
+	Hostname string
+	Since    time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("another revui (pid %d on %s) has been editing this review since %s",
+		e.PID, e.Hostname, e.Since.Format("15:04"))
+}
+
+// LockPath returns the lock file path for the session at path.
+func LockPath(path string) string {
+	return strings.TrimSuffix(path, ".json") + ".lock"
+}
+
+// Acquire locks the session at path. It returns a *LockedError if another
+// live process holds the lock. A lock left behind by a process that has
+// exited on this host is taken over.
+func Acquire(path string) (*Lock, error) {
+	lockPath := LockPath(path)
+	holder, err := take(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	if holder != nil {
+		return nil, &LockedError{PID: holder.PID, Hostname: holder.Hostname, Since: holder.Since}
+	}
+	return &Lock{path: lockPath}, nil
+}
+
+// errContended reports that a stale lock was taken over by another process
+// before this one could.
+var errContended = errors.New("session lock is contended")
+
+// take creates the lock file at lockPath for this process. It returns the
+// holder if another live process has it.
+func take(lockPath string) (*lockInfo, error) {
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, fmt.Errorf("creating session dir: %w", err)
+	}
+	hostname, _ := os.Hostname()
+	data, err := json.Marshal(lockInfo{PID: os.Getpid(), Hostname: hostname, Since: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	// The lock is written in full under a temporary name and linked into
+	// place, so that another process never reads it empty and takes it for
+	// stale.
+	tmp, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("creating session lock: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("writing session lock: %w", err)
+	}
+
+	// Two attempts: the second follows removing a stale lock.
+	for range 2 {
+		err := os.Link(tmp.Name(), lockPath)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("creating session lock: %w", err)
+		}
+
+		holder, err := readLock(lockPath)
+		if err != nil {
+			return nil, err
+		}
+		if holder != nil && (holder.Hostname != hostname || processAlive(holder.PID)) {
+			return holder, nil
+		}
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("removing stale session lock: %w", err)
+		}
+	}
+	return nil, errContended
+}
+
+// updateTimeout is how long Update waits for another process's update of
+// the same session to finish.
+const updateTimeout = 5 * time.Second
+
+// Update loads the session at path, passes it to fn, and saves what fn
+// returns, while any other process's Update of the same session waits. fn
+// gets nil when no session has been saved, and returns nil to leave the file
+// as it is. This lock is separate from Acquire's, which is held for a whole
+// review: the review and an MCP server both update the session it locks.
+func Update(path string, fn func(*Session) (*Session, error)) error {
+	lockPath := strings.TrimSuffix(path, ".json") + ".update.lock"
+	deadline := time.Now().Add(updateTimeout)
+	for {
+		holder, err := take(lockPath)
+		if err == nil && holder == nil {
+			break
+		}
+		if err != nil && !errors.Is(err, errContended) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			if holder == nil {
+				return err
+			}
+			return &LockedError{PID: holder.PID, Hostname: holder.Hostname, Since: holder.Since}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer os.Remove(lockPath)
+
+	s, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	s, err = fn(s)
+	if err != nil || s == nil {
+		return err
+	}
+	return Save(path, s)
+}
+
+// Release removes the lock.
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readLock returns the lock's holder, or nil if the lock is gone or
+// unreadable, which makes it stale.
+func readLock(path string) (*lockInfo, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading session lock: %w", err)
+	}
+	var info lockInfo
+	if json.Unmarshal(data, &info) != nil || info.PID <= 0 {
+		return nil, nil
+	}
+	return &info, nil
+}
+
+// processAlive reports whether a process with the given PID exists. Errors
+// other than "no such process", such as a permission error, count as alive.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || !(errors.Is(err, os.ErrProcessDone) || errors.Is(err, syscall.ESRCH))
+}
@@ -0,0 +1,177 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deparker/revui/internal/comment"
+)
+
+func TestAcquire(t *testing.T) {
+	path := Path(t.TempDir(), "feature")
+
+	lock, err := Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second acquire while this process holds the lock fails
+	_, err = Acquire(path)
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("second Acquire() error = %v, want a *LockedError", err)
+	}
+	if locked.PID != os.Getpid() {
+		t.Errorf("holder pid = %d, want %d", locked.PID, os.Getpid())
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	lock, err = Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire() after release: %v", err)
+	}
+	lock.Release()
+}
+
+func TestAcquireConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := Path(dir, "feature")
+
+	// A lock caught half written must not be taken for stale: only one of
+	// the racing acquires gets it.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	held := 0
+	for range 20 {
+		wg.Go(func() {
+			if _, err := Acquire(path); err == nil {
+				mu.Lock()
+				held++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	if held != 1 {
+		t.Errorf("%d acquires got the lock, want 1", held)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(LockPath(path))); len(entries) != 1 {
+		t.Errorf("lock dir has %d files, want only the lock", len(entries))
+	}
+}
+
+func TestAcquireStaleLock(t *testing.T) {
+	// A process that has exited leaves a stale lock behind
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("cannot start a process:", err)
+	}
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "exited process", content: mustJSON(t, lockInfo{PID: cmd.Process.Pid, Hostname: hostname, Since: time.Now()})},
+		{name: "corrupt lock", content: "{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := Path(t.TempDir(), "feature")
+			if _, err := Acquire(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(LockPath(path), []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			lock, err := Acquire(path)
+			if err != nil {
+				t.Fatalf("Acquire() over a stale lock: %v", err)
+			}
+			lock.Release()
+		})
+	}
+}
+
+func TestAcquireOtherHost(t *testing.T) {
+	path := Path(t.TempDir(), "feature")
+	if _, err := Acquire(path); err != nil {
+		t.Fatal(err)
+	}
+	// The PID can't be checked on another host, so the lock is respected
+	content := mustJSON(t, lockInfo{PID: 1 << 22, Hostname: "elsewhere", Since: time.Now()})
+	if err := os.WriteFile(LockPath(path), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var locked *LockedError
+	if _, err := Acquire(path); !errors.As(err, &locked) {
+		t.Errorf("Acquire() error = %v, want a *LockedError", err)
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := Path(dir, "feature")
+
+	// Concurrent updates each add a comment; none is lost to another's save
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			err := Update(path, func(s *Session) (*Session, error) {
+				if s == nil {
+					s = &Session{Branch: "feature"}
+				}
+				s.Comments = append(s.Comments, comment.Comment{FilePath: "a.go", StartLine: i + 1, EndLine: i + 1, Body: "x"})
+				return s, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Comments) != 20 {
+		t.Errorf("saved %d comments, want 20", len(s.Comments))
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("session dir has %d files, want only the session", len(entries))
+	}
+}
+
+func TestUpdateNothing(t *testing.T) {
+	path := Path(t.TempDir(), "feature")
+	err := Update(path, func(s *Session) (*Session, error) {
+		if s != nil {
+			t.Errorf("got session %+v, want nil before one is saved", s)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() error = %v, want no session saved", err)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/deparker/revui/internal/output"
+)
+
+// Pending is a review a hosting service didn't take, saved with what was left
+// to send, so it can be sent again later without redoing the review or the
+// placing of its comments on the pull request's diff.
+type Pending struct {
+	Version  int           `json:"version"`             // format version the review was saved at; see Version
+	Target   string        `json:"target"`              // label of the configured output target
+	ChangeID string        `json:"change_id,omitempty"` // the Gerrit change, which HEAD may have moved from since
+	Review   output.Review `json:"review"`
+	Error    string        `json:"error"`
+	FailedAt time.Time     `json:"failed_at"`
+
+	path string // file the review is saved in
+}
+
+// OutboxDir returns the directory holding pending reviews for the repository
+// whose .git directory is gitDir.
+func OutboxDir(gitDir string) string {
+	return filepath.Join(Dir(gitDir), "outbox")
+}
+
+// Path returns the file p was loaded from or last saved to.
+func (p *Pending) Path() string {
+	return p.path
+}
+
+// SavePending writes p to its file in dir, starting a new one named for when
+// it failed if p hasn't been saved before.
+func SavePending(dir string, p *Pending) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating outbox dir: %w", err)
+	}
+	if p.path == "" {
+		p.path = filepath.Join(dir, fmt.Sprintf("%d.json", p.FailedAt.UnixNano()))
+	}
+	p.Version = Version
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding pending review: %w", err)
+	}
+	if err := writeAtomic(p.path, data); err != nil {
+		return fmt.Errorf("writing pending review: %w", err)
+	}
+	return nil
+}
+
+// LoadPending reads the pending reviews in dir, oldest first. A missing dir
+// has none.
+func LoadPending(dir string) ([]*Pending, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Pending
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		p := &Pending{path: path}
+		if err := decode(data, p); err != nil {
+			return nil, fmt.Errorf("parsing pending review %s: %w", path, err)
+		}
+		pending = append(pending, p)
+	}
+	slices.SortFunc(pending, func(a, b *Pending) int { return a.FailedAt.Compare(b.FailedAt) })
+	return pending, nil
+}
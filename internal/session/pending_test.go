@@ -0,0 +1,63 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/output"
+)
+
+func TestPendingRoundTrip(t *testing.T) {
+	dir := OutboxDir(t.TempDir())
+	if pending, err := LoadPending(dir); err != nil || pending != nil {
+		t.Fatalf("LoadPending of a missing outbox = %v, %v; want none", pending, err)
+	}
+
+	now := time.Now()
+	later := &Pending{Target: "Gerrit: review", ChangeID: "Iabc", FailedAt: now.Add(time.Minute), Error: "503"}
+	earlier := &Pending{
+		Target: "Bitbucket PR: team/app",
+		Review: output.Review{
+			Branch:   "feature",
+			Comments: []comment.Comment{{FilePath: "a.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "nit"}},
+			Verdict:  output.VerdictApprove,
+		},
+		Error:    "connection refused",
+		FailedAt: now,
+	}
+	for _, p := range []*Pending{later, earlier} {
+		if err := SavePending(dir, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := LoadPending(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].Target != earlier.Target || pending[1].ChangeID != "Iabc" {
+		t.Fatalf("pending = %+v, want the two reviews oldest first", pending)
+	}
+	if !reflect.DeepEqual(pending[0].Review, earlier.Review) {
+		t.Errorf("review = %+v, want %+v", pending[0].Review, earlier.Review)
+	}
+
+	// Saving again replaces the file it came from.
+	pending[0].Review.Comments = nil
+	if err := SavePending(dir, pending[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(pending[1].Path()); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = LoadPending(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Review.Comments != nil || pending[0].Review.Verdict != output.VerdictApprove {
+		t.Errorf("pending = %+v, want the updated review alone", pending)
+	}
+}
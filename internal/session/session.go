@@ -0,0 +1,137 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/deparker/revui/internal/comment"
+)
+
+// Session is the persisted state of a review for one branch.
+type Session struct {
+	// Version is the format version the session was saved at; see Version.
+	Version     int    `json:"version"`
+	Branch      string `json:"branch"`
+	Base        string `json:"base,omitempty"`
+	Uncommitted bool   `json:"uncommitted,omitempty"`
+	// Head is the commit reviewed in branch mode, for showing what changed
+	// when the review is resumed.
+	Head     string            `json:"head,omitempty"`
+	Comments []comment.Comment `json:"comments"`
+	// File and Line are where the reviewer was when they quit, to resume
+	// there: the open file and the line number under the cursor.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Marks are the lines the reviewer marked to jump back to, by letter.
+	Marks map[string]Mark `json:"marks,omitempty"`
+	// Verdicts holds the reviewer's call on whole files.
+	Verdicts map[string]comment.FileVerdict `json:"verdicts,omitempty"`
+	// Delivered is set when the comments were sent somewhere, and cleared
+	// when they change.
+	Delivered bool      `json:"delivered,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Mark is a marked line: a file's path and a line number in it.
+type Mark struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// Dir returns the directory holding revui state for the repository whose
+// .git directory is gitDir.
+func Dir(gitDir string) string {
+	return filepath.Join(gitDir, "revui")
+}
+
+// Path returns the session file path for the given branch.
+func Path(gitDir, branch string) string {
+	return filepath.Join(Dir(gitDir), "sessions", url.PathEscape(branch)+".json")
+}
+
+// List returns the sessions saved for the repository whose .git directory is
+// gitDir, most recently updated first.
+func List(gitDir string) ([]*Session, error) {
+	paths, err := filepath.Glob(filepath.Join(Dir(gitDir), "sessions", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*Session, 0, len(paths))
+	for _, path := range paths {
+		s, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	slices.SortFunc(sessions, func(a, b *Session) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+	return sessions, nil
+}
+
+// Load reads a session from path, migrating one saved by an earlier revui
+// to the current Version. The returned error wraps os.ErrNotExist when no
+// session has been saved.
+func Load(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := decode(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing session %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes the session to path, creating parent directories as needed.
+// The file is replaced atomically so a crash never leaves a partial session.
+func Save(path string, s *Session) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating session dir: %w", err)
+	}
+	s.Version = Version
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding session: %w", err)
+	}
+	if err := writeAtomic(path, data); err != nil {
+		return fmt.Errorf("writing session: %w", err)
+	}
+	return nil
+}
+
+// writeAtomic replaces the file at path with data, by way of a temporary file
+// so a crash never leaves it partly written. The temporary file's name is
+// unique, so two processes writing at once never mix their data.
+func writeAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Remove deletes the session at path. A missing session is not an error.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	gitDir := t.TempDir()
+	path := Path(gitDir, "feature/auth")
+
+	want := &Session{
+		Branch: "feature/auth",
+		Base:   "main",
+		Comments: []comment.Comment{
+			{FilePath: "a.go", StartLine: 3, EndLine: 5, LineType: git.LineRemoved, Body: "why?"},
+		},
+		Verdicts: map[string]comment.FileVerdict{"a.go": comment.FileNeedsWork, "b.go": comment.FileLGTM},
+		Marks:    map[string]Mark{"a": {Path: "a.go", Line: 12}},
+	}
+	if err := Save(path, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Branch != want.Branch || got.Base != want.Base {
+		t.Errorf("got branch/base %q/%q, want %q/%q", got.Branch, got.Base, want.Branch, want.Base)
+	}
+	if len(got.Comments) != 1 || got.Comments[0] != want.Comments[0] {
+		t.Errorf("comments = %+v, want %+v", got.Comments, want.Comments)
+	}
+	if !reflect.DeepEqual(got.Marks, want.Marks) {
+		t.Errorf("marks = %v, want %v", got.Marks, want.Marks)
+	}
+	if !reflect.DeepEqual(got.Verdicts, want.Verdicts) {
+		t.Errorf("verdicts = %v, want %v", got.Verdicts, want.Verdicts)
+	}
+	if got.UpdatedAt.IsZero() {
+		t.Error("UpdatedAt should be set on save")
+	}
+}
+
+func TestPathEscapesBranch(t *testing.T) {
+	path := Path("/repo/.git", "feature/auth")
+	if filepath.Dir(path) != filepath.Join("/repo/.git", "revui", "sessions") {
+		t.Errorf("branch slashes should not create subdirectories: %s", path)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "nope.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	path := Path(t.TempDir(), "main")
+	if err := Save(path, &Session{Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("removing a missing session should not fail: %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	gitDir := t.TempDir()
+	if sessions, err := List(gitDir); err != nil || len(sessions) != 0 {
+		t.Fatalf("List with no sessions = %v, %v", sessions, err)
+	}
+	for _, s := range []*Session{{Branch: "old"}, {Branch: "feature/auth", Delivered: true}} {
+		if err := Save(Path(gitDir, s.Branch), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A lock beside a session isn't one.
+	if _, err := Acquire(Path(gitDir, "old")); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := List(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].Branch != "feature/auth" || !sessions[0].Delivered || sessions[1].Branch != "old" {
+		t.Errorf("sessions = %+v, want feature/auth then old", sessions)
+	}
+}
+
+func TestLoadVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"unversioned", `{"branch":"main","comments":[{"FilePath":"a.go","Body":"hm"}]}`, false},
+		{"current", `{"version":1,"branch":"main","comments":[{"FilePath":"a.go","Body":"hm"}]}`, false},
+		{"later with unknown fields", `{"version":7,"branch":"main","anchors":{"x":1},"comments":[{"FilePath":"a.go","Body":"hm","Severity":"nit"}]}`, false},
+		{"negative", `{"version":-1,"branch":"main"}`, true},
+		{"malformed version", `{"version":"one","branch":"main"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "s.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			s, err := Load(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Branch != "main" || len(s.Comments) != 1 || s.Comments[0].Body != "hm" {
+				t.Errorf("got %+v", s)
+			}
+			if s.Version < Version {
+				t.Errorf("Version = %d, want at least %d", s.Version, Version)
+			}
+		})
+	}
+}
+
+func TestSaveWritesVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.json")
+	if err := Save(path, &Session{Branch: "main", Version: 0}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("saved session has no version:\n%s", data)
+	}
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version is the format version of the sessions, exports, pending reviews,
+// and deliveries this revui writes. It goes up when a change to their fields
+// needs files saved by an earlier revui to be rewritten as they're read, by
+// adding a step to migrations.
+const Version = 1
+
+// migrations[v] upgrades the fields of a file saved at version v to version
+// v+1. Version 0 is a file saved before versions were recorded, which has the
+// same fields as version 1.
+var migrations = []func(fields map[string]json.RawMessage) error{
+	0: func(map[string]json.RawMessage) error { return nil },
+}
+
+// decode unmarshals data saved at any version into v, a *Session, *Pending,
+// or *Delivery, migrating it to Version first. A file from a later revui is
+// decoded as well as this one can: fields it doesn't know are ignored.
+func decode(data []byte, v any) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var version int
+	if raw, ok := fields["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return fmt.Errorf("version: %w", err)
+		}
+	}
+	if version < 0 {
+		return fmt.Errorf("invalid version %d", version)
+	}
+	if version < Version {
+		for ; version < Version; version++ {
+			if err := migrations[version](fields); err != nil {
+				return fmt.Errorf("migrating from version %d: %w", version, err)
+			}
+		}
+		fields["version"] = json.RawMessage(fmt.Sprint(Version))
+		migrated, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		data = migrated
+	}
+	return json.Unmarshal(data, v)
+}
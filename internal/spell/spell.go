@@ -0,0 +1,274 @@
+// Package spell checks the spelling of review comments against a word list.
+package spell
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SystemWordLists are tried, in order, when no word list is configured.
+var SystemWordLists = []string{
+	"/usr/share/dict/words",
+	"/usr/share/hunspell/en_US.dic",
+	"/usr/share/myspell/en_US.dic",
+	"/Library/Spelling/en_US.dic",
+}
+
+// Dictionary is a set of correctly spelled words.
+type Dictionary struct {
+	words map[string]bool
+}
+
+// New returns a dictionary of words.
+func New(words ...string) *Dictionary {
+	d := &Dictionary{words: make(map[string]bool, len(words))}
+	for _, w := range words {
+		d.words[w] = true
+	}
+	return d
+}
+
+// Load reads a word list of one word per line, such as /usr/share/dict/words,
+// or a hunspell .dic file, whose first line is a count and whose words may
+// end in "/" and affix flags. The flags are ignored, so only the plain forms
+// of such a file's words, and the common suffixes Correct allows, are known.
+func Load(path string) (*Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d := New()
+	scanner := bufio.NewScanner(f)
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if first && line != "" && strings.Trim(line, "0123456789") == "" {
+			first = false
+			continue
+		}
+		first = false
+		word, _, _ := strings.Cut(line, "/")
+		if word != "" && !strings.HasPrefix(word, "#") {
+			d.words[word] = true
+		}
+	}
+	return d, scanner.Err()
+}
+
+// LoadSystem loads the first of SystemWordLists that exists.
+func LoadSystem() (*Dictionary, error) {
+	for _, path := range SystemWordLists {
+		d, err := Load(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return d, err
+	}
+	return nil, errors.New("no word list found in " + strings.Join(SystemWordLists, ", "))
+}
+
+// suffixes are endings whose stems Correct also accepts, since word lists
+// with affix flags only list the stems.
+var suffixes = []string{"'s", "s", "es", "ed", "d", "ing", "ly", "er", "ers"}
+
+// Correct reports whether word is in the dictionary as written, in lower
+// case for a capitalized word, or as one of its stems with a common suffix.
+func (d *Dictionary) Correct(word string) bool {
+	if d.known(word) {
+		return true
+	}
+	lower := strings.ToLower(word)
+	for _, s := range suffixes {
+		if stem, ok := strings.CutSuffix(lower, s); ok && len(stem) > 1 && d.known(stem) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dictionary) known(word string) bool {
+	if d.words[word] {
+		return true
+	}
+	// "The" at the start of a sentence, or "Paris" written as listed
+	r, size := utf8.DecodeRuneInString(word)
+	return unicode.IsUpper(r) && d.words[string(unicode.ToLower(r))+word[size:]]
+}
+
+// Span is a range of runes in a text, from Start up to End.
+type Span struct {
+	Start, End int
+}
+
+// Misspelled returns the spans of the words in text that aren't spelled
+// correctly. Anything that looks like code is skipped rather than flagged:
+// text in backticks, and words that have digits, underscores, or capitals
+// after the first letter, or that are part of a path, URL, or mention.
+func (d *Dictionary) Misspelled(text string) []Span {
+	runes := []rune(text)
+	var spans []Span
+	inCode := false
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if r == '`' {
+			inCode = !inCode
+			i++
+			continue
+		}
+		if unicode.IsSpace(r) || inCode {
+			i++
+			continue
+		}
+		// A whitespace-separated field, cut short at a backtick
+		end := i
+		for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '`' {
+			end++
+		}
+		if !codeLike(string(runes[i:end])) {
+			spans = append(spans, d.checkField(runes, i, end)...)
+		}
+		i = end
+	}
+	return spans
+}
+
+// codeLike reports whether field looks like code, a path, or a reference
+// rather than prose.
+func codeLike(field string) bool {
+	if strings.ContainsAny(field, "_/\\@#<>=()[]{}*$|") || strings.Contains(field, "://") {
+		return true
+	}
+	// A dot inside a word, as in main.go or fmt.Println
+	trimmed := strings.TrimRight(field, ".,;:!?\"')")
+	return strings.Contains(trimmed, ".")
+}
+
+// checkField returns the spans of misspelled words in runes[start:end],
+// where words are runs of letters with apostrophes inside them.
+func (d *Dictionary) checkField(runes []rune, start, end int) []Span {
+	var spans []Span
+	for i := start; i < end; {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < end && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) ||
+			runes[j] == '\'' && j+1 < end && unicode.IsLetter(runes[j+1])) {
+			j++
+		}
+		if word := string(runes[i:j]); checkable(word) && !d.Correct(word) {
+			spans = append(spans, Span{i, j})
+		}
+		i = j
+	}
+	return spans
+}
+
+// checkable reports whether word is prose worth checking: longer than a
+// letter, without digits, and not an acronym or camelCase identifier.
+func checkable(word string) bool {
+	if utf8.RuneCountInString(word) < 2 {
+		return false
+	}
+	for i, r := range []rune(word) {
+		if unicode.IsDigit(r) || i > 0 && unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// edited is a word made by an edit of the given kind to another.
+type edited struct {
+	word string
+	kind int
+}
+
+// Edit kinds, in the order their suggestions are offered: swapped letters
+// are the likeliest typo, an extra letter the least.
+const (
+	transposed = iota
+	replaced
+	omitted
+	extra
+)
+
+// Suggest returns up to n correctly spelled words that word may have been
+// meant as, one edit away from it, or else two. Swapped letters are offered
+// first, then a wrong letter, a missing one, and an extra one. Suggestions
+// for a capitalized word are capitalized.
+func (d *Dictionary) Suggest(word string, n int) []string {
+	lower := strings.ToLower(word)
+	var found []edited
+	seen := map[string]bool{lower: true}
+	add := func(w string, kind int) {
+		if !seen[w] && d.words[w] {
+			seen[w] = true
+			found = append(found, edited{w, kind})
+		}
+	}
+	first := edits(lower)
+	for _, e := range first {
+		add(e.word, e.kind)
+	}
+	if len(found) == 0 {
+		for _, e := range first {
+			for _, e2 := range edits(e.word) {
+				add(e2.word, max(e.kind, e2.kind))
+			}
+		}
+	}
+	slices.SortStableFunc(found, func(a, b edited) int {
+		if a.kind != b.kind {
+			return a.kind - b.kind
+		}
+		return strings.Compare(a.word, b.word)
+	})
+	r, _ := utf8.DecodeRuneInString(word)
+	var out []string
+	for _, c := range found[:min(len(found), n)] {
+		w := c.word
+		if unicode.IsUpper(r) {
+			first, size := utf8.DecodeRuneInString(w)
+			w = string(unicode.ToUpper(first)) + w[size:]
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// edits returns the words one edit away from word, using lower case letters.
+func edits(word string) []edited {
+	runes := []rune(word)
+	var out []edited
+	for i := 0; i+1 < len(runes); i++ {
+		s := slices.Clone(runes)
+		s[i], s[i+1] = s[i+1], s[i]
+		out = append(out, edited{string(s), transposed})
+	}
+	for i := range runes {
+		for c := 'a'; c <= 'z'; c++ {
+			if c != runes[i] {
+				s := slices.Clone(runes)
+				s[i] = c
+				out = append(out, edited{string(s), replaced})
+			}
+		}
+	}
+	for i := 0; i <= len(runes); i++ {
+		for c := 'a'; c <= 'z'; c++ {
+			out = append(out, edited{string(runes[:i]) + string(c) + string(runes[i:]), omitted})
+		}
+	}
+	for i := range runes {
+		out = append(out, edited{string(runes[:i]) + string(runes[i+1:]), extra})
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package spell
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content string
+		known         []string
+		unknown       []string
+	}{
+		{
+			name:    "word list",
+			content: "apple\nbanana\nParis\n",
+			known:   []string{"apple", "Apple", "bananas", "Paris"},
+			unknown: []string{"paris", "cherry"},
+		},
+		{
+			name:    "hunspell dic",
+			content: "3\nreview/SDG\ncomment/MS\n# not a word\nnit\n",
+			known:   []string{"review", "reviewed", "reviewing", "comments", "nit"},
+			unknown: []string{"3", "SDG", "#"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			d, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.known {
+				if !d.Correct(w) {
+					t.Errorf("Correct(%q) = false, want true", w)
+				}
+			}
+			for _, w := range tt.unknown {
+				if d.Correct(w) {
+					t.Errorf("Correct(%q) = true, want false", w)
+				}
+			}
+		})
+	}
+}
+
+func TestMisspelled(t *testing.T) {
+	d := New("this", "is", "the", "wrong", "name", "use", "here", "see", "it", "a", "or", "and", "don't")
+	tests := []struct {
+		text string
+		want []Span
+	}{
+		{"this is teh wrong name", []Span{{8, 11}}},
+		{"Teh name, here.", []Span{{0, 3}}},
+		{"don't use `fooBar teh` here", nil},
+		{"use parseArgs or MAX_LEN or HTTP here", nil},
+		{"see main.go and pkg/util, or https://example.com", nil},
+		{"@alice see #42", nil},
+		{"use v2 here", nil},
+		{"wrnog nmae", []Span{{0, 5}, {6, 10}}},
+	}
+	for _, tt := range tests {
+		if got := d.Misspelled(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Misspelled(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	d := New("the", "tea", "ten", "then", "receive", "review", "comment")
+	tests := []struct {
+		word string
+		n    int
+		want []string
+	}{
+		{"teh", 5, []string{"the", "tea", "ten"}},
+		{"teh", 1, []string{"the"}},
+		{"Teh", 2, []string{"The", "Tea"}},
+		{"recieve", 5, []string{"receive"}},
+		{"comnet", 5, []string{"comment"}},
+		{"xyzzy", 5, nil},
+	}
+	for _, tt := range tests {
+		if got := d.Suggest(tt.word, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q, %d) = %v, want %v", tt.word, tt.n, got, tt.want)
+		}
+	}
+}
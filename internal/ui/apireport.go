@@ -0,0 +1,232 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/deparker/revui/internal/apidiff"
+	"github.com/deparker/revui/internal/git"
+)
+
+// apiFinding is a change to the exported API of the Go package in dir.
+type apiFinding struct {
+	dir    string
+	change apidiff.Change
+}
+
+// path returns the path of the file the finding is in.
+func (f apiFinding) path() string {
+	return path.Join(f.dir, f.change.File)
+}
+
+// commentBody returns a review comment describing the finding, labeled as an
+// issue if it breaks the package's importers.
+func (f apiFinding) commentBody() string {
+	c := f.change
+	var what string
+	switch {
+	case c.Kind == apidiff.Removed:
+		what = fmt.Sprintf("`%s` is removed", c.Name)
+	case c.Kind == apidiff.Changed:
+		what = fmt.Sprintf("`%s` changes from `%s` to `%s`", c.Name, c.Old, c.New)
+	case c.Breaking:
+		what = fmt.Sprintf("`%s` is added to an interface, which other packages' types may no longer implement", c.Name)
+	default:
+		what = fmt.Sprintf("`%s` is added", c.Name)
+	}
+	if c.Breaking {
+		return "issue: breaking API change: " + what
+	}
+	return "API change: " + what
+}
+
+// apiReportMsg carries the findings of comparing the APIs of the changed Go
+// packages, and the packages that couldn't be compared.
+type apiReportMsg struct {
+	findings []apiFinding
+	errs     []string
+}
+
+// APIJumpMsg is sent when the user picks a finding to go to.
+type APIJumpMsg struct {
+	Finding apiFinding
+}
+
+// APICommentMsg is sent when the user turns findings into review comments.
+type APICommentMsg struct {
+	Findings []apiFinding
+}
+
+// APIReportCloseMsg is sent when the user closes the API report.
+type APIReportCloseMsg struct{}
+
+// goPackages returns the directories of the Go files in files, leaving out
+// those with only tests changed.
+func goPackages(files []git.ChangedFile) []string {
+	var dirs []string
+	for _, f := range files {
+		if !strings.HasSuffix(f.Path, ".go") || strings.HasSuffix(f.Path, "_test.go") {
+			continue
+		}
+		if dir := path.Dir(f.Path); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	slices.Sort(dirs)
+	return dirs
+}
+
+// compareAPIs compares the exported API of each Go package in dirs before
+// and after the change.
+func compareAPIs(reader dirReader, base string, worktree bool, dirs []string) tea.Cmd {
+	return func() tea.Msg {
+		var msg apiReportMsg
+		for _, dir := range dirs {
+			old, new, err := reader.DirFiles(base, worktree, dir, ".go")
+			var changes []apidiff.Change
+			if err == nil {
+				changes, err = apidiff.Compare(old, new)
+			}
+			if err != nil {
+				msg.errs = append(msg.errs, dir+": "+err.Error())
+				continue
+			}
+			for _, c := range changes {
+				msg.findings = append(msg.findings, apiFinding{dir: dir, change: c})
+			}
+		}
+		return msg
+	}
+}
+
+// APIReport is a sub-model listing the changes to the exported APIs of the
+// change's Go packages, breaking ones first, which can be turned into review
+// comments.
+type APIReport struct {
+	packages int  // how many packages are compared
+	loading  bool // the comparison is still running
+	findings []apiFinding
+	errs     []string
+	cursor   int
+	width    int
+	height   int
+}
+
+// NewAPIReport creates the report of comparing packages, which is running.
+func NewAPIReport(packages, width, height int) APIReport {
+	return APIReport{packages: packages, loading: true, width: width, height: height}
+}
+
+// SetResult shows the findings of the comparison, breaking ones first.
+func (r *APIReport) SetResult(findings []apiFinding, errs []string) {
+	r.loading = false
+	r.findings = slices.Clone(findings)
+	slices.SortStableFunc(r.findings, func(a, b apiFinding) int {
+		switch {
+		case a.change.Breaking == b.change.Breaking:
+			return 0
+		case a.change.Breaking:
+			return -1
+		}
+		return 1
+	})
+	r.errs = errs
+	r.cursor = 0
+}
+
+// Update handles key messages.
+func (r APIReport) Update(msg tea.Msg) (APIReport, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return r, nil
+	}
+	switch key.String() {
+	case "j", "down":
+		if r.cursor < len(r.findings)-1 {
+			r.cursor++
+		}
+	case "k", "up":
+		if r.cursor > 0 {
+			r.cursor--
+		}
+	case "enter":
+		if r.cursor < len(r.findings) {
+			jump := APIJumpMsg{Finding: r.findings[r.cursor]}
+			return r, func() tea.Msg { return jump }
+		}
+	case "c":
+		if r.cursor < len(r.findings) {
+			add := APICommentMsg{Findings: []apiFinding{r.findings[r.cursor]}}
+			return r, func() tea.Msg { return add }
+		}
+	case "a":
+		var breaking []apiFinding
+		for _, f := range r.findings {
+			if f.change.Breaking {
+				breaking = append(breaking, f)
+			}
+		}
+		if len(breaking) > 0 {
+			add := APICommentMsg{Findings: breaking}
+			return r, func() tea.Msg { return add }
+		}
+	case "esc", "q", "A":
+		return r, func() tea.Msg { return APIReportCloseMsg{} }
+	}
+	return r, nil
+}
+
+// View renders the API report.
+func (r APIReport) View() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	breakingStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	compatibleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(fmt.Sprintf("API changes — %d Go package(s)", r.packages)))
+	s.WriteString("\n")
+	switch {
+	case r.loading:
+		s.WriteString("  Comparing exported APIs…\n")
+	case len(r.findings) == 0:
+		s.WriteString("  No exported API changed\n")
+	}
+
+	// Keep the cursor in view when there are more findings than rows
+	rows := max(r.height-5-len(r.errs), 1)
+	start := max(0, min(r.cursor-rows/2, len(r.findings)-rows))
+	end := min(start+rows, len(r.findings))
+	for i := start; i < end; i++ {
+		f := r.findings[i]
+		sign := compatibleStyle.Render("+")
+		if f.change.Breaking {
+			sign = breakingStyle.Render("!")
+		}
+		text := f.dir + ": " + f.change.String()
+		if w := r.width - 6; w > 0 {
+			text = runewidth.Truncate(text, w, "…")
+		}
+		if i == r.cursor {
+			s.WriteString(selectedStyle.Render("  > ") + sign + " " + selectedStyle.Render(text))
+		} else {
+			s.WriteString("    " + sign + " " + text)
+		}
+		s.WriteString("\n")
+	}
+	for _, e := range r.errs {
+		s.WriteString(breakingStyle.Render(runewidth.Truncate("  Couldn't compare "+e, max(r.width, 1), "…")))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(footerStyle.Render("  [enter] go to  [c] comment  [a] comment on all breaking  [esc] close"))
+	return s.String()
+}
@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/deparker/revui/internal/apidiff"
+	"github.com/deparker/revui/internal/comment"
+	"github.com/deparker/revui/internal/git"
+)
+
+// dirMock is a source whose packages' files can be read on both sides.
+type dirMock struct {
+	*mockGitRunner
+	old, new map[string]map[string]string // files by directory
+}
+
+func (d *dirMock) DirFiles(base string, worktree bool, dir, ext string) (old, new map[string]string, err error) {
+	return d.old[dir], d.new[dir], nil
+}
+
+func TestGoPackages(t *testing.T) {
+	files := changedFiles("cmd/revui/main.go", "internal/ui/root.go", "internal/ui/root_test.go", "internal/git/git_test.go", "README.md", "internal/ui/help.go")
+	if got, want := goPackages(files), []string{"cmd/revui", "internal/ui"}; !slices.Equal(got, want) {
+		t.Errorf("goPackages = %q, want %q", got, want)
+	}
+}
+
+func TestAPIFindingCommentBody(t *testing.T) {
+	tests := []struct {
+		change apidiff.Change
+		want   string
+	}{
+		{apidiff.Change{Name: "Run", Kind: apidiff.Changed, Old: "func()", New: "func(int)", Breaking: true}, "issue: breaking API change: `Run` changes from `func()` to `func(int)`"},
+		{apidiff.Change{Name: "Gone", Kind: apidiff.Removed, Breaking: true}, "issue: breaking API change: `Gone` is removed"},
+		{apidiff.Change{Name: "New", Kind: apidiff.Added}, "API change: `New` is added"},
+	}
+	for _, tt := range tests {
+		if got := (apiFinding{change: tt.change}).commentBody(); got != tt.want {
+			t.Errorf("commentBody = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestAPIReportKeys(t *testing.T) {
+	r := NewAPIReport(1, 80, 24)
+	if !strings.Contains(r.View(), "Comparing") {
+		t.Errorf("a running comparison should say so:\n%s", r.View())
+	}
+	compatible := apiFinding{dir: "pkg", change: apidiff.Change{Name: "New", Kind: apidiff.Added, File: "a.go", Line: 3}}
+	breaking := apiFinding{dir: "pkg", change: apidiff.Change{Name: "Old", Kind: apidiff.Removed, Breaking: true, File: "a.go", Line: 5}}
+	r.SetResult([]apiFinding{compatible, breaking}, []string{"bad: parse error"})
+	if view := r.View(); !strings.Contains(view, "Couldn't compare bad: parse error") {
+		t.Errorf("errors missing from the report:\n%s", view)
+	}
+
+	// Breaking findings come first
+	_, cmd := r.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg, ok := cmd().(APIJumpMsg); !ok || msg.Finding != breaking {
+		t.Errorf("enter = %#v, want a jump to the breaking finding", msg)
+	}
+	r, _ = r.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	_, cmd = r.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	if msg, ok := cmd().(APICommentMsg); !ok || !slices.Equal(msg.Findings, []apiFinding{compatible}) {
+		t.Errorf("c = %#v, want a comment on the finding under the cursor", msg)
+	}
+	_, cmd = r.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	if msg, ok := cmd().(APICommentMsg); !ok || !slices.Equal(msg.Findings, []apiFinding{breaking}) {
+		t.Errorf("a = %#v, want comments on the breaking findings", msg)
+	}
+}
+
+func TestRootAPIReport(t *testing.T) {
+	mock := &dirMock{
+		mockGitRunner: &mockGitRunner{
+			files: []git.ChangedFile{{Path: "pkg/a.go", Status: "M"}},
+			diffs: map[string]*git.FileDiff{
+				"pkg/a.go": {Path: "pkg/a.go", Status: "M", Hunks: []git.Hunk{{
+					Header: "@@ -1,3 +1,3 @@", OldStart: 1, OldCount: 3, NewStart: 1, NewCount: 3,
+					Lines: []git.Line{
+						{Content: "package pkg", Type: git.LineContext, OldLineNo: 1, NewLineNo: 1},
+						{Content: "", Type: git.LineContext, OldLineNo: 2, NewLineNo: 2},
+						{Content: "func Run() {}", Type: git.LineRemoved, OldLineNo: 3},
+						{Content: "func Run(n int) {}", Type: git.LineAdded, NewLineNo: 3},
+					},
+				}}},
+			},
+		},
+		old: map[string]map[string]string{"pkg": {"a.go": "package pkg\n\nfunc Run() {}\n"}},
+		new: map[string]map[string]string{"pkg": {"a.go": "package pkg\n\nfunc Run(n int) {}\n"}},
+	}
+	m := NewRootModel(mock, "main", 80, 24)
+	var saved []comment.Comment
+	m.SetOnCommentsChanged(func(c []comment.Comment) error {
+		saved = c
+		return nil
+	})
+	send := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(RootModel)
+		return cmd
+	}
+
+	cmd := send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'A'}})
+	if m.focus != focusAPIReport || cmd == nil {
+		t.Fatalf("A should compare the APIs, focus = %d", m.focus)
+	}
+	send(cmd())
+	if view := m.View(); !strings.Contains(view, "Run: changed from func() to func(int)") {
+		t.Fatalf("finding missing from the report:\n%s", view)
+	}
+
+	send(send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})())
+	c := m.comments.Get("pkg/a.go", 3)
+	if c == nil || c.LineType != git.LineAdded || c.Body != "issue: breaking API change: `Run` changes from `func()` to `func(int)`" {
+		t.Fatalf("comment = %+v", c)
+	}
+	if m.focus != focusDiffViewer {
+		t.Errorf("focus = %d after commenting, want the diff", m.focus)
+	}
+	if len(saved) != 1 || saved[0].Body != c.Body {
+		t.Errorf("saved %+v, want the API comment saved", saved)
+	}
+
+	// Commenting again doesn't repeat it
+	send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'A'}})
+	send(compareAPIs(mock, "main", false, []string{"pkg"})())
+	send(send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})())
+	if got := len(m.comments.All()); got != 1 || m.comments.Get("pkg/a.go", 3).Body != c.Body {
+		t.Errorf("after commenting again: %d comments, body %q", got, m.comments.Get("pkg/a.go", 3).Body)
+	}
+}
@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+// reviewChunk is a group of files to review in one pass.
+type reviewChunk struct {
+	name  string
+	paths []string
+}
+
+// ChunkSelectMsg is sent when the user picks a chunk to review. A nil Chunk
+// lists every file again.
+type ChunkSelectMsg struct {
+	Chunk *reviewChunk
+}
+
+// ChunkSelectorCloseMsg is sent when the user closes the chunk selector
+// without picking one.
+type ChunkSelectorCloseMsg struct{}
+
+// topLevelChunk names the chunk of files outside any directory.
+const topLevelChunk = "top level"
+
+// directoryChunks groups files by directory, at the shallowest depth that
+// splits them into more than one group. Files outside any directory come
+// first; other groups are sorted by name.
+func directoryChunks(files []git.ChangedFile) []reviewChunk {
+	for depth := 1; ; depth++ {
+		var chunks []reviewChunk
+		index := make(map[string]int)
+		deeper := false
+		for _, f := range files {
+			name := topLevelChunk
+			if d := path.Dir(f.Path); d != "." {
+				parts := strings.Split(d, "/")
+				deeper = deeper || len(parts) > depth
+				name = strings.Join(parts[:min(depth, len(parts))], "/") + "/"
+			}
+			i, ok := index[name]
+			if !ok {
+				i = len(chunks)
+				index[name] = i
+				chunks = append(chunks, reviewChunk{name: name})
+			}
+			chunks[i].paths = append(chunks[i].paths, f.Path)
+		}
+		if len(chunks) > 1 || !deeper {
+			key := func(c reviewChunk) string {
+				if c.name == topLevelChunk {
+					return ""
+				}
+				return c.name
+			}
+			slices.SortFunc(chunks, func(a, b reviewChunk) int {
+				return cmp.Compare(key(a), key(b))
+			})
+			return chunks
+		}
+	}
+}
+
+// commitChunks groups files by the commits that touched them, oldest first.
+// A file touched by several commits is in each of their chunks. Files no
+// longer in the diff are left out, along with commits left with none.
+func commitChunks(commits []git.Commit, files []git.ChangedFile) []reviewChunk {
+	changed := make(map[string]bool, len(files))
+	for _, f := range files {
+		changed[f.Path] = true
+	}
+	var chunks []reviewChunk
+	for _, c := range commits {
+		chunk := reviewChunk{name: c.Hash + " " + c.Subject}
+		for _, p := range c.Files {
+			if changed[p] {
+				chunk.paths = append(chunk.paths, p)
+			}
+		}
+		if len(chunk.paths) > 0 {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// ChunkSelector is a sub-model for picking a group of files to review in one
+// pass, by directory or by commit, which the file list is then limited to.
+type ChunkSelector struct {
+	byDir    []reviewChunk
+	byCommit []reviewChunk // nil when the source has no commits
+	commits  bool          // listing byCommit
+	total    int           // files under review
+	current  string        // name of the chunk being reviewed; "" for all files
+	cursor   int           // 0 is "All files"; i is chunks()[i-1]
+	width    int
+	height   int
+}
+
+// NewChunkSelector creates a chunk selector offering byDir and byCommit, with
+// current marked as the chunk being reviewed.
+func NewChunkSelector(byDir, byCommit []reviewChunk, total int, current string, width, height int) ChunkSelector {
+	cs := ChunkSelector{byDir: byDir, byCommit: byCommit, total: total, current: current, width: width, height: height}
+	if current != "" && slices.ContainsFunc(byCommit, func(c reviewChunk) bool { return c.name == current }) {
+		cs.commits = true
+	}
+	for i, c := range cs.chunks() {
+		if c.name == current {
+			cs.cursor = i + 1
+		}
+	}
+	return cs
+}
+
+// chunks returns the chunks in the current grouping.
+func (cs ChunkSelector) chunks() []reviewChunk {
+	if cs.commits {
+		return cs.byCommit
+	}
+	return cs.byDir
+}
+
+// Update handles key messages.
+func (cs ChunkSelector) Update(msg tea.Msg) (ChunkSelector, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return cs, nil
+	}
+	switch key.String() {
+	case "j", "down":
+		if cs.cursor < len(cs.chunks()) {
+			cs.cursor++
+		}
+	case "k", "up":
+		if cs.cursor > 0 {
+			cs.cursor--
+		}
+	case "tab":
+		if cs.byCommit != nil {
+			cs.commits = !cs.commits
+			cs.cursor = 0
+		}
+	case "enter":
+		var chunk *reviewChunk
+		if cs.cursor > 0 {
+			c := cs.chunks()[cs.cursor-1]
+			chunk = &c
+		}
+		return cs, func() tea.Msg { return ChunkSelectMsg{Chunk: chunk} }
+	case "esc", "q", "p":
+		return cs, func() tea.Msg { return ChunkSelectorCloseMsg{} }
+	}
+	return cs, nil
+}
+
+// View renders the chunk selector.
+func (cs ChunkSelector) View() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var s strings.Builder
+	grouping := "by directory"
+	if cs.commits {
+		grouping = "by commit"
+	}
+	s.WriteString(titleStyle.Render("Review in passes — " + grouping))
+	s.WriteString("\n")
+
+	chunks := cs.chunks()
+	item := func(i int) string {
+		name, n := "All files", cs.total
+		if i > 0 {
+			name, n = chunks[i-1].name, len(chunks[i-1].paths)
+		}
+		if name == cs.current || (i == 0 && cs.current == "") {
+			name = "• " + name
+		}
+		return fmt.Sprintf("%s  (%d files)", name, n)
+	}
+
+	// Keep the cursor in view when there are more chunks than rows
+	rows := max(cs.height-4, 1)
+	count := len(chunks) + 1
+	start := max(0, min(cs.cursor-rows/2, count-rows))
+	end := min(start+rows, count)
+	for i := start; i < end; i++ {
+		line := item(i)
+		if w := cs.width - 4; w > 0 {
+			line = runewidth.Truncate(line, w, "…")
+		}
+		if i == cs.cursor {
+			s.WriteString(selectedStyle.Render("  > " + line))
+		} else {
+			s.WriteString("    " + line)
+		}
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	footer := "  [enter] review  [esc] close"
+	if cs.byCommit != nil {
+		footer = "  [enter] review  [tab] by directory/commit  [esc] close"
+	}
+	s.WriteString(footerStyle.Render(footer))
+	return s.String()
+}
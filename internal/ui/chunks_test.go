@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+func changedFiles(paths ...string) []git.ChangedFile {
+	files := make([]git.ChangedFile, len(paths))
+	for i, p := range paths {
+		files[i] = git.ChangedFile{Path: p, Status: "M"}
+	}
+	return files
+}
+
+func TestDirectoryChunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []git.ChangedFile
+		want  []reviewChunk
+	}{
+		{
+			name:  "top-level directories",
+			files: changedFiles("web/app.js", "go.mod", "api/server.go", "api/v2/routes.go"),
+			want: []reviewChunk{
+				{name: "top level", paths: []string{"go.mod"}},
+				{name: "api/", paths: []string{"api/server.go", "api/v2/routes.go"}},
+				{name: "web/", paths: []string{"web/app.js"}},
+			},
+		},
+		{
+			name:  "one shared directory splits deeper",
+			files: changedFiles("internal/ui/root.go", "internal/git/git.go", "internal/ui/help.go"),
+			want: []reviewChunk{
+				{name: "internal/git/", paths: []string{"internal/git/git.go"}},
+				{name: "internal/ui/", paths: []string{"internal/ui/root.go", "internal/ui/help.go"}},
+			},
+		},
+		{
+			name:  "single directory",
+			files: changedFiles("pkg/a.go", "pkg/b.go"),
+			want:  []reviewChunk{{name: "pkg/", paths: []string{"pkg/a.go", "pkg/b.go"}}},
+		},
+		{
+			name: "no files",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := directoryChunks(tt.files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("directoryChunks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitChunks(t *testing.T) {
+	commits := []git.Commit{
+		{Hash: "a1b2c3d", Subject: "Add parser", Files: []string{"parse.go", "parse_test.go"}},
+		{Hash: "e4f5a6b", Subject: "Add scratch file", Files: []string{"scratch.txt"}},
+		{Hash: "c7d8e9f", Subject: "Use parser", Files: []string{"main.go", "parse.go"}},
+	}
+	// scratch.txt was removed again, so isn't in the diff
+	got := commitChunks(commits, changedFiles("main.go", "parse.go", "parse_test.go"))
+	want := []reviewChunk{
+		{name: "a1b2c3d Add parser", paths: []string{"parse.go", "parse_test.go"}},
+		{name: "c7d8e9f Use parser", paths: []string{"main.go", "parse.go"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commitChunks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestChunkSelector(t *testing.T) {
+	byDir := []reviewChunk{{name: "api/", paths: []string{"api/a.go"}}, {name: "web/", paths: []string{"web/b.js"}}}
+	byCommit := []reviewChunk{{name: "a1b2c3d Add api", paths: []string{"api/a.go"}}}
+	cs := NewChunkSelector(byDir, byCommit, 2, "", 80, 20)
+
+	view := cs.View()
+	for _, want := range []string{"by directory", "All files  (2 files)", "web/  (1 files)"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	// The first entry lists every file again
+	_, cmd := cs.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg, ok := cmd().(ChunkSelectMsg); !ok || msg.Chunk != nil {
+		t.Errorf("enter on All files sent %#v, want a nil chunk", cmd())
+	}
+
+	cs, _ = cs.Update(runeKey('j'))
+	cs, _ = cs.Update(runeKey('j'))
+	_, cmd = cs.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg, ok := cmd().(ChunkSelectMsg); !ok || msg.Chunk == nil || msg.Chunk.name != "web/" {
+		t.Errorf("enter on web/ sent %#v", cmd())
+	}
+
+	// Tab switches to commits, starting over at the top
+	cs, _ = cs.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if !strings.Contains(cs.View(), "a1b2c3d Add api") || cs.cursor != 0 {
+		t.Error("tab should list the chunks by commit")
+	}
+
+	// Without commits, tab does nothing
+	cs = NewChunkSelector(byDir, nil, 2, "web/", 80, 20)
+	if cs.cursor != 2 {
+		t.Errorf("cursor = %d, want it on the current chunk", cs.cursor)
+	}
+	cs, _ = cs.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if cs.commits {
+		t.Error("tab without commits should keep the directory grouping")
+	}
+
+	_, cmd = cs.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if _, ok := cmd().(ChunkSelectorCloseMsg); !ok {
+		t.Error("esc should close the selector")
+	}
+}
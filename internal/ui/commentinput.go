@@ -1,11 +1,21 @@
 package ui
 
 import (
-	"github.com/charmbracelet/bubbles/textinput"
+	"cmp"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 
 	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/spell"
 )
 
 var commentInputStyle = lipgloss.NewStyle().
@@ -13,6 +23,19 @@ var commentInputStyle = lipgloss.NewStyle().
 	BorderForeground(lipgloss.Color("3")).
 	Padding(0, 1)
 
+var (
+	misspelledStyle = lipgloss.NewStyle().Underline(true).Foreground(lipgloss.Color("1"))
+	suggestionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	chosenStyle     = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3"))
+)
+
+// maxSuggestions is how many spellings Ctrl+l cycles through, and how many
+// completions are offered.
+const maxSuggestions = 5
+
+// maxCommentRows is how tall the comment editor grows before it scrolls.
+const maxCommentRows = 8
+
 // CommentSubmitMsg is sent when the user submits a comment.
 type CommentSubmitMsg struct {
 	FilePath  string
@@ -20,31 +43,77 @@ type CommentSubmitMsg struct {
 	EndLineNo int
 	Body      string
 	LineType  git.LineType
+	Thread    string // the hosting service thread replied to, if a reply
 }
 
 // CommentCancelMsg is sent when the user cancels comment input.
 type CommentCancelMsg struct{}
 
-// CommentInput is a sub-model for entering review comments.
+// editorDoneMsg carries a comment back from the external editor.
+type editorDoneMsg struct {
+	body string
+	err  error
+}
+
+// CommentInput is a sub-model for entering review comments, which may run
+// over several lines. Enter or Ctrl+s submits; Alt+Enter, which terminals
+// commonly send for Shift+Enter, or Ctrl+j starts a new line. Ctrl+e
+// edits the comment in $VISUAL or $EDITOR instead, saving it on exit.
 type CommentInput struct {
-	input     textinput.Model
+	input     textarea.Model
 	active    bool
 	filePath  string
 	lineNo    int
 	endLineNo int
 	lineType  git.LineType
+	thread    string // thread being replied to
+	replyTo   string // who started it, for the label
 	width     int
+	dict      *spell.Dictionary
+	cycle     *spellCycle // the word Ctrl+l is replacing, if pressed last
+	mentions  []git.Author
+	paths     []string
+	choice    int // the highlighted completion
+}
+
+// completion is a reference offered for the word being typed.
+type completion struct {
+	label  string // what the list shows
+	insert string // what replaces the word
+}
+
+// spellCycle is a misspelled word being replaced by each of its suggested
+// spellings in turn, and then by itself again.
+type spellCycle struct {
+	start       int // rune offset of the word
+	original    string
+	suggestions []string
+	next        int // the suggestion in place; len(suggestions) for original
+}
+
+// current returns the spelling in place.
+func (sc *spellCycle) current() string {
+	if sc.next == len(sc.suggestions) {
+		return sc.original
+	}
+	return sc.suggestions[sc.next]
 }
 
 // NewCommentInput creates a new comment input component.
 func NewCommentInput(width int) CommentInput {
-	ti := textinput.New()
-	ti.Placeholder = "Enter comment..."
-	ti.CharLimit = 500
-	ti.Width = width - 6
+	ta := textarea.New()
+	ta.Placeholder = "Enter comment..."
+	ta.CharLimit = 0
+	ta.MaxWidth = 0
+	ta.Prompt = ""
+	ta.ShowLineNumbers = false
+	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	ta.KeyMap.InsertNewline.SetKeys("alt+enter", "ctrl+j")
+	ta.SetWidth(width - 6)
+	ta.SetHeight(1)
 
 	return CommentInput{
-		input: ti,
+		input: ta,
 		width: width,
 	}
 }
@@ -56,13 +125,23 @@ func (ci *CommentInput) Activate(filePath string, lineNo, endLineNo int, lineTyp
 	ci.lineNo = lineNo
 	ci.endLineNo = endLineNo
 	ci.lineType = lineType
+	ci.thread, ci.replyTo = "", ""
+	ci.cycle = nil
 	ci.input.SetValue(existing)
 	ci.input.Focus()
+	ci.fitHeight()
+}
+
+// ActivateReply shows the input for a reply to thread, started by author, on
+// a line of filePath.
+func (ci *CommentInput) ActivateReply(filePath string, lineNo int, lineType git.LineType, thread, author, existing string) {
+	ci.Activate(filePath, lineNo, lineNo, lineType, existing)
+	ci.thread, ci.replyTo = thread, author
 }
 
-// Init returns the text input blink command.
+// Init returns the text area blink command.
 func (ci CommentInput) Init() tea.Cmd {
-	return textinput.Blink
+	return textarea.Blink
 }
 
 // Update handles key messages.
@@ -73,41 +152,370 @@ func (ci CommentInput) Update(msg tea.Msg) (CommentInput, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlL:
+			ci.cycleSpelling()
+			return ci, nil
+		case tea.KeyCtrlE:
+			ci.cycle = nil
+			return ci, openEditor(ci.input.Value())
+		}
+		ci.cycle = nil
+		if start, items := ci.completions(); len(items) > 0 {
+			switch msg.Type {
+			case tea.KeyTab:
+				ci.complete(start, items[min(ci.choice, len(items)-1)])
+				return ci, nil
+			case tea.KeyCtrlN:
+				ci.choice = (ci.choice + 1) % len(items)
+				return ci, nil
+			case tea.KeyCtrlP:
+				ci.choice = (ci.choice + len(items) - 1) % len(items)
+				return ci, nil
+			}
+		}
+		ci.choice = 0
 		switch msg.Type {
 		case tea.KeyEscape:
 			ci.active = false
 			ci.input.Blur()
 			return ci, func() tea.Msg { return CommentCancelMsg{} }
-		case tea.KeyEnter:
-			body := ci.input.Value()
-			ci.active = false
-			ci.input.Blur()
-			if body == "" {
-				return ci, func() tea.Msg { return CommentCancelMsg{} }
-			}
-			submitMsg := CommentSubmitMsg{
-				FilePath:  ci.filePath,
-				LineNo:    ci.lineNo,
-				EndLineNo: ci.endLineNo,
-				Body:      body,
-				LineType:  ci.lineType,
+		case tea.KeyEnter, tea.KeyCtrlS:
+			if msg.Alt {
+				// Alt+Enter starts a new line
+				break
 			}
-			return ci, func() tea.Msg { return submitMsg }
+			return ci, ci.submit()
 		}
+
+	case editorDoneMsg:
+		// A failed edit leaves the comment as it was, to try again
+		if msg.err != nil {
+			return ci, nil
+		}
+		ci.input.SetValue(msg.body)
+		return ci, ci.submit()
 	}
 
 	var cmd tea.Cmd
 	ci.input, cmd = ci.input.Update(msg)
+	ci.fitHeight()
 	return ci, cmd
 }
 
+// submit closes the input, sending the comment, or cancelling if it's empty.
+func (ci *CommentInput) submit() tea.Cmd {
+	body := ci.input.Value()
+	ci.active = false
+	ci.input.Blur()
+	if body == "" {
+		return func() tea.Msg { return CommentCancelMsg{} }
+	}
+	submitMsg := CommentSubmitMsg{
+		FilePath:  ci.filePath,
+		LineNo:    ci.lineNo,
+		EndLineNo: ci.endLineNo,
+		Body:      body,
+		LineType:  ci.lineType,
+		Thread:    ci.thread,
+	}
+	return func() tea.Msg { return submitMsg }
+}
+
+// openEditor suspends the TUI to edit body in a temporary Markdown file with
+// $VISUAL, $EDITOR, or vi, the first set, run by sh so that it may have
+// arguments, as in "code --wait". The file's contents come back in an
+// editorDoneMsg when the editor exits, trailing blank lines trimmed.
+func openEditor(body string) tea.Cmd {
+	f, err := os.CreateTemp("", "revui-comment-*.md")
+	if err != nil {
+		return func() tea.Msg { return editorDoneMsg{err: err} }
+	}
+	name := f.Name()
+	_, err = f.WriteString(body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+		return func() tea.Msg { return editorDoneMsg{err: err} }
+	}
+	editor := cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi")
+	cmd := exec.Command("sh", "-c", editor+" "+shellQuote(name))
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		defer os.Remove(name)
+		if err != nil {
+			return editorDoneMsg{err: err}
+		}
+		data, err := os.ReadFile(name)
+		return editorDoneMsg{body: strings.TrimRightFunc(string(data), unicode.IsSpace), err: err}
+	})
+}
+
+// fitHeight grows the editor to show every row of the comment, soft-wrapped
+// ones included, up to maxCommentRows.
+func (ci *CommentInput) fitHeight() {
+	width := max(ci.input.Width(), 1)
+	rows := 0
+	for line := range strings.SplitSeq(ci.input.Value(), "\n") {
+		rows += 1 + runewidth.StringWidth(line)/width
+	}
+	ci.input.SetHeight(min(max(rows, 1), maxCommentRows))
+}
+
+// position returns the cursor's offset in runes from the start of the
+// comment, counting each line break as one.
+func (ci CommentInput) position() int {
+	lines := strings.Split(ci.input.Value(), "\n")
+	pos := 0
+	for _, line := range lines[:min(ci.input.Line(), len(lines))] {
+		pos += len([]rune(line)) + 1
+	}
+	info := ci.input.LineInfo()
+	return pos + info.StartColumn + info.ColumnOffset
+}
+
+// setPosition moves the cursor to rune offset pos, as position counts it.
+func (ci *CommentInput) setPosition(pos int) {
+	lines := strings.Split(ci.input.Value(), "\n")
+	row := 0
+	for row < len(lines)-1 && pos > len([]rune(lines[row])) {
+		pos -= len([]rune(lines[row])) + 1
+		row++
+	}
+	for ci.input.Line() > row {
+		ci.input.CursorUp()
+	}
+	for ci.input.Line() < row {
+		ci.input.CursorDown()
+	}
+	ci.input.SetCursor(pos)
+}
+
+// completions returns the references that could complete the word before
+// the cursor, and the rune offset it starts at. A word starting with @ is
+// completed with the authors of recent commits, and one starting with # or :
+// with the paths of the files in the change.
+func (ci CommentInput) completions() (int, []completion) {
+	value := []rune(ci.input.Value())
+	pos := min(ci.position(), len(value))
+	start := pos
+	for start > 0 && !unicode.IsSpace(value[start-1]) {
+		start--
+	}
+	if start == pos {
+		return 0, nil
+	}
+	query := strings.ToLower(string(value[start+1 : pos]))
+	var items []completion
+	switch value[start] {
+	case '@':
+		for _, a := range ci.mentions {
+			if !mentionMatches(a, query) {
+				continue
+			}
+			user, _, _ := strings.Cut(a.Email, "@")
+			items = append(items, completion{label: a.Name + " <" + a.Email + ">", insert: "@" + user})
+		}
+	case '#', ':':
+		// Files whose names start with the query come before those with it
+		// elsewhere in their paths.
+		var later []completion
+		for _, p := range ci.paths {
+			item := completion{label: p, insert: "`" + p + "`"}
+			switch lower := strings.ToLower(p); {
+			case strings.HasPrefix(path.Base(lower), query):
+				items = append(items, item)
+			case strings.Contains(lower, query):
+				later = append(later, item)
+			}
+		}
+		items = append(items, later...)
+	}
+	return start, items[:min(len(items), maxSuggestions)]
+}
+
+// mentionMatches reports whether one of a's names, or their email address,
+// starts with query.
+func mentionMatches(a git.Author, query string) bool {
+	if strings.HasPrefix(strings.ToLower(a.Email), query) {
+		return true
+	}
+	for _, name := range strings.Fields(strings.ToLower(a.Name)) {
+		if strings.HasPrefix(name, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// complete replaces the word from start to the cursor with c's reference,
+// followed by a space.
+func (ci *CommentInput) complete(start int, c completion) {
+	value := []rune(ci.input.Value())
+	pos := min(ci.position(), len(value))
+	ci.replaceWord(value, start, pos-start, c.insert+" ")
+	ci.choice = 0
+}
+
+// cycleSpelling replaces the misspelled word at or before the cursor with
+// its next suggested spelling, coming back round to the word as written.
+// Without a dictionary, or a misspelled word with suggestions, it does
+// nothing.
+func (ci *CommentInput) cycleSpelling() {
+	value := []rune(ci.input.Value())
+	if sc := ci.cycle; sc != nil {
+		cur := []rune(sc.current())
+		if sc.start+len(cur) <= len(value) && string(value[sc.start:sc.start+len(cur)]) == string(cur) {
+			sc.next = (sc.next + 1) % (len(sc.suggestions) + 1)
+			ci.replaceWord(value, sc.start, len(cur), sc.current())
+			return
+		}
+		ci.cycle = nil
+	}
+	if ci.dict == nil {
+		return
+	}
+	spans := ci.dict.Misspelled(string(value))
+	if len(spans) == 0 {
+		return
+	}
+	// The word at the cursor, else the last one before it, else the first
+	pos := ci.position()
+	span := spans[0]
+	for _, sp := range spans {
+		if sp.Start <= pos {
+			span = sp
+		}
+	}
+	word := string(value[span.Start:span.End])
+	suggestions := ci.dict.Suggest(word, maxSuggestions)
+	if len(suggestions) == 0 {
+		return
+	}
+	ci.cycle = &spellCycle{start: span.Start, original: word, suggestions: suggestions}
+	ci.replaceWord(value, span.Start, span.End-span.Start, suggestions[0])
+}
+
+// replaceWord sets the input to value with the n runes at start replaced by
+// word, leaving the cursor after it.
+func (ci *CommentInput) replaceWord(value []rune, start, n int, word string) {
+	replaced := string(value[:start]) + word + string(value[start+n:])
+	ci.input.SetValue(replaced)
+	ci.setPosition(start + len([]rune(word)))
+	ci.fitHeight()
+}
+
 // View renders the comment input.
 func (ci CommentInput) View() string {
 	if !ci.active {
 		return ""
 	}
-	label := lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("Comment: ")
-	return commentInputStyle.Render(label + ci.input.View())
+	text := "Comment: "
+	if ci.thread != "" {
+		text = "Reply to " + cmp.Or(ci.replyTo, "thread") + ": "
+	}
+	label := lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render(text)
+	hint := suggestionStyle.Render("Enter saves · Alt+Enter new line · Ctrl+e editor")
+	view := label + hint + "\n" + ci.inputView()
+	if sc := ci.cycle; sc != nil {
+		choices := make([]string, 0, len(sc.suggestions)+1)
+		for i, s := range append(slices.Clone(sc.suggestions), sc.original) {
+			if i == sc.next {
+				choices = append(choices, chosenStyle.Render(s))
+			} else {
+				choices = append(choices, suggestionStyle.Render(s))
+			}
+		}
+		view += "\n" + suggestionStyle.Render("Ctrl+l: ") + strings.Join(choices, suggestionStyle.Render(" · "))
+	}
+	if _, items := ci.completions(); len(items) > 0 {
+		choices := make([]string, len(items))
+		for i, item := range items {
+			if i == min(ci.choice, len(items)-1) {
+				choices[i] = chosenStyle.Render(item.label)
+			} else {
+				choices[i] = suggestionStyle.Render(item.label)
+			}
+		}
+		view += "\n" + suggestionStyle.Render("Tab: ") + strings.Join(choices, suggestionStyle.Render(" · "))
+	}
+	return commentInputStyle.Render(view)
+}
+
+// inputView renders the text area with misspelled words underlined. Only
+// comments shown whole, without soft-wrapped or scrolled lines, are drawn
+// here; others, and the word being typed at the end, are left to the text
+// area as they are.
+func (ci CommentInput) inputView() string {
+	value := []rune(ci.input.Value())
+	if ci.dict == nil || len(value) == 0 {
+		return ci.input.View()
+	}
+	lines := strings.Split(string(value), "\n")
+	if len(lines) > ci.input.Height() {
+		return ci.input.View()
+	}
+	for _, line := range lines {
+		if runewidth.StringWidth(line) >= ci.input.Width() {
+			return ci.input.View()
+		}
+	}
+	pos := ci.position()
+	var spans []spell.Span
+	for _, sp := range ci.dict.Misspelled(string(value)) {
+		if sp.End != pos || pos != len(value) {
+			spans = append(spans, sp)
+		}
+	}
+	if len(spans) == 0 {
+		return ci.input.View()
+	}
+
+	var b strings.Builder
+	text := ci.input.FocusedStyle.Text.Inline(true)
+	// Runs of runes drawn alike, each either misspelled or not
+	write := func(from, to int) {
+		for from < to {
+			style, end := text, to
+			for _, sp := range spans {
+				switch {
+				case from >= sp.Start && from < sp.End:
+					style, end = misspelledStyle, min(end, sp.End)
+				case sp.Start > from:
+					end = min(end, sp.Start)
+				}
+			}
+			b.WriteString(style.Render(string(value[from:end])))
+			from = end
+		}
+	}
+	offset := 0
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		n := len([]rune(line))
+		padding := ci.input.Width() - runewidth.StringWidth(line)
+		if pos >= offset && pos <= offset+n {
+			write(offset, pos)
+			cursor := ci.input.Cursor
+			if pos < offset+n {
+				cursor.SetChar(string(value[pos]))
+				b.WriteString(cursor.View())
+				write(pos+1, offset+n)
+			} else {
+				cursor.SetChar(" ")
+				b.WriteString(cursor.View())
+				padding--
+			}
+		} else {
+			write(offset, offset+n)
+		}
+		b.WriteString(text.Render(strings.Repeat(" ", max(padding, 0))))
+		offset += n + 1
+	}
+	return b.String()
 }
 
 // Active returns whether the input is currently shown.
@@ -130,8 +538,25 @@ func (ci CommentInput) Value() string {
 	return ci.input.Value()
 }
 
+// SetDictionary sets the word list misspellings are underlined by and
+// suggestions drawn from; nil turns spell checking off.
+func (ci *CommentInput) SetDictionary(d *spell.Dictionary) {
+	ci.dict = d
+}
+
+// SetMentions sets the authors an @ in a comment can be completed with.
+func (ci *CommentInput) SetMentions(authors []git.Author) {
+	ci.mentions = authors
+}
+
+// SetPaths sets the file paths a # or : in a comment can be completed with.
+func (ci *CommentInput) SetPaths(paths []string) {
+	ci.paths = paths
+}
+
 // SetWidth updates the width.
 func (ci *CommentInput) SetWidth(width int) {
 	ci.width = width
-	ci.input.Width = width - 6
+	ci.input.SetWidth(width - 6)
+	ci.fitHeight()
 }
@@ -1,11 +1,14 @@
 package ui
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/spell"
 )
 
 func TestCommentInputActivate(t *testing.T) {
@@ -45,3 +48,143 @@ func TestCommentInputEditExisting(t *testing.T) {
 		t.Errorf("value = %q, want %q", ci.Value(), "existing comment")
 	}
 }
+
+func TestCommentInputSpelling(t *testing.T) {
+	ci := NewCommentInput(80)
+	ci.SetDictionary(spell.New("fix", "the", "tea", "name"))
+	ci.Activate("main.go", 10, 10, git.LineAdded, "fix teh name")
+
+	if view := ci.View(); !strings.Contains(view, misspelledStyle.Render("teh")) {
+		t.Errorf("misspelling not underlined in %q", view)
+	}
+
+	// The cursor is at the end, after the last misspelling before it.
+	want := []string{"fix the name", "fix tea name", "fix teh name", "fix the name"}
+	for i, w := range want {
+		ci, _ = ci.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+		if ci.Value() != w {
+			t.Fatalf("after %d Ctrl+l, value = %q, want %q", i+1, ci.Value(), w)
+		}
+	}
+
+	// Typing, at the cursor left after the word, ends the cycle, and correctly
+	// spelled input has nothing to cycle.
+	ci, _ = ci.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'!'}})
+	ci, _ = ci.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if ci.Value() != "fix the! name" {
+		t.Errorf("value = %q, want %q", ci.Value(), "fix the! name")
+	}
+}
+
+func TestCommentInputCompletion(t *testing.T) {
+	ci := NewCommentInput(80)
+	ci.SetMentions([]git.Author{{Name: "Ann Lee", Email: "ann@example.com"}, {Name: "Bob Lewis", Email: "bob.l@example.com"}})
+	ci.SetPaths([]string{"cmd/main.go", "internal/ui/root.go", "internal/ui/root_test.go", "docs/ui.md"})
+	typeText := func(s string) {
+		for _, r := range s {
+			ci, _ = ci.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+
+	tests := []struct {
+		name  string
+		typed string
+		keys  []tea.KeyType
+		want  string
+	}{
+		{"mention by first name", "ask @an", []tea.KeyType{tea.KeyTab}, "ask @ann "},
+		{"mention by last name", "cc @le", []tea.KeyType{tea.KeyCtrlN, tea.KeyTab}, "cc @bob.l "},
+		{"path by file name", "like #roo", []tea.KeyType{tea.KeyTab}, "like `internal/ui/root.go` "},
+		{"file name before directory", "see :ui", []tea.KeyType{tea.KeyTab}, "see `docs/ui.md` "},
+		{"path anywhere in it", "see :ui/", []tea.KeyType{tea.KeyCtrlP, tea.KeyTab}, "see `internal/ui/root_test.go` "},
+		{"nothing to complete", "ask @zed", []tea.KeyType{tea.KeyTab}, "ask @zed"},
+		{"not a reference", "a:b", []tea.KeyType{tea.KeyTab}, "a:b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ci.Activate("main.go", 1, 1, git.LineAdded, "")
+			typeText(tt.typed)
+			for _, k := range tt.keys {
+				ci, _ = ci.Update(tea.KeyMsg{Type: k})
+			}
+			if ci.Value() != tt.want {
+				t.Errorf("value = %q, want %q", ci.Value(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentInputMultiline(t *testing.T) {
+	ci := NewCommentInput(80)
+	ci.Activate("main.go", 10, 10, git.LineAdded, "")
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("first")},
+		{Type: tea.KeyEnter, Alt: true},
+		{Type: tea.KeyRunes, Runes: []rune("second")},
+		{Type: tea.KeyCtrlJ},
+		{Type: tea.KeyRunes, Runes: []rune(strings.Repeat("x", 500))},
+	} {
+		ci, _ = ci.Update(msg)
+		if !ci.Active() {
+			t.Fatalf("%v submitted the comment", msg)
+		}
+	}
+	want := "first\nsecond\n" + strings.Repeat("x", 500)
+	if ci.Value() != want {
+		t.Fatalf("value = %q, want %q", ci.Value(), want)
+	}
+	if h := ci.input.Height(); h != maxCommentRows {
+		t.Errorf("editor height = %d, want it grown to %d", h, maxCommentRows)
+	}
+
+	ci, cmd := ci.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if msg, ok := cmd().(CommentSubmitMsg); !ok || msg.Body != want {
+		t.Errorf("Ctrl+s sent %+v, want the comment submitted", msg)
+	}
+	if ci.Active() {
+		t.Error("should be inactive after submitting")
+	}
+}
+
+func TestCommentInputCompletionOnLaterLine(t *testing.T) {
+	ci := NewCommentInput(80)
+	ci.SetPaths([]string{"internal/ui/root.go"})
+	ci.Activate("main.go", 10, 10, git.LineAdded, "see\nalso #ro")
+	ci, _ = ci.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if want := "see\nalso `internal/ui/root.go` "; ci.Value() != want {
+		t.Errorf("value = %q, want %q", ci.Value(), want)
+	}
+	ci, _ = ci.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'!'}})
+	if want := "see\nalso `internal/ui/root.go` !"; ci.Value() != want {
+		t.Errorf("typing after a completion: value = %q, want %q", ci.Value(), want)
+	}
+}
+
+func TestCommentInputEditorDone(t *testing.T) {
+	ci := NewCommentInput(80)
+	ci.Activate("main.go", 10, 12, git.LineAdded, "draft")
+
+	// A failed edit keeps the comment open as it was
+	ci, cmd := ci.Update(editorDoneMsg{err: errors.New("exit status 1")})
+	if !ci.Active() || ci.Value() != "draft" || cmd != nil {
+		t.Fatalf("after a failed edit: active = %v, value = %q", ci.Active(), ci.Value())
+	}
+
+	ci, cmd = ci.Update(editorDoneMsg{body: "line one\nline two"})
+	if ci.Active() {
+		t.Error("should be inactive after the editor saves")
+	}
+	want := CommentSubmitMsg{FilePath: "main.go", LineNo: 10, EndLineNo: 12, Body: "line one\nline two", LineType: git.LineAdded}
+	if msg := cmd(); msg != want {
+		t.Errorf("msg = %#v, want %#v", msg, want)
+	}
+
+	// Saving an empty file cancels the comment
+	ci.Activate("main.go", 10, 10, git.LineAdded, "draft")
+	if _, cmd = ci.Update(editorDoneMsg{}); cmd == nil {
+		t.Fatal("expected a cancel command")
+	}
+	if msg := cmd(); msg != (CommentCancelMsg{}) {
+		t.Errorf("msg = %#v, want CommentCancelMsg", msg)
+	}
+}
@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/deparker/revui/internal/comment"
+)
+
+// CommentJumpMsg is sent when the user picks a comment to go to.
+type CommentJumpMsg struct {
+	Comment comment.Comment
+}
+
+// CommentDeleteMsg is sent when the user deletes comments from the list.
+type CommentDeleteMsg struct {
+	Comments []comment.Comment
+}
+
+// CommentListCloseMsg is sent when the user closes the comment list.
+type CommentListCloseMsg struct{}
+
+// CommentList is a sub-model listing every comment in the review, for going
+// to one or deleting several without finding each in the diff.
+type CommentList struct {
+	comments     []comment.Comment // sorted by file and line
+	cursor       int
+	width        int
+	height       int
+	pendingD     bool // first d of dd
+	confirmClear bool // asking whether to delete every comment
+}
+
+// NewCommentList creates a comment list showing comments.
+func NewCommentList(comments []comment.Comment, width, height int) CommentList {
+	cl := CommentList{width: width, height: height}
+	cl.SetComments(comments)
+	return cl
+}
+
+// SetComments replaces the listed comments, keeping the cursor in range.
+func (cl *CommentList) SetComments(comments []comment.Comment) {
+	cl.comments = slices.Clone(comments)
+	slices.SortFunc(cl.comments, func(a, b comment.Comment) int {
+		return cmp.Or(cmp.Compare(a.FilePath, b.FilePath), cmp.Compare(a.StartLine, b.StartLine))
+	})
+	cl.cursor = max(min(cl.cursor, len(cl.comments)-1), 0)
+}
+
+// Update handles key messages.
+func (cl CommentList) Update(msg tea.Msg) (CommentList, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return cl, nil
+	}
+
+	// Clearing every comment needs an explicit y; anything else cancels.
+	if cl.confirmClear {
+		cl.confirmClear = false
+		if key.String() == "y" {
+			all := cl.comments
+			return cl, func() tea.Msg { return CommentDeleteMsg{Comments: all} }
+		}
+		return cl, nil
+	}
+
+	pendingD := cl.pendingD
+	cl.pendingD = false
+	switch key.String() {
+	case "j", "down":
+		if cl.cursor < len(cl.comments)-1 {
+			cl.cursor++
+		}
+	case "k", "up":
+		if cl.cursor > 0 {
+			cl.cursor--
+		}
+	case "d":
+		if len(cl.comments) == 0 {
+			return cl, nil
+		}
+		if !pendingD {
+			cl.pendingD = true
+			return cl, nil
+		}
+		c := cl.comments[cl.cursor]
+		return cl, func() tea.Msg { return CommentDeleteMsg{Comments: []comment.Comment{c}} }
+	case "X":
+		cl.confirmClear = len(cl.comments) > 0
+	case "enter":
+		if len(cl.comments) > 0 {
+			c := cl.comments[cl.cursor]
+			return cl, func() tea.Msg { return CommentJumpMsg{Comment: c} }
+		}
+	case "esc", "q", "C":
+		return cl, func() tea.Msg { return CommentListCloseMsg{} }
+	}
+	return cl, nil
+}
+
+// View renders the comment list.
+func (cl CommentList) View() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(fmt.Sprintf("Comments (%d)", len(cl.comments))))
+	s.WriteString("\n")
+	if len(cl.comments) == 0 {
+		s.WriteString("  No comments yet\n")
+	}
+
+	// Keep the cursor in view when there are more comments than rows
+	rows := max(cl.height-4, 1)
+	start := max(0, min(cl.cursor-rows/2, len(cl.comments)-rows))
+	end := min(start+rows, len(cl.comments))
+	for i := start; i < end; i++ {
+		c := cl.comments[i]
+		body, _, _ := strings.Cut(c.Body, "\n")
+		line := fmt.Sprintf("%s:%d  %s", c.FilePath, c.StartLine, body)
+		if c.Question {
+			line = fmt.Sprintf("%s:%d  ? %s", c.FilePath, c.StartLine, body)
+		}
+		if w := cl.width - 4; w > 0 {
+			line = runewidth.Truncate(line, w, "…")
+		}
+		if i == cl.cursor {
+			s.WriteString(selectedStyle.Render("  > " + line))
+		} else {
+			s.WriteString("    " + line)
+		}
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	if cl.confirmClear {
+		s.WriteString(warnStyle.Render(fmt.Sprintf("  Delete all %d comments? [y] yes  [any other key] no", len(cl.comments))))
+		return s.String()
+	}
+	s.WriteString(footerStyle.Render("  [enter] go to  [dd] delete  [X] delete all  [esc] close"))
+	return s.String()
+}
@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/deparker/revui/internal/comment"
+)
+
+func testComments() []comment.Comment {
+	return []comment.Comment{
+		{FilePath: "b.go", StartLine: 3, EndLine: 3, Body: "third"},
+		{FilePath: "a.go", StartLine: 9, EndLine: 9, Body: "second"},
+		{FilePath: "a.go", StartLine: 2, EndLine: 2, Body: "first\nmore"},
+	}
+}
+
+func runeKey(r rune) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+}
+
+func TestCommentListOrderAndJump(t *testing.T) {
+	cl := NewCommentList(testComments(), 80, 24)
+	view := cl.View()
+	if !strings.Contains(view, "Comments (3)") || strings.Index(view, "a.go:2") > strings.Index(view, "a.go:9") {
+		t.Errorf("comments should be listed by file and line, got:\n%s", view)
+	}
+	if strings.Contains(view, "more") {
+		t.Error("only the first line of a comment should be listed")
+	}
+
+	cl, _ = cl.Update(runeKey('j'))
+	_, cmd := cl.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg, ok := cmd().(CommentJumpMsg); !ok || msg.Comment.StartLine != 9 {
+		t.Errorf("enter should go to the selected comment, got %+v", cmd())
+	}
+}
+
+func TestCommentListDelete(t *testing.T) {
+	cl := NewCommentList(testComments(), 80, 24)
+
+	// A single d does nothing; dd deletes the selected comment
+	cl, cmd := cl.Update(runeKey('d'))
+	if cmd != nil {
+		t.Fatal("a single d should wait for the second")
+	}
+	cl, cmd = cl.Update(runeKey('d'))
+	if msg, ok := cmd().(CommentDeleteMsg); !ok || len(msg.Comments) != 1 || msg.Comments[0].Body != "first\nmore" {
+		t.Errorf("dd should delete the selected comment, got %+v", cmd())
+	}
+
+	// d then another key is not a delete
+	cl, _ = cl.Update(runeKey('d'))
+	cl, _ = cl.Update(runeKey('j'))
+	if _, cmd = cl.Update(runeKey('d')); cmd != nil {
+		t.Error("d, j, d should not delete")
+	}
+}
+
+func TestCommentListClearAll(t *testing.T) {
+	cl := NewCommentList(testComments(), 80, 24)
+
+	cl, _ = cl.Update(runeKey('X'))
+	if !strings.Contains(cl.View(), "Delete all 3 comments?") {
+		t.Fatal("X should ask before deleting everything")
+	}
+	cl, cmd := cl.Update(runeKey('n'))
+	if cmd != nil || cl.confirmClear {
+		t.Error("any key but y should cancel")
+	}
+
+	cl, _ = cl.Update(runeKey('X'))
+	_, cmd = cl.Update(runeKey('y'))
+	if msg, ok := cmd().(CommentDeleteMsg); !ok || len(msg.Comments) != 3 {
+		t.Errorf("y should delete every comment, got %+v", cmd())
+	}
+}
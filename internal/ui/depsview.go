@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/deparker/revui/internal/deps"
+)
+
+// summarizeDeps works out the dependencies the diff changes, if it's of a
+// dependency manifest.
+func (dv *DiffViewer) summarizeDeps() {
+	dv.depSummary = nil
+	if dv.diff != nil {
+		if s, ok := deps.Summarize(dv.diff); ok {
+			dv.depSummary = &s
+		}
+	}
+}
+
+// ShowingDeps reports whether the diff, of a dependency manifest such as
+// go.mod or package.json, is shown as a summary of the dependencies it
+// changes instead of as lines.
+func (dv DiffViewer) ShowingDeps() bool {
+	return dv.depSummary != nil && !dv.rawDeps
+}
+
+// ToggleDeps switches a dependency manifest between its summary and its
+// diff.
+func (dv *DiffViewer) ToggleDeps() {
+	if dv.depSummary == nil {
+		return
+	}
+	dv.rawDeps = !dv.rawDeps
+	dv.cursor = 0
+	dv.offset = 0
+	dv.lines = dv.flattenLines()
+	dv.computeMatches()
+}
+
+// depMarks are the signs the summary shows each kind of change with, their
+// colors, and how the footer counts them.
+var depMarks = map[deps.Kind]struct {
+	sign  string
+	color lipgloss.Color
+	label string
+}{
+	deps.Upgraded:   {"↑", "2", "upgraded"},
+	deps.Downgraded: {"↓", "3", "downgraded"},
+	deps.Changed:    {"~", "3", "changed"},
+	deps.Added:      {"+", "2", "added"},
+	deps.Removed:    {"-", "1", "removed"},
+}
+
+// depOrder is the order the summary lists kinds of change in, after major
+// version changes.
+var depOrder = []deps.Kind{deps.Upgraded, deps.Downgraded, deps.Changed, deps.Added, deps.Removed}
+
+// depsView renders the summary of a dependency manifest's changes: major
+// version changes first, then by kind and name.
+func depsView(s deps.Summary, width int) string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	majorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	changes := slices.Clone(s.Changes)
+	slices.SortStableFunc(changes, func(a, b deps.Change) int {
+		if a.Major != b.Major {
+			if a.Major {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(slices.Index(depOrder, a.Kind), slices.Index(depOrder, b.Kind))
+	})
+	nameWidth := 0
+	for _, c := range changes {
+		nameWidth = max(nameWidth, runewidth.StringWidth(c.Name))
+	}
+	nameWidth = min(nameWidth, max(width/2, 10))
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Dependencies — "+s.Manifest) + "\n\n")
+	for _, c := range changes {
+		mark := depMarks[c.Kind]
+		name := runewidth.FillRight(runewidth.Truncate(c.Name, nameWidth, "…"), nameWidth)
+		text := name + "  " + depVersions(c)
+		if scope := depScope(c); scope != "" {
+			text += "  " + scope
+		}
+		if c.OldName != "" {
+			text += "  (was " + c.OldName + ")"
+		}
+		// The sign, its space, and the indent take 4 columns; "major" 7
+		room := width - 4
+		if c.Major {
+			room -= 7
+		}
+		if room > 0 {
+			text = runewidth.Truncate(text, room, "…")
+		}
+		b.WriteString("  " + lipgloss.NewStyle().Foreground(mark.color).Render(mark.sign) + " " + text)
+		if c.Major {
+			b.WriteString("  " + majorStyle.Render("major"))
+		}
+		b.WriteString("\n")
+	}
+
+	counts, major := s.Counts()
+	var parts []string
+	for _, k := range depOrder {
+		if n := counts[k]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, depMarks[k].label))
+		}
+	}
+	if major > 0 {
+		parts = append(parts, majorStyle.Render(plural(major, "major version change")))
+	}
+	b.WriteString("\n  " + strings.Join(parts, " · ") + "\n")
+	if s.Other > 0 {
+		b.WriteString("  " + plural(s.Other, "other changed line") + "\n")
+	}
+	b.WriteString("\n" + dimStyle.Render("d shows the diff"))
+	return b.String()
+}
+
+// depVersions describes the versions a change is between.
+func depVersions(c deps.Change) string {
+	switch {
+	case c.Kind == deps.Added:
+		return c.New
+	case c.Kind == deps.Removed:
+		return c.Old
+	case c.Old == c.New:
+		return c.New
+	}
+	return c.Old + " → " + c.New
+}
+
+// depScope describes a change's scope, or a change of scope, as in
+// "indirect → direct".
+func depScope(c deps.Change) string {
+	if c.Kind == deps.Added || c.Kind == deps.Removed || c.OldScope == c.Scope {
+		return c.Scope
+	}
+	name := func(scope string) string {
+		if scope == "" {
+			return "direct"
+		}
+		return scope
+	}
+	return name(c.OldScope) + " → " + name(c.Scope)
+}
+
+// plural returns n and noun, with an s if n isn't 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -1,26 +1,61 @@
 package ui
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 
+	"github.com/deparker/revui/internal/deps"
 	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/worddiff"
 )
 
 var (
 	addedLineStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
 	removedLineStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	addedWordStyle     = addedLineStyle.Background(lipgloss.Color("22"))
+	removedWordStyle   = removedLineStyle.Background(lipgloss.Color("52"))
 	hunkHeaderStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("14")).Faint(true)
+	foldStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
+	dirtyTagStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	revisedTagStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("13"))
 	lineNoStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Width(6)
 	cursorStyle        = lipgloss.NewStyle().Bold(true)
 	cursorLineBg       = lipgloss.Color("236")
 	commentMarkerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	threadMarkerStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
 	visualSelectStyle  = lipgloss.NewStyle().Background(lipgloss.Color("238"))
 	sideSeparatorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 )
 
+// ageHeat colors context line numbers by how long ago the line was last
+// changed, from warm for fresh code to dim for old code.
+var ageHeat = []struct {
+	maxAge time.Duration
+	color  lipgloss.Color
+}{
+	{7 * 24 * time.Hour, "209"},
+	{30 * 24 * time.Hour, "215"},
+	{182 * 24 * time.Hour, "180"},
+	{365 * 24 * time.Hour, "144"},
+	{3 * 365 * 24 * time.Hour, "246"},
+}
+
+// ageHeatStyles holds the line number style for each ageHeat bucket, plus a
+// final one for anything older.
+var ageHeatStyles = func() []lipgloss.Style {
+	styles := make([]lipgloss.Style, 0, len(ageHeat)+1)
+	for _, h := range ageHeat {
+		styles = append(styles, lineNoStyle.Foreground(h.color))
+	}
+	return append(styles, lineNoStyle.Foreground(lipgloss.Color("238")))
+}()
+
 // emptyStyle is a reusable zero-value style to avoid allocating lipgloss.NewStyle() per call.
 var emptyStyle = lipgloss.NewStyle()
 
@@ -48,36 +83,276 @@ func formatLineNo(lineNo int) string {
 	return string(buf[:])
 }
 
-// navigateFileMsg signals that the diff viewer has hit a boundary and wants to
-// navigate to the next or previous file.
+const (
+	// cursorPrefixWidth is the width of the "→ " / "  " prefix on every row.
+	cursorPrefixWidth = 2
+	// unifiedGutterWidth covers both line number columns, the comment marker,
+	// and the +/-/space sign in unified mode.
+	unifiedGutterWidth = 6 + 6 + 2 + 1
+	// sideGutterWidth covers one line number column and the sign in side-by-side mode.
+	sideGutterWidth = 6 + 1
+	// hScrollStep is how many columns each horizontal scroll key moves.
+	hScrollStep = 8
+	// tabWidth matches lipgloss's default tab expansion.
+	tabWidth = 4
+)
+
+// clipContent expands tabs, hides the first offset display columns, and
+// truncates the remainder to width columns. Hidden text on either side is
+// marked with "…" so long lines never wrap and break the layout.
+func clipContent(s string, offset, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if strings.IndexByte(s, '\t') >= 0 {
+		s = strings.ReplaceAll(s, "\t", strings.Repeat(" ", tabWidth))
+	}
+	// Fast path: byte length bounds display width for the common ASCII case.
+	if offset == 0 && len(s) <= width {
+		return s
+	}
+	if offset > 0 {
+		if runewidth.StringWidth(s) <= offset {
+			return ""
+		}
+		s = runewidth.TruncateLeft(s, offset+1, "…")
+	}
+	if runewidth.StringWidth(s) > width {
+		s = runewidth.Truncate(s, width, "…")
+	}
+	return s
+}
+
+// clipSpans clips content as clipContent does, and returns the ranges of the
+// clipped text that spans, ranges of content, cover.
+func clipSpans(content string, spans []worddiff.Span, offset, width int) (string, []worddiff.Span) {
+	text := clipContent(content, offset, width)
+	if text == "" {
+		return "", nil
+	}
+	expanded := strings.ReplaceAll(content, "\t", strings.Repeat(" ", tabWidth))
+	expand := func(i int) int { return i + (tabWidth-1)*strings.Count(content[:i], "\t") }
+
+	// text is expanded[from:to], after lead bytes of "…" when scrolled,
+	// and a space more when that cut a wide character in half, and before
+	// a "…" when cut short.
+	shown, from, lead := expanded, 0, 0
+	if offset > 0 {
+		rest := runewidth.TruncateLeft(expanded, offset+1, "")
+		from = len(expanded) - len(rest)
+		if expanded[from:] != rest {
+			from++ // rest starts with the space standing in for half a character
+			lead++
+		}
+		shown = "…" + rest
+		lead += len("…")
+	}
+	to := len(expanded)
+	if text != shown {
+		to = from + len(text) - len("…") - lead
+	}
+	if to <= from {
+		return text, nil
+	}
+
+	var out []worddiff.Span
+	for _, sp := range spans {
+		start, end := max(expand(sp.Start), from), min(expand(sp.End), to)
+		if start < end {
+			out = append(out, worddiff.Span{Start: lead + start - from, End: lead + end - from})
+		}
+	}
+	return text, out
+}
+
+// renderSpans renders text in style, with the ranges spans of it in emph.
+func renderSpans(text string, spans []worddiff.Span, style, emph lipgloss.Style) string {
+	var b strings.Builder
+	pos := 0
+	for _, sp := range spans {
+		if sp.Start > pos {
+			b.WriteString(style.Render(text[pos:sp.Start]))
+		}
+		b.WriteString(emph.Render(text[sp.Start:sp.End]))
+		pos = sp.End
+	}
+	if pos < len(text) {
+		b.WriteString(style.Render(text[pos:]))
+	}
+	return b.String()
+}
+
+// wordCache holds the words that changed within a diff's paired removed and
+// added lines, worked out as each line is first drawn, since diffing every
+// line of a large diff up front would hold up opening it. It's shared by
+// copies of the DiffViewer, which View is called on.
+type wordCache struct {
+	diff     *git.FileDiff
+	partners map[int]map[*git.Line]*git.Line // by hunk, the line each changed line is paired with
+	spans    map[*git.Line][]worddiff.Span
+}
+
+func newWordCache(fd *git.FileDiff) *wordCache {
+	return &wordCache{
+		diff:     fd,
+		partners: make(map[int]map[*git.Line]*git.Line),
+		spans:    make(map[*git.Line][]worddiff.Span),
+	}
+}
+
+// changed returns the words of l, a line of the given hunk, that differ from
+// the line it's paired with as side-by-side mode shows them.
+func (wc *wordCache) changed(hunk int, l *git.Line) []worddiff.Span {
+	if wc == nil || wc.diff == nil || hunk >= len(wc.diff.Hunks) {
+		return nil
+	}
+	if spans, ok := wc.spans[l]; ok {
+		return spans
+	}
+	partners, ok := wc.partners[hunk]
+	if !ok {
+		partners = make(map[*git.Line]*git.Line)
+		for _, p := range BuildSideBySidePairs(wc.diff.Hunks[hunk].Lines) {
+			if p.Left != nil && p.Right != nil && p.Left != p.Right {
+				partners[p.Left], partners[p.Right] = p.Right, p.Left
+			}
+		}
+		wc.partners[hunk] = partners
+	}
+	other := partners[l]
+	if other == nil {
+		wc.spans[l] = nil
+		return nil
+	}
+	if l.Type == git.LineRemoved {
+		wc.spans[l], wc.spans[other] = worddiff.Diff(l.Content, other.Content)
+	} else {
+		wc.spans[other], wc.spans[l] = worddiff.Diff(other.Content, l.Content)
+	}
+	return wc.spans[l]
+}
+
+// renderChanged renders a changed line of the given hunk, its sign and its
+// content clipped to width, in style, with the words that differ from the
+// line it's paired with in emph.
+func (dv DiffViewer) renderChanged(hunk int, l *git.Line, prefix string, width int, style, emph lipgloss.Style) string {
+	spans := dv.words.changed(hunk, l)
+	if spans == nil {
+		return style.Render(prefix + clipContent(l.Content, dv.hOffset, width))
+	}
+	text, spans := clipSpans(l.Content, spans, dv.hOffset, width)
+	return style.Render(prefix) + renderSpans(text, spans, style, emph)
+}
+
+// navigateFileMsg signals that the diff viewer wants to navigate to the next
+// or previous file, either because a jump hit a boundary or on ctrl+n/ctrl+p.
 type navigateFileMsg struct {
-	direction int // +1 for next, -1 for prev
+	direction int  // +1 for next, -1 for prev
+	fromTop   bool // start at the top of the file even when moving backwards
+}
+
+// gotoLineMsg asks to move the cursor to a new-file line, typed as a count
+// before G.
+type gotoLineMsg struct {
+	line int
+}
+
+// linesChangedMsg signals that the flattened line layout changed (e.g. a fold
+// was expanded) and index-based state such as comment markers must be rebuilt.
+type linesChangedMsg struct{}
+
+const (
+	// contextFoldThreshold is the length above which a run of context lines
+	// inside a hunk is collapsed into a single fold row.
+	contextFoldThreshold = 8
+	// contextFoldMargin is the number of context lines kept visible next to a change.
+	contextFoldMargin = 3
+
+	// maxGotoLine caps a typed line number.
+	maxGotoLine = 1_000_000
+)
+
+// foldKey identifies a collapsed context run by hunk index and the new-file
+// line number of its first hidden line, so it stays stable across view modes.
+type foldKey struct {
+	hunk      int
+	newLineNo int
 }
 
-// diffLine is a flattened line for display, which can be a hunk header or a code line.
+// diffSide identifies a column in side-by-side mode.
+type diffSide int
+
+const (
+	sideRight diffSide = iota // new file (default)
+	sideLeft                  // old file
+)
+
+// diffLine is a flattened row for display, which can be a hunk header, a
+// collapsed run of context lines, or a code line. In side-by-side mode a code
+// row carries the paired left (old) and right (new) lines, and line points at
+// the side that comments target.
 type diffLine struct {
 	isHunkHeader bool
 	hunkHeader   string
+	hunk         int
 	line         *git.Line
+	left         *git.Line
+	right        *git.Line
+	folded       int // number of hidden context lines for a fold row, 0 otherwise
+	fold         foldKey
+}
+
+// isContext reports whether the row is a plain context line.
+func (dl diffLine) isContext() bool {
+	return dl.line != nil && dl.line.Type == git.LineContext
+}
+
+// contains reports whether the row holds the given line on any side.
+func (dl diffLine) contains(l *git.Line) bool {
+	return l != nil && (dl.line == l || dl.left == l || dl.right == l)
+}
+
+// commentLineNo returns the line number a comment on l refers to: the old line
+// number for removed lines and the new line number otherwise.
+func commentLineNo(l *git.Line) int {
+	if l.Type == git.LineRemoved {
+		return l.OldLineNo
+	}
+	return l.NewLineNo
 }
 
 // DiffViewer is a Bubble Tea sub-model for displaying file diffs.
 type DiffViewer struct {
 	diff             *git.FileDiff
-	lines            []diffLine
-	cursor           int
-	offset           int // scroll offset
+	lines            []diffLine // one logical diff line each, however it is displayed
+	cursor           int        // index into lines, not a screen row
+	offset           int        // scroll offset
 	width            int
 	height           int
 	focused          bool
 	commentLines     map[int]bool // lines with comments (by flattened index)
+	threadLines      map[int]bool // lines with comments from the hosting service
 	visualMode       bool
-	visualStart      int
+	visualStart      int // index into lines, like cursor
 	sideBySide       bool
 	searchTerm       string
 	searchMatches    []int
 	pendingBracket   rune // for ]c / [c sequences
 	preBracketCursor int  // cursor position before bracket hunk jump
+	expanded         map[foldKey]bool
+	activeSide       diffSide // focused column in side-by-side mode
+	hOffset          int      // horizontal scroll offset for code content
+	ageSource        func(path string) map[int]time.Time
+	ages             map[int]time.Time // author time of old-side lines, for age indicators
+	agesAt           time.Time         // when ages were loaded, the reference for their heat
+	count            int               // line number typed before G; 0 if none
+	words            *wordCache        // words changed within paired removed and added lines
+	depSummary       *deps.Summary     // the dependencies a manifest's diff changes; nil for other files
+	rawDeps          bool              // show a manifest's diff instead of its summary
+	generatedSource  func(path string) (generated, set bool)
+	generated        string                            // why the diff is collapsed as generated code; empty once shown
+	contentSource    func(path string) (string, error) // reads a file's new side to show it whole; nil shows only the hunks
+	changes          *git.FileDiff                     // the diff as set, before the rest of the file is filled in
 }
 
 // NewDiffViewer creates a new diff viewer.
@@ -91,10 +366,18 @@ func NewDiffViewer(width, height int) DiffViewer {
 
 // SetDiff sets the diff content to display.
 func (dv *DiffViewer) SetDiff(fd *git.FileDiff) {
-	dv.diff = fd
+	dv.changes = fd
+	dv.diff, _ = dv.wholeFile(fd)
 	dv.cursor = 0
 	dv.offset = 0
+	dv.hOffset = 0
+	dv.expanded = nil
+	dv.rawDeps = false
+	dv.summarizeDeps()
+	dv.generated = dv.generatedReason()
 	dv.lines = dv.flattenLines()
+	dv.words = newWordCache(fd)
+	dv.loadAges()
 }
 
 // RefreshDiff updates the diff content while preserving cursor and scroll position.
@@ -103,10 +386,14 @@ func (dv *DiffViewer) SetDiff(fd *git.FileDiff) {
 // are no longer valid after a refresh. Search matches are recomputed if there's an
 // active search term.
 func (dv *DiffViewer) RefreshDiff(fd *git.FileDiff) {
-	dv.diff = fd
+	dv.changes = fd
+	dv.diff, _ = dv.wholeFile(fd)
+	dv.summarizeDeps()
 	dv.lines = dv.flattenLines()
+	dv.words = newWordCache(fd)
 	dv.visualMode = false
 	dv.pendingBracket = 0
+	dv.loadAges()
 
 	// Recompute search matches if there's an active search
 	dv.computeMatches()
@@ -129,6 +416,80 @@ func (dv *DiffViewer) RefreshDiff(fd *git.FileDiff) {
 	dv.adjustScroll()
 }
 
+// SetContentSource sets the function that reads a file's new side, to show
+// files whole with their changes in place, or nil to show only the hunks, and
+// reshows the current diff with the cursor on the same line. It returns why
+// the current file couldn't be shown whole, if it couldn't.
+func (dv *DiffViewer) SetContentSource(source func(path string) (string, error)) error {
+	dv.contentSource = source
+	lineNo := dv.CurrentLineNo()
+	var err error
+	dv.diff, err = dv.wholeFile(dv.changes)
+	dv.expanded = nil
+	dv.visualMode = false
+	dv.lines = dv.flattenLines()
+	dv.words = newWordCache(dv.diff)
+	dv.computeMatches()
+	dv.cursor, dv.offset = 0, 0
+	if lineNo > 0 {
+		dv.GotoLine(lineNo)
+	}
+	return err
+}
+
+// wholeFile returns fd filled out to the whole file when there is a content
+// source, or else fd. Files with no new side, or no lines, are left as is.
+func (dv *DiffViewer) wholeFile(fd *git.FileDiff) (*git.FileDiff, error) {
+	if dv.contentSource == nil || fd == nil || len(fd.Hunks) == 0 || fd.Status == "D" || fd.Status == "B" {
+		return fd, nil
+	}
+	content, err := dv.contentSource(fd.Path)
+	if err != nil {
+		return fd, err
+	}
+	return git.FullFile(fd, content), nil
+}
+
+// ShowingWholeFiles reports whether files are shown whole rather than as
+// hunks.
+func (dv DiffViewer) ShowingWholeFiles() bool {
+	return dv.contentSource != nil
+}
+
+// SetAgeSource sets the function that provides line ages for a file, or nil
+// to turn age indicators off, and reloads the ages of the current diff.
+func (dv *DiffViewer) SetAgeSource(source func(path string) map[int]time.Time) {
+	dv.ageSource = source
+	dv.loadAges()
+}
+
+func (dv *DiffViewer) loadAges() {
+	dv.ages = nil
+	if dv.ageSource != nil && dv.diff != nil {
+		dv.ages = dv.ageSource(dv.diff.Path)
+		dv.agesAt = time.Now()
+	}
+}
+
+// ageStyle returns the line number style for a context line colored by how
+// long ago it was last changed, or false if its age is unknown.
+func (dv DiffViewer) ageStyle(l *git.Line) (lipgloss.Style, bool) {
+	if dv.ages == nil || l.Type != git.LineContext {
+		return lipgloss.Style{}, false
+	}
+	t, ok := dv.ages[l.OldLineNo]
+	if !ok {
+		return lipgloss.Style{}, false
+	}
+	age := dv.agesAt.Sub(t)
+	for i, h := range ageHeat {
+		if age < h.maxAge {
+			return ageHeatStyles[i], true
+		}
+	}
+	return ageHeatStyles[len(ageHeat)], true
+}
+
 // SetCursorToEnd positions the cursor at the last line and scrolls to show it.
 func (dv *DiffViewer) SetCursorToEnd() {
 	if len(dv.lines) > 0 {
@@ -142,8 +503,39 @@ func (dv *DiffViewer) SetCommentLines(lines map[int]bool) {
 	dv.commentLines = lines
 }
 
+// SetThreadLines updates which lines have comments already on the pull
+// request, marked apart from the reviewer's own.
+func (dv *DiffViewer) SetThreadLines(lines map[int]bool) {
+	dv.threadLines = lines
+}
+
+// marker renders the comment marker column of line idx: the reviewer's own
+// comment, else another reviewer's thread, else blank.
+func (dv DiffViewer) marker(idx int, highlight bool) string {
+	var marker string
+	switch {
+	case dv.commentLines[idx]:
+		marker = commentMarkerStyle.Render("●")
+		if highlight {
+			marker = commentMarkerStyle.Background(cursorLineBg).Render("●")
+		}
+	case dv.threadLines[idx]:
+		marker = threadMarkerStyle.Render("◆")
+		if highlight {
+			marker = threadMarkerStyle.Background(cursorLineBg).Render("◆")
+		}
+	default:
+		marker = " "
+	}
+	marker += " "
+	if highlight {
+		return emptyStyle.Background(cursorLineBg).Render(marker)
+	}
+	return marker
+}
+
 func (dv *DiffViewer) flattenLines() []diffLine {
-	if dv.diff == nil {
+	if dv.diff == nil || dv.IsLFS() || dv.ShowingDeps() || dv.generated != "" {
 		return nil
 	}
 	// Pre-compute total capacity: one header per hunk plus all lines
@@ -152,20 +544,221 @@ func (dv *DiffViewer) flattenLines() []diffLine {
 		total += 1 + len(h.Lines)
 	}
 	result := make([]diffLine, 0, total)
-	for _, h := range dv.diff.Hunks {
+	for hi := range dv.diff.Hunks {
+		h := &dv.diff.Hunks[hi]
 		result = append(result, diffLine{
 			isHunkHeader: true,
 			hunkHeader:   h.Header,
+			hunk:         hi,
 		})
-		for i := range h.Lines {
-			result = append(result, diffLine{
-				line: &h.Lines[i],
-			})
+		rows := dv.hunkRows(hi, h)
+		if dv.contentSource != nil {
+			// The whole file was asked for, so none of it is folded.
+			result = append(result, rows...)
+			continue
+		}
+		for i := 0; i < len(rows); {
+			if !rows[i].isContext() {
+				result = append(result, rows[i])
+				i++
+				continue
+			}
+			j := i
+			for j < len(rows) && rows[j].isContext() {
+				j++
+			}
+			result = appendContextRun(result, rows[i:j], i == 0, j == len(rows), dv.expanded)
+			i = j
 		}
 	}
 	return result
 }
 
+// hunkRows returns the display rows for a hunk: one per line in unified mode,
+// or one per aligned LinePair in side-by-side mode.
+func (dv *DiffViewer) hunkRows(hunk int, h *git.Hunk) []diffLine {
+	if !dv.sideBySide {
+		rows := make([]diffLine, len(h.Lines))
+		for i := range h.Lines {
+			rows[i] = diffLine{line: &h.Lines[i], hunk: hunk}
+		}
+		return rows
+	}
+	pairs := BuildSideBySidePairs(h.Lines)
+	rows := make([]diffLine, len(pairs))
+	for i, p := range pairs {
+		primary := p.Right
+		if primary == nil {
+			primary = p.Left
+		}
+		rows[i] = diffLine{line: primary, left: p.Left, right: p.Right, hunk: hunk}
+	}
+	return rows
+}
+
+// appendContextRun appends a run of context rows, collapsing the middle of the
+// run into a fold row when it is long and not expanded. Rows adjacent to a
+// change are always kept visible.
+func appendContextRun(result, run []diffLine, atStart, atEnd bool, expanded map[foldKey]bool) []diffLine {
+	lead, trail := contextFoldMargin, contextFoldMargin
+	if atStart {
+		lead = 0
+	}
+	if atEnd {
+		trail = 0
+	}
+	hidden := len(run) - lead - trail
+	if len(run) <= contextFoldThreshold || hidden <= 0 {
+		return append(result, run...)
+	}
+	key := foldKey{hunk: run[0].hunk, newLineNo: run[lead].line.NewLineNo}
+	if expanded[key] {
+		return append(result, run...)
+	}
+	result = append(result, run[:lead]...)
+	result = append(result, diffLine{hunk: run[0].hunk, folded: hidden, fold: key})
+	return append(result, run[len(run)-trail:]...)
+}
+
+// toggleSideBySide switches between unified and side-by-side layouts, keeping
+// the cursor on the same line.
+func (dv *DiffViewer) toggleSideBySide() {
+	var cur diffLine
+	if dl := dv.lineAt(dv.cursor); dl != nil {
+		cur = *dl
+	}
+	dv.sideBySide = !dv.sideBySide
+	dv.lines = dv.flattenLines()
+	dv.visualMode = false
+	dv.computeMatches()
+	dv.cursor = dv.rowIndex(cur)
+	dv.adjustScroll()
+}
+
+// rowIndex finds the index of the row corresponding to a row from a previous
+// layout, matching code lines by identity and headers and folds by hunk.
+func (dv *DiffViewer) rowIndex(target diffLine) int {
+	for _, l := range [...]*git.Line{target.line, target.left} {
+		if l == nil {
+			continue
+		}
+		for i, dl := range dv.lines {
+			if dl.contains(l) {
+				return i
+			}
+		}
+	}
+	for i, dl := range dv.lines {
+		if target.folded > 0 && dl.folded > 0 && dl.fold == target.fold {
+			return i
+		}
+		if target.folded == 0 && dl.isHunkHeader && dl.hunk == target.hunk {
+			return i
+		}
+	}
+	return 0
+}
+
+// expandFold reveals the collapsed context run under the cursor.
+// Returns false if the cursor is not on a fold row.
+func (dv *DiffViewer) expandFold() bool {
+	dl := dv.lineAt(dv.cursor)
+	if dl == nil || dl.folded == 0 {
+		return false
+	}
+	if dv.expanded == nil {
+		dv.expanded = make(map[foldKey]bool)
+	}
+	dv.expanded[dl.fold] = true
+	dv.lines = dv.flattenLines()
+	dv.computeMatches()
+	return true
+}
+
+// SetGeneratedSource sets the lookup of whether .gitattributes marks a path
+// as generated, or nil if there's none.
+func (dv *DiffViewer) SetGeneratedSource(source func(path string) (generated, set bool)) {
+	dv.generatedSource = source
+}
+
+// HidesGenerated reports whether the diff is of generated code, collapsed
+// until enter shows it.
+func (dv DiffViewer) HidesGenerated() bool {
+	return dv.generated != ""
+}
+
+// generatedReason returns why the diff is of generated code, going by the
+// linguist-generated attribute if it's given, and otherwise a marker such as
+// "Code generated ... DO NOT EDIT." in the file's header; empty if it isn't.
+func (dv DiffViewer) generatedReason() string {
+	if dv.diff == nil {
+		return ""
+	}
+	if dv.generatedSource != nil {
+		if generated, set := dv.generatedSource(dv.diff.Path); set {
+			if generated {
+				return "linguist-generated"
+			}
+			return ""
+		}
+	}
+	if dv.diff.HasGeneratedMarker() {
+		return "marked generated in its header"
+	}
+	return ""
+}
+
+// IsLFS reports whether the diff is of a Git LFS pointer file, which is shown
+// as a summary of the objects instead of the pointer text.
+func (dv DiffViewer) IsLFS() bool {
+	_, _, ok := dv.lfsPointers()
+	return ok
+}
+
+func (dv DiffViewer) lfsPointers() (old, new *git.LFSPointer, ok bool) {
+	if dv.diff == nil {
+		return nil, nil, false
+	}
+	return dv.diff.LFSPointers()
+}
+
+// lfsView summarizes the objects behind an LFS pointer diff.
+func lfsView(old, new *git.LFSPointer) string {
+	side := func(label string, p *git.LFSPointer) string {
+		if p == nil {
+			return "  " + label + "  (none)\n"
+		}
+		return fmt.Sprintf("  %s  %s  %s\n", label, p.OID, formatSize(p.Size))
+	}
+	return "Git LFS object\n\n" + side("old", old) + side("new", new) +
+		"\nf fetches the objects and shows their diff"
+}
+
+// formatSize renders a byte count in the largest whole unit.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// dirtyHunkTag marks hunks that include uncommitted working tree changes.
+const dirtyHunkTag = " [uncommitted]"
+
+// revisedHunkTag marks hunks that changed since the previous review session.
+const revisedHunkTag = " [changed since last review]"
+
+// foldLabel returns the text displayed for a collapsed context run.
+func foldLabel(n int) string {
+	return "··· " + strconv.Itoa(n) + " unchanged lines ···"
+}
+
 // Init returns no initial command.
 func (dv DiffViewer) Init() tea.Cmd {
 	return nil
@@ -194,7 +787,15 @@ func (dv DiffViewer) Update(msg tea.Msg) (DiffViewer, tea.Cmd) {
 			dv.pendingBracket = 0
 		}
 
+		// A line number applies only to the G typed right after it
+		count := dv.count
+		dv.count = 0
+
 		switch key {
+		case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if d := int(msg.Runes[0] - '0'); d > 0 || count > 0 {
+				dv.count = min(count*10+d, maxGotoLine)
+			}
 		case "j", "down":
 			if dv.cursor < len(dv.lines)-1 {
 				dv.cursor++
@@ -206,6 +807,9 @@ func (dv DiffViewer) Update(msg tea.Msg) (DiffViewer, tea.Cmd) {
 				dv.adjustScroll()
 			}
 		case "G":
+			if count > 0 {
+				return dv, func() tea.Msg { return gotoLineMsg{line: count} }
+			}
 			dv.cursor = len(dv.lines) - 1
 			dv.adjustScroll()
 		case "g":
@@ -235,6 +839,10 @@ func (dv DiffViewer) Update(msg tea.Msg) (DiffViewer, tea.Cmd) {
 				dv.cursor = 0
 			}
 			dv.adjustScroll()
+		case "ctrl+n":
+			return dv, func() tea.Msg { return navigateFileMsg{direction: 1, fromTop: true} }
+		case "ctrl+p":
+			return dv, func() tea.Msg { return navigateFileMsg{direction: -1, fromTop: true} }
 		case "}":
 			if !dv.jumpToNextHunk() {
 				return dv, func() tea.Msg { return navigateFileMsg{direction: 1} }
@@ -250,10 +858,25 @@ func (dv DiffViewer) Update(msg tea.Msg) (DiffViewer, tea.Cmd) {
 				dv.visualMode = true
 				dv.visualStart = dv.cursor
 			}
+		case "right", ">":
+			dv.hOffset += hScrollStep
+		case "left", "<":
+			dv.hOffset = max(0, dv.hOffset-hScrollStep)
 		case "esc":
 			dv.visualMode = false
+		case "enter":
+			if dv.generated != "" {
+				dv.generated = ""
+				dv.lines = dv.flattenLines()
+				dv.computeMatches()
+				return dv, func() tea.Msg { return linesChangedMsg{} }
+			}
+			if dv.expandFold() {
+				return dv, func() tea.Msg { return linesChangedMsg{} }
+			}
 		case "tab":
-			dv.sideBySide = !dv.sideBySide
+			dv.toggleSideBySide()
+			return dv, func() tea.Msg { return linesChangedMsg{} }
 		case "]":
 			dv.preBracketCursor = dv.cursor
 			if !dv.jumpToNextChange() {
@@ -358,6 +981,15 @@ func (dv DiffViewer) View() string {
 	if dv.diff != nil && dv.diff.Status == "B" {
 		return "Binary file — cannot display diff"
 	}
+	if old, new, ok := dv.lfsPointers(); ok {
+		return lfsView(old, new)
+	}
+	if dv.ShowingDeps() {
+		return depsView(*dv.depSummary, dv.width)
+	}
+	if dv.generated != "" {
+		return "Generated file (" + dv.generated + ") — press enter to view"
+	}
 	if dv.diff == nil || len(dv.lines) == 0 {
 		return "No diff to display. Select a file."
 	}
@@ -386,10 +1018,32 @@ func (dv DiffViewer) View() string {
 
 		var line string
 		if dl.isHunkHeader {
+			headerWidth := dv.width - cursorPrefixWidth
+			dirty := dv.diff.Hunks[dl.hunk].Dirty
+			if dirty {
+				headerWidth -= len(dirtyHunkTag)
+			}
+			revised := dv.diff.Hunks[dl.hunk].Revised
+			if revised {
+				headerWidth -= len(revisedHunkTag)
+			}
+			header := clipContent(dl.hunkHeader, 0, headerWidth)
 			if isCursor {
-				line = hunkHeaderStyle.Background(cursorLineBg).Render(dl.hunkHeader)
+				line = hunkHeaderStyle.Background(cursorLineBg).Render(header)
 			} else {
-				line = hunkHeaderStyle.Render(dl.hunkHeader)
+				line = hunkHeaderStyle.Render(header)
+			}
+			if dirty {
+				line += dirtyTagStyle.Render(dirtyHunkTag)
+			}
+			if revised {
+				line += revisedTagStyle.Render(revisedHunkTag)
+			}
+		} else if dl.folded > 0 {
+			if isCursor {
+				line = foldStyle.Background(cursorLineBg).Render(foldLabel(dl.folded))
+			} else {
+				line = foldStyle.Render(foldLabel(dl.folded))
 			}
 		} else if dv.sideBySide {
 			line = dv.renderSideBySideLine(dl, i, isCursor)
@@ -426,6 +1080,9 @@ func (dv DiffViewer) renderCodeLine(dl diffLine, idx int, highlight bool) string
 	l := dl.line
 
 	lnStyle := lineNoStyle
+	if s, ok := dv.ageStyle(l); ok {
+		lnStyle = s
+	}
 	addStyle := addedLineStyle
 	rmStyle := removedLineStyle
 	if highlight {
@@ -438,39 +1095,25 @@ func (dv DiffViewer) renderCodeLine(dl diffLine, idx int, highlight bool) string
 	newNo := formatLineNo(l.NewLineNo)
 	gutter := lnStyle.Render(oldNo) + lnStyle.Render(newNo)
 
-	var marker string
-	if dv.commentLines[idx] {
-		mStyle := commentMarkerStyle
-		if highlight {
-			mStyle = mStyle.Background(cursorLineBg)
-		}
-		if highlight {
-			bgStyle := emptyStyle.Background(cursorLineBg)
-			marker = bgStyle.Render(mStyle.Render("●") + " ")
-		} else {
-			marker = mStyle.Render("●") + " "
-		}
-	} else {
-		if highlight {
-			bgStyle := emptyStyle.Background(cursorLineBg)
-			marker = bgStyle.Render("  ")
-		} else {
-			marker = "  "
-		}
-	}
+	marker := dv.marker(idx, highlight)
+
+	// A combined diff of a merge has one marker column per parent
+	prefix := l.Marker()
+	width := dv.width - cursorPrefixWidth - unifiedGutterWidth - (len(prefix) - 1)
 
 	var content string
 	switch l.Type {
 	case git.LineAdded:
-		content = addStyle.Render("+" + l.Content)
+		content = dv.renderChanged(dl.hunk, l, prefix, width, addStyle, addedWordStyle)
 	case git.LineRemoved:
-		content = rmStyle.Render("-" + l.Content)
+		content = dv.renderChanged(dl.hunk, l, prefix, width, rmStyle, removedWordStyle)
 	default:
+		text := clipContent(l.Content, dv.hOffset, width)
 		if highlight {
 			bgStyle := emptyStyle.Background(cursorLineBg)
-			content = bgStyle.Render(" " + l.Content)
+			content = bgStyle.Render(prefix + text)
 		} else {
-			content = " " + l.Content
+			content = prefix + text
 		}
 	}
 
@@ -481,50 +1124,61 @@ func (dv DiffViewer) renderCodeLine(dl diffLine, idx int, highlight bool) string
 const emptyLineNoPad = "      " // 6 spaces
 
 func (dv DiffViewer) renderSideBySideLine(dl diffLine, idx int, highlight bool) string {
-	l := dl.line
-	halfWidth := dv.width / 2
+	// Each column gets half of what remains after the cursor prefix, the
+	// comment marker, and the separator.
+	halfWidth := (dv.width - cursorPrefixWidth - 3) / 2
 
-	lnStyle := lineNoStyle
-	addStyle := addedLineStyle
-	rmStyle := removedLineStyle
 	sepStyle := sideSeparatorStyle
 	if highlight {
-		lnStyle = lnStyle.Background(cursorLineBg)
-		addStyle = addStyle.Background(cursorLineBg)
-		rmStyle = rmStyle.Background(cursorLineBg)
 		sepStyle = sepStyle.Background(cursorLineBg)
 	}
 
-	var markerSection string
-	if dv.commentLines[idx] {
-		mStyle := commentMarkerStyle
-		if highlight {
-			mStyle = mStyle.Background(cursorLineBg)
-			bgStyle := emptyStyle.Background(cursorLineBg)
-			markerSection = bgStyle.Render(mStyle.Render("●") + " ")
-		} else {
-			markerSection = mStyle.Render("●") + " "
-		}
-	} else {
-		if highlight {
-			bgStyle := emptyStyle.Background(cursorLineBg)
-			markerSection = bgStyle.Render("  ")
-		} else {
-			markerSection = "  "
-		}
-	}
+	markerSection := dv.marker(idx, highlight)
 
 	sep := sepStyle.Render("│")
 
-	padToWidth := func(s string, w int) string {
-		visible := lipgloss.Width(s)
-		if visible < w {
-			pad := strings.Repeat(" ", w-visible)
-			if highlight {
-				bgStyle := emptyStyle.Background(cursorLineBg)
-				return s + bgStyle.Render(pad)
+	// renderSide renders one column; a nil line leaves the column blank. Only
+	// the active column of the cursor row gets the cursor background.
+	renderSide := func(l *git.Line, lineNo int, hl bool) string {
+		lnStyle := lineNoStyle
+		if l != nil {
+			if s, ok := dv.ageStyle(l); ok {
+				lnStyle = s
+			}
+		}
+		addStyle := addedLineStyle
+		rmStyle := removedLineStyle
+		bgStyle := emptyStyle
+		if hl {
+			lnStyle = lnStyle.Background(cursorLineBg)
+			addStyle = addStyle.Background(cursorLineBg)
+			rmStyle = rmStyle.Background(cursorLineBg)
+			bgStyle = bgStyle.Background(cursorLineBg)
+		}
+		renderBg := func(s string) string {
+			if hl {
+				return bgStyle.Render(s)
+			}
+			return s
+		}
+
+		var s string
+		if l == nil {
+			s = renderBg(emptyLineNoPad)
+		} else {
+			gutter := lnStyle.Render(formatLineNo(lineNo))
+			width := halfWidth - sideGutterWidth
+			switch l.Type {
+			case git.LineRemoved:
+				s = gutter + dv.renderChanged(dl.hunk, l, "-", width, rmStyle, removedWordStyle)
+			case git.LineAdded:
+				s = gutter + dv.renderChanged(dl.hunk, l, "+", width, addStyle, addedWordStyle)
+			default:
+				s = gutter + renderBg(" "+clipContent(l.Content, dv.hOffset, width))
 			}
-			return s + pad
+		}
+		if visible := lipgloss.Width(s); visible < halfWidth {
+			s += renderBg(strings.Repeat(" ", halfWidth-visible))
 		}
 		return s
 	}
@@ -532,49 +1186,17 @@ func (dv DiffViewer) renderSideBySideLine(dl diffLine, idx int, highlight bool)
 	var b strings.Builder
 	b.Grow(256)
 
-	// renderBg applies background styling only when highlight is active.
-	renderBg := func(s string) string {
-		if highlight {
-			return emptyStyle.Background(cursorLineBg).Render(s)
-		}
-		return s
+	var oldNo, newNo int
+	if dl.left != nil {
+		oldNo = dl.left.OldLineNo
 	}
-
-	switch l.Type {
-	case git.LineRemoved:
-		oldNo := formatLineNo(l.OldLineNo)
-		leftGutter := lnStyle.Render(oldNo)
-		leftContent := rmStyle.Render("-" + l.Content)
-		left := padToWidth(leftGutter+leftContent, halfWidth)
-		right := padToWidth(renderBg(emptyLineNoPad), halfWidth)
-		b.WriteString(left)
-		b.WriteString(markerSection)
-		b.WriteString(sep)
-		b.WriteString(right)
-
-	case git.LineAdded:
-		left := padToWidth(renderBg(emptyLineNoPad), halfWidth)
-		newNo := formatLineNo(l.NewLineNo)
-		rightGutter := lnStyle.Render(newNo)
-		rightContent := addStyle.Render("+" + l.Content)
-		right := padToWidth(rightGutter+rightContent, halfWidth)
-		b.WriteString(left)
-		b.WriteString(markerSection)
-		b.WriteString(sep)
-		b.WriteString(right)
-
-	default: // context
-		oldNo := formatLineNo(l.OldLineNo)
-		newNo := formatLineNo(l.NewLineNo)
-		leftGutter := lnStyle.Render(oldNo)
-		left := padToWidth(leftGutter+renderBg(" "+l.Content), halfWidth)
-		rightGutter := lnStyle.Render(newNo)
-		right := padToWidth(rightGutter+renderBg(" "+l.Content), halfWidth)
-		b.WriteString(left)
-		b.WriteString(markerSection)
-		b.WriteString(sep)
-		b.WriteString(right)
+	if dl.right != nil {
+		newNo = dl.right.NewLineNo
 	}
+	b.WriteString(renderSide(dl.left, oldNo, highlight && dv.activeSide == sideLeft))
+	b.WriteString(markerSection)
+	b.WriteString(sep)
+	b.WriteString(renderSide(dl.right, newNo, highlight && dv.activeSide == sideRight))
 
 	return b.String()
 }
@@ -585,11 +1207,43 @@ func (dv DiffViewer) CursorLine() int {
 }
 
 // CurrentLine returns the git.Line at the cursor, or nil if on a hunk header.
+// In side-by-side mode this is the line in the active column.
 func (dv DiffViewer) CurrentLine() *git.Line {
-	if dv.cursor >= 0 && dv.cursor < len(dv.lines) {
-		return dv.lines[dv.cursor].line
+	return dv.targetLine(dv.cursor)
+}
+
+// targetLine returns the line that selection and commenting act on for the row
+// at idx. In side-by-side mode it prefers the active column, falling back to
+// the other side when the active column is blank.
+func (dv DiffViewer) targetLine(idx int) *git.Line {
+	dl := dv.lineAt(idx)
+	if dl == nil {
+		return nil
 	}
-	return nil
+	if dv.sideBySide {
+		if dv.activeSide == sideLeft && dl.left != nil {
+			return dl.left
+		}
+		if dv.activeSide == sideRight && dl.right != nil {
+			return dl.right
+		}
+	}
+	return dl.line
+}
+
+// SetActiveSide focuses the left (old) or right (new) column in side-by-side
+// mode. Returns false if side-by-side mode is off or the column is already active.
+func (dv *DiffViewer) SetActiveSide(side diffSide) bool {
+	if !dv.sideBySide || dv.activeSide == side {
+		return false
+	}
+	dv.activeSide = side
+	return true
+}
+
+// ActiveSide returns the focused column in side-by-side mode.
+func (dv DiffViewer) ActiveSide() diffSide {
+	return dv.activeSide
 }
 
 // CurrentLineNo returns the relevant line number for commenting (new line for added/context, old for removed).
@@ -598,10 +1252,115 @@ func (dv DiffViewer) CurrentLineNo() int {
 	if l == nil {
 		return 0
 	}
-	if l.Type == git.LineRemoved {
-		return l.OldLineNo
+	return commentLineNo(l)
+}
+
+// CurrentFunction returns the enclosing function git reported in the header
+// of the cursor's hunk, or "" if there is none.
+func (dv DiffViewer) CurrentFunction() string {
+	dl := dv.lineAt(dv.cursor)
+	if dl == nil || dv.diff == nil || dl.hunk >= len(dv.diff.Hunks) {
+		return ""
 	}
-	return l.NewLineNo
+	return hunkContext(dv.diff.Hunks[dl.hunk].Header)
+}
+
+// CurrentHunk returns the index of the hunk under the cursor, or -1 if there
+// is none.
+func (dv DiffViewer) CurrentHunk() int {
+	dl := dv.lineAt(dv.cursor)
+	if dl == nil || dv.diff == nil || dl.hunk >= len(dv.diff.Hunks) {
+		return -1
+	}
+	return dl.hunk
+}
+
+// JumpToHunk moves the cursor to the header of the given hunk.
+func (dv *DiffViewer) JumpToHunk(hunk int) {
+	for i, dl := range dv.lines {
+		if dl.isHunkHeader && dl.hunk == hunk {
+			dv.cursor = i
+			dv.adjustScroll()
+			return
+		}
+	}
+}
+
+// GotoLine moves the cursor to new-file line n, expanding a fold that hides
+// it, and scrolls it to the middle of the view. If the diff doesn't show line
+// n, the cursor moves to the nearest line it does show and GotoLine returns
+// false.
+func (dv *DiffViewer) GotoLine(n int) bool {
+	for _, dl := range dv.lines {
+		if dl.folded > 0 && n >= dl.fold.newLineNo && n < dl.fold.newLineNo+dl.folded {
+			if dv.expanded == nil {
+				dv.expanded = make(map[foldKey]bool)
+			}
+			dv.expanded[dl.fold] = true
+			dv.lines = dv.flattenLines()
+			dv.computeMatches()
+			break
+		}
+	}
+
+	best, bestDist := -1, 0
+	for i, dl := range dv.lines {
+		l := dl.line
+		if dl.right != nil {
+			l = dl.right
+		}
+		if l == nil || l.Type == git.LineRemoved {
+			continue
+		}
+		if d := max(l.NewLineNo-n, n-l.NewLineNo); best < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	if best < 0 {
+		return false
+	}
+	dv.cursor = best
+	dv.activeSide = sideRight
+	dv.offset = max(0, min(best-dv.height/2, len(dv.lines)-dv.height))
+	return bestDist == 0
+}
+
+// Diff returns the file diff being displayed, or nil.
+func (dv DiffViewer) Diff() *git.FileDiff {
+	return dv.diff
+}
+
+// PatchHunks returns the hunks to export as a patch: in visual mode, the
+// hunks the selection overlaps trimmed to the selected changes, otherwise the
+// hunk under the cursor. It returns nil if nothing is selected.
+func (dv DiffViewer) PatchHunks() []git.Hunk {
+	if dv.diff == nil {
+		return nil
+	}
+	if !dv.visualMode {
+		dl := dv.lineAt(dv.cursor)
+		if dl == nil || dl.hunk >= len(dv.diff.Hunks) {
+			return nil
+		}
+		return []git.Hunk{dv.diff.Hunks[dl.hunk]}
+	}
+
+	start, end := dv.VisualRange()
+	selected := make(map[*git.Line]bool)
+	for i := start; i <= end; i++ {
+		if dl := dv.lineAt(i); dl != nil {
+			selected[dl.line] = true
+			selected[dl.left] = true
+			selected[dl.right] = true
+		}
+	}
+	var hunks []git.Hunk
+	for _, h := range dv.diff.Hunks {
+		if sub, ok := h.Subset(func(l *git.Line) bool { return selected[l] }); ok {
+			hunks = append(hunks, sub)
+		}
+	}
+	return hunks
 }
 
 // SetSize updates the dimensions.
@@ -625,14 +1384,11 @@ func (dv DiffViewer) lineAt(idx int) *diffLine {
 
 // LineNoAt returns the relevant line number at the given flattened index.
 func (dv DiffViewer) LineNoAt(idx int) int {
-	dl := dv.lineAt(idx)
-	if dl == nil || dl.line == nil {
+	l := dv.targetLine(idx)
+	if l == nil {
 		return 0
 	}
-	if dl.line.Type == git.LineRemoved {
-		return dl.line.OldLineNo
-	}
-	return dl.line.NewLineNo
+	return commentLineNo(l)
 }
 
 // ExitVisualMode exits visual mode.
@@ -645,7 +1401,10 @@ func (dv DiffViewer) InVisualMode() bool {
 	return dv.visualMode
 }
 
-// VisualRange returns the start and end of the visual selection.
+// VisualRange returns the start and end of the visual selection as indices
+// into the logical diff lines. Rendering is the only place that maps lines to
+// screen rows, so a selection, and a comment made from it, always covers
+// whole lines even if their display is clipped or scrolled.
 func (dv DiffViewer) VisualRange() (int, int) {
 	start, end := dv.visualStart, dv.cursor
 	if start > end {
@@ -654,6 +1413,13 @@ func (dv DiffViewer) VisualRange() (int, int) {
 	return start, end
 }
 
+// SetSideBySide switches to or from side-by-side mode.
+func (dv *DiffViewer) SetSideBySide(on bool) {
+	if dv.sideBySide != on {
+		dv.toggleSideBySide()
+	}
+}
+
 // IsSideBySide returns whether side-by-side mode is active.
 func (dv DiffViewer) IsSideBySide() bool {
 	return dv.sideBySide
@@ -666,7 +1432,11 @@ func (dv *DiffViewer) computeMatches() {
 		return
 	}
 	for i, dl := range dv.lines {
-		if dl.line != nil && strings.Contains(dl.line.Content, dv.searchTerm) {
+		if dl.line == nil {
+			continue
+		}
+		if strings.Contains(dl.line.Content, dv.searchTerm) ||
+			(dl.left != nil && dl.left != dl.line && strings.Contains(dl.left.Content, dv.searchTerm)) {
 			dv.searchMatches = append(dv.searchMatches, i)
 		}
 	}
@@ -719,7 +1489,7 @@ func (dv *DiffViewer) jumpToPrevSearch() {
 
 func (dv *DiffViewer) jumpToNextComment() {
 	for i := dv.cursor + 1; i < len(dv.lines); i++ {
-		if dv.commentLines[i] {
+		if dv.commentLines[i] || dv.threadLines[i] {
 			dv.cursor = i
 			dv.adjustScroll()
 			return
@@ -729,7 +1499,7 @@ func (dv *DiffViewer) jumpToNextComment() {
 
 func (dv *DiffViewer) jumpToPrevComment() {
 	for i := dv.cursor - 1; i >= 0; i-- {
-		if dv.commentLines[i] {
+		if dv.commentLines[i] || dv.threadLines[i] {
 			dv.cursor = i
 			dv.adjustScroll()
 			return
@@ -1,12 +1,17 @@
 package ui
 
 import (
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 
+	"github.com/deparker/revui/internal/difftest"
 	"github.com/deparker/revui/internal/git"
+	"github.com/deparker/revui/internal/worddiff"
 )
 
 func makeTestDiff() *git.FileDiff {
@@ -84,6 +89,19 @@ func TestDiffViewRenderNotEmpty(t *testing.T) {
 	}
 }
 
+func TestDiffViewCombinedMarkers(t *testing.T) {
+	diffs, err := git.ParseDiff("diff --cc f\n--- a/f\n+++ b/f\n@@@ -1,1 -1,1 +1,2 @@@\n  a\n+ merged\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dv := NewDiffViewer(80, 20)
+	dv.SetDiff(&diffs[0])
+
+	if view := dv.View(); !strings.Contains(view, "+ merged") {
+		t.Errorf("view should show one marker column per parent, got:\n%s", view)
+	}
+}
+
 func TestDiffViewNoDiff(t *testing.T) {
 	dv := NewDiffViewer(80, 20)
 	view := dv.View()
@@ -118,6 +136,27 @@ func TestDiffViewVisualMode(t *testing.T) {
 	}
 }
 
+func TestDiffViewVisualModeLongLines(t *testing.T) {
+	fd := makeTestDiff()
+	for i := range fd.Hunks[0].Lines {
+		fd.Hunks[0].Lines[i].Content += strings.Repeat(" long", 40)
+	}
+	dv := NewDiffViewer(30, 20)
+	dv.SetDiff(fd)
+
+	// Clipped and scrolled lines still move and select one diff line at a time
+	for _, k := range []rune{'j', '>', 'v', 'j'} {
+		dv, _ = dv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+	}
+	start, end := dv.VisualRange()
+	if start != 1 || end != 2 {
+		t.Errorf("range = %d-%d, want 1-2", start, end)
+	}
+	if got, want := dv.LineNoAt(end), dv.LineNoAt(start)+1; got != want {
+		t.Errorf("selection ends at line %d, want %d", got, want)
+	}
+}
+
 func TestDiffViewCommentNavigation(t *testing.T) {
 	dv := NewDiffViewer(80, 20)
 	dv.SetDiff(makeTestDiff())
@@ -393,11 +432,12 @@ func BenchmarkRenderCodeLine(b *testing.B) {
 
 func BenchmarkRenderSideBySideLine(b *testing.B) {
 	dv := NewDiffViewer(120, 40)
+	dv.sideBySide = true
 	dv.SetDiff(makeTestDiff())
-	dl := dv.lines[1]
+	dl := dv.lines[2] // removed/added pair
 	b.ResetTimer()
 	for b.Loop() {
-		dv.renderSideBySideLine(dl, 1, false)
+		dv.renderSideBySideLine(dl, 2, false)
 	}
 }
 
@@ -419,6 +459,41 @@ func BenchmarkView(b *testing.B) {
 	}
 }
 
+// BenchmarkViewLarge opens each file of a generated 50k-line diff, and of
+// any diffs captured into difftest's testdata, and renders its top and
+// bottom, unified and side by side.
+func BenchmarkViewLarge(b *testing.B) {
+	cases, err := difftest.Cases(difftest.Dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	bottom := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'G'}}
+	for _, c := range cases {
+		diffs, err := git.ParseDiff(c.Diff)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, sideBySide := range []bool{false, true} {
+			name := c.Name + "/unified"
+			if sideBySide {
+				name = c.Name + "/side-by-side"
+			}
+			b.Run(name, func(b *testing.B) {
+				for b.Loop() {
+					for i := range diffs {
+						dv := NewDiffViewer(120, 40)
+						dv.SetSideBySide(sideBySide)
+						dv.SetDiff(&diffs[i])
+						dv.View()
+						dv, _ = dv.Update(bottom)
+						dv.View()
+					}
+				}
+			})
+		}
+	}
+}
+
 func TestDiffViewRefreshDiff(t *testing.T) {
 	dv := NewDiffViewer(80, 20)
 	dv.SetDiff(makeTestDiff())
@@ -468,3 +543,405 @@ func TestDiffViewRefreshDiff(t *testing.T) {
 		}
 	})
 }
+
+func makeLongContextDiff() *git.FileDiff {
+	lines := []git.Line{{Content: "removed", Type: git.LineRemoved, OldLineNo: 1}}
+	for i := 0; i < 20; i++ {
+		lines = append(lines, git.Line{Content: "ctx", Type: git.LineContext, OldLineNo: i + 2, NewLineNo: i + 1})
+	}
+	lines = append(lines, git.Line{Content: "added", Type: git.LineAdded, NewLineNo: 21})
+	return &git.FileDiff{
+		Path:   "long.go",
+		Status: "M",
+		Hunks: []git.Hunk{
+			{Header: "@@ -1,21 +1,21 @@", OldStart: 1, OldCount: 21, NewStart: 1, NewCount: 21, Lines: lines},
+		},
+	}
+}
+
+func TestDiffViewFoldsLongContext(t *testing.T) {
+	dv := NewDiffViewer(80, 40)
+	dv.SetDiff(makeLongContextDiff())
+
+	// header + removed + 3 ctx + fold + 3 ctx + added
+	if dv.TotalLines() != 10 {
+		t.Fatalf("TotalLines() = %d, want 10", dv.TotalLines())
+	}
+	fold := dv.lineAt(5)
+	if fold == nil || fold.folded != 14 {
+		t.Fatalf("expected fold row of 14 lines at index 5, got %+v", fold)
+	}
+	if !strings.Contains(dv.View(), "··· 14 unchanged lines ···") {
+		t.Error("expected fold label in view")
+	}
+
+	// Enter on a non-fold row does nothing
+	_, cmd := dv.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Error("enter off a fold row should not produce a command")
+	}
+
+	dv.cursor = 5
+	dv, cmd = dv.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expanding a fold should signal a layout change")
+	}
+	if _, ok := cmd().(linesChangedMsg); !ok {
+		t.Error("expected linesChangedMsg")
+	}
+	if dv.TotalLines() != 23 {
+		t.Errorf("after expand: TotalLines() = %d, want 23", dv.TotalLines())
+	}
+}
+
+func TestDiffViewShortContextNotFolded(t *testing.T) {
+	dv := NewDiffViewer(80, 20)
+	dv.SetDiff(makeTestDiff())
+	for i := 0; i < dv.TotalLines(); i++ {
+		if dv.lineAt(i).folded > 0 {
+			t.Fatalf("unexpected fold row at %d", i)
+		}
+	}
+}
+
+func TestDiffViewSideBySideAlignsPairs(t *testing.T) {
+	dv := NewDiffViewer(80, 20)
+	dv.SetDiff(makeTestDiff())
+
+	// Move to "new line" (unified index 3), then switch to side-by-side
+	for range 3 {
+		dv, _ = dv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	}
+	dv, cmd := dv.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if cmd == nil {
+		t.Fatal("toggling layout should signal a layout change")
+	}
+
+	// header, context, old|new pair, -|another new, context
+	if dv.TotalLines() != 5 {
+		t.Fatalf("TotalLines() = %d, want 5", dv.TotalLines())
+	}
+	pair := dv.lineAt(2)
+	if pair.left == nil || pair.left.Content != "old line" || pair.right == nil || pair.right.Content != "new line" {
+		t.Errorf("row 2 should pair old/new lines, got left=%v right=%v", pair.left, pair.right)
+	}
+	if dv.CursorLine() != 2 {
+		t.Errorf("cursor = %d, want 2 (same line after toggle)", dv.CursorLine())
+	}
+	if dv.CurrentLineNo() != 2 {
+		t.Errorf("CurrentLineNo() = %d, want 2 (new side)", dv.CurrentLineNo())
+	}
+
+	view := dv.View()
+	for _, line := range strings.Split(view, "\n") {
+		if strings.Contains(line, "old line") && !strings.Contains(line, "new line") {
+			t.Error("removed line and its replacement should render on the same row")
+		}
+	}
+
+	// Toggling back restores the cursor on the unified line
+	dv, _ = dv.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if dv.CursorLine() != 3 {
+		t.Errorf("after toggle back: cursor = %d, want 3", dv.CursorLine())
+	}
+}
+
+func TestDiffViewSideBySideActiveColumn(t *testing.T) {
+	dv := NewDiffViewer(80, 20)
+	dv.SetDiff(makeTestDiff())
+
+	if dv.SetActiveSide(sideLeft) {
+		t.Error("SetActiveSide should be a no-op in unified mode")
+	}
+
+	dv, _ = dv.Update(tea.KeyMsg{Type: tea.KeyTab})
+	dv.cursor = 2 // old line | new line
+
+	if got := dv.CurrentLine().Content; got != "new line" {
+		t.Errorf("right column: CurrentLine = %q, want %q", got, "new line")
+	}
+	if !dv.SetActiveSide(sideLeft) {
+		t.Fatal("SetActiveSide(sideLeft) should switch columns")
+	}
+	if got := dv.CurrentLine().Content; got != "old line" {
+		t.Errorf("left column: CurrentLine = %q, want %q", got, "old line")
+	}
+	if dv.CurrentLineNo() != 2 {
+		t.Errorf("left column: CurrentLineNo = %d, want old line 2", dv.CurrentLineNo())
+	}
+
+	// Row with a blank left column falls back to the right side
+	dv.cursor = 3
+	if got := dv.CurrentLine().Content; got != "another new" {
+		t.Errorf("blank column fallback: CurrentLine = %q, want %q", got, "another new")
+	}
+}
+
+func TestClipContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		offset int
+		width  int
+		want   string
+	}{
+		{name: "fits", in: "short", width: 10, want: "short"},
+		{name: "truncated", in: "abcdefghij", width: 5, want: "abcd…"},
+		{name: "scrolled", in: "abcdefghij", offset: 4, width: 10, want: "…fghij"},
+		{name: "scrolled and truncated", in: "abcdefghij", offset: 2, width: 4, want: "…de…"},
+		{name: "scrolled past end", in: "abc", offset: 8, width: 10, want: ""},
+		{name: "tabs expanded", in: "\tx", width: 10, want: "    x"},
+		{name: "wide runes", in: "日本語テキスト", width: 6, want: "日本…"},
+		{name: "zero width", in: "abc", width: 0, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clipContent(tt.in, tt.offset, tt.width); got != tt.want {
+				t.Errorf("clipContent(%q, %d, %d) = %q, want %q", tt.in, tt.offset, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipSpans(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		span   worddiff.Span
+		offset int
+		width  int
+		want   []string // the clipped text the span covers
+	}{
+		{name: "fits", in: "return s.serve(ctx)", span: worddiff.Span{Start: 15, End: 18}, width: 80, want: []string{"ctx"}},
+		{name: "after a tab", in: "\treturn x", span: worddiff.Span{Start: 8, End: 9}, width: 80, want: []string{"x"}},
+		{name: "scrolled", in: "abcdefghij", span: worddiff.Span{Start: 5, End: 7}, offset: 4, width: 10, want: []string{"fg"}},
+		{name: "scrolled past the span", in: "abcdefghij", span: worddiff.Span{Start: 0, End: 3}, offset: 4, width: 10},
+		{name: "cut short", in: "abcdefghij", span: worddiff.Span{Start: 3, End: 8}, width: 5, want: []string{"d"}},
+		{name: "half a wide rune scrolled off", in: "日本語abc", span: worddiff.Span{Start: 6, End: 9}, offset: 2, width: 10, want: []string{"語"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, spans := clipSpans(tt.in, []worddiff.Span{tt.span}, tt.offset, tt.width)
+			if want := clipContent(tt.in, tt.offset, tt.width); text != want {
+				t.Errorf("text = %q, want %q as clipContent clips it", text, want)
+			}
+			var got []string
+			for _, sp := range spans {
+				got = append(got, text[sp.Start:sp.End])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("spans cover %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffViewWordChanges(t *testing.T) {
+	dv := NewDiffViewer(80, 20)
+	fd := makeTestDiff()
+	dv.SetDiff(fd)
+	lines := fd.Hunks[0].Lines
+	// "old line" is paired with "new line"; "another new" has no pair
+	if got := dv.words.changed(0, &lines[1]); !slices.Equal(got, []worddiff.Span{{Start: 0, End: 3}}) {
+		t.Errorf("removed line's changed words = %v", got)
+	}
+	if got := dv.words.changed(0, &lines[2]); !slices.Equal(got, []worddiff.Span{{Start: 0, End: 3}}) {
+		t.Errorf("added line's changed words = %v", got)
+	}
+	if got := dv.words.changed(0, &lines[3]); got != nil {
+		t.Errorf("unpaired line has changed words %v", got)
+	}
+
+	for _, sideBySide := range []bool{false, true} {
+		dv.SetSideBySide(sideBySide)
+		view := dv.View()
+		for _, want := range []string{removedWordStyle.Render("old"), addedWordStyle.Render("new")} {
+			if !strings.Contains(view, want) {
+				t.Errorf("side by side %v: %q missing from\n%s", sideBySide, want, view)
+			}
+		}
+	}
+}
+
+func TestDiffViewLongLinesDoNotOverflow(t *testing.T) {
+	fd := makeTestDiff()
+	fd.Hunks[0].Lines[2].Content = strings.Repeat("x", 300)
+
+	for _, sideBySide := range []bool{false, true} {
+		dv := NewDiffViewer(60, 20)
+		dv.focused = true
+		dv.sideBySide = sideBySide
+		dv.SetDiff(fd)
+		for i, line := range strings.Split(strings.TrimRight(dv.View(), "\n"), "\n") {
+			if w := lipgloss.Width(line); w > 60 {
+				t.Errorf("sideBySide=%v: row %d is %d columns wide, want <= 60", sideBySide, i, w)
+			}
+		}
+		if !strings.Contains(dv.View(), "…") {
+			t.Errorf("sideBySide=%v: expected truncation indicator", sideBySide)
+		}
+	}
+}
+
+func TestDiffViewHorizontalScroll(t *testing.T) {
+	dv := NewDiffViewer(80, 20)
+	dv.SetDiff(makeTestDiff())
+
+	dv, _ = dv.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if dv.hOffset != hScrollStep {
+		t.Errorf("after right: hOffset = %d, want %d", dv.hOffset, hScrollStep)
+	}
+	dv, _ = dv.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	dv, _ = dv.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	if dv.hOffset != 0 {
+		t.Errorf("after left twice: hOffset = %d, want 0", dv.hOffset)
+	}
+}
+
+func TestDiffViewAgeStyle(t *testing.T) {
+	dv := NewDiffViewer(80, 20)
+	fd := makeTestDiff()
+	now := time.Now()
+	dv.SetAgeSource(func(path string) map[int]time.Time {
+		return map[int]time.Time{
+			1: now.Add(-2 * 24 * time.Hour),
+			2: now,
+			3: now.Add(-10 * 365 * 24 * time.Hour),
+		}
+	})
+	dv.SetDiff(fd)
+
+	lines := fd.Hunks[0].Lines
+	tests := []struct {
+		name   string
+		line   *git.Line
+		want   int // index into ageHeatStyles
+		wantOK bool
+	}{
+		{name: "fresh context", line: &lines[0], want: 0, wantOK: true},
+		{name: "old context", line: &lines[4], want: len(ageHeat), wantOK: true},
+		{name: "removed line", line: &lines[1], wantOK: false},
+		{name: "added line", line: &lines[2], wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style, ok := dv.ageStyle(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("ageStyle ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && style.GetForeground() != ageHeatStyles[tt.want].GetForeground() {
+				t.Errorf("foreground = %v, want %v", style.GetForeground(), ageHeatStyles[tt.want].GetForeground())
+			}
+		})
+	}
+
+	dv.SetAgeSource(nil)
+	if _, ok := dv.ageStyle(&lines[0]); ok {
+		t.Error("clearing the age source should turn indicators off")
+	}
+}
+
+func TestDiffViewGotoLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		diff       *git.FileDiff
+		sideBySide bool
+		line       int
+		wantExact  bool
+		wantLine   int
+		wantTotal  int
+	}{
+		{name: "added line", diff: makeTestDiff(), line: 3, wantExact: true, wantLine: 3, wantTotal: 6},
+		{name: "context line", diff: makeTestDiff(), line: 4, wantExact: true, wantLine: 4, wantTotal: 6},
+		{name: "side by side", diff: makeTestDiff(), sideBySide: true, line: 2, wantExact: true, wantLine: 2, wantTotal: 5},
+		{name: "past the diff", diff: makeTestDiff(), line: 100, wantExact: false, wantLine: 4, wantTotal: 6},
+		{name: "inside a fold", diff: makeLongContextDiff(), line: 10, wantExact: true, wantLine: 10, wantTotal: 23},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dv := NewDiffViewer(80, 20)
+			dv.SetDiff(tt.diff)
+			if tt.sideBySide {
+				dv.toggleSideBySide()
+			}
+			if got := dv.GotoLine(tt.line); got != tt.wantExact {
+				t.Errorf("GotoLine(%d) = %v, want %v", tt.line, got, tt.wantExact)
+			}
+			if got := dv.CurrentLineNo(); got != tt.wantLine {
+				t.Errorf("CurrentLineNo() = %d, want %d", got, tt.wantLine)
+			}
+			if got := dv.TotalLines(); got != tt.wantTotal {
+				t.Errorf("TotalLines() = %d, want %d", got, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestDiffViewCountG(t *testing.T) {
+	dv := NewDiffViewer(80, 20)
+	dv.SetDiff(makeTestDiff())
+
+	for _, r := range "12" {
+		dv, _ = dv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	_, cmd := dv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'G'}})
+	if cmd == nil {
+		t.Fatal("expected a command for 12G")
+	}
+	if msg, ok := cmd().(gotoLineMsg); !ok || msg.line != 12 {
+		t.Errorf("12G sent %#v, want gotoLineMsg{line: 12}", cmd())
+	}
+
+	// Another key in between drops the count, so G goes to the end
+	for _, r := range "3jG" {
+		dv, cmd = dv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if cmd != nil {
+		t.Error("G after a dropped count should not send a command")
+	}
+	if dv.CursorLine() != dv.TotalLines()-1 {
+		t.Errorf("cursor = %d, want the last line", dv.CursorLine())
+	}
+}
+
+func TestDiffViewGenerated(t *testing.T) {
+	generatedDiff := func() *git.FileDiff {
+		fd := makeTestDiff()
+		fd.Hunks[0].Lines[0].Content = "// Code generated by stringer. DO NOT EDIT."
+		return fd
+	}
+	attr := func(generated bool) func(string) (bool, bool) {
+		return func(string) (bool, bool) { return generated, true }
+	}
+	tests := []struct {
+		name   string
+		fd     *git.FileDiff
+		source func(string) (bool, bool)
+		hidden bool
+	}{
+		{name: "marker", fd: generatedDiff(), hidden: true},
+		{name: "attribute", fd: makeTestDiff(), source: attr(true), hidden: true},
+		{name: "attribute unset", fd: generatedDiff(), source: attr(false)},
+		{name: "plain", fd: makeTestDiff()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dv := NewDiffViewer(80, 20)
+			dv.SetGeneratedSource(tt.source)
+			dv.SetDiff(tt.fd)
+			if dv.HidesGenerated() != tt.hidden {
+				t.Fatalf("HidesGenerated() = %v, want %v", dv.HidesGenerated(), tt.hidden)
+			}
+			if !tt.hidden {
+				return
+			}
+			if !strings.Contains(dv.View(), "press enter to view") || dv.TotalLines() != 0 {
+				t.Errorf("a generated diff should be collapsed:\n%s", dv.View())
+			}
+			dv, cmd := dv.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			if dv.HidesGenerated() || dv.TotalLines() == 0 || cmd == nil {
+				t.Error("enter should show the diff")
+			}
+		})
+	}
+}
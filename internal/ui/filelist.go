@@ -1,11 +1,16 @@
 package ui
 
 import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/deparker/revui/internal/comment"
 	"github.com/deparker/revui/internal/git"
 )
 
@@ -17,21 +22,81 @@ var (
 	statusModifiedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
 	statusDeletedStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
 	statusBinaryStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
+	progressStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	seenStyle              = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	dirHeaderStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 )
 
+// verdictMarks follow the names of files given a verdict.
+var verdictMarks = map[comment.FileVerdict]string{
+	comment.FileLGTM:        lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Render("✔"),
+	comment.FileNeedsWork:   lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render("✘"),
+	comment.FileNotReviewed: lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("∅"),
+}
+
+// severityStyles tint the marker of a file with comments by the most severe
+// of them.
+var severityStyles = map[comment.Severity]lipgloss.Style{
+	comment.SeverityNone:    lipgloss.NewStyle().Foreground(lipgloss.Color("6")),
+	comment.SeverityNit:     lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+	comment.SeverityIssue:   lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+	comment.SeverityBlocker: lipgloss.NewStyle().Foreground(lipgloss.Color("1")),
+}
+
+// fileSort is the order of the file list.
+type fileSort int
+
+const (
+	sortPath      fileSort = iota // as git lists them, by path
+	sortStatus                    // added, modified, renamed, deleted, binary
+	sortChurn                     // most changed lines first
+	sortDirectory                 // grouped under directory headings
+	numFileSorts
+)
+
+var fileSortNames = [numFileSorts]string{"path", "status", "churn", "directory"}
+
+func (s fileSort) String() string {
+	return fileSortNames[s]
+}
+
+// parseFileSort returns the sort order with the given name.
+func parseFileSort(name string) (fileSort, error) {
+	for i, n := range fileSortNames {
+		if n == name {
+			return fileSort(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown file sort %q (want path, status, churn, or directory)", name)
+}
+
+// statusRank orders statuses for sortStatus.
+var statusRank = map[string]int{"A": 0, "C": 1, "M": 2, "R": 3, "D": 4, "B": 5}
+
 // FileList is a Bubble Tea sub-model for displaying changed files.
 type FileList struct {
-	files   []git.ChangedFile
-	cursor  int
-	focused bool
-	width   int
-	height  int
+	files    []git.ChangedFile // in display order
+	listed   []git.ChangedFile // in the order they were given
+	sort     fileSort
+	churn    map[string]int // changed lines per path, for sortChurn
+	cursor   int
+	offset   int // first file shown
+	focused  bool
+	width    int
+	height   int
+	progress map[string]int              // percentage of each opened file's hunks seen
+	count    int                         // file number typed before Enter or G; 0 if none
+	numbered bool                        // show each file's number
+	only     map[string]bool             // paths listed when reviewing a chunk; nil lists all
+	severity map[string]comment.Severity // most severe comment on each commented file
+	verdicts map[string]comment.FileVerdict
 }
 
 // NewFileList creates a new file list with the given changed files.
 func NewFileList(files []git.ChangedFile, width, height int) FileList {
 	return FileList{
 		files:  files,
+		listed: files,
 		cursor: 0,
 		width:  width,
 		height: height,
@@ -57,69 +122,215 @@ func (fl FileList) Update(msg tea.Msg) (FileList, tea.Cmd) {
 				fl.cursor--
 			}
 		case "G":
-			fl.cursor = len(fl.files) - 1
+			if n := fl.TakeCount(); n > 0 {
+				fl.Select(min(n, len(fl.files)) - 1)
+			} else {
+				fl.cursor = len(fl.files) - 1
+			}
+		case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if d := int(msg.Runes[0] - '0'); d > 0 || fl.count > 0 {
+				fl.count = min(fl.count*10+d, len(fl.files))
+			}
 		case "g":
 			// gg handled by root model tracking "g" prefix
 			fl.cursor = 0
 		}
+		fl.scroll()
 	}
 	return fl, nil
 }
 
-// View renders the file list.
+// TakeCount returns the file number typed so far, 1-based, and clears it. It
+// returns 0 if no number was typed.
+func (fl *FileList) TakeCount() int {
+	n := fl.count
+	fl.count = 0
+	return n
+}
+
+// isCountKey reports whether key types or uses a file number.
+func isCountKey(key string) bool {
+	return key == "G" || (len(key) == 1 && key[0] >= '0' && key[0] <= '9')
+}
+
+// SetNumbered sets whether each file's number is shown, for jumping to it by
+// typing the number and Enter.
+func (fl *FileList) SetNumbered(on bool) {
+	fl.numbered = on
+	fl.scroll()
+}
+
+// View renders the files that fit in the pane, followed by which of them are
+// shown when they don't all fit.
 func (fl FileList) View() string {
 	if len(fl.files) == 0 {
 		return "No changed files"
 	}
 
 	var b strings.Builder
-	for i, f := range fl.files {
-		icon := statusIcon(f.Status)
-
-		// Calculate available width for path (sidebar width - prefix length)
-		// Prefix: "▸ " (2) + icon (1) + " " (1) = 4 chars
-		availableWidth := max(1, fl.width-4)
-
-		// Create wrapping style for path
-		pathStyle := lipgloss.NewStyle().Width(availableWidth)
-		wrappedPath := pathStyle.Render(f.Path)
-
-		// Split into lines
-		lines := strings.Split(wrappedPath, "\n")
-
-		// Build the complete entry with proper prefixes
-		for lineIdx, pathLine := range lines {
-			var prefix string
-			if lineIdx == 0 {
-				// First line: add arrow/indent + icon + space
-				if i == fl.cursor {
-					prefix = "▸ " + icon + " "
-				} else {
-					prefix = "  " + icon + " "
-				}
+	h := fl.heights()
+	rows := fl.rows(h)
+	overflows := rows < fl.height
+	last := fl.offset - 1
+	for i := fl.offset; i < len(fl.files); i++ {
+		lines := fl.entryLines(i, i == fl.offset)
+		if rows -= len(lines); rows < 0 && i > fl.offset {
+			break
+		}
+		for _, line := range lines {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		last = i
+	}
+	if overflows {
+		b.WriteString(dirHeaderStyle.Render(fmt.Sprintf("%d–%d of %d", fl.offset+1, last+1, len(fl.files))))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// entryLines renders the file at index i, preceded by its directory heading
+// when grouping by directory and the file starts a group or the view.
+func (fl FileList) entryLines(i int, first bool) []string {
+	f := fl.files[i]
+	var out []string
+	icon := statusIcon(f.Status)
+	numberWidth := 0
+	if fl.numbered {
+		numberWidth = len(fmt.Sprint(len(fl.files))) + 1
+		icon = fmt.Sprintf("%*d ", numberWidth-1, i+1) + icon
+	}
+
+	// Grouped files are listed by name under their directory
+	name := f.Path
+	if fl.sort == sortDirectory {
+		prev := "."
+		if i > 0 && !first {
+			prev = path.Dir(fl.files[i-1].Path)
+		}
+		if d := path.Dir(f.Path); d != prev {
+			out = append(out, dirHeaderStyle.Render(clipContent(d+"/", 0, fl.width)))
+		}
+		name = path.Base(f.Path)
+	}
+	if f.From != "" {
+		name += " ← " + f.From
+	}
+
+	// Calculate available width for path (sidebar width - prefix length)
+	// Prefix: "▸ " (2) + icon (1) + " " (1) = 4 chars
+	availableWidth := max(1, fl.width-4-numberWidth)
+
+	// Files that have been opened show how much of them has been seen
+	pct, tracked := fl.progress[f.Path]
+	if tracked {
+		availableWidth = max(1, availableWidth-progressWidth)
+	}
+	severity, commented := fl.severity[f.Path]
+	if commented {
+		availableWidth = max(1, availableWidth-2)
+	}
+	verdict, judged := verdictMarks[fl.verdicts[f.Path]]
+	if judged {
+		availableWidth = max(1, availableWidth-2)
+	}
+
+	// Create wrapping style for path
+	pathStyle := lipgloss.NewStyle().Width(availableWidth)
+	wrappedPath := pathStyle.Render(name)
+
+	// Build the complete entry with proper prefixes
+	for lineIdx, pathLine := range strings.Split(wrappedPath, "\n") {
+		var prefix string
+		if lineIdx == 0 {
+			// First line: add arrow/indent + icon + space
+			if i == fl.cursor {
+				prefix = "▸ " + icon + " "
 			} else {
-				// Continuation lines: indent to align with path text
-				prefix = "    " // 4 spaces to align with first line text
+				prefix = "  " + icon + " "
 			}
+		} else {
+			prefix = strings.Repeat(" ", 4+numberWidth) // align with first line text
+		}
 
-			line := prefix + pathLine
+		line := prefix + pathLine
+		if lineIdx == 0 && commented {
+			line += " " + severityStyles[severity].Render("●")
+		}
+		if lineIdx == 0 && judged {
+			line += " " + verdict
+		}
+		if lineIdx == 0 && tracked {
+			line += formatProgress(pct)
+		}
 
-			// Apply selection styling
-			if i == fl.cursor {
-				if fl.focused {
-					line = selectedStyle.Render(line)
-				} else {
-					line = selectedUnfocusedStyle.Render(line)
-				}
+		// Apply selection styling
+		if i == fl.cursor {
+			if fl.focused {
+				line = selectedStyle.Render(line)
 			} else {
-				line = unselectedStyle.Render(line)
+				line = selectedUnfocusedStyle.Render(line)
 			}
+		} else {
+			line = unselectedStyle.Render(line)
+		}
+		out = append(out, line)
+	}
+	return out
+}
 
-			b.WriteString(line)
-			b.WriteByte('\n')
+// heights returns the rows each file's entry takes up when it isn't the first
+// one shown.
+func (fl FileList) heights() []int {
+	h := make([]int, len(fl.files))
+	for i := range fl.files {
+		h[i] = len(fl.entryLines(i, false))
+	}
+	return h
+}
+
+// span returns the rows files from through to take up when from is the first
+// shown, which repeats its directory heading if its group started above.
+func (fl FileList) span(h []int, from, to int) int {
+	n := 0
+	for i := from; i <= to; i++ {
+		n += h[i]
+	}
+	if fl.sort == sortDirectory && from > 0 {
+		if d := path.Dir(fl.files[from].Path); d != "." && d == path.Dir(fl.files[from-1].Path) {
+			n++
 		}
 	}
-	return b.String()
+	return n
+}
+
+// rows returns the rows available for files given their heights, leaving one
+// for the position indicator when they don't all fit.
+func (fl FileList) rows(h []int) int {
+	if fl.span(h, 0, len(h)-1) > fl.height {
+		return max(fl.height-1, 1)
+	}
+	return fl.height
+}
+
+// scroll moves the window of shown files as little as possible to keep the
+// selected one in view.
+func (fl *FileList) scroll() {
+	if len(fl.files) == 0 || fl.height <= 0 {
+		fl.offset = 0
+		return
+	}
+	h := fl.heights()
+	rows := fl.rows(h)
+	fl.offset = min(fl.offset, fl.cursor)
+	for fl.offset < fl.cursor && fl.span(h, fl.offset, fl.cursor) > rows {
+		fl.offset++
+	}
+	// Don't leave rows empty below the last file after the list shrinks
+	for fl.offset > 0 && fl.span(h, fl.offset-1, len(fl.files)-1) <= rows {
+		fl.offset--
+	}
 }
 
 // SelectedFile returns the currently selected file.
@@ -141,6 +352,7 @@ func (fl *FileList) SelectNext() bool {
 		return false
 	}
 	fl.cursor++
+	fl.scroll()
 	return true
 }
 
@@ -150,21 +362,74 @@ func (fl *FileList) SelectPrev() bool {
 		return false
 	}
 	fl.cursor--
+	fl.scroll()
 	return true
 }
 
+// progressWidth is the width of the seen percentage after a file's path.
+const progressWidth = 5
+
+// formatProgress renders a seen percentage, or a check mark once the whole
+// file has been seen.
+func formatProgress(pct int) string {
+	if pct >= 100 {
+		return seenStyle.Render(fmt.Sprintf("%*s", progressWidth, "✓"))
+	}
+	return progressStyle.Render(fmt.Sprintf("%*d%%", progressWidth-1, pct))
+}
+
+// SetCommentSeverities marks each file with comments, tinted by the most
+// severe of them.
+func (fl *FileList) SetCommentSeverities(comments []comment.Comment) {
+	fl.severity = make(map[string]comment.Severity)
+	for _, c := range comments {
+		if s, ok := fl.severity[c.FilePath]; !ok || c.Severity() > s {
+			fl.severity[c.FilePath] = c.Severity()
+		}
+	}
+}
+
+// SetVerdicts marks each file given a verdict with it.
+func (fl *FileList) SetVerdicts(verdicts map[string]comment.FileVerdict) {
+	fl.verdicts = verdicts
+}
+
+// SetProgress records the percentage of a file's hunks that have been seen.
+func (fl *FileList) SetProgress(path string, pct int) {
+	if fl.progress == nil {
+		fl.progress = make(map[string]int)
+	}
+	fl.progress[path] = pct
+}
+
+// Select moves the cursor to the file at index i, if it exists.
+func (fl *FileList) Select(i int) {
+	if i >= 0 && i < len(fl.files) {
+		fl.cursor = i
+		fl.scroll()
+	}
+}
+
+// Files returns the listed files.
+func (fl FileList) Files() []git.ChangedFile {
+	return fl.files
+}
+
 // SetSize updates the dimensions.
 func (fl *FileList) SetSize(width, height int) {
 	fl.width = width
 	fl.height = height
+	fl.scroll()
 }
 
 // SetFiles updates the file list, preserving the cursor on the same file path
 // if it still exists. If the selected file was removed, the cursor clamps to
 // the nearest valid index.
 func (fl *FileList) SetFiles(files []git.ChangedFile) {
+	defer fl.scroll()
 	if len(files) == 0 {
 		fl.files = files
+		fl.listed = files
 		fl.cursor = 0
 		return
 	}
@@ -175,7 +440,8 @@ func (fl *FileList) SetFiles(files []git.ChangedFile) {
 		selectedPath = fl.files[fl.cursor].Path
 	}
 
-	fl.files = files
+	fl.listed = files
+	fl.files = fl.sorted()
 
 	// Look for the same path in the new list
 	for i, f := range fl.files {
@@ -187,8 +453,76 @@ func (fl *FileList) SetFiles(files []git.ChangedFile) {
 
 	// File not found — clamp cursor
 	if fl.cursor >= len(fl.files) {
-		fl.cursor = len(fl.files) - 1
+		fl.cursor = max(len(fl.files)-1, 0)
+	}
+}
+
+// SetFilter limits the list to the given paths, keeping the cursor on the
+// selected file if it's still listed. nil lists every file.
+func (fl *FileList) SetFilter(paths []string) {
+	fl.only = nil
+	if paths != nil {
+		fl.only = make(map[string]bool, len(paths))
+		for _, p := range paths {
+			fl.only[p] = true
+		}
+	}
+	fl.SetFiles(fl.listed)
+}
+
+// Sort returns the file list's order.
+func (fl FileList) Sort() fileSort {
+	return fl.sort
+}
+
+// SetSort reorders the files, keeping the cursor on the selected file. churn
+// gives the changed lines of each path for sortChurn.
+func (fl *FileList) SetSort(s fileSort, churn map[string]int) {
+	fl.sort = s
+	fl.churn = churn
+	fl.SetFiles(fl.listed)
+}
+
+// sorted returns the listed files in the current sort order. Ties keep the
+// order they were listed in.
+func (fl FileList) sorted() []git.ChangedFile {
+	files := fl.listed
+	if fl.only != nil {
+		files = slices.DeleteFunc(slices.Clone(files), func(f git.ChangedFile) bool { return !fl.only[f.Path] })
+	}
+	if fl.sort == sortPath {
+		return files
+	}
+	files = slices.Clone(files)
+	slices.SortStableFunc(files, func(a, b git.ChangedFile) int {
+		switch fl.sort {
+		case sortStatus:
+			return cmp.Compare(rank(a.Status), rank(b.Status))
+		case sortChurn:
+			return cmp.Compare(fl.churn[b.Path], fl.churn[a.Path])
+		default:
+			// Top-level files first, then each directory's files together
+			return strings.Compare(groupDir(a.Path), groupDir(b.Path))
+		}
+	})
+	return files
+}
+
+// rank returns a status's position in sortStatus; unknown statuses go last.
+func rank(status string) int {
+	if r, ok := statusRank[status]; ok {
+		return r
+	}
+	return len(statusRank)
+}
+
+// groupDir returns the directory a file is grouped under, empty for files
+// at the top level so they sort first.
+func groupDir(p string) string {
+	if d := path.Dir(p); d != "." {
+		return d
 	}
+	return ""
 }
 
 func statusIcon(status string) string {
@@ -201,6 +535,8 @@ func statusIcon(status string) string {
 		return statusDeletedStyle.Render("D")
 	case "R":
 		return statusModifiedStyle.Render("R")
+	case "C":
+		return statusAddedStyle.Render("C")
 	case "B":
 		return statusBinaryStyle.Render("B")
 	default:
@@ -1,10 +1,15 @@
 package ui
 
 import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/deparker/revui/internal/comment"
 	"github.com/deparker/revui/internal/git"
 )
 
@@ -74,6 +79,51 @@ func TestFileListGAndGG(t *testing.T) {
 	}
 }
 
+func TestFileListCount(t *testing.T) {
+	var files []git.ChangedFile
+	for _, name := range "abcdefghijkl" {
+		files = append(files, git.ChangedFile{Path: string(name) + ".go", Status: "M"})
+	}
+
+	tests := []struct {
+		keys string
+		want int
+	}{
+		{keys: "3G", want: 2},
+		{keys: "12G", want: 11},
+		{keys: "99G", want: 11}, // clamped to the last file
+		{keys: "0G", want: 11},  // a leading 0 is not a number
+		{keys: "G", want: 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			fl := NewFileList(files, 20, 10)
+			for _, r := range tt.keys {
+				fl, _ = fl.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+			if fl.SelectedIndex() != tt.want {
+				t.Errorf("cursor = %d, want %d", fl.SelectedIndex(), tt.want)
+			}
+			if fl.TakeCount() != 0 {
+				t.Error("G should use up the count")
+			}
+		})
+	}
+}
+
+func TestFileListNumbered(t *testing.T) {
+	files := []git.ChangedFile{{Path: "a.go", Status: "M"}, {Path: "b.go", Status: "A"}}
+	fl := NewFileList(files, 30, 10)
+	if strings.Contains(fl.View(), "1 ") {
+		t.Error("files should not be numbered by default")
+	}
+	fl.SetNumbered(true)
+	lines := strings.Split(fl.View(), "\n")
+	if !strings.Contains(lines[0], "1 ") || !strings.Contains(lines[1], "2 ") {
+		t.Errorf("files should be numbered, got:\n%s", fl.View())
+	}
+}
+
 func TestFileListSetFiles(t *testing.T) {
 	files := []git.ChangedFile{
 		{Path: "a.go", Status: "M"},
@@ -239,3 +289,212 @@ func splitLines(s string) []string {
 	}
 	return lines
 }
+
+func TestFileListProgress(t *testing.T) {
+	files := []git.ChangedFile{
+		{Path: "a.go", Status: "M"},
+		{Path: "b.go", Status: "M"},
+		{Path: "c.go", Status: "M"},
+	}
+	fl := NewFileList(files, 30, 10)
+	fl.SetProgress("a.go", 50)
+	fl.SetProgress("b.go", 100)
+
+	lines := strings.Split(strings.TrimRight(fl.View(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	tests := []struct {
+		line int
+		want string
+		not  string
+	}{
+		{line: 0, want: "50%"},
+		{line: 1, want: "✓"},
+		{line: 2, not: "%"}, // never opened
+	}
+	for _, tt := range tests {
+		if tt.want != "" && !strings.Contains(lines[tt.line], tt.want) {
+			t.Errorf("line %d = %q, want it to contain %q", tt.line, lines[tt.line], tt.want)
+		}
+		if tt.not != "" && strings.Contains(lines[tt.line], tt.not) {
+			t.Errorf("line %d = %q, should not contain %q", tt.line, lines[tt.line], tt.not)
+		}
+	}
+}
+
+func TestFileListSort(t *testing.T) {
+	files := []git.ChangedFile{
+		{Path: "b/x.go", Status: "D"},
+		{Path: "a/y.go", Status: "M"},
+		{Path: "top.go", Status: "A"},
+		{Path: "b/z.go", Status: "M"},
+	}
+	churn := map[string]int{"b/x.go": 3, "a/y.go": 10, "top.go": 1, "b/z.go": 7}
+
+	tests := []struct {
+		sort fileSort
+		want []string
+	}{
+		{sortPath, []string{"b/x.go", "a/y.go", "top.go", "b/z.go"}},
+		{sortStatus, []string{"top.go", "a/y.go", "b/z.go", "b/x.go"}},
+		{sortChurn, []string{"a/y.go", "b/z.go", "b/x.go", "top.go"}},
+		{sortDirectory, []string{"top.go", "a/y.go", "b/x.go", "b/z.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort.String(), func(t *testing.T) {
+			fl := NewFileList(files, 30, 10)
+			fl.Select(3) // b/z.go
+			fl.SetSort(tt.sort, churn)
+
+			var got []string
+			for _, f := range fl.Files() {
+				got = append(got, f.Path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+			if sel := fl.SelectedFile().Path; sel != "b/z.go" {
+				t.Errorf("selected %q after sorting, want b/z.go", sel)
+			}
+		})
+	}
+}
+
+func TestFileListDirectoryHeadings(t *testing.T) {
+	fl := NewFileList([]git.ChangedFile{
+		{Path: "pkg/a.go", Status: "M"},
+		{Path: "main.go", Status: "M"},
+		{Path: "pkg/b.go", Status: "A"},
+	}, 30, 10)
+	fl.SetSort(sortDirectory, nil)
+
+	lines := strings.Split(strings.TrimRight(fl.View(), "\n"), "\n")
+	want := []string{"main.go", "pkg/", "a.go", "b.go"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines %q, want %d", len(lines), lines, len(want))
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], w) {
+			t.Errorf("line %d = %q, want it to contain %q", i, lines[i], w)
+		}
+	}
+	if strings.Contains(lines[2], "pkg/a.go") {
+		t.Error("grouped files should be listed by name")
+	}
+}
+
+func TestFileListScroll(t *testing.T) {
+	var files []git.ChangedFile
+	for i := range 10 {
+		files = append(files, git.ChangedFile{Path: fmt.Sprintf("f%d.go", i), Status: "M"})
+	}
+
+	tests := []struct {
+		name   string
+		moves  int // times j is pressed
+		height int
+		want   string // position indicator, "" for none
+		shown  []string
+		hidden []string
+	}{
+		{name: "fits", moves: 0, height: 10, want: "", shown: []string{"f0.go", "f9.go"}},
+		{name: "top", moves: 0, height: 4, want: "1–3 of 10", shown: []string{"f0.go", "f2.go"}, hidden: []string{"f3.go"}},
+		{name: "follows cursor", moves: 4, height: 4, want: "3–5 of 10", shown: []string{"f4.go"}, hidden: []string{"f1.go", "f5.go"}},
+		{name: "bottom", moves: 20, height: 4, want: "8–10 of 10", shown: []string{"f9.go"}, hidden: []string{"f6.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fl := NewFileList(files, 30, tt.height)
+			for range tt.moves {
+				fl, _ = fl.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+			}
+			view := fl.View()
+			if tt.want == "" {
+				if strings.Contains(view, " of 10") {
+					t.Errorf("unexpected position indicator in:\n%s", view)
+				}
+			} else if !strings.Contains(view, tt.want) {
+				t.Errorf("view missing %q:\n%s", tt.want, view)
+			}
+			for _, s := range tt.shown {
+				if !strings.Contains(view, s) {
+					t.Errorf("view missing %q:\n%s", s, view)
+				}
+			}
+			for _, s := range tt.hidden {
+				if strings.Contains(view, s) {
+					t.Errorf("view shows %q:\n%s", s, view)
+				}
+			}
+			if n := strings.Count(strings.TrimSuffix(view, "\n"), "\n") + 1; n > tt.height {
+				t.Errorf("view has %d lines, want at most %d", n, tt.height)
+			}
+		})
+	}
+
+	// Moving back up scrolls as little as needed
+	fl := NewFileList(files, 30, 4)
+	fl.Select(9)
+	fl.SelectPrev()
+	fl.SelectPrev()
+	fl.SelectPrev()
+	if view := fl.View(); !strings.Contains(view, "7–9 of 10") {
+		t.Errorf("view after moving up missing %q:\n%s", "7–9 of 10", view)
+	}
+}
+
+func TestFileListFilter(t *testing.T) {
+	fl := NewFileList(changedFiles("a.go", "b.go", "c.go", "d.go"), 30, 20)
+	fl.Select(2)
+
+	fl.SetFilter([]string{"c.go", "a.go"})
+	var paths []string
+	for _, f := range fl.Files() {
+		paths = append(paths, f.Path)
+	}
+	if !slices.Equal(paths, []string{"a.go", "c.go"}) {
+		t.Errorf("filtered files = %v, want [a.go c.go]", paths)
+	}
+	if fl.SelectedFile().Path != "c.go" {
+		t.Errorf("selected %q, want c.go to stay selected", fl.SelectedFile().Path)
+	}
+
+	// Sorting and new files keep the filter
+	fl.SetSort(sortStatus, nil)
+	fl.SetFiles(changedFiles("a.go", "b.go", "c.go", "d.go", "e.go"))
+	if len(fl.Files()) != 2 {
+		t.Errorf("have %d files after SetFiles, want the filter kept", len(fl.Files()))
+	}
+
+	fl.SetFilter(nil)
+	if len(fl.Files()) != 5 || fl.SelectedFile().Path != "c.go" {
+		t.Errorf("clearing the filter should list every file, keeping c.go selected")
+	}
+
+	fl.SetFilter([]string{"gone.go"})
+	if len(fl.Files()) != 0 || fl.SelectedIndex() != 0 {
+		t.Errorf("a filter matching nothing should leave an empty list, cursor %d", fl.SelectedIndex())
+	}
+}
+
+func TestFileListCommentSeverities(t *testing.T) {
+	files := []git.ChangedFile{{Path: "a.go", Status: "M"}, {Path: "b.go", Status: "M"}, {Path: "c.go", Status: "A"}}
+	fl := NewFileList(files, 40, 10)
+	fl.SetCommentSeverities([]comment.Comment{
+		{FilePath: "a.go", StartLine: 1, Body: "nit: spacing"},
+		{FilePath: "a.go", StartLine: 5, Body: "issue (blocking): race"},
+		{FilePath: "a.go", StartLine: 9, Body: "issue: slow"},
+		{FilePath: "b.go", StartLine: 2, Body: "why?"},
+	})
+	want := map[string]comment.Severity{"a.go": comment.SeverityBlocker, "b.go": comment.SeverityNone}
+	if !reflect.DeepEqual(fl.severity, want) {
+		t.Errorf("severity = %v, want %v", fl.severity, want)
+	}
+	lines := strings.Split(fl.View(), "\n")
+	for i, marked := range []bool{true, true, false} {
+		if got := strings.Contains(lines[i], "●"); got != marked {
+			t.Errorf("line %q marked = %v, want %v", lines[i], got, marked)
+		}
+	}
+}
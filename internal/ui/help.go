@@ -7,38 +7,85 @@ var helpStyle = lipgloss.NewStyle().
 	BorderForeground(lipgloss.Color("12")).
 	Padding(1, 2)
 
-// RenderHelp returns the help overlay text.
-func RenderHelp() string {
+// RenderHelp returns the help overlay text. In read-only mode the commenting
+// and finishing keys are left out since they are disabled.
+func RenderHelp(readOnly bool) string {
 	title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render("revui — Keybindings")
 
 	help := title + "\n\n" +
 		"Navigation\n" +
 		"  j/k         Move down/up\n" +
 		"  h/l         Switch panel (file list ↔ diff)\n" +
+		"              In side-by-side: switch old/new column\n" +
 		"  G           Jump to bottom\n" +
 		"  gg          Jump to top\n" +
+		"  N Enter/NG  Open file number N (file list)\n" +
+		"  :N/NG       Go to new-file line N (diff)\n" +
 		"  Ctrl+d/u    Half-page down/up\n" +
 		"  Ctrl+f/b    Full-page down/up\n" +
 		"  [/]         Jump to prev/next change\n" +
 		"  {/}         Jump to prev/next hunk\n" +
-		"\n" +
-		"Commenting\n" +
-		"  c           Add/edit comment on current line\n" +
-		"  D           Delete comment on current line\n" +
-		"  v           Visual mode (select line range)\n" +
-		"  ]c/[c       Jump to next/prev comment\n" +
-		"\n" +
-		"Views\n" +
+		"  Ctrl+n/p    Next/prev file (from the diff)\n" +
+		"  U           Jump to next unseen hunk (any file)\n" +
+		"  R           Jump to next hunk changed since last review\n" +
+		"  Enter       Expand collapsed unchanged lines or a generated file\n" +
+		"  ←/→ or </>  Scroll long lines left/right\n" +
+		"  y/Y         Copy file:line reference (Y adds the function)\n" +
+		"  ma, 'a      Mark the line as a (diff), jump to mark a\n" +
+		"  ctrl+]      Jump to the changed function the line calls\n" +
+		"  ctrl+t      Jump back\n" +
+		"\n"
+
+	if !readOnly {
+		help += "Commenting\n" +
+			"  c           Add/edit comment on current line\n" +
+			"  D           Delete comment on current line\n" +
+			"              In visual mode: delete comments in the selection\n" +
+			"  C           List all comments (dd deletes, X deletes all)\n" +
+			"  v           Visual mode (select line range)\n" +
+			"  r           Reply to the pull request's thread on the line\n" +
+			"  Q           Mark the comment on the line as a question, or not\n" +
+			"  V           Cycle the file's verdict: LGTM, needs work, didn't review\n" +
+			"  ]c/[c       Jump to next/prev comment (yours or the PR's)\n" +
+			"  Tab         Complete @author or #path/:path (typing a comment)\n" +
+			"  Enter       Save the comment being typed (or Ctrl+s)\n" +
+			"  Alt+Enter   New line in a comment (or Shift+Enter, Ctrl+j)\n" +
+			"  Ctrl+l      Cycle spellings of a misspelled word (typing a comment)\n" +
+			"  Ctrl+e      Finish the comment in $EDITOR (typing a comment)\n" +
+			"\n"
+	}
+
+	help += "Views\n" +
 		"  Tab         Toggle unified/side-by-side view\n" +
-		"  e           Toggle file list\n" +
+		"  e           Hide/show file list (diff gets the full width)\n" +
+		"  o           Sort files by path/status/churn/directory\n" +
+		"  p           Review in passes: limit files to a directory or commit\n" +
+		"  s           Outline the functions, methods, and types changed\n" +
+		"  A           Report changes to Go packages' exported APIs\n" +
+		"  u           Toggle branch / uncommitted changes\n" +
+		"  b           Color unchanged lines by age (git blame)\n" +
+		"  f           Show whole files with changes in place; fetch LFS objects\n" +
+		"  m           Mark a file; m on another compares the two (file list)\n" +
 		"  /           Search in diff\n" +
 		"  n/N         Next/prev search result\n" +
 		"\n" +
 		"Actions\n" +
-		"  ZZ          Finish review (choose output destination)\n" +
-		"              • Clipboard uses OSC 52 (works over SSH)\n" +
-		"  q           Quit without copying\n" +
-		"  ?           Toggle this help\n" +
+		"  P           Write hunk (or visual selection) as a patch file\n" +
+		"  S           Summarize hunk (or selection) with summarize_command\n" +
+		"  d           Switch a go.mod, package.json, ... between summary and diff\n" +
+		"  :!cmd, !    Run a shell command; % is the selected file\n"
+
+	if readOnly {
+		help += "  q           Quit\n"
+	} else {
+		help += "  ZZ          Finish review (choose output destination)\n" +
+			"              • Clipboard uses OSC 52 (works over SSH)\n" +
+			"              • Write to file asks for a file name\n" +
+			"              • Afterwards, r returns to the review\n" +
+			"  q           Quit without copying\n"
+	}
+
+	help += "  ?           Toggle this help\n" +
 		"\n" +
 		"Press ? or Esc to close"
 
@@ -0,0 +1,285 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+// topLevelDecl matches a function, method, or type declared at the start of
+// a line, after any modifiers, in the common forms of most languages. The
+// groups are the keyword, a Go method's receiver, and the name.
+var topLevelDecl = regexp.MustCompile(`^(?:(?:pub(?:\([^)]*\))?|export|default|async|public|private|protected|internal|static|abstract|final|unsafe|extern|data|sealed|open)\s+)*(func|def|class|type|struct|interface|enum|trait|impl|fn|function|record|object)\s+(\([^)]*\)\s*)?([A-Za-z_]\w*)`)
+
+// symbolChange is how a change touches a symbol.
+type symbolChange int
+
+const (
+	symbolModified symbolChange = iota // its body or signature changed
+	symbolAdded
+	symbolRemoved
+)
+
+// changedSymbol is a top-level symbol a file's diff adds, removes, or changes.
+type changedSymbol struct {
+	name   string // with a method's receiver type, as in Server.Run
+	kind   string // "func", "method", or "type"
+	change symbolChange
+	line   int // new line number of its declaration or first change; 0 if removed
+	hunk   int // the hunk it's in, for a removed symbol
+}
+
+// parseDecl returns the symbol line declares at the top level, and whether
+// it declares one.
+func parseDecl(line string) (name, kind string, ok bool) {
+	m := topLevelDecl.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", false
+	}
+	name, kind = m[3], "type"
+	switch m[1] {
+	case "func", "def", "fn", "function":
+		kind = "func"
+	}
+	if m[2] != "" {
+		kind = "method"
+		if recv := receiverType(m[2]); recv != "" {
+			name = recv + "." + name
+		}
+	}
+	return name, kind, true
+}
+
+// receiverType returns the type of a Go receiver such as "(s *Server[T])".
+func receiverType(recv string) string {
+	fields := strings.Fields(strings.Trim(recv, "() \t"))
+	if len(fields) == 0 {
+		return ""
+	}
+	t := strings.TrimLeft(fields[len(fields)-1], "*")
+	t, _, _ = strings.Cut(t, "[")
+	return t
+}
+
+// hunkContext returns the enclosing declaration git reported in a hunk
+// header, as in "@@ -1,2 +1,3 @@ func (s *Server) Run() {": the context
+// follows the second "@@".
+func hunkContext(header string) string {
+	if i := strings.Index(header[min(2, len(header)):], "@@"); i >= 0 {
+		return strings.TrimSpace(header[i+4:])
+	}
+	return ""
+}
+
+// fileSymbols returns the top-level symbols fd adds, removes, or modifies, in
+// the order they appear. A symbol declared on an added line is added and one
+// on a removed line removed, unless it's declared on both, when its
+// signature changed; one whose lines change under an unchanged declaration,
+// or the enclosing declaration git names in a hunk header, is modified.
+func fileSymbols(fd *git.FileDiff) []changedSymbol {
+	var symbols []changedSymbol
+	index := make(map[string]int)
+	note := func(s changedSymbol) {
+		i, ok := index[s.name]
+		if !ok {
+			index[s.name] = len(symbols)
+			symbols = append(symbols, s)
+			return
+		}
+		prev := &symbols[i]
+		if prev.change != s.change {
+			// Declared on removed and added lines: rewritten in place
+			prev.change = symbolModified
+		}
+		if prev.line == 0 {
+			prev.line = s.line
+		}
+	}
+	for hi, h := range fd.Hunks {
+		// The symbol whose body the lines being walked are in
+		var enclosing *changedSymbol
+		if name, kind, ok := parseDecl(hunkContext(h.Header)); ok {
+			enclosing = &changedSymbol{name: name, kind: kind, hunk: hi}
+		}
+		for _, l := range h.Lines {
+			name, kind, ok := parseDecl(l.Content)
+			switch {
+			case ok && l.Type == git.LineAdded:
+				note(changedSymbol{name: name, kind: kind, change: symbolAdded, line: l.NewLineNo, hunk: hi})
+				enclosing = nil
+			case ok && l.Type == git.LineRemoved:
+				note(changedSymbol{name: name, kind: kind, change: symbolRemoved, hunk: hi})
+				enclosing = nil
+			case ok:
+				enclosing = &changedSymbol{name: name, kind: kind, line: l.NewLineNo, hunk: hi}
+			case l.Type != git.LineContext && enclosing != nil:
+				s := *enclosing
+				if s.line == 0 {
+					s.line = l.NewLineNo
+				}
+				note(s)
+				// Once its line is known, the rest of its changes add nothing
+				if s.line > 0 {
+					enclosing = nil
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// outlineEntry is a row of the outline: a file, or one of its symbols.
+type outlineEntry struct {
+	path   string
+	symbol *changedSymbol // nil for the file's row
+}
+
+// OutlineJumpMsg is sent when the user picks a symbol in the outline.
+type OutlineJumpMsg struct {
+	Path string
+	Line int // 0 to go to Hunk instead, for a removed symbol
+	Hunk int
+}
+
+// OutlineCloseMsg is sent when the user closes the outline.
+type OutlineCloseMsg struct{}
+
+// Outline is a sub-model listing the top-level symbols each file's diff
+// adds, removes, or modifies, for reviewing a change an API at a time.
+type Outline struct {
+	entries []outlineEntry
+	cursor  int // index into entries, always on a symbol when there is one
+	width   int
+	height  int
+}
+
+// NewOutline creates the outline of diffs, with the cursor on the first
+// symbol of current, the open file, if it has any. Files without changed
+// symbols are left out.
+func NewOutline(diffs []*git.FileDiff, current string, width, height int) Outline {
+	o := Outline{width: width, height: height}
+	for _, fd := range diffs {
+		symbols := fileSymbols(fd)
+		if len(symbols) == 0 {
+			continue
+		}
+		o.entries = append(o.entries, outlineEntry{path: fd.Path})
+		for i := range symbols {
+			if fd.Path == current && o.cursor == 0 {
+				o.cursor = len(o.entries)
+			}
+			o.entries = append(o.entries, outlineEntry{path: fd.Path, symbol: &symbols[i]})
+		}
+	}
+	if o.cursor == 0 && len(o.entries) > 1 {
+		o.cursor = 1
+	}
+	return o
+}
+
+// move moves the cursor by delta symbols, skipping file rows.
+func (o *Outline) move(delta int) {
+	for i := o.cursor + delta; i >= 0 && i < len(o.entries); i += delta {
+		if o.entries[i].symbol != nil {
+			o.cursor = i
+			return
+		}
+	}
+}
+
+// Update handles key messages.
+func (o Outline) Update(msg tea.Msg) (Outline, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return o, nil
+	}
+	switch key.String() {
+	case "j", "down":
+		o.move(1)
+	case "k", "up":
+		o.move(-1)
+	case "g":
+		o.cursor = 0
+		o.move(1)
+	case "G":
+		o.cursor = len(o.entries)
+		o.move(-1)
+	case "enter":
+		if o.cursor >= len(o.entries) || o.entries[o.cursor].symbol == nil {
+			return o, nil
+		}
+		e := o.entries[o.cursor]
+		jump := OutlineJumpMsg{Path: e.path, Line: e.symbol.line, Hunk: e.symbol.hunk}
+		return o, func() tea.Msg { return jump }
+	case "esc", "q", "s":
+		return o, func() tea.Msg { return OutlineCloseMsg{} }
+	}
+	return o, nil
+}
+
+// symbolMarks are the signs the outline shows changes with, and their colors.
+var symbolMarks = map[symbolChange]struct {
+	sign  string
+	color lipgloss.Color
+}{
+	symbolAdded:    {"+", "2"},
+	symbolRemoved:  {"-", "1"},
+	symbolModified: {"~", "3"},
+}
+
+// View renders the outline.
+func (o Outline) View() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	fileStyle := lipgloss.NewStyle().Bold(true)
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Changed symbols"))
+	s.WriteString("\n")
+	if len(o.entries) == 0 {
+		s.WriteString("  No functions, methods, or types changed\n")
+	}
+
+	// Keep the cursor in view when there are more entries than rows
+	rows := max(o.height-4, 1)
+	start := max(0, min(o.cursor-rows/2, len(o.entries)-rows))
+	end := min(start+rows, len(o.entries))
+	for i := start; i < end; i++ {
+		e := o.entries[i]
+		if e.symbol == nil {
+			line := "  " + e.path
+			if w := o.width - 2; w > 0 {
+				line = runewidth.Truncate(line, w, "…")
+			}
+			s.WriteString(fileStyle.Render(line))
+			s.WriteString("\n")
+			continue
+		}
+		mark := symbolMarks[e.symbol.change]
+		text := fmt.Sprintf("%-6s %s", e.symbol.kind, e.symbol.name)
+		if e.symbol.line > 0 {
+			text += fmt.Sprintf("  :%d", e.symbol.line)
+		}
+		if w := o.width - 8; w > 0 {
+			text = runewidth.Truncate(text, w, "…")
+		}
+		sign := lipgloss.NewStyle().Foreground(mark.color).Render(mark.sign)
+		if i == o.cursor {
+			s.WriteString(selectedStyle.Render("  > ") + sign + " " + selectedStyle.Render(text))
+		} else {
+			s.WriteString("    " + sign + " " + text)
+		}
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(footerStyle.Render("  [enter] go to  [j/k] move  [esc] close"))
+	return s.String()
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+func TestParseDecl(t *testing.T) {
+	tests := []struct {
+		line       string
+		name, kind string
+		ok         bool
+	}{
+		{line: "func parseArgs(args []string) error {", name: "parseArgs", kind: "func", ok: true},
+		{line: "func (s *Server[T]) Run() {", name: "Server.Run", kind: "method", ok: true},
+		{line: "type Config struct {", name: "Config", kind: "type", ok: true},
+		{line: "pub(crate) async fn fetch(url: &str) {", name: "fetch", kind: "func", ok: true},
+		{line: "export default class Widget {", name: "Widget", kind: "type", ok: true},
+		{line: "def main():", name: "main", kind: "func", ok: true},
+		{line: "\tdef helper(self):", ok: false},
+		{line: "x := typeOf(y)", ok: false},
+	}
+	for _, tt := range tests {
+		name, kind, ok := parseDecl(tt.line)
+		if name != tt.name || kind != tt.kind || ok != tt.ok {
+			t.Errorf("parseDecl(%q) = %q, %q, %v, want %q, %q, %v", tt.line, name, kind, ok, tt.name, tt.kind, tt.ok)
+		}
+	}
+}
+
+func TestFileSymbols(t *testing.T) {
+	fd := &git.FileDiff{Path: "server.go", Hunks: []git.Hunk{
+		{
+			Header: "@@ -10,4 +10,4 @@ func (s *Server) Run() error {",
+			Lines: []git.Line{
+				{Content: "\tctx := context.Background()", Type: git.LineContext, OldLineNo: 10, NewLineNo: 10},
+				{Content: "\treturn s.serve()", Type: git.LineRemoved, OldLineNo: 11},
+				{Content: "\treturn s.serve(ctx)", Type: git.LineAdded, NewLineNo: 11},
+				{Content: "}", Type: git.LineContext, OldLineNo: 12, NewLineNo: 12},
+				{Content: "func old() {}", Type: git.LineRemoved, OldLineNo: 13},
+				{Content: "func listen(addr string) error {", Type: git.LineRemoved, OldLineNo: 14},
+				{Content: "func listen(addr string, tls bool) error {", Type: git.LineAdded, NewLineNo: 13},
+			},
+		},
+		{
+			Header: "@@ -30,2 +29,5 @@",
+			Lines: []git.Line{
+				{Content: "type Options struct {", Type: git.LineContext, OldLineNo: 30, NewLineNo: 29},
+				{Content: "\tTimeout time.Duration", Type: git.LineAdded, NewLineNo: 30},
+				{Content: "}", Type: git.LineContext, OldLineNo: 31, NewLineNo: 31},
+				{Content: "type Handler interface {", Type: git.LineAdded, NewLineNo: 32},
+				{Content: "}", Type: git.LineAdded, NewLineNo: 33},
+			},
+		},
+	}}
+	want := []changedSymbol{
+		{name: "Server.Run", kind: "method", change: symbolModified, line: 11},
+		{name: "old", kind: "func", change: symbolRemoved},
+		{name: "listen", kind: "func", change: symbolModified, line: 13},
+		{name: "Options", kind: "type", change: symbolModified, line: 29, hunk: 1},
+		{name: "Handler", kind: "type", change: symbolAdded, line: 32, hunk: 1},
+	}
+	if got := fileSymbols(fd); !reflect.DeepEqual(got, want) {
+		t.Errorf("fileSymbols =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestOutlineNavigation(t *testing.T) {
+	diffs := []*git.FileDiff{
+		{Path: "a.go", Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "func A() {}", Type: git.LineAdded, NewLineNo: 3},
+		}}}},
+		{Path: "notes.txt", Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "prose", Type: git.LineAdded, NewLineNo: 1},
+		}}}},
+		{Path: "b.go", Hunks: []git.Hunk{{Lines: []git.Line{
+			{Content: "func B() {}", Type: git.LineAdded, NewLineNo: 7},
+			{Content: "func C() {}", Type: git.LineRemoved, OldLineNo: 8},
+		}}}},
+	}
+	o := NewOutline(diffs, "b.go", 80, 24)
+	view := o.View()
+	if strings.Contains(view, "notes.txt") {
+		t.Errorf("files without symbols should be left out:\n%s", view)
+	}
+
+	enter := func() tea.Msg {
+		_, cmd := o.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		return cmd()
+	}
+	if got, want := enter(), (OutlineJumpMsg{Path: "b.go", Line: 7}); got != want {
+		t.Errorf("enter on the open file's first symbol = %#v, want %#v", got, want)
+	}
+
+	// k skips b.go's row to a.go's symbol
+	o, _ = o.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	if got, want := enter(), (OutlineJumpMsg{Path: "a.go", Line: 3}); got != want {
+		t.Errorf("after k = %#v, want %#v", got, want)
+	}
+
+	o, _ = o.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'G'}})
+	if got, want := enter(), (OutlineJumpMsg{Path: "b.go", Line: 0, Hunk: 0}); got != want {
+		t.Errorf("after G = %#v, want %#v", got, want)
+	}
+
+	_, cmd := o.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if _, ok := cmd().(OutlineCloseMsg); !ok {
+		t.Error("esc should close the outline")
+	}
+}
@@ -1,17 +1,26 @@
 package ui
 
 import (
+	"cmp"
+	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 
+	"github.com/deparker/revui/internal/comment"
 	"github.com/deparker/revui/internal/output"
 )
 
-// OutputSelectMsg is sent when the user selects an output target.
+// OutputSelectMsg is sent when the user selects an output target. Draft and
+// Verdict are chosen on the confirmation screen of a hosted target.
 type OutputSelectMsg struct {
-	Target output.OutputTarget
+	Target  output.OutputTarget
+	Draft   bool
+	Verdict output.Verdict
 }
 
 // OutputCancelMsg is sent when the user cancels the output selection.
@@ -23,7 +32,19 @@ type OutputSelector struct {
 	cursor  int
 	width   int
 	height  int
-	err     string // delivery error to display
+	err     string       // delivery error to display
+	failed  map[int]bool // targets whose delivery failed
+	// naming is set while prompting for the file name of a file target.
+	naming    bool
+	nameInput textinput.Model
+	fileDir   string // directory the file name prompt starts in
+	// confirming is set while showing what will be posted to a hosted target.
+	confirming bool
+	comments   []comment.Comment
+	offset     int // first comment shown while confirming
+	// sending is the label of the target being delivered to; keys are
+	// ignored until the delivery finishes.
+	sending string
 }
 
 // NewOutputSelector creates a new output selector component.
@@ -36,13 +57,69 @@ func NewOutputSelector(targets []output.OutputTarget, width, height int) OutputS
 	}
 }
 
+// SetFileDir sets the directory the file name prompt is pre-filled with,
+// typically the one last written to. Empty means /tmp.
+func (os *OutputSelector) SetFileDir(dir string) {
+	os.fileDir = dir
+}
+
+// SetComments sets the comments listed when confirming what will be posted to
+// a hosted target.
+func (os *OutputSelector) SetComments(comments []comment.Comment) {
+	os.comments = slices.Clone(comments)
+	slices.SortFunc(os.comments, func(a, b comment.Comment) int {
+		return cmp.Or(cmp.Compare(a.FilePath, b.FilePath), cmp.Compare(a.StartLine, b.StartLine))
+	})
+}
+
 // SetError sets an error message to display (called when delivery fails).
 func (os *OutputSelector) SetError(msg string) {
 	os.err = msg
 }
 
+// SetSending shows that the review is being delivered to the target labelled
+// label, until SetFailed or the delivery result replaces the selector.
+func (os *OutputSelector) SetSending(label string) {
+	os.sending = label
+	os.err = ""
+}
+
+// SetFailed reports that delivery to the selected target failed. The target
+// is marked and the cursor moves to the next one that hasn't failed, so
+// another destination is one keypress away. A failed file name stays in the
+// prompt to be corrected.
+func (os *OutputSelector) SetFailed(msg string) {
+	os.err = msg
+	os.sending = ""
+	if os.naming {
+		return
+	}
+	os.confirming = false
+	if os.failed == nil {
+		os.failed = make(map[int]bool)
+	}
+	os.failed[os.cursor] = true
+	for i := 1; i < len(os.targets); i++ {
+		next := (os.cursor + i) % len(os.targets)
+		if !os.failed[next] {
+			os.cursor = next
+			return
+		}
+	}
+}
+
 // Update handles key messages.
 func (os OutputSelector) Update(msg tea.Msg) (OutputSelector, tea.Cmd) {
+	if os.sending != "" {
+		return os, nil
+	}
+	if os.naming {
+		return os.updateNaming(msg)
+	}
+	if os.confirming {
+		return os.updateConfirming(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.Type {
@@ -58,6 +135,13 @@ func (os OutputSelector) Update(msg tea.Msg) (OutputSelector, tea.Cmd) {
 				}
 			case "q":
 				return os, func() tea.Msg { return OutputCancelMsg{} }
+			case "s":
+				// Send to Claude and submit it, without going back to the pane.
+				if len(os.targets) > 0 && os.targets[os.cursor].Kind == output.TargetClaude {
+					target := os.targets[os.cursor]
+					target.Submit = true
+					return os, func() tea.Msg { return OutputSelectMsg{Target: target} }
+				}
 			}
 		case tea.KeyDown:
 			if os.cursor < len(os.targets)-1 {
@@ -68,6 +152,15 @@ func (os OutputSelector) Update(msg tea.Msg) (OutputSelector, tea.Cmd) {
 				os.cursor--
 			}
 		case tea.KeyEnter:
+			if len(os.targets) > 0 && os.targets[os.cursor].Kind == output.TargetFile {
+				return os.startNaming()
+			}
+			if len(os.targets) > 0 && os.targets[os.cursor].Hosted() {
+				os.confirming = true
+				os.offset = 0
+				os.err = ""
+				return os, nil
+			}
 			if len(os.targets) > 0 {
 				return os, func() tea.Msg {
 					return OutputSelectMsg{Target: os.targets[os.cursor]}
@@ -81,11 +174,102 @@ func (os OutputSelector) Update(msg tea.Msg) (OutputSelector, tea.Cmd) {
 	return os, nil
 }
 
+// startNaming opens the file name prompt, pre-filled with the pattern of a new
+// review file in the file directory, which is given a unique name.
+func (os OutputSelector) startNaming() (OutputSelector, tea.Cmd) {
+	dir := os.fileDir
+	if dir == "" {
+		dir = output.TempDir()
+	}
+	os.nameInput = textinput.New()
+	os.nameInput.Prompt = "  "
+	os.nameInput.Width = max(20, os.width-6)
+	os.nameInput.SetValue(output.DefaultFilePath(dir))
+	os.nameInput.CursorEnd()
+	os.nameInput.Focus()
+	os.naming = true
+	os.err = ""
+	return os, textinput.Blink
+}
+
+// updateNaming handles keys while the file name prompt is open. Enter writes
+// to the entered path and Esc goes back to the target list.
+func (os OutputSelector) updateNaming(msg tea.Msg) (OutputSelector, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.Type {
+		case tea.KeyEnter:
+			path := strings.TrimSpace(os.nameInput.Value())
+			if path == "" {
+				return os, nil
+			}
+			target := os.targets[os.cursor]
+			target.Path = path
+			return os, func() tea.Msg { return OutputSelectMsg{Target: target} }
+		case tea.KeyEscape:
+			os.naming = false
+			os.nameInput.Blur()
+			return os, nil
+		}
+	}
+	var cmd tea.Cmd
+	os.nameInput, cmd = os.nameInput.Update(msg)
+	return os, cmd
+}
+
+// updateConfirming handles keys on the confirmation screen of a hosted
+// target: Enter posts the comments, a and r post them with a verdict, d saves
+// them as drafts where the target can, and Esc goes back to the target list.
+func (os OutputSelector) updateConfirming(msg tea.Msg) (OutputSelector, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return os, nil
+	}
+	sel := OutputSelectMsg{Target: os.targets[os.cursor]}
+	switch key.String() {
+	case "j", "down":
+		if os.offset < len(os.comments)-1 {
+			os.offset++
+		}
+		return os, nil
+	case "k", "up":
+		if os.offset > 0 {
+			os.offset--
+		}
+		return os, nil
+	case "enter":
+	case "a":
+		sel.Verdict = output.VerdictApprove
+	case "r":
+		sel.Verdict = output.VerdictRequestChanges
+	case "d":
+		if !sel.Target.Drafts() {
+			return os, nil
+		}
+		sel.Draft = true
+	case "esc", "q":
+		os.confirming = false
+		return os, nil
+	default:
+		return os, nil
+	}
+	return os, func() tea.Msg { return sel }
+}
+
 // View renders the selection list.
 func (os OutputSelector) View() string {
 	if len(os.targets) == 0 {
 		return renderEmptyView()
 	}
+	if os.sending != "" {
+		titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+		return titleStyle.Render("Sending review to " + os.sending + "…")
+	}
+	if os.naming {
+		return os.namingView()
+	}
+	if os.confirming {
+		return os.confirmView()
+	}
 
 	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
 	separatorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
@@ -114,11 +298,18 @@ func (os OutputSelector) View() string {
 			s.WriteString("\n")
 		}
 
+		label := target.Label
+		if os.failed[i] {
+			label += " (failed)"
+		}
 		var line string
-		if i == os.cursor {
-			line = selectedStyle.Render("  > " + target.Label)
-		} else {
-			line = normalStyle.Render("    " + target.Label)
+		switch {
+		case i == os.cursor:
+			line = selectedStyle.Render("  > " + label)
+		case os.failed[i]:
+			line = separatorStyle.Render("    " + label)
+		default:
+			line = normalStyle.Render("    " + label)
 		}
 		s.WriteString(line)
 		s.WriteString("\n")
@@ -129,11 +320,81 @@ func (os OutputSelector) View() string {
 		s.WriteString("\n")
 		s.WriteString(errorStyle.Render("  Error: " + os.err))
 		s.WriteString("\n")
+		if len(os.failed) > 0 {
+			s.WriteString(footerStyle.Render("  The review is kept; choose another destination."))
+			s.WriteString("\n")
+		}
 	}
 
 	s.WriteString("\n")
-	s.WriteString(footerStyle.Render("  [Enter] select  [q] cancel"))
+	footer := "  [Enter] select  [q] cancel"
+	if os.targets[os.cursor].Kind == output.TargetClaude {
+		footer = "  [Enter] select  [s] send and submit  [q] cancel"
+	}
+	s.WriteString(footerStyle.Render(footer))
+
+	return s.String()
+}
+
+// namingView renders the file name prompt.
+func (os OutputSelector) namingView() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
 
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Write review to:"))
+	s.WriteString("\n")
+	s.WriteString(os.nameInput.View())
+	s.WriteString("\n")
+	if os.err != "" {
+		s.WriteString("\n")
+		s.WriteString(errorStyle.Render("  Error: " + os.err))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(footerStyle.Render("  [Enter] write  [Esc] back"))
+	return s.String()
+}
+
+// confirmView lists the comments that will be posted to the selected hosted
+// target.
+func (os OutputSelector) confirmView() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	moreStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	target := os.targets[os.cursor]
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(fmt.Sprintf("Post %d comments to %s:", len(os.comments), target.Label)))
+	s.WriteString("\n")
+
+	rows := max(os.height-5, 1)
+	end := min(os.offset+rows, len(os.comments))
+	for _, c := range os.comments[os.offset:end] {
+		where := c.FilePath
+		if c.StartLine > 0 {
+			where = fmt.Sprintf("%s:%d", c.FilePath, c.StartLine)
+		}
+		body, _, _ := strings.Cut(c.Body, "\n")
+		line := where + "  " + body
+		if w := os.width - 6; w > 0 {
+			line = runewidth.Truncate(line, w, "…")
+		}
+		s.WriteString("    " + line)
+		s.WriteString("\n")
+	}
+	if end < len(os.comments) {
+		s.WriteString(moreStyle.Render(fmt.Sprintf("    … %d more", len(os.comments)-end)))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	footer := "  [Enter] post  [a] post and approve  [r] post and request changes"
+	if target.Drafts() {
+		footer += "  [d] save as drafts"
+	}
+	s.WriteString(footerStyle.Render(footer + "  [Esc] back"))
 	return s.String()
 }
 
@@ -6,6 +6,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/deparker/revui/internal/comment"
 	"github.com/deparker/revui/internal/output"
 )
 
@@ -99,6 +100,32 @@ func TestOutputSelector_Select(t *testing.T) {
 	}
 }
 
+func TestOutputSelector_SendAndSubmit(t *testing.T) {
+	targets := testTargets()
+	os := NewOutputSelector(targets, 80, 24)
+	if !strings.Contains(os.View(), "[s] send and submit") {
+		t.Error("the footer should offer submitting on a Claude pane")
+	}
+
+	_, cmd := os.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	if cmd == nil {
+		t.Fatal("Expected command after s, got nil")
+	}
+	selectMsg, ok := cmd().(OutputSelectMsg)
+	if !ok || !selectMsg.Target.Submit || selectMsg.Target.TmuxTarget != "revui:0.0" {
+		t.Errorf("s = %+v, want the first Claude pane submitted", selectMsg)
+	}
+
+	// Other targets have nothing to submit.
+	os.cursor = 3
+	if _, cmd := os.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}}); cmd != nil {
+		t.Errorf("s on the clipboard sent %+v", cmd())
+	}
+	if strings.Contains(os.View(), "[s]") {
+		t.Error("the footer offers submitting on the clipboard")
+	}
+}
+
 func TestOutputSelector_Cancel(t *testing.T) {
 	targets := testTargets()
 	os := NewOutputSelector(targets, 80, 24)
@@ -188,3 +215,170 @@ func TestOutputSelector_ErrorDisplay(t *testing.T) {
 		t.Error("Expected view to contain error message")
 	}
 }
+
+func TestOutputSelector_FileNamePrompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		fileDir string
+		wantDir string
+	}{
+		{name: "default directory", wantDir: output.TempDir() + "/"},
+		{name: "last used directory", fileDir: "/home/me/reviews", wantDir: "/home/me/reviews/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := testTargets()
+			os := NewOutputSelector(targets, 80, 24)
+			os.SetFileDir(tt.fileDir)
+			os.cursor = len(targets) - 1 // Write to file
+
+			os, _ = os.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			if !os.naming {
+				t.Fatal("Enter on the file target should prompt for a name")
+			}
+			value := os.nameInput.Value()
+			if !strings.HasPrefix(value, tt.wantDir+"revui-review-") || !strings.HasSuffix(value, ".md") {
+				t.Errorf("prompt pre-filled with %q, want a timestamped name in %s", value, tt.wantDir)
+			}
+			if !strings.Contains(os.View(), "Write review to:") {
+				t.Error("view should show the file name prompt")
+			}
+
+			// Typing goes to the prompt, including keys bound in the list
+			os.nameInput.SetValue(tt.wantDir + "q")
+			_, cmd := os.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			if cmd == nil {
+				t.Fatal("Enter in the prompt should select the target")
+			}
+			msg, ok := cmd().(OutputSelectMsg)
+			if !ok {
+				t.Fatalf("Expected OutputSelectMsg, got %T", cmd())
+			}
+			if msg.Target.Kind != output.TargetFile || msg.Target.Path != tt.wantDir+"q" {
+				t.Errorf("selected %+v, want the file target with the entered path", msg.Target)
+			}
+		})
+	}
+}
+
+func TestOutputSelector_FileNamePromptEscape(t *testing.T) {
+	targets := testTargets()
+	os := NewOutputSelector(targets, 80, 24)
+	os.cursor = len(targets) - 1
+
+	os, _ = os.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	os, cmd := os.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if os.naming {
+		t.Error("Esc should close the prompt")
+	}
+	if cmd != nil {
+		t.Error("Esc in the prompt should return to the list, not cancel")
+	}
+	if !strings.Contains(os.View(), "Send review to:") {
+		t.Error("view should show the target list again")
+	}
+}
+
+func TestOutputSelector_SetFailed(t *testing.T) {
+	targets := testTargets()
+	os := NewOutputSelector(targets, 80, 24)
+	os.cursor = 3 // System clipboard
+
+	os.SetFailed("no clipboard")
+	if os.cursor != 4 {
+		t.Errorf("cursor = %d, want the next target 4", os.cursor)
+	}
+	view := os.View()
+	for _, want := range []string{"System clipboard (failed)", "Error: no clipboard", "choose another destination"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q", want)
+		}
+	}
+
+	// Wraps around and skips targets that already failed
+	os.SetFailed("disk full")
+	if os.cursor != 0 {
+		t.Errorf("cursor = %d, want to wrap to 0", os.cursor)
+	}
+}
+
+func TestOutputSelector_Sending(t *testing.T) {
+	targets := testTargets()
+	os := NewOutputSelector(targets, 80, 24)
+	os.SetSending(targets[0].Label)
+	if !strings.Contains(os.View(), "Sending review to "+targets[0].Label) {
+		t.Error("view should show the delivery in progress")
+	}
+	if _, cmd := os.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Error("keys should be ignored while sending")
+	}
+
+	os.SetFailed("timed out")
+	if !strings.Contains(os.View(), "Error: timed out") {
+		t.Error("a failure should return to the targets")
+	}
+}
+
+func TestOutputSelector_ConfirmHosted(t *testing.T) {
+	targets := []output.OutputTarget{
+		{Kind: output.TargetBitbucket, Label: "Bitbucket PR: team/app", Bitbucket: &output.Bitbucket{}},
+		{Kind: output.TargetAzureDevOps, Label: "Azure DevOps PR: Platform/api", AzureDevOps: &output.AzureDevOps{}},
+	}
+	os := NewOutputSelector(targets, 80, 24)
+	os.SetComments([]comment.Comment{
+		{FilePath: "b.go", StartLine: 3, Body: "rename this\nand that"},
+		{FilePath: "a.go", Body: "split the file"},
+	})
+
+	// Enter on a hosted target shows what will be posted
+	os, cmd := os.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || !os.confirming {
+		t.Fatal("enter on a hosted target should confirm first")
+	}
+	view := os.View()
+	for _, want := range []string{"Post 2 comments to Bitbucket PR: team/app", "a.go  split the file", "b.go:3  rename this", "[d] save as drafts"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	tests := []struct {
+		key   tea.KeyMsg
+		draft bool
+		want  output.Verdict
+	}{
+		{key: tea.KeyMsg{Type: tea.KeyEnter}},
+		{key: runeKey('a'), want: output.VerdictApprove},
+		{key: runeKey('r'), want: output.VerdictRequestChanges},
+		{key: runeKey('d'), draft: true},
+	}
+	for _, tt := range tests {
+		_, cmd := os.Update(tt.key)
+		msg, ok := cmd().(OutputSelectMsg)
+		if !ok || msg.Draft != tt.draft || msg.Verdict != tt.want || msg.Target.Kind != output.TargetBitbucket {
+			t.Errorf("%q sent %+v", tt.key.String(), cmd())
+		}
+	}
+
+	// Esc goes back to the list
+	os, _ = os.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if os.confirming {
+		t.Error("esc should go back to the target list")
+	}
+
+	// Azure DevOps has no drafts
+	os.cursor = 1
+	os, _ = os.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if strings.Contains(os.View(), "drafts") {
+		t.Error("drafts shouldn't be offered for Azure DevOps")
+	}
+	if _, cmd := os.Update(runeKey('d')); cmd != nil {
+		t.Error("d should do nothing without drafts")
+	}
+
+	// A failed delivery returns to the list to show the error
+	os.SetFailed("no open pull request")
+	if os.confirming || !strings.Contains(os.View(), "Error: no open pull request") {
+		t.Error("a failure should show the target list with the error")
+	}
+}
@@ -1,18 +1,27 @@
 package ui
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 
+	"github.com/deparker/revui/internal/apidiff"
 	"github.com/deparker/revui/internal/comment"
 	"github.com/deparker/revui/internal/git"
 	"github.com/deparker/revui/internal/output"
+	"github.com/deparker/revui/internal/spell"
 )
 
 type focusArea int
@@ -22,6 +31,12 @@ const (
 	focusDiffViewer
 	focusCommentInput
 	focusOutputSelect
+	focusDelivered // delivery result shown, waiting to exit or resume
+	focusCommentList
+	focusChunkSelect
+	focusShellOutput
+	focusOutline
+	focusAPIReport
 )
 
 type reviewMode int
@@ -31,14 +46,74 @@ const (
 	modeUncommitted
 )
 
-// GitRunner is the interface for git operations, enabling testing with mocks.
-type GitRunner interface {
+// DiffSource supplies the changes under review: the changed files, the diff
+// of each, and the name shown in the header. A git repository is one source;
+// a fixed set of diffs such as a patch file (git.Static) is another. Sources
+// that have nothing to compare against ignore the base.
+type DiffSource interface {
 	ChangedFiles(base string) ([]git.ChangedFile, error)
 	FileDiff(base, path string) (*git.FileDiff, error)
 	CurrentBranch() (string, error)
+}
+
+// WorktreeSource is a DiffSource with a working tree, whose uncommitted
+// changes can be reviewed on their own or on top of the branch.
+type WorktreeSource interface {
+	DiffSource
 	HasUncommittedChanges() bool
 	UncommittedFiles() ([]git.ChangedFile, error)
 	UncommittedFileDiff(path string) (*git.FileDiff, error)
+	WorktreeChangedFiles(base string) ([]git.ChangedFile, error)
+	WorktreeFileDiff(base, path string) (*git.FileDiff, error)
+}
+
+// ageBlamer is implemented by sources that can report when lines were
+// last changed. Sources without history, such as patch files, don't offer age
+// indicators.
+type ageBlamer interface {
+	BlameAges(rev, path string) (map[int]time.Time, error)
+}
+
+// lineCounter is implemented by sources that can count each file's changed
+// lines without loading its diff.
+type lineCounter interface {
+	LineCounts(base string, worktree bool) (map[string]git.LineCount, error)
+}
+
+// commitLister is implemented by sources with history, whose commits the
+// review can be split by.
+type commitLister interface {
+	Commits(base string) ([]git.Commit, error)
+}
+
+// lfsFetcher is implemented by sources that can fetch Git LFS objects to
+// show what changed behind a pointer file.
+type lfsFetcher interface {
+	LFSDiff(path string, old, new *git.LFSPointer) (*git.FileDiff, error)
+}
+
+// contentReader is implemented by sources that can read a file as it is
+// after the change, to show it whole.
+type contentReader interface {
+	FileContent(worktree bool, path string) (string, error)
+}
+
+// fileComparer is implemented by sources that can diff two files of the
+// change against each other.
+type fileComparer interface {
+	CompareFiles(base string, worktree bool, oldPath, newPath string) (*git.FileDiff, error)
+}
+
+// dirReader is implemented by sources that can read a directory's files on
+// both sides of the change, for comparing Go packages' APIs.
+type dirReader interface {
+	DirFiles(base string, worktree bool, dir, ext string) (old, new map[string]string, err error)
+}
+
+// generatedChecker is implemented by sources that can look up whether
+// .gitattributes marks a file as generated.
+type generatedChecker interface {
+	GeneratedAttr(path string) (generated, set bool, err error)
 }
 
 // finishMsg signals the review is done and comments should be copied.
@@ -57,46 +132,133 @@ type refreshResultMsg struct {
 
 // RootModel is the top-level Bubble Tea model.
 type RootModel struct {
-	git               GitRunner
+	source            DiffSource
 	mode              reviewMode
+	includeDirty      bool // branch mode diffs base against the working tree
 	base              string
 	branch            string
+	pullRequest       int    // the pull request under review, if known
+	hostBranch        string // the branch's name on the hosting service, if not branch
 	files             []git.ChangedFile
 	fileList          FileList
 	diffViewer        DiffViewer
 	commentInput      CommentInput
 	comments          *comment.Store
+	threads           []comment.Comment // comments already on the pull request, read-only
 	focus             focusArea
 	width             int
 	height            int
-	err               error
 	quitting          bool
 	finished          bool
 	output            string // formatted comments for clipboard
 	fileListWidth     int
 	hideFileList      bool
+	compareMark       string // file marked for comparing with another
+	comparing         string // the two files the diff viewer compares, if it does
 	pendingZ          bool
+	pendingMark       string          // "m" or "'" when the next key names a mark to set or jump to
+	marks             map[string]Mark // lines marked to jump back to, by letter
+	jumps             []Mark          // lines jumped from to a definition, latest last
 	showHelp          bool
 	searchInput       textinput.Model
 	searching         bool
+	lineInput         textinput.Model // line number, or !command, typed after :
+	goingToLine       bool
 	refreshInProgress bool
+	refreshTicking    bool // a refresh tick loop is scheduled
 	outputSelector    OutputSelector
-	deliveryResult    string // status message after delivery
+	commentList       CommentList
+	chunkSelector     ChunkSelector
+	outline           Outline
+	apiReport         APIReport
+	shellOutput       ShellOutput
+	shellRuns         int      // counts shell commands run, to match results to them
+	workDir           string   // where shell commands run: the repository's top level
+	chunk             string   // name of the chunk the file list is limited to; "" for all files
+	deliveryResult    string   // status message after the latest delivery
+	deliveries        []string // status messages of every delivery this run
+	autoAdvance       bool     // jump to the next change after submitting a comment
+	readOnly          bool     // browse only: commenting and finishing are disabled
+	onCommentsChanged func([]comment.Comment) error
+	verdicts          map[string]comment.FileVerdict // the reviewer's call on each file
+	onVerdictsChanged func(map[string]comment.FileVerdict) error
+	onUnsent          func(output.OutputTarget, output.Review, error) error
+	onDelivered       func(output.OutputTarget, output.Review, string, error)
+	extraTargets      []output.OutputTarget      // configured targets offered after the detected ones
+	flash             string                     // one-off status message, cleared on the next key
+	toasts            []toast                    // queued notifications; the first is shown
+	toastSeq          int                        // id of the latest toast
+	seen              map[string]map[string]bool // hunk headers the cursor has visited, by file path
+	revised           map[string][]git.Hunk      // changes since the previous review session, by file path
+	churn             map[string]int             // changed lines per file path, counted when sorting by churn
+	showAges          bool                       // color context line numbers by age
+	fullFile          bool                       // show files whole, with the changes in place
+	lastFileDir       string                     // directory the review was last written to
+	fallbackToFile    bool                       // save to a file when delivery fails
+	author            string                     // recorded on the reviewer's comments
+	snippets          bool                       // follow each comment in the output with its code
+	seeAlso           bool                       // refer declarations' comments to other files using them
+	summarizeCommand  string                     // summarizes a hunk piped to it, as with an LLM
+	failure           error                      // a source error mid-review, shown until retried or dismissed
+	changingBase      bool                       // the failure panel is asking for a new base
+	baseInput         textinput.Model
+	added, removed    int // changed lines under review, for the size estimate
+	reviewPace        int // lines per minute the review time estimate assumes
+	snippetContext    int // lines around the commented ones in snippets
+}
+
+// copyToClipboard is replaced in tests to avoid writing escape sequences.
+var copyToClipboard = output.CopyToClipboard
+
+// patchDir is where exported hunk patches are written, or empty for the
+// review file directory; replaced in tests.
+var patchDir string
+
+// CommentsAddedMsg delivers comments written by another process, such as an
+// agent using the MCP server, into the running review.
+type CommentsAddedMsg struct {
+	Comments []comment.Comment
+}
+
+// ThreadsLoadedMsg delivers the comments already on the pull request for the
+// reviewed branch, fetched from Source by output.FetchThreads, to be shown
+// alongside the reviewer's own. Err reports why they couldn't be fetched.
+type ThreadsLoadedMsg struct {
+	Source   string
+	Comments []comment.Comment
+	Err      error
 }
 
-// NewRootModel creates the root model with the given git runner and base branch.
-func NewRootModel(gitRunner GitRunner, base string, width, height int) RootModel {
+// NewRootModel creates the root model for the changes source has against base.
+func NewRootModel(source DiffSource, base string, width, height int) RootModel {
+	return newBranchRootModel(source, base, false, width, height)
+}
+
+// NewRootModelWorktree creates a branch-mode root model that diffs the base
+// branch against the working tree, so uncommitted work on top of the branch is
+// part of the review. Hunks touched by uncommitted changes are tagged.
+func NewRootModelWorktree(source WorktreeSource, base string, width, height int) RootModel {
+	return newBranchRootModel(source, base, true, width, height)
+}
+
+func newBranchRootModel(source DiffSource, base string, includeDirty bool, width, height int) RootModel {
 	fileListWidth := 30
 
-	files, err := gitRunner.ChangedFiles(base)
-	if err != nil {
-		return RootModel{err: err}
+	// A failure is shown in the failure panel, from which the user can retry
+	// or pick another base.
+	var files []git.ChangedFile
+	var err error
+	if includeDirty {
+		files, err = source.(WorktreeSource).WorktreeChangedFiles(base)
+	} else {
+		files, err = source.ChangedFiles(base)
 	}
 
-	branch, _ := gitRunner.CurrentBranch()
+	branch, _ := source.CurrentBranch()
 
-	fl := NewFileList(files, fileListWidth, height-2)
+	fl := NewFileList(files, fileListWidth, height-3)
 	dv := NewDiffViewer(width-fileListWidth-3, height-2)
+	dv.SetGeneratedSource(generatedSource(source))
 	ci := NewCommentInput(width)
 
 	si := textinput.New()
@@ -104,15 +266,9 @@ func NewRootModel(gitRunner GitRunner, base string, width, height int) RootModel
 	si.CharLimit = 100
 	si.Width = width - 10
 
-	// Load the first file's diff if available
-	if len(files) > 0 {
-		if fd, err := gitRunner.FileDiff(base, files[0].Path); err == nil {
-			dv.SetDiff(fd)
-		}
-	}
-
-	return RootModel{
-		git:           gitRunner,
+	m := RootModel{
+		source:        source,
+		includeDirty:  includeDirty,
 		base:          base,
 		branch:        branch,
 		files:         files,
@@ -120,25 +276,35 @@ func NewRootModel(gitRunner GitRunner, base string, width, height int) RootModel
 		diffViewer:    dv,
 		commentInput:  ci,
 		searchInput:   si,
+		lineInput:     newLineInput(),
 		comments:      comment.NewStore(),
 		focus:         focusFileList,
 		width:         width,
 		height:        height,
 		fileListWidth: fileListWidth,
+		failure:       err,
+	}
+
+	// Load the first file's diff if available
+	if len(files) > 0 {
+		if fd, err := m.loadFileDiff(files[0].Path); err == nil {
+			m.diffViewer.SetDiff(fd)
+		}
 	}
+	m.countSize()
+
+	return m
 }
 
 // NewRootModelUncommitted creates the root model for reviewing uncommitted changes.
-func NewRootModelUncommitted(gitRunner GitRunner, width, height int) RootModel {
+func NewRootModelUncommitted(source WorktreeSource, width, height int) RootModel {
 	fileListWidth := 30
 
-	files, err := gitRunner.UncommittedFiles()
-	if err != nil {
-		return RootModel{err: err}
-	}
+	files, err := source.UncommittedFiles()
 
-	fl := NewFileList(files, fileListWidth, height-2)
+	fl := NewFileList(files, fileListWidth, height-3)
 	dv := NewDiffViewer(width-fileListWidth-3, height-2)
+	dv.SetGeneratedSource(generatedSource(source))
 	ci := NewCommentInput(width)
 
 	si := textinput.New()
@@ -146,27 +312,32 @@ func NewRootModelUncommitted(gitRunner GitRunner, width, height int) RootModel {
 	si.CharLimit = 100
 	si.Width = width - 10
 
+	m := RootModel{
+		source:         source,
+		mode:           modeUncommitted,
+		refreshTicking: true,
+		files:          files,
+		fileList:       fl,
+		diffViewer:     dv,
+		commentInput:   ci,
+		searchInput:    si,
+		lineInput:      newLineInput(),
+		comments:       comment.NewStore(),
+		focus:          focusFileList,
+		width:          width,
+		height:         height,
+		fileListWidth:  fileListWidth,
+		failure:        err,
+	}
+
 	// Load the first file's diff if available
 	if len(files) > 0 {
-		if fd, err := gitRunner.UncommittedFileDiff(files[0].Path); err == nil {
-			dv.SetDiff(fd)
+		if fd, err := m.loadFileDiff(files[0].Path); err == nil {
+			m.diffViewer.SetDiff(fd)
 		}
 	}
-
-	return RootModel{
-		git:           gitRunner,
-		mode:          modeUncommitted,
-		files:         files,
-		fileList:      fl,
-		diffViewer:    dv,
-		commentInput:  ci,
-		searchInput:   si,
-		comments:      comment.NewStore(),
-		focus:         focusFileList,
-		width:         width,
-		height:        height,
-		fileListWidth: fileListWidth,
-	}
+	m.countSize()
+	return m
 }
 
 // Init returns the initial command.
@@ -183,13 +354,15 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.fileList.SetSize(m.fileListWidth, m.height-2)
+		m.fileList.SetSize(m.fileListWidth, m.height-3)
 		m.diffViewer.SetSize(m.diffViewerWidth(), m.height-2)
 		m.commentInput.SetWidth(m.width)
+		m.shellOutput.SetSize(m.width, m.height)
 		return m, nil
 
 	case tickRefreshMsg:
 		if m.mode != modeUncommitted {
+			m.refreshTicking = false
 			return m, nil
 		}
 		if m.refreshInProgress {
@@ -198,22 +371,49 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.refreshInProgress = true
 		return m, m.refreshCmd()
 
+	case lfsResultMsg:
+		sel := m.fileList.SelectedFile()
+		if sel.Path != msg.path || !m.diffViewer.IsLFS() {
+			// The user moved on while the objects were fetched.
+			return m, nil
+		}
+		if msg.err != nil {
+			m.flash = "LFS fetch failed: " + msg.err.Error()
+			return m, nil
+		}
+		m.flash = ""
+		fd := msg.diff
+		fd.Status = sel.Status
+		if len(fd.Hunks) == 0 {
+			fd.Status = "B"
+		}
+		m.diffViewer.SetDiff(fd)
+		m.updateCommentMarkers()
+		return m, nil
+
 	case refreshResultMsg:
 		m.refreshInProgress = false
+		if m.mode != modeUncommitted {
+			// Mode was switched while the refresh was in flight.
+			return m, nil
+		}
 		if msg.err != nil {
-			return m, scheduleRefreshTick()
+			return m, tea.Batch(scheduleRefreshTick(), m.notify("Refresh failed, retrying: "+msg.err.Error()))
 		}
 
 		// Update file list
 		m.files = msg.files
 		m.fileList.SetFiles(msg.files)
+		m.churn = nil
+		m.countChurn()
+		m.countSize()
 
 		// Update diff only if the user is still on the same file
 		currentPath := ""
 		if len(m.files) > 0 {
 			currentPath = m.fileList.SelectedFile().Path
 		}
-		if msg.diff != nil && msg.requestedPath == currentPath {
+		if msg.diff != nil && msg.requestedPath == currentPath && m.comparing == "" {
 			m.diffViewer.RefreshDiff(msg.diff)
 			m.updateCommentMarkers()
 		} else if currentPath == "" {
@@ -224,30 +424,186 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, scheduleRefreshTick()
 
 	case CommentSubmitMsg:
-		m.comments.Add(comment.Comment{
+		c := comment.Comment{
 			FilePath:  msg.FilePath,
 			StartLine: msg.LineNo,
 			EndLine:   msg.EndLineNo,
 			LineType:  msg.LineType,
 			Body:      msg.Body,
-		})
+			Author:    m.author,
+			Thread:    msg.Thread,
+		}
+		if fd := m.diffViewer.Diff(); fd != nil && msg.LineType == git.LineContext {
+			c.OldStartLine = fd.OldLineNo(msg.LineNo)
+			c.OldEndLine = fd.OldLineNo(msg.EndLineNo)
+			c.NearStartLine, c.NearEndLine, c.NearRemoved = fd.NearestChange(msg.LineNo, max(msg.EndLineNo, msg.LineNo))
+		}
+		if old := m.comments.Get(c.FilePath, c.StartLine); old != nil {
+			// Editing a comment keeps it a question
+			c.Question = old.Question
+		}
+		m.comments.Add(c)
+		cmd := m.commentsChanged("Comment saved")
 		m.focus = focusDiffViewer
+		if m.autoAdvance {
+			m.diffViewer.jumpToNextChange()
+			m.markSeen()
+		}
 		m.updateCommentMarkers()
-		return m, nil
+		return m, cmd
+
+	case editorDoneMsg:
+		if m.focus != focusCommentInput {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.flash = "Editor failed: " + msg.err.Error()
+		}
+		var cmd tea.Cmd
+		m.commentInput, cmd = m.commentInput.Update(msg)
+		// The terminal may have been resized while the editor had it
+		return m, tea.Batch(cmd, tea.WindowSize())
 
 	case CommentCancelMsg:
 		m.focus = focusDiffViewer
 		return m, nil
 
+	case CommentsAddedMsg:
+		for _, c := range msg.Comments {
+			m.comments.Add(c)
+		}
+		m.updateCommentMarkers()
+		return m, m.notify(fmt.Sprintf("%d comment(s) added by %s", len(msg.Comments), authorOf(msg.Comments)))
+
+	case ThreadsLoadedMsg:
+		if msg.Err != nil {
+			return m, m.notify(fmt.Sprintf("Could not load comments from %s: %v", msg.Source, msg.Err))
+		}
+		m.threads = msg.Comments
+		m.updateCommentMarkers()
+		if len(msg.Comments) == 0 {
+			return m, nil
+		}
+		return m, m.notify(fmt.Sprintf("%d comment(s) already on %s", len(msg.Comments), msg.Source))
+
+	case toastExpiredMsg:
+		return m, m.expireToast(msg.id)
+
 	case OutputSelectMsg:
-		result, err := output.Deliver(msg.Target, m.output)
-		if err != nil {
-			m.outputSelector.SetError(err.Error())
+		review := output.Review{
+			Branch:      m.branch,
+			Base:        m.base,
+			Markdown:    m.output,
+			Comments:    m.reviewComments(),
+			Diffs:       m.commentedDiffs(),
+			Draft:       msg.Draft,
+			Verdict:     msg.Verdict,
+			PullRequest: m.pullRequest,
+			HostBranch:  m.hostBranch,
+		}
+		m.outputSelector.SetSending(msg.Target.Label)
+		return m, m.deliverCmd(msg.Target, review)
+
+	case deliveryDoneMsg:
+		if msg.fallback {
+			m.deliveryResult = fmt.Sprintf("%s failed: %v\n%s", msg.target.Label, msg.err, msg.result)
+			m.deliveries = append(m.deliveries, m.deliveryResult)
+			m.focus = focusDelivered
 			return m, nil
 		}
-		m.deliveryResult = result
-		m.finished = true
-		return m, tea.Quit
+		if msg.err != nil {
+			m.outputSelector.SetFailed(msg.err.Error())
+			return m, nil
+		}
+		if msg.target.Kind == output.TargetFile && msg.target.Path != "" {
+			path := msg.target.Path
+			if !strings.HasPrefix(path, "~/") {
+				path, _ = filepath.Abs(path)
+			}
+			m.lastFileDir = filepath.Dir(path)
+		}
+		m.deliveryResult = msg.result
+		m.deliveries = append(m.deliveries, msg.result)
+		m.focus = focusDelivered
+		return m, nil
+
+	case CommentDeleteMsg:
+		for _, c := range msg.Comments {
+			m.comments.Delete(c.FilePath, c.StartLine)
+		}
+		m.commentList.SetComments(m.comments.All())
+		m.updateCommentMarkers()
+		return m, m.commentsChanged(fmt.Sprintf("%d comment(s) deleted", len(msg.Comments)))
+
+	case CommentJumpMsg:
+		m.closeCommentList()
+		m.revealComment(msg.Comment)
+		return m, nil
+
+	case CommentListCloseMsg:
+		m.closeCommentList()
+		return m, nil
+
+	case shellDoneMsg:
+		if m.focus == focusShellOutput && msg.run == m.shellOutput.run {
+			output := msg.output
+			if msg.err != nil {
+				// What went wrong is worth the noise.
+				output += msg.stderr
+			}
+			m.shellOutput.SetResult(output, msg.err)
+		}
+		return m, nil
+
+	case ShellCloseMsg:
+		m.closeCommentList()
+		return m, nil
+
+	case ChunkSelectMsg:
+		m.selectChunk(msg.Chunk)
+		return m, nil
+
+	case ChunkSelectorCloseMsg:
+		m.closeCommentList()
+		return m, nil
+
+	case OutlineJumpMsg:
+		m.closeCommentList()
+		if m.SetPosition(msg.Path, msg.Line) && msg.Line == 0 {
+			m.diffViewer.JumpToHunk(msg.Hunk)
+		}
+		return m, nil
+
+	case OutlineCloseMsg:
+		m.closeCommentList()
+		return m, nil
+
+	case apiReportMsg:
+		if m.focus == focusAPIReport {
+			m.apiReport.SetResult(msg.findings, msg.errs)
+		}
+		return m, nil
+
+	case APIJumpMsg:
+		m.closeCommentList()
+		m.gotoFinding(msg.Finding)
+		return m, nil
+
+	case APICommentMsg:
+		m.closeCommentList()
+		if m.readOnly {
+			m.flash = "Browsing read-only; comments are off"
+			return m, nil
+		}
+		for _, f := range msg.Findings {
+			m.commentOnFinding(f)
+		}
+		m.updateCommentMarkers()
+		return m, m.commentsChanged(fmt.Sprintf("Added %d API comment(s)", len(msg.Findings)))
+
+	case APIReportCloseMsg:
+		m.closeCommentList()
+		return m, nil
 
 	case OutputCancelMsg:
 		m.quitting = true
@@ -260,29 +616,25 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			switched = m.fileList.SelectPrev()
 		}
-		if switched {
-			sel := m.fileList.SelectedFile()
-			if fd, err := m.loadFileDiff(sel.Path); err == nil {
-				m.diffViewer.SetDiff(fd)
-				if msg.direction < 0 {
-					m.diffViewer.SetCursorToEnd()
-				}
-				m.updateCommentMarkers()
+		if switched && m.openSelected() {
+			if msg.direction < 0 && !msg.fromTop {
+				m.diffViewer.SetCursorToEnd()
 			}
+			m.markSeen()
 		}
 		return m, nil
 
-	case finishMsg:
-		m.output = comment.Format(m.comments.All())
-		if m.output == "" {
-			m.finished = true
-			return m, tea.Quit
-		}
-		targets := output.DetectTargets(os.Getenv("TMUX"), os.Getenv("TMUX_PANE"))
-		m.outputSelector = NewOutputSelector(targets, m.width, m.height)
-		m.focus = focusOutputSelect
+	case linesChangedMsg:
+		m.updateCommentMarkers()
+		return m, nil
+
+	case gotoLineMsg:
+		m.gotoLine(msg.line)
 		return m, nil
 
+	case finishMsg:
+		return m.finish()
+
 	case tea.KeyMsg:
 		// Comment input gets priority when active
 		if m.focus == focusCommentInput {
@@ -291,6 +643,36 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
+		if m.focus == focusCommentList {
+			var cmd tea.Cmd
+			m.commentList, cmd = m.commentList.Update(msg)
+			return m, cmd
+		}
+
+		if m.focus == focusChunkSelect {
+			var cmd tea.Cmd
+			m.chunkSelector, cmd = m.chunkSelector.Update(msg)
+			return m, cmd
+		}
+
+		if m.focus == focusOutline {
+			var cmd tea.Cmd
+			m.outline, cmd = m.outline.Update(msg)
+			return m, cmd
+		}
+
+		if m.focus == focusAPIReport {
+			var cmd tea.Cmd
+			m.apiReport, cmd = m.apiReport.Update(msg)
+			return m, cmd
+		}
+
+		if m.focus == focusShellOutput {
+			var cmd tea.Cmd
+			m.shellOutput, cmd = m.shellOutput.Update(msg)
+			return m, cmd
+		}
+
 		// Output selector gets priority when active
 		if m.focus == focusOutputSelect {
 			var cmd tea.Cmd
@@ -298,6 +680,21 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
+		// After delivery, r resumes the review and any other key exits
+		if m.focus == focusDelivered {
+			if msg.String() == "r" {
+				m.focus = focusDiffViewer
+				return m, nil
+			}
+			m.finished = true
+			return m, tea.Quit
+		}
+
+		// The failure panel replaces the review until retried or dismissed
+		if m.failure != nil {
+			return m.handleFailureKey(msg)
+		}
+
 		// Search input gets priority when active
 		if m.searching {
 			switch msg.Type {
@@ -322,7 +719,38 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
-		return m.handleKeyMsg(msg)
+		// Line number input gets priority when active
+		if m.goingToLine {
+			switch msg.Type {
+			case tea.KeyEscape:
+				m.goingToLine = false
+				m.lineInput.Blur()
+				return m, nil
+			case tea.KeyEnter:
+				m.goingToLine = false
+				m.lineInput.Blur()
+				text := strings.TrimSpace(m.lineInput.Value())
+				if command, ok := strings.CutPrefix(text, "!"); ok {
+					return m.runShell(strings.TrimSpace(command))
+				}
+				if n, err := strconv.Atoi(text); err == nil && n > 0 {
+					m.gotoLine(n)
+				} else if text != "" {
+					m.flash = "Not a line number: " + text
+				}
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.lineInput, cmd = m.lineInput.Update(msg)
+			return m, cmd
+		}
+
+		updated, cmd := m.handleKeyMsg(msg)
+		if rm, ok := updated.(RootModel); ok {
+			rm.markSeen()
+			return rm, cmd
+		}
+		return updated, cmd
 	}
 
 	return m, nil
@@ -330,6 +758,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m RootModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
+	m.flash = ""
 
 	// Help overlay dismissal
 	if m.showHelp {
@@ -339,28 +768,55 @@ func (m RootModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Browse mode: commenting and finishing are disabled
+	if m.readOnly {
+		switch key {
+		case "c", "C", "D", "Q", "v", "V", "Z", "r":
+			return m, nil
+		}
+	}
+
+	// A comparison isn't the diff of the file comments would be attached to.
+	if m.comparing != "" {
+		switch key {
+		case "c", "D", "Q", "v", "r":
+			m.flash = "Comparing files; press m to go back to the diff and comment"
+			return m, nil
+		}
+	}
+
+	// m or ' then a letter sets or jumps to a mark; anything else cancels
+	if prefix := m.pendingMark; prefix != "" {
+		m.pendingMark = ""
+		if !isMarkName(key) {
+			return m, nil
+		}
+		if prefix == "m" {
+			return m.setMark(key)
+		}
+		return m.jumpToMark(key)
+	}
+
 	// ZZ key sequence
 	if key == "Z" {
 		if m.pendingZ {
 			m.pendingZ = false
-			m.output = comment.Format(m.comments.All())
-			if m.output == "" {
-				// No comments — quit directly
-				m.finished = true
-				return m, tea.Quit
-			}
-			// Show output selector
-			targets := output.DetectTargets(os.Getenv("TMUX"), os.Getenv("TMUX_PANE"))
-			m.outputSelector = NewOutputSelector(targets, m.width, m.height)
-			m.focus = focusOutputSelect
-			return m, nil
+			return m.finish()
 		}
 		m.pendingZ = true
 		return m, nil
 	}
 	m.pendingZ = false
 
+	// A file number applies only to the Enter or G typed right after it
+	if m.focus == focusFileList && !isCountKey(key) && key != "enter" && key != "l" {
+		m.fileList.TakeCount()
+	}
+
 	switch key {
+	case "u":
+		return m.toggleMode()
+
 	case "e":
 		m.hideFileList = !m.hideFileList
 		if m.hideFileList && m.focus == focusFileList {
@@ -369,6 +825,31 @@ func (m RootModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.diffViewer.SetSize(m.diffViewerWidth(), m.height-2)
 		return m, nil
 
+	case "m":
+		// In the diff, m names a mark for the line, unless it's comparing
+		// files or a file is marked for comparing.
+		if m.focus == focusDiffViewer && m.comparing == "" && m.compareMark == "" {
+			m.pendingMark = key
+			return m, nil
+		}
+		return m.compare()
+
+	case "'":
+		m.pendingMark = key
+		return m, nil
+
+	case "ctrl+]":
+		if m.focus == focusDiffViewer {
+			return m.jumpToDefinition()
+		}
+		return m, nil
+
+	case "ctrl+t":
+		return m.jumpBack()
+
+	case "V":
+		return m.judgeFile()
+
 	case "q":
 		m.quitting = true
 		return m, tea.Quit
@@ -386,6 +867,22 @@ func (m RootModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case ":":
+		if m.focus == focusDiffViewer {
+			m.goingToLine = true
+			m.lineInput.SetValue("")
+			m.lineInput.Focus()
+			return m, textinput.Blink
+		}
+		return m, nil
+
+	case "!":
+		m.goingToLine = true
+		m.lineInput.SetValue("!")
+		m.lineInput.CursorEnd()
+		m.lineInput.Focus()
+		return m, textinput.Blink
+
 	case "ctrl+d":
 		if m.focus == focusDiffViewer {
 			m.diffViewer, _ = m.diffViewer.Update(msg)
@@ -399,26 +896,37 @@ func (m RootModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "h":
+		if m.focus == focusDiffViewer && m.diffViewer.SetActiveSide(sideLeft) {
+			return m, nil
+		}
 		if m.focus == focusDiffViewer && !m.hideFileList {
 			m.focus = focusFileList
 		}
 		return m, nil
 
 	case "l", "enter":
+		if m.focus == focusDiffViewer && key == "l" {
+			m.diffViewer.SetActiveSide(sideRight)
+			return m, nil
+		}
+		if m.focus == focusDiffViewer && key == "enter" {
+			var cmd tea.Cmd
+			m.diffViewer, cmd = m.diffViewer.Update(msg)
+			return m, cmd
+		}
 		if m.focus == focusFileList {
-			m.focus = focusDiffViewer
-			// Load diff for selected file
-			sel := m.fileList.SelectedFile()
-			if fd, err := m.loadFileDiff(sel.Path); err == nil {
-				m.diffViewer.SetDiff(fd)
-				m.updateCommentMarkers()
+			if n := m.fileList.TakeCount(); n > 0 {
+				m.fileList.Select(n - 1)
 			}
+			m.focus = focusDiffViewer
+			m.openSelected()
 		}
 		return m, nil
 
 	case "c":
 		if m.focus == focusDiffViewer {
 			sel := m.fileList.SelectedFile()
+			m.commentInput.SetPaths(m.changedPaths())
 
 			if m.diffViewer.InVisualMode() {
 				// Range comment from visual selection
@@ -426,8 +934,8 @@ func (m RootModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				startLineNo := m.diffViewer.LineNoAt(vStart)
 				endLineNo := m.diffViewer.LineNoAt(vEnd)
 				lineType := git.LineContext
-				if dl := m.diffViewer.lineAt(vStart); dl != nil && dl.line != nil {
-					lineType = dl.line.Type
+				if l := m.diffViewer.targetLine(vStart); l != nil {
+					lineType = l.Type
 				}
 				m.diffViewer.ExitVisualMode()
 				m.commentInput.Activate(sel.Path, startLineNo, endLineNo, lineType, "")
@@ -437,14 +945,16 @@ func (m RootModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				line := m.diffViewer.CurrentLine()
 				if line != nil {
 					lineNo := m.diffViewer.CurrentLineNo()
-					existing := ""
-					if c := m.comments.Get(sel.Path, lineNo); c != nil {
-						existing = c.Body
+					if c := m.comments.Covering(sel.Path, lineNo, line.Type); c != nil {
+						// Edit the comment on the line, which may be on a
+						// range around it, in place.
+						m.commentInput.Activate(sel.Path, c.StartLine, c.EndLine, c.LineType, c.Body)
+					} else {
+						m.commentInput.Activate(sel.Path, lineNo, lineNo, line.Type, "")
 					}
-					m.commentInput.Activate(sel.Path, lineNo, lineNo, line.Type, existing)
 					m.focus = focusCommentInput
-				} else if sel.Status == "B" {
-					// Binary file: allow comment on file itself
+				} else if sel.Status == "B" || m.diffViewer.IsLFS() || m.diffViewer.ShowingDeps() || m.diffViewer.HidesGenerated() {
+					// Binary, LFS, or summarized file: allow comment on file itself
 					existing := ""
 					if c := m.comments.Get(sel.Path, 0); c != nil {
 						existing = c.Body
@@ -456,70 +966,1640 @@ func (m RootModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
-	case "D":
+	case "r":
 		if m.focus == focusDiffViewer {
-			lineNo := m.diffViewer.CurrentLineNo()
+			line := m.diffViewer.CurrentLine()
+			threads := m.threadsAtCursor()
+			if len(threads) == 0 {
+				m.flash = "No comments from the pull request on this line"
+				return m, nil
+			}
 			sel := m.fileList.SelectedFile()
-			m.comments.Delete(sel.Path, lineNo)
-			m.updateCommentMarkers()
+			lineNo := m.diffViewer.CurrentLineNo()
+			existing := ""
+			if c := m.comments.Get(sel.Path, lineNo); c != nil {
+				existing = c.Body
+			}
+			m.commentInput.SetPaths(m.changedPaths())
+			m.commentInput.ActivateReply(sel.Path, lineNo, line.Type, threads[0].Thread, threads[0].Author, existing)
+			m.focus = focusCommentInput
 		}
 		return m, nil
-	}
 
-	// Route to focused sub-model
-	switch m.focus {
-	case focusFileList:
-		var cmd tea.Cmd
-		m.fileList, cmd = m.fileList.Update(msg)
-		// Auto-load diff when selection changes
-		if key == "j" || key == "k" || key == "G" || key == "g" {
-			sel := m.fileList.SelectedFile()
-			if fd, err := m.loadFileDiff(sel.Path); err == nil {
-				m.diffViewer.SetDiff(fd)
-				m.updateCommentMarkers()
-			}
+	case "y", "Y":
+		if m.focus == focusDiffViewer {
+			m.copyReference(key == "Y")
 		}
-		return m, cmd
+		return m, nil
 
-	case focusDiffViewer:
-		var cmd tea.Cmd
+	case "U":
+		m.jumpToUnseen()
+		return m, nil
+
+	case "R":
+		m.jumpToRevised()
+		return m, nil
+
+	case "o":
+		m.setFileSort((m.fileList.Sort() + 1) % numFileSorts)
+		m.flash = "Files sorted by " + m.fileList.Sort().String()
+		return m, nil
+
+	case "f":
+		if m.diffViewer.IsLFS() {
+			return m, m.fetchLFS()
+		}
+		if _, ok := m.source.(contentReader); !ok {
+			m.flash = "Showing whole files needs a git repository"
+			return m, nil
+		}
+		m.fullFile = !m.fullFile
+		if err := m.applyContentSource(); err != nil {
+			m.flash = "Can't show the whole file: " + err.Error()
+		}
+		m.updateCommentMarkers()
+		return m, nil
+
+	case "S":
+		if m.focus == focusDiffViewer {
+			return m.summarizeHunk()
+		}
+		return m, nil
+
+	case "d":
+		m.diffViewer.ToggleDeps()
+		m.updateCommentMarkers()
+		return m, nil
+
+	case "b":
+		if _, ok := m.source.(ageBlamer); !ok {
+			m.flash = "Line ages need a git repository"
+			return m, nil
+		}
+		m.showAges = !m.showAges
+		m.applyAgeSource()
+		return m, nil
+
+	case "P":
+		if m.focus == focusDiffViewer {
+			m.exportPatch()
+		}
+		return m, nil
+
+	case "D":
+		if m.focus == focusDiffViewer && m.diffViewer.InVisualMode() {
+			return m.deleteSelectedComments()
+		}
+		if m.focus == focusDiffViewer {
+			sel := m.fileList.SelectedFile()
+			var c *comment.Comment
+			if l := m.diffViewer.CurrentLine(); l != nil {
+				c = m.comments.Covering(sel.Path, commentLineNo(l), l.Type)
+			}
+			if c == nil {
+				m.flash = "No comment on this line"
+				return m, nil
+			}
+			m.comments.Delete(sel.Path, c.StartLine)
+			cmd := m.commentsChanged("Comment deleted")
+			m.updateCommentMarkers()
+			return m, cmd
+		}
+		return m, nil
+
+	case "Q":
+		if m.focus == focusDiffViewer {
+			return m.toggleQuestion()
+		}
+		return m, nil
+
+	case "C":
+		m.commentList = NewCommentList(m.comments.All(), m.width, m.height)
+		m.focus = focusCommentList
+		return m, nil
+
+	case "p":
+		m.openChunkSelector()
+		return m, nil
+
+	case "s":
+		m.outline = NewOutline(m.allDiffs(), m.fileList.SelectedFile().Path, m.width, m.height)
+		m.focus = focusOutline
+		return m, nil
+
+	case "A":
+		return m.openAPIReport()
+	}
+
+	// Route to focused sub-model
+	switch m.focus {
+	case focusFileList:
+		var cmd tea.Cmd
+		m.fileList, cmd = m.fileList.Update(msg)
+		// Auto-load diff when selection changes
+		if key == "j" || key == "k" || key == "G" || key == "g" {
+			m.openSelected()
+		}
+		return m, cmd
+
+	case focusDiffViewer:
+		var cmd tea.Cmd
 		m.diffViewer, cmd = m.diffViewer.Update(msg)
 		return m, cmd
 	}
 
-	return m, nil
+	return m, nil
+}
+
+// toggleQuestion marks the comment on the current line as a question, or
+// back as a statement.
+func (m RootModel) toggleQuestion() (tea.Model, tea.Cmd) {
+	lineNo := 0
+	if m.diffViewer.CurrentLine() != nil {
+		lineNo = m.diffViewer.CurrentLineNo()
+	}
+	c := m.comments.Get(m.fileList.SelectedFile().Path, lineNo)
+	if c == nil {
+		m.flash = "No comment on this line"
+		return m, nil
+	}
+	toggled := *c
+	toggled.Question = !toggled.Question
+	m.comments.Add(toggled)
+	if toggled.Question {
+		return m, m.commentsChanged("Marked as a question")
+	}
+	return m, m.commentsChanged("Marked as a statement")
+}
+
+// newLineInput returns the input for a line number to go to.
+func newLineInput() textinput.Model {
+	li := textinput.New()
+	li.Prompt = ""
+	li.Placeholder = "line or !command"
+	return li
+}
+
+// gotoLine moves the diff cursor to new-file line n of the current file,
+// saying where it went instead when the diff doesn't show that line.
+func (m *RootModel) gotoLine(n int) {
+	if m.diffViewer.TotalLines() == 0 {
+		m.flash = "No lines to go to"
+		return
+	}
+	exact := m.diffViewer.GotoLine(n)
+	m.updateCommentMarkers()
+	if !exact {
+		m.flash = fmt.Sprintf("Line %d isn't in the diff; went to the nearest line, %d", n, m.diffViewer.CurrentLineNo())
+	}
+}
+
+// copyReference copies a path:line reference for the cursor line to the
+// clipboard, with the enclosing function from the hunk header if withFunc.
+func (m *RootModel) copyReference(withFunc bool) {
+	lineNo := m.diffViewer.CurrentLineNo()
+	if lineNo == 0 {
+		return
+	}
+	ref := fmt.Sprintf("%s:%d", m.fileList.SelectedFile().Path, lineNo)
+	if fn := m.diffViewer.CurrentFunction(); withFunc && fn != "" {
+		ref += " (" + fn + ")"
+	}
+	if err := copyToClipboard(ref); err != nil {
+		m.flash = "Copy failed: " + err.Error()
+		return
+	}
+	m.flash = "Copied " + ref
+}
+
+// markSeen records the hunk under the diff cursor as seen and updates the
+// file's progress in the file list. Files without hunks count as seen once
+// opened.
+func (m *RootModel) markSeen() {
+	fd := m.diffViewer.Diff()
+	if m.focus != focusDiffViewer || fd == nil || m.comparing != "" {
+		return
+	}
+	key := ""
+	if len(fd.Hunks) > 0 {
+		h := m.diffViewer.CurrentHunk()
+		if h < 0 {
+			return
+		}
+		key = fd.Hunks[h].Header
+	}
+	if m.seen == nil {
+		m.seen = make(map[string]map[string]bool)
+	}
+	path := m.fileList.SelectedFile().Path
+	if m.seen[path] == nil {
+		m.seen[path] = make(map[string]bool)
+	}
+	m.seen[path][key] = true
+	m.fileList.SetProgress(path, m.seenPercent(path, fd))
+}
+
+// seenPercent returns the percentage of the file's hunks that have been seen.
+func (m RootModel) seenPercent(path string, fd *git.FileDiff) int {
+	if len(fd.Hunks) == 0 {
+		if m.seen[path][""] {
+			return 100
+		}
+		return 0
+	}
+	n := 0
+	for _, h := range fd.Hunks {
+		if m.seen[path][h.Header] {
+			n++
+		}
+	}
+	return n * 100 / len(fd.Hunks)
+}
+
+// jumpToUnseen moves to the next hunk the cursor hasn't visited, searching
+// the rest of the current file and then the following files, wrapping around.
+func (m *RootModel) jumpToUnseen() {
+	m.jumpToHunk(m.firstUnseen, "All changes seen")
+}
+
+// jumpToRevised moves to the next hunk that changed since the previous review
+// session, searching forward from the cursor across files and wrapping around.
+func (m *RootModel) jumpToRevised() {
+	if m.revised == nil {
+		m.flash = "No earlier review to compare with"
+		return
+	}
+	m.jumpToHunk(func(_ string, fd *git.FileDiff, from int) int {
+		for h := max(from, 0); h < len(fd.Hunks); h++ {
+			if fd.Hunks[h].Revised {
+				return h
+			}
+		}
+		return -1
+	}, "Nothing changed since the last review")
+}
+
+// jumpToHunk moves to the next hunk picked by find, which returns the index of
+// the first match at or after from, or -1. It searches forward from the cursor
+// across files, wrapping around, and flashes none if nothing matches.
+func (m *RootModel) jumpToHunk(find func(path string, fd *git.FileDiff, from int) int, none string) {
+	files := m.fileList.Files()
+	if len(files) == 0 {
+		return
+	}
+	cur := m.fileList.SelectedIndex()
+	for i := 0; i <= len(files); i++ {
+		idx := (cur + i) % len(files)
+		fd := m.diffViewer.Diff()
+		if idx != cur || fd == nil {
+			var err error
+			if fd, err = m.loadFileDiff(files[idx].Path); err != nil {
+				continue
+			}
+		}
+		from := 0
+		if i == 0 && m.focus == focusDiffViewer {
+			// Only hunks after the cursor in the current file; earlier ones
+			// are reached after wrapping around.
+			from = m.diffViewer.CurrentHunk() + 1
+		}
+		hunk := find(files[idx].Path, fd, from)
+		if hunk < 0 {
+			continue
+		}
+		if idx != cur || m.diffViewer.Diff() != fd {
+			m.fileList.Select(idx)
+			m.diffViewer.SetDiff(fd)
+			m.updateCommentMarkers()
+		}
+		m.focus = focusDiffViewer
+		m.diffViewer.JumpToHunk(hunk)
+		return
+	}
+	m.flash = none
+}
+
+// firstUnseen returns the index of the first unseen hunk at or after from, or
+// -1. A file without hunks is one unseen unit at index 0.
+func (m RootModel) firstUnseen(path string, fd *git.FileDiff, from int) int {
+	if len(fd.Hunks) == 0 {
+		if from == 0 && !m.seen[path][""] {
+			return 0
+		}
+		return -1
+	}
+	for h := max(from, 0); h < len(fd.Hunks); h++ {
+		if !m.seen[path][fd.Hunks[h].Header] {
+			return h
+		}
+	}
+	return -1
+}
+
+// lfsResultMsg carries the diff of the Git LFS objects fetched for path.
+type lfsResultMsg struct {
+	path string
+	diff *git.FileDiff
+	err  error
+}
+
+// fetchLFS replaces the LFS pointer diff on screen with the diff of the
+// objects it points to. The pointer diff comes back when the file is reopened.
+func (m *RootModel) fetchLFS() tea.Cmd {
+	fetcher, ok := m.source.(lfsFetcher)
+	if !ok {
+		m.flash = "Fetching LFS objects needs a git repository"
+		return nil
+	}
+	old, new, _ := m.diffViewer.Diff().LFSPointers()
+	path := m.fileList.SelectedFile().Path
+	m.flash = "Fetching LFS objects…"
+	return func() tea.Msg {
+		fd, err := fetcher.LFSDiff(path, old, new)
+		return lfsResultMsg{path: path, diff: fd, err: err}
+	}
+}
+
+// exportPatch writes the hunk under the cursor, or the visual selection, to a
+// standalone patch file and reports its path.
+func (m *RootModel) exportPatch() {
+	hunks := m.diffViewer.PatchHunks()
+	m.diffViewer.ExitVisualMode()
+	if len(hunks) == 0 {
+		m.flash = "No changes to export"
+		return
+	}
+	sel := m.fileList.SelectedFile()
+	fd := git.FileDiff{Path: sel.Path, OldPath: m.diffViewer.Diff().OldPath, Status: sel.Status, Hunks: hunks}
+	f, err := os.CreateTemp(cmp.Or(patchDir, output.TempDir()), "revui-"+filepath.Base(sel.Path)+"-*.patch")
+	if err != nil {
+		m.flash = "Export failed: " + err.Error()
+		return
+	}
+	_, err = f.WriteString(fd.Patch())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		m.flash = "Export failed: " + err.Error()
+		return
+	}
+	m.flash = "Patch written to " + f.Name()
+}
+
+// finish formats the comments and shows the output selector, or quits
+// directly when there are no comments.
+func (m RootModel) finish() (tea.Model, tea.Cmd) {
+	m.output = m.formatComments()
+	if m.output == "" {
+		m.finished = true
+		return m, tea.Quit
+	}
+	targets := output.DetectTargets(os.Getenv("TMUX"), os.Getenv("TMUX_PANE"))
+	targets = append(targets, m.extraTargets...)
+	m.outputSelector = NewOutputSelector(targets, m.width, m.height)
+	m.outputSelector.SetFileDir(m.lastFileDir)
+	m.outputSelector.SetComments(m.comments.All())
+	m.focus = focusOutputSelect
+	return m, nil
+}
+
+// formatComments formats the review, with snippets if they are enabled. A
+// file whose diff can't be loaded gets no snippets.
+func (m RootModel) formatComments() string {
+	comments := m.reviewComments()
+	var out string
+	if !m.snippets {
+		out = comment.Format(comments)
+	} else {
+		out = comment.FormatSnippets(comments, m.commentedDiffs(), m.snippetContext)
+	}
+	if table := comment.FormatVerdicts(m.verdicts, comments); table != "" {
+		if out == "" {
+			return table
+		}
+		out = table + "\n" + out
+	}
+	return out
+}
+
+// reviewComments returns the comments as they go out in the review: with
+// references to other files using what they declare, if enabled.
+func (m RootModel) reviewComments() []comment.Comment {
+	comments := m.comments.All()
+	if !m.seeAlso || len(comments) == 0 {
+		return comments
+	}
+	return comment.SeeAlso(comments, m.commentedDiffs(), comment.NewIndex(m.allDiffs()))
+}
+
+// allDiffs returns the diffs of the files in the review, leaving out any
+// that can't be loaded.
+func (m RootModel) allDiffs() []*git.FileDiff {
+	diffs := make([]*git.FileDiff, 0, len(m.files))
+	for _, f := range m.files {
+		if fd, err := m.loadFileDiff(f.Path); err == nil {
+			diffs = append(diffs, fd)
+		}
+	}
+	return diffs
+}
+
+// commentedDiffs returns the diff of each file with comments. A file whose
+// diff can't be loaded maps to nil.
+func (m RootModel) commentedDiffs() map[string]*git.FileDiff {
+	diffs := make(map[string]*git.FileDiff)
+	for _, c := range m.comments.All() {
+		if _, ok := diffs[c.FilePath]; !ok {
+			diffs[c.FilePath], _ = m.loadFileDiff(c.FilePath)
+		}
+	}
+	return diffs
+}
+
+// deleteSelectedComments deletes the comments on the lines of the visual
+// selection, including ranges that only partly overlap it.
+func (m RootModel) deleteSelectedComments() (tea.Model, tea.Cmd) {
+	vStart, vEnd := m.diffViewer.VisualRange()
+	m.diffViewer.ExitVisualMode()
+	path := m.fileList.SelectedFile().Path
+	n := 0
+	for i := vStart; i <= vEnd; i++ {
+		l := m.diffViewer.targetLine(i)
+		if l == nil {
+			continue
+		}
+		for c := m.comments.Covering(path, commentLineNo(l), l.Type); c != nil; c = m.comments.Covering(path, commentLineNo(l), l.Type) {
+			m.comments.Delete(path, c.StartLine)
+			n++
+		}
+	}
+	if n == 0 {
+		m.flash = "No comments in the selection"
+		return m, nil
+	}
+	m.updateCommentMarkers()
+	return m, m.commentsChanged(fmt.Sprintf("%d comment(s) deleted", n))
+}
+
+// closeCommentList returns from the comment list, chunk selector, or shell
+// output to the diff, or to the file list when no file is open.
+func (m *RootModel) closeCommentList() {
+	m.focus = focusFileList
+	if m.diffViewer.Diff() != nil {
+		m.focus = focusDiffViewer
+	}
+}
+
+// runShell runs command, with % standing for the selected file, and shows
+// its output over the review.
+func (m RootModel) runShell(command string) (tea.Model, tea.Cmd) {
+	if command == "" {
+		return m, nil
+	}
+	command = expandShell(command, m.fileList.SelectedFile().Path)
+	m.shellRuns++
+	m.shellOutput = NewShellOutput(command, m.width, m.height)
+	m.shellOutput.run = m.shellRuns
+	m.focus = focusShellOutput
+	return m, runShell(m.workDir, command, "", m.shellRuns, false)
+}
+
+// summarizeHunk pipes the hunk under the cursor, or the visual selection, as
+// a patch to the configured summarize command, such as an LLM's command line
+// tool, and shows what it writes over the review.
+func (m RootModel) summarizeHunk() (tea.Model, tea.Cmd) {
+	if m.summarizeCommand == "" {
+		m.flash = "Set summarize_command in the config to summarize hunks"
+		return m, nil
+	}
+	hunks := m.diffViewer.PatchHunks()
+	m.diffViewer.ExitVisualMode()
+	if len(hunks) == 0 {
+		m.flash = "No changes to summarize"
+		return m, nil
+	}
+	sel := m.fileList.SelectedFile()
+	fd := git.FileDiff{Path: sel.Path, Status: sel.Status, Hunks: hunks}
+	title := "Summary of " + sel.Path
+	if len(hunks) == 1 {
+		title += " " + hunks[0].Header
+	}
+	m.shellRuns++
+	m.shellOutput = NewSummaryOutput(title, m.summarizeCommand, m.width, m.height)
+	m.shellOutput.run = m.shellRuns
+	m.focus = focusShellOutput
+	// Only what the command writes to stdout is the summary.
+	return m, runShell(m.workDir, m.summarizeCommand, fd.Patch(), m.shellRuns, true)
+}
+
+// judgeFile moves the selected file on to the next verdict: LGTM, needs
+// work, didn't review, and back to none.
+func (m RootModel) judgeFile() (tea.Model, tea.Cmd) {
+	if len(m.fileList.Files()) == 0 {
+		return m, nil
+	}
+	path := m.fileList.SelectedFile().Path
+	verdicts := maps.Clone(m.verdicts)
+	if verdicts == nil {
+		verdicts = make(map[string]comment.FileVerdict)
+	}
+	v := verdicts[path].Next()
+	if v == comment.FileUnjudged {
+		delete(verdicts, path)
+	} else {
+		verdicts[path] = v
+	}
+	m.verdicts = verdicts
+	m.fileList.SetVerdicts(verdicts)
+
+	done := path + ": no verdict"
+	if v != comment.FileUnjudged {
+		done = path + ": " + v.String()
+	}
+	if m.onVerdictsChanged == nil {
+		m.flash = done
+		return m, nil
+	}
+	if err := m.onVerdictsChanged(verdicts); err != nil {
+		return m, m.notify("Could not save verdicts: " + err.Error())
+	}
+	return m, m.notify(done)
+}
+
+// openChunkSelector offers the groups of files to review in passes: by
+// directory, and by commit when the source has history.
+func (m *RootModel) openChunkSelector() {
+	var byCommit []reviewChunk
+	if cl, ok := m.source.(commitLister); ok && m.mode == modeBranch {
+		if commits, err := cl.Commits(m.base); err == nil {
+			byCommit = commitChunks(commits, m.files)
+		}
+	}
+	m.chunkSelector = NewChunkSelector(directoryChunks(m.files), byCommit, len(m.files), m.chunk, m.width, m.height)
+	m.focus = focusChunkSelect
+}
+
+// selectChunk limits the file list to chunk's files and opens the first, or
+// lists every file again when chunk is nil.
+func (m *RootModel) selectChunk(chunk *reviewChunk) {
+	m.chunk = ""
+	var paths []string
+	if chunk != nil {
+		m.chunk = chunk.name
+		paths = chunk.paths
+	}
+	m.fileList.SetFilter(paths)
+	if chunk != nil {
+		m.fileList.Select(0)
+	}
+	m.focus = focusFileList
+	if len(m.fileList.Files()) > 0 {
+		m.openSelected()
+	}
+}
+
+// revealComment opens c's file and puts the diff cursor on its first line.
+func (m *RootModel) revealComment(c comment.Comment) {
+	idx := slices.IndexFunc(m.fileList.Files(), func(f git.ChangedFile) bool { return f.Path == c.FilePath })
+	if idx < 0 && m.chunk != "" {
+		// The file is outside the chunk being reviewed
+		m.selectChunk(nil)
+		idx = slices.IndexFunc(m.fileList.Files(), func(f git.ChangedFile) bool { return f.Path == c.FilePath })
+	}
+	if idx < 0 {
+		m.flash = c.FilePath + " isn't part of this view"
+		return
+	}
+	if idx != m.fileList.SelectedIndex() || m.diffViewer.Diff() == nil {
+		m.fileList.Select(idx)
+		if !m.openSelected() {
+			return
+		}
+	}
+	m.focus = focusDiffViewer
+	for i := 0; i < m.diffViewer.TotalLines(); i++ {
+		if l := m.diffViewer.targetLine(i); l != nil && commentLineNo(l) == c.StartLine &&
+			(l.Type == git.LineRemoved) == (c.LineType == git.LineRemoved) {
+			m.diffViewer.cursor = i
+			m.diffViewer.adjustScroll()
+			break
+		}
+	}
+	m.markSeen()
+}
+
+func (m *RootModel) updateCommentMarkers() {
+	m.fileList.SetCommentSeverities(m.comments.All())
+	sel := m.fileList.SelectedFile()
+	markers := make(map[int]bool)
+	threadMarkers := make(map[int]bool)
+	fileComments := m.comments.ForFile(sel.Path)
+	fileThreads := threadsForFile(m.threads, sel.Path)
+	if len(fileComments) > 0 || len(fileThreads) > 0 {
+		// Build a map of line numbers to flattened indices. Side-by-side rows
+		// carry a line on each side, so both are checked.
+		for i := 0; i < m.diffViewer.TotalLines(); i++ {
+			dl := m.diffViewer.lineAt(i)
+			if dl == nil {
+				continue
+			}
+			for _, l := range [...]*git.Line{dl.line, dl.left, dl.right} {
+				if l == nil {
+					continue
+				}
+				lineNo := commentLineNo(l)
+				for _, c := range fileComments {
+					if lineNo == c.StartLine {
+						markers[i] = true
+					}
+				}
+				if len(threadsOnLine(fileThreads, l)) > 0 {
+					threadMarkers[i] = true
+				}
+			}
+		}
+	}
+	m.diffViewer.SetCommentLines(markers)
+	m.diffViewer.SetThreadLines(threadMarkers)
+}
+
+// threadsForFile returns the pull request's comments on path.
+func threadsForFile(threads []comment.Comment, path string) []comment.Comment {
+	var result []comment.Comment
+	for _, c := range threads {
+		if c.FilePath == path {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// threadsOnLine returns the pull request's comments on l, matching the side
+// of the diff they were made on as well as the line number.
+func threadsOnLine(threads []comment.Comment, l *git.Line) []comment.Comment {
+	var result []comment.Comment
+	for _, c := range threads {
+		if c.StartLine == commentLineNo(l) && (c.LineType == git.LineRemoved) == (l.Type == git.LineRemoved) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// threadsAtCursor returns the pull request's comments on the line under the
+// cursor.
+func (m RootModel) threadsAtCursor() []comment.Comment {
+	line := m.diffViewer.CurrentLine()
+	if line == nil || len(m.threads) == 0 {
+		return nil
+	}
+	return threadsOnLine(threadsForFile(m.threads, m.fileList.SelectedFile().Path), line)
+}
+
+// threadSummary describes the pull request's comments on a line for the
+// status bar: who wrote the first and what it says, and how many follow.
+func threadSummary(threads []comment.Comment) string {
+	body, _, _ := strings.Cut(threads[0].Body, "\n")
+	summary := cmp.Or(threads[0].Author, "Someone") + ": " + runewidth.Truncate(body, 60, "…")
+	if n := len(threads) - 1; n > 0 {
+		summary += fmt.Sprintf(" (+%d more)", n)
+	}
+	return summary
+}
+
+// toggleMode switches between reviewing base→HEAD and reviewing the working
+// tree. Comments are kept; they reappear wherever the same paths are shown.
+func (m RootModel) toggleMode() (tea.Model, tea.Cmd) {
+	if _, ok := m.source.(WorktreeSource); !ok {
+		m.flash = "No working tree to review"
+		return m, nil
+	}
+	next := modeUncommitted
+	if m.mode == modeUncommitted {
+		if m.base == "" {
+			m.flash = "No base branch to compare with"
+			return m, nil
+		}
+		next = modeBranch
+	}
+
+	files, err := m.listFiles(next)
+	if err != nil {
+		m.failure = err
+		return m, nil
+	}
+	m.mode = next
+	m.showFiles(files)
+	// An empty list on its own looks like revui is broken
+	if len(files) == 0 {
+		if m.mode == modeUncommitted {
+			m.flash = "No uncommitted changes"
+		} else {
+			m.flash = "No changes against " + m.base
+		}
+	}
+
+	if m.mode == modeUncommitted && !m.refreshTicking {
+		m.refreshTicking = true
+		return m, scheduleRefreshTick()
+	}
+	return m, nil
+}
+
+// listFiles lists the changed files for mode.
+func (m RootModel) listFiles(mode reviewMode) ([]git.ChangedFile, error) {
+	switch {
+	case mode == modeUncommitted:
+		return m.source.(WorktreeSource).UncommittedFiles()
+	case m.includeDirty:
+		return m.source.(WorktreeSource).WorktreeChangedFiles(m.base)
+	default:
+		return m.source.ChangedFiles(m.base)
+	}
+}
+
+// changedPaths returns the paths of the files in the change, for completing
+// references to them in comments.
+func (m RootModel) changedPaths() []string {
+	paths := make([]string, len(m.files))
+	for i, f := range m.files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// showFiles replaces the listed files and opens the selected one.
+func (m *RootModel) showFiles(files []git.ChangedFile) {
+	m.files = files
+	m.compareMark, m.comparing = "", ""
+	m.chunk = ""
+	m.fileList.SetFilter(nil)
+	m.fileList.SetFiles(files)
+	m.churn = nil
+	m.countChurn()
+	m.countSize()
+	m.applyAgeSource()
+	m.diffViewer.SetDiff(nil)
+	m.applyContentSource()
+	if len(files) > 0 {
+		m.openSelected()
+	}
+	m.updateCommentMarkers()
+}
+
+// openSelected shows the selected file's diff. If it can't be loaded, the
+// failure panel reports why and false is returned.
+func (m *RootModel) openSelected() bool {
+	fd, err := m.loadFileDiff(m.fileList.SelectedFile().Path)
+	if err != nil {
+		m.failure = err
+		return false
+	}
+	m.comparing = ""
+	m.diffViewer.SetDiff(fd)
+	m.updateCommentMarkers()
+	return true
+}
+
+// compare marks the selected file for comparing with another, or, with a
+// file marked, shows the marked file as it was before the change against
+// the selected one as it is after, side by side. While comparing, it goes
+// back to the selected file's diff.
+func (m RootModel) compare() (tea.Model, tea.Cmd) {
+	sel := m.fileList.SelectedFile()
+	comparer, ok := m.source.(fileComparer)
+	switch {
+	case sel.Path == "":
+		return m, nil
+	case !ok:
+		m.flash = "Comparing files needs a git repository"
+		return m, nil
+	case m.comparing != "" && m.compareMark == "":
+		m.openSelected()
+		return m, nil
+	case m.compareMark == "":
+		m.compareMark = sel.Path
+		m.flash = "Marked " + sel.Path + "; press m on another file to compare them"
+		return m, nil
+	case m.compareMark == sel.Path:
+		m.compareMark = ""
+		m.flash = "Unmarked " + sel.Path
+		return m, nil
+	}
+
+	base, worktree := m.base, m.includeDirty
+	if m.mode == modeUncommitted {
+		base, worktree = "HEAD", true
+	}
+	fd, err := comparer.CompareFiles(base, worktree, m.compareMark, sel.Path)
+	if err != nil {
+		m.flash = "Compare failed: " + err.Error()
+		return m, nil
+	}
+	m.compareMark = ""
+	m.comparing = fd.Path
+	m.diffViewer.SetDiff(fd)
+	m.diffViewer.SetSideBySide(true)
+	m.focus = focusDiffViewer
+	if len(fd.Hunks) == 0 {
+		m.flash = "The files are the same"
+	}
+	return m, nil
+}
+
+// Mark is a line marked to jump back to: a file's path and the line number
+// under the diff cursor, as Position reports them.
+type Mark struct {
+	Path string
+	Line int
+}
+
+// isMarkName reports whether key can name a mark: a letter, as in vim.
+func isMarkName(key string) bool {
+	return len(key) == 1 && ('a' <= key[0] && key[0] <= 'z' || 'A' <= key[0] && key[0] <= 'Z')
+}
+
+// setMark marks the line under the diff cursor as name.
+func (m RootModel) setMark(name string) (tea.Model, tea.Cmd) {
+	path, line := m.Position()
+	if path == "" {
+		m.flash = "Open a file to mark a line in it"
+		return m, nil
+	}
+	marks := maps.Clone(m.marks)
+	if marks == nil {
+		marks = make(map[string]Mark)
+	}
+	marks[name] = Mark{Path: path, Line: line}
+	m.marks = marks
+	m.flash = fmt.Sprintf("Marked %s:%d as '%s", path, line, name)
+	return m, nil
+}
+
+// jumpToMark opens the file of mark name at its line.
+func (m RootModel) jumpToMark(name string) (tea.Model, tea.Cmd) {
+	mark, ok := m.marks[name]
+	switch {
+	case !ok:
+		m.flash = "Mark '" + name + " isn't set"
+	case !m.SetPosition(mark.Path, mark.Line):
+		m.flash = mark.Path + " isn't in the review"
+	}
+	return m, nil
+}
+
+// jumpToDefinition opens the line declaring a function the cursor's line
+// calls, when the change adds or rewrites that declaration, remembering the
+// line to go back to with jumpBack.
+func (m RootModel) jumpToDefinition() (tea.Model, tea.Cmd) {
+	l := m.diffViewer.CurrentLine()
+	if l == nil || l.Type == git.LineRemoved {
+		m.flash = "No call to a changed function on this line"
+		return m, nil
+	}
+	path, line := m.Position()
+	calls := comment.Calls(l.Content)
+	if len(calls) == 0 {
+		m.flash = "No call to a changed function on this line"
+		return m, nil
+	}
+	defs := comment.Definitions(m.allDiffs())
+	for _, name := range calls {
+		for _, d := range defs[name] {
+			if d.Path == path && d.Line == line {
+				continue
+			}
+			m.jumps = append(slices.Clone(m.jumps), Mark{path, line})
+			m.SetPosition(d.Path, d.Line)
+			m.flash = fmt.Sprintf("%s at %s:%d; ctrl+t goes back", name, d.Path, d.Line)
+			return m, nil
+		}
+	}
+	m.flash = "None of " + strings.Join(calls, ", ") + " is changed here"
+	return m, nil
+}
+
+// jumpBack returns to the line the last jumpToDefinition left.
+func (m RootModel) jumpBack() (tea.Model, tea.Cmd) {
+	if len(m.jumps) == 0 {
+		m.flash = "No jump to go back from"
+		return m, nil
+	}
+	last := m.jumps[len(m.jumps)-1]
+	m.jumps = m.jumps[:len(m.jumps)-1]
+	if !m.SetPosition(last.Path, last.Line) {
+		m.flash = last.Path + " isn't in the review"
+	}
+	return m, nil
+}
+
+// openAPIReport compares the exported APIs of the change's Go packages
+// before and after it, showing the report once it's done.
+func (m RootModel) openAPIReport() (tea.Model, tea.Cmd) {
+	reader, ok := m.source.(dirReader)
+	if !ok {
+		m.flash = "Comparing APIs needs a git repository"
+		return m, nil
+	}
+	dirs := goPackages(m.files)
+	if len(dirs) == 0 {
+		m.flash = "No Go packages changed"
+		return m, nil
+	}
+	base, worktree := m.base, m.includeDirty
+	if m.mode == modeUncommitted {
+		base, worktree = "HEAD", true
+	}
+	m.apiReport = NewAPIReport(len(dirs), m.width, m.height)
+	m.focus = focusAPIReport
+	return m, compareAPIs(reader, base, worktree, dirs)
+}
+
+// findingLine returns the diff line a finding is on, and the index of its
+// hunk, or nil if the diff doesn't show the line. A removed declaration is
+// looked for among the removed lines, and others among the new file's.
+func (m RootModel) findingLine(f apiFinding) (*git.FileDiff, *git.Line, int) {
+	fd, err := m.loadFileDiff(f.path())
+	if err != nil {
+		return nil, nil, 0
+	}
+	removed := f.change.Kind == apidiff.Removed
+	for hi, h := range fd.Hunks {
+		for i := range h.Lines {
+			l := &h.Lines[i]
+			if removed && l.Type == git.LineRemoved && l.OldLineNo == f.change.Line ||
+				!removed && l.Type != git.LineRemoved && l.NewLineNo == f.change.Line {
+				return fd, l, hi
+			}
+		}
+	}
+	return fd, nil, 0
+}
+
+// gotoFinding opens the file of a finding at its declaration.
+func (m *RootModel) gotoFinding(f apiFinding) {
+	_, l, hunk := m.findingLine(f)
+	line := f.change.Line
+	if f.change.Kind == apidiff.Removed {
+		line = 0
+	}
+	if !m.SetPosition(f.path(), line) {
+		m.flash = f.path() + " isn't in the review"
+		return
+	}
+	if l != nil && l.Type == git.LineRemoved {
+		m.diffViewer.JumpToHunk(hunk)
+	}
+}
+
+// commentOnFinding adds a finding to the review as a comment on its
+// declaration's line, or on its file if the diff doesn't show the line,
+// after any comment already there.
+func (m *RootModel) commentOnFinding(f apiFinding) {
+	fd, l, _ := m.findingLine(f)
+	c := comment.Comment{FilePath: f.path(), LineType: git.LineContext, Body: f.commentBody(), Author: m.author}
+	if l != nil {
+		c.StartLine, c.EndLine, c.LineType = commentLineNo(l), commentLineNo(l), l.Type
+		if l.Type == git.LineContext {
+			c.OldStartLine = fd.OldLineNo(c.StartLine)
+			c.OldEndLine = c.OldStartLine
+		}
+	}
+	if existing := m.comments.Get(c.FilePath, c.StartLine); existing != nil && existing.Thread == "" {
+		if strings.Contains(existing.Body, c.Body) {
+			return
+		}
+		c = *existing
+		c.Body += "\n\n" + f.commentBody()
+	}
+	m.comments.Add(c)
+}
+
+// Marks returns the marked lines by letter, for saving with the session.
+func (m RootModel) Marks() map[string]Mark {
+	return m.marks
+}
+
+// LoadMarks restores marks saved with a previous session.
+func (m *RootModel) LoadMarks(marks map[string]Mark) {
+	m.marks = marks
+}
+
+// handleFailureKey handles keys while the failure panel is shown: retry, pick
+// another base, go back to the review as it was, or quit. Comments are kept
+// whichever is chosen.
+func (m RootModel) handleFailureKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.changingBase {
+		switch msg.Type {
+		case tea.KeyEscape:
+			m.changingBase = false
+			m.baseInput.Blur()
+			return m, nil
+		case tea.KeyEnter:
+			m.changingBase = false
+			m.baseInput.Blur()
+			if base := strings.TrimSpace(m.baseInput.Value()); base != "" {
+				m.base = base
+				m.mode = modeBranch
+				m.retry()
+			}
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.baseInput, cmd = m.baseInput.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "r":
+		m.retry()
+	case "b":
+		if m.base != "" {
+			m.changingBase = true
+			m.baseInput = textinput.New()
+			m.baseInput.Prompt = "Base: "
+			m.baseInput.SetValue(m.base)
+			m.baseInput.Focus()
+			return m, textinput.Blink
+		}
+	case "esc":
+		m.failure = nil
+	case "q", "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// retry lists the files again and reopens the selected one, clearing the
+// failure panel if that works.
+func (m *RootModel) retry() {
+	files, err := m.listFiles(m.mode)
+	if err != nil {
+		m.failure = err
+		return
+	}
+	m.failure = nil
+	m.showFiles(files)
+	if m.failure == nil && m.mode == modeBranch {
+		m.flash = "Reloaded changes against " + m.base
+	}
+}
+
+// failureView shows a failed git command and what it printed, with the keys
+// to recover.
+func (m RootModel) failureView() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("A git command failed"))
+	s.WriteString("\n\n")
+	detail := m.failure.Error()
+	var cmdErr *git.CommandError
+	if errors.As(m.failure, &cmdErr) {
+		s.WriteString("  git " + strings.Join(cmdErr.Args, " ") + "\n\n")
+		detail = strings.TrimSpace(cmdErr.Stderr)
+	}
+	for _, line := range strings.Split(detail, "\n") {
+		s.WriteString("  " + line + "\n")
+	}
+	s.WriteString("\n  Your comments are kept.\n\n")
+	if m.changingBase {
+		s.WriteString("  " + m.baseInput.View() + "\n")
+		s.WriteString(footerStyle.Render("  [enter] review against this base  [esc] cancel"))
+		return s.String()
+	}
+	keys := "  [r] retry"
+	if m.base != "" {
+		keys += "  [b] change base"
+	}
+	keys += "  [esc] back to the review  [q] quit"
+	s.WriteString(footerStyle.Render(keys))
+	return s.String()
 }
 
-func (m *RootModel) updateCommentMarkers() {
-	sel := m.fileList.SelectedFile()
-	markers := make(map[int]bool)
-	fileComments := m.comments.ForFile(sel.Path)
-	if len(fileComments) > 0 {
-		// Build a map of line numbers to flattened indices
-		for i := 0; i < m.diffViewer.TotalLines(); i++ {
-			dl := m.diffViewer.lineAt(i)
-			if dl != nil && dl.line != nil {
-				lineNo := dl.line.NewLineNo
-				if dl.line.Type == git.LineRemoved {
-					lineNo = dl.line.OldLineNo
-				}
-				for _, c := range fileComments {
-					if lineNo == c.StartLine {
-						markers[i] = true
-					}
-				}
+// generatedSource returns the lookup of whether .gitattributes marks a
+// path as generated, from source if it can tell, remembering each answer;
+// nil if it can't.
+func generatedSource(source DiffSource) func(path string) (generated, set bool) {
+	checker, ok := source.(generatedChecker)
+	if !ok {
+		return nil
+	}
+	type attr struct{ generated, set bool }
+	cache := make(map[string]attr)
+	return func(path string) (bool, bool) {
+		a, ok := cache[path]
+		if !ok {
+			a.generated, a.set, _ = checker.GeneratedAttr(path)
+			cache[path] = a
+		}
+		return a.generated, a.set
+	}
+}
+
+// applyContentSource has the diff viewer show files whole, as they are after
+// the change, when full file view is on. It returns why the open file
+// couldn't be shown whole.
+func (m *RootModel) applyContentSource() error {
+	reader, ok := m.source.(contentReader)
+	if !m.fullFile || !ok {
+		return m.diffViewer.SetContentSource(nil)
+	}
+	worktree := m.mode == modeUncommitted || m.includeDirty
+	return m.diffViewer.SetContentSource(func(path string) (string, error) {
+		return reader.FileContent(worktree, path)
+	})
+}
+
+// applyAgeSource gives the diff viewer line ages from blame of the old side
+// when age indicators are on. Ages are cached per file until the mode changes.
+func (m *RootModel) applyAgeSource() {
+	blamer, ok := m.source.(ageBlamer)
+	if !m.showAges || !ok {
+		m.diffViewer.SetAgeSource(nil)
+		return
+	}
+	rev := m.base
+	if m.mode == modeUncommitted {
+		rev = "HEAD"
+	}
+	cache := make(map[string]map[int]time.Time)
+	m.diffViewer.SetAgeSource(func(path string) map[int]time.Time {
+		if ages, ok := cache[path]; ok {
+			return ages
+		}
+		// Files added on the branch have nothing to blame at the base;
+		// cache the failure so blame isn't rerun on every visit.
+		ages, err := blamer.BlameAges(rev, path)
+		if err != nil {
+			ages = map[int]time.Time{}
+		}
+		cache[path] = ages
+		return ages
+	})
+}
+
+// SetFallbackToFile makes a failed delivery save the review to a file and
+// finish, instead of returning to the output selector.
+func (m *RootModel) SetFallbackToFile(on bool) {
+	m.fallbackToFile = on
+}
+
+// SetLastFileDir sets the directory the "Write to file" prompt starts in.
+func (m *RootModel) SetLastFileDir(dir string) {
+	m.lastFileDir = dir
+}
+
+// LastFileDir returns the directory the review was last written to, so it can
+// be remembered for the next run.
+func (m RootModel) LastFileDir() string {
+	return m.lastFileDir
+}
+
+// SetExtraTargets adds configured destinations, such as webhooks, to the
+// output selector.
+func (m *RootModel) SetExtraTargets(targets []output.OutputTarget) {
+	m.extraTargets = targets
+}
+
+// SetOnCommentsChanged registers fn to be called with all comments whenever
+// the reviewer adds, edits, or deletes one, such as to save them. Its outcome
+// is shown as a toast.
+func (m *RootModel) SetOnCommentsChanged(fn func([]comment.Comment) error) {
+	m.onCommentsChanged = fn
+}
+
+// SetOnVerdictsChanged registers fn to be called with every file's verdict
+// whenever the reviewer gives or changes one, such as to save them.
+func (m *RootModel) SetOnVerdictsChanged(fn func(map[string]comment.FileVerdict) error) {
+	m.onVerdictsChanged = fn
+}
+
+// SetOnUnsent registers fn to keep what a hosting service didn't take when a
+// delivery fails in a way that may pass, with the error, so it can be sent
+// later. A failure to keep it is reported with the delivery's.
+func (m *RootModel) SetOnUnsent(fn func(output.OutputTarget, output.Review, error) error) {
+	m.onUnsent = fn
+}
+
+// SetOnDelivered registers fn to be told of every delivery of the review, with
+// what the target reported or why it failed, such as to keep a history.
+func (m *RootModel) SetOnDelivered(fn func(target output.OutputTarget, review output.Review, result string, err error)) {
+	m.onDelivered = fn
+}
+
+// deliveryDoneMsg carries the result of a delivery run by deliverCmd.
+type deliveryDoneMsg struct {
+	target output.OutputTarget
+	result string
+	err    error
+	// fallback is set when target failed with err and the review was
+	// written to the file in result instead.
+	fallback bool
+}
+
+// deliverCmd sends review to target in the background, so a slow or retrying
+// host doesn't freeze the TUI, telling the delivery hook how it went. A review
+// the host rejected is kept with the unsent hook, and with fallback to file on
+// a failed review is written to a file.
+func (m *RootModel) deliverCmd(target output.OutputTarget, review output.Review) tea.Cmd {
+	onDelivered, onUnsent := m.onDelivered, m.onUnsent
+	deliver := func(target output.OutputTarget) (string, error) {
+		result, err := output.Deliver(target, review)
+		if onDelivered != nil {
+			onDelivered(target, review, result, err)
+		}
+		return result, err
+	}
+	fallback := m.fallbackToFile && target.Kind != output.TargetFile
+	dir := cmp.Or(m.lastFileDir, output.TempDir())
+	return func() tea.Msg {
+		result, err := deliver(target)
+		var unsent *output.UnsentError
+		if errors.As(err, &unsent) && onUnsent != nil {
+			if qerr := onUnsent(target, unsent.Unsent, err); qerr != nil {
+				err = fmt.Errorf("%w (saving it to send later failed: %v)", err, qerr)
+			} else {
+				err = fmt.Errorf("%w; saved to send later with revui submit --retry", err)
+			}
+		}
+		if err != nil && fallback {
+			// Save the review rather than risk losing it; the failure is
+			// still reported alongside where it went.
+			file := output.OutputTarget{Kind: output.TargetFile, Path: output.DefaultFilePath(dir)}
+			if saved, ferr := deliver(file); ferr == nil {
+				return deliveryDoneMsg{target: target, result: saved, err: err, fallback: true}
 			}
 		}
+		return deliveryDoneMsg{target: target, result: result, err: err}
+	}
+}
+
+// commentsChanged runs the comments hook, toasting done or the hook's error.
+func (m *RootModel) commentsChanged(done string) tea.Cmd {
+	if m.onCommentsChanged == nil {
+		return nil
+	}
+	if err := m.onCommentsChanged(m.comments.All()); err != nil {
+		return m.notify("Could not save comments: " + err.Error())
+	}
+	return m.notify(done)
+}
+
+// SetReadOnly turns the model into a diff pager: commenting, visual
+// selection, and finishing are disabled and hidden from the status bar and help.
+func (m *RootModel) SetReadOnly(on bool) {
+	m.readOnly = on
+}
+
+// SetPullRequest sets the number of the pull request under review, which the
+// review is posted to rather than the one found by branch.
+func (m *RootModel) SetPullRequest(number int) {
+	m.pullRequest = number
+}
+
+// PullRequest returns the number of the pull request under review, or 0 if it
+// isn't known.
+func (m RootModel) PullRequest() int {
+	return m.pullRequest
+}
+
+// SetHostBranch sets the name the hosting service knows the reviewed branch
+// by, for finding its pull request, when the review names it otherwise, as
+// with a remote branch reviewed as origin/feature.
+func (m *RootModel) SetHostBranch(name string) {
+	m.hostBranch = name
+}
+
+// HostBranch returns the name set by SetHostBranch, or "".
+func (m RootModel) HostBranch() string {
+	return m.hostBranch
+}
+
+// SetAuthor sets the name recorded on comments the reviewer writes.
+func (m *RootModel) SetAuthor(name string) {
+	m.author = name
+}
+
+// SetRevisions marks hunks overlapping the given changes, keyed by file path,
+// as changed since the previous review session. R jumps between them.
+func (m *RootModel) SetRevisions(revised map[string][]git.Hunk) {
+	m.revised = revised
+	if fd := m.diffViewer.Diff(); fd != nil && len(m.files) > 0 {
+		git.MarkRevisedHunks(fd, revised[m.fileList.SelectedFile().Path])
+	}
+}
+
+// SetFileSort sets the file list order by name: path, status, churn, or
+// directory. o cycles through them.
+func (m *RootModel) SetFileSort(name string) error {
+	s, err := parseFileSort(name)
+	if err != nil {
+		return err
+	}
+	m.setFileSort(s)
+	return nil
+}
+
+// setFileSort reorders the file list. The selected file, and everything keyed
+// by path such as comments and progress, stays put.
+func (m *RootModel) setFileSort(s fileSort) {
+	m.fileList.SetSort(s, m.churn)
+	m.countChurn()
+}
+
+// countChurn counts the changed lines of files not yet counted when the file
+// list is sorted by churn, and re-sorts it. Files whose diff can't be loaded
+// count as unchanged.
+func (m *RootModel) countChurn() {
+	if m.fileList.Sort() != sortChurn {
+		return
+	}
+	if m.churn == nil {
+		m.churn = make(map[string]int)
+	}
+	var counts map[string]git.LineCount
+	counted := false
+	for _, f := range m.files {
+		if _, ok := m.churn[f.Path]; ok {
+			continue
+		}
+		if !counted {
+			counts, counted = m.lineCounts(), true
+		}
+		c, ok := counts[f.Path]
+		if !ok {
+			c = m.countLines(f.Path)
+		}
+		m.churn[f.Path] = c.Added + c.Removed
+	}
+	m.fileList.SetSort(sortChurn, m.churn)
+}
+
+// defaultReviewPace is the lines per minute the review time estimate assumes
+// unless configured.
+const defaultReviewPace = 10
+
+// SetReviewPace sets the lines per minute the review time estimate in the
+// header assumes. Zero or less uses the default.
+func (m *RootModel) SetReviewPace(linesPerMinute int) {
+	m.reviewPace = linesPerMinute
+}
+
+// countSize counts the lines added and removed across the files under
+// review. Files whose diff can't be loaded count as unchanged.
+func (m *RootModel) countSize() {
+	m.added, m.removed = 0, 0
+	counts := m.lineCounts()
+	for _, f := range m.files {
+		c, ok := counts[f.Path]
+		if !ok {
+			c = m.countLines(f.Path)
+		}
+		m.added += c.Added
+		m.removed += c.Removed
+	}
+}
+
+// lineCounts returns the changed lines of the files under review as the
+// source counts them in one go, or nil if it can't. Files it leaves out, such
+// as untracked ones, are counted by loading their diffs.
+func (m *RootModel) lineCounts() map[string]git.LineCount {
+	lc, ok := m.source.(lineCounter)
+	if !ok {
+		return nil
+	}
+	base, worktree := m.base, m.includeDirty
+	if m.mode == modeUncommitted {
+		base, worktree = "HEAD", true
+	}
+	counts, err := lc.LineCounts(base, worktree)
+	if err != nil {
+		return nil
+	}
+	return counts
+}
+
+// countLines counts the lines added and removed by the diff of path, or none
+// if it can't be loaded.
+func (m *RootModel) countLines(path string) git.LineCount {
+	var c git.LineCount
+	fd, err := m.loadFileDiff(path)
+	if err != nil {
+		return c
+	}
+	for _, h := range fd.Hunks {
+		for _, l := range h.Lines {
+			switch l.Type {
+			case git.LineAdded:
+				c.Added++
+			case git.LineRemoved:
+				c.Removed++
+			}
+		}
+	}
+	return c
+}
+
+// sizeText returns the changed line counts and how long reviewing them is
+// estimated to take, or "" when nothing changed.
+func (m RootModel) sizeText() string {
+	lines := m.added + m.removed
+	if lines == 0 {
+		return ""
+	}
+	pace := m.reviewPace
+	if pace <= 0 {
+		pace = defaultReviewPace
+	}
+	minutes := (lines + pace - 1) / pace
+	estimate := fmt.Sprintf("~%d min", minutes)
+	if minutes >= 60 {
+		estimate = fmt.Sprintf("~%dh %02dm", minutes/60, minutes%60)
+	}
+	return fmt.Sprintf("+%d −%d · %s", m.added, m.removed, estimate)
+}
+
+// toastDuration is how long each toast stays in the status bar.
+const toastDuration = 3 * time.Second
+
+// maxToasts bounds the queue so a burst of notifications doesn't take minutes
+// to drain.
+const maxToasts = 5
+
+// toast is a status bar notification for something that happened without a
+// key press, such as an autosave. Unlike the flash it goes away on its own.
+type toast struct {
+	id   int
+	text string
+}
+
+// toastExpiredMsg ends the toast with the given id.
+type toastExpiredMsg struct {
+	id int
+}
+
+// notify queues a toast. It returns the command that expires the toast if it
+// is shown right away; queued toasts get theirs when they reach the front.
+// A repeat of the newest toast, such as from a failing retry loop, is dropped.
+func (m *RootModel) notify(text string) tea.Cmd {
+	if n := len(m.toasts); n >= maxToasts || (n > 0 && m.toasts[n-1].text == text) {
+		return nil
+	}
+	m.toastSeq++
+	m.toasts = append(m.toasts, toast{id: m.toastSeq, text: text})
+	if len(m.toasts) > 1 {
+		return nil
+	}
+	return expireToast(m.toastSeq)
+}
+
+// expireToast removes the shown toast if it is id and starts the next one's
+// timer.
+func (m *RootModel) expireToast(id int) tea.Cmd {
+	if len(m.toasts) == 0 || m.toasts[0].id != id {
+		return nil
+	}
+	m.toasts = m.toasts[1:]
+	if len(m.toasts) == 0 {
+		return nil
+	}
+	return expireToast(m.toasts[0].id)
+}
+
+func expireToast(id int) tea.Cmd {
+	return tea.Tick(toastDuration, func(time.Time) tea.Msg {
+		return toastExpiredMsg{id: id}
+	})
+}
+
+// authorOf names who wrote comments, for notices about them.
+func authorOf(comments []comment.Comment) string {
+	if len(comments) > 0 && comments[0].Author != "" && !comment.MultipleAuthors(comments) {
+		return comments[0].Author
+	}
+	return "another session"
+}
+
+// SetFlash shows a one-off status message until the next key, for notices
+// from setup such as why the review opened read-only.
+func (m *RootModel) SetFlash(msg string) {
+	m.flash = msg
+}
+
+// SetFileNumbers sets whether the file list numbers its files.
+func (m *RootModel) SetFileNumbers(on bool) {
+	m.fileList.SetNumbered(on)
+}
+
+// SetAutoAdvance sets whether submitting a comment moves the cursor to the
+// next change.
+func (m *RootModel) SetAutoAdvance(on bool) {
+	m.autoAdvance = on
+}
+
+// SetMentions offers authors for completing @ mentions in comments.
+func (m *RootModel) SetMentions(authors []git.Author) {
+	m.commentInput.SetMentions(authors)
+}
+
+// SetDictionary spell checks comments against d as they're typed; nil turns
+// spell checking off.
+func (m *RootModel) SetDictionary(d *spell.Dictionary) {
+	m.commentInput.SetDictionary(d)
+}
+
+// SetWorkDir sets the directory shell commands run from the review run in,
+// which is where the changed files' paths are relative to.
+func (m *RootModel) SetWorkDir(dir string) {
+	m.workDir = dir
+}
+
+// SetSeeAlso sets whether comments on added declarations are followed in
+// the review by where other changed files mention what they declare.
+func (m *RootModel) SetSeeAlso(on bool) {
+	m.seeAlso = on
+}
+
+// SetSummarizeCommand sets the shell command S pipes a hunk to, as a patch,
+// to summarize it; empty turns summaries off.
+func (m *RootModel) SetSummarizeCommand(command string) {
+	m.summarizeCommand = command
+}
+
+// SetSnippetContext includes a snippet of the diff with each comment in the
+// output, with up to n lines of context around the commented lines.
+func (m *RootModel) SetSnippetContext(n int) {
+	m.snippets = true
+	m.snippetContext = n
+}
+
+// SetBase records the base branch used when toggling into branch mode from a
+// model created with NewRootModelUncommitted.
+func (m *RootModel) SetBase(base string) {
+	m.base = base
+	if m.branch == "" && m.source != nil {
+		m.branch, _ = m.source.CurrentBranch()
 	}
-	m.diffViewer.SetCommentLines(markers)
 }
 
 // loadFileDiff loads the diff for the given path based on the current review mode.
 func (m *RootModel) loadFileDiff(path string) (*git.FileDiff, error) {
-	if m.mode == modeUncommitted {
-		return m.git.UncommittedFileDiff(path)
+	var fd *git.FileDiff
+	var err error
+	copied := m.copySource(path)
+	switch {
+	case copied != "":
+		// A copy is diffed against the file it was made from, so that only
+		// what was changed after copying shows.
+		fd, err = m.source.(fileComparer).CompareFiles(m.base, m.includeDirty, copied, path)
+		if err == nil {
+			fd.Path, fd.Status = path, "C"
+		}
+	case m.mode == modeUncommitted:
+		fd, err = m.source.(WorktreeSource).UncommittedFileDiff(path)
+	case m.includeDirty:
+		fd, err = m.source.(WorktreeSource).WorktreeFileDiff(m.base, path)
+	default:
+		fd, err = m.source.FileDiff(m.base, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The changed file list knows what diffing one path can't, such as that
+	// it was renamed.
+	if f, ok := m.changedFile(path); ok && f.Status != "" {
+		fd.Status = f.Status
+	}
+	if m.revised != nil {
+		git.MarkRevisedHunks(fd, m.revised[path])
+	}
+	return fd, nil
+}
+
+// changedFile returns the listed changed file at path.
+func (m *RootModel) changedFile(path string) (git.ChangedFile, bool) {
+	i := slices.IndexFunc(m.files, func(f git.ChangedFile) bool { return f.Path == path })
+	if i < 0 {
+		return git.ChangedFile{}, false
 	}
-	return m.git.FileDiff(m.base, path)
+	return m.files[i], true
+}
+
+// copySource returns the file path is a copy of, if it is one that can be
+// diffed against it.
+func (m *RootModel) copySource(path string) string {
+	if _, ok := m.source.(fileComparer); !ok || m.mode == modeUncommitted {
+		return ""
+	}
+	if f, ok := m.changedFile(path); ok && f.Status == "C" {
+		return f.From
+	}
+	return ""
 }
 
 // diffViewerWidth returns the width for the diff viewer panel.
@@ -547,10 +2627,11 @@ func (m RootModel) refreshCmd() tea.Cmd {
 	if len(m.files) > 0 {
 		currentPath = m.fileList.SelectedFile().Path
 	}
-	gitRunner := m.git
+	// Refreshes only run in uncommitted mode, which needs a working tree.
+	worktree := m.source.(WorktreeSource)
 
 	return func() tea.Msg {
-		files, err := gitRunner.UncommittedFiles()
+		files, err := worktree.UncommittedFiles()
 		if err != nil {
 			return refreshResultMsg{err: err}
 		}
@@ -559,7 +2640,7 @@ func (m RootModel) refreshCmd() tea.Cmd {
 		if currentPath != "" {
 			for _, f := range files {
 				if f.Path == currentPath {
-					diff, _ = gitRunner.UncommittedFileDiff(currentPath)
+					diff, _ = worktree.UncommittedFileDiff(currentPath)
 					break
 				}
 			}
@@ -575,24 +2656,53 @@ func (m RootModel) refreshCmd() tea.Cmd {
 
 // View renders the full UI.
 func (m RootModel) View() string {
-	if m.err != nil {
-		return fmt.Sprintf("Error: %v\n\nPress q to quit.", m.err)
+	if m.showHelp {
+		return RenderHelp(m.readOnly)
 	}
 
-	if m.showHelp {
-		return RenderHelp()
+	if m.failure != nil {
+		return m.failureView()
 	}
 
 	if m.focus == focusOutputSelect {
 		return m.outputSelector.View()
 	}
 
+	if m.focus == focusCommentList {
+		return m.commentList.View()
+	}
+
+	if m.focus == focusShellOutput {
+		return m.shellOutput.View()
+	}
+
+	if m.focus == focusChunkSelect {
+		return m.chunkSelector.View()
+	}
+
+	if m.focus == focusOutline {
+		return m.outline.View()
+	}
+
+	if m.focus == focusAPIReport {
+		return m.apiReport.View()
+	}
+
+	if m.focus == focusDelivered {
+		return m.deliveredView()
+	}
+
 	var b strings.Builder
 
 	// Header
 	var headerText string
 	if m.mode == modeUncommitted {
 		headerText = " revui — uncommitted changes "
+	} else if m.base == "" {
+		// Static sources such as patch files have nothing to compare against.
+		headerText = fmt.Sprintf(" revui — %s ", m.branch)
+	} else if m.includeDirty {
+		headerText = fmt.Sprintf(" revui — %s → %s (+ uncommitted) ", m.base, m.branch)
 	} else {
 		headerText = fmt.Sprintf(" revui — %s → %s ", m.base, m.branch)
 	}
@@ -601,16 +2711,45 @@ func (m RootModel) View() string {
 		Foreground(lipgloss.Color("12")).
 		Render(headerText)
 	b.WriteString(header)
+	if size := m.sizeText(); size != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(" " + size))
+	}
+	if m.hideFileList {
+		// With no file list to show it, say which file this is.
+		room := m.width - lipgloss.Width(b.String())
+		b.WriteString(m.fileIndicator(room))
+	}
 	b.WriteString("\n")
 
 	// Set focus state for sub-models
 	m.fileList.focused = m.focus == focusFileList
 	m.diffViewer.focused = m.focus == focusDiffViewer
 
+	// Status bar or overlay input
+	var bottom string
+	if m.commentInput.Active() {
+		bottom = m.commentInput.View()
+	} else if m.searching {
+		bottom = lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("/") + m.searchInput.View()
+	} else if m.goingToLine {
+		bottom = lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render(":") + m.lineInput.View()
+	} else {
+		bottom = m.renderStatusBar()
+	}
+	// A comment editor taller than the status bar takes its rows from the
+	// panels, keeping the diff cursor in view above it.
+	panelHeight := m.height - 3
+	if extra := lipgloss.Height(bottom) - 1; extra > 0 {
+		panelHeight = max(panelHeight-extra, 1)
+		m.diffViewer.SetSize(m.diffViewerWidth(), m.height-2-extra)
+		m.diffViewer.adjustScroll()
+	}
+
 	// Diff viewer panel — expands to full width when file list is hidden
 	diffPanel := lipgloss.NewStyle().
 		Width(m.diffViewerWidth()).
-		Height(m.height - 3).
+		Height(panelHeight).
+		MaxHeight(panelHeight).
 		Render(m.diffViewer.View())
 
 	var content string
@@ -619,7 +2758,8 @@ func (m RootModel) View() string {
 	} else {
 		fileListPanel := lipgloss.NewStyle().
 			Width(m.fileListWidth).
-			Height(m.height - 3).
+			Height(panelHeight).
+			MaxHeight(panelHeight).
 			BorderRight(true).
 			BorderStyle(lipgloss.NormalBorder()).
 			BorderForeground(lipgloss.Color("240")).
@@ -628,23 +2768,82 @@ func (m RootModel) View() string {
 	}
 	b.WriteString(content)
 	b.WriteString("\n")
-
-	// Status bar or overlay input
-	if m.commentInput.Active() {
-		b.WriteString(m.commentInput.View())
-	} else if m.searching {
-		searchBar := lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("/") + m.searchInput.View()
-		b.WriteString(searchBar)
-	} else {
-		b.WriteString(m.renderStatusBar())
-	}
+	b.WriteString(bottom)
 
 	return b.String()
 }
 
+// fileIndicator returns where the review is, for the header when the file
+// list is hidden: the open file, its status, its number among the listed
+// files, and the cursor's line, fitted into width columns by shortening the path from the
+// start.
+func (m RootModel) fileIndicator(width int) string {
+	files := m.fileList.Files()
+	if len(files) == 0 {
+		return ""
+	}
+	position := fmt.Sprintf("  %d/%d", m.fileList.SelectedIndex()+1, len(files))
+	if lineNo := m.diffViewer.CurrentLineNo(); lineNo > 0 {
+		position += fmt.Sprintf("  L%d", lineNo)
+	}
+	const sep = " │ "
+	path := m.fileList.SelectedFile().Path
+	// The status is left out before the path is shortened.
+	if fd := m.diffViewer.Diff(); fd != nil && fd.Status != "" {
+		status := "  " + git.FileStatusString(fd.Status)
+		if runewidth.StringWidth(sep+path+status+position) <= width {
+			position = status + position
+		}
+	}
+	room := width - runewidth.StringWidth(sep+position)
+	if room < 2 {
+		return ""
+	}
+	if runewidth.StringWidth(path) > room {
+		path = "…" + runewidth.TruncateLeft(path, runewidth.StringWidth(path)-room+1, "")
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(sep) +
+		lipgloss.NewStyle().Bold(true).Render(path) +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(position)
+}
+
 func (m RootModel) renderStatusBar() string {
-	commentCount := len(m.comments.All())
-	status := fmt.Sprintf(" [c]omment  [v]isual  [Tab]view  [e]files  [q]uit  [ZZ]done  [?]help  │  %d comments", commentCount)
+	var status string
+	if m.readOnly {
+		status = " [Tab]view  [e]files  [u]ncommitted  [/]search  [q]uit  [?]help  │  read-only"
+	} else {
+		commentCount := len(m.comments.All())
+		status = fmt.Sprintf(" [c]omment  [v]isual  [Tab]view  [e]files  [u]ncommitted  [q]uit  [ZZ]done  [?]help  │  %d comments", commentCount)
+	}
+	if threads := m.threadsAtCursor(); m.focus == focusDiffViewer && len(threads) > 0 {
+		status = " ◆ " + threadSummary(threads)
+		if !m.readOnly {
+			status += "  │  [r]eply"
+		}
+	}
+	if m.flash != "" {
+		status = " " + m.flash
+	} else if len(m.toasts) > 0 {
+		status = " " + m.toasts[0].text
+	}
+	if m.chunk != "" {
+		status += "  │  pass: " + m.chunk
+	}
+	if m.comparing != "" {
+		status += "  │  comparing " + m.comparing + " (m: back)"
+	} else if m.compareMark != "" {
+		status += "  │  marked " + m.compareMark
+	}
+	if m.diffViewer.ShowingWholeFiles() {
+		status += "  │  whole file (f: changes only)"
+	}
+	if m.diffViewer.IsSideBySide() {
+		column := "new"
+		if m.diffViewer.ActiveSide() == sideLeft {
+			column = "old"
+		}
+		status += "  │  column: " + column
+	}
 
 	return lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240")).
@@ -661,7 +2860,95 @@ func (m RootModel) Finished() bool {
 	return m.finished
 }
 
-// DeliveryResult returns the status message from delivery (available after finish).
+// DeliveryResult returns the status messages of the deliveries made this
+// run, one per line, including those made before returning to the review.
 func (m RootModel) DeliveryResult() string {
-	return m.deliveryResult
+	return strings.Join(m.deliveries, "\n")
+}
+
+// Comments returns all review comments in the session.
+func (m RootModel) Comments() []comment.Comment {
+	if m.comments == nil {
+		return nil
+	}
+	return m.comments.All()
+}
+
+// LoadComments adds previously saved comments to the review, replacing any
+// comment on the same file and line.
+func (m *RootModel) LoadComments(comments []comment.Comment) {
+	if m.comments == nil {
+		return
+	}
+	for _, c := range comments {
+		m.comments.Add(c)
+	}
+	m.updateCommentMarkers()
+}
+
+// Verdicts returns the reviewer's verdict on each file given one.
+func (m RootModel) Verdicts() map[string]comment.FileVerdict {
+	return m.verdicts
+}
+
+// LoadVerdicts restores previously saved verdicts on files.
+func (m *RootModel) LoadVerdicts(verdicts map[string]comment.FileVerdict) {
+	m.verdicts = maps.Clone(verdicts)
+	m.fileList.SetVerdicts(m.verdicts)
+}
+
+// Position returns the open file and the line number under the diff
+// cursor, for resuming the review there; path is "" when no file is open.
+func (m RootModel) Position() (path string, line int) {
+	if m.diffViewer.Diff() == nil || len(m.fileList.Files()) == 0 {
+		return "", 0
+	}
+	return m.fileList.SelectedFile().Path, m.diffViewer.CurrentLineNo()
+}
+
+// SetPosition opens path with the cursor on line, or the nearest line the
+// diff shows, as Position reported them. It reports false, doing nothing,
+// for a path no longer in the review.
+func (m *RootModel) SetPosition(path string, line int) bool {
+	idx := slices.IndexFunc(m.fileList.Files(), func(f git.ChangedFile) bool { return f.Path == path })
+	if idx < 0 {
+		return false
+	}
+	m.fileList.Select(idx)
+	if !m.openSelected() {
+		return true
+	}
+	m.focus = focusDiffViewer
+	if line > 0 {
+		m.diffViewer.GotoLine(line)
+		m.updateCommentMarkers()
+	}
+	return true
+}
+
+// Base returns the base branch being reviewed against (empty in uncommitted
+// mode unless set with SetBase).
+func (m RootModel) Base() string {
+	return m.base
+}
+
+// Uncommitted returns whether the model is reviewing working tree changes.
+func (m RootModel) Uncommitted() bool {
+	return m.mode == modeUncommitted
+}
+
+// deliveredView shows the delivery result and how to leave or resume.
+func (m RootModel) deliveredView() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Review sent"))
+	s.WriteString("\n")
+	for _, line := range strings.Split(m.deliveryResult, "\n") {
+		s.WriteString("  " + line + "\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(footerStyle.Render("  [any key] exit  [r] return to review"))
+	return s.String()
 }
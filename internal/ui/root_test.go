@@ -1,10 +1,17 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
@@ -16,13 +23,20 @@ import (
 type mockGitRunner struct {
 	files []git.ChangedFile
 	diffs map[string]*git.FileDiff
+	err   error // returned by ChangedFiles and FileDiff when set
 }
 
 func (m *mockGitRunner) ChangedFiles(_ string) ([]git.ChangedFile, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
 	return m.files, nil
 }
 
 func (m *mockGitRunner) FileDiff(_ string, path string) (*git.FileDiff, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
 	if d, ok := m.diffs[path]; ok {
 		return d, nil
 	}
@@ -48,6 +62,14 @@ func (m *mockGitRunner) UncommittedFileDiff(path string) (*git.FileDiff, error)
 	return &git.FileDiff{Path: path}, nil
 }
 
+func (m *mockGitRunner) WorktreeChangedFiles(base string) ([]git.ChangedFile, error) {
+	return m.ChangedFiles(base)
+}
+
+func (m *mockGitRunner) WorktreeFileDiff(base, path string) (*git.FileDiff, error) {
+	return m.FileDiff(base, path)
+}
+
 func newTestRoot() RootModel {
 	mock := &mockGitRunner{
 		files: []git.ChangedFile{
@@ -245,6 +267,14 @@ func (d *dynamicMockGitRunner) UncommittedFileDiff(path string) (*git.FileDiff,
 	return &git.FileDiff{Path: path}, nil
 }
 
+func (d *dynamicMockGitRunner) WorktreeChangedFiles(base string) ([]git.ChangedFile, error) {
+	return d.ChangedFiles(base)
+}
+
+func (d *dynamicMockGitRunner) WorktreeFileDiff(base, path string) (*git.FileDiff, error) {
+	return d.FileDiff(base, path)
+}
+
 func TestRefreshCmd(t *testing.T) {
 	mock := &dynamicMockGitRunner{
 		filesResults: [][]git.ChangedFile{
@@ -496,23 +526,40 @@ func TestRootOutputSelectorCancel(t *testing.T) {
 	}
 }
 
+// sendReview selects a target and runs the delivery to completion.
+func sendReview(m RootModel, msg OutputSelectMsg) RootModel {
+	updated, cmd := m.Update(msg)
+	m = updated.(RootModel)
+	if cmd != nil {
+		updated, _ = m.Update(cmd())
+		m = updated.(RootModel)
+	}
+	return m
+}
+
 func TestRootOutputSelectorDeliverFile(t *testing.T) {
 	m := newTestRoot()
 	m.focus = focusOutputSelect
 	m.output = "## Test Review\n\nTest content"
 
 	target := output.OutputTarget{Kind: output.TargetFile, Label: "Write to file"}
-	updated, cmd := m.Update(OutputSelectMsg{Target: target})
-	m = updated.(RootModel)
+	m = sendReview(m, OutputSelectMsg{Target: target})
 
-	if !m.Finished() {
-		t.Error("successful delivery should set finished")
-	}
 	if m.DeliveryResult() == "" {
 		t.Error("delivery result should not be empty")
 	}
+	if m.focus != focusDelivered || !strings.Contains(m.View(), m.DeliveryResult()) {
+		t.Error("successful delivery should show the result in the TUI")
+	}
+
+	// Any key exits
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	m = updated.(RootModel)
+	if !m.Finished() {
+		t.Error("a key after delivery should set finished")
+	}
 	if cmd == nil {
-		t.Error("successful delivery should produce quit command")
+		t.Error("a key after delivery should produce quit command")
 	}
 
 	// Clean up temp file
@@ -525,6 +572,21 @@ func TestRootOutputSelectorDeliverFile(t *testing.T) {
 	}
 }
 
+func TestRootDeliveredReturnToReview(t *testing.T) {
+	m := newTestRoot()
+	m.focus = focusDelivered
+	m.deliveryResult = "Review loaded into tmux paste buffer."
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	m = updated.(RootModel)
+	if cmd != nil || m.Finished() {
+		t.Error("r should not exit")
+	}
+	if m.focus != focusDiffViewer {
+		t.Errorf("focus = %v, want the diff viewer", m.focus)
+	}
+}
+
 func TestRootOutputSelectorViewRendered(t *testing.T) {
 	m := newTestRoot()
 	m.focus = focusOutputSelect
@@ -645,3 +707,1771 @@ func TestFileListToggle_ViewOmitsFileListWhenHidden(t *testing.T) {
 		t.Error("hidden file list should not render cursor arrow ▸")
 	}
 }
+
+func TestRootSideBySideCommentMarkers(t *testing.T) {
+	m := newTestRoot()
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineRemoved, Body: "why?"})
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	m = updated.(RootModel)
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(RootModel)
+	updated, _ = m.Update(cmd())
+	m = updated.(RootModel)
+
+	// The removed line sits on the left of the paired row at index 2
+	if !m.diffViewer.commentLines[2] {
+		t.Errorf("expected comment marker on paired row, got %v", m.diffViewer.commentLines)
+	}
+}
+
+func TestRootSideBySideColumnFocus(t *testing.T) {
+	m := newTestRoot()
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	m = updated.(RootModel)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(RootModel)
+
+	// h focuses the old column before leaving the diff panel
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+	m = updated.(RootModel)
+	if m.focus != focusDiffViewer || m.diffViewer.ActiveSide() != sideLeft {
+		t.Fatalf("first h: focus = %d, side = %d; want diff viewer, left column", m.focus, m.diffViewer.ActiveSide())
+	}
+	if !strings.Contains(m.renderStatusBar(), "column: old") {
+		t.Error("status bar should show the active column")
+	}
+
+	// l returns to the new column
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	m = updated.(RootModel)
+	if m.diffViewer.ActiveSide() != sideRight {
+		t.Error("l should focus the new column")
+	}
+
+	// h twice leaves the diff panel
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+	m = updated.(RootModel)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+	m = updated.(RootModel)
+	if m.focus != focusFileList {
+		t.Error("h on the old column should move focus to the file list")
+	}
+}
+
+func TestRootToggleMode(t *testing.T) {
+	m := newTestRoot()
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, Body: "keep me"})
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if m.mode != modeUncommitted {
+		t.Fatal("u should switch to uncommitted mode")
+	}
+	if cmd == nil {
+		t.Error("switching to uncommitted mode should start the refresh tick")
+	}
+	if !strings.Contains(m.View(), "uncommitted changes") {
+		t.Error("header should reflect uncommitted mode")
+	}
+	if len(m.comments.All()) != 1 {
+		t.Error("comments should be preserved across mode switches")
+	}
+
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if m.mode != modeBranch {
+		t.Fatal("second u should switch back to branch mode")
+	}
+	if cmd != nil {
+		t.Error("switching to branch mode should not produce a command")
+	}
+
+	// Back to uncommitted while the previous tick loop is still pending
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if cmd != nil {
+		t.Error("should not start a second refresh loop while one is pending")
+	}
+}
+
+func TestRootToggleModeWithoutBase(t *testing.T) {
+	m := newTestRootUncommitted()
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if m.mode != modeUncommitted {
+		t.Error("toggle should be a no-op without a base branch")
+	}
+	if !strings.Contains(m.View(), "No base branch") {
+		t.Error("toggle without a base branch should say why")
+	}
+
+	m.SetBase("main")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if m.mode != modeBranch {
+		t.Error("toggle should switch to branch mode once a base is set")
+	}
+}
+
+func TestRootToggleModeWithoutWorktree(t *testing.T) {
+	source := &git.Static{Name: "patch", Files: []git.FileDiff{*makeTestDiff()}}
+	m := NewRootModel(source, "", 80, 24)
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if m.mode != modeBranch {
+		t.Error("toggle should be a no-op for a source without a working tree")
+	}
+	if !strings.Contains(m.View(), "No working tree") {
+		t.Error("toggle without a working tree should say why")
+	}
+}
+
+func TestRootFailurePanel(t *testing.T) {
+	m := newTestRoot()
+	mock := m.source.(*mockGitRunner)
+	mock.err = &git.CommandError{Args: []string{"diff", "main...HEAD"}, Stderr: "fatal: bad revision 'main...HEAD'\n"}
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, Body: "keep me"})
+
+	press := func(keys ...tea.KeyMsg) {
+		t.Helper()
+		for _, k := range keys {
+			updated, _ := m.Update(k)
+			m = updated.(RootModel)
+		}
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	view := m.View()
+	for _, want := range []string{"A git command failed", "git diff main...HEAD", "fatal: bad revision", "[b] change base"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("failure panel should show %q, got:\n%s", want, view)
+		}
+	}
+
+	// Retrying while git still fails keeps the panel
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if m.failure == nil {
+		t.Fatal("retry should keep the panel while git fails")
+	}
+
+	// Changing the base retries against it
+	mock.err = nil
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}})
+	if !m.changingBase {
+		t.Fatal("b should ask for a new base")
+	}
+	m.baseInput.SetValue("develop")
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.failure != nil || m.base != "develop" {
+		t.Errorf("failure = %v, base = %q; want the review reloaded against develop", m.failure, m.base)
+	}
+	if len(m.Comments()) != 1 {
+		t.Errorf("comments should survive the failure, have %d", len(m.Comments()))
+	}
+
+	// Esc goes back to the review as it was
+	mock.err = errors.New("boom")
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}}, tea.KeyMsg{Type: tea.KeyEscape})
+	if m.failure != nil || strings.Contains(m.View(), "boom") {
+		t.Error("esc should dismiss the failure panel")
+	}
+}
+
+func TestRootStartupFailure(t *testing.T) {
+	mock := &mockGitRunner{err: errors.New("bad base")}
+	m := NewRootModel(mock, "gone", 80, 24)
+	if !strings.Contains(m.View(), "bad base") {
+		t.Fatal("a failure listing files should show the failure panel")
+	}
+	mock.err = nil
+	mock.files = []git.ChangedFile{{Path: "main.go", Status: "M"}}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	m = updated.(RootModel)
+	if m.failure != nil || len(m.fileList.Files()) != 1 {
+		t.Errorf("retry should load the files, failure = %v", m.failure)
+	}
+}
+
+func TestRootToggleModeEmpty(t *testing.T) {
+	m := NewRootModel(&mockGitRunner{}, "main", 80, 24)
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if !strings.Contains(m.View(), "No uncommitted changes") {
+		t.Error("switching to an empty uncommitted mode should say so")
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if !strings.Contains(m.View(), "No changes against main") {
+		t.Error("switching to an empty branch mode should say so")
+	}
+}
+
+func TestRootRefreshResultIgnoredInBranchMode(t *testing.T) {
+	m := newTestRoot()
+	m.refreshInProgress = true
+	updated, cmd := m.Update(refreshResultMsg{files: []git.ChangedFile{{Path: "stale.go", Status: "A"}}})
+	m = updated.(RootModel)
+	if cmd != nil || len(m.files) != 2 {
+		t.Error("a refresh result arriving after switching to branch mode should be dropped")
+	}
+}
+
+func TestRootWorktreeMode(t *testing.T) {
+	fd := makeTestDiff()
+	fd.Hunks[0].Dirty = true
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{{Path: "main.go", Status: "M"}},
+		diffs: map[string]*git.FileDiff{"main.go": fd},
+	}
+	m := NewRootModelWorktree(mock, "main", 100, 24)
+
+	view := m.View()
+	if !strings.Contains(view, "(+ uncommitted)") {
+		t.Error("header should indicate uncommitted changes are included")
+	}
+	if !strings.Contains(view, "[uncommitted]") {
+		t.Error("dirty hunk header should be tagged")
+	}
+}
+
+func TestRootLoadComments(t *testing.T) {
+	m := newTestRoot()
+	m.LoadComments([]comment.Comment{
+		{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "restored"},
+	})
+	if len(m.Comments()) != 1 {
+		t.Fatalf("expected 1 comment, got %d", len(m.Comments()))
+	}
+	if !m.diffViewer.commentLines[3] {
+		t.Error("restored comment should be marked in the diff view")
+	}
+}
+
+func TestRootStaticSource(t *testing.T) {
+	fd := makeTestDiff()
+	source := &git.Static{Name: "changes.patch", Files: []git.FileDiff{*fd}}
+	m := NewRootModel(source, "", 100, 24)
+
+	view := m.View()
+	if !strings.Contains(view, "revui — changes.patch ") || strings.Contains(view, "→") {
+		t.Error("header should show only the source name when there is no base")
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(RootModel)
+	if m.mode != modeBranch || len(m.files) != 1 {
+		t.Error("toggling to uncommitted mode should be a no-op without a working tree")
+	}
+}
+
+func TestRootCtrlNPFileSwitching(t *testing.T) {
+	m := newTestRoot()
+	m.source.(*mockGitRunner).diffs["util.go"] = makeTestDiff()
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	m = updated.(RootModel)
+
+	press := func(key tea.KeyType) {
+		t.Helper()
+		updated, cmd := m.Update(tea.KeyMsg{Type: key})
+		m = updated.(RootModel)
+		if cmd == nil {
+			t.Fatal("expected a file navigation command")
+		}
+		updated, _ = m.Update(cmd())
+		m = updated.(RootModel)
+	}
+
+	press(tea.KeyCtrlN)
+	if got := m.fileList.SelectedFile().Path; got != "util.go" {
+		t.Fatalf("after ctrl+n: selected %q, want util.go", got)
+	}
+	if m.focus != focusDiffViewer {
+		t.Error("ctrl+n should keep focus in the diff viewer")
+	}
+
+	press(tea.KeyCtrlP)
+	if got := m.fileList.SelectedFile().Path; got != "main.go" {
+		t.Fatalf("after ctrl+p: selected %q, want main.go", got)
+	}
+	if m.diffViewer.cursor != 0 {
+		t.Errorf("ctrl+p should land at the top of the file, cursor = %d", m.diffViewer.cursor)
+	}
+}
+
+func TestRootAutoAdvance(t *testing.T) {
+	fd := &git.FileDiff{
+		Path: "main.go",
+		Hunks: []git.Hunk{{
+			Header:   "@@ -1,2 +1,4 @@",
+			OldStart: 1, OldCount: 2, NewStart: 1, NewCount: 4,
+			Lines: []git.Line{
+				{Content: "a", Type: git.LineContext, OldLineNo: 1, NewLineNo: 1},
+				{Content: "b", Type: git.LineAdded, NewLineNo: 2},
+				{Content: "c", Type: git.LineContext, OldLineNo: 2, NewLineNo: 3},
+				{Content: "d", Type: git.LineAdded, NewLineNo: 4},
+			},
+		}},
+	}
+
+	for _, on := range []bool{false, true} {
+		mock := &mockGitRunner{
+			files: []git.ChangedFile{{Path: "main.go", Status: "M"}},
+			diffs: map[string]*git.FileDiff{"main.go": fd},
+		}
+		m := NewRootModel(mock, "main", 80, 24)
+		m.SetAutoAdvance(on)
+		m.diffViewer.cursor = 2 // the first added line
+
+		updated, _ := m.Update(CommentSubmitMsg{FilePath: "main.go", LineNo: 2, EndLineNo: 2, LineType: git.LineAdded, Body: "nit"})
+		m = updated.(RootModel)
+
+		want := 2
+		if on {
+			want = 4
+		}
+		if m.diffViewer.cursor != want {
+			t.Errorf("autoAdvance=%v: cursor = %d, want %d", on, m.diffViewer.cursor, want)
+		}
+	}
+}
+
+func TestRootReadOnly(t *testing.T) {
+	m := newTestRoot()
+	m.SetReadOnly(true)
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	m = updated.(RootModel)
+	m.diffViewer.cursor = 2
+
+	for _, key := range []string{"c", "v", "Z", "Z"} {
+		updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = updated.(RootModel)
+		if cmd != nil {
+			t.Errorf("key %q should be ignored in read-only mode", key)
+		}
+	}
+	if m.focus != focusDiffViewer || m.diffViewer.InVisualMode() || m.finished {
+		t.Error("read-only mode should not comment, select, or finish")
+	}
+
+	view := m.View()
+	if strings.Contains(view, "[c]omment") || strings.Contains(view, "[ZZ]done") {
+		t.Error("status bar should hide comment and finish keys in read-only mode")
+	}
+	if !strings.Contains(view, "read-only") {
+		t.Error("status bar should indicate read-only mode")
+	}
+	if strings.Contains(RenderHelp(true), "Commenting") || !strings.Contains(RenderHelp(false), "Commenting") {
+		t.Error("help should omit the commenting section only in read-only mode")
+	}
+}
+
+func TestRootCommentListDeleteAndJump(t *testing.T) {
+	m := newTestRoot()
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "nit"})
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 4, EndLine: 4, Body: "ok"})
+	// Without a comments hook no toast timers are started, so every command
+	// is a list message to deliver.
+	var update func(tea.Msg)
+	update = func(msg tea.Msg) {
+		t.Helper()
+		updated, cmd := m.Update(msg)
+		m = updated.(RootModel)
+		if cmd != nil {
+			update(cmd())
+		}
+	}
+
+	update(runeKey('C'))
+	if m.focus != focusCommentList || !strings.Contains(m.View(), "Comments (2)") {
+		t.Fatal("C should open the comment list")
+	}
+	update(runeKey('d'))
+	update(runeKey('d'))
+	if len(m.Comments()) != 1 || m.comments.Get("main.go", 4) == nil {
+		t.Fatalf("dd should delete the first comment, have %+v", m.Comments())
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.focus != focusDiffViewer || m.diffViewer.CurrentLineNo() != 4 {
+		t.Errorf("enter should go to the comment, focus = %d, line = %d", m.focus, m.diffViewer.CurrentLineNo())
+	}
+}
+
+func TestRootVisualDeleteComments(t *testing.T) {
+	m := newTestRoot()
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "a"})
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "b"})
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 4, EndLine: 4, Body: "c"})
+
+	// Select "new line" and "another new", then D
+	for _, k := range []tea.KeyMsg{runeKey('l'), runeKey('j'), runeKey('j'), runeKey('j'), runeKey('v'), runeKey('j'), runeKey('D')} {
+		updated, _ := m.Update(k)
+		m = updated.(RootModel)
+	}
+	if len(m.Comments()) != 1 || m.comments.Get("main.go", 4) == nil {
+		t.Errorf("D in visual mode should delete the selected lines' comments, have %+v", m.Comments())
+	}
+	if m.diffViewer.InVisualMode() {
+		t.Error("deleting should leave visual mode")
+	}
+}
+
+func TestRootRangeCommentFromInside(t *testing.T) {
+	m := newTestRoot()
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 3, LineType: git.LineAdded, Body: "range"})
+
+	// c on "another new", the range's second line, edits the range
+	for _, k := range []tea.KeyMsg{runeKey('l'), runeKey('j'), runeKey('j'), runeKey('j'), runeKey('j'), runeKey('c')} {
+		updated, _ := m.Update(k)
+		m = updated.(RootModel)
+	}
+	if ci := m.commentInput; !ci.Active() || ci.lineNo != 2 || ci.endLineNo != 3 || ci.input.Value() != "range" {
+		t.Fatalf("c inside a range should edit it: lines %d-%d, %q", ci.lineNo, ci.endLineNo, ci.input.Value())
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	updated, _ = updated.(RootModel).Update(cmd())
+	m = updated.(RootModel)
+
+	// "old line" is removed line 2 of the old file, not in the range
+	for _, k := range []tea.KeyMsg{runeKey('k'), runeKey('k'), runeKey('D')} {
+		updated, _ := m.Update(k)
+		m = updated.(RootModel)
+	}
+	if l := m.diffViewer.CurrentLine(); l == nil || l.Content != "old line" {
+		t.Fatalf("cursor on %+v, want the removed line", l)
+	}
+	if len(m.Comments()) != 1 {
+		t.Error("D on a removed line should leave a range on new lines of the same numbers")
+	}
+
+	for _, k := range []tea.KeyMsg{runeKey('j'), runeKey('j'), runeKey('D')} {
+		updated, _ := m.Update(k)
+		m = updated.(RootModel)
+	}
+	if len(m.Comments()) != 0 {
+		t.Errorf("D inside a range should delete it, have %+v", m.Comments())
+	}
+}
+
+func TestRootFileNumberJump(t *testing.T) {
+	m := newTestRoot()
+	for _, k := range []tea.KeyMsg{runeKey('2'), {Type: tea.KeyEnter}} {
+		updated, _ := m.Update(k)
+		m = updated.(RootModel)
+	}
+	if m.fileList.SelectedFile().Path != "util.go" || m.focus != focusDiffViewer {
+		t.Errorf("2 Enter should open the second file, selected %q", m.fileList.SelectedFile().Path)
+	}
+
+	// A number followed by another key is dropped
+	m = newTestRoot()
+	for _, k := range []tea.KeyMsg{runeKey('2'), runeKey('b'), {Type: tea.KeyEnter}} {
+		updated, _ := m.Update(k)
+		m = updated.(RootModel)
+	}
+	if m.fileList.SelectedIndex() != 0 {
+		t.Error("a number should only apply to the key right after it")
+	}
+}
+
+func TestRootToasts(t *testing.T) {
+	m := newTestRoot()
+	if m.notify("first") == nil {
+		t.Fatal("the first toast should start its timer")
+	}
+	if m.notify("second") != nil || m.notify("second") != nil {
+		t.Error("queued toasts should wait for their turn")
+	}
+	if len(m.toasts) != 2 {
+		t.Fatalf("a repeated toast should be dropped, have %d", len(m.toasts))
+	}
+	if !strings.Contains(m.View(), "first") {
+		t.Error("the first toast should show in the status bar")
+	}
+
+	// A stale expiry is ignored; the right one moves on to the next toast
+	updated, cmd := m.Update(toastExpiredMsg{id: 99})
+	m = updated.(RootModel)
+	if cmd != nil || len(m.toasts) != 2 {
+		t.Error("an expiry for another toast should do nothing")
+	}
+	updated, cmd = m.Update(toastExpiredMsg{id: m.toasts[0].id})
+	m = updated.(RootModel)
+	if cmd == nil || !strings.Contains(m.View(), "second") {
+		t.Error("the next toast should show with its own timer")
+	}
+
+	// Toasts outlive key presses, unlike the flash
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	m = updated.(RootModel)
+	if !strings.Contains(m.View(), "second") {
+		t.Error("a key press should not clear the toast")
+	}
+}
+
+func TestRootCommentsChangedHookError(t *testing.T) {
+	m := newTestRoot()
+	m.SetOnCommentsChanged(func([]comment.Comment) error { return errors.New("disk full") })
+	updated, _ := m.Update(CommentSubmitMsg{FilePath: "main.go", LineNo: 2, EndLineNo: 2, LineType: git.LineAdded, Body: "nit"})
+	m = updated.(RootModel)
+	if !strings.Contains(m.View(), "Could not save comments: disk full") {
+		t.Error("a failed save should be reported")
+	}
+}
+
+func TestRootCommentsChangedHookAndExternalComments(t *testing.T) {
+	m := newTestRoot()
+	var saved []comment.Comment
+	calls := 0
+	m.SetOnCommentsChanged(func(all []comment.Comment) error {
+		calls++
+		saved = append([]comment.Comment(nil), all...)
+		return nil
+	})
+
+	updated, _ := m.Update(CommentSubmitMsg{FilePath: "main.go", LineNo: 2, EndLineNo: 2, LineType: git.LineAdded, Body: "mine"})
+	m = updated.(RootModel)
+	if calls != 1 || len(saved) != 1 {
+		t.Fatalf("hook should run once with the new comment, got %d calls, %v", calls, saved)
+	}
+
+	updated, _ = m.Update(CommentsAddedMsg{Comments: []comment.Comment{
+		{FilePath: "main.go", StartLine: 3, EndLine: 3, LineType: git.LineAdded, Body: "from agent"},
+	}})
+	m = updated.(RootModel)
+	if len(m.Comments()) != 2 {
+		t.Errorf("external comment should be added, have %d comments", len(m.Comments()))
+	}
+	if calls != 1 {
+		t.Error("external comments should not trigger the change hook")
+	}
+	if !m.diffViewer.commentLines[3] {
+		t.Error("external comment should get a marker")
+	}
+}
+
+func TestRootThreads(t *testing.T) {
+	m := newTestRoot()
+	var update func(tea.Msg)
+	update = func(msg tea.Msg) {
+		t.Helper()
+		updated, cmd := m.Update(msg)
+		m = updated.(RootModel)
+		if cmd != nil {
+			update(cmd())
+		}
+	}
+
+	updated, _ := m.Update(ThreadsLoadedMsg{Source: "Bitbucket PR: team/app", Comments: []comment.Comment{
+		{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "why?", Author: "Ann", Thread: "1"},
+		{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "because", Author: "Cy", Thread: "1"},
+	}})
+	m = updated.(RootModel)
+	m.toasts = nil
+	// Old line 2 is removed; the thread is on new line 2.
+	if m.diffViewer.threadLines[2] || !m.diffViewer.threadLines[3] {
+		t.Errorf("thread markers = %v, want only the added line 2", m.diffViewer.threadLines)
+	}
+	if len(m.Comments()) != 0 {
+		t.Error("the pull request's comments aren't the reviewer's")
+	}
+
+	update(runeKey('l'))
+	update(runeKey('r'))
+	if m.focus == focusCommentInput {
+		t.Fatal("r should only reply on a line with a thread")
+	}
+	for range 3 {
+		update(runeKey('j'))
+	}
+	if status := m.renderStatusBar(); !strings.Contains(status, "Ann: why? (+1 more)") || !strings.Contains(status, "[r]eply") {
+		t.Errorf("status bar = %q, want the thread", status)
+	}
+
+	update(runeKey('r'))
+	if m.focus != focusCommentInput || !strings.Contains(m.View(), "Reply to Ann:") {
+		t.Fatal("r should open a reply to the thread")
+	}
+	update(runeKey('k'))
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	want := []comment.Comment{{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "k", Thread: "1"}}
+	if got := m.Comments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("comments = %+v, want the reply %+v", got, want)
+	}
+}
+
+func TestRootThreadsLoadError(t *testing.T) {
+	m := newTestRoot()
+	updated, _ := m.Update(ThreadsLoadedMsg{Source: "Gerrit: review", Err: errors.New("401 Unauthorized")})
+	m = updated.(RootModel)
+	if !strings.Contains(m.View(), "Could not load comments from Gerrit: review: 401 Unauthorized") {
+		t.Error("a failed fetch should be reported")
+	}
+}
+
+func TestRootExtraTargets(t *testing.T) {
+	m := newTestRoot()
+	m.SetExtraTargets(output.WebhookTargets([]output.Webhook{{Name: "review-bot", URL: "https://example.com"}}))
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "nit"})
+
+	updated, _ := m.Update(finishMsg{})
+	m = updated.(RootModel)
+	if m.focus != focusOutputSelect {
+		t.Fatal("finishing with comments should open the output selector")
+	}
+	if !strings.Contains(m.View(), "Webhook: review-bot") {
+		t.Error("configured webhook should be offered as a target")
+	}
+}
+
+func TestRootCopyReference(t *testing.T) {
+	var copied string
+	old := copyToClipboard
+	copyToClipboard = func(s string) error { copied = s; return nil }
+	defer func() { copyToClipboard = old }()
+
+	fd := makeTestDiff()
+	fd.Hunks[0].Header = "@@ -1,3 +1,4 @@ func main() {"
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{{Path: "main.go", Status: "M"}},
+		diffs: map[string]*git.FileDiff{"main.go": fd},
+	}
+	m := NewRootModel(mock, "main", 100, 24)
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	m = updated.(RootModel)
+
+	tests := []struct {
+		cursor int
+		key    rune
+		want   string
+	}{
+		{cursor: 3, key: 'y', want: "main.go:2"},
+		{cursor: 2, key: 'y', want: "main.go:2"}, // removed line uses its old number
+		{cursor: 4, key: 'Y', want: "main.go:3 (func main() {)"},
+		{cursor: 0, key: 'y', want: ""}, // hunk header has no line
+	}
+	for _, tt := range tests {
+		copied = ""
+		m.diffViewer.cursor = tt.cursor
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{tt.key}})
+		m = updated.(RootModel)
+		if copied != tt.want {
+			t.Errorf("cursor %d, %c: copied %q, want %q", tt.cursor, tt.key, copied, tt.want)
+		}
+		if tt.want != "" && !strings.Contains(m.View(), "Copied "+tt.want) {
+			t.Errorf("status bar should confirm the copy of %q", tt.want)
+		}
+	}
+}
+
+func TestRootExportPatch(t *testing.T) {
+	dir := t.TempDir()
+	old := patchDir
+	patchDir = dir
+	defer func() { patchDir = old }()
+
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{{Path: "main.go", Status: "M"}},
+		diffs: map[string]*git.FileDiff{"main.go": makeTestDiff()},
+	}
+
+	tests := []struct {
+		name   string
+		keys   string
+		cursor int
+		want   string
+	}{
+		{
+			name:   "hunk under cursor",
+			cursor: 1,
+			keys:   "P",
+			want:   "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,4 @@\n package main\n-old line\n+new line\n+another new\n unchanged\n",
+		},
+		{
+			name:   "visual selection",
+			cursor: 3,
+			keys:   "vP",
+			want:   "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,4 @@\n package main\n old line\n+new line\n unchanged\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewRootModel(mock, "main", 100, 24)
+			updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+			m = updated.(RootModel)
+			m.diffViewer.cursor = tt.cursor
+			for _, k := range tt.keys {
+				updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+				m = updated.(RootModel)
+			}
+
+			matches, _ := filepath.Glob(filepath.Join(dir, "revui-main.go-*.patch"))
+			if len(matches) != 1 {
+				t.Fatalf("got %d patch files, want 1", len(matches))
+			}
+			defer os.Remove(matches[0])
+			got, err := os.ReadFile(matches[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("patch =\n%s\nwant:\n%s", got, tt.want)
+			}
+			if m.diffViewer.InVisualMode() {
+				t.Error("exporting should leave visual mode")
+			}
+			if !strings.Contains(m.View(), "Patch written to") {
+				t.Error("status bar should show where the patch was written")
+			}
+		})
+	}
+}
+
+func TestRootExportPatchRenamed(t *testing.T) {
+	dir := t.TempDir()
+	old := patchDir
+	patchDir = dir
+	defer func() { patchDir = old }()
+
+	renamed := makeTestDiff()
+	renamed.Path, renamed.OldPath = "main.go", "app.go"
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{{Path: "main.go", Status: "R"}},
+		diffs: map[string]*git.FileDiff{"main.go": renamed},
+	}
+	m := NewRootModel(mock, "main", 100, 24)
+	for _, k := range "lP" {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		m = updated.(RootModel)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "revui-main.go-*.patch"))
+	if len(matches) != 1 {
+		t.Fatalf("got %d patch files, want 1", len(matches))
+	}
+	got, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "diff --git a/app.go b/main.go\nrename from app.go\nrename to main.go\n--- a/app.go\n+++ b/main.go\n"
+	if !strings.HasPrefix(string(got), want) {
+		t.Errorf("patch =\n%s\nwant it to start:\n%s", got, want)
+	}
+}
+
+func TestRootJumpToUnseen(t *testing.T) {
+	twoHunks := makeTestDiff()
+	second := twoHunks.Hunks[0]
+	second.Header = "@@ -20,3 +21,4 @@"
+	twoHunks.Hunks = append(twoHunks.Hunks, second)
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{
+			{Path: "a.go", Status: "M"},
+			{Path: "b.go", Status: "M"},
+		},
+		diffs: map[string]*git.FileDiff{
+			"a.go": twoHunks,
+			"b.go": makeTestDiff(),
+		},
+	}
+	m := NewRootModel(mock, "main", 100, 24)
+	press := func(k rune) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		m = updated.(RootModel)
+	}
+
+	press('l')
+	if got := m.fileList.progress["a.go"]; got != 50 {
+		t.Errorf("after opening a.go: progress %d%%, want 50%%", got)
+	}
+
+	steps := []struct {
+		wantFile string
+		wantHunk int
+	}{
+		{"a.go", 1},
+		{"b.go", 0},
+	}
+	for _, s := range steps {
+		press('U')
+		if got := m.fileList.SelectedFile().Path; got != s.wantFile {
+			t.Fatalf("U: file %q, want %q", got, s.wantFile)
+		}
+		if got := m.diffViewer.CurrentHunk(); got != s.wantHunk {
+			t.Errorf("U: hunk %d, want %d", got, s.wantHunk)
+		}
+	}
+	if m.fileList.progress["a.go"] != 100 || m.fileList.progress["b.go"] != 100 {
+		t.Errorf("progress = %v, want both files at 100%%", m.fileList.progress)
+	}
+
+	press('U')
+	if m.fileList.SelectedFile().Path != "b.go" {
+		t.Error("U with everything seen should stay put")
+	}
+	if !strings.Contains(m.View(), "All changes seen") {
+		t.Error("status bar should say everything has been seen")
+	}
+}
+
+func TestRootJumpToRevised(t *testing.T) {
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{
+			{Path: "a.go", Status: "M"},
+			{Path: "b.go", Status: "M"},
+		},
+		diffs: map[string]*git.FileDiff{
+			"a.go": makeTestDiff(),
+			"b.go": makeTestDiff(),
+		},
+	}
+	m := NewRootModel(mock, "main", 100, 24)
+	press := func(k rune) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		m = updated.(RootModel)
+	}
+
+	press('R')
+	if !strings.Contains(m.View(), "No earlier review") {
+		t.Error("R without a previous session should say there's nothing to compare with")
+	}
+
+	start := mock.diffs["b.go"].Hunks[0].NewStart
+	m.SetRevisions(map[string][]git.Hunk{"b.go": {{NewStart: start, NewCount: 1}}})
+	press('R')
+	if got := m.fileList.SelectedFile().Path; got != "b.go" {
+		t.Fatalf("R: file %q, want b.go", got)
+	}
+	if m.focus != focusDiffViewer || m.diffViewer.CurrentHunk() != 0 {
+		t.Errorf("R should focus the revised hunk, got focus %v hunk %d", m.focus, m.diffViewer.CurrentHunk())
+	}
+	if !strings.Contains(m.View(), "[changed since last review]") {
+		t.Error("revised hunk header should be tagged")
+	}
+
+	m.SetRevisions(map[string][]git.Hunk{})
+	press('R')
+	if !strings.Contains(m.View(), "Nothing changed since the last review") {
+		t.Error("R with no revisions should say so")
+	}
+}
+
+func TestRootCycleFileSort(t *testing.T) {
+	bigger := makeTestDiff()
+	bigger.Hunks = append(bigger.Hunks, bigger.Hunks[0])
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{
+			{Path: "a.go", Status: "M"},
+			{Path: "b.go", Status: "M"},
+		},
+		diffs: map[string]*git.FileDiff{
+			"a.go": makeTestDiff(),
+			"b.go": bigger,
+		},
+	}
+	m := NewRootModel(mock, "main", 100, 24)
+	m.comments.Add(comment.Comment{FilePath: "a.go", StartLine: 1, EndLine: 1, Body: "keep"})
+
+	for range 2 {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+		m = updated.(RootModel)
+	}
+	if !strings.Contains(m.View(), "Files sorted by churn") {
+		t.Error("status bar should name the new order")
+	}
+	if got := m.fileList.Files()[0].Path; got != "b.go" {
+		t.Errorf("first file by churn = %q, want b.go", got)
+	}
+	if got := m.fileList.SelectedFile().Path; got != "a.go" {
+		t.Errorf("selected file = %q after re-sorting, want a.go", got)
+	}
+	if m.comments.Get("a.go", 1) == nil {
+		t.Error("comments should stay with their file")
+	}
+
+	if err := m.SetFileSort("size"); err == nil {
+		t.Error("expected an error for an unknown sort")
+	}
+}
+
+// lfsMockGitRunner adds LFS object fetching to mockGitRunner.
+type lfsMockGitRunner struct {
+	mockGitRunner
+	fetched *git.LFSPointer
+}
+
+func (m *lfsMockGitRunner) LFSDiff(path string, old, new *git.LFSPointer) (*git.FileDiff, error) {
+	m.fetched = new
+	fd := makeTestDiff()
+	fd.Path = path
+	return fd, nil
+}
+
+func makeLFSDiff() *git.FileDiff {
+	return &git.FileDiff{
+		Path:   "model.bin",
+		Status: "M",
+		Hunks: []git.Hunk{{
+			OldStart: 1, OldCount: 3, NewStart: 1, NewCount: 3,
+			Header: "@@ -1,3 +1,3 @@",
+			Lines: []git.Line{
+				{Type: git.LineContext, Content: "version https://git-lfs.github.com/spec/v1", OldLineNo: 1, NewLineNo: 1},
+				{Type: git.LineRemoved, Content: "oid sha256:aaaa", OldLineNo: 2},
+				{Type: git.LineRemoved, Content: "size 2048", OldLineNo: 3},
+				{Type: git.LineAdded, Content: "oid sha256:bbbb", NewLineNo: 2},
+				{Type: git.LineAdded, Content: "size 3145728", NewLineNo: 3},
+			},
+		}},
+	}
+}
+
+func TestRootLFSFile(t *testing.T) {
+	files := []git.ChangedFile{{Path: "model.bin", Status: "M"}}
+	press := func(m RootModel, k rune) RootModel {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		return updated.(RootModel)
+	}
+
+	// Without LFS support the pointers are summarized but can't be fetched
+	m := NewRootModel(&mockGitRunner{files: files, diffs: map[string]*git.FileDiff{"model.bin": makeLFSDiff()}}, "main", 100, 24)
+	view := m.View()
+	for _, want := range []string{"Git LFS object", "sha256:aaaa", "2.0 KiB", "sha256:bbbb", "3.0 MiB"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q", want)
+		}
+	}
+	m = press(m, 'l')
+	m = press(m, 'f')
+	if !strings.Contains(m.View(), "needs a git repository") {
+		t.Error("f without LFS support should say why")
+	}
+	m = press(m, 'c')
+	if m.focus != focusCommentInput {
+		t.Error("c on an LFS file should comment on the file")
+	}
+
+	lfs := &lfsMockGitRunner{mockGitRunner: mockGitRunner{files: files, diffs: map[string]*git.FileDiff{"model.bin": makeLFSDiff()}}}
+	m = NewRootModel(lfs, "main", 100, 24)
+	m = press(m, 'l')
+	updated, cmd := m.Update(runeKey('f'))
+	m = updated.(RootModel)
+	if cmd == nil || !strings.Contains(m.View(), "Fetching LFS objects") {
+		t.Fatal("f should fetch the objects in the background")
+	}
+	updated, _ = m.Update(cmd())
+	m = updated.(RootModel)
+	if lfs.fetched == nil || lfs.fetched.OID != "sha256:bbbb" {
+		t.Fatalf("fetched %+v, want the new pointer", lfs.fetched)
+	}
+	if m.diffViewer.IsLFS() || strings.Contains(m.View(), "Git LFS object") {
+		t.Error("after f the object diff should replace the pointer summary")
+	}
+}
+
+func TestRootDependencySummary(t *testing.T) {
+	files := []git.ChangedFile{{Path: "go.mod", Status: "M"}}
+	goMod := &git.FileDiff{Path: "go.mod", Status: "M", Hunks: []git.Hunk{{
+		OldStart: 5, OldCount: 3, NewStart: 5, NewCount: 3,
+		Header: "@@ -5,3 +5,3 @@",
+		Lines: []git.Line{
+			{Type: git.LineContext, Content: "require (", OldLineNo: 5, NewLineNo: 5},
+			{Type: git.LineRemoved, Content: "\tgithub.com/a/mod v1.4.0", OldLineNo: 6},
+			{Type: git.LineAdded, Content: "\tgithub.com/a/mod/v2 v2.0.1", NewLineNo: 6},
+			{Type: git.LineContext, Content: ")", OldLineNo: 7, NewLineNo: 7},
+		},
+	}}}
+	press := func(m RootModel, k rune) RootModel {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		return updated.(RootModel)
+	}
+
+	m := NewRootModel(&mockGitRunner{files: files, diffs: map[string]*git.FileDiff{"go.mod": goMod}}, "main", 120, 24)
+	view := m.View()
+	for _, want := range []string{"Dependencies — go.mod", "github.com/a/mod/v2", "v1.4.0 → v2.0.1", "major", "1 upgraded"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q:\n%s", want, view)
+		}
+	}
+	m = press(m, 'l')
+	m = press(m, 'd')
+	if m.diffViewer.ShowingDeps() || m.diffViewer.TotalLines() == 0 {
+		t.Fatal("d should show the diff's lines")
+	}
+	m = press(m, 'd')
+	if !m.diffViewer.ShowingDeps() {
+		t.Error("d again should show the summary")
+	}
+	m = press(m, 'c')
+	if m.focus != focusCommentInput {
+		t.Error("c on the summary should comment on the file")
+	}
+}
+
+// blamingMockGitRunner adds line ages to mockGitRunner.
+type blamingMockGitRunner struct {
+	mockGitRunner
+	revs []string
+}
+
+func (m *blamingMockGitRunner) BlameAges(rev, _ string) (map[int]time.Time, error) {
+	m.revs = append(m.revs, rev)
+	return map[int]time.Time{1: time.Now()}, nil
+}
+
+func TestRootToggleAges(t *testing.T) {
+	files := []git.ChangedFile{{Path: "main.go", Status: "M"}}
+	diffs := map[string]*git.FileDiff{"main.go": makeTestDiff()}
+	press := func(m RootModel, k rune) RootModel {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		return updated.(RootModel)
+	}
+
+	blamer := &blamingMockGitRunner{mockGitRunner: mockGitRunner{files: files, diffs: diffs}}
+	m := NewRootModel(blamer, "main", 100, 24)
+	m = press(m, 'l')
+	m = press(m, 'b')
+	if m.diffViewer.ages == nil {
+		t.Fatal("b should load line ages")
+	}
+	if len(blamer.revs) != 1 || blamer.revs[0] != "main" {
+		t.Errorf("blamed revs %v, want the base", blamer.revs)
+	}
+	m = press(m, 'b')
+	if m.diffViewer.ages != nil {
+		t.Error("second b should turn line ages off")
+	}
+
+	m = NewRootModel(&mockGitRunner{files: files, diffs: diffs}, "main", 100, 24)
+	m = press(m, 'b')
+	if m.showAges || !strings.Contains(m.View(), "Line ages need a git repository") {
+		t.Error("b without blame support should explain why nothing happened")
+	}
+}
+
+func TestRootRemembersFileDir(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestRoot()
+	m.SetLastFileDir("/somewhere/else")
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 1, EndLine: 1, Body: "nit"})
+
+	updated, _ := m.finish()
+	m = updated.(RootModel)
+	if m.outputSelector.fileDir != "/somewhere/else" {
+		t.Errorf("prompt directory = %q, want the last used one", m.outputSelector.fileDir)
+	}
+
+	target := output.OutputTarget{Kind: output.TargetFile, Label: "Write to file", Path: filepath.Join(dir, "review.md")}
+	m = sendReview(m, OutputSelectMsg{Target: target})
+	if m.LastFileDir() != dir {
+		t.Errorf("LastFileDir() = %q, want %q", m.LastFileDir(), dir)
+	}
+}
+
+func TestRootDeliveryFailure(t *testing.T) {
+	broken := output.OutputTarget{
+		Kind:    output.TargetWebhook,
+		Label:   "webhook: broken",
+		Webhook: &output.Webhook{Name: "broken", URL: "://not a url"},
+	}
+
+	tests := []struct {
+		name          string
+		fallback      bool
+		wantDelivered bool
+	}{
+		{name: "back to the selector", fallback: false, wantDelivered: false},
+		{name: "fallback to file", fallback: true, wantDelivered: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := newTestRoot()
+			m.SetFallbackToFile(tt.fallback)
+			m.SetLastFileDir(dir)
+			m.SetExtraTargets([]output.OutputTarget{broken})
+			m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 1, EndLine: 1, Body: "nit"})
+			updated, _ := m.finish()
+			m = updated.(RootModel)
+
+			m = sendReview(m, OutputSelectMsg{Target: broken})
+			if delivered := m.focus == focusDelivered; delivered != tt.wantDelivered {
+				t.Fatalf("delivered = %v, want %v", delivered, tt.wantDelivered)
+			}
+			if !tt.fallback {
+				if m.focus != focusOutputSelect || !strings.Contains(m.View(), "(failed)") {
+					t.Error("a failed delivery should stay in the selector with the target marked")
+				}
+				return
+			}
+			if !strings.Contains(m.DeliveryResult(), "webhook: broken failed") ||
+				!strings.Contains(m.DeliveryResult(), "Review written to "+dir) {
+				t.Errorf("DeliveryResult() = %q, want the failure and the fallback file", m.DeliveryResult())
+			}
+			files, _ := filepath.Glob(filepath.Join(dir, "revui-review-*.md"))
+			if len(files) != 1 {
+				t.Errorf("got %d fallback files, want 1", len(files))
+			}
+		})
+	}
+}
+
+func TestRootOnDelivered(t *testing.T) {
+	broken := output.OutputTarget{
+		Kind:    output.TargetWebhook,
+		Label:   "webhook: broken",
+		Webhook: &output.Webhook{Name: "broken", URL: "://not a url"},
+	}
+	m := newTestRoot()
+	m.output = "## Review"
+	m.SetFallbackToFile(true)
+	m.SetLastFileDir(t.TempDir())
+	var got []string
+	m.SetOnDelivered(func(target output.OutputTarget, review output.Review, result string, err error) {
+		if review.Markdown != "## Review" {
+			t.Errorf("review = %+v, want the formatted review", review)
+		}
+		got = append(got, fmt.Sprintf("%s: %t", target.Label, err == nil && result != ""))
+	})
+
+	sendReview(m, OutputSelectMsg{Target: broken})
+	if want := []string{"webhook: broken: false", ": true"}; !slices.Equal(got, want) {
+		t.Errorf("deliveries = %q, want the failure and then the fallback file", got)
+	}
+}
+
+func TestRootUnsentDelivery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	target := output.BitbucketTargets([]output.Bitbucket{{URL: srv.URL, Workspace: "PROJ", Repo: "app", Token: "expired"}})[0]
+
+	m := newTestRoot()
+	m.branch = "feature"
+	var kept output.Review
+	m.SetOnUnsent(func(_ output.OutputTarget, review output.Review, err error) error {
+		kept = review
+		return nil
+	})
+	m.SetExtraTargets([]output.OutputTarget{target})
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "nit"})
+	updated, _ := m.finish()
+	m = updated.(RootModel)
+
+	m = sendReview(m, OutputSelectMsg{Target: target, Verdict: output.VerdictApprove})
+	if len(kept.Comments) != 1 || kept.Verdict != output.VerdictApprove || kept.Branch != "feature" {
+		t.Errorf("kept %+v, want the whole review", kept)
+	}
+	if !strings.Contains(m.View(), "saved to send later with revui submit --retry") {
+		t.Error("the failure should say the review was kept")
+	}
+}
+
+func TestRootDeliveryResultAccumulates(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestRoot()
+	m.output = "## Review"
+
+	for i, name := range []string{"first.md", "second.md"} {
+		target := output.OutputTarget{Kind: output.TargetFile, Label: "Write to file", Path: filepath.Join(dir, name)}
+		m = sendReview(m, OutputSelectMsg{Target: target})
+		if i == 0 {
+			// Return to the review before delivering again
+			updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+			m = updated.(RootModel)
+		}
+	}
+
+	want := "Review written to " + filepath.Join(dir, "first.md") + "\nReview written to " + filepath.Join(dir, "second.md")
+	if got := m.DeliveryResult(); got != want {
+		t.Errorf("DeliveryResult() = %q, want %q", got, want)
+	}
+	if strings.Contains(m.View(), "first.md") {
+		t.Error("the result screen should only show the latest delivery")
+	}
+}
+
+func TestRootSetFlash(t *testing.T) {
+	m := newTestRoot()
+	m.SetFlash("opened read-only")
+	if !strings.Contains(m.View(), "opened read-only") {
+		t.Error("the flash message should show in the status bar")
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	m = updated.(RootModel)
+	if strings.Contains(m.View(), "opened read-only") {
+		t.Error("the flash message should clear on the next key")
+	}
+}
+
+func TestRootCommentAuthor(t *testing.T) {
+	m := newTestRoot()
+	m.SetAuthor("Ann")
+	updated, _ := m.Update(CommentSubmitMsg{FilePath: "main.go", LineNo: 2, EndLineNo: 2, LineType: git.LineAdded, Body: "nit"})
+	m = updated.(RootModel)
+	if c := m.comments.Get("main.go", 2); c == nil || c.Author != "Ann" {
+		t.Errorf("comment = %+v, want it attributed to Ann", c)
+	}
+}
+
+func TestRootFormatCommentsSnippets(t *testing.T) {
+	m := newTestRoot()
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "nit"})
+	if got := m.formatComments(); strings.Contains(got, "```") {
+		t.Errorf("snippets should be off by default, got:\n%s", got)
+	}
+	m.SetSnippetContext(1)
+	want := "main.go\n- new L2 (added): nit\n  ```go\n    -old line\n  > +new line\n    +another new\n  ```\n"
+	if got := m.formatComments(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRootCommentOldLineNumbers(t *testing.T) {
+	m := newTestRoot()
+	// "unchanged" is line 4 of the new file and line 3 of the old one
+	updated, _ := m.Update(CommentSubmitMsg{FilePath: "main.go", LineNo: 4, EndLineNo: 4, LineType: git.LineContext, Body: "ok"})
+	m = updated.(RootModel)
+	c := m.comments.Get("main.go", 4)
+	if c == nil || c.OldStartLine != 3 || c.OldEndLine != 3 {
+		t.Fatalf("comment = %+v, want old lines 3-3", c)
+	}
+	if got := comment.Format(m.Comments()); !strings.Contains(got, "- new L4 (old L3, near change at L2-3): ok") {
+		t.Errorf("output should name both sides and the nearest change, got:\n%s", got)
+	}
+}
+
+func TestRootGotoLine(t *testing.T) {
+	press := func(m RootModel, keys ...tea.KeyMsg) RootModel {
+		for _, k := range keys {
+			updated, cmd := m.Update(k)
+			m = updated.(RootModel)
+			// Deliver the diff viewer's message for NG
+			if cmd != nil && k.String() == "G" {
+				if msg, ok := cmd().(gotoLineMsg); ok {
+					updated, _ = m.Update(msg)
+					m = updated.(RootModel)
+				}
+			}
+		}
+		return m
+	}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	m := press(newTestRoot(), enter, runeKey(':'), runeKey('3'), enter)
+	if got := m.diffViewer.CurrentLineNo(); got != 3 || m.flash != "" {
+		t.Errorf(":3 went to line %d with flash %q, want line 3", got, m.flash)
+	}
+
+	m = press(m, runeKey('4'), runeKey('G'))
+	if got := m.diffViewer.CurrentLineNo(); got != 4 {
+		t.Errorf("4G went to line %d, want 4", got)
+	}
+
+	m = press(m, runeKey(':'), runeKey('9'), runeKey('9'), enter)
+	if !strings.Contains(m.flash, "Line 99 isn't in the diff") {
+		t.Errorf("flash = %q, want a note that line 99 isn't shown", m.flash)
+	}
+
+	m = press(m, runeKey(':'), runeKey('x'), enter)
+	if m.flash != "Not a line number: x" {
+		t.Errorf("flash = %q, want a note about the bad number", m.flash)
+	}
+}
+
+// countingMockGitRunner adds counting changed lines in one go to
+// mockGitRunner.
+type countingMockGitRunner struct {
+	mockGitRunner
+	counts   map[string]git.LineCount
+	base     string
+	worktree bool
+}
+
+func (m *countingMockGitRunner) LineCounts(base string, worktree bool) (map[string]git.LineCount, error) {
+	m.base, m.worktree = base, worktree
+	return m.counts, nil
+}
+
+func TestRootReviewSizeCounted(t *testing.T) {
+	files := []git.ChangedFile{{Path: "main.go", Status: "M"}, {Path: "new.go", Status: "A"}}
+	diffs := map[string]*git.FileDiff{"new.go": makeTestDiff()}
+	mock := &countingMockGitRunner{
+		mockGitRunner: mockGitRunner{files: files, diffs: diffs},
+		counts:        map[string]git.LineCount{"main.go": {Added: 10, Removed: 4}},
+	}
+
+	// Counted files aren't loaded; new.go, which isn't counted, is
+	m := NewRootModel(mock, "main", 80, 24)
+	if m.added != 12 || m.removed != 5 || mock.base != "main" || mock.worktree {
+		t.Errorf("size = +%d -%d counted against %s (worktree %v), want +12 -5 against main",
+			m.added, m.removed, mock.base, mock.worktree)
+	}
+
+	// Uncommitted changes are counted against HEAD, again on each refresh
+	m = NewRootModelUncommitted(mock, 80, 24)
+	if mock.base != "HEAD" || !mock.worktree {
+		t.Errorf("counted against %s (worktree %v), want the working tree against HEAD", mock.base, mock.worktree)
+	}
+	mock.counts["main.go"] = git.LineCount{Added: 1}
+	updated, _ := m.Update(refreshResultMsg{files: files, requestedPath: "main.go"})
+	m = updated.(RootModel)
+	if m.added != 3 || m.removed != 1 {
+		t.Errorf("size after refresh = +%d -%d, want +3 -1", m.added, m.removed)
+	}
+}
+
+func TestRootChurnCounted(t *testing.T) {
+	files := []git.ChangedFile{{Path: "a.go", Status: "M"}, {Path: "b.go", Status: "M"}}
+	mock := &countingMockGitRunner{
+		mockGitRunner: mockGitRunner{files: files},
+		counts: map[string]git.LineCount{
+			"a.go": {Added: 2},
+			"b.go": {Added: 1, Removed: 3},
+		},
+	}
+	m := NewRootModelUncommitted(mock, 100, 24)
+	if err := m.SetFileSort("churn"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.fileList.Files()[0].Path; got != "b.go" {
+		t.Errorf("first file by churn = %q, want b.go", got)
+	}
+
+	// A refresh counts the files again
+	mock.counts["a.go"] = git.LineCount{Added: 9}
+	updated, _ := m.Update(refreshResultMsg{files: files, requestedPath: "b.go"})
+	m = updated.(RootModel)
+	if got := m.fileList.Files()[0].Path; got != "a.go" {
+		t.Errorf("first file by churn after refresh = %q, want a.go", got)
+	}
+}
+
+func TestRootReviewSize(t *testing.T) {
+	m := newTestRoot()
+	// main.go removes one line and adds two; util.go has no diff
+	if m.added != 2 || m.removed != 1 {
+		t.Fatalf("size = +%d -%d, want +2 -1", m.added, m.removed)
+	}
+	if !strings.Contains(m.View(), "+2 −1 · ~1 min") {
+		t.Error("expected the size estimate in the header")
+	}
+
+	tests := []struct {
+		added, removed, pace int
+		want                 string
+	}{
+		{added: 0, removed: 0, want: ""},
+		{added: 25, removed: 5, want: "+25 −5 · ~3 min"},
+		{added: 25, removed: 5, pace: 30, want: "+25 −5 · ~1 min"},
+		{added: 800, removed: 260, want: "+800 −260 · ~1h 46m"},
+	}
+	for _, tt := range tests {
+		m.added, m.removed = tt.added, tt.removed
+		m.SetReviewPace(tt.pace)
+		if got := m.sizeText(); got != tt.want {
+			t.Errorf("sizeText() for +%d -%d at %d/min = %q, want %q", tt.added, tt.removed, tt.pace, got, tt.want)
+		}
+	}
+}
+
+func TestRootReviewChunks(t *testing.T) {
+	mock := &mockGitRunner{
+		files: changedFiles("api/server.go", "web/app.js", "api/routes.go"),
+		diffs: map[string]*git.FileDiff{"web/app.js": makeTestDiff()},
+	}
+	m := NewRootModel(mock, "main", 80, 24)
+
+	updated, _ := m.Update(runeKey('p'))
+	m = updated.(RootModel)
+	if m.focus != focusChunkSelect || !strings.Contains(m.View(), "api/  (2 files)") {
+		t.Fatalf("p should open the chunk selector:\n%s", m.View())
+	}
+
+	// Pick web/, the second chunk
+	for _, k := range []tea.KeyMsg{runeKey('j'), runeKey('j'), {Type: tea.KeyEnter}} {
+		var cmd tea.Cmd
+		updated, cmd = m.Update(k)
+		m = updated.(RootModel)
+		if cmd != nil {
+			updated, _ = m.Update(cmd())
+			m = updated.(RootModel)
+		}
+	}
+	if len(m.fileList.Files()) != 1 || m.fileList.SelectedFile().Path != "web/app.js" {
+		t.Fatalf("file list = %+v, want only web/app.js", m.fileList.Files())
+	}
+	if m.diffViewer.Diff() == nil || !strings.Contains(m.View(), "pass: web/") {
+		t.Error("the chunk's first file should be open, with the pass in the status bar")
+	}
+
+	// Going to a comment outside the chunk lists every file again
+	m.revealComment(comment.Comment{FilePath: "api/routes.go", StartLine: 1})
+	if m.chunk != "" || len(m.fileList.Files()) != 3 {
+		t.Error("revealing a comment outside the chunk should clear it")
+	}
+}
+
+func TestFileListToggle_HeaderNamesFile(t *testing.T) {
+	m := newTestRoot()
+	if strings.Contains(strings.SplitN(m.View(), "\n", 2)[0], "main.go") {
+		t.Error("header shouldn't name the file while the file list shows it")
+	}
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	m = updated.(RootModel)
+	header := strings.SplitN(m.View(), "\n", 2)[0]
+	if !strings.Contains(header, "main.go") || !strings.Contains(header, "modified") || !strings.Contains(header, "1/2") {
+		t.Errorf("header = %q, want the file, its status, and its position", header)
+	}
+	if got := m.fileIndicator(12); !strings.Contains(got, "…") {
+		t.Errorf("fileIndicator(12) = %q, want a shortened path", got)
+	}
+}
+
+// comparingMockGitRunner adds comparing two files to mockGitRunner.
+type comparingMockGitRunner struct {
+	mockGitRunner
+	compared []string
+}
+
+func (m *comparingMockGitRunner) CompareFiles(base string, worktree bool, oldPath, newPath string) (*git.FileDiff, error) {
+	m.compared = []string{base, oldPath, newPath}
+	fd := makeTestDiff()
+	fd.Path = oldPath + " → " + newPath
+	return fd, nil
+}
+
+func TestRootCompareFiles(t *testing.T) {
+	files := []git.ChangedFile{{Path: "old.go", Status: "D"}, {Path: "new.go", Status: "A"}}
+	press := func(m RootModel, k rune) RootModel {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		return updated.(RootModel)
+	}
+
+	m := NewRootModel(&mockGitRunner{files: files}, "main", 100, 24)
+	m = press(m, 'm')
+	if !strings.Contains(m.View(), "needs a git repository") {
+		t.Error("m without comparing support should say why")
+	}
+
+	src := &comparingMockGitRunner{mockGitRunner: mockGitRunner{files: files}}
+	m = NewRootModel(src, "main", 100, 24)
+	m = press(m, 'm')
+	if m.compareMark != "old.go" {
+		t.Fatalf("compareMark = %q, want old.go", m.compareMark)
+	}
+	m = press(m, 'j')
+	m = press(m, 'm')
+	if want := []string{"main", "old.go", "new.go"}; !reflect.DeepEqual(src.compared, want) {
+		t.Errorf("compared %v, want %v", src.compared, want)
+	}
+	if m.comparing != "old.go → new.go" || !m.diffViewer.IsSideBySide() || m.focus != focusDiffViewer {
+		t.Fatalf("comparing = %q, side by side %v, focus %d", m.comparing, m.diffViewer.IsSideBySide(), m.focus)
+	}
+	m = press(m, 'c')
+	if m.focus == focusCommentInput {
+		t.Error("c shouldn't comment on a comparison")
+	}
+	m = press(m, 'm')
+	if m.comparing != "" || m.diffViewer.Diff().Path != "new.go" {
+		t.Errorf("m should go back to new.go's diff, showing %q", m.diffViewer.Diff().Path)
+	}
+}
+
+func TestRootCopiedFile(t *testing.T) {
+	files := []git.ChangedFile{{Path: "copy.go", Status: "C", From: "main.go"}}
+	src := &comparingMockGitRunner{mockGitRunner: mockGitRunner{files: files}}
+	m := NewRootModel(src, "main", 100, 24)
+	if want := []string{"main", "main.go", "copy.go"}; !reflect.DeepEqual(src.compared, want) {
+		t.Errorf("compared %v, want %v", src.compared, want)
+	}
+	if fd := m.diffViewer.Diff(); fd.Path != "copy.go" || fd.Status != "C" {
+		t.Errorf("diff is of %q with status %q, want copy.go, C", fd.Path, fd.Status)
+	}
+	if !strings.Contains(m.View(), "copy.go ← main.go") {
+		t.Error("file list should show where the copy came from")
+	}
+}
+
+func TestRootFileVerdicts(t *testing.T) {
+	m := newTestRoot()
+	var saved map[string]comment.FileVerdict
+	m.SetOnVerdictsChanged(func(v map[string]comment.FileVerdict) error {
+		saved = v
+		return nil
+	})
+	press := func(k rune) {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		m = updated.(RootModel)
+	}
+
+	press('V')
+	if m.verdicts["main.go"] != comment.FileLGTM || saved["main.go"] != comment.FileLGTM {
+		t.Fatalf("V should mark main.go LGTM and save it, got %v, saved %v", m.verdicts, saved)
+	}
+	if !strings.Contains(m.fileList.View(), "✔") {
+		t.Error("the file list should mark main.go LGTM")
+	}
+	press('V')
+	press('j')
+	press('V')
+	press('V')
+	press('V')
+	want := "| File | Verdict | Comments |\n" +
+		"|------|---------|----------|\n" +
+		"| `main.go` | needs work | 0 |\n" +
+		"| `util.go` | didn't review | 0 |\n"
+	if got := m.formatComments(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+	press('V')
+	if _, ok := saved["util.go"]; ok || len(m.verdicts) != 1 {
+		t.Errorf("a fourth V should clear util.go's verdict, got %v", m.verdicts)
+	}
+
+	m.SetReadOnly(true)
+	press('V')
+	if len(m.verdicts) != 1 {
+		t.Error("V shouldn't judge files when browsing read-only")
+	}
+}
+
+func TestRootPosition(t *testing.T) {
+	m := newTestRoot()
+	m.SetPosition("main.go", 3)
+	if m.focus != focusDiffViewer {
+		t.Errorf("focus = %d, want focusDiffViewer", m.focus)
+	}
+	if path, line := m.Position(); path != "main.go" || line != 3 {
+		t.Errorf("Position = %s:%d, want main.go:3", path, line)
+	}
+
+	m.SetPosition("gone.go", 1)
+	if path, line := m.Position(); path != "main.go" || line != 3 {
+		t.Errorf("a file no longer in the review should be ignored, got %s:%d", path, line)
+	}
+}
+
+func TestRootToggleQuestion(t *testing.T) {
+	m := newTestRoot()
+	var saved []comment.Comment
+	m.SetOnCommentsChanged(func(c []comment.Comment) error {
+		saved = c
+		return nil
+	})
+	m.SetPosition("main.go", 2)
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'Q'}})
+	m = updated.(RootModel)
+	if m.flash != "No comment on this line" {
+		t.Errorf("flash = %q, want a note that there's no comment", m.flash)
+	}
+
+	m.comments.Add(comment.Comment{FilePath: "main.go", StartLine: 2, EndLine: 2, LineType: git.LineAdded, Body: "why?"})
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'Q'}})
+	m = updated.(RootModel)
+	if c := m.comments.Get("main.go", 2); c == nil || !c.Question || len(saved) != 1 || !saved[0].Question {
+		t.Fatalf("Q should mark the comment a question and save it, got %+v", c)
+	}
+
+	// Editing the comment keeps it a question
+	updated, _ = m.Update(CommentSubmitMsg{FilePath: "main.go", LineNo: 2, EndLineNo: 2, LineType: git.LineAdded, Body: "why a map?"})
+	m = updated.(RootModel)
+	if c := m.comments.Get("main.go", 2); !c.Question {
+		t.Error("an edited question should stay one")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'Q'}})
+	m = updated.(RootModel)
+	if c := m.comments.Get("main.go", 2); c.Question {
+		t.Error("Q again should mark the comment a statement")
+	}
+}
+
+func TestRootMarks(t *testing.T) {
+	m := newTestRoot()
+	press := func(keys string) {
+		for _, k := range keys {
+			updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+			m = updated.(RootModel)
+		}
+	}
+
+	m.SetPosition("main.go", 3)
+	press("ma")
+	if want := map[string]Mark{"a": {Path: "main.go", Line: 3}}; !reflect.DeepEqual(m.Marks(), want) {
+		t.Fatalf("marks = %v, want %v", m.Marks(), want)
+	}
+
+	press("gg")
+	press("'a")
+	if path, line := m.Position(); path != "main.go" || line != 3 {
+		t.Errorf("'a went to %s:%d, want main.go:3", path, line)
+	}
+
+	press("'b")
+	if m.flash != "Mark 'b isn't set" {
+		t.Errorf("flash = %q", m.flash)
+	}
+
+	m.LoadMarks(map[string]Mark{"c": {Path: "gone.go", Line: 1}})
+	press("'c")
+	if m.flash != "gone.go isn't in the review" {
+		t.Errorf("flash = %q", m.flash)
+	}
+
+	// In the file list, m still compares files, which needs a repository
+	press("h")
+	press("m")
+	if m.pendingMark != "" || m.flash != "Comparing files needs a git repository" {
+		t.Errorf("m in the file list should compare files, got flash %q, pending %q", m.flash, m.pendingMark)
+	}
+}
+
+func TestRootJumpToDefinition(t *testing.T) {
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{
+			{Path: "main.go", Status: "M"},
+			{Path: "util.go", Status: "A"},
+		},
+		diffs: map[string]*git.FileDiff{
+			"main.go": {Path: "main.go", Status: "M", Hunks: []git.Hunk{{
+				Header: "@@ -1,2 +1,3 @@", OldStart: 1, OldCount: 2, NewStart: 1, NewCount: 3,
+				Lines: []git.Line{
+					{Content: "func main() {", Type: git.LineContext, OldLineNo: 1, NewLineNo: 1},
+					{Content: "\tfmt.Println(parse(os.Args))", Type: git.LineAdded, NewLineNo: 2},
+					{Content: "}", Type: git.LineContext, OldLineNo: 2, NewLineNo: 3},
+				},
+			}}},
+			"util.go": {Path: "util.go", Status: "A", Hunks: []git.Hunk{{
+				Header: "@@ -0,0 +1,2 @@", NewStart: 1, NewCount: 2,
+				Lines: []git.Line{
+					{Content: "package main", Type: git.LineAdded, NewLineNo: 1},
+					{Content: "func parse(args []string) string {", Type: git.LineAdded, NewLineNo: 2},
+				},
+			}}},
+		},
+	}
+	m := NewRootModel(mock, "main", 80, 24)
+	press := func(k tea.KeyType) {
+		updated, _ := m.Update(tea.KeyMsg{Type: k})
+		m = updated.(RootModel)
+	}
+
+	m.SetPosition("main.go", 1)
+	press(tea.KeyCtrlCloseBracket)
+	if m.flash != "No call to a changed function on this line" {
+		t.Errorf("flash on a line without calls = %q", m.flash)
+	}
+
+	m.SetPosition("main.go", 2)
+	press(tea.KeyCtrlCloseBracket)
+	if path, line := m.Position(); path != "util.go" || line != 2 {
+		t.Fatalf("ctrl+] went to %s:%d, want util.go:2", path, line)
+	}
+
+	press(tea.KeyCtrlT)
+	if path, line := m.Position(); path != "main.go" || line != 2 {
+		t.Errorf("ctrl+t went back to %s:%d, want main.go:2", path, line)
+	}
+	press(tea.KeyCtrlT)
+	if m.flash != "No jump to go back from" {
+		t.Errorf("flash with nothing to go back to = %q", m.flash)
+	}
+}
+
+func TestRootOutline(t *testing.T) {
+	mock := &mockGitRunner{
+		files: []git.ChangedFile{
+			{Path: "main.go", Status: "M"},
+			{Path: "util.go", Status: "A"},
+		},
+		diffs: map[string]*git.FileDiff{
+			"main.go": makeTestDiff(),
+			"util.go": {Path: "util.go", Status: "A", Hunks: []git.Hunk{{
+				Header: "@@ -0,0 +1,3 @@", NewStart: 1, NewCount: 3,
+				Lines: []git.Line{
+					{Content: "package main", Type: git.LineAdded, NewLineNo: 1},
+					{Content: "", Type: git.LineAdded, NewLineNo: 2},
+					{Content: "func helper() {}", Type: git.LineAdded, NewLineNo: 3},
+				},
+			}}},
+		},
+	}
+	m := NewRootModel(mock, "main", 80, 24)
+	send := func(msg tea.Msg) {
+		updated, cmd := m.Update(msg)
+		m = updated.(RootModel)
+		if cmd != nil {
+			if msg := cmd(); msg != nil {
+				updated, _ = m.Update(msg)
+				m = updated.(RootModel)
+			}
+		}
+	}
+
+	send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	if m.focus != focusOutline || !strings.Contains(m.View(), "helper") {
+		t.Fatalf("s should show the outline, got focus %d:\n%s", m.focus, m.View())
+	}
+	send(tea.KeyMsg{Type: tea.KeyEnter})
+	if path, line := m.Position(); m.focus != focusDiffViewer || path != "util.go" || line != 3 {
+		t.Errorf("enter went to %s:%d with focus %d, want util.go:3 in the diff", path, line, m.focus)
+	}
+}
+
+// contentMockGitRunner adds reading files after the change to mockGitRunner.
+type contentMockGitRunner struct {
+	mockGitRunner
+	contents map[string]string
+}
+
+func (m *contentMockGitRunner) FileContent(worktree bool, path string) (string, error) {
+	content, ok := m.contents[path]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return content, nil
+}
+
+func TestRootFullFile(t *testing.T) {
+	press := func(m RootModel, k rune) RootModel {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{k}})
+		return updated.(RootModel)
+	}
+	fd := makeTestDiff()
+	fd.Path = "main.go"
+	src := &contentMockGitRunner{
+		mockGitRunner: mockGitRunner{
+			files: []git.ChangedFile{{Path: "main.go", Status: "M"}},
+			diffs: map[string]*git.FileDiff{"main.go": fd},
+		},
+		contents: map[string]string{"main.go": "package main\nnew line\nanother new\nunchanged\nmore\nend\n"},
+	}
+	m := NewRootModel(src, "main", 100, 24)
+	m = press(m, 'l')
+	m.diffViewer.GotoLine(3)
+	diffLines := m.diffViewer.TotalLines()
+
+	m = press(m, 'f')
+	if !m.diffViewer.ShowingWholeFiles() || m.diffViewer.TotalLines() != diffLines+2 {
+		t.Fatalf("f should show the whole file: %d lines, want %d", m.diffViewer.TotalLines(), diffLines+2)
+	}
+	if got := m.diffViewer.CurrentLineNo(); got != 3 {
+		t.Errorf("cursor on line %d, want it kept on 3", got)
+	}
+	view := m.View()
+	if !strings.Contains(view, "end") || !strings.Contains(view, "whole file") {
+		t.Errorf("view should show the end of the file and say it's whole:\n%s", view)
+	}
+
+	m = press(m, 'f')
+	if m.diffViewer.ShowingWholeFiles() || m.diffViewer.TotalLines() != diffLines {
+		t.Errorf("f again should show only the hunks: %d lines, want %d", m.diffViewer.TotalLines(), diffLines)
+	}
+
+	m = press(newTestRoot(), 'f')
+	if m.diffViewer.ShowingWholeFiles() || !strings.Contains(m.View(), "needs a git repository") {
+		t.Error("f without a way to read files should say it can't show them whole")
+	}
+}
@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/deparker/revui/internal/git"
+)
+
+// ShellCloseMsg is sent when the user closes the output of a shell command.
+type ShellCloseMsg struct{}
+
+// shellDoneMsg carries the output of a shell command run from the review.
+type shellDoneMsg struct {
+	run    int // which run of a command it was, as counted by RootModel
+	output string
+	stderr string // when kept apart from output
+	err    error
+}
+
+// runShell runs command with sh in dir, the repository's top level, or the
+// working directory if dir is empty, with input, if any, on its stdin. The
+// result is tagged with run, so that a command's late result isn't taken for
+// that of the one run after it. With separate set, only stdout is the output,
+// and stderr, such as a command line tool's progress, is kept apart.
+func runShell(dir, command, input string, run int, separate bool) tea.Cmd {
+	return func() tea.Msg {
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Dir = dir
+		if input != "" {
+			cmd.Stdin = strings.NewReader(input)
+		}
+		if !separate {
+			out, err := cmd.CombinedOutput()
+			return shellDoneMsg{run: run, output: string(out), err: err}
+		}
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		return shellDoneMsg{run: run, output: string(out), stderr: stderr.String(), err: err}
+	}
+}
+
+// expandShell substitutes the open file's path, quoted for the shell, for
+// each % in command, as in vim's :!. %% is a literal %.
+func expandShell(command, path string) string {
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] != '%' {
+			b.WriteByte(command[i])
+			continue
+		}
+		if i+1 < len(command) && command[i+1] == '%' {
+			b.WriteByte('%')
+			i++
+			continue
+		}
+		b.WriteString(shellQuote(path))
+	}
+	return b.String()
+}
+
+// shellQuote quotes s as a single word for sh.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./+,:@", r))
+	}) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ShellOutput is a sub-model showing the output of a shell command run from
+// the review, scrollable while it's read.
+type ShellOutput struct {
+	command string
+	run     int // the run whose result is awaited
+	title   string
+	wrap    bool   // wrap lines to the width, for prose, instead of truncating them
+	output  string // as the command printed it, for wrapping again on resize
+	lines   []string
+	running bool
+	err     error
+	offset  int // first line shown
+	width   int
+	height  int
+}
+
+// NewShellOutput creates the view of command, which is running.
+func NewShellOutput(command string, width, height int) ShellOutput {
+	return ShellOutput{command: command, title: "$ " + command, running: true, width: width, height: height}
+}
+
+// NewSummaryOutput creates the view of the summary command is writing of
+// what title names, wrapped to fit.
+func NewSummaryOutput(title, command string, width, height int) ShellOutput {
+	so := NewShellOutput(command, width, height)
+	so.title, so.wrap = title, true
+	return so
+}
+
+// SetResult shows the output command finished with, and its error if it
+// failed.
+func (so *ShellOutput) SetResult(output string, err error) {
+	so.running = false
+	so.err = err
+	so.output = strings.ReplaceAll(git.StripColor(output), "\t", "    ")
+	so.layout()
+	so.offset = 0
+}
+
+// layout splits the output into the lines shown, wrapping them if it's
+// prose.
+func (so *ShellOutput) layout() {
+	output := strings.TrimRight(so.output, "\n")
+	if so.wrap {
+		output = ansi.Wrap(output, max(so.width-2, 1), "")
+	}
+	so.lines = strings.Split(output, "\n")
+	if so.output == "" {
+		so.lines = nil
+	}
+}
+
+// SetSize sets the size of the view.
+func (so *ShellOutput) SetSize(width, height int) {
+	so.width, so.height = width, height
+	if so.wrap {
+		so.layout()
+	}
+	so.offset = min(so.offset, so.maxOffset())
+}
+
+// rows is how many lines of output fit between the title and the footer.
+func (so ShellOutput) rows() int {
+	return max(so.height-4, 1)
+}
+
+func (so ShellOutput) maxOffset() int {
+	return max(len(so.lines)-so.rows(), 0)
+}
+
+// Update handles key messages.
+func (so ShellOutput) Update(msg tea.Msg) (ShellOutput, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return so, nil
+	}
+	switch key.String() {
+	case "j", "down":
+		so.offset++
+	case "k", "up":
+		so.offset--
+	case "ctrl+d", "pgdown", " ":
+		so.offset += so.rows() / 2
+	case "ctrl+u", "pgup":
+		so.offset -= so.rows() / 2
+	case "g", "home":
+		so.offset = 0
+	case "G", "end":
+		so.offset = so.maxOffset()
+	case "esc", "q", "enter":
+		return so, func() tea.Msg { return ShellCloseMsg{} }
+	}
+	so.offset = max(min(so.offset, so.maxOffset()), 0)
+	return so, nil
+}
+
+// View renders the command's output.
+func (so ShellOutput) View() string {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(runewidth.Truncate(so.title, max(so.width, 1), "…")))
+	s.WriteString("\n")
+	switch {
+	case so.running:
+		s.WriteString("  Running…\n")
+	case len(so.lines) == 0:
+		s.WriteString(footerStyle.Render("  (no output)") + "\n")
+	}
+	end := min(so.offset+so.rows(), len(so.lines))
+	for _, line := range so.lines[so.offset:end] {
+		s.WriteString("  " + runewidth.Truncate(line, max(so.width-2, 1), "…") + "\n")
+	}
+
+	s.WriteString("\n")
+	if so.err != nil {
+		s.WriteString(failStyle.Render("  "+so.err.Error()) + "  ")
+	}
+	footer := "[esc] close"
+	if len(so.lines) > so.rows() {
+		footer = fmt.Sprintf("%d-%d of %d  [j/k] scroll  [g/G] top/bottom  [esc] close", so.offset+1, end, len(so.lines))
+	}
+	s.WriteString(footerStyle.Render("  " + footer))
+	return s.String()
+}
@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+)
+
+func TestExpandShell(t *testing.T) {
+	tests := []struct {
+		command, path, want string
+	}{
+		{"go test ./...", "main.go", "go test ./..."},
+		{"wc -l %", "pkg/main.go", "wc -l pkg/main.go"},
+		{"cat % %", "a b.go", "cat 'a b.go' 'a b.go'"},
+		{"printf 100%%", "main.go", "printf 100%"},
+		{"echo %", "it's.go", `echo 'it'\''s.go'`},
+		{"echo %", "", "echo ''"},
+	}
+	for _, tt := range tests {
+		if got := expandShell(tt.command, tt.path); got != tt.want {
+			t.Errorf("expandShell(%q, %q) = %q, want %q", tt.command, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestShellOutputScroll(t *testing.T) {
+	so := NewShellOutput("seq 100", 80, 24)
+	if !strings.Contains(so.View(), "Running") {
+		t.Error("should say the command is running")
+	}
+	var out strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&out, "line %d\n", i)
+	}
+	so.SetResult(out.String(), nil)
+
+	press := func(k string) {
+		so, _ = so.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+	if view := so.View(); !strings.Contains(view, "line 1\n") || strings.Contains(view, "line 30\n") {
+		t.Errorf("should show the top of the output:\n%s", view)
+	}
+	press("G")
+	if view := so.View(); !strings.Contains(view, "line 100") || !strings.Contains(view, "of 100") {
+		t.Errorf("G should show the bottom of the output:\n%s", view)
+	}
+	press("j")
+	if so.offset != so.maxOffset() {
+		t.Error("j shouldn't scroll past the end")
+	}
+	press("g")
+	press("k")
+	if so.offset != 0 {
+		t.Error("k shouldn't scroll past the top")
+	}
+}
+
+func TestRootShellCommand(t *testing.T) {
+	m := newTestRoot()
+	m.workDir = t.TempDir()
+	var cmd tea.Cmd
+	press := func(msg tea.KeyMsg) {
+		var updated tea.Model
+		updated, cmd = m.Update(msg)
+		m = updated.(RootModel)
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'!'}})
+	if !m.goingToLine || m.lineInput.Value() != "!" {
+		t.Fatalf("! should open the command prompt, got %q", m.lineInput.Value())
+	}
+	for _, r := range "echo file=% && false" {
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.focus != focusShellOutput || cmd == nil {
+		t.Fatalf("focus = %d; Enter should run the command", m.focus)
+	}
+	updated, _ := m.Update(cmd())
+	m = updated.(RootModel)
+	view := m.View()
+	if !strings.Contains(view, "$ echo file=main.go && false") || !strings.Contains(view, "file=main.go") {
+		t.Errorf("should show the command with the file substituted, and its output:\n%s", view)
+	}
+	if !strings.Contains(view, "exit status 1") {
+		t.Errorf("should show that the command failed:\n%s", view)
+	}
+	press(tea.KeyMsg{Type: tea.KeyEscape})
+	updated, _ = m.Update(cmd())
+	m = updated.(RootModel)
+	if m.focus == focusShellOutput {
+		t.Error("esc should close the output")
+	}
+}
+
+func TestRootSummarizeHunk(t *testing.T) {
+	m := newTestRoot()
+	m.workDir = t.TempDir()
+	var cmd tea.Cmd
+	press := func(r rune) {
+		var updated tea.Model
+		updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(RootModel)
+	}
+	press('l')
+	press('S')
+	if m.focus == focusShellOutput || !strings.Contains(m.View(), "summarize_command") {
+		t.Fatal("S without a summarize command should say how to set one")
+	}
+
+	// cat stands in for the LLM, echoing the patch it's given
+	m.SetSummarizeCommand("cat")
+	press('j')
+	press('S')
+	if m.focus != focusShellOutput || cmd == nil {
+		t.Fatalf("focus = %d; S should summarize the hunk", m.focus)
+	}
+	updated, _ := m.Update(cmd())
+	m = updated.(RootModel)
+	view := m.View()
+	for _, want := range []string{"Summary of main.go @@", "-old line", "+new line", "+++ b/main.go"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestRootShellRuns(t *testing.T) {
+	m := newTestRoot()
+	m.workDir = t.TempDir()
+	run := func(command string) tea.Cmd {
+		updated, cmd := m.runShell(command)
+		m = updated.(RootModel)
+		return cmd
+	}
+
+	// The same command run again: only the second run's result is shown
+	first := run("echo $$")
+	second := run("echo $$")
+	late := first()
+	updated, _ := m.Update(second())
+	m = updated.(RootModel)
+	want := m.shellOutput.output
+	updated, _ = m.Update(late)
+	m = updated.(RootModel)
+	if m.shellOutput.output != want {
+		t.Errorf("output = %q, want the second run's %q", m.shellOutput.output, want)
+	}
+
+	// A summary leaves out what the command reports on stderr, unless it fails
+	for _, tt := range []struct {
+		command, want, notWant string
+	}{
+		{command: "echo thinking... >&2; echo summary", want: "summary", notWant: "thinking"},
+		{command: "echo no API key >&2; false", want: "no API key"},
+	} {
+		m.SetSummarizeCommand(tt.command)
+		m.focus = focusDiffViewer
+		updated, cmd := m.summarizeHunk()
+		m = updated.(RootModel)
+		if cmd == nil {
+			t.Fatal("S should summarize the hunk")
+		}
+		updated, _ = m.Update(cmd())
+		m = updated.(RootModel)
+		view := m.View()
+		if !strings.Contains(view, tt.want) || tt.notWant != "" && strings.Contains(view, tt.notWant) {
+			t.Errorf("%s: view should contain %q and not %q:\n%s", tt.command, tt.want, tt.notWant, view)
+		}
+	}
+}
+
+func TestSummaryOutputWraps(t *testing.T) {
+	so := NewSummaryOutput("Summary", "llm", 22, 24)
+	so.SetResult("Renames the config loader and threads its errors up.\n", nil)
+	if len(so.lines) < 3 {
+		t.Fatalf("lines = %q, want the sentence wrapped to 20 columns", so.lines)
+	}
+	for _, l := range so.lines {
+		if runewidth.StringWidth(l) > 20 {
+			t.Errorf("line %q is wider than 20 columns", l)
+		}
+	}
+	so.SetSize(80, 24)
+	if len(so.lines) != 1 {
+		t.Errorf("after widening, lines = %q", so.lines)
+	}
+}
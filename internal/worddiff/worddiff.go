@@ -0,0 +1,200 @@
+// Package worddiff finds the words that differ between two versions of a
+// line, for highlighting what changed within a changed line.
+package worddiff
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTokens is the most tokens a pair of lines may have between them to be
+// compared; longer lines, such as minified code, are left alone.
+const maxTokens = 500
+
+// Span is a range of bytes in a line, from Start up to End.
+type Span struct {
+	Start, End int
+}
+
+// Tokens splits s into words, runs of letters, digits, and underscores; runs
+// of whitespace; and each other character on its own. The tokens join to s.
+func Tokens(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		end := i + size
+		switch {
+		case isWord(r):
+			end = i + runLength(s[i:], isWord)
+		case unicode.IsSpace(r):
+			end = i + runLength(s[i:], unicode.IsSpace)
+		}
+		tokens = append(tokens, s[i:end])
+		i = end
+	}
+	return tokens
+}
+
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// runLength returns the length in bytes of the run of runes at the start of
+// s that in accepts.
+func runLength(s string, in func(rune) bool) int {
+	for i, r := range s {
+		if !in(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// Diff returns the spans of old and of new that differ between them, by
+// word. Lines with too little in common for the difference to be telling,
+// such as a line rewritten outright, have no spans, and nor do lines that
+// are the same or are too long to compare.
+func Diff(old, new string) (removed, added []Span) {
+	a, b := Tokens(old), Tokens(new)
+	if len(a)+len(b) > maxTokens || old == new {
+		return nil, nil
+	}
+	inA, inB := common(a, b)
+	// Keeping less than a third of the shorter line, whitespace aside, is
+	// a rewrite rather than an edit
+	if visible(a, inA)*3 < min(visible(a, nil), visible(b, nil)) {
+		return nil, nil
+	}
+	return spans(a, inA), spans(b, inB)
+}
+
+// visible returns the length of the tokens that aren't whitespace, or of
+// those of them marked in only, if it isn't nil.
+func visible(tokens []string, only []bool) int {
+	n := 0
+	for i, t := range tokens {
+		if strings.TrimSpace(t) != "" && (only == nil || only[i]) {
+			n += len(t)
+		}
+	}
+	return n
+}
+
+// spans returns the byte ranges of the tokens that aren't kept, joining
+// those that are next to each other or apart only by whitespace.
+func spans(tokens []string, kept []bool) []Span {
+	var out []Span
+	pos := 0
+	for i, t := range tokens {
+		start := pos
+		pos += len(t)
+		if kept[i] {
+			continue
+		}
+		if n := len(out); n > 0 && gapIsSpace(tokens, out[n-1].End, start) {
+			out[n-1].End = pos
+			continue
+		}
+		out = append(out, Span{start, pos})
+	}
+	// A change to the whole line says no more than the line's color
+	if len(out) == 1 && out[0] == (Span{0, pos}) {
+		return nil
+	}
+	return out
+}
+
+// gapIsSpace reports whether the tokens from byte from up to byte to are
+// only whitespace.
+func gapIsSpace(tokens []string, from, to int) bool {
+	pos := 0
+	for _, t := range tokens {
+		if pos >= from && pos < to && strings.TrimSpace(t) != "" {
+			return false
+		}
+		pos += len(t)
+	}
+	return true
+}
+
+// common reports, for each token of a and of b, whether it's in a longest
+// common subsequence of the two. The tokens the two start and end with are
+// matched directly; the rest by Myers' O(ND) algorithm.
+func common(a, b []string) (inA, inB []bool) {
+	inA, inB = make([]bool, len(a)), make([]bool, len(b))
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		inA[pre], inB[pre] = true, true
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		inA[len(a)-1-suf], inB[len(b)-1-suf] = true, true
+		suf++
+	}
+	myers(a[pre:len(a)-suf], b[pre:len(b)-suf], inA[pre:len(a)-suf], inB[pre:len(b)-suf])
+	return inA, inB
+}
+
+// myers marks in inA and inB the tokens of a and b in a longest common
+// subsequence of the two.
+func myers(a, b []string, inA, inB []bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return
+	}
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d] holds the diagonals -d to d of v as it was before the edits
+	// of step d were tried
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+		done := false
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1] // down: an insertion
+			} else {
+				x = v[offset+k-1] + 1 // right: a deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		if done {
+			break
+		}
+	}
+
+	// Walk back from the end, marking the diagonal moves: the tokens kept
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d][i] is diagonal i-d-1
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			inA[x], inB[y] = true, true
+		}
+		x, y = prevX, prevY
+	}
+}
@@ -0,0 +1,106 @@
+package worddiff
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	got := Tokens("\tif err := s.serve(ctx_1); err != nil {")
+	want := []string{"\t", "if", " ", "err", " ", ":", "=", " ", "s", ".", "serve", "(", "ctx_1", ")", ";", " ", "err", " ", "!", "=", " ", "nil", " ", "{"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tokens = %q, want %q", got, want)
+	}
+	if strings.Join(Tokens("héllo wörld→x"), "") != "héllo wörld→x" {
+		t.Error("tokens don't join to the line")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name           string
+		old, new       string
+		removed, added []string
+	}{
+		{
+			name:  "an argument added",
+			old:   "return s.serve()",
+			new:   "return s.serve(ctx)",
+			added: []string{"ctx"},
+		},
+		{
+			name:    "a word replaced",
+			old:     "timeout := 5 * time.Second",
+			new:     "timeout := 10 * time.Second",
+			removed: []string{"5"},
+			added:   []string{"10"},
+		},
+		{
+			name:    "neighbouring words join across spaces",
+			old:     "x := old value here",
+			new:     "x := new thing here",
+			removed: []string{"old value"},
+			added:   []string{"new thing"},
+		},
+		{
+			name: "rewritten outright",
+			old:  "fmt.Println(a, b)",
+			new:  "return errors.New(msg)",
+		},
+		{
+			name: "the same",
+			old:  "x := 1",
+			new:  "x := 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed, added := Diff(tt.old, tt.new)
+			if got := text(tt.old, removed); !reflect.DeepEqual(got, tt.removed) {
+				t.Errorf("removed = %q, want %q", got, tt.removed)
+			}
+			if got := text(tt.new, added); !reflect.DeepEqual(got, tt.added) {
+				t.Errorf("added = %q, want %q", got, tt.added)
+			}
+		})
+	}
+}
+
+func TestDiffTooLong(t *testing.T) {
+	old := strings.Repeat("a ", maxTokens)
+	if removed, added := Diff(old, old+"b"); removed != nil || added != nil {
+		t.Errorf("Diff of long lines = %v, %v, want nothing", removed, added)
+	}
+}
+
+func TestCommon(t *testing.T) {
+	a := strings.Split("abcabba", "")
+	b := strings.Split("cbabac", "")
+	inA, inB := common(a, b)
+	var lcsA, lcsB []string
+	for i, ok := range inA {
+		if ok {
+			lcsA = append(lcsA, a[i])
+		}
+	}
+	for i, ok := range inB {
+		if ok {
+			lcsB = append(lcsB, b[i])
+		}
+	}
+	// The classic example: the longest common subsequences have 4 letters
+	if len(lcsA) != 4 || !slices.Equal(lcsA, lcsB) {
+		t.Errorf("common = %q and %q, want the same 4 letters", lcsA, lcsB)
+	}
+}
+
+// text returns the parts of s that spans cover.
+func text(s string, spans []Span) []string {
+	var out []string
+	for _, sp := range spans {
+		out = append(out, s[sp.Start:sp.End])
+	}
+	return out
+}